@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/service"
+	"github.com/srinivasarynh/age_calculator/pkg/client"
+)
+
+// exportFormats are the formats export asks the server for.
+var exportFormats = []string{"csv", "ndjson", "vcf", "xlsx"}
+
+var commands = map[string]func(args []string, stdout io.Writer) error{
+	"create": runCreate,
+	"get":    runGet,
+	"list":   runList,
+	"update": runUpdate,
+	"delete": runDelete,
+	"calc":   runCalc,
+	"export": runExport,
+}
+
+type commonFlags struct {
+	url    string
+	apiKey string
+	json   bool
+}
+
+func newFlagSet(name string) (*flag.FlagSet, *commonFlags) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	common := &commonFlags{}
+	fs.StringVar(&common.url, "url", envOr("AGECLI_URL", "http://localhost:8080"), "API base URL")
+	fs.StringVar(&common.apiKey, "api-key", os.Getenv("AGECLI_API_KEY"), "API key")
+	fs.BoolVar(&common.json, "json", false, "print JSON")
+	return fs, common
+}
+
+func (c *commonFlags) client() *client.Client {
+	return client.New(c.url, client.WithAPIKey(c.apiKey))
+}
+
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return usagef("%s: %v", fs.Name(), err)
+	}
+	return nil
+}
+
+func idArg(fs *flag.FlagSet) (int32, error) {
+	if fs.NArg() != 1 {
+		return 0, usagef("%s: expected exactly one user id", fs.Name())
+	}
+	id, err := strconv.ParseInt(fs.Arg(0), 10, 32)
+	if err != nil {
+		return 0, usagef("%s: invalid user id %q", fs.Name(), fs.Arg(0))
+	}
+	return int32(id), nil
+}
+
+func runCreate(args []string, stdout io.Writer) error {
+	fs, common := newFlagSet("create")
+	var req client.CreateUserRequest
+	fs.StringVar(&req.Name, "name", "", "user name")
+	fs.StringVar(&req.DOB, "dob", "", "date of birth (YYYY-MM-DD)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if req.Name == "" || req.DOB == "" {
+		return usagef("create: --name and --dob are required")
+	}
+
+	user, err := common.client().CreateUser(context.Background(), &req)
+	if err != nil {
+		return err
+	}
+	return printUsers(stdout, common.json, user, []client.UserResponse{*user})
+}
+
+func runGet(args []string, stdout io.Writer) error {
+	fs, common := newFlagSet("get")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	id, err := idArg(fs)
+	if err != nil {
+		return err
+	}
+
+	user, err := common.client().GetUser(context.Background(), id)
+	if err != nil {
+		return err
+	}
+	return printUsers(stdout, common.json, user, []client.UserResponse{*user})
+}
+
+func runList(args []string, stdout io.Writer) error {
+	fs, common := newFlagSet("list")
+	page := fs.Int("page", 1, "page number")
+	size := fs.Int("size", 10, "page size")
+	name := fs.String("name", "", "filter by name")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	opts := []client.ListOption{client.WithPage(*page), client.WithPageSize(*size)}
+	if *name != "" {
+		opts = append(opts, client.WithName(*name))
+	}
+
+	result, err := common.client().ListUsers(context.Background(), opts...)
+	if err != nil {
+		return err
+	}
+	if err := printUsers(stdout, common.json, result, result.Users); err != nil {
+		return err
+	}
+	if !common.json && result.Total != nil && result.TotalPages != nil {
+		fmt.Fprintf(stdout, "\npage %d of %d (%d users)\n", result.Page, *result.TotalPages, *result.Total)
+	}
+	return nil
+}
+
+func runUpdate(args []string, stdout io.Writer) error {
+	fs, common := newFlagSet("update")
+	var req client.UpdateUserRequest
+	fs.StringVar(&req.Name, "name", "", "user name")
+	fs.StringVar(&req.DOB, "dob", "", "date of birth (YYYY-MM-DD)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	id, err := idArg(fs)
+	if err != nil {
+		return err
+	}
+	if req.Name == "" || req.DOB == "" {
+		return usagef("update: --name and --dob are required")
+	}
+
+	user, err := common.client().UpdateUser(context.Background(), id, &req)
+	if err != nil {
+		return err
+	}
+	return printUsers(stdout, common.json, user, []client.UserResponse{*user})
+}
+
+func runDelete(args []string, stdout io.Writer) error {
+	fs, common := newFlagSet("delete")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	id, err := idArg(fs)
+	if err != nil {
+		return err
+	}
+
+	if err := common.client().DeleteUser(context.Background(), id); err != nil {
+		return err
+	}
+	if common.json {
+		return writeJSON(stdout, map[string]string{"deleted": strconv.Itoa(int(id))})
+	}
+	fmt.Fprintf(stdout, "deleted user %d\n", id)
+	return nil
+}
+
+func runCalc(args []string, stdout io.Writer) error {
+	fs, common := newFlagSet("calc")
+	dobFlag := fs.String("dob", "", "date of birth (YYYY-MM-DD)")
+	asOfFlag := fs.String("as-of", "", "reference date (YYYY-MM-DD, default today)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *dobFlag == "" {
+		return usagef("calc: --dob is required")
+	}
+
+	dob, err := service.ParseDOB(*dobFlag)
+	if err != nil {
+		return usagef("calc: %v", err)
+	}
+	asOf := time.Now()
+	if *asOfFlag != "" {
+		if asOf, err = service.ParseDOB(*asOfFlag); err != nil {
+			return usagef("calc: --as-of: %v", err)
+		}
+	}
+
+	result := struct {
+		DOB       string               `json:"dob"`
+		AsOf      string               `json:"as_of"`
+		Age       int                  `json:"age"`
+		Breakdown service.AgeBreakdown `json:"breakdown"`
+	}{
+		DOB:       dob.Format(time.DateOnly),
+		AsOf:      asOf.Format(time.DateOnly),
+		Age:       service.CalculateAge(dob, asOf),
+		Breakdown: service.CalculateAgeBreakdown(dob, asOf),
+	}
+
+	if common.json {
+		return writeJSON(stdout, result)
+	}
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DOB\tAS OF\tAGE\tYEARS\tMONTHS\tDAYS")
+	fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\n", result.DOB, result.AsOf, result.Age,
+		result.Breakdown.Years, result.Breakdown.Months, result.Breakdown.Days)
+	return w.Flush()
+}
+
+func runExport(args []string, stdout io.Writer) error {
+	fs, common := newFlagSet("export")
+	format := fs.String("format", "csv", "output format (csv, ndjson, vcf or xlsx)")
+	name := fs.String("name", "", "only users with this name")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if !slices.Contains(exportFormats, *format) {
+		return usagef("export: unsupported format %q", *format)
+	}
+
+	_, err := common.client().Export(context.Background(), &client.ExportUsersRequest{Format: *format, Name: *name}, stdout)
+	return err
+}
+
+func printUsers(stdout io.Writer, asJSON bool, raw any, users []client.UserResponse) error {
+	if asJSON {
+		return writeJSON(stdout, raw)
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tDOB\tAGE")
+	for _, user := range users {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", user.ID, user.Name, user.DOB, formatAge(user.Age))
+	}
+	return w.Flush()
+}
+
+func formatAge(age *int) string {
+	if age == nil {
+		return "-"
+	}
+	return strconv.Itoa(*age)
+}
+
+func writeJSON(stdout io.Writer, v any) error {
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/srinivasarynh/age_calculator/pkg/client"
+)
+
+const (
+	exitOK = iota
+	exitError
+	exitUsage
+	exitNotFound
+	exitInvalid
+	exitServer
+)
+
+const usage = `Usage: agecli <command> [flags] [args]
+
+Commands:
+  create  --name NAME --dob YYYY-MM-DD     create a user
+  get     <id>                             show a user
+  list    [--page N] [--size N] [--name S] list users
+  update  <id> --name NAME --dob YYYY-MM-DD replace a user
+  delete  <id>                             delete a user
+  calc    --dob YYYY-MM-DD [--as-of DATE]  compute an age locally
+  export  [--format F] [--name S]          write every user as csv, ndjson, vcf or xlsx
+
+Common flags:
+  --url      API base URL (env AGECLI_URL, default http://localhost:8080)
+  --api-key  API key sent as X-API-Key (env AGECLI_API_KEY)
+  --json     print raw JSON instead of a table
+
+Exit codes: 0 ok, 1 error, 2 usage, 3 not found, 4 invalid request, 5 server error
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		fmt.Fprint(stderr, usage)
+		if len(args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "agecli: unknown command %q\n\n%s", args[0], usage)
+		return exitUsage
+	}
+
+	err := cmd(args[1:], stdout)
+	if err == nil {
+		return exitOK
+	}
+
+	fmt.Fprintf(stderr, "agecli: %v\n", err)
+	return exitCode(err)
+}
+
+func exitCode(err error) int {
+	var usageErr *usageError
+	if errors.As(err, &usageErr) {
+		return exitUsage
+	}
+
+	var apiErr *client.Error
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.Status == 404:
+			return exitNotFound
+		case apiErr.Status >= 500:
+			return exitServer
+		default:
+			return exitInvalid
+		}
+	}
+	return exitError
+}
+
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func usagef(format string, args ...any) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/srinivasarynh/age_calculator/config"
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/handler"
+	"github.com/srinivasarynh/age_calculator/internal/middleware"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/routes"
+	"github.com/srinivasarynh/age_calculator/internal/service"
+	"github.com/srinivasarynh/age_calculator/pkg/client"
+	"go.uber.org/zap"
+)
+
+var testNow = time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	fixed := clock.Fixed(testNow)
+	repo := repository.NewMemoryUserRepository(fixed)
+	svc := service.NewUserService(repo, zap.NewNop(), service.WithClock(fixed))
+
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	routes.SetupRoutes(app, handler.NewUserHandler(svc, zap.NewNop()), middleware.AdminOnly(config.NewHolder(&config.Config{})), middleware.Tenant(config.NewHolder(&config.Config{})), routes.CachePolicies{})
+
+	srv := httptest.NewServer(adaptor.FiberApp(app))
+	t.Cleanup(srv.Close)
+	t.Setenv("AGECLI_URL", srv.URL)
+	return srv
+}
+
+func runCLI(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestCRUDRoundTrip(t *testing.T) {
+	newTestServer(t)
+
+	code, out, errOut := runCLI(t, "create", "--json", "--name", "Alice", "--dob", "1990-05-10")
+	if code != exitOK {
+		t.Fatalf("create exit = %d, stderr = %s", code, errOut)
+	}
+	var created client.UserResponse
+	if err := json.Unmarshal([]byte(out), &created); err != nil {
+		t.Fatalf("create output is not JSON: %v\n%s", err, out)
+	}
+	if created.ID != 1 || created.Name != "Alice" {
+		t.Errorf("created = %+v", created)
+	}
+
+	code, out, _ = runCLI(t, "get", "1")
+	if code != exitOK {
+		t.Fatalf("get exit = %d", code)
+	}
+	if !strings.Contains(out, "NAME") || !strings.Contains(out, "Alice") || !strings.Contains(out, "35") {
+		t.Errorf("get table output:\n%s", out)
+	}
+
+	if code, _, errOut = runCLI(t, "update", "--name", "Alicia", "--dob", "1990-05-10", "1"); code != exitOK {
+		t.Fatalf("update exit = %d, stderr = %s", code, errOut)
+	}
+
+	code, out, _ = runCLI(t, "list", "--json", "--page", "1", "--size", "5")
+	if code != exitOK {
+		t.Fatalf("list exit = %d", code)
+	}
+	var list client.UserListResponse
+	if err := json.Unmarshal([]byte(out), &list); err != nil {
+		t.Fatal(err)
+	}
+	if list.Total == nil || *list.Total != 1 || list.PageSize != 5 || list.Users[0].Name != "Alicia" {
+		t.Errorf("list = %+v", list)
+	}
+
+	if code, _, _ = runCLI(t, "delete", "1"); code != exitOK {
+		t.Fatalf("delete exit = %d", code)
+	}
+	if code, _, _ = runCLI(t, "get", "1"); code != exitNotFound {
+		t.Errorf("get after delete exit = %d, want %d", code, exitNotFound)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	newTestServer(t)
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{name: "no command", args: nil, code: exitUsage},
+		{name: "unknown command", args: []string{"frobnicate"}, code: exitUsage},
+		{name: "missing flags", args: []string{"create", "--name", "Alice"}, code: exitUsage},
+		{name: "bad id", args: []string{"get", "abc"}, code: exitUsage},
+		{name: "not found", args: []string{"get", "42"}, code: exitNotFound},
+		{name: "validation", args: []string{"create", "--name", "A", "--dob", "1990-05-10"}, code: exitInvalid},
+		{name: "bad pagination", args: []string{"list", "--size", "1000"}, code: exitInvalid},
+		{name: "unsupported export", args: []string{"export", "--format", "xml"}, code: exitUsage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, _ := runCLI(t, tt.args...)
+			if code != tt.code {
+				t.Errorf("exit = %d, want %d", code, tt.code)
+			}
+		})
+	}
+}
+
+func TestServerErrorExitCode(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"Failed to list users"}`))
+	}))
+	defer srv.Close()
+
+	code, _, errOut := runCLI(t, "list", "--url", srv.URL)
+	if code != exitServer {
+		t.Errorf("exit = %d, want %d", code, exitServer)
+	}
+	if !strings.Contains(errOut, "Failed to list users") {
+		t.Errorf("stderr = %q", errOut)
+	}
+}
+
+func TestAPIKeyHeader(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("X-API-Key")
+		w.Write([]byte(`{"id":1,"name":"Alice","dob":"1990-05-10"}`))
+	}))
+	defer srv.Close()
+
+	t.Setenv("AGECLI_API_KEY", "from-env")
+	if code, _, _ := runCLI(t, "get", "--url", srv.URL, "1"); code != exitOK || got != "from-env" {
+		t.Errorf("env key: exit = %d, header = %q", code, got)
+	}
+	if code, _, _ := runCLI(t, "get", "--url", srv.URL, "--api-key", "from-flag", "1"); code != exitOK || got != "from-flag" {
+		t.Errorf("flag key: exit = %d, header = %q", code, got)
+	}
+}
+
+func TestCalc(t *testing.T) {
+	code, out, _ := runCLI(t, "calc", "--json", "--dob", "1990-05-10", "--as-of", "2025-06-15")
+	if code != exitOK {
+		t.Fatalf("exit = %d", code)
+	}
+
+	var result struct {
+		Age       int                  `json:"age"`
+		Breakdown service.AgeBreakdown `json:"breakdown"`
+	}
+	if err := json.Unmarshal([]byte(out), &result); err != nil {
+		t.Fatal(err)
+	}
+	want := service.AgeBreakdown{Years: 35, Months: 1, Days: 5}
+	if result.Age != 35 || result.Breakdown != want {
+		t.Errorf("calc = %+v, want age 35 and %+v", result, want)
+	}
+
+	if code, _, _ := runCLI(t, "calc", "--dob", "not-a-date"); code != exitUsage {
+		t.Errorf("invalid dob exit = %d, want %d", code, exitUsage)
+	}
+}
+
+func TestExportCSVWritesAllUsers(t *testing.T) {
+	newTestServer(t)
+	// More than a page of the list, which the export does not page through.
+	const users = 105
+	for i := 0; i < users; i++ {
+		if code, _, errOut := runCLI(t, "create", "--name", "User", "--dob", "1990-01-01"); code != exitOK {
+			t.Fatalf("seed exit = %d: %s", code, errOut)
+		}
+	}
+
+	code, out, errOut := runCLI(t, "export", "--format", "csv")
+	if code != exitOK {
+		t.Fatalf("export exit = %d: %s", code, errOut)
+	}
+
+	records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != users+1 {
+		t.Fatalf("rows = %d, want header plus %d users", len(records), users)
+	}
+	if strings.Join(records[0], ",") != "id,name,dob,age" {
+		t.Errorf("header = %v", records[0])
+	}
+	if strings.Join(records[1], ",") != "1,User,1990-01-01,35" {
+		t.Errorf("first row = %v", records[1])
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+type options struct {
+	url       string
+	apiKey    string
+	workers   int
+	duration  time.Duration
+	mix       string
+	seedUsers int
+	csvPath   string
+	cleanup   bool
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("loadgen", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var opts options
+	fs.StringVar(&opts.url, "url", envOr("LOADGEN_URL", "http://localhost:8080"), "target API base URL")
+	fs.StringVar(&opts.apiKey, "api-key", os.Getenv("LOADGEN_API_KEY"), "API key sent as X-API-Key")
+	fs.IntVar(&opts.workers, "workers", 10, "concurrent workers")
+	fs.DurationVar(&opts.duration, "duration", 30*time.Second, "how long to generate load")
+	fs.StringVar(&opts.mix, "mix", "get=70,list=20,create=10", "weighted request mix")
+	fs.IntVar(&opts.seedUsers, "seed-users", 50, "users to create before the run so GETs hit real ids")
+	fs.StringVar(&opts.csvPath, "csv", "", "write per-request timings to this CSV file")
+	fs.BoolVar(&opts.cleanup, "cleanup", true, "delete every user created by the run")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	mix, err := parseMix(opts.mix)
+	if err != nil {
+		fmt.Fprintf(stderr, "loadgen: %v\n", err)
+		return 2
+	}
+	if opts.workers < 1 || opts.duration <= 0 {
+		fmt.Fprintln(stderr, "loadgen: --workers and --duration must be positive")
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	client := newClient(opts.url, opts.apiKey)
+	runner := newRunner(client, mix, opts.workers)
+
+	fmt.Fprintf(stdout, "seeding %d users at %s\n", opts.seedUsers, opts.url)
+	if err := runner.seed(ctx, opts.seedUsers); err != nil {
+		fmt.Fprintf(stderr, "loadgen: seeding: %v\n", err)
+		runner.cleanup(context.Background(), stdout)
+		return 1
+	}
+
+	fmt.Fprintf(stdout, "running %s with %d workers, mix %s\n", opts.duration, opts.workers, opts.mix)
+	results, elapsed := runner.run(ctx, opts.duration)
+
+	if opts.cleanup {
+		runner.cleanup(context.Background(), stdout)
+	}
+
+	summarize(results, elapsed).print(stdout)
+
+	if opts.csvPath != "" {
+		if err := writeCSV(opts.csvPath, results); err != nil {
+			fmt.Fprintf(stderr, "loadgen: writing CSV: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "timings written to %s\n", opts.csvPath)
+	}
+	return 0
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
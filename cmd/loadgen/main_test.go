@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/srinivasarynh/age_calculator/config"
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/handler"
+	"github.com/srinivasarynh/age_calculator/internal/middleware"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/routes"
+	"github.com/srinivasarynh/age_calculator/internal/service"
+	"go.uber.org/zap"
+)
+
+func TestParseMix(t *testing.T) {
+	mix, err := parseMix("get=70, list=20,create=10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mix) != 3 || mix[0] != (weightedOp{opGet, 70}) || mix[2] != (weightedOp{opCreate, 10}) {
+		t.Errorf("mix = %+v", mix)
+	}
+
+	if got := pick(mix, 0); got != opGet {
+		t.Errorf("pick(0) = %s, want get", got)
+	}
+	if got := pick(mix, 70); got != opList {
+		t.Errorf("pick(70) = %s, want list", got)
+	}
+	if got := pick(mix, 99); got != opCreate {
+		t.Errorf("pick(99) = %s, want create", got)
+	}
+
+	for _, spec := range []string{"get", "get=x", "delete=10", "get=10,get=5", "get=0", "list=-1"} {
+		if _, err := parseMix(spec); err == nil {
+			t.Errorf("parseMix(%q) succeeded, want error", spec)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var durations []time.Duration
+	for i := 1; i <= 100; i++ {
+		durations = append(durations, time.Duration(i)*time.Millisecond)
+	}
+
+	tests := []struct {
+		p        int
+		expected time.Duration
+	}{
+		{50, 50 * time.Millisecond},
+		{95, 95 * time.Millisecond},
+		{99, 99 * time.Millisecond},
+		{100, 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := percentile(durations, tt.p); got != tt.expected {
+			t.Errorf("p%d = %v, want %v", tt.p, got, tt.expected)
+		}
+	}
+	if got := percentile(nil, 50); got != 0 {
+		t.Errorf("percentile of empty = %v", got)
+	}
+}
+
+func TestRunSeedsLoadsAndCleansUp(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(clock.Real())
+	svc := service.NewUserService(repo, zap.NewNop())
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	routes.SetupRoutes(app, handler.NewUserHandler(svc, zap.NewNop()), middleware.AdminOnly(config.NewHolder(&config.Config{})), middleware.Tenant(config.NewHolder(&config.Config{})), routes.CachePolicies{})
+	srv := httptest.NewServer(adaptor.FiberApp(app))
+	defer srv.Close()
+
+	csvPath := filepath.Join(t.TempDir(), "timings.csv")
+	var stdout, stderr bytes.Buffer
+	code := run([]string{
+		"--url", srv.URL,
+		"--workers", "4",
+		"--duration", "200ms",
+		"--seed-users", "5",
+		"--csv", csvPath,
+	}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("exit = %d, stderr = %s", code, stderr.String())
+	}
+
+	out := stdout.String()
+	for _, want := range []string{"requests:", "p50", "p95", "p99", "req/s", "cleaned up"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if count, _ := repo.Count(context.Background(), repository.UserFilter{}); count != 0 {
+		t.Errorf("%d users left after cleanup", count)
+	}
+
+	f, err := os.Open(csvPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) < 2 || strings.Join(records[0], ",") != "start,operation,duration_ms,status,error" {
+		t.Errorf("csv has %d rows, header %v", len(records), records[0])
+	}
+}
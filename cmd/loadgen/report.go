@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type summary struct {
+	requests   int
+	errors     int
+	p50        time.Duration
+	p95        time.Duration
+	p99        time.Duration
+	throughput float64
+	byOp       map[operation]int
+}
+
+func summarize(results []result, elapsed time.Duration) summary {
+	s := summary{requests: len(results), byOp: map[operation]int{}}
+	if len(results) == 0 {
+		return s
+	}
+
+	durations := make([]time.Duration, len(results))
+	for i, res := range results {
+		durations[i] = res.duration
+		s.byOp[res.op]++
+		if res.err != nil {
+			s.errors++
+		}
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	s.p50 = percentile(durations, 50)
+	s.p95 = percentile(durations, 95)
+	s.p99 = percentile(durations, 99)
+	if elapsed > 0 {
+		s.throughput = float64(len(results)) / elapsed.Seconds()
+	}
+	return s
+}
+
+// percentile uses the nearest-rank method on an already sorted slice.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func (s summary) errorRate() float64 {
+	if s.requests == 0 {
+		return 0
+	}
+	return float64(s.errors) / float64(s.requests) * 100
+}
+
+func (s summary) print(w io.Writer) {
+	fmt.Fprintf(w, "requests:   %d (get %d, list %d, create %d)\n", s.requests, s.byOp[opGet], s.byOp[opList], s.byOp[opCreate])
+	fmt.Fprintf(w, "errors:     %d (%.2f%%)\n", s.errors, s.errorRate())
+	fmt.Fprintf(w, "throughput: %.1f req/s\n", s.throughput)
+	fmt.Fprintf(w, "latency:    p50 %s  p95 %s  p99 %s\n", s.p50, s.p95, s.p99)
+}
+
+func writeCSV(path string, results []result) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"start", "operation", "duration_ms", "status", "error"})
+	for _, res := range results {
+		errText := ""
+		if res.err != nil {
+			errText = res.err.Error()
+		}
+		w.Write([]string{
+			res.start.UTC().Format(time.RFC3339Nano),
+			string(res.op),
+			strconv.FormatFloat(float64(res.duration)/float64(time.Millisecond), 'f', 3, 64),
+			strconv.Itoa(res.status),
+			errText,
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return f.Close()
+}
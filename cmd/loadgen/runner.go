@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type operation string
+
+const (
+	opGet    operation = "get"
+	opList   operation = "list"
+	opCreate operation = "create"
+)
+
+type weightedOp struct {
+	op     operation
+	weight int
+}
+
+type result struct {
+	op       operation
+	start    time.Time
+	duration time.Duration
+	status   int
+	err      error
+}
+
+func parseMix(spec string) ([]weightedOp, error) {
+	var mix []weightedOp
+	seen := map[operation]bool{}
+	for _, part := range strings.Split(spec, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid mix entry %q, want op=weight", part)
+		}
+
+		op := operation(name)
+		switch op {
+		case opGet, opList, opCreate:
+		default:
+			return nil, fmt.Errorf("unknown operation %q in mix", name)
+		}
+		if seen[op] {
+			return nil, fmt.Errorf("operation %q listed twice in mix", name)
+		}
+		seen[op] = true
+
+		weight, err := strconv.Atoi(value)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid weight %q for %s", value, name)
+		}
+		if weight > 0 {
+			mix = append(mix, weightedOp{op: op, weight: weight})
+		}
+	}
+	if len(mix) == 0 {
+		return nil, fmt.Errorf("mix %q has no positive weights", spec)
+	}
+	return mix, nil
+}
+
+func pick(mix []weightedOp, n int) operation {
+	for _, w := range mix {
+		if n < w.weight {
+			return w.op
+		}
+		n -= w.weight
+	}
+	return mix[len(mix)-1].op
+}
+
+type runner struct {
+	client  *client
+	mix     []weightedOp
+	total   int
+	workers int
+
+	mu  sync.Mutex
+	ids []int32
+}
+
+func newRunner(c *client, mix []weightedOp, workers int) *runner {
+	total := 0
+	for _, w := range mix {
+		total += w.weight
+	}
+	return &runner{client: c, mix: mix, total: total, workers: workers}
+}
+
+func (r *runner) seed(ctx context.Context, n int) error {
+	for i := 0; i < n; i++ {
+		user, _, err := r.client.createUser(ctx, fmt.Sprintf("loadgen seed %d", i), randomDOB())
+		if err != nil {
+			return err
+		}
+		r.track(user.ID)
+	}
+	return nil
+}
+
+func (r *runner) track(id int32) {
+	r.mu.Lock()
+	r.ids = append(r.ids, id)
+	r.mu.Unlock()
+}
+
+func (r *runner) randomID() (int32, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.ids) == 0 {
+		return 0, false
+	}
+	return r.ids[rand.IntN(len(r.ids))], true
+}
+
+// run stops issuing requests at the deadline but lets in-flight ones finish,
+// so a create that reached the server is always tracked for cleanup. Only an
+// interrupt of ctx aborts requests mid-flight.
+func (r *runner) run(ctx context.Context, duration time.Duration) ([]result, time.Duration) {
+	start := time.Now()
+	deadline := start.Add(duration)
+
+	out := make(chan []result, r.workers)
+	for w := 0; w < r.workers; w++ {
+		go func() {
+			var local []result
+			for ctx.Err() == nil && time.Now().Before(deadline) {
+				res := r.fire(ctx)
+				if ctx.Err() != nil && res.err != nil {
+					break
+				}
+				local = append(local, res)
+			}
+			out <- local
+		}()
+	}
+
+	var results []result
+	for w := 0; w < r.workers; w++ {
+		results = append(results, <-out...)
+	}
+	elapsed := time.Since(start)
+	sort.Slice(results, func(i, j int) bool { return results[i].start.Before(results[j].start) })
+	return results, elapsed
+}
+
+func (r *runner) fire(ctx context.Context) result {
+	op := pick(r.mix, rand.IntN(r.total))
+	res := result{op: op, start: time.Now()}
+
+	switch op {
+	case opGet:
+		id, ok := r.randomID()
+		if !ok {
+			res.op = opList
+			res.status, res.err = r.client.do(ctx, http.MethodGet, "/api/v1/users?page=1&page_size=10", nil, nil)
+			break
+		}
+		res.status, res.err = r.client.do(ctx, http.MethodGet, fmt.Sprintf("/api/v1/users/%d", id), nil, nil)
+	case opList:
+		page := rand.IntN(5) + 1
+		res.status, res.err = r.client.do(ctx, http.MethodGet, fmt.Sprintf("/api/v1/users?page=%d&page_size=10", page), nil, nil)
+	case opCreate:
+		user, status, err := r.client.createUser(ctx, "loadgen user", randomDOB())
+		res.status, res.err = status, err
+		if err == nil {
+			r.track(user.ID)
+		}
+	}
+
+	res.duration = time.Since(res.start)
+	return res
+}
+
+// cleanup deletes every user the run created. The API has no bulk delete
+// yet, so ids are removed one request at a time across the worker pool.
+func (r *runner) cleanup(ctx context.Context, stdout io.Writer) {
+	r.mu.Lock()
+	ids := r.ids
+	r.ids = nil
+	r.mu.Unlock()
+	if len(ids) == 0 {
+		return
+	}
+
+	jobs := make(chan int32)
+	var failed int
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for w := 0; w < r.workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range jobs {
+				if status, err := r.client.deleteUser(ctx, id); err != nil && status != http.StatusNotFound {
+					mu.Lock()
+					failed++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, id := range ids {
+		jobs <- id
+	}
+	close(jobs)
+	wg.Wait()
+
+	fmt.Fprintf(stdout, "cleaned up %d users (%d failed)\n", len(ids)-failed, failed)
+}
+
+func randomDOB() string {
+	start := time.Date(1940, 1, 1, 0, 0, 0, 0, time.UTC)
+	return start.AddDate(0, 0, rand.IntN(365*80)).Format("2006-01-02")
+}
@@ -2,80 +2,149 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
 	"time"
+	// Users' timezones must resolve in images without a zone database.
+	_ "time/tzdata"
 
-	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/recover"
-	_ "github.com/lib/pq"
 	"github.com/srinivasarynh/age_calculator/config"
-	"github.com/srinivasarynh/age_calculator/internal/handler"
+	"github.com/srinivasarynh/age_calculator/internal/buildinfo"
 	"github.com/srinivasarynh/age_calculator/internal/logger"
-	"github.com/srinivasarynh/age_calculator/internal/middleware"
-	"github.com/srinivasarynh/age_calculator/internal/repository"
-	"github.com/srinivasarynh/age_calculator/internal/routes"
-	"github.com/srinivasarynh/age_calculator/internal/service"
+	"github.com/srinivasarynh/age_calculator/internal/metrics"
+	"github.com/srinivasarynh/age_calculator/internal/server"
 	"go.uber.org/zap"
 )
 
 func main() {
-	zapLogger := logger.NewLogger()
+	zapLogger, level := logger.NewLogger()
 	defer zapLogger.Sync()
 
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		zapLogger.Fatal("Failed to load config", zap.Error(err))
 	}
+	if err := logger.SetLevel(level, cfg.LogLevel); err != nil {
+		zapLogger.Fatal("Invalid log level", zap.Error(err))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(migrateMain(cfg, zapLogger, os.Args[2:]))
+	}
+	runtimeCfg := config.NewHolder(cfg)
+
+	info := buildinfo.Get()
+	zapLogger.Info("Starting User API",
+		zap.String("version", info.Version),
+		zap.String("commit", info.Commit),
+		zap.String("build_date", info.BuildDate),
+		zap.String("go_version", info.GoVersion),
+	)
+	registry := metrics.NewRegistry()
 
-	db, err := config.NewDatabase(cfg)
+	db, err := config.OpenDatabase(cfg)
 	if err != nil {
-		zapLogger.Fatal("Failed to connect to database", zap.Error(err))
+		zapLogger.Fatal("Failed to open database", zap.Error(err))
 	}
 	defer db.Close()
 
-	zapLogger.Info("Database connection extablished")
-
-	userRepo := repository.NewUserRepository(db, zapLogger)
-	userService := service.NewUserService(userRepo, zapLogger)
-	userHandler := handler.NewUserHandler(userService, zapLogger)
-
-	app := fiber.New(fiber.Config{
-		ErrorHandler: middleware.ErrorHandler,
-		AppName:      "User API v1.0",
-	})
-
-	app.Use(cors.New())
-	app.Use(recover.New())
-	app.Use(middleware.RequestID())
-	app.Use(middleware.Logger(zapLogger))
+	stopSampling := metrics.NewDBPool(registry).Sample(db, 15*time.Second)
+	defer stopSampling()
 
-	routes.SetupRoutes(app, userHandler)
-	app.Get("/health", func(c *fiber.Ctx) error {
-		return c.JSON(fiber.Map{
-			"status": "ok",
-			"time":   time.Now(),
+	// The server listens while the database comes up so the liveness probe
+	// passes; /ready stays 503 until the first successful ping.
+	var ready atomic.Bool
+	app, jobRunner, dispatcher, tracker, warmup := server.Build(runtimeCfg, db, registry, zapLogger, ready.Load)
+	notifier := server.NewBirthdayNotifier(runtimeCfg, db, registry, zapLogger)
+	go func() {
+		err := config.WaitForDatabase(context.Background(), db, cfg.DBConnectTimeout, func(attempt int, err error, wait time.Duration) {
+			zapLogger.Warn("Database not reachable, retrying",
+				zap.Int("attempt", attempt),
+				zap.Duration("retry_in", wait),
+				zap.Error(err),
+			)
 		})
-	})
+		if err != nil {
+			zapLogger.Fatal("Failed to connect to database", zap.Error(err))
+		}
+		zapLogger.Info("Database connection established")
+		if cfg.SchemaCheck {
+			if err := checkSchema(context.Background(), db); err != nil {
+				zapLogger.Fatal("Database schema check failed", zap.Error(err))
+			}
+		}
+		warmup(context.Background())
+		ready.Store(true)
+		if err := jobRunner.Start(context.Background()); err != nil {
+			zapLogger.Error("Failed to start job workers", zap.Error(err))
+		}
+		if notifier != nil {
+			if err := notifier.Start(context.Background()); err != nil {
+				zapLogger.Error("Failed to start birthday notifier", zap.Error(err))
+			}
+		}
+		if dispatcher != nil {
+			if err := dispatcher.Start(context.Background()); err != nil {
+				zapLogger.Error("Failed to start outbox dispatcher", zap.Error(err))
+			}
+		}
+		if tracker != nil {
+			if err := tracker.Start(context.Background()); err != nil {
+				zapLogger.Error("Failed to start access tracker", zap.Error(err))
+			}
+		}
+	}()
+
+	stopReload := server.ReloadOnSIGHUP(runtimeCfg, level, zapLogger)
+	defer stopReload()
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 
+	stopped := make(chan struct{})
 	go func() {
 		<-quit
 		zapLogger.Info("Shutting down server...")
 		if err := app.ShutdownWithContext(context.Background()); err != nil {
 			zapLogger.Fatal("Server forced to shutdown", zap.Error(err))
 		}
+		// A running import finishes the batch it is writing and is recorded
+		// as failed; pending jobs wait for the next start.
+		if err := jobRunner.Stop(context.Background()); err != nil {
+			zapLogger.Error("Failed to stop job workers", zap.Error(err))
+		}
+		if notifier != nil {
+			if err := notifier.Stop(context.Background()); err != nil {
+				zapLogger.Error("Failed to stop birthday notifier", zap.Error(err))
+			}
+		}
+		// An event being delivered is claimed again by the next dispatcher
+		// once its lease runs out.
+		if dispatcher != nil {
+			if err := dispatcher.Stop(context.Background()); err != nil {
+				zapLogger.Error("Failed to stop outbox dispatcher", zap.Error(err))
+			}
+		}
+		// The app is down, so no read comes in after the last write.
+		if tracker != nil {
+			if err := tracker.Stop(context.Background()); err != nil {
+				zapLogger.Error("Failed to write access counts", zap.Error(err))
+			}
+		}
+		close(stopped)
 	}()
 
-	addr := fmt.Sprintf(":%s", cfg.ServerPort)
-	zapLogger.Info("Server starting", zap.String("address", addr))
-	if err := app.Listen(addr); err != nil {
+	listeners, err := server.Listen(app, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, ln := range listeners {
+		zapLogger.Info("Server starting", zap.String("address", ln.Addr().String()))
+	}
+	if err := server.Serve(app, listeners); err != nil {
 		log.Fatal(err)
 	}
+	<-stopped
 }
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/config"
+	"github.com/srinivasarynh/age_calculator/db"
+	"github.com/srinivasarynh/age_calculator/internal/migrate"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"go.uber.org/zap"
+)
+
+const migrateUsage = `Usage: server migrate <command>
+
+Commands:
+  up               apply every pending migration
+  down <n>         revert the n most recently applied migrations
+  status           list migrations, whether each is applied, and its checksum
+  force <version>  record the schema as exactly version without running SQL;
+                   use it after fixing a dirty migration by hand (0 forgets all)
+
+up also creates the case-insensitive unique index on user names when
+UNIQUE_NAMES is true, and drops it when false.
+
+The database settings are the server's usual DB_* variables.
+`
+
+type migrator interface {
+	Up(ctx context.Context) ([]int64, error)
+	Down(ctx context.Context, n int) ([]int64, error)
+	Status(ctx context.Context) ([]migrate.Status, error)
+	Force(ctx context.Context, version int64) error
+}
+
+var errUsage = errors.New("usage")
+
+// migrateMain runs one migrate subcommand and returns the exit code: 0 on
+// success, 1 on failure, 2 for bad arguments. It never starts the HTTP server.
+func migrateMain(cfg *config.Config, logger *zap.Logger, args []string) int {
+	cmd, err := parseMigrateArgs(args)
+	if err != nil {
+		if err != errUsage {
+			fmt.Fprintf(os.Stderr, "%v\n\n", err)
+		}
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	migrations, err := migrate.Load(db.Migrations, "migrations")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	database, err := config.OpenDatabase(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	err = config.WaitForDatabase(ctx, database, cfg.DBConnectTimeout, func(attempt int, err error, wait time.Duration) {
+		logger.Warn("Database not reachable, retrying", zap.Int("attempt", attempt), zap.Duration("retry_in", wait), zap.Error(err))
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+		return 1
+	}
+
+	if err := runMigrate(ctx, migrate.New(database, migrations, repository.UniqueNamesStep(cfg.UniqueNames)), cmd, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+type migrateCommand struct {
+	name    string
+	count   int
+	version int64
+}
+
+// parseMigrateArgs checks the arguments before anything connects, so a typo
+// fails at once instead of after waiting for the database.
+func parseMigrateArgs(args []string) (migrateCommand, error) {
+	if len(args) == 0 {
+		return migrateCommand{}, errUsage
+	}
+	cmd := migrateCommand{name: args[0]}
+	rest := args[1:]
+
+	switch {
+	case (cmd.name == "up" || cmd.name == "status") && len(rest) == 0:
+		return cmd, nil
+	case cmd.name == "down" && len(rest) == 1:
+		n, err := strconv.Atoi(rest[0])
+		if err != nil || n <= 0 {
+			return cmd, fmt.Errorf("%w: down needs a positive count, got %q", errUsage, rest[0])
+		}
+		cmd.count = n
+		return cmd, nil
+	case cmd.name == "force" && len(rest) == 1:
+		version, err := strconv.ParseInt(rest[0], 10, 64)
+		if err != nil || version < 0 {
+			return cmd, fmt.Errorf("%w: force needs a version, got %q", errUsage, rest[0])
+		}
+		cmd.version = version
+		return cmd, nil
+	}
+	return cmd, errUsage
+}
+
+// runMigrate prints what it did to stdout. status fails when any migration is
+// dirty, edited or unknown, so it can gate a deploy.
+func runMigrate(ctx context.Context, m migrator, cmd migrateCommand, stdout io.Writer) error {
+	switch cmd.name {
+	case "up":
+		applied, err := m.Up(ctx)
+		for _, version := range applied {
+			fmt.Fprintf(stdout, "applied %d\n", version)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Fprintln(stdout, "no pending migrations")
+		}
+		return err
+	case "down":
+		reverted, err := m.Down(ctx, cmd.count)
+		for _, version := range reverted {
+			fmt.Fprintf(stdout, "reverted %d\n", version)
+		}
+		return err
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		return printStatus(stdout, statuses)
+	case "force":
+		if err := m.Force(ctx, cmd.version); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "forced version %d\n", cmd.version)
+		return nil
+	}
+	return fmt.Errorf("unknown migrate command %q", cmd.name)
+}
+
+func printStatus(stdout io.Writer, statuses []migrate.Status) error {
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tCHECKSUM\tAPPLIED AT")
+
+	var problems int
+	for _, s := range statuses {
+		state, checksum, appliedAt := "pending", short(s.Checksum), "-"
+		if s.Applied {
+			state, appliedAt = "applied", s.AppliedAt.UTC().Format("2006-01-02 15:04:05")
+		}
+		switch {
+		case s.Dirty:
+			state = "dirty"
+			problems++
+		case s.Missing:
+			state, checksum = "no file", short(s.Recorded)
+			problems++
+		case s.Mismatch():
+			state, checksum = "changed", short(s.Recorded)+" -> "+short(s.Checksum)
+			problems++
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", s.Version, s.Name, state, checksum, appliedAt)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if problems > 0 {
+		return fmt.Errorf("%d migration(s) need attention", problems)
+	}
+	return nil
+}
+
+func short(checksum string) string {
+	if len(checksum) > 12 {
+		return checksum[:12]
+	}
+	return checksum
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/migrate"
+)
+
+func TestParseMigrateArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		want migrateCommand
+		ok   bool
+	}{
+		{args: []string{"up"}, want: migrateCommand{name: "up"}, ok: true},
+		{args: []string{"status"}, want: migrateCommand{name: "status"}, ok: true},
+		{args: []string{"down", "2"}, want: migrateCommand{name: "down", count: 2}, ok: true},
+		{args: []string{"force", "0"}, want: migrateCommand{name: "force"}, ok: true},
+		{args: []string{"force", "3"}, want: migrateCommand{name: "force", version: 3}, ok: true},
+		{args: nil},
+		{args: []string{"down"}},
+		{args: []string{"down", "0"}},
+		{args: []string{"down", "all"}},
+		{args: []string{"force", "-1"}},
+		{args: []string{"up", "extra"}},
+		{args: []string{"sideways"}},
+	}
+	for _, tt := range tests {
+		got, err := parseMigrateArgs(tt.args)
+		if tt.ok {
+			if err != nil || got != tt.want {
+				t.Errorf("parseMigrateArgs(%q) = %+v, %v; want %+v", tt.args, got, err, tt.want)
+			}
+		} else if !errors.Is(err, errUsage) {
+			t.Errorf("parseMigrateArgs(%q) error = %v, want a usage error", tt.args, err)
+		}
+	}
+}
+
+type fakeMigrator struct {
+	statuses []migrate.Status
+	applied  []int64
+	upErr    error
+	forced   int64
+}
+
+func (f *fakeMigrator) Up(ctx context.Context) ([]int64, error) { return f.applied, f.upErr }
+func (f *fakeMigrator) Down(ctx context.Context, n int) ([]int64, error) {
+	return []int64{4, 3}[:n], nil
+}
+func (f *fakeMigrator) Status(ctx context.Context) ([]migrate.Status, error) {
+	return f.statuses, nil
+}
+func (f *fakeMigrator) Force(ctx context.Context, version int64) error {
+	f.forced = version
+	return nil
+}
+
+func TestMigrateStatus(t *testing.T) {
+	appliedAt := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	m := &fakeMigrator{statuses: []migrate.Status{
+		{Version: 1, Name: "create_users", Checksum: "aaaaaaaaaaaaaaaa", Recorded: "aaaaaaaaaaaaaaaa", Applied: true, AppliedAt: appliedAt},
+		{Version: 2, Name: "create_users", Checksum: "bbbbbbbbbbbbbbbb", Recorded: "cccccccccccccccc", Applied: true, AppliedAt: appliedAt},
+		{Version: 3, Name: "users_dob_date", Checksum: "dddddddddddddddd"},
+	}}
+
+	var out bytes.Buffer
+	err := runMigrate(context.Background(), m, migrateCommand{name: "status"}, &out)
+	if err == nil || !strings.Contains(err.Error(), "1 migration(s) need attention") {
+		t.Errorf("err = %v, want the changed migration reported", err)
+	}
+
+	want := `VERSION  NAME            STATE    CHECKSUM                      APPLIED AT
+1        create_users    applied  aaaaaaaaaaaa                  2025-06-15 12:00:00
+2        create_users    changed  cccccccccccc -> bbbbbbbbbbbb  2025-06-15 12:00:00
+3        users_dob_date  pending  dddddddddddd                  -
+`
+	if out.String() != want {
+		t.Errorf("status output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestMigrateUpDownForce(t *testing.T) {
+	m := &fakeMigrator{applied: []int64{3, 4}}
+	var out bytes.Buffer
+	ctx := context.Background()
+
+	if err := runMigrate(ctx, m, migrateCommand{name: "up"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if err := runMigrate(ctx, m, migrateCommand{name: "down", count: 2}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if err := runMigrate(ctx, m, migrateCommand{name: "force", version: 2}, &out); err != nil || m.forced != 2 {
+		t.Fatalf("force: err = %v, forced = %d", err, m.forced)
+	}
+	want := "applied 3\napplied 4\nreverted 4\nreverted 3\nforced version 2\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+
+	m = &fakeMigrator{upErr: migrate.ErrDirty}
+	out.Reset()
+	if err := runMigrate(ctx, m, migrateCommand{name: "up"}, &out); !errors.Is(err, migrate.ErrDirty) || out.Len() != 0 {
+		t.Errorf("up on a dirty database: err = %v, output %q", err, out.String())
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/srinivasarynh/age_calculator/config"
+	"github.com/srinivasarynh/age_calculator/db"
+	"github.com/srinivasarynh/age_calculator/internal/migrate"
+)
+
+// checkSchema verifies database against what the embedded migrations build,
+// up to the last of them. It lives apart from main, whose db variable shadows
+// the package.
+func checkSchema(ctx context.Context, database *sql.DB) error {
+	migrations, err := migrate.Load(db.Migrations, "migrations")
+	if err != nil {
+		return err
+	}
+	req := db.SchemaRequirements()
+	if len(migrations) > 0 {
+		req.Version = migrations[len(migrations)-1].Version
+	}
+	return config.VerifySchema(ctx, database, req)
+}
@@ -1,62 +1,509 @@
 package config
 
 import (
-	"database/sql"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
 
-	_ "github.com/lib/pq"
+	"go.uber.org/zap/zapcore"
 )
 
 type Config struct {
-	DBHost     string
-	DBPort     string
-	DBUser     string
-	DBPassword string
-	DBName     string
-	ServerPort string
+	DBHost     string `env:"DB_HOST" default:"localhost"`
+	DBPort     string `env:"DB_PORT" default:"5432"`
+	DBUser     string `env:"DB_USER" default:"postgres"`
+	DBPassword string `env:"DB_PASSWORD" default:"postgres" secret:"true"`
+	DBName     string `env:"DB_NAME" default:"userdb"`
+	ServerPort string `env:"SERVER_PORT" default:"8080"`
+
+	// DBConnectTimeout bounds how long startup keeps retrying an unreachable
+	// database; 0 tries once.
+	DBConnectTimeout time.Duration `env:"DB_CONNECT_TIMEOUT" default:"60s"`
+	// SchemaCheck makes startup exit when the database lacks a table, column,
+	// extension or privilege the server needs, rather than failing requests.
+	SchemaCheck bool `env:"SCHEMA_CHECK" default:"true"`
+	// WarmupTimeout bounds the warm-up run once the database is reachable,
+	// before the server reports ready; 0 skips it. DBPreparedStatements lets
+	// the warm-up prepare the hottest queries. Turn it off behind a pooler
+	// that does not keep sessions, such as pgbouncer in transaction mode.
+	WarmupTimeout        time.Duration `env:"WARMUP_TIMEOUT" default:"0s"`
+	DBPreparedStatements bool          `env:"DB_PREPARED_STATEMENTS" default:"true"`
+
+	ServerReadTimeout        time.Duration `env:"SERVER_READ_TIMEOUT" default:"10s"`
+	ServerWriteTimeout       time.Duration `env:"SERVER_WRITE_TIMEOUT" default:"10s"`
+	ServerIdleTimeout        time.Duration `env:"SERVER_IDLE_TIMEOUT" default:"60s"`
+	ServerStreamWriteTimeout time.Duration `env:"SERVER_STREAM_WRITE_TIMEOUT" default:"10m"`
+	ServerMaxHeaderSize      int           `env:"SERVER_MAX_HEADER_SIZE" default:"8192"`
+
+	// ListenSocket, when set, is the path of a unix socket served alongside
+	// SERVER_PORT, or alone with ListenTCP off. ListenSocketMode is its octal
+	// permissions. Peers on it reach admin routes only with ADMIN_TOKEN set.
+	ListenSocket     string `env:"LISTEN_SOCKET"`
+	ListenSocketMode string `env:"LISTEN_SOCKET_MODE" default:"0660"`
+	ListenTCP        bool   `env:"LISTEN_TCP" default:"true"`
+
+	LogLevel             string        `env:"LOG_LEVEL" reload:"true"`
+	SlowRequestThreshold time.Duration `env:"SLOW_REQUEST_THRESHOLD" default:"1s" reload:"true"`
+	RequestTimeout       time.Duration `env:"REQUEST_TIMEOUT" default:"5s" reload:"true"`
+
+	// UserCacheSize of 0 disables the in-process GetUser cache. RedisURL, when
+	// set, replaces it with a cache shared by every instance.
+	UserCacheSize int           `env:"USER_CACHE_SIZE" default:"0"`
+	UserCacheTTL  time.Duration `env:"USER_CACHE_TTL" default:"1m"`
+	RedisURL      string        `env:"REDIS_URL" secret:"true"`
+
+	// SingleFlightReads lets concurrent reads of the same user share one
+	// query, run for at most SingleFlightTimeout whichever caller started it.
+	SingleFlightReads   bool          `env:"SINGLE_FLIGHT_READS" default:"false"`
+	SingleFlightTimeout time.Duration `env:"SINGLE_FLIGHT_TIMEOUT" default:"5s"`
+
+	// AggregateCacheTTL is how old a cohort or birthday collision result may
+	// be before a request refreshes it in the background, still being served
+	// the old one; 0 computes them on every request.
+	AggregateCacheTTL time.Duration `env:"AGGREGATE_CACHE_TTL" default:"0s"`
+
+	// Cache-Control values per route group. Error responses always get no-store.
+	CacheControlUser    string `env:"CACHE_CONTROL_USER" default:"private, max-age=30"`
+	CacheControlList    string `env:"CACHE_CONTROL_LIST" default:"no-cache"`
+	CacheControlNoStore string `env:"CACHE_CONTROL_NO_STORE" default:"no-store"`
+	CacheControlStatic  string `env:"CACHE_CONTROL_STATIC" default:"public, max-age=86400"`
+
+	// NameFuzzyThreshold is the trigram similarity, from 0 to 1, a name needs
+	// to match the list's name_fuzzy parameter.
+	NameFuzzyThreshold float64 `env:"NAME_FUZZY_THRESHOLD" default:"0.3"`
+
+	// DefaultTimezone is the IANA zone whose calendar decides the age and
+	// birthday of users who have no timezone of their own.
+	DefaultTimezone string `env:"DEFAULT_TIMEZONE" default:"UTC"`
+
+	// UniqueNames makes names unique per tenant, ignoring case, and enables
+	// lookup by name. migrate up creates or drops the index that enforces it.
+	UniqueNames bool `env:"UNIQUE_NAMES" default:"false"`
+
+	// NormalizeNames trims names, collapses their whitespace, strips control
+	// and zero-width characters and composes them to NFC before they are
+	// validated, stored or looked up. Existing rows are not rewritten.
+	NormalizeNames bool `env:"NORMALIZE_NAMES" default:"true"`
+
+	// NamePolicy restricts the characters of names written: any,
+	// letters_spaces, or letters_punct, which also allows hyphens and
+	// apostrophes.
+	NamePolicy string `env:"NAME_POLICY" default:"any"`
+
+	// MinDOBYear rejects dates of birth written before Jan 1 of this year,
+	// such as the 0001-01-01 of an unset date elsewhere.
+	MinDOBYear int `env:"MIN_DOB_YEAR" default:"1900"`
+
+	// MaxUsers caps the users each tenant may hold; creates and imports
+	// past it are refused. 0 is no cap.
+	MaxUsers int `env:"MAX_USERS" default:"0"`
+
+	// DedupeWindow makes a create return the user created within it with
+	// the same name and DOB, rather than a second one. 0 always creates.
+	DedupeWindow time.Duration `env:"DEDUPE_WINDOW" default:"0s"`
+
+	// ListHidesDOB leaves dob out of user lists and exports, which still give
+	// the age; fetching a user by id still returns it.
+	ListHidesDOB bool `env:"LIST_HIDES_DOB" default:"false"`
+
+	// InfantAgeYears adds age_months and age_weeks to the users younger than
+	// it. 0 adds them only when a request asks.
+	InfantAgeYears int `env:"INFANT_AGE_YEARS" default:"2"`
+
+	// CohortBounds are the ages GET /users/stats/cohorts splits users at when
+	// the request names none.
+	CohortBounds []int `env:"COHORT_BOUNDS" default:"18,30,45,65"`
+
+	// ShareTTL is how long a link from POST /users/:id/share lasts.
+	ShareTTL time.Duration `env:"SHARE_TTL" default:"168h"`
+
+	// MaxOffset bounds page × page_size on list requests, which the database
+	// can only answer by reading every row before the page. Cursors are not
+	// bounded. 0 allows any page.
+	MaxOffset int `env:"MAX_OFFSET" default:"10000"`
+
+	// StrictJSON rejects request bodies carrying fields the API does not know.
+	StrictJSON bool `env:"STRICT_JSON" default:"true"`
+
+	// StrictStatusCodes answers 422 rather than 400 for requests that parse
+	// but break a business rule, such as min_age above max_age. False keeps
+	// every validation failure at 400 for older clients.
+	StrictStatusCodes bool `env:"STRICT_STATUS_CODES" default:"true"`
+
+	// ResponseEnvelope wraps every JSON response but GraphQL's, successes in
+	// {"data": ..., "meta": {"request_id", "timestamp"}} and errors in the
+	// structured {"error": {...}} of /api/v2.
+	ResponseEnvelope bool `env:"RESPONSE_ENVELOPE" default:"false"`
+
+	// Tenants lists the X-Tenant-ID values the API accepts. Empty runs a
+	// single-tenant deployment where every request is the default tenant.
+	Tenants []string `env:"TENANTS" reload:"true"`
+
+	// JobWorkers is how many background jobs this instance runs at once; 0
+	// leaves them to other instances. A running job that has not reported
+	// progress for JobStaleAfter is presumed lost and failed.
+	JobWorkers    int           `env:"JOB_WORKERS" default:"2"`
+	JobStaleAfter time.Duration `env:"JOB_STALE_AFTER" default:"2m"`
+
+	// ExportDir holds the files of finished export jobs, each deleted
+	// ExportRetention after it was written. Empty uses a directory under the
+	// system temporary directory.
+	ExportDir       string        `env:"EXPORT_DIR"`
+	ExportRetention time.Duration `env:"EXPORT_RETENTION" default:"24h"`
+	// XLSXMaxRows is the most rows GET /users/export serves as xlsx; larger
+	// spreadsheets must be exported as a job.
+	XLSXMaxRows int `env:"XLSX_MAX_ROWS" default:"100000"`
+
+	// BirthdayNotifications runs the birthday scheduler: every
+	// BirthdayCheckInterval it posts a user.birthday event to
+	// BirthdayWebhookURL for each user whose birthday has begun in their
+	// timezone and who has not been notified this year.
+	BirthdayNotifications bool          `env:"BIRTHDAY_NOTIFICATIONS" default:"false"`
+	BirthdayCheckInterval time.Duration `env:"BIRTHDAY_CHECK_INTERVAL" default:"1h"`
+	BirthdayWebhookURL    string        `env:"BIRTHDAY_WEBHOOK_URL" secret:"true"`
+
+	// UserEventsWebhookURL receives the user events the API publishes, such
+	// as user.anonymized. Empty sends none.
+	UserEventsWebhookURL string `env:"USER_EVENTS_WEBHOOK_URL" secret:"true"`
+	// User events are stored in the events outbox with the write they report
+	// and delivered from there every OutboxPollInterval. An event that fails
+	// OutboxMaxAttempts deliveries is dead-lettered; delivered events are
+	// deleted after OutboxRetention, or kept with 0.
+	OutboxPollInterval time.Duration `env:"OUTBOX_POLL_INTERVAL" default:"1s"`
+	OutboxMaxAttempts  int           `env:"OUTBOX_MAX_ATTEMPTS" default:"10"`
+	OutboxRetention    time.Duration `env:"OUTBOX_RETENTION" default:"168h"`
+
+	// AccessTracking counts every GetUser per user, for include=access_count.
+	// Reads are queued in a buffer of AccessBufferSize, dropped when it is
+	// full, and written every AccessFlushInterval.
+	AccessTracking      bool          `env:"ACCESS_TRACKING" default:"false"`
+	AccessFlushInterval time.Duration `env:"ACCESS_FLUSH_INTERVAL" default:"5s"`
+	AccessBufferSize    int           `env:"ACCESS_BUFFER_SIZE" default:"1024"`
+
+	AdminAllowedIPs []string `env:"ADMIN_ALLOWED_IPS" default:"127.0.0.1,::1"`
+	AdminToken      string   `env:"ADMIN_TOKEN" secret:"true"`
+
+	sources map[string]Source
 }
 
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceEnv     Source = "env"
+	SourceFile    Source = "file"
+)
+
 func LoadConfig() (*Config, error) {
-	cfg := &Config{
-		DBHost:     getEnv("DB_HOST", "localhost"),
-		DBPort:     getEnv("DB_PORT", "5432"),
-		DBUser:     getEnv("DB_USER", "postgres"),
-		DBPassword: getEnv("DB_PASSWORD", "postgres"),
-		DBName:     getEnv("DB_NAME", "userdb"),
-		ServerPort: getEnv("SERVER_PORT", "8080"),
+	cfg := &Config{sources: make(map[string]Source)}
+
+	v := reflect.ValueOf(cfg).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := field.Tag.Get("env")
+		if key == "" {
+			continue
+		}
+
+		value, source, err := lookupValue(key, field.Tag.Get("secret") == "true")
+		if err != nil {
+			return nil, err
+		}
+		if value == "" {
+			value = field.Tag.Get("default")
+			source = SourceDefault
+		}
+		cfg.sources[key] = source
+
+		if err := setField(v.Field(i), value); err != nil {
+			return nil, fmt.Errorf("config: invalid %s: %w", key, err)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
 	}
 
 	return cfg, nil
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+func (c *Config) Validate() error {
+	timeouts := []struct {
+		key   string
+		value time.Duration
+	}{
+		{"SERVER_READ_TIMEOUT", c.ServerReadTimeout},
+		{"SERVER_WRITE_TIMEOUT", c.ServerWriteTimeout},
+		{"SERVER_IDLE_TIMEOUT", c.ServerIdleTimeout},
+		{"SERVER_STREAM_WRITE_TIMEOUT", c.ServerStreamWriteTimeout},
+		{"SLOW_REQUEST_THRESHOLD", c.SlowRequestThreshold},
+		{"REQUEST_TIMEOUT", c.RequestTimeout},
+		{"USER_CACHE_TTL", c.UserCacheTTL},
+		{"DB_CONNECT_TIMEOUT", c.DBConnectTimeout},
+		{"WARMUP_TIMEOUT", c.WarmupTimeout},
+		{"AGGREGATE_CACHE_TTL", c.AggregateCacheTTL},
+	}
+	for _, t := range timeouts {
+		if t.value < 0 {
+			return fmt.Errorf("config: %s must not be negative", t.key)
+		}
+	}
+
+	if c.UserCacheSize < 0 {
+		return fmt.Errorf("config: USER_CACHE_SIZE must not be negative")
+	}
+	if c.SingleFlightTimeout <= 0 {
+		return fmt.Errorf("config: SINGLE_FLIGHT_TIMEOUT must be positive")
+	}
+
+	if _, err := time.LoadLocation(c.DefaultTimezone); err != nil || c.DefaultTimezone == "" || c.DefaultTimezone == "Local" {
+		return fmt.Errorf("config: DEFAULT_TIMEZONE must be an IANA timezone name, got %q", c.DefaultTimezone)
+	}
+
+	if c.NameFuzzyThreshold < 0 || c.NameFuzzyThreshold > 1 {
+		return fmt.Errorf("config: NAME_FUZZY_THRESHOLD must be between 0 and 1")
+	}
+
+	switch c.NamePolicy {
+	case "any", "letters_spaces", "letters_punct":
+	default:
+		return fmt.Errorf("config: NAME_POLICY must be any, letters_spaces or letters_punct, got %q", c.NamePolicy)
+	}
+
+	if c.MinDOBYear < 1 || c.MinDOBYear > 9999 {
+		return fmt.Errorf("config: MIN_DOB_YEAR must be between 1 and 9999")
+	}
+	if c.MaxUsers < 0 {
+		return fmt.Errorf("config: MAX_USERS must not be negative")
+	}
+	if c.DedupeWindow < 0 {
+		return fmt.Errorf("config: DEDUPE_WINDOW must not be negative")
+	}
+	if c.InfantAgeYears < 0 {
+		return fmt.Errorf("config: INFANT_AGE_YEARS must not be negative")
+	}
+	if len(c.CohortBounds) > 20 {
+		return fmt.Errorf("config: COHORT_BOUNDS may have at most 20 ages")
 	}
-	return defaultValue
+	for i, bound := range c.CohortBounds {
+		if bound < 1 || bound > 150 || (i > 0 && bound <= c.CohortBounds[i-1]) {
+			return fmt.Errorf("config: COHORT_BOUNDS must be strictly increasing ages from 1 to 150")
+		}
+	}
+	if c.MaxOffset < 0 {
+		return fmt.Errorf("config: MAX_OFFSET must not be negative")
+	}
+	if c.ShareTTL <= 0 {
+		return fmt.Errorf("config: SHARE_TTL must be positive")
+	}
+
+	if c.JobWorkers < 0 {
+		return fmt.Errorf("config: JOB_WORKERS must not be negative")
+	}
+	if c.JobStaleAfter <= 0 {
+		return fmt.Errorf("config: JOB_STALE_AFTER must be positive")
+	}
+	if c.ExportRetention <= 0 {
+		return fmt.Errorf("config: EXPORT_RETENTION must be positive")
+	}
+	if c.XLSXMaxRows < 1 || c.XLSXMaxRows > 1_048_575 {
+		return fmt.Errorf("config: XLSX_MAX_ROWS must be between 1 and 1048575, the rows of a worksheet under its header")
+	}
+
+	if c.BirthdayCheckInterval <= 0 {
+		return fmt.Errorf("config: BIRTHDAY_CHECK_INTERVAL must be positive")
+	}
+	if c.BirthdayNotifications {
+		if u, err := url.Parse(c.BirthdayWebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("config: BIRTHDAY_NOTIFICATIONS needs BIRTHDAY_WEBHOOK_URL to be an http:// or https:// URL")
+		}
+	}
+
+	if c.UserEventsWebhookURL != "" {
+		if u, err := url.Parse(c.UserEventsWebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("config: USER_EVENTS_WEBHOOK_URL must be an http:// or https:// URL")
+		}
+	}
+	if c.OutboxPollInterval <= 0 {
+		return fmt.Errorf("config: OUTBOX_POLL_INTERVAL must be positive")
+	}
+	if c.OutboxMaxAttempts < 1 {
+		return fmt.Errorf("config: OUTBOX_MAX_ATTEMPTS must be at least 1")
+	}
+	if c.OutboxRetention < 0 {
+		return fmt.Errorf("config: OUTBOX_RETENTION must not be negative")
+	}
+	if c.AccessFlushInterval <= 0 {
+		return fmt.Errorf("config: ACCESS_FLUSH_INTERVAL must be positive")
+	}
+	if c.AccessBufferSize < 1 {
+		return fmt.Errorf("config: ACCESS_BUFFER_SIZE must be at least 1")
+	}
+
+	if c.RedisURL != "" {
+		if u, err := url.Parse(c.RedisURL); err != nil || (u.Scheme != "redis" && u.Scheme != "rediss") {
+			return fmt.Errorf("config: REDIS_URL must be a redis:// or rediss:// URL")
+		}
+	}
+
+	if mode, err := strconv.ParseUint(c.ListenSocketMode, 8, 32); err != nil || mode > 0o777 {
+		return fmt.Errorf("config: LISTEN_SOCKET_MODE must be octal permissions such as 0660, got %q", c.ListenSocketMode)
+	}
+	if !c.ListenTCP && c.ListenSocket == "" {
+		return fmt.Errorf("config: LISTEN_TCP=false needs LISTEN_SOCKET, or the server listens nowhere")
+	}
+
+	if c.ServerMaxHeaderSize < 1024 || c.ServerMaxHeaderSize > 1<<20 {
+		return fmt.Errorf("config: SERVER_MAX_HEADER_SIZE must be between 1024 and %d bytes", 1<<20)
+	}
+
+	for _, id := range c.Tenants {
+		if !validTenantID(id) {
+			return fmt.Errorf("config: invalid TENANTS entry %q", id)
+		}
+	}
+
+	for _, entry := range c.AdminAllowedIPs {
+		if _, err := ParseIPOrCIDR(entry); err != nil {
+			return fmt.Errorf("config: invalid ADMIN_ALLOWED_IPS entry %q", entry)
+		}
+	}
+
+	if c.LogLevel != "" {
+		if _, err := zapcore.ParseLevel(c.LogLevel); err != nil {
+			return fmt.Errorf("config: invalid LOG_LEVEL: %w", err)
+		}
+	}
+
+	return nil
 }
 
-func NewDatabase(cfg *Config) (*sql.DB, error) {
-	dsn := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		cfg.DBHost,
-		cfg.DBPort,
-		cfg.DBUser,
-		cfg.DBPassword,
-		cfg.DBName,
-	)
-	db, err := sql.Open("postgres", dsn)
-	if err != nil {
-		return nil, err
+// validTenantID accepts 1 to 64 letters, digits, '-' and '_'.
+func validTenantID(id string) bool {
+	if len(id) == 0 || len(id) > 64 {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
 	}
+	return true
+}
 
-	if err := db.Ping(); err != nil {
-		return nil, err
+func ParseIPOrCIDR(entry string) (*net.IPNet, error) {
+	if strings.Contains(entry, "/") {
+		_, network, err := net.ParseCIDR(entry)
+		return network, err
+	}
+
+	ip := net.ParseIP(entry)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address %q", entry)
+	}
+	bits := 8 * net.IPv6len
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 8*net.IPv4len
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func setField(field reflect.Value, value string) error {
+	if value == "" {
+		return nil
+	}
+
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		switch field.Type().Elem().Kind() {
+		case reflect.String:
+			field.Set(reflect.ValueOf(items))
+		case reflect.Int:
+			numbers := make([]int, len(items))
+			for i, item := range items {
+				n, err := strconv.Atoi(item)
+				if err != nil {
+					return err
+				}
+				numbers[i] = n
+			}
+			field.Set(reflect.ValueOf(numbers))
+		default:
+			return fmt.Errorf("unsupported field type %s", field.Type())
+		}
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
+
+// lookupValue resolves key from the environment. Secret fields may instead be
+// read from the file named by KEY_FILE, which is how Docker and Kubernetes
+// mount secrets.
+func lookupValue(key string, secret bool) (string, Source, error) {
+	value := os.Getenv(key)
+	if !secret {
+		return value, SourceEnv, nil
 	}
 
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
+	fileKey := key + "_FILE"
+	path := os.Getenv(fileKey)
+	if path == "" {
+		return value, SourceEnv, nil
+	}
+	if value != "" {
+		return "", "", fmt.Errorf("config: both %s and %s are set", key, fileKey)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", fmt.Errorf("config: reading %s: %w", fileKey, err)
+	}
 
-	return db, nil
+	content := strings.TrimSuffix(string(data), "\n")
+	content = strings.TrimSuffix(content, "\r")
+	return content, SourceFile, nil
 }
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeSecret(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	if cfg.DBHost != "localhost" || cfg.ServerPort != "8080" || cfg.DBPassword != "postgres" || !cfg.SchemaCheck {
+		t.Errorf("unexpected defaults: %+v", cfg)
+	}
+	if !slices.Equal(cfg.CohortBounds, []int{18, 30, 45, 65}) {
+		t.Errorf("CohortBounds = %v, want 18,30,45,65", cfg.CohortBounds)
+	}
+}
+
+func TestLoadConfigSecretFiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      string
+		file     string
+		expected string
+	}{
+		{name: "plain env var", env: "from-env", expected: "from-env"},
+		{name: "file variant", file: "from-file", expected: "from-file"},
+		{name: "trailing newline trimmed", file: "from-file\n", expected: "from-file"},
+		{name: "trailing CRLF trimmed", file: "from-file\r\n", expected: "from-file"},
+		{name: "only one newline trimmed", file: "from-file\n\n", expected: "from-file\n"},
+		{name: "default when neither set", expected: "postgres"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DB_PASSWORD", tt.env)
+			t.Setenv("DB_PASSWORD_FILE", "")
+			if tt.file != "" {
+				t.Setenv("DB_PASSWORD_FILE", writeSecret(t, tt.file))
+			}
+
+			cfg, err := LoadConfig()
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if cfg.DBPassword != tt.expected {
+				t.Errorf("DBPassword = %q, want %q", cfg.DBPassword, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLoadConfigSecretFileErrors(t *testing.T) {
+	t.Run("both variants set", func(t *testing.T) {
+		t.Setenv("DB_PASSWORD", "from-env")
+		t.Setenv("DB_PASSWORD_FILE", writeSecret(t, "from-file"))
+
+		_, err := LoadConfig()
+		if err == nil {
+			t.Fatal("expected error when both variants are set")
+		}
+		if !strings.Contains(err.Error(), "DB_PASSWORD_FILE") {
+			t.Errorf("error %q does not name the variable", err)
+		}
+		if strings.Contains(err.Error(), "from-env") || strings.Contains(err.Error(), "from-file") {
+			t.Errorf("error %q leaks the secret value", err)
+		}
+	})
+
+	t.Run("unreadable file", func(t *testing.T) {
+		t.Setenv("DB_PASSWORD", "")
+		t.Setenv("DB_PASSWORD_FILE", filepath.Join(t.TempDir(), "missing"))
+
+		_, err := LoadConfig()
+		if err == nil {
+			t.Fatal("expected error for unreadable file")
+		}
+		if !strings.Contains(err.Error(), "DB_PASSWORD_FILE") {
+			t.Errorf("error %q does not name the variable", err)
+		}
+	})
+
+	t.Run("non-secret fields ignore _FILE", func(t *testing.T) {
+		t.Setenv("DB_HOST_FILE", writeSecret(t, "from-file"))
+
+		cfg, err := LoadConfig()
+		if err != nil {
+			t.Fatalf("LoadConfig() error = %v", err)
+		}
+		if cfg.DBHost != "localhost" {
+			t.Errorf("DBHost = %q, want default", cfg.DBHost)
+		}
+	})
+}
+
+func TestLoadConfigServerLimits(t *testing.T) {
+	t.Setenv("SERVER_READ_TIMEOUT", "5s")
+	t.Setenv("SERVER_WRITE_TIMEOUT", "1m")
+	t.Setenv("SERVER_MAX_HEADER_SIZE", "16384")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.ServerReadTimeout != 5*time.Second {
+		t.Errorf("ServerReadTimeout = %v, want 5s", cfg.ServerReadTimeout)
+	}
+	if cfg.ServerWriteTimeout != time.Minute {
+		t.Errorf("ServerWriteTimeout = %v, want 1m", cfg.ServerWriteTimeout)
+	}
+	if cfg.ServerIdleTimeout != 60*time.Second {
+		t.Errorf("ServerIdleTimeout = %v, want default 60s", cfg.ServerIdleTimeout)
+	}
+	if cfg.ServerMaxHeaderSize != 16384 {
+		t.Errorf("ServerMaxHeaderSize = %d, want 16384", cfg.ServerMaxHeaderSize)
+	}
+}
+
+func TestLoadConfigServerLimitErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   string
+		value string
+	}{
+		{name: "unparseable duration", key: "SERVER_READ_TIMEOUT", value: "soon"},
+		{name: "negative duration", key: "SERVER_IDLE_TIMEOUT", value: "-1s"},
+		{name: "unparseable size", key: "SERVER_MAX_HEADER_SIZE", value: "big"},
+		{name: "header size too small", key: "SERVER_MAX_HEADER_SIZE", value: "10"},
+		{name: "socket mode not octal", key: "LISTEN_SOCKET_MODE", value: "rw-rw----"},
+		{name: "socket mode past 0777", key: "LISTEN_SOCKET_MODE", value: "1777"},
+		{name: "no tcp and no socket", key: "LISTEN_TCP", value: "false"},
+		{name: "negative warmup timeout", key: "WARMUP_TIMEOUT", value: "-1s"},
+		{name: "negative cache size", key: "USER_CACHE_SIZE", value: "-1"},
+		{name: "negative cache ttl", key: "USER_CACHE_TTL", value: "-1m"},
+		{name: "zero single flight timeout", key: "SINGLE_FLIGHT_TIMEOUT", value: "0s"},
+		{name: "negative aggregate cache ttl", key: "AGGREGATE_CACHE_TTL", value: "-1m"},
+		{name: "redis url scheme", key: "REDIS_URL", value: "http://cache:6379"},
+		{name: "negative job workers", key: "JOB_WORKERS", value: "-1"},
+		{name: "zero job stale after", key: "JOB_STALE_AFTER", value: "0s"},
+		{name: "zero export retention", key: "EXPORT_RETENTION", value: "0s"},
+		{name: "xlsx max rows past a worksheet", key: "XLSX_MAX_ROWS", value: "1048576"},
+		{name: "zero share ttl", key: "SHARE_TTL", value: "0s"},
+		{name: "fuzzy threshold above one", key: "NAME_FUZZY_THRESHOLD", value: "1.5"},
+		{name: "fuzzy threshold not a number", key: "NAME_FUZZY_THRESHOLD", value: "high"},
+		{name: "unknown name policy", key: "NAME_POLICY", value: "ascii"},
+		{name: "zero min dob year", key: "MIN_DOB_YEAR", value: "0"},
+		{name: "negative max users", key: "MAX_USERS", value: "-1"},
+		{name: "negative dedupe window", key: "DEDUPE_WINDOW", value: "-5s"},
+		{name: "negative max offset", key: "MAX_OFFSET", value: "-1"},
+		{name: "negative infant age", key: "INFANT_AGE_YEARS", value: "-1"},
+		{name: "cohort bound not a number", key: "COHORT_BOUNDS", value: "18,thirty"},
+		{name: "cohort bounds out of order", key: "COHORT_BOUNDS", value: "18,30,30"},
+		{name: "cohort bound too old", key: "COHORT_BOUNDS", value: "18,151"},
+		{name: "cohort bound zero", key: "COHORT_BOUNDS", value: "0,18"},
+		{name: "too many cohort bounds", key: "COHORT_BOUNDS", value: "1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,17,18,19,20,21"},
+		{name: "unknown default timezone", key: "DEFAULT_TIMEZONE", value: "Mars/Olympus"},
+		{name: "local default timezone", key: "DEFAULT_TIMEZONE", value: "Local"},
+		{name: "zero birthday check interval", key: "BIRTHDAY_CHECK_INTERVAL", value: "0s"},
+		{name: "birthday notifications without a webhook", key: "BIRTHDAY_NOTIFICATIONS", value: "true"},
+		{name: "user events webhook without a scheme", key: "USER_EVENTS_WEBHOOK_URL", value: "hooks.example.com/users"},
+		{name: "zero outbox poll interval", key: "OUTBOX_POLL_INTERVAL", value: "0s"},
+		{name: "zero outbox max attempts", key: "OUTBOX_MAX_ATTEMPTS", value: "0"},
+		{name: "zero access flush interval", key: "ACCESS_FLUSH_INTERVAL", value: "0s"},
+		{name: "zero access buffer size", key: "ACCESS_BUFFER_SIZE", value: "0"},
+		{name: "tenant with a space", key: "TENANTS", value: "acme,big corp"},
+		{name: "tenant too long", key: "TENANTS", value: strings.Repeat("a", 65)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(tt.key, tt.value)
+
+			_, err := LoadConfig()
+			if err == nil {
+				t.Fatalf("expected error for %s=%s", tt.key, tt.value)
+			}
+			if !strings.Contains(err.Error(), tt.key) {
+				t.Errorf("error %q does not name %s", err, tt.key)
+			}
+		})
+	}
+}
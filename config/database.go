@@ -0,0 +1,73 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+const (
+	connectInitialBackoff = 250 * time.Millisecond
+	connectMaxBackoff     = 5 * time.Second
+)
+
+// OpenDatabase only prepares the pool; sql.Open does not connect, so use
+// WaitForDatabase before relying on it.
+func OpenDatabase(cfg *Config) (*sql.DB, error) {
+	dsn := fmt.Sprintf(
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		cfg.DBHost,
+		cfg.DBPort,
+		cfg.DBUser,
+		cfg.DBPassword,
+		cfg.DBName,
+	)
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(25)
+	db.SetMaxIdleConns(5)
+
+	return db, nil
+}
+
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// WaitForDatabase pings db until it answers or timeout has passed, doubling
+// the pause between attempts from 250ms up to 5s. retry, if set, is told
+// about every failed attempt and the pause before the next one.
+func WaitForDatabase(ctx context.Context, db Pinger, timeout time.Duration, retry func(attempt int, err error, wait time.Duration)) error {
+	if timeout <= 0 {
+		return db.PingContext(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	wait := connectInitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		if retry != nil {
+			retry(attempt, err, wait)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("database unreachable after %d attempts: %w", attempt, err)
+		case <-timer.C:
+		}
+		wait = min(wait*2, connectMaxBackoff)
+	}
+}
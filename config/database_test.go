@@ -0,0 +1,92 @@
+package config
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+// tcpPinger stands in for a database that is reachable once something
+// accepts connections on addr.
+type tcpPinger struct {
+	addr string
+}
+
+func (p tcpPinger) PingContext(ctx context.Context) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", p.addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return addr
+}
+
+func TestWaitForDatabaseRetriesUntilReachable(t *testing.T) {
+	addr := freeAddr(t)
+	go func() {
+		time.Sleep(600 * time.Millisecond)
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		t.Cleanup(func() { ln.Close() })
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	var waits []time.Duration
+	err := WaitForDatabase(context.Background(), tcpPinger{addr}, 10*time.Second, func(attempt int, err error, wait time.Duration) {
+		waits = append(waits, wait)
+	})
+	if err != nil {
+		t.Fatalf("WaitForDatabase: %v", err)
+	}
+	if len(waits) < 2 || waits[0] != connectInitialBackoff || waits[1] != 2*connectInitialBackoff {
+		t.Errorf("backoff = %v, want at least two retries doubling from %v", waits, connectInitialBackoff)
+	}
+}
+
+func TestWaitForDatabaseGivesUp(t *testing.T) {
+	start := time.Now()
+	attempts := 0
+	err := WaitForDatabase(context.Background(), tcpPinger{freeAddr(t)}, 700*time.Millisecond, func(int, error, time.Duration) {
+		attempts++
+	})
+	if err == nil {
+		t.Fatal("WaitForDatabase succeeded against a closed port")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("gave up after %v, want about 700ms", elapsed)
+	}
+	if attempts < 2 {
+		t.Errorf("attempts = %d, want retries before giving up", attempts)
+	}
+}
+
+func TestWaitForDatabaseZeroTimeoutTriesOnce(t *testing.T) {
+	attempts := 0
+	err := WaitForDatabase(context.Background(), tcpPinger{freeAddr(t)}, 0, func(int, error, time.Duration) {
+		attempts++
+	})
+	if err == nil || attempts != 0 {
+		t.Errorf("err = %v, retries = %d, want one failed attempt and no retries", err, attempts)
+	}
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"reflect"
+	"time"
+)
+
+const maskedValue = "***"
+
+type Setting struct {
+	Value  any    `json:"value"`
+	Source Source `json:"source,omitempty"`
+	Secret bool   `json:"secret,omitempty"`
+}
+
+// Effective describes every env-backed field as it was resolved. Fields tagged
+// secret:"true" are masked so the result is safe to log or serve.
+func (c *Config) Effective() map[string]Setting {
+	settings := make(map[string]Setting)
+
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := field.Tag.Get("env")
+		if key == "" {
+			continue
+		}
+
+		setting := Setting{Source: c.sources[key]}
+		switch value := v.Field(i).Interface().(type) {
+		case time.Duration:
+			setting.Value = value.String()
+		default:
+			setting.Value = value
+		}
+
+		if field.Tag.Get("secret") == "true" {
+			setting.Secret = true
+			if !v.Field(i).IsZero() {
+				setting.Value = maskedValue
+			}
+		}
+
+		settings[key] = setting
+	}
+
+	return settings
+}
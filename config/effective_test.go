@@ -0,0 +1,63 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEffectiveMasksSecretsAndReportsSources(t *testing.T) {
+	t.Setenv("DB_HOST", "db.internal")
+	t.Setenv("DB_PASSWORD", "")
+	t.Setenv("DB_PASSWORD_FILE", writeSecret(t, "hunter2\n"))
+	t.Setenv("ADMIN_TOKEN", "s3cret")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings := cfg.Effective()
+
+	tests := []struct {
+		key    string
+		value  any
+		source Source
+	}{
+		{key: "DB_HOST", value: "db.internal", source: SourceEnv},
+		{key: "DB_PORT", value: "5432", source: SourceDefault},
+		{key: "DB_PASSWORD", value: "***", source: SourceFile},
+		{key: "ADMIN_TOKEN", value: "***", source: SourceEnv},
+		{key: "SERVER_READ_TIMEOUT", value: "10s", source: SourceDefault},
+	}
+	for _, tt := range tests {
+		got, ok := settings[tt.key]
+		if !ok {
+			t.Errorf("%s missing from effective config", tt.key)
+			continue
+		}
+		if !reflect.DeepEqual(got.Value, tt.value) || got.Source != tt.source {
+			t.Errorf("%s = %+v, want value %v from %s", tt.key, got, tt.value, tt.source)
+		}
+	}
+
+	for key, setting := range settings {
+		if s, ok := setting.Value.(string); ok && (strings.Contains(s, "hunter2") || strings.Contains(s, "s3cret")) {
+			t.Errorf("%s leaks a secret value", key)
+		}
+	}
+}
+
+func TestSecretLookingFieldsAreTagged(t *testing.T) {
+	markers := []string{"PASSWORD", "SECRET", "TOKEN", "API_KEY"}
+
+	typ := reflect.TypeOf(Config{})
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		key := field.Tag.Get("env")
+		for _, marker := range markers {
+			if strings.Contains(key, marker) && field.Tag.Get("secret") != "true" {
+				t.Errorf("%s looks secret but is not tagged secret:\"true\"", key)
+			}
+		}
+	}
+}
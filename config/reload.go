@@ -0,0 +1,61 @@
+package config
+
+import (
+	"reflect"
+	"sync/atomic"
+)
+
+// Holder publishes the active configuration to request handlers. Fields tagged
+// reload:"true" are swapped in place on Reload; everything else keeps its
+// startup value until the process restarts.
+type Holder struct {
+	current atomic.Pointer[Config]
+}
+
+func NewHolder(cfg *Config) *Holder {
+	h := &Holder{}
+	h.current.Store(cfg)
+	return h
+}
+
+func (h *Holder) Load() *Config {
+	return h.current.Load()
+}
+
+func (h *Holder) Reload() (changed, restartRequired []string, err error) {
+	next, err := LoadConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	current := h.Load()
+	merged := *current
+	merged.sources = make(map[string]Source, len(current.sources))
+	for key, source := range current.sources {
+		merged.sources[key] = source
+	}
+
+	cv := reflect.ValueOf(current).Elem()
+	nv := reflect.ValueOf(next).Elem()
+	mv := reflect.ValueOf(&merged).Elem()
+	t := cv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := field.Tag.Get("env")
+		if key == "" || reflect.DeepEqual(cv.Field(i).Interface(), nv.Field(i).Interface()) {
+			continue
+		}
+
+		if field.Tag.Get("reload") != "true" {
+			restartRequired = append(restartRequired, key)
+			continue
+		}
+
+		mv.Field(i).Set(nv.Field(i))
+		merged.sources[key] = next.sources[key]
+		changed = append(changed, key)
+	}
+
+	h.current.Store(&merged)
+	return changed, restartRequired, nil
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHolderReload(t *testing.T) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	holder := NewHolder(cfg)
+
+	t.Setenv("LOG_LEVEL", "warn")
+	t.Setenv("SLOW_REQUEST_THRESHOLD", "250ms")
+	t.Setenv("SERVER_PORT", "9090")
+
+	changed, restartRequired, err := holder.Reload()
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+
+	if want := []string{"LOG_LEVEL", "SLOW_REQUEST_THRESHOLD"}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("changed = %v, want %v", changed, want)
+	}
+	if want := []string{"SERVER_PORT"}; !reflect.DeepEqual(restartRequired, want) {
+		t.Errorf("restartRequired = %v, want %v", restartRequired, want)
+	}
+
+	got := holder.Load()
+	if got.LogLevel != "warn" || got.SlowRequestThreshold != 250*time.Millisecond {
+		t.Errorf("reloadable fields not applied: %+v", got)
+	}
+	if got.ServerPort != "8080" {
+		t.Errorf("ServerPort = %q, want startup value 8080", got.ServerPort)
+	}
+	if cfg.LogLevel != "" {
+		t.Error("Reload mutated the previous snapshot")
+	}
+}
+
+func TestHolderReloadInvalidKeepsCurrent(t *testing.T) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	holder := NewHolder(cfg)
+
+	t.Setenv("LOG_LEVEL", "loud")
+	if _, _, err := holder.Reload(); err == nil {
+		t.Fatal("expected error for invalid LOG_LEVEL")
+	}
+	if holder.Load() != cfg {
+		t.Error("failed reload replaced the active config")
+	}
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// SchemaRequirements is what the server needs of its database before it can
+// serve a request.
+type SchemaRequirements struct {
+	// Version is the newest migration this build ships; schema_migrations
+	// must record it, or a later one, cleanly applied.
+	Version    int64
+	Tables     []TableRequirement
+	Extensions []string
+}
+
+// TableRequirement lists the columns the server reads or writes, each with
+// its information_schema data_type, and the privileges its role needs.
+type TableRequirement struct {
+	Name       string
+	Columns    map[string]string
+	Privileges []string
+}
+
+// SchemaError lists every way the database falls short of the requirements.
+type SchemaError struct {
+	Problems []string
+}
+
+func (e *SchemaError) Error() string {
+	return "database schema check failed: " + strings.Join(e.Problems, "; ")
+}
+
+const undefinedTable = "42P01"
+
+// VerifySchema checks db against req and returns a *SchemaError naming each
+// problem, such as "column users.locale missing; run migrations", or the
+// error of a query that could not run at all.
+func VerifySchema(ctx context.Context, db *sql.DB, req SchemaRequirements) error {
+	var problems []string
+
+	var version sql.NullInt64
+	var dirty bool
+	err := db.QueryRowContext(ctx, `SELECT MAX(version), COALESCE(bool_or(dirty), false) FROM schema_migrations`).Scan(&version, &dirty)
+	var pqErr *pq.Error
+	switch {
+	case errors.As(err, &pqErr) && pqErr.Code == undefinedTable:
+		problems = append(problems, "table schema_migrations missing; run migrations")
+	case err != nil:
+		return err
+	case dirty:
+		problems = append(problems, "a migration is dirty; fix it by hand and run migrate force")
+	case version.Int64 < req.Version:
+		problems = append(problems, fmt.Sprintf("schema is at version %d, this build needs %d; run migrations", version.Int64, req.Version))
+	}
+
+	for _, table := range req.Tables {
+		found, err := tableProblems(ctx, db, table)
+		if err != nil {
+			return err
+		}
+		problems = append(problems, found...)
+	}
+
+	for _, name := range req.Extensions {
+		var installed bool
+		if err := db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = $1)`, name).Scan(&installed); err != nil {
+			return err
+		}
+		if !installed {
+			problems = append(problems, fmt.Sprintf("extension %s missing; install it or run migrations as a role allowed to", name))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &SchemaError{Problems: problems}
+	}
+	return nil
+}
+
+func tableProblems(ctx context.Context, db *sql.DB, table TableRequirement) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `SELECT column_name, data_type FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1`, table.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	types := map[string]string{}
+	for rows.Next() {
+		var column, dataType string
+		if err := rows.Scan(&column, &dataType); err != nil {
+			return nil, err
+		}
+		types[column] = dataType
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(types) == 0 {
+		return []string{fmt.Sprintf("table %s missing; run migrations", table.Name)}, nil
+	}
+
+	var problems []string
+	for _, column := range slices.Sorted(maps.Keys(table.Columns)) {
+		want := table.Columns[column]
+		switch got, ok := types[column]; {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("column %s.%s missing; run migrations", table.Name, column))
+		case got != want:
+			problems = append(problems, fmt.Sprintf("column %s.%s is %s, want %s; run migrations", table.Name, column, got, want))
+		}
+	}
+	for _, privilege := range table.Privileges {
+		var granted bool
+		if err := db.QueryRowContext(ctx, `SELECT has_table_privilege(current_schema() || '.' || $1, $2)`, table.Name, privilege).Scan(&granted); err != nil {
+			return nil, err
+		}
+		if !granted {
+			problems = append(problems, fmt.Sprintf("database role lacks %s on %s", privilege, table.Name))
+		}
+	}
+	return problems, nil
+}
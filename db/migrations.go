@@ -0,0 +1,8 @@
+// Package db embeds the SQL migrations so the server binary can apply them
+// without the files on disk.
+package db
+
+import "embed"
+
+//go:embed migrations/*.sql
+var Migrations embed.FS
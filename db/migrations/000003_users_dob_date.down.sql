@@ -0,0 +1 @@
+-- dob is DATE in 000001 as well, so there is nothing to revert.
@@ -0,0 +1,4 @@
+-- 000001 already declares dob as DATE; this pins it for databases whose users
+-- table was created by hand as a timestamp, which shifts with the session
+-- time zone on read.
+ALTER TABLE users ALTER COLUMN dob TYPE DATE USING dob::date;
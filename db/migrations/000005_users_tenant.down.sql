@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_users_tenant_dob;
+DROP INDEX IF EXISTS idx_users_tenant_created_at_id;
+DROP INDEX IF EXISTS idx_users_tenant_id;
+CREATE INDEX IF NOT EXISTS idx_users_dob ON users(dob);
+CREATE INDEX IF NOT EXISTS idx_users_created_at_id ON users(created_at, id);
+ALTER TABLE users DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,10 @@
+-- Existing rows belong to the default tenant, which is also what a
+-- single-tenant deployment writes.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+
+-- Every query filters on tenant_id first, so it leads each index.
+DROP INDEX IF EXISTS idx_users_created_at_id;
+DROP INDEX IF EXISTS idx_users_dob;
+CREATE INDEX IF NOT EXISTS idx_users_tenant_id ON users(tenant_id, id);
+CREATE INDEX IF NOT EXISTS idx_users_tenant_created_at_id ON users(tenant_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_users_tenant_dob ON users(tenant_id, dob);
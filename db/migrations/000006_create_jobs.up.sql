@@ -0,0 +1,18 @@
+-- Background jobs. payload holds the upload until the job finishes; report
+-- holds the result a client polls for.
+CREATE TABLE IF NOT EXISTS jobs (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    state TEXT NOT NULL DEFAULT 'pending',
+    payload BYTEA,
+    rows_processed INTEGER NOT NULL DEFAULT 0,
+    report JSONB,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP
+);
+
+-- Workers claim the oldest pending job; the stale sweep scans running ones.
+CREATE INDEX IF NOT EXISTS idx_jobs_state_id ON jobs(state, id);
@@ -0,0 +1,2 @@
+-- The extension stays: other schemas may rely on it.
+DROP INDEX IF EXISTS idx_users_name_trgm;
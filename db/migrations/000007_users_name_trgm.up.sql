@@ -0,0 +1,6 @@
+-- Fuzzy name search ranks by trigram similarity. Creating the extension needs
+-- a role allowed to; where the migrations run without one, install pg_trgm
+-- beforehand and this is a no-op.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_users_name_trgm ON users USING GIN (name gin_trgm_ops);
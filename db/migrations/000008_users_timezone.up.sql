@@ -0,0 +1,3 @@
+-- An IANA zone name such as America/Los_Angeles. NULL means the server's
+-- DEFAULT_TIMEZONE, so existing rows keep behaving as before.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone TEXT;
@@ -0,0 +1,9 @@
+-- One row per user and year once their birthday notification has been sent.
+-- Every instance runs the scheduler; the primary key lets only one of them
+-- claim a given birthday.
+CREATE TABLE IF NOT EXISTS birthday_notifications (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    year INTEGER NOT NULL,
+    notified_on DATE NOT NULL,
+    PRIMARY KEY (user_id, year)
+);
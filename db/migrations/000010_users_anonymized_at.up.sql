@@ -0,0 +1,3 @@
+-- Set once a user's personal data has been scrubbed; the row then takes no
+-- further updates.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS anonymized_at TIMESTAMP;
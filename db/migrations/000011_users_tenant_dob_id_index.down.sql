@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS idx_users_tenant_dob ON users(tenant_id, dob);
+DROP INDEX IF EXISTS idx_users_tenant_dob_id;
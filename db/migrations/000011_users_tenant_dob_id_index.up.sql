@@ -0,0 +1,3 @@
+-- sort=age pages on (dob, id); the id keeps ties in keyset order.
+CREATE INDEX IF NOT EXISTS idx_users_tenant_dob_id ON users(tenant_id, dob, id);
+DROP INDEX IF EXISTS idx_users_tenant_dob;
@@ -0,0 +1,2 @@
+-- Birthday twins and collisions match on the month and day of the DOB.
+CREATE INDEX IF NOT EXISTS idx_users_tenant_birthday ON users(tenant_id, (EXTRACT(MONTH FROM dob)), (EXTRACT(DAY FROM dob)));
@@ -0,0 +1,13 @@
+-- Read-only links to one user's public profile. token_hash is the SHA-256 of
+-- the token handed out; the token itself is never stored.
+CREATE TABLE IF NOT EXISTS share_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash BYTEA NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_share_tokens_user ON share_tokens(user_id);
@@ -0,0 +1,3 @@
+-- A BCP 47 tag such as de-DE for the user's display dates. NULL formats them
+-- in English unless the request sends Accept-Language.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale TEXT;
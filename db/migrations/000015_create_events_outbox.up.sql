@@ -0,0 +1,18 @@
+-- Events waiting to be delivered, written in the transaction of the change
+-- they report so that none is lost to a crash between the two.
+CREATE TABLE IF NOT EXISTS events_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    last_error TEXT NOT NULL DEFAULT '',
+    sent_at TIMESTAMP,
+    dead_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_events_outbox_due ON events_outbox(next_attempt_at, id) WHERE sent_at IS NULL AND dead_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_events_outbox_dead ON events_outbox(id) WHERE dead_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_events_outbox_sent ON events_outbox(sent_at) WHERE sent_at IS NOT NULL;
@@ -0,0 +1,8 @@
+-- How often each user has been read, written in batches by the access
+-- tracker when ACCESS_TRACKING is on.
+CREATE TABLE IF NOT EXISTS access_counts (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    tenant_id TEXT NOT NULL,
+    count BIGINT NOT NULL,
+    last_accessed_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,25 @@
+INSERT INTO jobs (tenant_id, kind, payload)
+VALUES ($1, $2, $3)
+RETURNING id, tenant_id, kind, state, rows_processed, report, error, created_at, updated_at, finished_at;
+
+SELECT id, tenant_id, kind, state, rows_processed, report, error, created_at, updated_at, finished_at
+FROM jobs
+WHERE tenant_id = $1 AND id = $2;
+
+UPDATE jobs
+SET state = 'running', updated_at = CURRENT_TIMESTAMP
+WHERE id = (SELECT id FROM jobs WHERE state = 'pending' ORDER BY id FOR UPDATE SKIP LOCKED LIMIT 1)
+RETURNING id, tenant_id, kind, state, rows_processed, report, error, created_at, updated_at, finished_at, payload;
+
+UPDATE jobs
+SET rows_processed = $1, updated_at = CURRENT_TIMESTAMP
+WHERE id = $2 AND state = 'running';
+
+UPDATE jobs
+SET state = $1, rows_processed = $2, report = $3, error = $4, payload = NULL,
+    updated_at = CURRENT_TIMESTAMP, finished_at = CURRENT_TIMESTAMP
+WHERE id = $5 AND state = 'running';
+
+UPDATE jobs
+SET state = 'failed', error = $1, payload = NULL, updated_at = CURRENT_TIMESTAMP, finished_at = CURRENT_TIMESTAMP
+WHERE state = 'running' AND updated_at < CURRENT_TIMESTAMP - make_interval(secs => $2);
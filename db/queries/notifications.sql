@@ -0,0 +1,14 @@
+INSERT INTO birthday_notifications (user_id, year, notified_on)
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING;
+
+DELETE FROM birthday_notifications
+WHERE user_id = $1 AND year = $2;
+
+SELECT year, notified_on
+FROM birthday_notifications
+WHERE user_id = $1
+ORDER BY year;
+
+DELETE FROM birthday_notifications
+WHERE user_id = $1;
@@ -0,0 +1,38 @@
+INSERT INTO events_outbox (tenant_id, type, payload, created_at, next_attempt_at)
+VALUES ($1, $2, $3, $4, $4);
+
+UPDATE events_outbox
+SET attempts = attempts + 1, next_attempt_at = $2
+WHERE id = (
+    SELECT id FROM events_outbox
+    WHERE sent_at IS NULL AND dead_at IS NULL AND next_attempt_at <= $1
+    ORDER BY next_attempt_at, id
+    FOR UPDATE SKIP LOCKED
+    LIMIT 1
+)
+RETURNING id, tenant_id, type, payload, created_at, attempts, next_attempt_at, last_error, sent_at, dead_at;
+
+UPDATE events_outbox
+SET sent_at = $2, last_error = ''
+WHERE id = $1;
+
+UPDATE events_outbox
+SET last_error = $2, next_attempt_at = $3
+WHERE id = $1;
+
+UPDATE events_outbox
+SET last_error = $2, dead_at = $3
+WHERE id = $1;
+
+SELECT id, tenant_id, type, payload, created_at, attempts, next_attempt_at, last_error, sent_at, dead_at
+FROM events_outbox
+WHERE dead_at IS NOT NULL
+ORDER BY id
+LIMIT $1;
+
+UPDATE events_outbox
+SET attempts = 0, next_attempt_at = $2, dead_at = NULL
+WHERE id = $1 AND dead_at IS NOT NULL;
+
+DELETE FROM events_outbox
+WHERE sent_at < $1;
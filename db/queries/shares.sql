@@ -0,0 +1,16 @@
+INSERT INTO share_tokens (tenant_id, user_id, token_hash, expires_at)
+VALUES ($1, $2, $3, $4)
+RETURNING id, tenant_id, user_id, token_hash, created_at, expires_at, revoked_at;
+
+SELECT id, tenant_id, user_id, token_hash, created_at, expires_at, revoked_at
+FROM share_tokens
+WHERE tenant_id = $1 AND user_id = $2
+ORDER BY id;
+
+UPDATE share_tokens
+SET revoked_at = $1
+WHERE tenant_id = $2 AND user_id = $3 AND id = $4 AND revoked_at IS NULL;
+
+SELECT id, tenant_id, user_id, token_hash, created_at, expires_at, revoked_at
+FROM share_tokens
+WHERE token_hash = $1;
@@ -1,22 +1,80 @@
-INSERT INTO users (name, dob)
-VALUES ($1, $2)
-RETURNING id, name, dob, created_at, updated_at;
+INSERT INTO users (tenant_id, name, dob)
+VALUES ($1, $2, $3)
+RETURNING id, tenant_id, name, dob, created_at, updated_at;
 
-SELECT id, name, dob, created_at, updated_at
+SELECT id, tenant_id, name, dob, created_at, updated_at
 FROM users
-WHERE id = $1;
+WHERE tenant_id = $1 AND id = $2;
 
-SELECT id, name, dob, created_at, updated_at
+SELECT id, tenant_id, name, dob, created_at, updated_at
 FROM users
+WHERE tenant_id = $1 AND lower(name) = lower($2)
 ORDER BY id
-LIMIT $1 OFFSET $2;
+LIMIT 1;
+
+SELECT id, tenant_id, name, dob, created_at, updated_at
+FROM users
+WHERE tenant_id = $1
+ORDER BY id
+LIMIT $2 OFFSET $3;
 
 UPDATE users
 SET name = $1, dob = $2, updated_at = CURRENT_TIMESTAMP
-WHERE id = $3
-RETURNING id, name, dob, created_at, updated_at;
+WHERE tenant_id = $3 AND id = $4
+RETURNING id, tenant_id, name, dob, created_at, updated_at;
 
 DELETE FROM users
-WHERE id = $1;
+WHERE tenant_id = $1 AND id = $2;
+
+SELECT COUNT(*) FROM users WHERE tenant_id = $1;
+
+-- Users born before, on and after a DOB, for the age percentile.
+SELECT COUNT(*) FILTER (WHERE dob < $1::date), COUNT(*) FILTER (WHERE dob = $1::date), COUNT(*) FILTER (WHERE dob > $1::date)
+FROM users
+WHERE tenant_id = $2;
+
+-- Fuzzy name search; % reads pg_trgm.similarity_threshold, set per transaction.
+SELECT set_config('pg_trgm.similarity_threshold', $1, true);
 
-SELECT COUNT(*) FROM users;
+SELECT id, tenant_id, name, dob, created_at, updated_at
+FROM users
+WHERE tenant_id = $2 AND name % $3
+ORDER BY similarity(name, $3) DESC, id
+LIMIT $4 OFFSET $5;
+
+-- Oldest users; the youngest sort by dob DESC, still with ties in id order.
+SELECT id, tenant_id, name, dob, timezone, locale, created_at, updated_at, anonymized_at
+FROM users
+WHERE tenant_id = $1 AND anonymized_at IS NULL
+ORDER BY dob ASC, id
+LIMIT $2;
+
+-- Birthday twins of a user; the month and day expressions match the
+-- idx_users_tenant_birthday index.
+SELECT id, tenant_id, name, dob, timezone, locale, created_at, updated_at, anonymized_at
+FROM users
+WHERE tenant_id = $1 AND id <> $2 AND anonymized_at IS NULL
+  AND (EXTRACT(MONTH FROM dob), EXTRACT(DAY FROM dob)) IN (($3, $4))
+ORDER BY id
+LIMIT $5 OFFSET $6;
+
+-- Birthdays shared by more than one user.
+SELECT EXTRACT(MONTH FROM dob)::int, EXTRACT(DAY FROM dob)::int, COUNT(*)
+FROM users
+WHERE tenant_id = $1 AND anonymized_at IS NULL
+GROUP BY EXTRACT(MONTH FROM dob), EXTRACT(DAY FROM dob)
+HAVING COUNT(*) > 1
+ORDER BY COUNT(*) DESC, 1, 2
+LIMIT $2;
+
+-- Anonymize; the guard leaves an already anonymized row untouched.
+UPDATE users
+SET name = $1, dob = $2, timezone = NULL, locale = NULL, anonymized_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE tenant_id = $3 AND id = $4 AND anonymized_at IS NULL
+RETURNING id, tenant_id, name, dob, timezone, locale, created_at, updated_at, anonymized_at;
+
+-- Locale; an empty tag clears it.
+UPDATE users
+SET locale = NULLIF($1, ''), updated_at = CURRENT_TIMESTAMP
+WHERE tenant_id = $2 AND id = $3 AND anonymized_at IS NULL
+RETURNING id, tenant_id, name, dob, timezone, locale, created_at, updated_at, anonymized_at;
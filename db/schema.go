@@ -0,0 +1,75 @@
+package db
+
+import "github.com/srinivasarynh/age_calculator/config"
+
+const timestamp = "timestamp without time zone"
+
+// SchemaRequirements describes the schema the embedded migrations build: the
+// tables and columns the repositories query. Version is left for the caller,
+// which loads the migrations themselves. A
+// migration that adds a column the server reads adds it here too, and so
+// does a query that needs a privilege the table's entry does not list.
+func SchemaRequirements() config.SchemaRequirements {
+	return config.SchemaRequirements{
+		Tables: []config.TableRequirement{
+			{Name: "users", Privileges: []string{"SELECT", "INSERT", "UPDATE", "DELETE"}, Columns: map[string]string{
+				"id":            "integer",
+				"tenant_id":     "text",
+				"name":          "text",
+				"dob":           "date",
+				"timezone":      "text",
+				"locale":        "text",
+				"created_at":    timestamp,
+				"updated_at":    timestamp,
+				"anonymized_at": timestamp,
+			}},
+			{Name: "jobs", Privileges: []string{"SELECT", "INSERT", "UPDATE"}, Columns: map[string]string{
+				"id":             "bigint",
+				"tenant_id":      "text",
+				"kind":           "text",
+				"state":          "text",
+				"payload":        "bytea",
+				"rows_processed": "integer",
+				"report":         "jsonb",
+				"error":          "text",
+				"created_at":     timestamp,
+				"updated_at":     timestamp,
+				"finished_at":    timestamp,
+			}},
+			{Name: "birthday_notifications", Privileges: []string{"SELECT", "INSERT", "DELETE"}, Columns: map[string]string{
+				"user_id":     "integer",
+				"year":        "integer",
+				"notified_on": "date",
+			}},
+			{Name: "share_tokens", Privileges: []string{"SELECT", "INSERT", "UPDATE"}, Columns: map[string]string{
+				"id":         "bigint",
+				"tenant_id":  "text",
+				"user_id":    "integer",
+				"token_hash": "bytea",
+				"created_at": timestamp,
+				"expires_at": timestamp,
+				"revoked_at": timestamp,
+			}},
+			{Name: "events_outbox", Privileges: []string{"SELECT", "INSERT", "UPDATE", "DELETE"}, Columns: map[string]string{
+				"id":              "bigint",
+				"tenant_id":       "text",
+				"type":            "text",
+				"payload":         "jsonb",
+				"created_at":      timestamp,
+				"attempts":        "integer",
+				"next_attempt_at": timestamp,
+				"last_error":      "text",
+				"sent_at":         timestamp,
+				"dead_at":         timestamp,
+			}},
+			{Name: "access_counts", Privileges: []string{"SELECT", "INSERT", "UPDATE"}, Columns: map[string]string{
+				"user_id":          "integer",
+				"tenant_id":        "text",
+				"count":            "bigint",
+				"last_accessed_at": timestamp,
+			}},
+		},
+		// Fuzzy name search, and the index 000007 builds on users.name.
+		Extensions: []string{"pg_trgm"},
+	}
+}
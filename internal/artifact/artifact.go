@@ -0,0 +1,141 @@
+// Package artifact keeps the files background jobs produce, such as exports,
+// until they are downloaded or expire.
+package artifact
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned by Open for a file that was never published or has
+// been pruned.
+var ErrNotFound = errors.New("artifact: not found")
+
+// Store holds named files. A file is invisible to Open until its Writer is
+// closed, so a reader never sees one half written. The local directory is the
+// only implementation; the interface leaves room for object storage.
+type Store interface {
+	// Create starts a file; closing the Writer publishes it under name,
+	// replacing any file already there.
+	Create(ctx context.Context, name string) (Writer, error)
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+	// Prune deletes the files last written before cutoff, including ones
+	// abandoned mid-write, and reports how many it deleted.
+	Prune(ctx context.Context, cutoff time.Time) (int, error)
+}
+
+// Writer is a file being written. Abort discards it instead of publishing.
+type Writer interface {
+	io.WriteCloser
+	Abort() error
+}
+
+type fileStore struct {
+	dir string
+}
+
+// NewFileStore keeps files in dir, creating it if needed.
+func NewFileStore(dir string) (Store, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("artifact: %w", err)
+	}
+	return &fileStore{dir: dir}, nil
+}
+
+// path rejects names that would reach outside dir or collide with the
+// temporary files of writes in progress.
+func (s *fileStore) path(name string) (string, error) {
+	if name == "" || strings.HasPrefix(name, ".") || filepath.Base(name) != name {
+		return "", fmt.Errorf("artifact: invalid name %q", name)
+	}
+	return filepath.Join(s.dir, name), nil
+}
+
+func (s *fileStore) Create(ctx context.Context, name string) (Writer, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(s.dir, "."+name+".*")
+	if err != nil {
+		return nil, fmt.Errorf("artifact: %w", err)
+	}
+	return &fileWriter{File: f, path: path}, nil
+}
+
+func (s *fileStore) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("artifact: %w", err)
+	}
+	return f, nil
+}
+
+func (s *fileStore) Prune(ctx context.Context, cutoff time.Time) (int, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return 0, fmt.Errorf("artifact: %w", err)
+	}
+	pruned := 0
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return pruned, err
+		}
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.dir, entry.Name())); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return pruned, fmt.Errorf("artifact: %w", err)
+		}
+		pruned++
+	}
+	return pruned, nil
+}
+
+// fileWriter writes to a temporary file in the store's directory and renames
+// it into place on Close, which is atomic on one filesystem.
+type fileWriter struct {
+	*os.File
+	path string
+	done bool
+}
+
+func (w *fileWriter) Close() error {
+	if w.done {
+		return nil
+	}
+	w.done = true
+	if err := w.File.Close(); err != nil {
+		os.Remove(w.File.Name())
+		return fmt.Errorf("artifact: %w", err)
+	}
+	if err := os.Rename(w.File.Name(), w.path); err != nil {
+		os.Remove(w.File.Name())
+		return fmt.Errorf("artifact: %w", err)
+	}
+	return nil
+}
+
+func (w *fileWriter) Abort() error {
+	if w.done {
+		return nil
+	}
+	w.done = true
+	w.File.Close()
+	return os.Remove(w.File.Name())
+}
@@ -0,0 +1,110 @@
+package artifact
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func readAll(t *testing.T, store Store, name string) (string, error) {
+	t.Helper()
+	r, err := store.Open(context.Background(), name)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b), nil
+}
+
+func TestFileStorePublishesOnClose(t *testing.T) {
+	store, err := NewFileStore(filepath.Join(t.TempDir(), "jobs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	w, err := store.Create(ctx, "job-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(w, "id,name\n")
+	if _, err := store.Open(ctx, "job-1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open while writing: err = %v, want ErrNotFound", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := readAll(t, store, "job-1"); err != nil || got != "id,name\n" {
+		t.Errorf("Open after Close = %q, %v", got, err)
+	}
+
+	aborted, _ := store.Create(ctx, "job-2")
+	io.WriteString(aborted, "partial")
+	if err := aborted.Abort(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Open(ctx, "job-2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open after Abort: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFileStoreRejectsNamesOutsideTheDirectory(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"", "../job-1", "a/b", ".job-1"} {
+		if _, err := store.Create(context.Background(), name); err == nil {
+			t.Errorf("Create(%q) succeeded", name)
+		}
+		if _, err := store.Open(context.Background(), name); err == nil || errors.Is(err, ErrNotFound) {
+			t.Errorf("Open(%q): err = %v, want an invalid name", name, err)
+		}
+	}
+}
+
+func TestFileStorePrune(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	now := time.Now()
+
+	for _, name := range []string{"old", "new"} {
+		w, _ := store.Create(ctx, name)
+		io.WriteString(w, name)
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	abandoned, _ := store.Create(ctx, "crashed")
+	io.WriteString(abandoned, "partial")
+	dayAgo := now.Add(-24 * time.Hour)
+	os.Chtimes(filepath.Join(dir, "old"), dayAgo, dayAgo)
+	os.Chtimes(abandoned.(*fileWriter).File.Name(), dayAgo, dayAgo)
+
+	n, err := store.Prune(ctx, now.Add(-time.Hour))
+	if err != nil || n != 2 {
+		t.Fatalf("Prune = %d, %v; want the old file and the abandoned write", n, err)
+	}
+	if _, err := store.Open(ctx, "old"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("old file survived: err = %v", err)
+	}
+	if got, err := readAll(t, store, "new"); err != nil || got != "new" {
+		t.Errorf("new file = %q, %v", got, err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d entries, want 1", len(entries))
+	}
+}
@@ -0,0 +1,28 @@
+package buildinfo
+
+import "runtime"
+
+// Populated at build time, e.g.
+//
+//	go build -ldflags "-X github.com/srinivasarynh/age_calculator/internal/buildinfo.Version=1.2.0"
+var (
+	Version   = "dev"
+	Commit    = "dev"
+	BuildDate = "dev"
+)
+
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+}
+
+func Get() Info {
+	return Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	}
+}
@@ -0,0 +1,21 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+)
+
+// Cache stores user rows by id. Implementations are interchangeable behind
+// NewUserRepository; errors are reported rather than handled, so the
+// decorator can fall back to the database.
+//
+// Rows are cached, never UserResponse values: age depends on the current date
+// and must be computed on every read.
+type Cache interface {
+	Get(ctx context.Context, id int32) (models.User, bool, error)
+	Set(ctx context.Context, user models.User) error
+	Delete(ctx context.Context, id int32) error
+	// Purge drops every cached row, for when the table is replaced whole.
+	Purge(ctx context.Context) error
+}
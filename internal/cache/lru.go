@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+)
+
+type lruEntry struct {
+	user      models.User
+	expiresAt time.Time
+}
+
+// lru is an in-process Cache bounded by entry count. The least recently used
+// entry is evicted when full, and entries older than ttl are treated as misses.
+type lru struct {
+	mu         sync.Mutex
+	clock      clock.Clock
+	maxEntries int
+	ttl        time.Duration
+	order      *list.List
+	entries    map[int32]*list.Element
+}
+
+func NewLRU(maxEntries int, ttl time.Duration, c clock.Clock) Cache {
+	return &lru{
+		clock:      c,
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		order:      list.New(),
+		entries:    make(map[int32]*list.Element),
+	}
+}
+
+func (c *lru) Get(ctx context.Context, id int32) (models.User, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[id]
+	if !ok {
+		return models.User{}, false, nil
+	}
+	entry := elem.Value.(*lruEntry)
+	if !c.clock.Now().Before(entry.expiresAt) {
+		c.remove(elem)
+		return models.User{}, false, nil
+	}
+	c.order.MoveToFront(elem)
+	return entry.user, true, nil
+}
+
+func (c *lru) Set(ctx context.Context, user models.User) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := c.clock.Now().Add(c.ttl)
+	if elem, ok := c.entries[user.ID]; ok {
+		elem.Value = &lruEntry{user: user, expiresAt: expiresAt}
+		c.order.MoveToFront(elem)
+		return nil
+	}
+
+	c.entries[user.ID] = c.order.PushFront(&lruEntry{user: user, expiresAt: expiresAt})
+	for c.order.Len() > c.maxEntries {
+		c.remove(c.order.Back())
+	}
+	return nil
+}
+
+func (c *lru) Delete(ctx context.Context, id int32) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[id]; ok {
+		c.remove(elem)
+	}
+	return nil
+}
+
+func (c *lru) Purge(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	clear(c.entries)
+	return nil
+}
+
+func (c *lru) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*lruEntry).user.ID)
+}
@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+)
+
+type manualClock struct {
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	return c.now
+}
+
+func (c *manualClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestLRU(maxEntries int, ttl time.Duration) (Cache, *manualClock) {
+	clk := &manualClock{now: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
+	return NewLRU(maxEntries, ttl, clk), clk
+}
+
+func mustGet(t *testing.T, c Cache, id int32) (models.User, bool) {
+	t.Helper()
+	user, ok, err := c.Get(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user, ok
+}
+
+func TestLRUHitAndMiss(t *testing.T) {
+	c, _ := newTestLRU(2, time.Minute)
+	if _, ok := mustGet(t, c, 1); ok {
+		t.Fatal("empty cache reported a hit")
+	}
+
+	c.Set(context.Background(), models.User{ID: 1, Name: "Alice"})
+	if user, ok := mustGet(t, c, 1); !ok || user.Name != "Alice" {
+		t.Errorf("Get(1) = %+v, %v, want Alice", user, ok)
+	}
+}
+
+func TestLRUExpiresAfterTTL(t *testing.T) {
+	c, clk := newTestLRU(2, time.Minute)
+	c.Set(context.Background(), models.User{ID: 1, Name: "Alice"})
+
+	clk.Advance(59 * time.Second)
+	if _, ok := mustGet(t, c, 1); !ok {
+		t.Error("entry expired before its TTL")
+	}
+	clk.Advance(time.Second)
+	if _, ok := mustGet(t, c, 1); ok {
+		t.Error("entry still served at its TTL")
+	}
+}
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	c, _ := newTestLRU(2, time.Minute)
+	ctx := context.Background()
+	c.Set(ctx, models.User{ID: 1})
+	c.Set(ctx, models.User{ID: 2})
+	mustGet(t, c, 1) // 2 is now the least recently used
+	c.Set(ctx, models.User{ID: 3})
+
+	for id, want := range map[int32]bool{1: true, 2: false, 3: true} {
+		if _, ok := mustGet(t, c, id); ok != want {
+			t.Errorf("Get(%d) hit = %v, want %v", id, ok, want)
+		}
+	}
+}
+
+func TestLRUDelete(t *testing.T) {
+	c, _ := newTestLRU(2, time.Minute)
+	c.Set(context.Background(), models.User{ID: 1})
+	c.Delete(context.Background(), 1)
+	if _, ok := mustGet(t, c, 1); ok {
+		t.Error("deleted entry still served")
+	}
+}
+
+func TestLRUPurge(t *testing.T) {
+	c, _ := newTestLRU(2, time.Minute)
+	ctx := context.Background()
+	c.Set(ctx, models.User{ID: 1})
+	c.Set(ctx, models.User{ID: 2})
+	if err := c.Purge(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []int32{1, 2} {
+		if _, ok := mustGet(t, c, id); ok {
+			t.Errorf("Get(%d) hit after Purge", id)
+		}
+	}
+	c.Set(ctx, models.User{ID: 3})
+	if _, ok := mustGet(t, c, 3); !ok {
+		t.Error("entry set after Purge not served")
+	}
+}
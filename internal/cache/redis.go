@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+)
+
+const redisKeyPrefix = "user_api:user:"
+
+// redisPurgeBatch is the keys Purge asks SCAN for, and deletes, at a time.
+const redisPurgeBatch = 500
+
+// Timeouts applied when REDIS_URL does not set its own. A cache that takes
+// longer than the database it fronts is worse than none, so these are far
+// below go-redis's defaults.
+const (
+	redisDialTimeout = 250 * time.Millisecond
+	redisIOTimeout   = 100 * time.Millisecond
+)
+
+// redisCache shares user rows between instances. Every write DELs the key, so
+// an update on one replica is seen by the others on their next read.
+type redisCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewRedis(client *redis.Client, ttl time.Duration) Cache {
+	return &redisCache{client: client, ttl: ttl}
+}
+
+// NewRedisClient parses a redis:// or rediss:// URL, filling in short
+// timeouts the URL leaves unset. Retries are off unless the URL asks for them:
+// on a miss the database is the retry.
+func NewRedisClient(rawURL string) (*redis.Client, error) {
+	opts, err := redis.ParseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if opts.DialTimeout == 0 {
+		opts.DialTimeout = redisDialTimeout
+	}
+	if opts.ReadTimeout == 0 {
+		opts.ReadTimeout = redisIOTimeout
+	}
+	if opts.WriteTimeout == 0 {
+		opts.WriteTimeout = redisIOTimeout
+	}
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = -1
+	}
+	if opts.DialerRetries == 0 {
+		opts.DialerRetries = 1
+	}
+	return redis.NewClient(opts), nil
+}
+
+func redisKey(id int32) string {
+	return redisKeyPrefix + strconv.FormatInt(int64(id), 10)
+}
+
+func (c *redisCache) Get(ctx context.Context, id int32) (models.User, bool, error) {
+	raw, err := c.client.Get(ctx, redisKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return models.User{}, false, nil
+	}
+	if err != nil {
+		return models.User{}, false, err
+	}
+
+	var user models.User
+	if err := json.Unmarshal(raw, &user); err != nil {
+		return models.User{}, false, err
+	}
+	return user, true, nil
+}
+
+func (c *redisCache) Set(ctx context.Context, user models.User) error {
+	raw, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+	return c.client.Set(ctx, redisKey(user.ID), raw, c.ttl).Err()
+}
+
+func (c *redisCache) Delete(ctx context.Context, id int32) error {
+	return c.client.Del(ctx, redisKey(id)).Err()
+}
+
+// Purge deletes every key under the prefix. It SCANs rather than using KEYS,
+// so a large cache does not block Redis for the other clients sharing it.
+func (c *redisCache) Purge(ctx context.Context) error {
+	iter := c.client.Scan(ctx, 0, redisKeyPrefix+"*", redisPurgeBatch).Iterator()
+	keys := make([]string, 0, redisPurgeBatch)
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+		if len(keys) == redisPurgeBatch {
+			if err := c.client.Del(ctx, keys...).Err(); err != nil {
+				return err
+			}
+			keys = keys[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return c.client.Del(ctx, keys...).Err()
+}
@@ -0,0 +1,135 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/metrics"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"go.uber.org/zap"
+)
+
+func newTestRedis(t *testing.T, ttl time.Duration) (Cache, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client, err := NewRedisClient("redis://" + server.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return NewRedis(client, ttl), server
+}
+
+func TestRedisRoundTrip(t *testing.T) {
+	c, server := newTestRedis(t, time.Minute)
+	ctx := context.Background()
+	want := models.User{
+		ID:        7,
+		Name:      "Alice",
+		DOB:       time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC),
+		CreatedAt: time.Date(2025, 1, 1, 10, 0, 0, 123456000, time.UTC),
+		UpdatedAt: time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC),
+	}
+
+	if _, ok := mustGet(t, c, 7); ok {
+		t.Fatal("empty Redis reported a hit")
+	}
+	if err := c.Set(ctx, want); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := mustGet(t, c, 7)
+	if !ok || got.Name != want.Name || !got.DOB.Equal(want.DOB) || !got.CreatedAt.Equal(want.CreatedAt) {
+		t.Errorf("Get(7) = %+v, %v, want %+v", got, ok, want)
+	}
+	if ttl := server.TTL("user_api:user:7"); ttl != time.Minute {
+		t.Errorf("key TTL = %v, want 1m", ttl)
+	}
+
+	if err := c.Delete(ctx, 7); err != nil {
+		t.Fatal(err)
+	}
+	if server.Exists("user_api:user:7") {
+		t.Error("Delete left the key in Redis")
+	}
+}
+
+func TestRedisExpiresAfterTTL(t *testing.T) {
+	c, server := newTestRedis(t, time.Minute)
+	c.Set(context.Background(), models.User{ID: 1})
+
+	server.FastForward(time.Minute)
+	if _, ok := mustGet(t, c, 1); ok {
+		t.Error("entry still served after its TTL")
+	}
+}
+
+func TestRedisPurge(t *testing.T) {
+	c, server := newTestRedis(t, time.Minute)
+	ctx := context.Background()
+	// More than one SCAN batch, beside a key that is not the cache's.
+	for id := range int32(redisPurgeBatch + 10) {
+		if err := c.Set(ctx, models.User{ID: id + 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server.Set("other:key", "kept")
+
+	if err := c.Purge(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if keys := server.Keys(); len(keys) != 1 || keys[0] != "other:key" {
+		t.Errorf("keys after Purge = %d (%v...), want only other:key", len(keys), keys[:min(len(keys), 3)])
+	}
+}
+
+// Two replicas share one Redis: a write through either evicts the row for
+// both.
+func TestRedisInvalidationAcrossInstances(t *testing.T) {
+	c, _ := newTestRedis(t, time.Minute)
+	base := repository.NewMemoryUserRepository(clock.Fixed(time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC)))
+	if _, err := base.Create(context.Background(), "Alice", time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	replicaA := NewUserRepository(base, c, metrics.NewCache(prometheus.NewRegistry()), zap.NewNop())
+	replicaB := NewUserRepository(base, c, metrics.NewCache(prometheus.NewRegistry()), zap.NewNop())
+	ctx := context.Background()
+
+	if _, err := replicaB.GetById(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := replicaA.Update(ctx, 1, "Alicia", time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	if user, err := replicaB.GetById(ctx, 1); err != nil || user.Name != "Alicia" {
+		t.Errorf("replica B read %+v, %v after replica A's update, want Alicia", user, err)
+	}
+}
+
+func TestRedisUnreachableFallsBackToDatabase(t *testing.T) {
+	c, server := newTestRedis(t, time.Minute)
+	server.Close()
+
+	repo, base, m := newCachedRepository(t, c)
+	ctx := context.Background()
+
+	start := time.Now()
+	user, err := repo.GetById(ctx, 1)
+	if err != nil || user.Name != "Alice" {
+		t.Fatalf("GetById = %+v, %v, want Alice from the database", user, err)
+	}
+	if err := repo.Delete(ctx, 1); err != nil {
+		t.Fatalf("Delete failed because of Redis: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("requests took %v with Redis down, want the short client timeouts", elapsed)
+	}
+	if base.gets != 1 || promtestutil.ToFloat64(m.Errors) != 3 {
+		t.Errorf("database lookups = %d, cache errors = %v, want 1 and 3", base.gets, promtestutil.ToFloat64(m.Errors))
+	}
+}
@@ -0,0 +1,177 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/metrics"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+	"go.uber.org/zap"
+)
+
+// cachedUserRepository serves GetById from a Cache and invalidates an id
+// after every successful write in this process. Everything else goes straight
+// to the wrapped repository.
+type cachedUserRepository struct {
+	repository.UserRepository
+	cache   Cache
+	metrics *metrics.Cache
+	logger  *zap.Logger
+}
+
+func NewUserRepository(repo repository.UserRepository, c Cache, m *metrics.Cache, logger *zap.Logger) repository.UserRepository {
+	return &cachedUserRepository{
+		UserRepository: repo,
+		cache:          c,
+		metrics:        m,
+		logger:         logger,
+	}
+}
+
+func (r *cachedUserRepository) GetById(ctx context.Context, id int32) (*models.User, error) {
+	user, ok, err := r.cache.Get(ctx, id)
+	if err != nil {
+		r.failed("get", id, err)
+	}
+	// Ids are unique across tenants, so one entry per id suffices; a row
+	// cached for another tenant is a miss and the scoped query answers.
+	if ok && user.TenantID == tenant.FromContext(ctx) {
+		r.metrics.Hits.Inc()
+		return &user, nil
+	}
+	r.metrics.Misses.Inc()
+
+	fresh, err := r.UserRepository.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.cache.Set(ctx, *fresh); err != nil {
+		r.failed("set", id, err)
+	}
+	return fresh, nil
+}
+
+func (r *cachedUserRepository) Update(ctx context.Context, id int32, name string, dob time.Time) (*models.User, error) {
+	user, err := r.UserRepository.Update(ctx, id, name, dob)
+	if err != nil {
+		return nil, err
+	}
+	r.invalidate(ctx, id)
+	return user, nil
+}
+
+func (r *cachedUserRepository) UpdatePartial(ctx context.Context, id int32, name *string, dob *time.Time) (*models.User, error) {
+	user, err := r.UserRepository.UpdatePartial(ctx, id, name, dob)
+	if err != nil {
+		return nil, err
+	}
+	r.invalidate(ctx, id)
+	return user, nil
+}
+
+func (r *cachedUserRepository) SetTimezone(ctx context.Context, id int32, timezone string) (*models.User, error) {
+	user, err := r.UserRepository.SetTimezone(ctx, id, timezone)
+	if err != nil {
+		return nil, err
+	}
+	r.invalidate(ctx, id)
+	return user, nil
+}
+
+func (r *cachedUserRepository) SetLocale(ctx context.Context, id int32, locale string) (*models.User, error) {
+	user, err := r.UserRepository.SetLocale(ctx, id, locale)
+	if err != nil {
+		return nil, err
+	}
+	r.invalidate(ctx, id)
+	return user, nil
+}
+
+func (r *cachedUserRepository) Anonymize(ctx context.Context, id int32, name string, dob time.Time) (*models.User, error) {
+	user, err := r.UserRepository.Anonymize(ctx, id, name, dob)
+	if err != nil {
+		return nil, err
+	}
+	r.invalidate(ctx, id)
+	return user, nil
+}
+
+// Transact invalidates every id written inside fn once the transaction ends,
+// committed or not; evicting before the commit would let a concurrent read
+// cache the old row again.
+func (r *cachedUserRepository) Transact(ctx context.Context, fn func(tx repository.UserRepository) error) error {
+	var written []int32
+	err := r.UserRepository.Transact(ctx, func(tx repository.UserRepository) error {
+		return fn(&writeRecorder{UserRepository: tx, written: &written})
+	})
+	for _, id := range written {
+		r.invalidate(ctx, id)
+	}
+	return err
+}
+
+// writeRecorder notes the ids written through a transaction's repository.
+type writeRecorder struct {
+	repository.UserRepository
+	written *[]int32
+}
+
+// Transact keeps recording through a nested call's repository.
+func (w *writeRecorder) Transact(ctx context.Context, fn func(tx repository.UserRepository) error) error {
+	return w.UserRepository.Transact(ctx, func(tx repository.UserRepository) error {
+		return fn(&writeRecorder{UserRepository: tx, written: w.written})
+	})
+}
+
+func (w *writeRecorder) Update(ctx context.Context, id int32, name string, dob time.Time) (*models.User, error) {
+	*w.written = append(*w.written, id)
+	return w.UserRepository.Update(ctx, id, name, dob)
+}
+
+func (w *writeRecorder) UpdatePartial(ctx context.Context, id int32, name *string, dob *time.Time) (*models.User, error) {
+	*w.written = append(*w.written, id)
+	return w.UserRepository.UpdatePartial(ctx, id, name, dob)
+}
+
+func (w *writeRecorder) SetTimezone(ctx context.Context, id int32, timezone string) (*models.User, error) {
+	*w.written = append(*w.written, id)
+	return w.UserRepository.SetTimezone(ctx, id, timezone)
+}
+
+func (w *writeRecorder) SetLocale(ctx context.Context, id int32, locale string) (*models.User, error) {
+	*w.written = append(*w.written, id)
+	return w.UserRepository.SetLocale(ctx, id, locale)
+}
+
+func (w *writeRecorder) Anonymize(ctx context.Context, id int32, name string, dob time.Time) (*models.User, error) {
+	*w.written = append(*w.written, id)
+	return w.UserRepository.Anonymize(ctx, id, name, dob)
+}
+
+func (w *writeRecorder) Delete(ctx context.Context, id int32) error {
+	*w.written = append(*w.written, id)
+	return w.UserRepository.Delete(ctx, id)
+}
+
+func (r *cachedUserRepository) Delete(ctx context.Context, id int32) error {
+	if err := r.UserRepository.Delete(ctx, id); err != nil {
+		return err
+	}
+	r.invalidate(ctx, id)
+	return nil
+}
+
+func (r *cachedUserRepository) invalidate(ctx context.Context, id int32) {
+	if err := r.cache.Delete(ctx, id); err != nil {
+		r.failed("delete", id, err)
+	}
+}
+
+// failed never fails the request: a cache that cannot be read or written
+// just means the next read goes to the database.
+func (r *cachedUserRepository) failed(op string, id int32, err error) {
+	r.metrics.Errors.Inc()
+	r.logger.Warn("User cache unavailable", zap.String("op", op), zap.Int32("id", id), zap.Error(err))
+}
@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/metrics"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/testutil"
+	"go.uber.org/zap"
+)
+
+// countingRepository records how many lookups reach the database.
+type countingRepository struct {
+	repository.UserRepository
+	gets int
+}
+
+func (r *countingRepository) GetById(ctx context.Context, id int32) (*models.User, error) {
+	r.gets++
+	return r.UserRepository.GetById(ctx, id)
+}
+
+func newCachedRepository(t *testing.T, c Cache) (repository.UserRepository, *countingRepository, *metrics.Cache) {
+	t.Helper()
+	base := &countingRepository{UserRepository: repository.NewMemoryUserRepository(clock.Fixed(time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC)))}
+	if _, err := base.Create(context.Background(), "Alice", time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	m := metrics.NewCache(prometheus.NewRegistry())
+	return NewUserRepository(base, c, m, zap.NewNop()), base, m
+}
+
+func TestCachedGetById(t *testing.T) {
+	c, _ := newTestLRU(10, time.Minute)
+	repo, base, m := newCachedRepository(t, c)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		user, err := repo.GetById(ctx, 1)
+		if err != nil || user.Name != "Alice" {
+			t.Fatalf("GetById = %+v, %v", user, err)
+		}
+	}
+	if base.gets != 1 {
+		t.Errorf("database lookups = %d, want 1", base.gets)
+	}
+	if hits, misses := promtestutil.ToFloat64(m.Hits), promtestutil.ToFloat64(m.Misses); hits != 2 || misses != 1 {
+		t.Errorf("hits = %v, misses = %v, want 2 and 1", hits, misses)
+	}
+
+	if _, err := repo.GetById(ctx, 99); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetById(99) err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestCachedWritesInvalidate(t *testing.T) {
+	c, _ := newTestLRU(10, time.Minute)
+	repo, _, _ := newCachedRepository(t, c)
+	ctx := context.Background()
+
+	if _, err := repo.GetById(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Update(ctx, 1, "Alicia", time.Date(1991, 3, 15, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	user, err := repo.GetById(ctx, 1)
+	if err != nil || user.Name != "Alicia" || user.DOB.Year() != 1991 {
+		t.Errorf("GetById after update = %+v, %v, want the updated row", user, err)
+	}
+
+	if err := repo.Delete(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetById(ctx, 1); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetById after delete err = %v, want ErrNotFound", err)
+	}
+}
+
+type brokenCache struct{}
+
+func (brokenCache) Get(ctx context.Context, id int32) (models.User, bool, error) {
+	return models.User{}, false, errors.New("connection refused")
+}
+
+func (brokenCache) Set(ctx context.Context, user models.User) error {
+	return errors.New("connection refused")
+}
+
+func (brokenCache) Delete(ctx context.Context, id int32) error {
+	return errors.New("connection refused")
+}
+
+func (brokenCache) Purge(ctx context.Context) error {
+	return errors.New("connection refused")
+}
+
+func TestCacheFailuresFallBackToDatabase(t *testing.T) {
+	repo, base, m := newCachedRepository(t, brokenCache{})
+	ctx := context.Background()
+
+	if user, err := repo.GetById(ctx, 1); err != nil || user.Name != "Alice" {
+		t.Fatalf("GetById = %+v, %v, want Alice from the database", user, err)
+	}
+	if _, err := repo.Update(ctx, 1, "Alicia", time.Date(1991, 3, 15, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Update failed because of the cache: %v", err)
+	}
+	if base.gets != 1 || promtestutil.ToFloat64(m.Errors) != 3 {
+		t.Errorf("database lookups = %d, cache errors = %v, want 1 and 3", base.gets, promtestutil.ToFloat64(m.Errors))
+	}
+}
+
+func TestCachedRepositoryNeverReturnsNilWithoutError(t *testing.T) {
+	c, _ := newTestLRU(10, time.Minute)
+	repo := NewUserRepository(repository.NewMemoryUserRepository(clock.Real()), c, metrics.NewCache(prometheus.NewRegistry()), zap.NewNop())
+	testutil.AssertNoNilResults(t, repo)
+}
+
+func TestCachedRepositoryTenantIsolation(t *testing.T) {
+	c, _ := newTestLRU(10, time.Minute)
+	repo := NewUserRepository(repository.NewMemoryUserRepository(clock.Real()), c, metrics.NewCache(prometheus.NewRegistry()), zap.NewNop())
+	testutil.AssertTenantIsolation(t, repo)
+}
+
+func TestCachedRepositoryTransactions(t *testing.T) {
+	c, _ := newTestLRU(10, time.Minute)
+	repo := NewUserRepository(repository.NewMemoryUserRepository(clock.Real()), c, metrics.NewCache(prometheus.NewRegistry()), zap.NewNop())
+	testutil.AssertTransactions(t, repo)
+}
+
+func TestCachedRepositoryAnonymization(t *testing.T) {
+	c, _ := newTestLRU(10, time.Minute)
+	repo := NewUserRepository(repository.NewMemoryUserRepository(clock.Real()), c, metrics.NewCache(prometheus.NewRegistry()), zap.NewNop())
+	testutil.AssertAnonymization(t, repo)
+}
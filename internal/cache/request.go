@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+)
+
+type requestScopeKey struct{}
+
+type scopedUser struct {
+	tenantID string
+	id       int32
+}
+
+// requestScope holds the users read during one request. A handler may read
+// through it from several goroutines.
+type requestScope struct {
+	mu    sync.Mutex
+	users map[scopedUser]models.User
+}
+
+// WithRequestScope returns a context whose user lookups a request-scoped
+// repository answers once each. The memo goes when ctx does.
+func WithRequestScope(ctx context.Context) context.Context {
+	return context.WithValue(ctx, requestScopeKey{}, &requestScope{users: make(map[scopedUser]models.User)})
+}
+
+func scopeFrom(ctx context.Context) *requestScope {
+	scope, _ := ctx.Value(requestScopeKey{}).(*requestScope)
+	return scope
+}
+
+func (s *requestScope) get(key scopedUser) (models.User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, ok := s.users[key]
+	return user, ok
+}
+
+func (s *requestScope) set(key scopedUser, user models.User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[key] = user
+}
+
+func (s *requestScope) forget(id int32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key := range s.users {
+		if key.id == id {
+			delete(s.users, key)
+		}
+	}
+}
+
+// requestScopedUserRepository serves GetById from the context's request
+// scope, when it has one, so a request that looks a user up more than once
+// reaches the store once. A write forgets the id, and reads inside a
+// transaction always go to the store.
+type requestScopedUserRepository struct {
+	repository.UserRepository
+}
+
+func NewRequestScopedUserRepository(repo repository.UserRepository) repository.UserRepository {
+	return &requestScopedUserRepository{UserRepository: repo}
+}
+
+func (r *requestScopedUserRepository) GetById(ctx context.Context, id int32) (*models.User, error) {
+	scope := scopeFrom(ctx)
+	if scope == nil {
+		return r.UserRepository.GetById(ctx, id)
+	}
+	key := scopedUser{tenantID: tenant.FromContext(ctx), id: id}
+	if user, ok := scope.get(key); ok {
+		return &user, nil
+	}
+
+	user, err := r.UserRepository.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	scope.set(key, *user)
+	return user, nil
+}
+
+func (r *requestScopedUserRepository) Update(ctx context.Context, id int32, name string, dob time.Time) (*models.User, error) {
+	defer r.forget(ctx, id)
+	return r.UserRepository.Update(ctx, id, name, dob)
+}
+
+func (r *requestScopedUserRepository) UpdatePartial(ctx context.Context, id int32, name *string, dob *time.Time) (*models.User, error) {
+	defer r.forget(ctx, id)
+	return r.UserRepository.UpdatePartial(ctx, id, name, dob)
+}
+
+func (r *requestScopedUserRepository) SetTimezone(ctx context.Context, id int32, timezone string) (*models.User, error) {
+	defer r.forget(ctx, id)
+	return r.UserRepository.SetTimezone(ctx, id, timezone)
+}
+
+func (r *requestScopedUserRepository) SetLocale(ctx context.Context, id int32, locale string) (*models.User, error) {
+	defer r.forget(ctx, id)
+	return r.UserRepository.SetLocale(ctx, id, locale)
+}
+
+func (r *requestScopedUserRepository) Anonymize(ctx context.Context, id int32, name string, dob time.Time) (*models.User, error) {
+	defer r.forget(ctx, id)
+	return r.UserRepository.Anonymize(ctx, id, name, dob)
+}
+
+func (r *requestScopedUserRepository) Delete(ctx context.Context, id int32) error {
+	defer r.forget(ctx, id)
+	return r.UserRepository.Delete(ctx, id)
+}
+
+func (r *requestScopedUserRepository) Transact(ctx context.Context, fn func(tx repository.UserRepository) error) error {
+	var written []int32
+	err := r.UserRepository.Transact(ctx, func(tx repository.UserRepository) error {
+		return fn(&writeRecorder{UserRepository: tx, written: &written})
+	})
+	for _, id := range written {
+		r.forget(ctx, id)
+	}
+	return err
+}
+
+func (r *requestScopedUserRepository) forget(ctx context.Context, id int32) {
+	if scope := scopeFrom(ctx); scope != nil {
+		scope.forget(id)
+	}
+}
@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+)
+
+func newRequestScopedRepository(t *testing.T) (repository.UserRepository, *countingRepository) {
+	t.Helper()
+	base := &countingRepository{UserRepository: repository.NewMemoryUserRepository(clock.Fixed(time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC)))}
+	if _, err := base.Create(context.Background(), "Alice", time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	return NewRequestScopedUserRepository(base), base
+}
+
+func TestRequestScopedGetById(t *testing.T) {
+	repo, base := newRequestScopedRepository(t)
+
+	ctx := WithRequestScope(context.Background())
+	if _, err := repo.GetById(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if user, err := repo.GetById(ctx, 1); err != nil || user.Name != "Alice" {
+				t.Errorf("GetById = %+v, %v", user, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if base.gets != 1 {
+		t.Errorf("database lookups = %d, want 1", base.gets)
+	}
+
+	next := WithRequestScope(context.Background())
+	if _, err := repo.GetById(next, 1); err != nil || base.gets != 2 {
+		t.Errorf("another request: database lookups = %d, want 2", base.gets)
+	}
+	repo.GetById(context.Background(), 1)
+	repo.GetById(context.Background(), 1)
+	if base.gets != 4 {
+		t.Errorf("without a scope: database lookups = %d, want 4", base.gets)
+	}
+}
+
+func TestRequestScopedWritesForget(t *testing.T) {
+	repo, base := newRequestScopedRepository(t)
+	ctx := WithRequestScope(context.Background())
+
+	if _, err := repo.GetById(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Update(ctx, 1, "Alicia", time.Date(1991, 3, 15, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	if user, err := repo.GetById(ctx, 1); err != nil || user.Name != "Alicia" || base.gets != 2 {
+		t.Errorf("GetById after update = %+v, %v after %d lookups, want the updated row", user, err, base.gets)
+	}
+
+	if err := repo.Transact(ctx, func(tx repository.UserRepository) error {
+		_, err := tx.SetTimezone(ctx, 1, "Europe/Paris")
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if user, err := repo.GetById(ctx, 1); err != nil || user.Timezone != "Europe/Paris" {
+		t.Errorf("GetById after a transaction = %+v, %v, want the new timezone", user, err)
+	}
+}
+
+func TestRequestScopedTenants(t *testing.T) {
+	repo, _ := newRequestScopedRepository(t)
+	ctx := WithRequestScope(context.Background())
+
+	if _, err := repo.GetById(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetById(tenant.WithID(ctx, "acme"), 1); err == nil {
+		t.Error("GetById for another tenant returned the memoized user")
+	}
+}
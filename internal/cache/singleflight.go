@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/metrics"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+	"golang.org/x/sync/singleflight"
+)
+
+// singleFlightUserRepository lets concurrent GetById calls for the same user
+// of the same tenant share one query. Writes, and everything else, go
+// straight to the wrapped repository.
+type singleFlightUserRepository struct {
+	repository.UserRepository
+	group   singleflight.Group
+	timeout time.Duration
+	metrics *metrics.SingleFlight
+}
+
+// NewSingleFlightUserRepository runs each shared query detached from the
+// caller that started it, bounded by timeout instead, so the callers waiting
+// on it are not failed by that one going away.
+func NewSingleFlightUserRepository(repo repository.UserRepository, timeout time.Duration, m *metrics.SingleFlight) repository.UserRepository {
+	return &singleFlightUserRepository{UserRepository: repo, timeout: timeout, metrics: m}
+}
+
+func (r *singleFlightUserRepository) GetById(ctx context.Context, id int32) (*models.User, error) {
+	key := tenant.FromContext(ctx) + "/" + strconv.FormatInt(int64(id), 10)
+	ran := false
+	results := r.group.DoChan(key, func() (any, error) {
+		ran = true
+		detached, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.timeout)
+		defer cancel()
+		return r.UserRepository.GetById(detached, id)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-results:
+		if !ran {
+			r.metrics.Collapsed.Inc()
+		}
+		shared, _ := result.Val.(*models.User)
+		if result.Err != nil || shared == nil {
+			return nil, result.Err
+		}
+		// Each caller gets a copy it may change without the others seeing.
+		user := *shared
+		return &user, nil
+	}
+}
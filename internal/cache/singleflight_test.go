@@ -0,0 +1,145 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/metrics"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+)
+
+// slowRepository holds every lookup until release is closed.
+type slowRepository struct {
+	repository.UserRepository
+	release chan struct{}
+	calls   atomic.Int32
+}
+
+func (r *slowRepository) GetById(ctx context.Context, id int32) (*models.User, error) {
+	r.calls.Add(1)
+	select {
+	case <-r.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return r.UserRepository.GetById(ctx, id)
+}
+
+func newSingleFlightRepository(t *testing.T) (repository.UserRepository, *slowRepository, *metrics.SingleFlight) {
+	t.Helper()
+	base := &slowRepository{
+		UserRepository: repository.NewMemoryUserRepository(clock.Fixed(time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC))),
+		release:        make(chan struct{}),
+	}
+	if _, err := base.Create(context.Background(), "Alice", time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	m := metrics.NewSingleFlight(prometheus.NewRegistry())
+	return NewSingleFlightUserRepository(base, time.Second, m), base, m
+}
+
+func TestSingleFlightSharesOneQuery(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		repo, base, m := newSingleFlightRepository(t)
+		ctx := context.Background()
+
+		var wg sync.WaitGroup
+		users := make([]*models.User, 100)
+		errs := make([]error, 100)
+		for i := range users {
+			wg.Go(func() {
+				users[i], errs[i] = repo.GetById(ctx, 1)
+			})
+		}
+		synctest.Wait()
+		if n := base.calls.Load(); n != 1 {
+			t.Fatalf("%d queries in flight for 100 identical reads, want 1", n)
+		}
+		close(base.release)
+		wg.Wait()
+
+		for i, user := range users {
+			if errs[i] != nil || user.Name != "Alice" {
+				t.Fatalf("read %d = %+v, %v", i, user, errs[i])
+			}
+		}
+		if users[0] == users[1] {
+			t.Error("two readers were handed the same *User")
+		}
+		if collapsed := promtestutil.ToFloat64(m.Collapsed); collapsed != 99 {
+			t.Errorf("collapsed = %v, want 99", collapsed)
+		}
+	})
+}
+
+func TestSingleFlightFollowerOutlivesLeader(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		repo, base, _ := newSingleFlightRepository(t)
+		leaderCtx, disconnect := context.WithCancel(context.Background())
+
+		var leaderErr, followerErr error
+		var follower *models.User
+		var wg sync.WaitGroup
+		wg.Go(func() { _, leaderErr = repo.GetById(leaderCtx, 1) })
+		synctest.Wait()
+		wg.Go(func() { follower, followerErr = repo.GetById(context.Background(), 1) })
+		synctest.Wait()
+
+		disconnect()
+		synctest.Wait()
+		close(base.release)
+		wg.Wait()
+
+		if !errors.Is(leaderErr, context.Canceled) {
+			t.Errorf("disconnected leader: err = %v, want context.Canceled", leaderErr)
+		}
+		if followerErr != nil || follower.Name != "Alice" {
+			t.Errorf("follower = %+v, %v; want Alice despite the leader leaving", follower, followerErr)
+		}
+		if n := base.calls.Load(); n != 1 {
+			t.Errorf("queries = %d, want 1", n)
+		}
+	})
+}
+
+func TestSingleFlightTimeout(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		repo, _, _ := newSingleFlightRepository(t)
+
+		// Nothing releases the query, so only its own timeout ends it.
+		if _, err := repo.GetById(context.Background(), 1); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("GetById of a stuck query: err = %v, want DeadlineExceeded", err)
+		}
+	})
+}
+
+func TestSingleFlightKeepsTenantsApart(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		repo, base, _ := newSingleFlightRepository(t)
+
+		var acmeErr error
+		var wg sync.WaitGroup
+		wg.Go(func() { _, _ = repo.GetById(context.Background(), 1) })
+		wg.Go(func() { _, acmeErr = repo.GetById(tenant.WithID(context.Background(), "acme"), 1) })
+		synctest.Wait()
+		if n := base.calls.Load(); n != 2 {
+			t.Errorf("queries = %d, want one per tenant", n)
+		}
+		close(base.release)
+		wg.Wait()
+
+		if !errors.Is(acmeErr, repository.ErrNotFound) {
+			t.Errorf("acme's read of a default user: err = %v, want ErrNotFound", acmeErr)
+		}
+	})
+}
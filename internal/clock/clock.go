@@ -0,0 +1,29 @@
+package clock
+
+import "time"
+
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func Real() Clock {
+	return realClock{}
+}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+type fixedClock struct {
+	now time.Time
+}
+
+func Fixed(now time.Time) Clock {
+	return fixedClock{now: now}
+}
+
+func (c fixedClock) Now() time.Time {
+	return c.now
+}
@@ -0,0 +1,115 @@
+// Package dblock provides locks that hold across every instance sharing a
+// Postgres database, for work only one of them should do at a time.
+package dblock
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"time"
+)
+
+// lockClass is the first key of every lock taken here, the second is the hash
+// of the lock's name, so these locks cannot meet the ones migrate or the
+// repositories take.
+const lockClass = 0x64626c6b
+
+// releaseTimeout bounds the unlock of a lock whose context has ended.
+const releaseTimeout = 5 * time.Second
+
+// ErrNotHeld is returned by Release when the session no longer holds the
+// lock: it was released already or its connection was lost.
+var ErrNotHeld = errors.New("advisory lock not held by this session")
+
+// Locker takes session-level advisory locks, each on a connection of its own
+// that it keeps out of the pool until the lock is released.
+type Locker struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *Locker {
+	return &Locker{db: db}
+}
+
+// Lock is a held advisory lock. It is released by Release or once the
+// context it was acquired with ends, whichever comes first, and always on
+// the session that took it.
+type Lock struct {
+	key  string
+	stop func() bool
+
+	mu sync.Mutex
+	// conn is nil once the lock is released.
+	conn *sql.Conn
+}
+
+// AcquireTryLock takes the lock named key if no other session holds it. It
+// returns a nil Lock, and no error, when one does.
+func (l *Locker) AcquireTryLock(ctx context.Context, key string) (*Lock, error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1, hashtext($2))`, lockClass, key).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !acquired {
+		conn.Close()
+		return nil, nil
+	}
+
+	lock := &Lock{key: key, conn: conn}
+	lock.stop = context.AfterFunc(ctx, func() { lock.release() })
+	return lock, nil
+}
+
+// Release unlocks the lock and gives its connection back. Releasing a lock
+// already released, by an earlier Release or its context, is ErrNotHeld.
+func (lock *Lock) Release() error {
+	lock.stop()
+	return lock.release()
+}
+
+func (lock *Lock) release() error {
+	lock.mu.Lock()
+	defer lock.mu.Unlock()
+	if lock.conn == nil {
+		return ErrNotHeld
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+	defer cancel()
+
+	var unlocked bool
+	err := lock.conn.QueryRowContext(ctx, `SELECT pg_advisory_unlock($1, hashtext($2))`, lockClass, lock.key).Scan(&unlocked)
+	if err == nil && !unlocked {
+		err = ErrNotHeld
+	}
+	if err != nil {
+		// The session may still hold the lock; it must not go back to the
+		// pool, where the next user of the connection would inherit it.
+		lock.conn.Raw(func(any) error { return driver.ErrBadConn })
+	}
+	lock.conn.Close()
+	lock.conn = nil
+	return err
+}
+
+// WithLock runs fn while holding the lock named key and reports whether it
+// ran: when another session holds the lock, fn does not run and WithLock
+// returns false and no error. fn's context ends if ctx does.
+func (l *Locker) WithLock(ctx context.Context, key string, fn func(ctx context.Context) error) (bool, error) {
+	lock, err := l.AcquireTryLock(ctx, key)
+	if err != nil || lock == nil {
+		return false, err
+	}
+	err = fn(ctx)
+	// Once ctx has ended the lock is already released.
+	if releaseErr := lock.Release(); err == nil && ctx.Err() == nil {
+		err = releaseErr
+	}
+	return true, err
+}
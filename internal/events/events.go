@@ -0,0 +1,64 @@
+// Package events delivers domain events, such as a user's birthday, to
+// whoever listens: a webhook in production, an in-memory hub in tests.
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Event is one occurrence, serialised as the webhook body.
+type Event struct {
+	Type     string    `json:"type"`
+	TenantID string    `json:"tenant_id"`
+	At       time.Time `json:"at"`
+	Data     any       `json:"data"`
+}
+
+// Publisher delivers an event. An error means it may not have arrived.
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// Hub fans events out to in-process subscribers.
+type Hub struct {
+	mu   sync.Mutex
+	subs map[chan Event]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{subs: make(map[chan Event]struct{})}
+}
+
+// Subscribe returns a channel holding up to buffer undelivered events and a
+// function that unsubscribes and closes it. Events that find the buffer full
+// are dropped for that subscriber.
+func (h *Hub) Subscribe(buffer int) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+	h.mu.Lock()
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subs, ch)
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+func (h *Hub) Publish(ctx context.Context, event Event) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHub(t *testing.T) {
+	hub := NewHub()
+	first, unsubscribe := hub.Subscribe(1)
+	second, _ := hub.Subscribe(1)
+
+	hub.Publish(context.Background(), Event{Type: "one"})
+	// second's buffer is full, so it misses this one rather than blocking.
+	hub.Publish(context.Background(), Event{Type: "two"})
+	if got := (<-first).Type; got != "one" {
+		t.Errorf("first subscriber got %q, want one", got)
+	}
+	if got := (<-second).Type; got != "one" {
+		t.Errorf("second subscriber got %q, want one", got)
+	}
+
+	unsubscribe()
+	unsubscribe()
+	if _, ok := <-first; ok {
+		t.Error("channel still open after unsubscribing")
+	}
+	hub.Publish(context.Background(), Event{Type: "three"})
+	if got := (<-second).Type; got != "three" {
+		t.Errorf("second subscriber got %q, want three", got)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	status := http.StatusNoContent
+	var got Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("request = %s with Content-Type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	webhook := NewWebhook(server.URL, time.Second)
+
+	event := Event{Type: "user.birthday", TenantID: "acme", At: time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC), Data: map[string]any{"age": 35.0}}
+	if err := webhook.Publish(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	if got.Type != event.Type || got.TenantID != "acme" || !got.At.Equal(event.At) || got.Data.(map[string]any)["age"] != 35.0 {
+		t.Errorf("webhook received %+v", got)
+	}
+
+	status = http.StatusBadGateway
+	if err := webhook.Publish(context.Background(), event); err == nil {
+		t.Error("Publish succeeded against a 502")
+	}
+}
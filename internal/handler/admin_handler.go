@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/srinivasarynh/age_calculator/config"
+	"github.com/srinivasarynh/age_calculator/internal/metrics"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/service"
+	"go.uber.org/zap"
+)
+
+type AdminHandler struct {
+	cfg     *config.Holder
+	users   service.UserService
+	logger  *zap.Logger
+	stats   prometheus.Gatherer
+	outbox  *service.OutboxDispatcher
+	backups *service.Backups
+}
+
+type AdminOption func(*AdminHandler)
+
+// WithStats makes Stats summarize g. Without it Stats reports an empty
+// registry.
+func WithStats(g prometheus.Gatherer) AdminOption {
+	return func(h *AdminHandler) {
+		h.stats = g
+	}
+}
+
+// WithOutbox serves the dead letters of d. Without it they are 501.
+func WithOutbox(d *service.OutboxDispatcher) AdminOption {
+	return func(h *AdminHandler) {
+		h.outbox = d
+	}
+}
+
+// WithBackups serves backups and restores through b. Without it they are 501.
+func WithBackups(b *service.Backups) AdminOption {
+	return func(h *AdminHandler) {
+		h.backups = b
+	}
+}
+
+func NewAdminHandler(cfg *config.Holder, users service.UserService, logger *zap.Logger, opts ...AdminOption) *AdminHandler {
+	h := &AdminHandler{cfg: cfg, users: users, logger: logger, stats: prometheus.NewRegistry()}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+func (h *AdminHandler) Config(c *fiber.Ctx) error {
+	return c.JSON(h.cfg.Load().Effective())
+}
+
+func (h *AdminHandler) ValidateDOBs(c *fiber.Ctx) error {
+	report, err := h.users.FindFutureDOBs(c.UserContext())
+	if err != nil {
+		return fail(h.logger, err, "Failed to validate DOBs")
+	}
+	return c.JSON(report)
+}
+
+func (h *AdminHandler) Stats(c *fiber.Ctx) error {
+	stats, err := metrics.Snapshot(h.stats, time.Now())
+	if err != nil {
+		return fail(h.logger, err, "Failed to gather statistics")
+	}
+	return c.JSON(stats)
+}
+
+// ValidationStats sums the fields requests were rejected for since the
+// process started.
+func (h *AdminHandler) ValidationStats(c *fiber.Ctx) error {
+	stats, err := metrics.ValidationSnapshot(h.stats)
+	if err != nil {
+		return fail(h.logger, err, "Failed to gather statistics")
+	}
+	return c.JSON(stats)
+}
+
+// DeadEvents lists the events the outbox gave up delivering, of every tenant.
+func (h *AdminHandler) DeadEvents(c *fiber.Ctx) error {
+	if h.outbox == nil {
+		return fail(h.logger, service.ErrOutboxDisabled, "Failed to list dead events")
+	}
+	dead, err := h.outbox.DeadEvents(c.UserContext())
+	if err != nil {
+		return fail(h.logger, err, "Failed to list dead events")
+	}
+	return c.JSON(dead)
+}
+
+// ReplayEvent queues a dead-lettered event for delivery again.
+func (h *AdminHandler) ReplayEvent(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid event ID",
+		})
+	}
+	if h.outbox == nil {
+		return fail(h.logger, service.ErrOutboxDisabled, "Failed to replay event")
+	}
+	if err := h.outbox.Replay(c.UserContext(), id); err != nil {
+		return fail(h.logger, err, "Failed to replay event")
+	}
+	return c.SendStatus(fiber.StatusAccepted)
+}
+
+// Backup streams a gzipped JSON document of every tenant's dataset. As with
+// the exports, a failure once the body has started cuts it short.
+func (h *AdminHandler) Backup(c *fiber.Ctx) error {
+	if h.backups == nil {
+		return fail(h.logger, service.ErrBackupsDisabled, "Failed to back up")
+	}
+	ctx := c.UserContext()
+	setExportHeaders(c, exportFile{
+		ContentType: "application/gzip",
+		Filename:    service.ExportFilename("backup", "json.gz", time.Now()),
+		Rows:        -1,
+	})
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		zw := gzip.NewWriter(w)
+		if err := h.backups.Write(ctx, zw); err != nil {
+			h.logger.Error("Failed to back up", zap.Error(err))
+			return
+		}
+		if err := zw.Close(); err != nil {
+			h.logger.Error("Failed to back up", zap.Error(err))
+		}
+	})
+	return nil
+}
+
+// Restore loads a backup, gzipped as Backup writes it or plain JSON, into a
+// database with no users; force=truncate replaces the users it has.
+func (h *AdminHandler) Restore(c *fiber.Ctx) error {
+	force := c.Query("force")
+	if force != "" && force != "truncate" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid query parameters",
+			"details": []models.FieldError{{
+				Field:   "force",
+				Rule:    "oneof",
+				Param:   "truncate",
+				Message: "force must be truncate",
+			}},
+		})
+	}
+	if h.backups == nil {
+		return fail(h.logger, service.ErrBackupsDisabled, "Failed to restore backup")
+	}
+
+	var body io.Reader = bytes.NewReader(c.Body())
+	if gzipped := bytes.HasPrefix(c.Body(), []byte{0x1f, 0x8b}); gzipped {
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			return fail(h.logger, service.ErrInvalidBackup, "Failed to restore backup")
+		}
+		defer zr.Close()
+		body = zr
+	}
+	report, err := h.backups.Restore(c.UserContext(), body, force == "truncate")
+	if err != nil {
+		return fail(h.logger, err, "Failed to restore backup")
+	}
+	return c.JSON(report)
+}
@@ -0,0 +1,217 @@
+package handler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/srinivasarynh/age_calculator/config"
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/events"
+	"github.com/srinivasarynh/age_calculator/internal/middleware"
+	"github.com/srinivasarynh/age_calculator/internal/mocks"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/service"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+func TestAdminConfig(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "hunter2")
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	app.Get("/admin/config", NewAdminHandler(config.NewHolder(cfg), nil, zap.NewNop()).Config)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/admin/config", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var body map[string]config.Setting
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+
+	password := body["DB_PASSWORD"]
+	if password.Value != "***" || password.Source != config.SourceEnv || !password.Secret {
+		t.Errorf("DB_PASSWORD = %+v, want masked env value", password)
+	}
+	if host := body["DB_HOST"]; host.Value != "localhost" || host.Source != config.SourceDefault {
+		t.Errorf("DB_HOST = %+v, want default localhost", host)
+	}
+}
+
+func TestAdminValidateDOBs(t *testing.T) {
+	invalid := false
+	users := mocks.NewUserService(t)
+	users.EXPECT().FindFutureDOBs(mock.Anything).Return(&models.DOBValidationResponse{
+		AsOf:  "2025-06-15",
+		Count: 1,
+		Users: []models.UserResponse{{ID: 4, Name: "Time Traveller", DOB: "2030-01-01", AgeValid: &invalid}},
+	}, nil).Once()
+	users.EXPECT().FindFutureDOBs(mock.Anything).Return(nil, errors.New("database unavailable")).Once()
+
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	app.Post("/admin/users/validate-dobs", NewAdminHandler(nil, users, zap.NewNop()).ValidateDOBs)
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/admin/users/validate-dobs", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := io.ReadAll(resp.Body)
+	want := `{"as_of":"2025-06-15","count":1,"users":[{"id":4,"name":"Time Traveller","dob":"2030-01-01","age_valid":false}]}`
+	if resp.StatusCode != fiber.StatusOK || string(raw) != want {
+		t.Errorf("response = %d %s, want 200 %s", resp.StatusCode, raw, want)
+	}
+
+	resp, err = app.Test(httptest.NewRequest("POST", "/admin/users/validate-dobs", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ = io.ReadAll(resp.Body)
+	if resp.StatusCode != fiber.StatusInternalServerError || string(raw) != `{"error":"Failed to validate DOBs"}` {
+		t.Errorf("response = %d %s, want 500 with the failure message", resp.StatusCode, raw)
+	}
+}
+
+func TestAdminDeadEvents(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	outbox := repository.NewMemoryUserRepository(clock.Fixed(now)).Outbox()
+	ctx := context.Background()
+	outbox.Enqueue(ctx, events.Event{Type: models.EventUserAnonymized, TenantID: "acme", At: now, Data: models.UserEvent{UserID: 7}})
+	event, err := outbox.Claim(ctx, now, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outbox.MarkDead(ctx, event.ID, "webhook answered 410", now)
+
+	dispatcher := service.NewOutboxDispatcher(outbox, events.NewHub(), zap.NewNop(), service.WithDispatcherClock(clock.Fixed(now)))
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	h := NewAdminHandler(nil, nil, zap.NewNop(), WithOutbox(dispatcher))
+	app.Get("/admin/events/dead", h.DeadEvents)
+	app.Post("/admin/events/:id/replay", h.ReplayEvent)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/admin/events/dead", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body models.DeadEventsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Events) != 1 || body.Events[0].ID != event.ID || body.Events[0].LastError != "webhook answered 410" {
+		t.Fatalf("dead events = %+v, want the dead-lettered event", body.Events)
+	}
+
+	replay := fmt.Sprintf("/admin/events/%d/replay", event.ID)
+	for _, tc := range []struct {
+		path string
+		want int
+	}{
+		{"/admin/events/abc/replay", fiber.StatusBadRequest},
+		{replay, fiber.StatusAccepted},
+		{replay, fiber.StatusNotFound},
+	} {
+		resp, err := app.Test(httptest.NewRequest("POST", tc.path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tc.want {
+			t.Errorf("POST %s = %d, want %d", tc.path, resp.StatusCode, tc.want)
+		}
+	}
+}
+
+func TestAdminDeadEventsWithoutOutbox(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	app.Get("/admin/events/dead", NewAdminHandler(nil, nil, zap.NewNop()).DeadEvents)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/admin/events/dead", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != fiber.StatusNotImplemented || string(raw) != `{"error":"The events outbox is not enabled"}` {
+		t.Errorf("response = %d %s, want 501 OUTBOX_DISABLED", resp.StatusCode, raw)
+	}
+}
+
+func TestAdminBackupAndRestore(t *testing.T) {
+	user := models.BackupUser{ID: 4, TenantID: "acme", Name: "Alice", DOB: "1990-05-10", CreatedAt: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), UpdatedAt: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)}
+	source := service.NewBackups(repository.NewMemoryBackupRepository(&models.Backup{Users: []models.BackupUser{user}}), 16, zap.NewNop())
+	target := repository.NewMemoryBackupRepository(nil)
+
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	app.Get("/admin/backup", NewAdminHandler(nil, nil, zap.NewNop(), WithBackups(source)).Backup)
+	app.Post("/admin/restore", NewAdminHandler(nil, nil, zap.NewNop(), WithBackups(service.NewBackups(target, 16, zap.NewNop()))).Restore)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/admin/backup", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gzipped, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != fiber.StatusOK || resp.Header.Get(fiber.HeaderContentType) != "application/gzip" {
+		t.Fatalf("backup = %d %s", resp.StatusCode, resp.Header.Get(fiber.HeaderContentType))
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(gzipped))
+	if err != nil {
+		t.Fatalf("backup is not gzipped: %v", err)
+	}
+	plain, _ := io.ReadAll(zr)
+	var backup models.Backup
+	if err := json.Unmarshal(plain, &backup); err != nil || backup.SchemaVersion != 16 || len(backup.Users) != 1 || backup.Users[0] != user {
+		t.Fatalf("backup = %s, %v", plain, err)
+	}
+
+	restore := func(query string, body []byte) (int, string) {
+		resp, err := app.Test(httptest.NewRequest("POST", "/admin/restore"+query, bytes.NewReader(body)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(raw)
+	}
+	if status, body := restore("", gzipped); status != fiber.StatusOK || body != `{"schema_version":16,"users":1,"birthday_notifications":0,"share_tokens":0}` {
+		t.Errorf("gzipped restore = %d %s", status, body)
+	}
+	if status, body := restore("", plain); status != fiber.StatusConflict || !strings.Contains(body, "force=truncate") {
+		t.Errorf("restore into users = %d %s, want 409 naming force=truncate", status, body)
+	}
+	if status, _ := restore("?force=truncate", plain); status != fiber.StatusOK {
+		t.Errorf("plain restore with force=truncate = %d, want 200", status)
+	}
+	if status, body := restore("?force=yes", plain); status != fiber.StatusBadRequest || !strings.Contains(body, `"field":"force"`) {
+		t.Errorf("force=yes = %d %s, want 400 on force", status, body)
+	}
+	if status, _ := restore("", []byte{0x1f, 0x8b, 0x00}); status != fiber.StatusBadRequest {
+		t.Errorf("truncated gzip = %d, want 400", status)
+	}
+}
+
+func TestAdminBackupWithoutBackups(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	app.Get("/admin/backup", NewAdminHandler(nil, nil, zap.NewNop()).Backup)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/admin/backup", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusNotImplemented {
+		t.Errorf("status = %d, want 501", resp.StatusCode)
+	}
+}
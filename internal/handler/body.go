@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+)
+
+// unknownFields lists the top-level keys of a JSON body that do not map onto
+// a field of out, so a misspelt field fails as itself instead of as a missing
+// required one. Keys match case-insensitively, as they do when decoding. It
+// only runs in strict mode, after BodyParser has accepted the body.
+func (h *UserHandler) unknownFields(c *fiber.Ctx, out any) []models.FieldError {
+	if !h.strictJSON || !isJSON(c) {
+		return nil
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(c.Body(), &raw); err != nil {
+		return nil
+	}
+	return unknownKeys(raw, out)
+}
+
+func unknownKeys(raw map[string]json.RawMessage, out any) []models.FieldError {
+	t := reflect.TypeOf(out)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var known []string
+	for i := 0; i < t.NumField(); i++ {
+		if name := clientFieldName(t.Field(i)); name != "" {
+			known = append(known, name)
+		}
+	}
+
+	var details []models.FieldError
+	for key := range raw {
+		if !containsFold(known, key) {
+			details = append(details, models.FieldError{
+				Field:   key,
+				Rule:    "unknown",
+				Message: key + " is not a recognized field",
+			})
+		}
+	}
+	sort.Slice(details, func(i, j int) bool { return details[i].Field < details[j].Field })
+	return details
+}
+
+// isJSON accepts application/json and structured types such as
+// application/merge-patch+json.
+func isJSON(c *fiber.Ctx) bool {
+	mediaType, _, _ := strings.Cut(c.Get(fiber.HeaderContentType), ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	return mediaType == fiber.MIMEApplicationJSON || strings.HasSuffix(mediaType, "+json")
+}
+
+func containsFold(names []string, s string) bool {
+	for _, name := range names {
+		if strings.EqualFold(name, s) {
+			return true
+		}
+	}
+	return false
+}
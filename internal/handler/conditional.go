@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// setLastModified writes the Last-Modified header, truncated to the whole
+// second an HTTP date can carry. A zero time leaves the header unset.
+func setLastModified(c *fiber.Ctx, lastModified time.Time) {
+	if lastModified.IsZero() {
+		return
+	}
+	c.Set(fiber.HeaderLastModified, lastModified.UTC().Format(http.TimeFormat))
+}
+
+// notModified reports whether If-Modified-Since makes a 304 correct: the
+// resource must not have changed after the given date at second granularity,
+// so a change 0.9s past the date still counts as unmodified and one a full
+// second later does not. A missing or unparseable header never matches.
+func notModified(c *fiber.Ctx, lastModified time.Time) bool {
+	header := c.Get(fiber.HeaderIfModifiedSince)
+	if header == "" || lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(header)
+	if err != nil {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(since)
+}
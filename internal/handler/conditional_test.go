@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestNotModified(t *testing.T) {
+	stored := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		lastModified time.Time
+		header       string
+		want         bool
+	}{
+		{name: "no header", lastModified: stored, want: false},
+		{name: "same second", lastModified: stored, header: "Sun, 15 Jun 2025 12:00:00 GMT", want: true},
+		{name: "sub-second change is not newer", lastModified: stored.Add(900 * time.Millisecond), header: "Sun, 15 Jun 2025 12:00:00 GMT", want: true},
+		{name: "one second newer", lastModified: stored.Add(time.Second), header: "Sun, 15 Jun 2025 12:00:00 GMT", want: false},
+		{name: "client copy is newer", lastModified: stored, header: "Sun, 15 Jun 2025 12:00:01 GMT", want: true},
+		{name: "client copy one second older", lastModified: stored, header: "Sun, 15 Jun 2025 11:59:59 GMT", want: false},
+		{name: "unparseable date", lastModified: stored, header: "yesterday", want: false},
+		{name: "unknown modification time", header: "Sun, 15 Jun 2025 12:00:00 GMT", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			var got bool
+			app.Get("/", func(c *fiber.Ctx) error {
+				got = notModified(c, tt.lastModified)
+				return nil
+			})
+
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.header != "" {
+				req.Header.Set("If-Modified-Since", tt.header)
+			}
+			if _, err := app.Test(req); err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("notModified = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
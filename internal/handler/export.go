@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// HeaderTotalRows is the number of rows an export holds, sent only when
+	// it is known before the body, as for a job's finished file.
+	HeaderTotalRows = "X-Total-Rows"
+	// HeaderExportFilters is the query string of the filters that selected an
+	// export's rows, empty when none did.
+	HeaderExportFilters = "X-Export-Filters"
+)
+
+// exportFile describes an export attachment. Rows is negative when the file
+// is streamed and its length unknown.
+type exportFile struct {
+	ContentType string
+	Filename    string
+	Rows        int
+	Filters     url.Values
+}
+
+// setExportHeaders writes the headers of every export response. Neither the
+// filename nor the filters can break out of their header: the filename keeps
+// only letters, digits, dots, dashes and underscores, and the filters are
+// query-escaped.
+func setExportHeaders(c *fiber.Ctx, f exportFile) {
+	c.Set(fiber.HeaderContentType, f.ContentType)
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+sanitizeFilename(f.Filename)+`"`)
+	if f.Rows >= 0 {
+		c.Set(HeaderTotalRows, strconv.Itoa(f.Rows))
+	}
+	c.Set(HeaderExportFilters, f.Filters.Encode())
+}
+
+func sanitizeFilename(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, name)
+	if strings.Trim(name, "._") == "" {
+		return "export"
+	}
+	return name
+}
@@ -0,0 +1,19 @@
+package handler
+
+import "testing"
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"users-export-20250115-1030.csv", "users-export-20250115-1030.csv"},
+		{"users\"\r\nSet-Cookie: a=b.csv", "users___Set-Cookie__a_b.csv"},
+		{"../../etc/passwd", ".._.._etc_passwd"},
+		{"Müller.csv", "M_ller.csv"},
+		{"", "export"},
+		{"..", "export"},
+	}
+	for _, tt := range tests {
+		if got := sanitizeFilename(tt.in); got != tt.want {
+			t.Errorf("sanitizeFilename(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,546 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/srinivasarynh/age_calculator/config"
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/handler"
+	"github.com/srinivasarynh/age_calculator/internal/metrics"
+	"github.com/srinivasarynh/age_calculator/internal/middleware"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/routes"
+	"github.com/srinivasarynh/age_calculator/internal/service"
+	"github.com/srinivasarynh/age_calculator/internal/testutil"
+	"go.uber.org/zap"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+var goldenNow = time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+
+type failingRepository struct {
+	repository.UserRepository
+}
+
+func (failingRepository) List(ctx context.Context, query repository.ListQuery) ([]models.User, error) {
+	return nil, errors.New("database unavailable")
+}
+
+func (failingRepository) Count(ctx context.Context, filter repository.UserFilter) (int64, error) {
+	return 0, errors.New("database unavailable")
+}
+
+// newGoldenApp serves v1 and v2 from repo at goldenNow, behind any
+// middleware in use.
+func newGoldenApp(t testing.TB, repo repository.UserRepository, use ...fiber.Handler) *fiber.App {
+	t.Helper()
+	fixed := clock.Fixed(goldenNow)
+	svc := service.NewUserService(repo, zap.NewNop(), service.WithClock(fixed))
+
+	tenant := middleware.Tenant(config.NewHolder(&config.Config{}))
+
+	// v2 is mounted beside v1 as in production, and must leave every v1
+	// golden file as it was.
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	for _, handler := range use {
+		app.Use(handler)
+	}
+	routes.SetupRoutes(app, handler.NewUserHandler(svc, zap.NewNop(), handler.WithClock(fixed)), testAdminGuard, tenant, routes.CachePolicies{})
+	routes.SetupV2Routes(app, handler.NewUserHandler(svc, zap.NewNop(), handler.WithClock(fixed), handler.WithDateObjects(true)), tenant, routes.CachePolicies{})
+	return app
+}
+
+func seededRepository(t *testing.T) repository.UserRepository {
+	t.Helper()
+	repo := repository.NewMemoryUserRepository(clock.Fixed(goldenNow))
+	testutil.MustLoad(t, repo, "testdata/fixtures/users.yaml")
+	return repo
+}
+
+func TestGoldenResponses(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		body        string
+		contentType string
+		repo        func(t *testing.T) repository.UserRepository
+		status      int
+	}{
+		{name: "get_user", method: "GET", target: "/api/v1/users/1", status: fiber.StatusOK},
+		{name: "get_user_age_months", method: "GET", target: "/api/v1/users/1?include_age_months=true", status: fiber.StatusOK},
+		{name: "error_invalid_age_months", method: "GET", target: "/api/v1/users/1?include_age_months=maybe", status: fiber.StatusBadRequest},
+		{name: "get_user_milestones", method: "GET", target: "/api/v1/users/2?include=milestones", status: fiber.StatusOK},
+		{name: "get_user_progress", method: "GET", target: "/api/v1/users/1?include=progress,milestones", status: fiber.StatusOK},
+		{name: "error_access_tracking_disabled", method: "GET", target: "/api/v1/users/1?include=access_count", status: fiber.StatusNotImplemented},
+		{name: "error_invalid_include", method: "GET", target: "/api/v1/users/1?include=zodiac", status: fiber.StatusBadRequest},
+		{name: "age_cohorts", method: "GET", target: "/api/v1/users/stats/cohorts?bounds=1,30", status: fiber.StatusOK},
+		{name: "error_invalid_cohort_bounds", method: "GET", target: "/api/v1/users/stats/cohorts?bounds=30,18", status: fiber.StatusBadRequest},
+		{name: "list_users", method: "GET", target: "/api/v1/users?page=1&page_size=2", status: fiber.StatusOK},
+		{name: "create_user", method: "POST", target: "/api/v1/users", body: `{"name":"Dave","dob":"1985-12-01"}`, status: fiber.StatusCreated},
+		{name: "update_user", method: "PUT", target: "/api/v1/users/2", body: `{"name":"Bobby","dob":"2000-02-29"}`, status: fiber.StatusOK},
+		{name: "patch_user", method: "PATCH", target: "/api/v1/users/2", body: `{"name":"Bobby"}`, contentType: "application/merge-patch+json", status: fiber.StatusOK},
+		{name: "error_invalid_body", method: "POST", target: "/api/v1/users", body: `{"name":`, status: fiber.StatusBadRequest},
+		{name: "error_validation", method: "POST", target: "/api/v1/users", body: `{"name":"A","dob":"1990/05/10"}`, status: fiber.StatusBadRequest},
+		{name: "error_invalid_id", method: "GET", target: "/api/v1/users/abc", status: fiber.StatusBadRequest},
+		{name: "error_not_found", method: "GET", target: "/api/v1/users/404", status: fiber.StatusNotFound},
+		{name: "error_invalid_pagination", method: "GET", target: "/api/v1/users?page_size=500", status: fiber.StatusBadRequest},
+		{name: "error_invalid_cursor", method: "GET", target: "/api/v1/users?cursor=bogus", status: fiber.StatusBadRequest},
+		{name: "error_update_not_found", method: "PUT", target: "/api/v1/users/404", body: `{"name":"Nobody","dob":"1990-01-01"}`, status: fiber.StatusNotFound},
+		{name: "error_delete_not_found", method: "DELETE", target: "/api/v1/users/404", status: fiber.StatusNotFound},
+		{name: "error_unsupported_media_type", method: "POST", target: "/api/v1/users", body: `name=Dave`, contentType: "text/plain", status: fiber.StatusUnsupportedMediaType},
+		{
+			name:   "error_internal",
+			method: "GET",
+			target: "/api/v1/users",
+			repo: func(t *testing.T) repository.UserRepository {
+				return failingRepository{}
+			},
+			status: fiber.StatusInternalServerError,
+		},
+		{name: "error_route_not_found", method: "GET", target: "/api/v1/nothing", status: fiber.StatusNotFound},
+
+		{name: "v2_get_user", method: "GET", target: "/api/v2/users/1", status: fiber.StatusOK},
+		{name: "v2_list_users", method: "GET", target: "/api/v2/users?page=1&page_size=2", status: fiber.StatusOK},
+		{name: "v2_create_user", method: "POST", target: "/api/v2/users", body: `{"name":"Dave","dob":"1985-12-01"}`, status: fiber.StatusCreated},
+		{name: "v2_patch_user", method: "PATCH", target: "/api/v2/users/2", body: `{"name":"Bobby"}`, contentType: "application/merge-patch+json", status: fiber.StatusOK},
+		{name: "v2_error_validation", method: "POST", target: "/api/v2/users", body: `{"name":"A","dob":"1990/05/10"}`, status: fiber.StatusBadRequest},
+		{name: "v2_error_semantic", method: "GET", target: "/api/v2/users?min_age=40&max_age=30", status: fiber.StatusUnprocessableEntity},
+		{name: "v2_error_invalid_id", method: "GET", target: "/api/v2/users/abc", status: fiber.StatusBadRequest},
+		{name: "v2_error_not_found", method: "GET", target: "/api/v2/users/404", status: fiber.StatusNotFound},
+		{name: "v2_error_unsupported_media_type", method: "POST", target: "/api/v2/users", body: `name=Dave`, contentType: "text/plain", status: fiber.StatusUnsupportedMediaType},
+		{
+			name:   "v2_error_internal",
+			method: "GET",
+			target: "/api/v2/users",
+			repo: func(t *testing.T) repository.UserRepository {
+				return failingRepository{}
+			},
+			status: fiber.StatusInternalServerError,
+		},
+		{name: "v2_error_route_not_found", method: "GET", target: "/api/v2/nothing", status: fiber.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newRepo := seededRepository
+			if tt.repo != nil {
+				newRepo = tt.repo
+			}
+			app := newGoldenApp(t, newRepo(t))
+
+			var reader io.Reader
+			if tt.body != "" {
+				reader = strings.NewReader(tt.body)
+			}
+			req := httptest.NewRequest(tt.method, tt.target, reader)
+			contentType := "application/json"
+			if tt.contentType != "" {
+				contentType = tt.contentType
+			}
+			req.Header.Set("Content-Type", contentType)
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+
+			got, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertGolden(t, tt.name, got)
+		})
+	}
+}
+
+// TestGoldenEnvelopeResponses is TestGoldenResponses with RESPONSE_ENVELOPE
+// on, for a sample of the same requests.
+func TestGoldenEnvelopeResponses(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		repo   func(t *testing.T) repository.UserRepository
+		status int
+	}{
+		{name: "get_user", method: "GET", target: "/api/v1/users/1", status: fiber.StatusOK},
+		{name: "list_users", method: "GET", target: "/api/v1/users?page=1&page_size=2", status: fiber.StatusOK},
+		{name: "create_user", method: "POST", target: "/api/v1/users", body: `{"name":"Dave","dob":"1985-12-01"}`, status: fiber.StatusCreated},
+		{name: "error_validation", method: "POST", target: "/api/v1/users", body: `{"name":"A","dob":"1990/05/10"}`, status: fiber.StatusBadRequest},
+		{name: "error_semantic", method: "GET", target: "/api/v1/users?min_age=40&max_age=30", status: fiber.StatusUnprocessableEntity},
+		{name: "error_not_found", method: "GET", target: "/api/v1/users/404", status: fiber.StatusNotFound},
+		{
+			name:   "error_internal",
+			method: "GET",
+			target: "/api/v1/users",
+			repo: func(t *testing.T) repository.UserRepository {
+				return failingRepository{}
+			},
+			status: fiber.StatusInternalServerError,
+		},
+		{name: "error_route_not_found", method: "GET", target: "/api/v1/nothing", status: fiber.StatusNotFound},
+		{name: "v2_get_user", method: "GET", target: "/api/v2/users/1", status: fiber.StatusOK},
+		{name: "v2_error_not_found", method: "GET", target: "/api/v2/users/404", status: fiber.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newRepo := seededRepository
+			if tt.repo != nil {
+				newRepo = tt.repo
+			}
+			app := newGoldenApp(t, newRepo(t), middleware.RequestID(), middleware.Envelope(clock.Fixed(goldenNow), true))
+
+			var reader io.Reader
+			if tt.body != "" {
+				reader = strings.NewReader(tt.body)
+			}
+			req := httptest.NewRequest(tt.method, tt.target, reader)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Request-ID", "req-golden")
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+
+			got, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertGolden(t, "envelope_"+tt.name, got)
+		})
+	}
+}
+
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	assertGoldenFile(t, name+".json", got)
+}
+
+// assertGoldenFile compares got with testdata/file.
+func assertGoldenFile(t *testing.T, file string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", file)
+
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("response differs from %s\n got: %s\nwant: %s", path, got, want)
+	}
+}
+
+func TestBirthdayCalendar(t *testing.T) {
+	repo := seededRepository(t)
+	ctx := context.Background()
+	long, _ := repo.Create(ctx, "Zoë O'Brien; Jr., née Müller-Lüdenscheidt van der Achterberg-Ødegård", time.Date(1985, 6, 15, 0, 0, 0, 0, time.UTC))
+	// At goldenNow, noon UTC on Jun 15, Jun 15 has just ended in Tonga.
+	repo.SetTimezone(ctx, long.ID, "Pacific/Tongatapu")
+	app := newGoldenApp(t, repo)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/users/birthdays.ics", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	if got := resp.Header.Get(fiber.HeaderContentType); got != "text/calendar; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := resp.Header.Get(fiber.HeaderContentDisposition); got != `attachment; filename="birthdays-20250615-1200.ics"` {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+	if got := resp.Header.Get(handler.HeaderExportFilters); got != "" || resp.Header.Get(handler.HeaderTotalRows) != "" {
+		t.Errorf("export headers of an unfiltered stream = %q, %q; want filters empty and no row count", got, resp.Header.Get(handler.HeaderTotalRows))
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertGoldenFile(t, "birthdays.ics", body)
+
+	lines := strings.Split(strings.TrimSuffix(string(body), "\r\n"), "\r\n")
+	for _, line := range lines {
+		if len(line) > 75 || strings.ContainsAny(line, "\r\n") {
+			t.Errorf("line of %d octets: %q", len(line), line)
+		}
+	}
+	unfolded := strings.ReplaceAll(string(body), "\r\n ", "")
+	if want := `SUMMARY:Zoë O'Brien\; Jr.\, née Müller-Lüdenscheidt van der Achterberg-Ødegård's birthday (turns 41)`; !strings.Contains(unfolded, want+"\r\n") {
+		t.Errorf("unfolded calendar lacks %q", want)
+	}
+
+	status, filtered := doRawRequest(t, app, "/api/v1/users/birthdays.ics?name=bo&min_age=20")
+	if status != fiber.StatusOK || strings.Count(filtered, "BEGIN:VEVENT") != 1 || !strings.Contains(filtered, "SUMMARY:Bob's birthday") {
+		t.Errorf("filtered calendar: status = %d, body = %s", status, filtered)
+	}
+	if status, _ := doRawRequest(t, app, "/api/v1/users/birthdays.ics?name_fuzzy=bob"); status != fiber.StatusBadRequest {
+		t.Errorf("name_fuzzy: status = %d, want 400", status)
+	}
+	if status, _ := doRawRequest(t, app, "/api/v1/users/birthdays.ics?min_age=old"); status != fiber.StatusBadRequest {
+		t.Errorf("bad min_age: status = %d, want 400", status)
+	}
+}
+
+func doRawRequest(t *testing.T, app *fiber.App, target string) (int, string) {
+	t.Helper()
+	resp, err := app.Test(httptest.NewRequest("GET", target, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func TestVCard(t *testing.T) {
+	repo := seededRepository(t)
+	repo.Create(context.Background(), "Smith, Jane; \"JJ\"\\ Ødegård-Lüdenscheidt the Third of Her Name, Keeper of the Realm", time.Date(1975, 12, 24, 0, 0, 0, 0, time.UTC))
+	app := newGoldenApp(t, repo)
+
+	tests := []struct {
+		name, target, golden, filename string
+	}{
+		{name: "version 4", target: "/api/v1/users/4/vcard", golden: "user_v4.vcf", filename: "user-4.vcf"},
+		{name: "version 3", target: "/api/v1/users/4/vcard?version=3", golden: "user_v3.vcf", filename: "user-4.vcf"},
+		{name: "export version 4", target: "/api/v1/users/export?format=vcf", golden: "users_v4.vcf", filename: "users-export-20250615-1200.vcf"},
+		{name: "export version 3", target: "/api/v1/users/export?format=vcf&version=3&name=o", golden: "users_v3.vcf", filename: "users-export-20250615-1200.vcf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest("GET", tt.target, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != fiber.StatusOK {
+				t.Fatalf("status = %d, want 200", resp.StatusCode)
+			}
+			if got := resp.Header.Get(fiber.HeaderContentType); got != "text/vcard; charset=utf-8" {
+				t.Errorf("Content-Type = %q", got)
+			}
+			if got, want := resp.Header.Get(fiber.HeaderContentDisposition), `attachment; filename="`+tt.filename+`"`; got != want {
+				t.Errorf("Content-Disposition = %q, want %q", got, want)
+			}
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertGoldenFile(t, tt.golden, body)
+		})
+	}
+
+	for _, target := range []string{"/api/v1/users/1/vcard?version=2", "/api/v1/users/1/vcard?version=four", "/api/v1/users/export?format=xml"} {
+		if status, _ := doRawRequest(t, app, target); status != fiber.StatusBadRequest {
+			t.Errorf("GET %s: status = %d, want 400", target, status)
+		}
+	}
+	if status, _ := doRawRequest(t, app, "/api/v1/users/404/vcard"); status != fiber.StatusNotFound {
+		t.Errorf("vCard of a missing user: status = %d, want 404", status)
+	}
+	if status, body := doRawRequest(t, app, "/api/v1/users/export?name=bob"); status != fiber.StatusOK || body != "id,name,dob,age\n2,Bob,2000-02-29,25\n" {
+		t.Errorf("CSV export: status = %d, body = %q", status, body)
+	}
+}
+
+// TestExportHeadersEscapeFilters sends a name filter that would start a new
+// header if it were echoed as is.
+func TestExportHeadersEscapeFilters(t *testing.T) {
+	app := newGoldenApp(t, seededRepository(t))
+
+	for _, target := range []string{
+		"/api/v1/users/export?format=ndjson&name=o%0D%0AX-Injected:%201",
+		"/api/v1/users/birthdays.ics?name=o%0D%0AX-Injected:%201&min_age=20",
+	} {
+		resp, err := app.Test(httptest.NewRequest("GET", target, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("GET %s: status = %d, want 200", target, resp.StatusCode)
+		}
+		if got := resp.Header.Get("X-Injected"); got != "" {
+			t.Errorf("GET %s: a filter value set header X-Injected = %q", target, got)
+		}
+		filters, err := url.ParseQuery(resp.Header.Get(handler.HeaderExportFilters))
+		if err != nil || filters.Get("name") != "o\r\nX-Injected: 1" {
+			t.Errorf("GET %s: X-Export-Filters = %q, want the name filter escaped", target, resp.Header.Get(handler.HeaderExportFilters))
+		}
+	}
+}
+
+func TestXLSXExport(t *testing.T) {
+	resp, err := newGoldenApp(t, seededRepository(t)).Test(httptest.NewRequest("GET", "/api/v1/users/export?format=xlsx", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	if got := resp.Header.Get(fiber.HeaderContentType); got != "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got, want := resp.Header.Get(fiber.HeaderContentDisposition), `attachment; filename="users-export-20250615-1200.xlsx"`; got != want {
+		t.Errorf("Content-Disposition = %q, want %q", got, want)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sheet := testutil.ReadXLSX(t, body)
+	if sheet.FrozenRows != 1 {
+		t.Errorf("frozen rows = %d, want 1", sheet.FrozenRows)
+	}
+	want := [][]string{
+		{"id", "name", "dob", "age"},
+		{"1", "Alice", "33003", "35"},
+		{"2", "Bob", "36585", "25"},
+		{"3", "Carol", "45658", "0"},
+	}
+	if len(sheet.Rows) != len(want) {
+		t.Fatalf("rows = %d, want %d", len(sheet.Rows), len(want))
+	}
+	for i, row := range sheet.Rows {
+		var got []string
+		for _, cell := range row {
+			got = append(got, cell.Value+cell.Text)
+		}
+		if strings.Join(got, ",") != strings.Join(want[i], ",") {
+			t.Errorf("row %d = %v, want %v", i+1, got, want[i])
+		}
+	}
+	if dob := sheet.Rows[1][2]; dob.Type != "" || dob.Style == 0 {
+		t.Errorf("dob cell = %+v, want a number formatted as a date", dob)
+	}
+}
+
+func TestXLSXExportRowLimit(t *testing.T) {
+	svc := service.NewUserService(seededRepository(t), zap.NewNop(), service.WithClock(clock.Fixed(goldenNow)))
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	routes.SetupRoutes(app, handler.NewUserHandler(svc, zap.NewNop(), handler.WithXLSXMaxRows(2)), testAdminGuard, middleware.Tenant(config.NewHolder(&config.Config{})), routes.CachePolicies{})
+
+	status, body := doRawRequest(t, app, "/api/v1/users/export?format=xlsx")
+	if status != fiber.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want 413", status)
+	}
+	var got struct {
+		Details []models.FieldError `json:"details"`
+	}
+	if err := json.Unmarshal([]byte(body), &got); err != nil || len(got.Details) != 1 || got.Details[0].Rule != "max_rows" || got.Details[0].Param != "2" {
+		t.Errorf("body = %s, want a max_rows detail with param 2", body)
+	}
+
+	// A filter that leaves fewer rows than the limit is served.
+	if status, _ := doRawRequest(t, app, "/api/v1/users/export?format=xlsx&name=bob"); status != fiber.StatusOK {
+		t.Errorf("filtered export: status = %d, want 200", status)
+	}
+}
+
+// zeroNumbers replaces every number in a decoded JSON document with 0, leaving
+// only its shape.
+func zeroNumbers(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			v[k] = zeroNumbers(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = zeroNumbers(e)
+		}
+	case float64:
+		return 0
+	}
+	return v
+}
+
+func TestAdminStats(t *testing.T) {
+	registry := metrics.NewRegistry()
+	metrics.NewDBPool(registry).Observe(sql.DBStats{OpenConnections: 3, InUse: 1, Idle: 2, WaitCount: 4, WaitDuration: time.Second})
+	userCache := metrics.NewCache(registry)
+	userCache.Hits.Add(3)
+	userCache.Misses.Inc()
+
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	app.Use(middleware.Metrics(metrics.NewHTTP(registry)))
+	pass := func(c *fiber.Ctx) error { return c.Next() }
+	routes.SetupAdminRoutes(app, handler.NewAdminHandler(nil, nil, zap.NewNop(), handler.WithStats(registry)), pass, pass, routes.CachePolicies{})
+
+	snapshot := func() (metrics.Stats, []byte) {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest("GET", "/admin/stats", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, _ := io.ReadAll(resp.Body)
+		var stats metrics.Stats
+		if resp.StatusCode != fiber.StatusOK || json.Unmarshal(raw, &stats) != nil {
+			t.Fatalf("GET /admin/stats = %d %s", resp.StatusCode, raw)
+		}
+		return stats, raw
+	}
+	app.Test(httptest.NewRequest("GET", "/nothing", nil))
+	first, _ := snapshot()
+	second, raw := snapshot()
+
+	if first.HTTP.Requests != 1 || first.HTTP.ByClass["4xx"] != 1 || first.HTTP.InFlight != 1 {
+		t.Errorf("first HTTP stats = %+v, want the 404 counted and this request in flight", first.HTTP)
+	}
+	if second.HTTP.Requests != 2 || second.HTTP.ByClass["2xx"] != 1 {
+		t.Errorf("second HTTP stats = %+v, want the first snapshot counted", second.HTTP)
+	}
+	if second.UptimeSeconds <= 0 || second.UptimeSeconds < first.UptimeSeconds || second.Runtime.GC.Cycles < first.Runtime.GC.Cycles {
+		t.Errorf("uptime %v then %v, GC cycles %d then %d; want positive and not decreasing",
+			first.UptimeSeconds, second.UptimeSeconds, first.Runtime.GC.Cycles, second.Runtime.GC.Cycles)
+	}
+	if second.Runtime.Goroutines <= 0 || second.Runtime.HeapInUseBytes <= 0 || second.Runtime.GC.PauseTotalSeconds < 0 {
+		t.Errorf("runtime stats = %+v", second.Runtime)
+	}
+	if db := second.DB; db == nil || *db != (metrics.DBStats{Open: 3, InUse: 1, Idle: 2, WaitCount: 4, WaitDurationSeconds: 1}) {
+		t.Errorf("db stats = %+v", db)
+	}
+	if c := second.Cache; c == nil || c.HitRatio == nil || *c.HitRatio != 0.75 {
+		t.Errorf("cache stats = %+v, want a 0.75 hit ratio", c)
+	}
+
+	var shape any
+	json.Unmarshal(raw, &shape)
+	got, _ := json.Marshal(zeroNumbers(shape))
+	assertGolden(t, "admin_stats", got)
+}
@@ -0,0 +1,494 @@
+package handler
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/srinivasarynh/age_calculator/internal/middleware"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/service"
+)
+
+type graphQLRequest struct {
+	Query         string         `json:"query"`
+	Variables     map[string]any `json:"variables"`
+	OperationName string         `json:"operationName"`
+}
+
+// GraphQL answers a GraphQL query or mutation over the same service as the
+// REST routes. Like any GraphQL server it answers 200 once the request is
+// read, with failures in "errors", each carrying the REST error's code, and
+// details when there are any, in its extensions.
+func (h *UserHandler) GraphQL(c *fiber.Ctx) error {
+	var req graphQLRequest
+	if err := c.BodyParser(&req); err != nil || req.Query == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Request body must be a JSON object with a query",
+		})
+	}
+
+	h.graphQLOnce.Do(func() { h.graphQL = h.graphQLSchema() })
+	result := graphql.Do(graphql.Params{
+		Schema:         h.graphQL,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        c.UserContext(),
+	})
+	return c.JSON(result)
+}
+
+// graphQLError is a resolver's error as the client sees it: the message and
+// code StructuredErrors would send for it.
+type graphQLError struct {
+	body middleware.StructuredError
+}
+
+func (e *graphQLError) Error() string { return e.body.Message }
+
+func (e *graphQLError) Extensions() map[string]any {
+	extensions := map[string]any{"code": e.body.Code}
+	if len(e.body.Details) > 0 {
+		extensions["details"] = e.body.Details
+	}
+	return extensions
+}
+
+func (h *UserHandler) graphQLFail(err error, message string) error {
+	return &graphQLError{middleware.DescribeError(fail(h.logger, err, message))}
+}
+
+// calculation is the source of an AgeCalculation; each field is worked out
+// only when it is selected.
+type calculation struct {
+	dob, asOf time.Time
+	species   string
+}
+
+// graphQLSchema builds the schema. Its resolvers close over h, so it is built
+// once per handler, on the first request.
+func (h *UserHandler) graphQLSchema() graphql.Schema {
+	user := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"id":   userField(graphql.NewNonNull(graphql.Int), func(u *models.UserResponse) any { return u.ID }),
+			"name": userField(graphql.NewNonNull(graphql.String), func(u *models.UserResponse) any { return u.Name }),
+			// dob is null in lists when LIST_HIDES_DOB is set.
+			"dob": userField(graphql.String, func(u *models.UserResponse) any { return optional(u.DOB) }),
+			"age": userField(graphql.Int, func(u *models.UserResponse) any {
+				if u.Age == nil {
+					return nil
+				}
+				return *u.Age
+			}),
+			// ageMonths and ageWeeks are null past INFANT_AGE_YEARS.
+			"ageMonths": userField(graphql.Int, func(u *models.UserResponse) any { return optionalInt(u.AgeMonths) }),
+			"ageWeeks":  userField(graphql.Int, func(u *models.UserResponse) any { return optionalInt(u.AgeWeeks) }),
+			"ageValid": userField(graphql.Boolean, func(u *models.UserResponse) any {
+				if u.Age == nil {
+					return nil
+				}
+				return u.AgeValid == nil || *u.AgeValid
+			}),
+			"isBirthday": userField(graphql.Boolean, func(u *models.UserResponse) any {
+				if u.Age == nil {
+					return nil
+				}
+				return u.IsBirthday
+			}),
+			"nextBirthday": userField(graphql.String, func(u *models.UserResponse) any {
+				next, ok := service.NextBirthdayOf(u)
+				if !ok {
+					return nil
+				}
+				return next.Format(time.DateOnly)
+			}),
+			"timezone":  userField(graphql.String, func(u *models.UserResponse) any { return optional(u.Timezone) }),
+			"locale":    userField(graphql.String, func(u *models.UserResponse) any { return optional(u.Locale) }),
+			"createdAt": userField(graphql.NewNonNull(graphql.String), func(u *models.UserResponse) any { return u.CreatedAt.Format(time.RFC3339) }),
+			"updatedAt": userField(graphql.NewNonNull(graphql.String), func(u *models.UserResponse) any { return u.UpdatedAt.Format(time.RFC3339) }),
+			"anonymizedAt": userField(graphql.String, func(u *models.UserResponse) any {
+				if u.AnonymizedAt == nil {
+					return nil
+				}
+				return u.AnonymizedAt.Format(time.RFC3339)
+			}),
+		},
+	})
+
+	page := graphql.NewObject(graphql.ObjectConfig{
+		Name: "UserPage",
+		Fields: graphql.Fields{
+			"users": pageField(graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(user))), func(l *models.UserListResponse) any {
+				users := make([]*models.UserResponse, len(l.Users))
+				for i := range l.Users {
+					users[i] = &l.Users[i]
+				}
+				return users
+			}),
+			"total": pageField(graphql.Int, func(l *models.UserListResponse) any {
+				if l.Total == nil {
+					return nil
+				}
+				return *l.Total
+			}),
+			"totalPages": pageField(graphql.Int, func(l *models.UserListResponse) any {
+				if l.TotalPages == nil {
+					return nil
+				}
+				return *l.TotalPages
+			}),
+			"page":       pageField(graphql.NewNonNull(graphql.Int), func(l *models.UserListResponse) any { return l.Page }),
+			"pageSize":   pageField(graphql.NewNonNull(graphql.Int), func(l *models.UserListResponse) any { return l.PageSize }),
+			"nextCursor": pageField(graphql.String, func(l *models.UserListResponse) any { return optional(l.NextCursor) }),
+		},
+	})
+
+	stats := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Stats",
+		Fields: graphql.Fields{
+			"total": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					includeAge := false
+					list, err := h.service.ListUsers(p.Context, &models.UserListQuery{PageSize: 1, IncludeAge: &includeAge})
+					if err != nil {
+						return nil, h.graphQLFail(err, "Failed to count users")
+					}
+					return *list.Total, nil
+				},
+			},
+			"birthdaysToday": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					birthdays, err := h.service.BirthdaysToday(p.Context)
+					if err != nil {
+						return nil, h.graphQLFail(err, "Failed to get today's birthdays")
+					}
+					return birthdays.Count, nil
+				},
+			},
+			"oldest":   h.rankField(user, true),
+			"youngest": h.rankField(user, false),
+		},
+	})
+
+	calculationType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "AgeCalculation",
+		Fields: graphql.Fields{
+			"dob":  calculationField(graphql.NewNonNull(graphql.String), func(c calculation) any { return c.dob.Format(time.DateOnly) }),
+			"asOf": calculationField(graphql.NewNonNull(graphql.String), func(c calculation) any { return c.asOf.Format(time.DateOnly) }),
+			"age":  calculationField(graphql.NewNonNull(graphql.Int), func(c calculation) any { return service.CalculateAge(c.dob, c.asOf) }),
+			"ageValid": calculationField(graphql.NewNonNull(graphql.Boolean), func(c calculation) any {
+				return !service.IsFutureDOB(c.dob, c.asOf)
+			}),
+			"years":  calculationField(graphql.NewNonNull(graphql.Int), func(c calculation) any { return service.CalculateAgeBreakdown(c.dob, c.asOf).Years }),
+			"months": calculationField(graphql.NewNonNull(graphql.Int), func(c calculation) any { return service.CalculateAgeBreakdown(c.dob, c.asOf).Months }),
+			"days":   calculationField(graphql.NewNonNull(graphql.Int), func(c calculation) any { return service.CalculateAgeBreakdown(c.dob, c.asOf).Days }),
+			"ageMonths": calculationField(graphql.NewNonNull(graphql.Int), func(c calculation) any {
+				months, _ := service.AgeInMonthsAndWeeks(c.dob, c.asOf)
+				return months
+			}),
+			"ageWeeks": calculationField(graphql.NewNonNull(graphql.Int), func(c calculation) any {
+				_, weeks := service.AgeInMonthsAndWeeks(c.dob, c.asOf)
+				return weeks
+			}),
+			"nextBirthday": calculationField(graphql.NewNonNull(graphql.String), func(c calculation) any {
+				return service.NextBirthday(c.dob, c.asOf).Format(time.DateOnly)
+			}),
+			"daysUntilNextBirthday": calculationField(graphql.NewNonNull(graphql.Int), func(c calculation) any {
+				return int(service.NextBirthday(c.dob, c.asOf).Sub(c.asOf).Hours() / 24)
+			}),
+			"isBirthday": calculationField(graphql.NewNonNull(graphql.Boolean), func(c calculation) any {
+				return c.dob.Before(c.asOf) && service.NextBirthday(c.dob, c.asOf).Equal(c.asOf)
+			}),
+			"species": calculationField(graphql.NewNonNull(graphql.String), func(c calculation) any { return c.species }),
+			// humanAge is the age a human of the same stage of life would be.
+			"humanAge": calculationField(graphql.NewNonNull(graphql.Float), func(c calculation) any {
+				return service.HumanAge(c.species, c.dob, c.asOf)
+			}),
+		},
+	})
+
+	filter := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "UserFilter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"nameFuzzy": &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"dobFrom":   &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"dobTo":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"minAge":    &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"maxAge":    &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		},
+	})
+
+	input := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "UserInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":     &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"dob":      &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"timezone": &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"locale":   &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"user": &graphql.Field{
+				Type: user,
+				Args: graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)}},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					u, err := h.service.GetUser(p.Context, int32(p.Args["id"].(int)))
+					if err != nil {
+						return nil, h.graphQLFail(err, "Failed to get user")
+					}
+					return u, nil
+				},
+			},
+			"users": &graphql.Field{
+				Type: graphql.NewNonNull(page),
+				Args: graphql.FieldConfigArgument{
+					"filter":   &graphql.ArgumentConfig{Type: filter},
+					"page":     &graphql.ArgumentConfig{Type: graphql.Int},
+					"pageSize": &graphql.ArgumentConfig{Type: graphql.Int},
+					"sort":     &graphql.ArgumentConfig{Type: graphql.String},
+					"cursor":   &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: h.resolveUsers,
+			},
+			"stats": &graphql.Field{
+				Type:    graphql.NewNonNull(stats),
+				Resolve: func(graphql.ResolveParams) (any, error) { return struct{}{}, nil },
+			},
+			"calculate": &graphql.Field{
+				Type: graphql.NewNonNull(calculationType),
+				Args: graphql.FieldConfigArgument{
+					"dob":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"asOf":    &graphql.ArgumentConfig{Type: graphql.String},
+					"species": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					dob, err := service.ParseDOB(p.Args["dob"].(string))
+					if err != nil {
+						return nil, h.graphQLFail(err, "Failed to calculate age")
+					}
+					asOf := toDate(h.clock.Now().UTC())
+					if value, ok := p.Args["asOf"].(string); ok {
+						if asOf, err = service.ParseDOB(value); err != nil {
+							return nil, h.graphQLFail(err, "Failed to calculate age")
+						}
+					}
+					species, _ := p.Args["species"].(string)
+					if species, err = service.ParseSpecies(species); err != nil {
+						return nil, h.graphQLFail(err, "Failed to calculate age")
+					}
+					return calculation{dob: dob, asOf: asOf, species: species}, nil
+				},
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createUser": &graphql.Field{
+				Type: graphql.NewNonNull(user),
+				Args: graphql.FieldConfigArgument{"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(input)}},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					fields := p.Args["input"].(map[string]any)
+					req := models.CreateUserRequest{Name: stringArg(fields, "name"), DOB: stringArg(fields, "dob"), Timezone: stringArg(fields, "timezone"), Locale: stringArg(fields, "locale")}
+					if err := h.validate.Struct(req); err != nil {
+						return nil, h.graphQLFail(&service.ValidationError{Details: formatValidationErrors(err)}, "Validation failed")
+					}
+					u, err := h.service.CreateUser(p.Context, &req)
+					if err != nil {
+						return nil, h.graphQLFail(err, "Failed to create user")
+					}
+					return u, nil
+				},
+			},
+			"updateUser": &graphql.Field{
+				Type: graphql.NewNonNull(user),
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(input)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					fields := p.Args["input"].(map[string]any)
+					req := models.UpdateUserRequest{Name: stringArg(fields, "name"), DOB: stringArg(fields, "dob"), Timezone: stringArg(fields, "timezone"), Locale: stringArg(fields, "locale")}
+					if err := h.validate.Struct(req); err != nil {
+						return nil, h.graphQLFail(&service.ValidationError{Details: formatValidationErrors(err)}, "Validation failed")
+					}
+					u, err := h.service.UpdateUser(p.Context, int32(p.Args["id"].(int)), &req)
+					if err != nil {
+						return nil, h.graphQLFail(err, "Failed to update user")
+					}
+					return u, nil
+				},
+			},
+			"deleteUser": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)}},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					if err := h.service.DeleteUser(p.Context, int32(p.Args["id"].(int))); err != nil {
+						return nil, h.graphQLFail(err, "Failed to delete user")
+					}
+					return true, nil
+				},
+			},
+		},
+	})
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+	if err != nil {
+		panic("handler: invalid GraphQL schema: " + err.Error())
+	}
+	return schema
+}
+
+// resolveUsers lists users the way GET /api/users does. Ages are worked out
+// only when a field that needs one is selected, and the total only when it
+// or totalPages is.
+func (h *UserHandler) resolveUsers(p graphql.ResolveParams) (any, error) {
+	params := models.UserListQuery{Sort: stringArg(p.Args, "sort"), Cursor: stringArg(p.Args, "cursor")}
+	params.Page, _ = p.Args["page"].(int)
+	params.PageSize, _ = p.Args["pageSize"].(int)
+	if filter, ok := p.Args["filter"].(map[string]any); ok {
+		params.Name = stringArg(filter, "name")
+		params.NameFuzzy = stringArg(filter, "nameFuzzy")
+		params.DOBFrom = stringArg(filter, "dobFrom")
+		params.DOBTo = stringArg(filter, "dobTo")
+		if age, ok := filter["minAge"].(int); ok {
+			params.MinAge = &age
+		}
+		if age, ok := filter["maxAge"].(int); ok {
+			params.MaxAge = &age
+		}
+	}
+
+	fields := selections(p.Info, fieldSets(p.Info.FieldASTs))
+	userFields := selections(p.Info, fields["users"])
+	includeTotal := fields["total"] != nil || fields["totalPages"] != nil
+	includeAge := false
+	for _, name := range []string{"age", "ageMonths", "ageWeeks", "ageValid", "isBirthday", "nextBirthday"} {
+		includeAge = includeAge || userFields[name] != nil
+	}
+	params.IncludeTotal = &includeTotal
+	params.IncludeAge = &includeAge
+
+	var details []models.FieldError
+	if err := h.validate.Struct(params); err != nil {
+		details = formatValidationErrors(err)
+	}
+	details = append(details, params.Validate()...)
+	if details = append(details, params.ValidateDepth(h.maxOffset)...); len(details) > 0 {
+		return nil, h.graphQLFail(&service.ValidationError{Details: details}, "Invalid pagination parameters")
+	}
+
+	list, err := h.service.ListUsers(p.Context, &params)
+	if err != nil {
+		return nil, h.graphQLFail(err, "Failed to list users")
+	}
+	return list, nil
+}
+
+// rankField is the oldest or youngest user, null when there are none.
+func (h *UserHandler) rankField(user *graphql.Object, oldest bool) *graphql.Field {
+	return &graphql.Field{
+		Type: user,
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			users, err := h.service.UsersByAge(p.Context, oldest, 1)
+			if errors.Is(err, service.ErrNoUsers) {
+				return nil, nil
+			}
+			if err != nil {
+				return nil, h.graphQLFail(err, "Failed to rank users by age")
+			}
+			return &users[0], nil
+		},
+	}
+}
+
+func userField(typ graphql.Output, value func(*models.UserResponse) any) *graphql.Field {
+	return &graphql.Field{Type: typ, Resolve: func(p graphql.ResolveParams) (any, error) {
+		return value(p.Source.(*models.UserResponse)), nil
+	}}
+}
+
+func pageField(typ graphql.Output, value func(*models.UserListResponse) any) *graphql.Field {
+	return &graphql.Field{Type: typ, Resolve: func(p graphql.ResolveParams) (any, error) {
+		return value(p.Source.(*models.UserListResponse)), nil
+	}}
+}
+
+func calculationField(typ graphql.Output, value func(calculation) any) *graphql.Field {
+	return &graphql.Field{Type: typ, Resolve: func(p graphql.ResolveParams) (any, error) {
+		return value(p.Source.(calculation)), nil
+	}}
+}
+
+// optional is s, or null when it is empty.
+func optional(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func optionalInt(n *int) any {
+	if n == nil {
+		return nil
+	}
+	return *n
+}
+
+func stringArg(args map[string]any, name string) string {
+	s, _ := args[name].(string)
+	return s
+}
+
+func fieldSets(fields []*ast.Field) []*ast.SelectionSet {
+	sets := make([]*ast.SelectionSet, len(fields))
+	for i, field := range fields {
+		sets[i] = field.SelectionSet
+	}
+	return sets
+}
+
+// selections maps the name of each field selected in sets, fragments
+// included, to the selection sets under it. @skip and @include are not
+// looked at, so a skipped field still counts as selected.
+func selections(info graphql.ResolveInfo, sets []*ast.SelectionSet) map[string][]*ast.SelectionSet {
+	fields := map[string][]*ast.SelectionSet{}
+	var walk func(set *ast.SelectionSet)
+	walk = func(set *ast.SelectionSet) {
+		if set == nil {
+			return
+		}
+		for _, selection := range set.Selections {
+			switch selection := selection.(type) {
+			case *ast.Field:
+				fields[selection.Name.Value] = append(fields[selection.Name.Value], selection.SelectionSet)
+			case *ast.InlineFragment:
+				walk(selection.SelectionSet)
+			case *ast.FragmentSpread:
+				if fragment, ok := info.Fragments[selection.Name.Value].(*ast.FragmentDefinition); ok {
+					walk(fragment.SelectionSet)
+				}
+			}
+		}
+	}
+	for _, set := range sets {
+		walk(set)
+	}
+	return fields
+}
+
+func toDate(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,295 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/srinivasarynh/age_calculator/config"
+	"github.com/srinivasarynh/age_calculator/internal/cache"
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/handler"
+	"github.com/srinivasarynh/age_calculator/internal/middleware"
+	"github.com/srinivasarynh/age_calculator/internal/mocks"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/routes"
+	"github.com/srinivasarynh/age_calculator/internal/service"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+func newGraphQLApp(svc service.UserService) *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	routes.SetupGraphQLRoutes(app, handler.NewUserHandler(svc, zap.NewNop(), handler.WithClock(clock.Fixed(goldenNow))), middleware.Tenant(config.NewHolder(&config.Config{})), routes.CachePolicies{})
+	return app
+}
+
+func newSeededGraphQLApp(t *testing.T) *fiber.App {
+	t.Helper()
+	return newGraphQLApp(service.NewUserService(seededRepository(t), zap.NewNop(), service.WithClock(clock.Fixed(goldenNow))))
+}
+
+// doGraphQL posts query with variables and returns the response's data and
+// errors, after checking it answered 200 as GraphQL does for every query.
+func doGraphQL(t *testing.T, app *fiber.App, query string, variables map[string]any) (map[string]any, []any) {
+	t.Helper()
+	body, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, resp := doRequest(t, app, "POST", "/graphql", string(body))
+	if status != fiber.StatusOK {
+		t.Fatalf("status = %d, want 200: %v", status, resp)
+	}
+	data, _ := resp["data"].(map[string]any)
+	errs, _ := resp["errors"].([]any)
+	return data, errs
+}
+
+// asJSON decodes want the way a response is decoded, so numbers compare as
+// float64.
+func asJSON(t *testing.T, want string) map[string]any {
+	t.Helper()
+	var decoded map[string]any
+	if err := json.Unmarshal([]byte(want), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+func TestGraphQLQueries(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]any
+		want      string
+	}{
+		{
+			name:  "user",
+			query: `{ user(id: 2) { id name dob age ageValid isBirthday nextBirthday timezone } }`,
+			want:  `{"user":{"id":2,"name":"Bob","dob":"2000-02-29","age":25,"ageValid":true,"isBirthday":false,"nextBirthday":"2026-03-01","timezone":null}}`,
+		},
+		{
+			name:      "users with variables",
+			query:     `query Adults($filter: UserFilter, $size: Int) { users(filter: $filter, pageSize: $size, sort: "-age") { total totalPages page pageSize users { name age } } }`,
+			variables: map[string]any{"filter": map[string]any{"minAge": 20}, "size": 1},
+			want:      `{"users":{"total":2,"totalPages":2,"page":1,"pageSize":1,"users":[{"name":"Alice","age":35}]}}`,
+		},
+		{
+			name:  "users through fragments",
+			query: `{ users(filter: {name: "carol"}) { ...page } } fragment page on UserPage { users { ... on User { name nextBirthday } } }`,
+			want:  `{"users":{"users":[{"name":"Carol","nextBirthday":"2026-01-01"}]}}`,
+		},
+		{
+			name:  "stats",
+			query: `{ stats { total birthdaysToday oldest { name } youngest { name } } }`,
+			want:  `{"stats":{"total":3,"birthdaysToday":0,"oldest":{"name":"Alice"},"youngest":{"name":"Carol"}}}`,
+		},
+		{
+			name:      "calculate",
+			query:     `query Calc($dob: String!, $asOf: String) { calculate(dob: $dob, asOf: $asOf) { dob asOf age ageValid years months days nextBirthday daysUntilNextBirthday isBirthday } }`,
+			variables: map[string]any{"dob": "2000-02-29", "asOf": "2025-06-15"},
+			want:      `{"calculate":{"dob":"2000-02-29","asOf":"2025-06-15","age":25,"ageValid":true,"years":25,"months":3,"days":17,"nextBirthday":"2026-03-01","daysUntilNextBirthday":259,"isBirthday":false}}`,
+		},
+		{
+			name:  "calculate as of today",
+			query: `{ calculate(dob: "1990-06-15") { asOf age isBirthday daysUntilNextBirthday } }`,
+			want:  `{"calculate":{"asOf":"2025-06-15","age":35,"isBirthday":true,"daysUntilNextBirthday":0}}`,
+		},
+		{
+			name:  "calculate for an infant",
+			query: `{ calculate(dob: "2025-05-04") { age ageMonths ageWeeks } }`,
+			want:  `{"calculate":{"age":0,"ageMonths":1,"ageWeeks":6}}`,
+		},
+		{
+			name:  "calculate for a dog",
+			query: `{ dog: calculate(dob: "2015-06-15", species: "dog") { age species humanAge } human: calculate(dob: "2015-06-15") { species humanAge } }`,
+			want:  `{"dog":{"age":10,"species":"dog","humanAge":67.8},"human":{"species":"human","humanAge":10}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, errs := doGraphQL(t, newSeededGraphQLApp(t), tt.query, tt.variables)
+			if len(errs) > 0 {
+				t.Fatalf("errors = %v", errs)
+			}
+			if want := asJSON(t, tt.want); !reflect.DeepEqual(data, want) {
+				t.Errorf("data = %v, want %v", data, want)
+			}
+		})
+	}
+}
+
+func TestGraphQLMutations(t *testing.T) {
+	app := newSeededGraphQLApp(t)
+
+	data, errs := doGraphQL(t, app, `mutation Create($input: UserInput!) { createUser(input: $input) { id name dob age } }`,
+		map[string]any{"input": map[string]any{"name": "Dave", "dob": "1985-12-01"}})
+	if len(errs) > 0 {
+		t.Fatalf("createUser errors = %v", errs)
+	}
+	if want := asJSON(t, `{"createUser":{"id":4,"name":"Dave","dob":"1985-12-01","age":39}}`); !reflect.DeepEqual(data, want) {
+		t.Errorf("createUser = %v, want %v", data, want)
+	}
+
+	data, errs = doGraphQL(t, app, `mutation { updateUser(id: 4, input: {name: "David", dob: "1985-12-01", timezone: "Europe/Paris"}) { name timezone } }`, nil)
+	if len(errs) > 0 {
+		t.Fatalf("updateUser errors = %v", errs)
+	}
+	if want := asJSON(t, `{"updateUser":{"name":"David","timezone":"Europe/Paris"}}`); !reflect.DeepEqual(data, want) {
+		t.Errorf("updateUser = %v, want %v", data, want)
+	}
+
+	data, errs = doGraphQL(t, app, `mutation { deleteUser(id: 4) }`, nil)
+	if len(errs) > 0 || data["deleteUser"] != true {
+		t.Fatalf("deleteUser = %v, errors %v", data, errs)
+	}
+	data, errs = doGraphQL(t, app, `{ user(id: 4) { name } }`, nil)
+	if data["user"] != nil || len(errs) != 1 || errorCode(errs[0]) != "USER_NOT_FOUND" {
+		t.Errorf("user after delete = %v, errors %v; want null and USER_NOT_FOUND", data, errs)
+	}
+}
+
+func errorCode(err any) string {
+	extensions, _ := err.(map[string]any)["extensions"].(map[string]any)
+	code, _ := extensions["code"].(string)
+	return code
+}
+
+func TestGraphQLErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		code    string
+		message string
+		details string
+	}{
+		{name: "user not found", query: `{ user(id: 99) { name } }`, code: "USER_NOT_FOUND", message: "User not found"},
+		{name: "deleted twice", query: `mutation { deleteUser(id: 99) }`, code: "USER_NOT_FOUND", message: "User not found"},
+		{name: "invalid dob", query: `{ calculate(dob: "1990-13-01") { age } }`, code: "INVALID_DATE", message: "Invalid date format. Expected YYYY-MM-DD"},
+		{name: "invalid as of", query: `{ calculate(dob: "1990-01-01", asOf: "today") { age } }`, code: "INVALID_DATE", message: "Invalid date format. Expected YYYY-MM-DD"},
+		{
+			name:    "unknown species",
+			query:   `{ calculate(dob: "2020-01-01", species: "hamster") { humanAge } }`,
+			code:    "VALIDATION_FAILED",
+			message: "Validation failed",
+			details: `[{"field":"species","rule":"oneof","param":"cat dog human","message":"species must be one of cat, dog, human"}]`,
+		},
+		{
+			name:    "invalid input",
+			query:   `mutation { createUser(input: {name: "D", dob: "1985-12-01"}) { id } }`,
+			code:    "VALIDATION_FAILED",
+			message: "Validation failed",
+			details: `[{"field":"name","rule":"min","param":"2","message":"name must be at least 2 characters"}]`,
+		},
+		{
+			name:    "invalid paging",
+			query:   `{ users(pageSize: 500) { page } }`,
+			code:    "VALIDATION_FAILED",
+			message: "Validation failed",
+			details: `[{"field":"page_size","rule":"max","param":"100","message":"page_size must be at most 100"}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errs := doGraphQL(t, newSeededGraphQLApp(t), tt.query, nil)
+			if len(errs) != 1 {
+				t.Fatalf("errors = %v, want one", errs)
+			}
+			err := errs[0].(map[string]any)
+			if got := errorCode(err); got != tt.code {
+				t.Errorf("code = %q, want %q", got, tt.code)
+			}
+			if err["message"] != tt.message {
+				t.Errorf("message = %v, want %q", err["message"], tt.message)
+			}
+			details := err["extensions"].(map[string]any)["details"]
+			if tt.details == "" {
+				if details != nil {
+					t.Errorf("details = %v, want none", details)
+				}
+				return
+			}
+			var want any
+			if err := json.Unmarshal([]byte(tt.details), &want); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(details, want) {
+				t.Errorf("details = %v, want %v", details, want)
+			}
+		})
+	}
+}
+
+func TestGraphQLBadRequest(t *testing.T) {
+	app := newSeededGraphQLApp(t)
+	for _, body := range []string{`{}`, `[1]`, `{"query": 1}`} {
+		status, resp := doRequest(t, app, "POST", "/graphql", body)
+		if status != fiber.StatusBadRequest || resp["error"] != "Request body must be a JSON object with a query" {
+			t.Errorf("POST %s: %d %v, want 400", body, status, resp)
+		}
+	}
+
+	_, errs := doGraphQL(t, app, `{ user(id: 1) { shoeSize } }`, nil)
+	if len(errs) != 1 {
+		t.Errorf("unknown field: errors = %v, want one", errs)
+	}
+}
+
+func TestGraphQLComputesOnlyWhatIsSelected(t *testing.T) {
+	var got *models.UserListQuery
+	svc := &mockUserService{listUsers: func(_ context.Context, params *models.UserListQuery) (*models.UserListResponse, error) {
+		got = params
+		return &models.UserListResponse{Users: []models.UserResponse{}, Page: 1, PageSize: 20}, nil
+	}}
+	app := newGraphQLApp(svc)
+
+	tests := []struct {
+		query      string
+		age, total bool
+	}{
+		{query: `{ users { users { id name dob } } }`},
+		{query: `{ users { users { name age } } }`, age: true},
+		{query: `{ users { users { nextBirthday } } }`, age: true},
+		{query: `{ users { users { ...u } } } fragment u on User { isBirthday }`, age: true},
+		{query: `{ users { total users { name } } }`, total: true},
+		{query: `{ users { totalPages users { ... on User { ageValid } } } }`, age: true, total: true},
+	}
+	for _, tt := range tests {
+		got = nil
+		if _, errs := doGraphQL(t, app, tt.query, nil); len(errs) > 0 {
+			t.Fatalf("%s: errors = %v", tt.query, errs)
+		}
+		if got == nil || got.IncludeAge == nil || got.IncludeTotal == nil {
+			t.Fatalf("%s: query = %+v, want IncludeAge and IncludeTotal set", tt.query, got)
+		}
+		if *got.IncludeAge != tt.age || *got.IncludeTotal != tt.total {
+			t.Errorf("%s: include age %v, total %v; want %v, %v", tt.query, *got.IncludeAge, *got.IncludeTotal, tt.age, tt.total)
+		}
+	}
+}
+
+// TestGraphQLLooksUpEachUserOnce asks for the same user twice in one query;
+// the request's memo answers the second lookup, while a second request goes
+// to the store again.
+func TestGraphQLLooksUpEachUserOnce(t *testing.T) {
+	repo := mocks.NewUserRepository(t)
+	alice := &models.User{ID: 1, TenantID: tenant.Default, Name: "Alice", DOB: time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC)}
+	repo.EXPECT().GetById(mock.Anything, int32(1)).Return(alice, nil).Twice()
+
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	app.Use(middleware.RequestCache())
+	svc := service.NewUserService(cache.NewRequestScopedUserRepository(repo), zap.NewNop(), service.WithClock(clock.Fixed(goldenNow)))
+	routes.SetupGraphQLRoutes(app, handler.NewUserHandler(svc, zap.NewNop(), handler.WithClock(clock.Fixed(goldenNow))), middleware.Tenant(config.NewHolder(&config.Config{})), routes.CachePolicies{})
+
+	for range 2 {
+		data, errs := doGraphQL(t, app, `{ a: user(id: 1) { name } b: user(id: 1) { age } }`, nil)
+		if want := asJSON(t, `{"a":{"name":"Alice"},"b":{"age":35}}`); errs != nil || !reflect.DeepEqual(data, want) {
+			t.Errorf("data = %v, errors = %v, want %v", data, errs, want)
+		}
+	}
+}
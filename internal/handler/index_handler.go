@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/srinivasarynh/age_calculator/config"
+	"github.com/srinivasarynh/age_calculator/internal/buildinfo"
+)
+
+// APIResource is one entry of the API index. URL is a path on this server;
+// {name} marks a path parameter.
+type APIResource struct {
+	Name    string `json:"name"`
+	URL     string `json:"url"`
+	Summary string `json:"summary"`
+	// Deprecated routes may be removed; their responses say when.
+	Deprecated bool `json:"deprecated,omitempty"`
+}
+
+type IndexHandler struct {
+	cfg        *config.Holder
+	apiVersion string
+	resources  []APIResource
+}
+
+// NewIndexHandler lists resources as they are; the routes package owns the
+// list so that it matches what is mounted.
+func NewIndexHandler(cfg *config.Holder, apiVersion string, resources []APIResource) *IndexHandler {
+	return &IndexHandler{cfg: cfg, apiVersion: apiVersion, resources: resources}
+}
+
+// Index reports the features of the configuration in effect, so a reload
+// that adds tenants shows up on the next request.
+func (h *IndexHandler) Index(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"api_version": h.apiVersion,
+		"version":     buildinfo.Get().Version,
+		"resources":   h.resources,
+		"features":    features(h.cfg.Load()),
+	})
+}
+
+func features(c *config.Config) map[string]bool {
+	return map[string]bool{
+		"admin_token":            c.AdminToken != "",
+		"birthday_notifications": c.BirthdayNotifications,
+		"max_users":              c.MaxUsers > 0,
+		"response_envelope":      c.ResponseEnvelope,
+		"tenants":                len(c.Tenants) > 0,
+		"unique_names":           c.UniqueNames,
+		"user_cache":             c.UserCacheSize > 0 || c.RedisURL != "",
+		"user_events_webhook":    c.UserEventsWebhookURL != "",
+	}
+}
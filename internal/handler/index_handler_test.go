@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/srinivasarynh/age_calculator/config"
+)
+
+func TestIndex(t *testing.T) {
+	setBuildInfo(t, "1.4.2", "abc1234", "2025-01-15T10:30:00Z")
+	cfg := config.NewHolder(&config.Config{Tenants: []string{"acme"}, UniqueNames: true, RedisURL: "redis://cache:6379", AdminToken: "s3cret"})
+	resources := []APIResource{{Name: "list_users", URL: "/api/v1/users", Summary: "List users"}, {Name: "get_user", URL: "/api/v1/users/{id}", Summary: "Read a user"}}
+
+	app := fiber.New()
+	app.Get("/api/v1", NewIndexHandler(cfg, "v1", resources).Index)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	var body struct {
+		APIVersion string          `json:"api_version"`
+		Version    string          `json:"version"`
+		Resources  []APIResource   `json:"resources"`
+		Features   map[string]bool `json:"features"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.APIVersion != "v1" || body.Version != "1.4.2" {
+		t.Errorf("api_version, version = %q, %q; want v1, 1.4.2", body.APIVersion, body.Version)
+	}
+	if !reflect.DeepEqual(body.Resources, resources) {
+		t.Errorf("resources = %+v, want %+v", body.Resources, resources)
+	}
+	want := map[string]bool{
+		"admin_token":            true,
+		"birthday_notifications": false,
+		"max_users":              false,
+		"response_envelope":      false,
+		"tenants":                true,
+		"unique_names":           true,
+		"user_cache":             true,
+		"user_events_webhook":    false,
+	}
+	if !reflect.DeepEqual(body.Features, want) {
+		t.Errorf("features = %v, want %v", body.Features, want)
+	}
+
+}
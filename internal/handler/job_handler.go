@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/srinivasarynh/age_calculator/internal/service"
+	"go.uber.org/zap"
+)
+
+type JobHandler struct {
+	jobs   service.JobService
+	logger *zap.Logger
+}
+
+func NewJobHandler(jobs service.JobService, logger *zap.Logger) *JobHandler {
+	return &JobHandler{jobs: jobs, logger: logger}
+}
+
+// GetJob reports a job's state and progress, and its report once finished.
+// Another tenant's job is not found.
+func (h *JobHandler) GetJob(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid job ID",
+		})
+	}
+
+	job, err := h.jobs.GetJob(c.UserContext(), id)
+	if err != nil {
+		return fail(h.logger, err, "Failed to get job")
+	}
+	return c.JSON(job)
+}
+
+// Download serves the file a finished job produced, such as an export, as an
+// attachment. It is 409 while the job is pending or running.
+func (h *JobHandler) Download(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid job ID",
+		})
+	}
+
+	download, err := h.jobs.Download(c.UserContext(), id)
+	if err != nil {
+		return fail(h.logger, err, "Failed to download job")
+	}
+	filters, _ := url.ParseQuery(download.Filters)
+	setExportHeaders(c, exportFile{
+		ContentType: download.ContentType,
+		Filename:    download.Filename,
+		Rows:        download.Rows,
+		Filters:     filters,
+	})
+	return c.SendStream(download.Body)
+}
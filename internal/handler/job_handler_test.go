@@ -0,0 +1,302 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/srinivasarynh/age_calculator/config"
+	"github.com/srinivasarynh/age_calculator/internal/artifact"
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/handler"
+	"github.com/srinivasarynh/age_calculator/internal/middleware"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/routes"
+	"github.com/srinivasarynh/age_calculator/internal/service"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+	"go.uber.org/zap"
+)
+
+// newJobApp runs jobs only when the test calls RunNext.
+func newJobApp(t *testing.T) (*fiber.App, *service.JobRunner, repository.UserRepository) {
+	t.Helper()
+	repo := repository.NewMemoryUserRepository(clock.Real())
+	users := service.NewUserService(repo, zap.NewNop())
+	exports, err := artifact.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner := service.NewJobRunner(repository.NewMemoryJobRepository(clock.Real()), zap.NewNop(),
+		service.WithJobWorkers(0), service.WithJobArtifacts(exports, time.Hour))
+	runner.Handle(models.JobUserImport, service.ImportJob(users))
+	runner.Handle(models.JobUserExport, service.ExportJob(users, exports))
+
+	cfg := config.NewHolder(&config.Config{Tenants: []string{"acme", "globex"}})
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	routes.SetupRoutes(app, handler.NewUserHandler(users, zap.NewNop(), handler.WithJobs(runner)), testAdminGuard, middleware.Tenant(cfg), routes.CachePolicies{})
+	routes.SetupJobRoutes(app, handler.NewJobHandler(runner, zap.NewNop()), middleware.Tenant(cfg), routes.CachePolicies{})
+	return app, runner, repo
+}
+
+// postCSV sends X-Tenant-ID unless tenantID is empty.
+func postCSV(t *testing.T, app *fiber.App, tenantID, target, body string) (*httpResult, map[string]any) {
+	t.Helper()
+	req := httptest.NewRequest("POST", target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/csv")
+	if tenantID != "" {
+		req.Header.Set(middleware.HeaderTenantID, tenantID)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := io.ReadAll(resp.Body)
+	var out map[string]any
+	json.Unmarshal(raw, &out)
+	return &httpResult{status: resp.StatusCode, location: resp.Header.Get("Location")}, out
+}
+
+type httpResult struct {
+	status   int
+	location string
+}
+
+const importFile = "name,dob\nAlice,1990-05-10\nB,1985-01-01\nCarol,1992-07-04\n"
+
+func TestImportUsersSync(t *testing.T) {
+	app, _, repo := newJobApp(t)
+
+	res, body := postCSV(t, app, "acme", "/api/v1/users/import", importFile)
+	if res.status != fiber.StatusOK || body["created"] != float64(2) || body["failed"] != float64(1) {
+		t.Fatalf("import = %d %v", res.status, body)
+	}
+	errs := body["errors"].([]any)
+	if len(errs) != 1 || errs[0].(map[string]any)["row"] != float64(3) {
+		t.Errorf("errors = %v, want row 3", errs)
+	}
+	if n, _ := repo.Count(tenant.WithID(context.Background(), "acme"), repository.UserFilter{}); n != 2 {
+		t.Errorf("acme has %d users, want 2", n)
+	}
+}
+
+func TestImportUsersAsyncLifecycle(t *testing.T) {
+	app, runner, repo := newJobApp(t)
+
+	res, job := postCSV(t, app, "acme", "/api/v1/users/import?async=true", importFile)
+	if res.status != fiber.StatusAccepted || job["state"] != "pending" || job["kind"] != models.JobUserImport {
+		t.Fatalf("submit = %d %v", res.status, job)
+	}
+	if res.location != "/api/v1/jobs/1" {
+		t.Errorf("Location = %q", res.location)
+	}
+	if _, ok := job["report"]; ok {
+		t.Errorf("pending job has a report: %v", job)
+	}
+
+	status, pending := doTenantRequest(t, app, "acme", "GET", "/api/v1/jobs/1", "")
+	if status != fiber.StatusOK || pending["state"] != "pending" {
+		t.Fatalf("GET pending job = %d %v", status, pending)
+	}
+	if n, _ := repo.Count(tenant.WithID(context.Background(), "acme"), repository.UserFilter{}); n != 0 {
+		t.Errorf("%d users exist before the job ran", n)
+	}
+
+	if !runner.RunNext(context.Background()) {
+		t.Fatal("no job to run")
+	}
+	status, done := doTenantRequest(t, app, "acme", "GET", "/api/v1/jobs/1", "")
+	if status != fiber.StatusOK || done["state"] != "done" || done["rows_processed"] != float64(3) || done["finished_at"] == nil {
+		t.Fatalf("GET finished job = %d %v", status, done)
+	}
+	report := done["report"].(map[string]any)
+	if report["created"] != float64(2) || report["failed"] != float64(1) || len(report["errors"].([]any)) != 1 {
+		t.Errorf("report = %v", report)
+	}
+	if n, _ := repo.Count(tenant.WithID(context.Background(), "acme"), repository.UserFilter{}); n != 2 {
+		t.Errorf("acme has %d users after the job, want 2", n)
+	}
+
+	if status, _ := doTenantRequest(t, app, "globex", "GET", "/api/v1/jobs/1", ""); status != fiber.StatusNotFound {
+		t.Errorf("GET from another tenant = %d, want 404", status)
+	}
+}
+
+func TestImportUsersConflictStrategies(t *testing.T) {
+	app, runner, repo := newJobApp(t)
+	acme := tenant.WithID(context.Background(), "acme")
+	if res, _ := postCSV(t, app, "acme", "/api/v1/users/import", importFile); res.status != fiber.StatusOK {
+		t.Fatalf("first import = %d", res.status)
+	}
+
+	res, body := postCSV(t, app, "acme", "/api/v1/users/import?conflict=skip", importFile)
+	if res.status != fiber.StatusOK || body["created"] != float64(0) || body["skipped"] != float64(2) || body["failed"] != float64(1) {
+		t.Errorf("skip = %d %v, want Alice and Carol skipped", res.status, body)
+	}
+
+	res, body = postCSV(t, app, "acme", "/api/v1/users/import?conflict=fail&atomic=true", "name,dob\nDave,1980-01-01\nalice,1990-05-10\n")
+	if res.status != fiber.StatusUnprocessableEntity || body["rolled_back"] != true || body["created"] != float64(0) {
+		t.Errorf("atomic fail = %d %v, want 422 rolled back", res.status, body)
+	}
+	if n, _ := repo.Count(acme, repository.UserFilter{}); n != 2 {
+		t.Errorf("acme has %d users after the rollback, want 2", n)
+	}
+
+	// The options travel with an asynchronous import, and a job stored with
+	// the bare file, as before imports took options, still runs.
+	if res, _ := postCSV(t, app, "acme", "/api/v1/users/import?async=true&conflict=update", "name,dob\nALICE,1990-05-10\n"); res.status != fiber.StatusAccepted {
+		t.Fatalf("async submit = %d", res.status)
+	}
+	if _, err := runner.SubmitJob(acme, models.JobUserImport, []byte("name,dob\nAlice,1990-05-10\n")); err != nil {
+		t.Fatal(err)
+	}
+	runner.RunNext(context.Background())
+	runner.RunNext(context.Background())
+	for id, want := range map[int]string{1: "updated", 2: "created"} {
+		_, job := doTenantRequest(t, app, "acme", "GET", fmt.Sprintf("/api/v1/jobs/%d", id), "")
+		if report, _ := job["report"].(map[string]any); job["state"] != "done" || report[want] != float64(1) {
+			t.Errorf("job %d = %v, want one row %s", id, job, want)
+		}
+	}
+	if user, err := repo.GetById(acme, 1); err != nil || user.Name != "ALICE" {
+		t.Errorf("user 1 = %+v, %v; want renamed by the update", user, err)
+	}
+}
+
+func TestImportUsersAsyncInvalidFileFailsTheJob(t *testing.T) {
+	app, runner, _ := newJobApp(t)
+
+	if res, _ := postCSV(t, app, "acme", "/api/v1/users/import?async=1", "name,birthday\nAlice,1990-05-10\n"); res.status != fiber.StatusAccepted {
+		t.Fatalf("submit = %d", res.status)
+	}
+	runner.RunNext(context.Background())
+	_, job := doTenantRequest(t, app, "acme", "GET", "/api/v1/jobs/1", "")
+	if job["state"] != "failed" || !strings.Contains(job["error"].(string), "no dob column") {
+		t.Errorf("job = %v, want failed naming the missing column", job)
+	}
+}
+
+func TestImportUsersErrors(t *testing.T) {
+	app, _, _ := newJobApp(t)
+	noJobs := newTestApp(service.NewUserService(repository.NewMemoryUserRepository(clock.Real()), zap.NewNop()))
+
+	tests := []struct {
+		name   string
+		app    *fiber.App
+		tenant string
+		target string
+		body   string
+		status int
+		want   string
+	}{
+		{name: "empty upload", app: app, tenant: "acme", target: "/api/v1/users/import", body: "  \n", status: fiber.StatusBadRequest, want: "Import file is empty"},
+		{name: "no dob column", app: app, tenant: "acme", target: "/api/v1/users/import", body: "name\nAlice\n", status: fiber.StatusBadRequest, want: "Invalid import file"},
+		{name: "bad async flag", app: app, tenant: "acme", target: "/api/v1/users/import?async=soon", body: importFile, status: fiber.StatusBadRequest, want: "Invalid query parameters"},
+		{name: "bad atomic flag", app: app, tenant: "acme", target: "/api/v1/users/import?atomic=all", body: importFile, status: fiber.StatusBadRequest, want: "Invalid query parameters"},
+		{name: "unknown conflict strategy", app: app, tenant: "acme", target: "/api/v1/users/import?conflict=merge", body: importFile, status: fiber.StatusBadRequest, want: "Invalid query parameters"},
+		{name: "async without jobs", app: noJobs, target: "/api/v1/users/import?async=true", body: importFile, status: fiber.StatusNotImplemented, want: "Asynchronous import is not enabled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, body := postCSV(t, tt.app, tt.tenant, tt.target, tt.body)
+			if res.status != tt.status || body["error"] != tt.want {
+				t.Errorf("response = %d %v, want %d %q", res.status, body, tt.status, tt.want)
+			}
+		})
+	}
+
+	if status, body := doTenantRequest(t, app, "acme", "POST", "/api/v1/users/import", `{"name":"Alice"}`); status != fiber.StatusUnsupportedMediaType {
+		t.Errorf("JSON upload = %d %v, want 415", status, body)
+	}
+}
+
+func TestExportUsersJobLifecycle(t *testing.T) {
+	app, runner, repo := newJobApp(t)
+	acme := tenant.WithID(context.Background(), "acme")
+	for _, name := range []string{"Alice", "Bob", "Alicia"} {
+		repo.Create(acme, name, time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC))
+	}
+	repo.Create(tenant.WithID(context.Background(), "globex"), "Alina", time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC))
+
+	status, job := doTenantRequest(t, app, "acme", "POST", "/api/v1/users/export/jobs", `{"name":"ali"}`)
+	if status != fiber.StatusAccepted || job["kind"] != models.JobUserExport || job["state"] != "pending" {
+		t.Fatalf("submit = %d %v", status, job)
+	}
+	if status, body := doTenantRequest(t, app, "acme", "GET", "/api/v1/jobs/1/download", ""); status != fiber.StatusConflict || body["error"] != "Job has not finished" {
+		t.Errorf("download before the job ran = %d %v, want 409", status, body)
+	}
+
+	runner.RunNext(context.Background())
+	_, done := doTenantRequest(t, app, "acme", "GET", "/api/v1/jobs/1", "")
+	report, _ := done["report"].(map[string]any)
+	filename, _ := report["filename"].(string)
+	if done["state"] != "done" || done["rows_processed"] != float64(2) || !regexp.MustCompile(`^users-export-\d{8}-\d{4}\.csv$`).MatchString(filename) {
+		t.Fatalf("finished job = %v", done)
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/jobs/1/download", nil)
+	req.Header.Set(middleware.HeaderTenantID, "acme")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != fiber.StatusOK || resp.Header.Get("Content-Type") != "text/csv" {
+		t.Fatalf("download = %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if got := resp.Header.Get("Content-Disposition"); got != `attachment; filename="`+filename+`"` {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+	if rows, filters := resp.Header.Get(handler.HeaderTotalRows), resp.Header.Get(handler.HeaderExportFilters); rows != "2" || filters != "name=ali" {
+		t.Errorf("X-Total-Rows = %q, X-Export-Filters = %q; want 2 and name=ali", rows, filters)
+	}
+	if !strings.HasPrefix(string(body), "id,name,dob,age\n1,Alice,1990-05-10,") || !strings.Contains(string(body), "\n3,Alicia,") || strings.Contains(string(body), "Bob") {
+		t.Errorf("download body = %q", body)
+	}
+
+	if status, _ := doTenantRequest(t, app, "globex", "GET", "/api/v1/jobs/1/download", ""); status != fiber.StatusNotFound {
+		t.Errorf("download from another tenant = %d, want 404", status)
+	}
+}
+
+func TestExportUsersErrors(t *testing.T) {
+	app, runner, _ := newJobApp(t)
+	noJobs := newTestApp(service.NewUserService(repository.NewMemoryUserRepository(clock.Real()), zap.NewNop()))
+
+	if status, body := doTenantRequest(t, app, "acme", "POST", "/api/v1/users/export/jobs", `{"format":"xml"}`); status != fiber.StatusBadRequest || body["error"] != "Validation failed" {
+		t.Errorf("unknown format = %d %v", status, body)
+	}
+	if status, body := doTenantRequest(t, app, "acme", "POST", "/api/v1/users/export/jobs", `{"fromat":"csv"}`); status != fiber.StatusBadRequest || body["error"] != "Unknown fields in request body" {
+		t.Errorf("misspelt field = %d %v", status, body)
+	}
+	if status, body := doTenantRequest(t, noJobs, "", "POST", "/api/v1/users/export/jobs", `{}`); status != fiber.StatusNotImplemented {
+		t.Errorf("export without jobs = %d %v, want 501", status, body)
+	}
+
+	postCSV(t, app, "acme", "/api/v1/users/import?async=true", importFile)
+	runner.RunNext(context.Background())
+	if status, body := doTenantRequest(t, app, "acme", "GET", "/api/v1/jobs/1/download", ""); status != fiber.StatusNotFound || body["error"] != "Job has no download" {
+		t.Errorf("download of an import = %d %v, want 404", status, body)
+	}
+}
+
+func TestGetJobErrors(t *testing.T) {
+	app, _, _ := newJobApp(t)
+
+	if status, body := doTenantRequest(t, app, "acme", "GET", "/api/v1/jobs/abc", ""); status != fiber.StatusBadRequest || body["error"] != "Invalid job ID" {
+		t.Errorf("GET invalid id = %d %v", status, body)
+	}
+	if status, body := doTenantRequest(t, app, "acme", "GET", "/api/v1/jobs/42", ""); status != fiber.StatusNotFound || body["error"] != "Job not found" {
+		t.Errorf("GET missing job = %d %v", status, body)
+	}
+	if status, _ := doTenantRequest(t, app, "", "GET", "/api/v1/jobs/1", ""); status != fiber.StatusBadRequest {
+		t.Errorf("GET without a tenant = %d, want 400", status)
+	}
+}
@@ -0,0 +1,148 @@
+package handler_test
+
+import (
+	"context"
+	"io"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+)
+
+type mockUserService struct {
+	createUser      func(ctx context.Context, req *models.CreateUserRequest) (*models.UserResponse, error)
+	getUser         func(ctx context.Context, id int32) (*models.UserResponse, error)
+	getByName       func(ctx context.Context, name string) (*models.UserResponse, error)
+	listUsers       func(ctx context.Context, params *models.UserListQuery) (*models.UserListResponse, error)
+	updateUser      func(ctx context.Context, id int32, req *models.UpdateUserRequest) (*models.UserResponse, error)
+	patchUser       func(ctx context.Context, id int32, patch func(req *models.UpdateUserRequest) error) (*models.UserResponse, error)
+	updateMany      func(ctx context.Context, items []models.BatchUpdateItem, atomic bool) (*models.BatchUpdateResponse, error)
+	deleteUser      func(ctx context.Context, id int32) error
+	importCSV       func(ctx context.Context, r io.Reader, opts models.ImportOptions, progress func(rows int)) (*models.ImportReport, error)
+	exportCSV       func(ctx context.Context, req *models.ExportUsersRequest, w io.Writer, progress func(rows int)) (*models.ExportReport, error)
+	countExport     func(ctx context.Context, req *models.ExportUsersRequest) (int64, error)
+	findFuture      func(ctx context.Context) (*models.DOBValidationResponse, error)
+	birthdays       func(ctx context.Context) (*models.BirthdaysResponse, error)
+	calendar        func(ctx context.Context, params *models.UserListQuery, w io.Writer) error
+	exportData      func(ctx context.Context, id int32) (*models.UserDataExport, error)
+	anonymize       func(ctx context.Context, id int32) (*models.UserResponse, error)
+	anonymizeDryRun func(ctx context.Context, id int32) (*models.DryRunResponse, error)
+	byAge           func(ctx context.Context, oldest bool, count int) ([]models.UserResponse, error)
+	percentile      func(ctx context.Context, id int32, params *models.UserListQuery) (*models.AgePercentileResponse, error)
+	twins           func(ctx context.Context, id int32, params *models.UserListQuery) (*models.UserListResponse, error)
+	collisions      func(ctx context.Context, limit int) (*models.BirthdayCollisionsResponse, error)
+	leaplings       func(ctx context.Context, params *models.UserListQuery) (*models.LeaplingListResponse, error)
+	cohorts         func(ctx context.Context, bounds []int) (*models.CohortsResponse, error)
+	share           func(ctx context.Context, id int32) (*models.ShareResponse, error)
+	shares          func(ctx context.Context, id int32) (*models.ShareListResponse, error)
+	revoke          func(ctx context.Context, id int32, shareID int64) error
+	shared          func(ctx context.Context, token string) (*models.SharedProfile, error)
+}
+
+func (m *mockUserService) CreateUser(ctx context.Context, req *models.CreateUserRequest) (*models.UserResponse, error) {
+	return m.createUser(ctx, req)
+}
+
+func (m *mockUserService) GetUser(ctx context.Context, id int32) (*models.UserResponse, error) {
+	return m.getUser(ctx, id)
+}
+
+func (m *mockUserService) GetUserByName(ctx context.Context, name string) (*models.UserResponse, error) {
+	return m.getByName(ctx, name)
+}
+
+func (m *mockUserService) ListUsers(ctx context.Context, params *models.UserListQuery) (*models.UserListResponse, error) {
+	return m.listUsers(ctx, params)
+}
+
+func (m *mockUserService) UpdateUser(ctx context.Context, id int32, req *models.UpdateUserRequest) (*models.UserResponse, error) {
+	return m.updateUser(ctx, id, req)
+}
+
+func (m *mockUserService) PatchUser(ctx context.Context, id int32, patch func(req *models.UpdateUserRequest) error) (*models.UserResponse, error) {
+	return m.patchUser(ctx, id, patch)
+}
+
+func (m *mockUserService) UpdateUsers(ctx context.Context, items []models.BatchUpdateItem, atomic bool) (*models.BatchUpdateResponse, error) {
+	return m.updateMany(ctx, items, atomic)
+}
+
+func (m *mockUserService) DeleteUser(ctx context.Context, id int32) error {
+	return m.deleteUser(ctx, id)
+}
+
+func (m *mockUserService) ImportUsers(ctx context.Context, r io.Reader, opts models.ImportOptions, progress func(rows int)) (*models.ImportReport, error) {
+	return m.importCSV(ctx, r, opts, progress)
+}
+
+func (m *mockUserService) ExportUsers(ctx context.Context, req *models.ExportUsersRequest, w io.Writer, progress func(rows int)) (*models.ExportReport, error) {
+	return m.exportCSV(ctx, req, w, progress)
+}
+
+func (m *mockUserService) CountExport(ctx context.Context, req *models.ExportUsersRequest) (int64, error) {
+	return m.countExport(ctx, req)
+}
+
+func (m *mockUserService) FindFutureDOBs(ctx context.Context) (*models.DOBValidationResponse, error) {
+	return m.findFuture(ctx)
+}
+
+func (m *mockUserService) BirthdaysToday(ctx context.Context) (*models.BirthdaysResponse, error) {
+	return m.birthdays(ctx)
+}
+
+func (m *mockUserService) BirthdayCalendar(ctx context.Context, params *models.UserListQuery, w io.Writer) error {
+	return m.calendar(ctx, params, w)
+}
+
+func (m *mockUserService) ExportUserData(ctx context.Context, id int32) (*models.UserDataExport, error) {
+	return m.exportData(ctx, id)
+}
+
+func (m *mockUserService) AnonymizeUser(ctx context.Context, id int32) (*models.UserResponse, error) {
+	return m.anonymize(ctx, id)
+}
+
+func (m *mockUserService) AnonymizeUserDryRun(ctx context.Context, id int32) (*models.DryRunResponse, error) {
+	return m.anonymizeDryRun(ctx, id)
+}
+
+func (m *mockUserService) UsersByAge(ctx context.Context, oldest bool, count int) ([]models.UserResponse, error) {
+	return m.byAge(ctx, oldest, count)
+}
+
+func (m *mockUserService) AgePercentile(ctx context.Context, id int32, params *models.UserListQuery) (*models.AgePercentileResponse, error) {
+	return m.percentile(ctx, id, params)
+}
+
+func (m *mockUserService) BirthdayTwins(ctx context.Context, id int32, params *models.UserListQuery) (*models.UserListResponse, error) {
+	return m.twins(ctx, id, params)
+}
+
+func (m *mockUserService) BirthdayCollisions(ctx context.Context, limit int) (*models.BirthdayCollisionsResponse, error) {
+	return m.collisions(ctx, limit)
+}
+
+func (m *mockUserService) Leaplings(ctx context.Context, params *models.UserListQuery) (*models.LeaplingListResponse, error) {
+	return m.leaplings(ctx, params)
+}
+
+func (m *mockUserService) AgeCohorts(ctx context.Context, bounds []int) (*models.CohortsResponse, error) {
+	return m.cohorts(ctx, bounds)
+}
+
+func (m *mockUserService) CreateShare(ctx context.Context, id int32) (*models.ShareResponse, error) {
+	return m.share(ctx, id)
+}
+
+func (m *mockUserService) ListShares(ctx context.Context, id int32) (*models.ShareListResponse, error) {
+	return m.shares(ctx, id)
+}
+
+func (m *mockUserService) RevokeShare(ctx context.Context, id int32, shareID int64) error {
+	return m.revoke(ctx, id, shareID)
+}
+
+func (m *mockUserService) SharedProfile(ctx context.Context, token string) (*models.SharedProfile, error) {
+	return m.shared(ctx, token)
+}
+
+func (m *mockUserService) ResetAggregates() {}
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/srinivasarynh/age_calculator/internal/buildinfo"
+)
+
+type SystemHandler struct {
+	ready func() bool
+}
+
+// NewSystemHandler reports ready once ready returns true; a nil ready is
+// always ready. Liveness (Health) does not depend on it.
+func NewSystemHandler(ready func() bool) *SystemHandler {
+	return &SystemHandler{ready: ready}
+}
+
+func (h *SystemHandler) Health(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"status":  "ok",
+		"time":    time.Now(),
+		"version": buildinfo.Get(),
+	})
+}
+
+func (h *SystemHandler) Ready(c *fiber.Ctx) error {
+	if h.ready != nil && !h.ready() {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status": "not ready",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"status": "ready",
+	})
+}
+
+func (h *SystemHandler) Version(c *fiber.Ctx) error {
+	return c.JSON(buildinfo.Get())
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/srinivasarynh/age_calculator/internal/buildinfo"
+)
+
+func setBuildInfo(t *testing.T, version, commit, date string) {
+	t.Helper()
+	oldVersion, oldCommit, oldDate := buildinfo.Version, buildinfo.Commit, buildinfo.BuildDate
+	buildinfo.Version, buildinfo.Commit, buildinfo.BuildDate = version, commit, date
+	t.Cleanup(func() {
+		buildinfo.Version, buildinfo.Commit, buildinfo.BuildDate = oldVersion, oldCommit, oldDate
+	})
+}
+
+func TestVersion(t *testing.T) {
+	setBuildInfo(t, "1.4.2", "abc1234", "2025-01-15T10:30:00Z")
+
+	app := fiber.New()
+	h := NewSystemHandler(nil)
+	app.Get("/version", h.Version)
+	app.Get("/health", h.Health)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/version", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+
+	var info buildinfo.Info
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		t.Fatal(err)
+	}
+	want := buildinfo.Info{Version: "1.4.2", Commit: "abc1234", BuildDate: "2025-01-15T10:30:00Z", GoVersion: runtime.Version()}
+	if info != want {
+		t.Errorf("version = %+v, want %+v", info, want)
+	}
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/health", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var health struct {
+		Status  string         `json:"status"`
+		Version buildinfo.Info `json:"version"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		t.Fatal(err)
+	}
+	if health.Status != "ok" || health.Version != want {
+		t.Errorf("health = %+v, want status ok with version %+v", health, want)
+	}
+}
+
+func TestReadyFollowsDatabase(t *testing.T) {
+	var ready bool
+	app := fiber.New()
+	h := NewSystemHandler(func() bool { return ready })
+	app.Get("/health", h.Health)
+	app.Get("/ready", h.Ready)
+
+	for _, step := range []struct {
+		ready  bool
+		health int
+		status int
+	}{
+		{ready: false, health: fiber.StatusOK, status: fiber.StatusServiceUnavailable},
+		{ready: true, health: fiber.StatusOK, status: fiber.StatusOK},
+	} {
+		ready = step.ready
+		for path, want := range map[string]int{"/health": step.health, "/ready": step.status} {
+			resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != want {
+				t.Errorf("ready=%v: GET %s = %d, want %d", step.ready, path, resp.StatusCode, want)
+			}
+		}
+	}
+}
@@ -1,30 +1,149 @@
 package handler
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/url"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
+	"github.com/graphql-go/graphql"
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/middleware"
 	"github.com/srinivasarynh/age_calculator/internal/models"
 	"github.com/srinivasarynh/age_calculator/internal/service"
 	"go.uber.org/zap"
 )
 
 type UserHandler struct {
-	service  service.UserService
-	logger   *zap.Logger
-	validate *validator.Validate
+	service    service.UserService
+	jobs       service.JobService
+	logger     *zap.Logger
+	clock      clock.Clock
+	validate   *validator.Validate
+	strictJSON bool
+	namePolicy service.NamePolicy
+	// strictStatus answers 422 rather than 400 for requests that are well
+	// formed but break a rule across parameters.
+	strictStatus bool
+	// maxOffset is the deepest page × page_size a list may ask for.
+	maxOffset int
+	// dateObjects writes the DOB as a models.Date rather than a string.
+	dateObjects bool
+	// xlsxMaxRows is the most rows a direct xlsx export may hold.
+	xlsxMaxRows int
+	// isAdmin tells whether a request may force aggregates to refresh.
+	isAdmin func(*fiber.Ctx) bool
+
+	graphQLOnce sync.Once
+	graphQL     graphql.Schema
+}
+
+type Option func(*UserHandler)
+
+// WithStrictJSON controls whether JSON bodies with unrecognized fields are
+// rejected. It is on by default.
+func WithStrictJSON(strict bool) Option {
+	return func(h *UserHandler) {
+		h.strictJSON = strict
+	}
+}
+
+// WithStrictStatusCodes controls whether a well-formed request that breaks a
+// business rule answers 422 rather than 400. It is on by default.
+func WithStrictStatusCodes(strict bool) Option {
+	return func(h *UserHandler) {
+		h.strictStatus = strict
+	}
+}
+
+// WithNamePolicy sets the characters names may hold on create, update, patch
+// and batch update. It defaults to service.NamePolicyAny.
+func WithNamePolicy(p service.NamePolicy) Option {
+	return func(h *UserHandler) {
+		h.namePolicy = p
+	}
+}
+
+// WithMaxOffset bounds page × page_size on lists; 0 lifts the bound. It
+// defaults to models.DefaultMaxOffset.
+func WithMaxOffset(n int) Option {
+	return func(h *UserHandler) {
+		h.maxOffset = n
+	}
+}
+
+// WithXLSXMaxRows sets the most rows GET /users/export serves as xlsx, which
+// a spreadsheet application reads whole. It defaults to
+// DefaultXLSXMaxRows; larger exports are refused with 413 in favour of an
+// export job.
+func WithXLSXMaxRows(n int) Option {
+	return func(h *UserHandler) {
+		h.xlsxMaxRows = n
+	}
+}
+
+// DefaultXLSXMaxRows is XLSX_MAX_ROWS's default.
+const DefaultXLSXMaxRows = 100_000
+
+// WithDateObjects makes the user CRUD and list routes write the DOB as
+// {"year", "month", "day"}, as /api/v2 does. It is off by default.
+func WithDateObjects(on bool) Option {
+	return func(h *UserHandler) {
+		h.dateObjects = on
+	}
+}
+
+// WithClock sets the clock export filenames are stamped with. It defaults to
+// the real one.
+func WithClock(c clock.Clock) Option {
+	return func(h *UserHandler) {
+		h.clock = c
+	}
+}
+
+// WithJobs enables asynchronous imports and exports, which are submitted to
+// jobs.
+func WithJobs(jobs service.JobService) Option {
+	return func(h *UserHandler) {
+		h.jobs = jobs
+	}
 }
 
-func NewUserHandler(service service.UserService, logger *zap.Logger) *UserHandler {
-	return &UserHandler{
-		service:  service,
-		logger:   logger,
-		validate: validator.New(),
+// WithAdminCheck lets requests isAdmin accepts send refresh=true to the
+// aggregate routes. Without it refresh=true is refused.
+func WithAdminCheck(isAdmin func(*fiber.Ctx) bool) Option {
+	return func(h *UserHandler) {
+		h.isAdmin = isAdmin
 	}
 }
 
+func NewUserHandler(service service.UserService, logger *zap.Logger, opts ...Option) *UserHandler {
+	h := &UserHandler{
+		service:      service,
+		logger:       logger,
+		clock:        clock.Real(),
+		strictJSON:   true,
+		strictStatus: true,
+		maxOffset:    models.DefaultMaxOffset,
+		xlsxMaxRows:  DefaultXLSXMaxRows,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.validate = newValidator(h.namePolicy)
+	return h
+}
+
 func (h *UserHandler) CreateUser(c *fiber.Ctx) error {
 	var req models.CreateUserRequest
 	if err := c.BodyParser(&req); err != nil {
@@ -34,6 +153,13 @@ func (h *UserHandler) CreateUser(c *fiber.Ctx) error {
 		})
 	}
 
+	if details := h.unknownFields(c, &req); len(details) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Unknown fields in request body",
+			"details": details,
+		})
+	}
+
 	if err := h.validate.Struct(req); err != nil {
 		h.logger.Error("Validation failed", zap.Error(err))
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -42,76 +168,452 @@ func (h *UserHandler) CreateUser(c *fiber.Ctx) error {
 		})
 	}
 
-	user, err := h.service.CreateUser(c.Context(), &req)
+	user, err := h.service.CreateUser(c.UserContext(), &req)
 	if err != nil {
-		if errors.Is(err, service.ErrInvalidDate) {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid date format. Expected YYYY-MM-DD",
-			})
-		}
-		h.logger.Error("Failed to create user", zap.Error(err))
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create user",
-		})
+		return fail(h.logger, err, "Failed to create user")
+	}
+	if user.Deduplicated {
+		return c.JSON(h.user(user))
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(user)
+	return c.Status(fiber.StatusCreated).JSON(h.user(user))
 }
 
 func (h *UserHandler) GetUser(c *fiber.Ctx) error {
-	idParam := c.Params("id")
-	id, err := strconv.ParseInt(idParam, 10, 32)
+	id, err := parseID(c)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Invalid user ID",
 		})
 	}
+	var query models.UserQuery
+	if err := c.QueryParser(&query); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid query parameters",
+			"details": queryBoolErrors(c, "include_age_months"),
+		})
+	}
+	query.Include = splitList(query.Include)
+	if err := h.validate.Struct(query); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid query parameters",
+			"details": formatValidationErrors(err),
+		})
+	}
+	ctx := c.UserContext()
+	if query.IncludeAgeMonths {
+		ctx = service.WithAgeMonths(ctx)
+	}
+	if slices.Contains(query.Include, "milestones") {
+		ctx = service.WithMilestones(ctx)
+	}
+	if slices.Contains(query.Include, "progress") {
+		ctx = service.WithBirthdayYearProgress(ctx)
+	}
+	if slices.Contains(query.Include, "access_count") {
+		ctx = service.WithAccessCount(ctx)
+	}
 
-	user, err := h.service.GetUser(c.Context(), int32(id))
+	user, err := h.service.GetUser(ctx, id)
 	if err != nil {
-		if errors.Is(err, service.ErrUserNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "User not found",
-			})
-		}
-		h.logger.Error("Failed to get user", zap.Error(err))
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to get user",
+		return fail(h.logger, err, "Failed to get user")
+	}
+
+	setLastModified(c, user.LastModified)
+	if notModified(c, user.LastModified) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+	return c.JSON(h.user(user))
+}
+
+// user writes user in the shape this handler's API version uses.
+func (h *UserHandler) user(user *models.UserResponse) any {
+	if h.dateObjects {
+		return models.NewUserResponseV2(user)
+	}
+	return user
+}
+
+// MIMETextVCard is the media type of a vCard file, of either version.
+const MIMETextVCard = "text/vcard"
+
+// GetUserVCard answers the user as a vCard 4.0, or 3.0 with ?version=3.
+func (h *UserHandler) GetUserVCard(c *fiber.Ctx) error {
+	id, err := parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+	var query models.VCardQuery
+	if err := c.QueryParser(&query); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid query parameters",
+			"details": queryIntErrors(c, "version"),
 		})
 	}
+	if err := h.validate.Struct(query); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid query parameters",
+			"details": formatValidationErrors(err),
+		})
+	}
+
+	user, err := h.service.GetUser(c.UserContext(), id)
+	if err != nil {
+		return fail(h.logger, err, "Failed to get user")
+	}
+	c.Set(fiber.HeaderContentType, MIMETextVCard+"; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="user-%d.vcf"`, user.ID))
+	return c.Send(service.VCard(user, query.Version))
+}
+
+// ExportUserData answers everything stored about the user as JSON, or with
+// ?format=zip as a ZIP of that JSON and a CSV of the user record.
+func (h *UserHandler) ExportUserData(c *fiber.Ctx) error {
+	id, err := parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+	format := c.Query("format", "json")
+	if format != "json" && format != "zip" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid query parameters",
+			"details": []models.FieldError{{
+				Field:   "format",
+				Rule:    "oneof",
+				Param:   "json zip",
+				Message: "format must be one of [json zip]",
+			}},
+		})
+	}
+
+	export, err := h.service.ExportUserData(c.UserContext(), id)
+	if err != nil {
+		return fail(h.logger, err, "Failed to export user data")
+	}
+	if format == "json" {
+		return c.JSON(export)
+	}
+	bundle, err := service.UserDataZip(export)
+	if err != nil {
+		return fail(h.logger, err, "Failed to export user data")
+	}
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="user-%d.zip"`, id))
+	return c.Send(bundle)
+}
+
+// GetUserByName answers 404 for any name unless UNIQUE_NAMES is on.
+func (h *UserHandler) GetUserByName(c *fiber.Ctx) error {
+	name, err := url.PathUnescape(c.Params("name"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user name",
+		})
+	}
+
+	user, err := h.service.GetUserByName(c.UserContext(), name)
+	if err != nil {
+		return fail(h.logger, err, "Failed to get user by name")
+	}
+
+	setLastModified(c, user.LastModified)
+	if notModified(c, user.LastModified) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
 	return c.JSON(user)
 }
 
-func (h *UserHandler) ListUsers(c *fiber.Ctx) error {
-	var params models.PaginationParams
-	if err := c.QueryParser(&params); err != nil {
-		h.logger.Error("Failed to parse query params", zap.Error(err))
+func (h *UserHandler) BirthdaysToday(c *fiber.Ctx) error {
+	result, err := h.service.BirthdaysToday(c.UserContext())
+	if err != nil {
+		return fail(h.logger, err, "Failed to list birthdays")
 	}
+	return c.JSON(result)
+}
 
-	if err := h.validate.Struct(params); err != nil {
+// AgePercentile ranks the user's age among the users the list filters select.
+func (h *UserHandler) AgePercentile(c *fiber.Ctx) error {
+	id, err := parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+	params, details := h.parseListQuery(c)
+	if params.NameFuzzy != "" {
+		details = append(details, models.FieldError{
+			Field:   "name_fuzzy",
+			Rule:    "excluded",
+			Message: "name_fuzzy is not supported by the percentile",
+		})
+	}
+	if len(details) > 0 {
+		return c.Status(h.validationStatus(details)).JSON(fiber.Map{
+			"error":   "Invalid query parameters",
+			"details": details,
+		})
+	}
+
+	result, err := h.service.AgePercentile(c.UserContext(), id, &params)
+	if err != nil {
+		return fail(h.logger, err, "Failed to rank user")
+	}
+	return c.JSON(result)
+}
+
+// BirthdayTwins lists the other users who share the user's birthday, a page
+// at a time.
+func (h *UserHandler) BirthdayTwins(c *fiber.Ctx) error {
+	id, err := parseID(c)
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+	params, details := h.parseListQuery(c)
+	if len(details) > 0 {
+		return c.Status(h.validationStatus(details)).JSON(fiber.Map{
+			"error":   "Invalid pagination parameters",
+			"details": details,
+		})
+	}
+
+	result, err := h.service.BirthdayTwins(c.UserContext(), id, &params)
+	if err != nil {
+		return fail(h.logger, err, "Failed to list birthday twins")
+	}
+	return c.JSON(result)
+}
+
+// Leaplings lists the users born on Feb 29, a page at a time.
+func (h *UserHandler) Leaplings(c *fiber.Ctx) error {
+	params, details := h.parseListQuery(c)
+	if len(details) > 0 {
+		return c.Status(h.validationStatus(details)).JSON(fiber.Map{
 			"error":   "Invalid pagination parameters",
+			"details": details,
+		})
+	}
+
+	result, err := h.service.Leaplings(c.UserContext(), &params)
+	if err != nil {
+		return fail(h.logger, err, "Failed to list leaplings")
+	}
+	return c.JSON(result)
+}
+
+// AgeCohorts counts the users in each age range between the bounds query
+// parameter, or the configured bounds without one.
+func (h *UserHandler) AgeCohorts(c *fiber.Ctx) error {
+	bounds, err := service.ParseCohortBounds(c.Query("bounds"))
+	if err != nil {
+		return fail(h.logger, err, "Invalid cohort bounds")
+	}
+
+	ctx, refused, err := h.aggregateContext(c)
+	if refused {
+		return err
+	}
+	result, err := h.service.AgeCohorts(ctx, bounds)
+	if err != nil {
+		return fail(h.logger, err, "Failed to count age cohorts")
+	}
+	return c.JSON(result)
+}
+
+// aggregateContext is the context of an aggregate request, which computes
+// its result afresh with refresh=true. Only admins may send it; the request
+// is answered here, and refused reported, when it is not allowed.
+func (h *UserHandler) aggregateContext(c *fiber.Ctx) (context.Context, bool, error) {
+	var query models.RefreshQuery
+	if err := c.QueryParser(&query); err != nil {
+		return nil, true, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid query parameters",
+			"details": queryBoolErrors(c, "refresh"),
+		})
+	}
+	if !query.Refresh {
+		return c.UserContext(), false, nil
+	}
+	if h.isAdmin == nil || !h.isAdmin(c) {
+		return nil, true, c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Refresh not allowed",
+			"details": []models.FieldError{{
+				Field:   "refresh",
+				Rule:    "admin",
+				Message: "refresh=true needs the admin credentials",
+			}},
+		})
+	}
+	return service.WithRefresh(c.UserContext()), false, nil
+}
+
+func (h *UserHandler) BirthdayCollisions(c *fiber.Ctx) error {
+	var query models.CollisionsQuery
+	if err := c.QueryParser(&query); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid query parameters",
+			"details": queryIntErrors(c, "limit"),
+		})
+	}
+	if err := h.validate.Struct(query); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid query parameters",
 			"details": formatValidationErrors(err),
 		})
 	}
 
-	result, err := h.service.ListUsers(c.Context(), &params)
+	limit := 10
+	if query.Limit != nil {
+		limit = *query.Limit
+	}
+	ctx, refused, err := h.aggregateContext(c)
+	if refused {
+		return err
+	}
+	result, err := h.service.BirthdayCollisions(ctx, limit)
 	if err != nil {
-		h.logger.Error("Failed to list users", zap.Error(err))
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to list users",
+		return fail(h.logger, err, "Failed to find birthday collisions")
+	}
+	return c.JSON(result)
+}
+
+func (h *UserHandler) OldestUsers(c *fiber.Ctx) error {
+	return h.usersByAge(c, true)
+}
+
+func (h *UserHandler) YoungestUsers(c *fiber.Ctx) error {
+	return h.usersByAge(c, false)
+}
+
+// usersByAge answers the single oldest or youngest user, or with ?count=N an
+// AgeRankResponse of the top N.
+func (h *UserHandler) usersByAge(c *fiber.Ctx, oldest bool) error {
+	var query models.AgeRankQuery
+	if err := c.QueryParser(&query); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid query parameters",
+			"details": queryIntErrors(c, "count"),
 		})
 	}
+	if err := h.validate.Struct(query); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid query parameters",
+			"details": formatValidationErrors(err),
+		})
+	}
+
+	count := 1
+	if query.Count != nil {
+		count = *query.Count
+	}
+	users, err := h.service.UsersByAge(c.UserContext(), oldest, count)
+	if err != nil {
+		return fail(h.logger, err, "Failed to rank users by age")
+	}
+	if query.Count == nil {
+		return c.JSON(users[0])
+	}
+	return c.JSON(models.AgeRankResponse{Users: users})
+}
+
+// parseListQuery reports every problem at once: QueryParser still fills in
+// the parameters that did parse, so the rest are validated as well.
+func (h *UserHandler) parseListQuery(c *fiber.Ctx) (models.UserListQuery, []models.FieldError) {
+	var params models.UserListQuery
+	var details []models.FieldError
+	if err := c.QueryParser(&params); err != nil {
+		details = append(queryIntErrors(c, "page", "page_size", "min_age", "max_age"), queryBoolErrors(c, "include_total", "include_age", "include_age_months")...)
+		if len(details) == 0 {
+			details = formatValidationErrors(err)
+		}
+	}
+	if err := h.validate.Struct(params); err != nil {
+		details = append(details, formatValidationErrors(err)...)
+	}
+	details = append(details, params.Validate()...)
+	return params, append(details, params.ValidateDepth(h.maxOffset)...)
+}
+
+// MIMETextCalendar is the media type of an iCalendar file.
+const MIMETextCalendar = "text/calendar"
+
+// BirthdayCalendar streams an iCalendar file of the birthdays of the users
+// the list filters select. Headers are sent before the first user is read, so
+// a failure part way ends the body early, without END:VCALENDAR, rather than
+// with an error status.
+func (h *UserHandler) BirthdayCalendar(c *fiber.Ctx) error {
+	params, details := h.parseListQuery(c)
+	if params.NameFuzzy != "" {
+		details = append(details, models.FieldError{
+			Field:   "name_fuzzy",
+			Rule:    "excluded",
+			Message: "name_fuzzy is not supported by the calendar",
+		})
+	}
+	if len(details) > 0 {
+		return c.Status(h.validationStatus(details)).JSON(fiber.Map{
+			"error":   "Invalid query parameters",
+			"details": details,
+		})
+	}
+
+	// The stream is written after the handler returns, when c may already be
+	// reused; only values taken from it now are safe to use.
+	ctx := c.UserContext()
+	setExportHeaders(c, exportFile{
+		ContentType: MIMETextCalendar + "; charset=utf-8",
+		Filename:    service.ExportFilename("birthdays", "ics", h.clock.Now()),
+		Rows:        -1,
+		Filters:     params.Filters(),
+	})
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := h.service.BirthdayCalendar(ctx, &params, w); err != nil {
+			h.logger.Error("Failed to export birthday calendar", zap.Error(err))
+		}
+	})
+	return nil
+}
+
+func (h *UserHandler) ListUsers(c *fiber.Ctx) error {
+	params, details := h.parseListQuery(c)
+	if len(details) > 0 {
+		return c.Status(h.validationStatus(details)).JSON(fiber.Map{
+			"error":   "Invalid pagination parameters",
+			"details": details,
+		})
+	}
+
+	result, err := h.service.ListUsers(c.UserContext(), &params)
+	if err != nil {
+		return fail(h.logger, err, "Failed to list users")
+	}
 
+	// A page's Last-Modified only covers rows still on it; a delete does not
+	// move it forward, so the list never answers 304.
+	var lastModified time.Time
+	for _, user := range result.Users {
+		if user.LastModified.After(lastModified) {
+			lastModified = user.LastModified
+		}
+	}
+	setLastModified(c, lastModified)
+
+	if h.dateObjects {
+		return c.JSON(models.NewUserListResponseV2(result))
+	}
 	return c.JSON(result)
 }
 
 func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
-	idParam := c.Params("id")
-	id, err := strconv.ParseInt(idParam, 10, 32)
+	id, err := parseID(c)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invaid user ID",
+			"error": "Invalid user ID",
 		})
 	}
 
@@ -123,6 +625,13 @@ func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
 		})
 	}
 
+	if details := h.unknownFields(c, &req); len(details) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Unknown fields in request body",
+			"details": details,
+		})
+	}
+
 	if err := h.validate.Struct(req); err != nil {
 		h.logger.Error("Validation failed", zap.Error(err))
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -131,58 +640,448 @@ func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
 		})
 	}
 
-	user, err := h.service.UpdateUser(c.Context(), int32(id), &req)
+	user, err := h.service.UpdateUser(c.UserContext(), id, &req)
 	if err != nil {
-		if errors.Is(err, service.ErrUserNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "User not found",
-			})
+		return fail(h.logger, err, "Failed to update user")
+	}
+
+	return c.JSON(h.user(user))
+}
+
+// MIMEApplicationMergePatchJSON is the media type of an RFC 7386 merge patch.
+const MIMEApplicationMergePatchJSON = "application/merge-patch+json"
+
+// PatchUser applies an RFC 7386 merge patch: absent fields keep their value,
+// present ones are replaced and null clears one. The merged user is validated
+// like a PUT body, so clearing a required field fails with 400.
+func (h *UserHandler) PatchUser(c *fiber.Ctx) error {
+	id, err := parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	// Any patch other than an object would replace the user outright, which
+	// can never produce a valid one.
+	var patch models.UserPatch
+	body := bytes.TrimSpace(c.Body())
+	if len(body) == 0 || body[0] != '{' {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Merge patch must be a JSON object",
+		})
+	}
+	if err := json.Unmarshal(body, &patch); err != nil {
+		h.logger.Error("Failed to parse request body", zap.Error(err))
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if details := h.unknownFields(c, &patch); len(details) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Unknown fields in request body",
+			"details": details,
+		})
+	}
+
+	// The patch is applied to the user as stored inside the service's
+	// transaction; a patch that leaves it invalid is answered here.
+	var rejected fiber.Map
+	user, err := h.service.PatchUser(c.UserContext(), id, func(req *models.UpdateUserRequest) error {
+		if err := patch.Apply(req); err != nil {
+			rejected = fiber.Map{"error": "Invalid request body"}
+			return errPatchRejected
 		}
+		if err := h.validate.Struct(req); err != nil {
+			rejected = fiber.Map{"error": "Validation failed", "details": formatValidationErrors(err)}
+			return errPatchRejected
+		}
+		return nil
+	})
+	if errors.Is(err, errPatchRejected) {
+		return c.Status(fiber.StatusBadRequest).JSON(rejected)
+	}
+	if err != nil {
+		return fail(h.logger, err, "Failed to update user")
+	}
+
+	return c.JSON(h.user(user))
+}
+
+// errPatchRejected rolls back a patch the handler found invalid.
+var errPatchRejected = errors.New("patch rejected")
 
-		if errors.Is(err, service.ErrInvalidDate) {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid date format. Expected YYYY-MM-DD",
+// UpdateUsers applies a JSON array of partial updates. Items are decoded and
+// validated one by one, so a bad item fails in its own result rather than
+// failing the request. An atomic batch that applied nothing answers 422.
+func (h *UserHandler) UpdateUsers(c *fiber.Ctx) error {
+	atomic, err := queryBool(c, "atomic")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid query parameters",
+			"details": queryBoolErrors(c, "atomic"),
+		})
+	}
+
+	var raw []json.RawMessage
+	body := bytes.TrimSpace(c.Body())
+	if len(body) == 0 || body[0] != '[' {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Batch must be a JSON array",
+		})
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		h.logger.Error("Failed to parse request body", zap.Error(err))
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if len(raw) == 0 || len(raw) > models.MaxBatchSize {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Batch must contain between 1 and %d items", models.MaxBatchSize),
+		})
+	}
+
+	items := make([]models.BatchUpdateItem, len(raw))
+	for i, item := range raw {
+		items[i] = h.decodeBatchItem(item)
+	}
+
+	result, err := h.service.UpdateUsers(c.UserContext(), items, atomic)
+	if err != nil {
+		return fail(h.logger, err, "Failed to update users")
+	}
+
+	if atomic && result.Failed > 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(result)
+	}
+	return c.JSON(result)
+}
+
+// decodeBatchItem records every problem on the item instead of returning it.
+// null cannot clear name or dob, so it is reported as a missing value rather
+// than silently treated as absent.
+func (h *UserHandler) decodeBatchItem(raw json.RawMessage) models.BatchUpdateItem {
+	var item models.BatchUpdateItem
+	var keys map[string]json.RawMessage
+	if json.Unmarshal(raw, &keys) != nil || json.Unmarshal(raw, &item) != nil {
+		item.Invalid = []models.FieldError{{Rule: "invalid", Message: "item must be an object with an id and the fields to change"}}
+		return item
+	}
+
+	if h.strictJSON {
+		if details := unknownKeys(keys, &item); len(details) > 0 {
+			item.Invalid = details
+			return item
+		}
+	}
+
+	for _, field := range []string{"name", "dob"} {
+		for key, value := range keys {
+			if strings.EqualFold(key, field) && string(value) == "null" {
+				item.Invalid = append(item.Invalid, models.FieldError{Field: field, Rule: "required", Message: field + " cannot be cleared"})
+			}
+		}
+	}
+	if err := h.validate.Struct(item); err != nil {
+		item.Invalid = append(item.Invalid, formatValidationErrors(err)...)
+	}
+	return item
+}
+
+// MIMETextCSV is the media type of a user import upload.
+const MIMETextCSV = "text/csv"
+
+// ImportUsers creates users from a CSV upload and answers with the import
+// report, or 422 with it when an atomic import was rolled back. With
+// async=true the upload is stored as a job instead, and the response is 202
+// with the job to poll.
+func (h *UserHandler) ImportUsers(c *fiber.Ctx) error {
+	async, asyncErr := queryBool(c, "async")
+	atomic, atomicErr := queryBool(c, "atomic")
+	if asyncErr != nil || atomicErr != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid query parameters",
+			"details": queryBoolErrors(c, "async", "atomic"),
+		})
+	}
+	opts := models.ImportOptions{Conflict: c.Query("conflict"), Atomic: atomic}
+	if err := h.validate.Struct(opts); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid query parameters",
+			"details": formatValidationErrors(err),
+		})
+	}
+
+	body := c.Body()
+	if len(bytes.TrimSpace(body)) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Import file is empty",
+		})
+	}
+
+	if async {
+		if h.jobs == nil {
+			return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{
+				"error": "Asynchronous import is not enabled",
 			})
 		}
+		payload, err := json.Marshal(models.ImportJobPayload{Options: opts, File: body})
+		if err != nil {
+			return fail(h.logger, err, "Failed to submit import")
+		}
+		job, err := h.jobs.SubmitJob(c.UserContext(), models.JobUserImport, payload)
+		if err != nil {
+			return fail(h.logger, err, "Failed to submit import")
+		}
+		c.Location(fmt.Sprintf("/api/v1/jobs/%d", job.ID))
+		return c.Status(fiber.StatusAccepted).JSON(job)
+	}
+
+	report, err := h.service.ImportUsers(c.UserContext(), bytes.NewReader(body), opts, nil)
+	if err != nil {
+		return fail(h.logger, err, "Failed to import users")
+	}
+	if report.RolledBack {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(report)
+	}
+	return c.JSON(report)
+}
+
+// DownloadUsers streams the export the query describes, in any export
+// format, without submitting a job. As with the birthday calendar a failure
+// part way ends the body early rather than with an error status.
+func (h *UserHandler) DownloadUsers(c *fiber.Ctx) error {
+	var req models.ExportUsersRequest
+	if err := c.QueryParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid query parameters",
+			"details": queryIntErrors(c, "version"),
+		})
+	}
+	if err := h.validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid query parameters",
+			"details": formatValidationErrors(err),
+		})
+	}
+	if req.Format == "" {
+		req.Format = models.ExportCSV
+	}
+	contentType, _ := service.ExportContentType(req.Format)
+	if req.Format == models.ExportXLSX {
+		if refused, err := h.refuseLargeXLSX(c, &req); refused || err != nil {
+			return err
+		}
+	} else {
+		contentType += "; charset=utf-8"
+	}
+
+	ctx := c.UserContext()
+	setExportHeaders(c, exportFile{
+		ContentType: contentType,
+		Filename:    service.ExportFilename("users-export", req.Format, h.clock.Now()),
+		Rows:        -1,
+		Filters:     req.Filters(),
+	})
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if _, err := h.service.ExportUsers(ctx, &req, w, nil); err != nil {
+			h.logger.Error("Failed to export users", zap.Error(err))
+		}
+	})
+	return nil
+}
+
+// refuseLargeXLSX answers 413, and reports that it did, when the users req
+// selects are more than a direct xlsx export may hold.
+func (h *UserHandler) refuseLargeXLSX(c *fiber.Ctx, req *models.ExportUsersRequest) (bool, error) {
+	total, err := h.service.CountExport(c.UserContext(), req)
+	if err != nil {
+		return true, fail(h.logger, err, "Failed to count users to export")
+	}
+	if total <= int64(h.xlsxMaxRows) {
+		return false, nil
+	}
+	limit := strconv.Itoa(h.xlsxMaxRows)
+	return true, c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+		"error": "Export too large for a direct download",
+		"details": []models.FieldError{{
+			Field:   "format",
+			Rule:    "max_rows",
+			Param:   limit,
+			Message: fmt.Sprintf("an xlsx export of more than %s rows must be submitted with POST /api/v1/users/export/jobs", limit),
+		}},
+	})
+}
+
+// ExportUsers submits an export of the users matching the body's filters as a
+// job and answers 202 with the job to poll; the file is downloaded from the
+// job once it is done.
+func (h *UserHandler) ExportUsers(c *fiber.Ctx) error {
+	var req models.ExportUsersRequest
+	if err := c.BodyParser(&req); err != nil {
+		h.logger.Error("Failed to parse request body", zap.Error(err))
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if details := h.unknownFields(c, &req); len(details) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Unknown fields in request body",
+			"details": details,
+		})
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Validation failed",
+			"details": formatValidationErrors(err),
+		})
+	}
+
+	if h.jobs == nil {
+		return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{
+			"error": "Asynchronous export is not enabled",
+		})
+	}
+	if req.Format == "" {
+		req.Format = models.ExportCSV
+	}
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return fail(h.logger, err, "Failed to submit export")
+	}
+	job, err := h.jobs.SubmitJob(c.UserContext(), models.JobUserExport, payload)
+	if err != nil {
+		return fail(h.logger, err, "Failed to submit export")
+	}
+	c.Location(fmt.Sprintf("/api/v1/jobs/%d", job.ID))
+	return c.Status(fiber.StatusAccepted).JSON(job)
+}
 
-		h.logger.Error("Failed to update user", zap.Error(err))
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update user",
+func (h *UserHandler) AnonymizeUser(c *fiber.Ctx) error {
+	id, err := parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+	var query models.DryRunQuery
+	if err := c.QueryParser(&query); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid query parameters",
+			"details": queryBoolErrors(c, "dry_run"),
 		})
 	}
+	if query.DryRun {
+		result, err := h.service.AnonymizeUserDryRun(c.UserContext(), id)
+		if err != nil {
+			return fail(h.logger, err, "Failed to anonymize user")
+		}
+		return c.JSON(result)
+	}
+
+	user, err := h.service.AnonymizeUser(c.UserContext(), id)
+	if err != nil {
+		return fail(h.logger, err, "Failed to anonymize user")
+	}
 
 	return c.JSON(user)
 }
 
-func (h *UserHandler) DeleteUser(c *fiber.Ctx) error {
-	idParam := c.Params("id")
-	id, err := strconv.ParseInt(idParam, 10, 32)
+func (h *UserHandler) CreateShare(c *fiber.Ctx) error {
+	id, err := parseID(c)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Invalid user ID",
 		})
 	}
 
-	if err := h.service.DeleteUser(c.Context(), int32(id)); err != nil {
-		if errors.Is(err, service.ErrUserNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "User not found",
-			})
-		}
+	share, err := h.service.CreateShare(c.UserContext(), id)
+	if err != nil {
+		return fail(h.logger, err, "Failed to create share")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(share)
+}
 
-		h.logger.Error("Failed to delete user", zap.Error(err))
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to delete user",
+func (h *UserHandler) ListShares(c *fiber.Ctx) error {
+	id, err := parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
 		})
 	}
 
+	shares, err := h.service.ListShares(c.UserContext(), id)
+	if err != nil {
+		return fail(h.logger, err, "Failed to list shares")
+	}
+
+	return c.JSON(shares)
+}
+
+func (h *UserHandler) RevokeShare(c *fiber.Ctx) error {
+	id, err := parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+	shareID, err := strconv.ParseInt(c.Params("token_id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid share ID",
+		})
+	}
+
+	if err := h.service.RevokeShare(c.UserContext(), id, shareID); err != nil {
+		return fail(h.logger, err, "Failed to revoke share")
+	}
+
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
-func formatValidationErrors(err error) []string {
-	var errors []string
-	for _, err := range err.(validator.ValidationErrors) {
-		errors = append(errors, err.Field()+" validation failed on "+err.Tag())
+// SharedProfile needs no tenant header: the token names the user.
+func (h *UserHandler) SharedProfile(c *fiber.Ctx) error {
+	profile, err := h.service.SharedProfile(c.UserContext(), c.Params("token"))
+	if err != nil {
+		return fail(h.logger, err, "Failed to read shared profile")
+	}
+
+	return c.JSON(profile)
+}
+
+func (h *UserHandler) DeleteUser(c *fiber.Ctx) error {
+	id, err := parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	if err := h.service.DeleteUser(c.UserContext(), id); err != nil {
+		return fail(h.logger, err, "Failed to delete user")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// fail hands err to middleware.ErrorHandler, logging it first when it is not
+// one of the sentinels the error handler maps to a client error.
+func fail(logger *zap.Logger, err error, message string) error {
+	if !middleware.Known(err) {
+		logger.Error(message, zap.Error(err))
+	}
+	return middleware.Fail(err, message)
+}
+
+func parseID(c *fiber.Ctx) (int32, error) {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 32)
+	if err != nil {
+		return 0, err
 	}
-	return errors
+	return int32(id), nil
 }
@@ -0,0 +1,34 @@
+package handler_test
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+)
+
+func BenchmarkListUsersHTTP(b *testing.B) {
+	repo := repository.NewMemoryUserRepository(clock.Fixed(goldenNow))
+	start := time.Date(1950, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 10000; i++ {
+		if _, err := repo.Create(context.Background(), "User", start.AddDate(0, 0, i*3)); err != nil {
+			b.Fatal(err)
+		}
+	}
+	app := newGoldenApp(b, repo)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/users?page=50&page_size=100", nil), -1)
+		if err != nil {
+			b.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+}
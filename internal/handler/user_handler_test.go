@@ -0,0 +1,2181 @@
+package handler_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/srinivasarynh/age_calculator/config"
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/events"
+	"github.com/srinivasarynh/age_calculator/internal/handler"
+	"github.com/srinivasarynh/age_calculator/internal/metrics"
+	"github.com/srinivasarynh/age_calculator/internal/middleware"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/routes"
+	"github.com/srinivasarynh/age_calculator/internal/service"
+	"github.com/srinivasarynh/age_calculator/internal/testutil"
+	"go.uber.org/zap"
+)
+
+// testAdminGuard admits app.Test's requests, which come from 0.0.0.0, to the
+// admin-only routes.
+var testAdminGuard = middleware.AdminOnly(config.NewHolder(&config.Config{AdminAllowedIPs: []string{"0.0.0.0"}}))
+
+func newTestApp(svc service.UserService) *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	routes.SetupRoutes(app, handler.NewUserHandler(svc, zap.NewNop()), testAdminGuard, middleware.Tenant(config.NewHolder(&config.Config{})), routes.CachePolicies{})
+	return app
+}
+
+func doRequest(t *testing.T, app *fiber.App, method, target, body string) (int, map[string]any) {
+	t.Helper()
+	return doTenantRequest(t, app, "", method, target, body)
+}
+
+// doTenantRequest sends X-Tenant-ID unless tenantID is empty.
+func doTenantRequest(t *testing.T, app *fiber.App, tenantID, method, target, body string) (int, map[string]any) {
+	t.Helper()
+
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if tenantID != "" {
+		req.Header.Set(middleware.HeaderTenantID, tenantID)
+	}
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(raw) == 0 {
+		return resp.StatusCode, nil
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatalf("response is not a JSON object: %s", raw)
+	}
+	return resp.StatusCode, decoded
+}
+
+func TestCreateUser(t *testing.T) {
+	var received *models.CreateUserRequest
+	svc := &mockUserService{
+		createUser: func(ctx context.Context, req *models.CreateUserRequest) (*models.UserResponse, error) {
+			received = req
+			return &models.UserResponse{ID: 1, Name: req.Name, DOB: req.DOB}, nil
+		},
+	}
+
+	alice := testutil.NewUserBuilder().WithName("Alice").WithDOB("1990-05-10")
+	status, body := doRequest(t, newTestApp(svc), "POST", "/api/v1/users", alice.JSON())
+	if status != fiber.StatusCreated {
+		t.Fatalf("status = %d, want 201", status)
+	}
+	if received == nil || received.Name != "Alice" || received.DOB != "1990-05-10" {
+		t.Errorf("service received %+v", received)
+	}
+	want := map[string]any{"id": float64(1), "name": "Alice", "dob": "1990-05-10"}
+	if !reflect.DeepEqual(body, want) {
+		t.Errorf("body = %v, want %v", body, want)
+	}
+}
+
+func TestCreateUserDeduplicated(t *testing.T) {
+	svc := &mockUserService{
+		createUser: func(ctx context.Context, req *models.CreateUserRequest) (*models.UserResponse, error) {
+			return &models.UserResponse{ID: 1, Name: req.Name, DOB: req.DOB, Deduplicated: true}, nil
+		},
+	}
+
+	alice := testutil.NewUserBuilder().WithName("Alice").WithDOB("1990-05-10")
+	status, body := doRequest(t, newTestApp(svc), "POST", "/api/v1/users", alice.JSON())
+	if status != fiber.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	if body["id"] != float64(1) || body["deduplicated"] != true {
+		t.Errorf("body = %v, want user 1 marked deduplicated", body)
+	}
+}
+
+func TestCreateUserErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		serviceErr error
+		status     int
+		expected   map[string]any
+	}{
+		{
+			name:     "malformed json",
+			body:     `{"name":`,
+			status:   fiber.StatusBadRequest,
+			expected: map[string]any{"error": "Invalid request body"},
+		},
+		{
+			name:   "missing fields",
+			body:   `{}`,
+			status: fiber.StatusBadRequest,
+			expected: map[string]any{
+				"error": "Validation failed",
+				"details": []any{
+					map[string]any{"field": "name", "rule": "required", "message": "name is required"},
+					map[string]any{"field": "dob", "rule": "required", "message": "dob is required"},
+				},
+			},
+		},
+		{
+			name:   "name too short and bad date",
+			body:   `{"name":"A","dob":"10-05-1990"}`,
+			status: fiber.StatusBadRequest,
+			expected: map[string]any{
+				"error": "Validation failed",
+				"details": []any{
+					map[string]any{"field": "name", "rule": "min", "param": "2", "message": "name must be at least 2 characters"},
+					map[string]any{"field": "dob", "rule": "datetime", "param": "2006-01-02", "message": "dob must be a date in the format 2006-01-02"},
+				},
+			},
+		},
+		{
+			name:   "misnamed field",
+			body:   `{"name":"Alice","dateOfBirth":"1990-01-01"}`,
+			status: fiber.StatusBadRequest,
+			expected: map[string]any{
+				"error": "Unknown fields in request body",
+				"details": []any{
+					map[string]any{"field": "dateOfBirth", "rule": "unknown", "message": "dateOfBirth is not a recognized field"},
+				},
+			},
+		},
+		{
+			name:   "nested junk",
+			body:   `{"name":"Alice","dob":"1990-05-10","meta":{"tags":["a"],"extra":{"x":1}},"admin":true}`,
+			status: fiber.StatusBadRequest,
+			expected: map[string]any{
+				"error": "Unknown fields in request body",
+				"details": []any{
+					map[string]any{"field": "admin", "rule": "unknown", "message": "admin is not a recognized field"},
+					map[string]any{"field": "meta", "rule": "unknown", "message": "meta is not a recognized field"},
+				},
+			},
+		},
+		{
+			name:       "invalid date from service",
+			body:       `{"name":"Alice","dob":"1990-05-10"}`,
+			serviceErr: service.ErrInvalidDate,
+			status:     fiber.StatusBadRequest,
+			expected:   map[string]any{"error": "Invalid date format. Expected YYYY-MM-DD"},
+		},
+		{
+			name:       "dob before the minimum year",
+			body:       `{"name":"Alice","dob":"0001-01-01"}`,
+			serviceErr: fmt.Errorf("%w: 0001-01-01 is before 1900", service.ErrDOBTooEarly),
+			status:     fiber.StatusUnprocessableEntity,
+			expected:   map[string]any{"error": "Date of birth is before the earliest year allowed"},
+		},
+		{
+			name: "name too short once normalized",
+			body: `{"name":" A​ ","dob":"1990-05-10"}`,
+			serviceErr: &service.ValidationError{Details: []models.FieldError{
+				{Field: "name", Rule: "min", Param: "2", Message: "name must be at least 2 characters"},
+			}},
+			status: fiber.StatusBadRequest,
+			expected: map[string]any{
+				"error": "Validation failed",
+				"details": []any{
+					map[string]any{"field": "name", "rule": "min", "param": "2", "message": "name must be at least 2 characters"},
+				},
+			},
+		},
+		{
+			name:       "unexpected service error",
+			body:       `{"name":"Alice","dob":"1990-05-10"}`,
+			serviceErr: errors.New("connection reset"),
+			status:     fiber.StatusInternalServerError,
+			expected:   map[string]any{"error": "Failed to create user"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &mockUserService{
+				createUser: func(ctx context.Context, req *models.CreateUserRequest) (*models.UserResponse, error) {
+					if tt.serviceErr == nil {
+						t.Fatal("service should not be called")
+					}
+					return nil, tt.serviceErr
+				},
+			}
+
+			status, body := doRequest(t, newTestApp(svc), "POST", "/api/v1/users", tt.body)
+			if status != tt.status {
+				t.Errorf("status = %d, want %d", status, tt.status)
+			}
+			if !reflect.DeepEqual(body, tt.expected) {
+				t.Errorf("body = %v, want %v", body, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetUser(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		serviceErr error
+		status     int
+		expected   map[string]any
+	}{
+		{
+			name:     "found",
+			target:   "/api/v1/users/7",
+			status:   fiber.StatusOK,
+			expected: map[string]any{"id": float64(7), "name": "Alice", "dob": "1990-05-10", "age": float64(34)},
+		},
+		{
+			name:       "not found",
+			target:     "/api/v1/users/7",
+			serviceErr: service.ErrUserNotFound,
+			status:     fiber.StatusNotFound,
+			expected:   map[string]any{"error": "User not found"},
+		},
+		{
+			name:       "wrapped not found",
+			target:     "/api/v1/users/7",
+			serviceErr: fmt.Errorf("loading user 7: %w", service.ErrUserNotFound),
+			status:     fiber.StatusNotFound,
+			expected:   map[string]any{"error": "User not found"},
+		},
+		{
+			name:       "unexpected service error",
+			target:     "/api/v1/users/7",
+			serviceErr: errors.New("boom"),
+			status:     fiber.StatusInternalServerError,
+			expected:   map[string]any{"error": "Failed to get user"},
+		},
+		{
+			name:     "non-numeric id",
+			target:   "/api/v1/users/abc",
+			status:   fiber.StatusBadRequest,
+			expected: map[string]any{"error": "Invalid user ID"},
+		},
+		{
+			name:     "id overflows int32",
+			target:   "/api/v1/users/99999999999",
+			status:   fiber.StatusBadRequest,
+			expected: map[string]any{"error": "Invalid user ID"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &mockUserService{
+				getUser: func(ctx context.Context, id int32) (*models.UserResponse, error) {
+					if tt.serviceErr != nil {
+						return nil, tt.serviceErr
+					}
+					return testutil.NewUserBuilder().WithID(id).WithName("Alice").WithDOB("1990-05-10").WithAge(34).Response(), nil
+				},
+			}
+
+			status, body := doRequest(t, newTestApp(svc), "GET", tt.target, "")
+			if status != tt.status {
+				t.Errorf("status = %d, want %d", status, tt.status)
+			}
+			if !reflect.DeepEqual(body, tt.expected) {
+				t.Errorf("body = %v, want %v", body, tt.expected)
+			}
+		})
+	}
+}
+
+func TestListUsers(t *testing.T) {
+	var received models.UserListQuery
+	total, totalPages := int64(11), 3
+	svc := &mockUserService{
+		listUsers: func(ctx context.Context, params *models.UserListQuery) (*models.UserListResponse, error) {
+			received = *params
+			return &models.UserListResponse{
+				Users:      []models.UserResponse{*testutil.NewUserBuilder().WithName("Alice").WithDOB("1990-05-10").WithAge(34).Response()},
+				Total:      &total,
+				Page:       2,
+				PageSize:   5,
+				TotalPages: &totalPages,
+			}, nil
+		},
+	}
+
+	status, body := doRequest(t, newTestApp(svc), "GET", "/api/v1/users?page=2&page_size=5", "")
+	if status != fiber.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	if received.Page != 2 || received.PageSize != 5 {
+		t.Errorf("service received %+v", received)
+	}
+	want := map[string]any{
+		"users":       []any{map[string]any{"id": float64(1), "name": "Alice", "dob": "1990-05-10", "age": float64(34)}},
+		"total":       float64(11),
+		"page":        float64(2),
+		"page_size":   float64(5),
+		"total_pages": float64(3),
+	}
+	if !reflect.DeepEqual(body, want) {
+		t.Errorf("body = %v, want %v", body, want)
+	}
+}
+
+func TestListUsersErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		serviceErr error
+		status     int
+		expected   map[string]any
+	}{
+		{
+			name:   "page size above maximum",
+			target: "/api/v1/users?page_size=101",
+			status: fiber.StatusBadRequest,
+			expected: map[string]any{
+				"error":   "Invalid pagination parameters",
+				"details": []any{map[string]any{"field": "page_size", "rule": "max", "param": "100", "message": "page_size must be at most 100"}},
+			},
+		},
+		{
+			name:   "negative page",
+			target: "/api/v1/users?page=-5",
+			status: fiber.StatusBadRequest,
+			expected: map[string]any{
+				"error":   "Invalid pagination parameters",
+				"details": []any{map[string]any{"field": "page", "rule": "min", "param": "1", "message": "page must be at least 1"}},
+			},
+		},
+		{
+			name:   "include_total not a boolean",
+			target: "/api/v1/users?include_total=maybe",
+			status: fiber.StatusBadRequest,
+			expected: map[string]any{
+				"error":   "Invalid pagination parameters",
+				"details": []any{map[string]any{"field": "include_total", "rule": "boolean", "message": "include_total must be true or false"}},
+			},
+		},
+		{
+			name:   "include_age not a boolean",
+			target: "/api/v1/users?include_age=maybe",
+			status: fiber.StatusBadRequest,
+			expected: map[string]any{
+				"error":   "Invalid pagination parameters",
+				"details": []any{map[string]any{"field": "include_age", "rule": "boolean", "message": "include_age must be true or false"}},
+			},
+		},
+		{
+			name:   "unknown sort field",
+			target: "/api/v1/users?sort=name",
+			status: fiber.StatusBadRequest,
+			expected: map[string]any{
+				"error":   "Invalid pagination parameters",
+				"details": []any{map[string]any{"field": "sort", "rule": "oneof", "param": "id -id created_at -created_at age -age", "message": "sort must be one of: id, -id, created_at, -created_at, age, -age"}},
+			},
+		},
+		{
+			name:   "negative page size",
+			target: "/api/v1/users?page_size=-1",
+			status: fiber.StatusBadRequest,
+			expected: map[string]any{
+				"error":   "Invalid pagination parameters",
+				"details": []any{map[string]any{"field": "page_size", "rule": "min", "param": "1", "message": "page_size must be at least 1"}},
+			},
+		},
+		{
+			name:   "non-numeric page",
+			target: "/api/v1/users?page=abc",
+			status: fiber.StatusBadRequest,
+			expected: map[string]any{
+				"error":   "Invalid pagination parameters",
+				"details": []any{map[string]any{"field": "page", "rule": "integer", "message": "page must be an integer"}},
+			},
+		},
+		{
+			name:       "unexpected service error",
+			target:     "/api/v1/users",
+			serviceErr: errors.New("boom"),
+			status:     fiber.StatusInternalServerError,
+			expected:   map[string]any{"error": "Failed to list users"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &mockUserService{
+				listUsers: func(ctx context.Context, params *models.UserListQuery) (*models.UserListResponse, error) {
+					if tt.serviceErr == nil {
+						t.Fatal("service should not be called")
+					}
+					return nil, tt.serviceErr
+				},
+			}
+
+			status, body := doRequest(t, newTestApp(svc), "GET", tt.target, "")
+			if status != tt.status {
+				t.Errorf("status = %d, want %d", status, tt.status)
+			}
+			if !reflect.DeepEqual(body, tt.expected) {
+				t.Errorf("body = %v, want %v", body, tt.expected)
+			}
+		})
+	}
+}
+
+func TestListUsersReportsEveryProblem(t *testing.T) {
+	// Requests that only break a rule across parameters answer 422.
+	tests := []struct {
+		target string
+		want   []string
+		status int
+	}{
+		{"/api/v1/users?sort=nonsense&page_size=0", []string{
+			"sort must be one of: id, -id, created_at, -created_at, age, -age",
+		}, fiber.StatusBadRequest},
+		{"/api/v1/users?sort=nonsense&page_size=500", []string{
+			"page_size must be at most 100",
+			"sort must be one of: id, -id, created_at, -created_at, age, -age",
+		}, fiber.StatusBadRequest},
+		{"/api/v1/users?min_age=40&max_age=30", []string{
+			"min_age must not be greater than max_age",
+		}, fiber.StatusUnprocessableEntity},
+		{"/api/v1/users?min_age=-1&max_age=151", []string{
+			"min_age must be at least 0",
+			"max_age must be at most 150",
+		}, fiber.StatusBadRequest},
+		{"/api/v1/users?min_age=old&max_age=30", []string{
+			"min_age must be an integer",
+		}, fiber.StatusBadRequest},
+		{"/api/v1/users?dob_from=yesterday", []string{
+			"dob_from must be a date in the format 2006-01-02",
+		}, fiber.StatusBadRequest},
+		{"/api/v1/users?dob_from=2000-13-01&dob_to=1990-01-01", []string{
+			"dob_from must be a date in the format 2006-01-02",
+		}, fiber.StatusBadRequest},
+		{"/api/v1/users?dob_from=2000-01-01&dob_to=1990-01-01", []string{
+			"dob_from must not be after dob_to",
+		}, fiber.StatusUnprocessableEntity},
+		{"/api/v1/users?cursor=abc&page=2", []string{
+			"cursor cannot be combined with page",
+		}, fiber.StatusBadRequest},
+		{"/api/v1/users?cursor=abc&page=abc", []string{
+			"page must be an integer",
+		}, fiber.StatusBadRequest},
+		{"/api/v1/users?page=abc&include_total=maybe&sort=name", []string{
+			"page must be an integer",
+			"include_total must be true or false",
+			"sort must be one of: id, -id, created_at, -created_at, age, -age",
+		}, fiber.StatusBadRequest},
+		{"/api/v1/users?cursor=abc&page=3&dob_from=2000-01-02&dob_to=2000-01-01&min_age=9&max_age=8&name=" + strings.Repeat("a", 101), []string{
+			"name must be at most 100 characters",
+			"cursor cannot be combined with page",
+			"dob_from must not be after dob_to",
+			"min_age must not be greater than max_age",
+		}, fiber.StatusBadRequest},
+	}
+
+	svc := &mockUserService{
+		listUsers: func(ctx context.Context, params *models.UserListQuery) (*models.UserListResponse, error) {
+			t.Fatal("service should not be called")
+			return nil, nil
+		},
+	}
+	app := newTestApp(svc)
+	lenient := fiber.New()
+	routes.SetupRoutes(lenient, handler.NewUserHandler(svc, zap.NewNop(), handler.WithStrictStatusCodes(false)), testAdminGuard, middleware.Tenant(config.NewHolder(&config.Config{})), routes.CachePolicies{})
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			status, body := doRequest(t, app, "GET", tt.target, "")
+			if status != tt.status || body["error"] != "Invalid pagination parameters" {
+				t.Fatalf("response = %d %v, want %d", status, body, tt.status)
+			}
+			if status, _ := doRequest(t, lenient, "GET", tt.target, ""); status != fiber.StatusBadRequest {
+				t.Errorf("without strict status codes: status = %d, want 400", status)
+			}
+			var got []string
+			for _, d := range body["details"].([]any) {
+				got = append(got, d.(map[string]any)["message"].(string))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("messages = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateUser(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		body       string
+		serviceErr error
+		status     int
+		expected   map[string]any
+	}{
+		{
+			name:     "updated",
+			target:   "/api/v1/users/3",
+			body:     `{"name":"Alice Updated","dob":"1991-03-15"}`,
+			status:   fiber.StatusOK,
+			expected: map[string]any{"id": float64(3), "name": "Alice Updated", "dob": "1991-03-15"},
+		},
+		{
+			name:     "invalid id",
+			target:   "/api/v1/users/x",
+			body:     `{"name":"Alice","dob":"1991-03-15"}`,
+			status:   fiber.StatusBadRequest,
+			expected: map[string]any{"error": "Invalid user ID"},
+		},
+		{
+			name:     "malformed json",
+			target:   "/api/v1/users/3",
+			body:     `not json`,
+			status:   fiber.StatusBadRequest,
+			expected: map[string]any{"error": "Invalid request body"},
+		},
+		{
+			name:   "validation failure",
+			target: "/api/v1/users/3",
+			body:   `{"name":"Alice"}`,
+			status: fiber.StatusBadRequest,
+			expected: map[string]any{
+				"error":   "Validation failed",
+				"details": []any{map[string]any{"field": "dob", "rule": "required", "message": "dob is required"}},
+			},
+		},
+		{
+			name:       "not found",
+			target:     "/api/v1/users/3",
+			body:       `{"name":"Alice","dob":"1991-03-15"}`,
+			serviceErr: service.ErrUserNotFound,
+			status:     fiber.StatusNotFound,
+			expected:   map[string]any{"error": "User not found"},
+		},
+		{
+			name:       "invalid date from service",
+			target:     "/api/v1/users/3",
+			body:       `{"name":"Alice","dob":"1991-03-15"}`,
+			serviceErr: service.ErrInvalidDate,
+			status:     fiber.StatusBadRequest,
+			expected:   map[string]any{"error": "Invalid date format. Expected YYYY-MM-DD"},
+		},
+		{
+			name:       "unexpected service error",
+			target:     "/api/v1/users/3",
+			body:       `{"name":"Alice","dob":"1991-03-15"}`,
+			serviceErr: errors.New("boom"),
+			status:     fiber.StatusInternalServerError,
+			expected:   map[string]any{"error": "Failed to update user"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &mockUserService{
+				updateUser: func(ctx context.Context, id int32, req *models.UpdateUserRequest) (*models.UserResponse, error) {
+					if tt.serviceErr != nil {
+						return nil, tt.serviceErr
+					}
+					return &models.UserResponse{ID: id, Name: req.Name, DOB: req.DOB}, nil
+				},
+			}
+
+			status, body := doRequest(t, newTestApp(svc), "PUT", tt.target, tt.body)
+			if status != tt.status {
+				t.Errorf("status = %d, want %d", status, tt.status)
+			}
+			if !reflect.DeepEqual(body, tt.expected) {
+				t.Errorf("body = %v, want %v", body, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		serviceErr error
+		status     int
+		expected   map[string]any
+	}{
+		{
+			name:   "deleted",
+			target: "/api/v1/users/3",
+			status: fiber.StatusNoContent,
+		},
+		{
+			name:     "invalid id",
+			target:   "/api/v1/users/-",
+			status:   fiber.StatusBadRequest,
+			expected: map[string]any{"error": "Invalid user ID"},
+		},
+		{
+			name:       "not found",
+			target:     "/api/v1/users/3",
+			serviceErr: service.ErrUserNotFound,
+			status:     fiber.StatusNotFound,
+			expected:   map[string]any{"error": "User not found"},
+		},
+		{
+			name:       "wrapped not found",
+			target:     "/api/v1/users/3",
+			serviceErr: fmt.Errorf("deleting user 3: %w", service.ErrUserNotFound),
+			status:     fiber.StatusNotFound,
+			expected:   map[string]any{"error": "User not found"},
+		},
+		{
+			name:       "unexpected service error",
+			target:     "/api/v1/users/3",
+			serviceErr: errors.New("boom"),
+			status:     fiber.StatusInternalServerError,
+			expected:   map[string]any{"error": "Failed to delete user"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deleted := int32(0)
+			svc := &mockUserService{
+				deleteUser: func(ctx context.Context, id int32) error {
+					deleted = id
+					return tt.serviceErr
+				},
+			}
+
+			status, body := doRequest(t, newTestApp(svc), "DELETE", tt.target, "")
+			if status != tt.status {
+				t.Errorf("status = %d, want %d", status, tt.status)
+			}
+			if !reflect.DeepEqual(body, tt.expected) {
+				t.Errorf("body = %v, want %v", body, tt.expected)
+			}
+			if tt.status == fiber.StatusNoContent && deleted != 3 {
+				t.Errorf("deleted id = %d, want 3", deleted)
+			}
+		})
+	}
+}
+
+func TestWriteResponsesMatchGet(t *testing.T) {
+	app := newGoldenApp(t, seededRepository(t))
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+	}{
+		{name: "create", method: "POST", target: "/api/v1/users", body: testutil.NewUserBuilder().WithName("Dave").WithDOB("1985-12-01").JSON(), status: fiber.StatusCreated},
+		{name: "update", method: "PUT", target: "/api/v1/users/2", body: testutil.NewUserBuilder().WithName("Bobby").WithDOB("2000-02-29").JSON(), status: fiber.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, written := doRequest(t, app, tt.method, tt.target, tt.body)
+			if status != tt.status {
+				t.Fatalf("status = %d, want %d", status, tt.status)
+			}
+
+			_, fetched := doRequest(t, app, "GET", fmt.Sprintf("/api/v1/users/%v", written["id"]), "")
+			if !reflect.DeepEqual(written, fetched) {
+				t.Errorf("%s response %v differs from GET %v", tt.name, written, fetched)
+			}
+			for _, field := range []string{"age", "created_at", "updated_at"} {
+				if _, ok := written[field]; !ok {
+					t.Errorf("%s response missing %s", tt.name, field)
+				}
+			}
+		})
+	}
+}
+
+func TestListUsersPastLastPage(t *testing.T) {
+	// With MAX_OFFSET lifted a huge page still lands past the last row.
+	svc := service.NewUserService(seededRepository(t), zap.NewNop(), service.WithClock(clock.Fixed(goldenNow)))
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	routes.SetupRoutes(app, handler.NewUserHandler(svc, zap.NewNop(), handler.WithMaxOffset(0)), testAdminGuard, middleware.Tenant(config.NewHolder(&config.Config{})), routes.CachePolicies{})
+
+	tests := []struct {
+		target string
+		page   float64
+	}{
+		{target: "/api/v1/users?page=5&page_size=2", page: 5},
+		{target: "/api/v1/users?page=99999999999&page_size=100", page: 99999999999},
+	}
+
+	for _, tt := range tests {
+		status, body := doRequest(t, app, "GET", tt.target, "")
+		if status != fiber.StatusOK {
+			t.Fatalf("%s: status = %d, want 200", tt.target, status)
+		}
+		users, ok := body["users"].([]any)
+		if !ok || len(users) != 0 {
+			t.Errorf("%s: users = %#v, want empty array", tt.target, body["users"])
+		}
+		if body["total"] != float64(3) || body["page"] != tt.page {
+			t.Errorf("%s: body = %v", tt.target, body)
+		}
+	}
+}
+
+func TestListUsersMaxOffset(t *testing.T) {
+	app := newGoldenApp(t, seededRepository(t))
+
+	tests := []struct {
+		target string
+		status int
+	}{
+		{target: "/api/v1/users?page=100&page_size=100", status: fiber.StatusOK},
+		{target: "/api/v1/users?page=101&page_size=100", status: fiber.StatusBadRequest},
+		{target: "/api/v1/users?page=1000", status: fiber.StatusOK},
+		{target: "/api/v1/users?page=1001", status: fiber.StatusBadRequest},
+		{target: "/api/v1/users?name_fuzzy=ali&page=1001", status: fiber.StatusBadRequest},
+		{target: "/api/v1/users/1/birthday-twins?page=1001", status: fiber.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		status, body := doRequest(t, app, "GET", tt.target, "")
+		if status != tt.status {
+			t.Errorf("%s: status = %d, want %d (%v)", tt.target, status, tt.status, body)
+			continue
+		}
+		if status == fiber.StatusBadRequest {
+			details, _ := body["details"].([]any)
+			detail, _ := details[len(details)-1].(map[string]any)
+			if detail["rule"] != "max_offset" || detail["param"] != "10000" || !strings.Contains(detail["message"].(string), "cursor") {
+				t.Errorf("%s: details = %v, want max_offset pointing at cursors", tt.target, details)
+			}
+		}
+	}
+
+	// Cursors go as deep as the data does.
+	_, first := doRequest(t, app, "GET", "/api/v1/users?page_size=1", "")
+	cursor, _ := first["next_cursor"].(string)
+	if cursor == "" {
+		t.Fatalf("first page = %v, want a next_cursor", first)
+	}
+	if status, body := doRequest(t, app, "GET", "/api/v1/users?page_size=100&cursor="+url.QueryEscape(cursor), ""); status != fiber.StatusOK {
+		t.Errorf("cursor page = %d %v, want 200", status, body)
+	}
+}
+
+func TestListUsersZeroValuesUseDefaults(t *testing.T) {
+	app := newGoldenApp(t, seededRepository(t))
+
+	status, body := doRequest(t, app, "GET", "/api/v1/users?page=0&page_size=0", "")
+	if status != fiber.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	if body["page"] != float64(1) || body["page_size"] != float64(models.DefaultPageSize) || body["total_pages"] != float64(1) {
+		t.Errorf("body = %v", body)
+	}
+}
+
+func TestListUsersSortDescending(t *testing.T) {
+	app := newGoldenApp(t, seededRepository(t))
+
+	status, body := doRequest(t, app, "GET", "/api/v1/users?sort=-id&page_size=2", "")
+	if status != fiber.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	users, _ := body["users"].([]any)
+	var names []any
+	for _, u := range users {
+		names = append(names, u.(map[string]any)["name"])
+	}
+	if !reflect.DeepEqual(names, []any{"Carol", "Bob"}) {
+		t.Errorf("names = %v, want [Carol Bob]", names)
+	}
+}
+
+func listNames(t *testing.T, app *fiber.App, target string) ([]string, string) {
+	t.Helper()
+	status, body := doRequest(t, app, "GET", target, "")
+	if status != fiber.StatusOK {
+		t.Fatalf("GET %s status = %d, want 200", target, status)
+	}
+	var names []string
+	for _, u := range body["users"].([]any) {
+		names = append(names, u.(map[string]any)["name"].(string))
+	}
+	cursor, _ := body["next_cursor"].(string)
+	return names, cursor
+}
+
+// Newest-first paging with an insert between pages: offset mode shifts every
+// row down and repeats the last row of page 1, keyset mode does not.
+func TestListUsersInsertBetweenPages(t *testing.T) {
+	ctx := context.Background()
+	dob := time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("offset repeats a row", func(t *testing.T) {
+		repo := seededRepository(t)
+		app := newGoldenApp(t, repo)
+
+		first, _ := listNames(t, app, "/api/v1/users?sort=-id&page_size=2")
+		if _, err := repo.Create(ctx, "Dave", dob); err != nil {
+			t.Fatal(err)
+		}
+		second, _ := listNames(t, app, "/api/v1/users?sort=-id&page_size=2&page=2")
+
+		if !reflect.DeepEqual(first, []string{"Carol", "Bob"}) || !reflect.DeepEqual(second, []string{"Bob", "Alice"}) {
+			t.Errorf("pages = %v, %v, want Bob on both", first, second)
+		}
+	})
+
+	t.Run("keyset has no duplicates", func(t *testing.T) {
+		repo := seededRepository(t)
+		app := newGoldenApp(t, repo)
+
+		first, cursor := listNames(t, app, "/api/v1/users?sort=-id&page_size=2")
+		if cursor == "" {
+			t.Fatal("full page returned no next_cursor")
+		}
+		if _, err := repo.Create(ctx, "Dave", dob); err != nil {
+			t.Fatal(err)
+		}
+		second, next := listNames(t, app, "/api/v1/users?sort=-id&page_size=2&cursor="+cursor)
+
+		if !reflect.DeepEqual(first, []string{"Carol", "Bob"}) || !reflect.DeepEqual(second, []string{"Alice"}) {
+			t.Errorf("pages = %v, %v, want Carol, Bob then Alice", first, second)
+		}
+		if next != "" {
+			t.Errorf("short last page returned next_cursor %q", next)
+		}
+	})
+}
+
+// sort=age pages by keyset through users sharing a DOB and composes with the
+// age filters.
+func TestListUsersSortByAge(t *testing.T) {
+	ctx := context.Background()
+	repo := seededRepository(t)
+	for _, name := range []string{"Dave", "Erin"} {
+		if _, err := repo.Create(ctx, name, time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	app := newGoldenApp(t, repo)
+
+	tests := []struct {
+		target string
+		want   []string
+	}{
+		{"/api/v1/users?sort=age&page_size=2", []string{"Carol", "Bob", "Erin", "Dave", "Alice"}},
+		{"/api/v1/users?sort=-age&page_size=2", []string{"Alice", "Dave", "Erin", "Bob", "Carol"}},
+		{"/api/v1/users?sort=-age&page_size=2&min_age=1&include_age=false", []string{"Alice", "Dave", "Erin", "Bob"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		names, cursor := listNames(t, app, tt.target)
+		got = append(got, names...)
+		for cursor != "" {
+			names, cursor = listNames(t, app, tt.target+"&cursor="+cursor)
+			got = append(got, names...)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GET %s pages = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestOldestAndYoungestUsers(t *testing.T) {
+	ctx := context.Background()
+	repo := seededRepository(t)
+	// Dave ties with Alice, and Erin is anonymized.
+	if _, err := repo.Create(ctx, "Dave", time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	erin, err := repo.Create(ctx, "Erin", time.Date(1950, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Anonymize(ctx, erin.ID, "Deleted User", time.Date(1950, 1, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	app := newGoldenApp(t, repo)
+
+	if status, body := doRequest(t, app, "GET", "/api/v1/users/oldest", ""); status != fiber.StatusOK || body["name"] != "Alice" {
+		t.Errorf("oldest = %d %v, want Alice", status, body)
+	}
+	if status, body := doRequest(t, app, "GET", "/api/v1/users/youngest", ""); status != fiber.StatusOK || body["name"] != "Carol" {
+		t.Errorf("youngest = %d %v, want Carol", status, body)
+	}
+	for target, want := range map[string][]any{
+		"/api/v1/users/oldest?count=2":     {"Alice", "Dave"},
+		"/api/v1/users/oldest?count=100":   {"Alice", "Dave", "Bob", "Carol"},
+		"/api/v1/users/youngest?count=3":   {"Carol", "Bob", "Alice"},
+		"/api/v1/users/youngest?count=100": {"Carol", "Bob", "Alice", "Dave"},
+	} {
+		status, body := doRequest(t, app, "GET", target, "")
+		var names []any
+		users, _ := body["users"].([]any)
+		for _, u := range users {
+			names = append(names, u.(map[string]any)["name"])
+		}
+		if status != fiber.StatusOK || !reflect.DeepEqual(names, want) {
+			t.Errorf("GET %s = %d %v, want %v", target, status, names, want)
+		}
+	}
+
+	for _, target := range []string{"/api/v1/users/oldest?count=0", "/api/v1/users/oldest?count=101", "/api/v1/users/youngest?count=many"} {
+		status, body := doRequest(t, app, "GET", target, "")
+		details, _ := body["details"].([]any)
+		if status != fiber.StatusBadRequest || len(details) != 1 || details[0].(map[string]any)["field"] != "count" {
+			t.Errorf("GET %s = %d %v, want 400 on count", target, status, body)
+		}
+	}
+
+	empty := newGoldenApp(t, repository.NewMemoryUserRepository(clock.Fixed(goldenNow)))
+	for _, target := range []string{"/api/v1/users/oldest", "/api/v1/users/youngest?count=5"} {
+		if status, body := doRequest(t, empty, "GET", target, ""); status != fiber.StatusNotFound || body["error"] != "There are no users" {
+			t.Errorf("GET %s with no users = %d %v, want 404", target, status, body)
+		}
+	}
+}
+
+func TestAgePercentile(t *testing.T) {
+	app := newGoldenApp(t, seededRepository(t))
+
+	status, body := doRequest(t, app, "GET", "/api/v1/users/2/percentile", "")
+	want := map[string]any{"id": float64(2), "percentile": float64(50), "younger": float64(1), "older": float64(1), "same_dob": float64(1), "total": float64(3)}
+	if status != fiber.StatusOK || !reflect.DeepEqual(body, want) {
+		t.Errorf("percentile = %d %v, want %v", status, body, want)
+	}
+	if status, body := doRequest(t, app, "GET", "/api/v1/users/2/percentile?min_age=1", ""); status != fiber.StatusOK || body["percentile"] != float64(25) {
+		t.Errorf("percentile among min_age=1 = %d %v, want 25", status, body)
+	}
+	if status, _ := doRequest(t, app, "GET", "/api/v1/users/404/percentile", ""); status != fiber.StatusNotFound {
+		t.Errorf("missing user: status = %d, want 404", status)
+	}
+	if status, body := doRequest(t, app, "GET", "/api/v1/users/2/percentile?name_fuzzy=bob", ""); status != fiber.StatusBadRequest {
+		t.Errorf("name_fuzzy: %d %v, want 400", status, body)
+	}
+}
+
+func TestAggregateRefresh(t *testing.T) {
+	ctx := context.Background()
+	repo := seededRepository(t)
+	svc := service.NewUserService(repo, zap.NewNop(), service.WithClock(clock.Fixed(goldenNow)), service.WithAggregateTTL(time.Minute))
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	isAdmin := func(c *fiber.Ctx) bool { return c.Get(fiber.HeaderAuthorization) == "Bearer admin" }
+	routes.SetupRoutes(app, handler.NewUserHandler(svc, zap.NewNop(), handler.WithAdminCheck(isAdmin)), testAdminGuard, middleware.Tenant(config.NewHolder(&config.Config{})), routes.CachePolicies{})
+	total := func(admin bool, target string) (int, any) {
+		t.Helper()
+		req := httptest.NewRequest("GET", target, nil)
+		if admin {
+			req.Header.Set(fiber.HeaderAuthorization, "Bearer admin")
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body map[string]any
+		json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body["total"]
+	}
+
+	_, before := total(false, "/api/v1/users/stats/cohorts")
+	if _, err := repo.Create(ctx, "Zed", time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	if status, got := total(false, "/api/v1/users/stats/cohorts"); status != fiber.StatusOK || got != before {
+		t.Errorf("cached cohorts = %d, total %v; want %v", status, got, before)
+	}
+	if status, _ := total(false, "/api/v1/users/stats/cohorts?refresh=true"); status != fiber.StatusForbidden {
+		t.Errorf("refresh=true without admin credentials: status = %d, want 403", status)
+	}
+	if status, _ := total(true, "/api/v1/users/stats/cohorts?refresh=maybe"); status != fiber.StatusBadRequest {
+		t.Errorf("refresh=maybe: status = %d, want 400", status)
+	}
+	if status, got := total(true, "/api/v1/users/stats/cohorts?refresh=true"); status != fiber.StatusOK || got != before.(float64)+1 {
+		t.Errorf("admin refresh = %d, total %v; want Zed counted", status, got)
+	}
+	if status, _ := total(true, "/api/v1/users/birthday-collisions?refresh=true"); status != fiber.StatusOK {
+		t.Errorf("admin refresh of collisions: status = %d, want 200", status)
+	}
+}
+
+func TestBirthdayTwinsAndCollisions(t *testing.T) {
+	ctx := context.Background()
+	repo := seededRepository(t)
+	// Alice (May 10) gets three twins, Carol (Jan 1) one, and Bob (Feb 29)
+	// shares Mar 1 in 2025, though collisions count only exact days.
+	for _, u := range []struct {
+		name string
+		dob  time.Time
+	}{
+		{"Dave", time.Date(1985, 5, 10, 0, 0, 0, 0, time.UTC)},
+		{"Erin", time.Date(2001, 5, 10, 0, 0, 0, 0, time.UTC)},
+		{"Frank", time.Date(1970, 5, 10, 0, 0, 0, 0, time.UTC)},
+		{"Grace", time.Date(1999, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"Heidi", time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC)},
+	} {
+		if _, err := repo.Create(ctx, u.name, u.dob); err != nil {
+			t.Fatal(err)
+		}
+	}
+	app := newGoldenApp(t, repo)
+
+	tests := []struct {
+		target string
+		want   []string
+		total  float64
+	}{
+		{"/api/v1/users/1/birthday-twins?page_size=2", []string{"Dave", "Erin"}, 3},
+		{"/api/v1/users/1/birthday-twins?page_size=2&page=2", []string{"Frank"}, 3},
+		{"/api/v1/users/2/birthday-twins", []string{"Grace"}, 1},
+		{"/api/v1/users/7/birthday-twins", []string{"Bob"}, 1},
+		{"/api/v1/users/8/birthday-twins", []string{"Carol"}, 1},
+		{"/api/v1/users/1/birthday-twins?page=3&page_size=2", nil, 3},
+	}
+	for _, tt := range tests {
+		status, body := doRequest(t, app, "GET", tt.target, "")
+		var names []string
+		for _, u := range body["users"].([]any) {
+			names = append(names, u.(map[string]any)["name"].(string))
+		}
+		if status != fiber.StatusOK || !reflect.DeepEqual(names, tt.want) || body["total"] != tt.total {
+			t.Errorf("GET %s = %d %v (total %v), want %v (total %v)", tt.target, status, names, body["total"], tt.want, tt.total)
+		}
+	}
+	if status, _ := doRequest(t, app, "GET", "/api/v1/users/404/birthday-twins", ""); status != fiber.StatusNotFound {
+		t.Errorf("twins of a missing user: status = %d, want 404", status)
+	}
+
+	status, body := doRequest(t, app, "GET", "/api/v1/users/birthday-collisions", "")
+	want := map[string]any{"as_of": "2025-06-15T12:00:00Z", "collisions": []any{
+		map[string]any{"month": float64(5), "day": float64(10), "date": "05-10", "count": float64(4)},
+		map[string]any{"month": float64(1), "day": float64(1), "date": "01-01", "count": float64(2)},
+	}}
+	if status != fiber.StatusOK || !reflect.DeepEqual(body, want) {
+		t.Errorf("collisions = %d %v, want %v", status, body, want)
+	}
+	if status, _ := doRequest(t, app, "GET", "/api/v1/users/birthday-collisions?limit=0", ""); status != fiber.StatusBadRequest {
+		t.Errorf("limit=0: status = %d, want 400", status)
+	}
+}
+
+func TestLeaplings(t *testing.T) {
+	ctx := context.Background()
+	repo := seededRepository(t)
+	for _, u := range []struct {
+		name string
+		dob  time.Time
+	}{
+		{"Ivan", time.Date(1996, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"Grace", time.Date(1999, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"Judy", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+	} {
+		if _, err := repo.Create(ctx, u.name, u.dob); err != nil {
+			t.Fatal(err)
+		}
+	}
+	app := newGoldenApp(t, repo)
+
+	status, body := doRequest(t, app, "GET", "/api/v1/users/leaplings?page_size=2", "")
+	users, _ := body["users"].([]any)
+	if status != fiber.StatusOK || len(users) != 2 || body["total"] != float64(3) || body["total_pages"] != float64(2) {
+		t.Fatalf("GET leaplings = %d %v, want the first 2 of 3", status, body)
+	}
+	bob := users[0].(map[string]any)
+	if bob["name"] != "Bob" || bob["age"] != float64(25) || bob["leap_birthdays"] != float64(6) || bob["next_leap_birthday"] != "2028-02-29" {
+		t.Errorf("first leapling = %v, want Bob with 6 leap birthdays", bob)
+	}
+
+	status, body = doRequest(t, app, "GET", "/api/v1/users/leaplings?page_size=2&page=2", "")
+	users, _ = body["users"].([]any)
+	if status != fiber.StatusOK || len(users) != 1 {
+		t.Fatalf("second page = %d %v, want Judy alone", status, body)
+	}
+	if judy := users[0].(map[string]any); judy["name"] != "Judy" || judy["leap_birthdays"] != float64(0) {
+		t.Errorf("second page = %v, want Judy with no leap birthday yet", judy)
+	}
+	if status, _ := doRequest(t, app, "GET", "/api/v1/users/leaplings?page_size=abc", ""); status != fiber.StatusBadRequest {
+		t.Errorf("page_size=abc: status = %d, want 400", status)
+	}
+}
+
+func TestListUsersInvalidCursor(t *testing.T) {
+	app := newGoldenApp(t, seededRepository(t))
+	_, idCursor := listNames(t, app, "/api/v1/users?page_size=1")
+
+	for _, target := range []string{
+		"/api/v1/users?cursor=not-base64!",
+		"/api/v1/users?sort=-created_at&cursor=" + idCursor,
+	} {
+		status, body := doRequest(t, app, "GET", target, "")
+		if status != fiber.StatusBadRequest {
+			t.Errorf("GET %s status = %d, want 400", target, status)
+			continue
+		}
+		details, _ := body["details"].([]any)
+		if len(details) != 1 || details[0].(map[string]any)["field"] != "cursor" {
+			t.Errorf("GET %s details = %v", target, body["details"])
+		}
+	}
+}
+
+func TestListUsersNameFilter(t *testing.T) {
+	app := newGoldenApp(t, seededRepository(t))
+
+	status, body := doRequest(t, app, "GET", "/api/v1/users?name=AR", "")
+	if status != fiber.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	users, _ := body["users"].([]any)
+	if len(users) != 1 || users[0].(map[string]any)["name"] != "Carol" || body["total"] != float64(1) {
+		t.Errorf("body = %v, want only Carol", body)
+	}
+}
+
+func TestListUsersNameFuzzy(t *testing.T) {
+	app := newGoldenApp(t, seededRepository(t))
+
+	// The in-memory repository has no trigram index to rank by.
+	status, body := doRequest(t, app, "GET", "/api/v1/users?name_fuzzy=Carl", "")
+	if status != fiber.StatusNotImplemented || body["error"] != "Fuzzy name search is not supported by this database" {
+		t.Errorf("status = %d, body = %v; want 501", status, body)
+	}
+
+	status, body = doRequest(t, app, "GET", "/api/v1/users?name_fuzzy=Carl&sort=name", "")
+	if status != fiber.StatusBadRequest {
+		t.Errorf("status = %d, body = %v; want 400 for sort with name_fuzzy", status, body)
+	}
+}
+
+func TestUniqueNames(t *testing.T) {
+	for _, unique := range []bool{false, true} {
+		var opts []repository.MemoryOption
+		if unique {
+			opts = append(opts, repository.WithUniqueNames())
+		}
+		repo := repository.NewMemoryUserRepository(clock.Fixed(goldenNow), opts...)
+		testutil.MustLoad(t, repo, "testdata/fixtures/users.yaml")
+		svc := service.NewUserService(repo, zap.NewNop(), service.WithClock(clock.Fixed(goldenNow)), service.WithUniqueNames(unique))
+		app := newTestApp(svc)
+
+		status, body := doRequest(t, app, "GET", "/api/v1/users/by-name/aLiCe", "")
+		if unique && (status != fiber.StatusOK || body["name"] != "Alice") {
+			t.Errorf("unique lookup: status = %d, body = %v; want Alice", status, body)
+		}
+		if !unique && status != fiber.StatusNotFound {
+			t.Errorf("lookup without UNIQUE_NAMES: status = %d, want 404", status)
+		}
+
+		wantCreate := fiber.StatusCreated
+		if unique {
+			wantCreate = fiber.StatusConflict
+		}
+		if status, body := doRequest(t, app, "POST", "/api/v1/users", `{"name":"BOB","dob":"1990-01-01"}`); status != wantCreate {
+			t.Errorf("unique=%v: create of a taken name: status = %d, body = %v; want %d", unique, status, body, wantCreate)
+		}
+	}
+
+	repo := repository.NewMemoryUserRepository(clock.Fixed(goldenNow), repository.WithUniqueNames())
+	repo.Create(context.Background(), "Mary Jane", time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC))
+	app := newTestApp(service.NewUserService(repo, zap.NewNop(), service.WithUniqueNames(true)))
+	if status, body := doRequest(t, app, "GET", "/api/v1/users/by-name/mary%20jane", ""); status != fiber.StatusOK || body["name"] != "Mary Jane" {
+		t.Errorf("lookup of an escaped name: status = %d, body = %v", status, body)
+	}
+	if status, _ := doRequest(t, app, "GET", "/api/v1/users/by-name/Nobody", ""); status != fiber.StatusNotFound {
+		t.Errorf("lookup of an unknown name: status = %d, want 404", status)
+	}
+}
+
+func TestUserTimezone(t *testing.T) {
+	// 23:30 UTC on Jun 14 is already Jun 15 in Tonga.
+	now := time.Date(2025, 6, 14, 23, 30, 0, 0, time.UTC)
+	repo := repository.NewMemoryUserRepository(clock.Fixed(now))
+	app := newTestApp(service.NewUserService(repo, zap.NewNop(), service.WithClock(clock.Fixed(now))))
+
+	status, body := doRequest(t, app, "POST", "/api/v1/users", `{"name":"Sione","dob":"1990-06-15","timezone":"Pacific/Tongatapu"}`)
+	if status != fiber.StatusCreated || body["timezone"] != "Pacific/Tongatapu" || body["is_birthday"] != true {
+		t.Errorf("create with a timezone: status = %d, body = %v", status, body)
+	}
+	if status, _ := doRequest(t, app, "POST", "/api/v1/users", `{"name":"Nobody","dob":"1990-06-15","timezone":"Mars/Olympus"}`); status != fiber.StatusBadRequest {
+		t.Errorf("create with an unknown timezone: status = %d, want 400", status)
+	}
+
+	status, body = doRequest(t, app, "GET", "/api/v1/users/birthdays/today", "")
+	if users, _ := body["users"].([]any); status != fiber.StatusOK || body["count"] != float64(1) || len(users) != 1 {
+		t.Errorf("birthdays today: status = %d, body = %v", status, body)
+	}
+
+	status, body = doRequest(t, app, "PATCH", "/api/v1/users/1", `{"timezone":null}`)
+	if _, ok := body["timezone"]; status != fiber.StatusOK || ok || body["is_birthday"] != nil {
+		t.Errorf("clearing the timezone: status = %d, body = %v", status, body)
+	}
+	if _, body := doRequest(t, app, "GET", "/api/v1/users/birthdays/today", ""); body["count"] != float64(0) {
+		t.Errorf("birthdays today after moving back to UTC = %v, want none", body)
+	}
+}
+
+func TestExportUserData(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(clock.Fixed(goldenNow))
+	markers := repository.NewMemoryNotificationRepository()
+	markers.MarkNotified(context.Background(), 1, 2024, time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC))
+	markers.MarkNotified(context.Background(), 2, 2024, time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC))
+	svc := service.NewUserService(repo, zap.NewNop(), service.WithClock(clock.Fixed(goldenNow)), service.WithNotificationRepository(markers))
+	app := newTestApp(svc)
+	doRequest(t, app, "POST", "/api/v1/users", `{"name":"Alice","dob":"1990-05-10"}`)
+	doRequest(t, app, "PUT", "/api/v1/users/1", `{"name":"Alicia","dob":"1990-05-10","timezone":"Europe/Berlin","locale":"de-DE"}`)
+	doRequest(t, app, "PATCH", "/api/v1/users/1", `{"name":"Ali, \"the Great\""}`)
+
+	status, body := doRequest(t, app, "GET", "/api/v1/users/1/export", "")
+	if status != fiber.StatusOK {
+		t.Fatalf("status = %d, body = %v", status, body)
+	}
+	user, _ := body["user"].(map[string]any)
+	if user["name"] != `Ali, "the Great"` || user["timezone"] != "Europe/Berlin" || user["locale"] != "de-DE" || body["exported_at"] != "2025-06-15T12:00:00Z" {
+		t.Errorf("export = %v", body)
+	}
+	if got, want := fmt.Sprint(body["birthday_notifications"]), "[map[notified_on:2024-05-10 year:2024]]"; got != want {
+		t.Errorf("birthday_notifications = %s, want %s", got, want)
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/users/1/export?format=zip", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Header.Get(fiber.HeaderContentType) != "application/zip" || resp.Header.Get(fiber.HeaderContentDisposition) != `attachment; filename="user-1.zip"` {
+		t.Errorf("zip headers = %v", resp.Header)
+	}
+	raw, _ := io.ReadAll(resp.Body)
+	bundle, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, f := range bundle.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(r)
+		files[f.Name] = string(content)
+	}
+	var fromZip map[string]any
+	if err := json.Unmarshal([]byte(files["user-1.json"]), &fromZip); err != nil || !reflect.DeepEqual(fromZip, body) {
+		t.Errorf("user-1.json = %s (%v), want the JSON export", files["user-1.json"], err)
+	}
+	wantCSV := "id,name,dob,age,timezone,locale,created_at,updated_at\n1,\"Ali, \"\"the Great\"\"\",1990-05-10,35,Europe/Berlin,de-DE,2025-06-15T12:00:00Z,2025-06-15T12:00:00Z\n"
+	if files["user-1.csv"] != wantCSV || len(files) != 2 {
+		t.Errorf("user-1.csv = %q, want %q (files %v)", files["user-1.csv"], wantCSV, len(files))
+	}
+
+	if status, _ := doRequest(t, app, "GET", "/api/v1/users/1/export?format=pdf", ""); status != fiber.StatusBadRequest {
+		t.Errorf("unknown format: status = %d, want 400", status)
+	}
+	if status, _ := doRequest(t, app, "GET", "/api/v1/users/404/export", ""); status != fiber.StatusNotFound {
+		t.Errorf("missing user: status = %d, want 404", status)
+	}
+}
+
+func TestAnonymizeUser(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(clock.Fixed(goldenNow))
+	markers := repository.NewMemoryNotificationRepository()
+	markers.MarkNotified(context.Background(), 1, 2024, time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC))
+	hub := events.NewHub()
+	published, unsubscribe := hub.Subscribe(1)
+	defer unsubscribe()
+	svc := service.NewUserService(repo, zap.NewNop(), service.WithClock(clock.Fixed(goldenNow)),
+		service.WithNotificationRepository(markers), service.WithPublisher(hub))
+	app := newTestApp(svc)
+	doRequest(t, app, "POST", "/api/v1/users", `{"name":"Alice Liddell","dob":"1990-05-10","timezone":"Europe/Berlin"}`)
+
+	// A dry run reports the user and leaves them, their markers and the
+	// events alone.
+	status, body := doRequest(t, app, "POST", "/api/v1/users/1/anonymize?dry_run=true", "")
+	if want := map[string]any{"affected_count": 1.0, "sample_ids": []any{1.0}, "filters": map[string]any{"id": 1.0}}; status != fiber.StatusOK || !reflect.DeepEqual(body, want) {
+		t.Errorf("dry run = %d %v, want %v", status, body, want)
+	}
+	if _, body := doRequest(t, app, "GET", "/api/v1/users/1", ""); body["name"] != "Alice Liddell" || body["anonymized_at"] != nil {
+		t.Errorf("user after the dry run = %v", body)
+	}
+	if list, _ := markers.ListForUser(context.Background(), 1); len(list) != 1 {
+		t.Errorf("markers after the dry run = %v", list)
+	}
+	if len(published) != 0 {
+		t.Error("the dry run published an event")
+	}
+	if status, body := doRequest(t, app, "POST", "/api/v1/users/1/anonymize?dry_run=maybe", ""); status != fiber.StatusBadRequest {
+		t.Errorf("dry_run=maybe = %d %v, want 400", status, body)
+	}
+
+	status, body = doRequest(t, app, "POST", "/api/v1/users/1/anonymize", "")
+	if status != fiber.StatusOK {
+		t.Fatalf("status = %d, body = %v", status, body)
+	}
+	if body["name"] != "Deleted User 1" || body["dob"] != "1990-01-01" || body["timezone"] != nil || body["anonymized_at"] != "2025-06-15T12:00:00Z" {
+		t.Errorf("anonymized user = %v", body)
+	}
+	select {
+	case event := <-published:
+		if event.Type != models.EventUserAnonymized || event.Data != (models.UserEvent{UserID: 1}) {
+			t.Errorf("event = %+v", event)
+		}
+	default:
+		t.Error("no user.anonymized event")
+	}
+
+	// Nothing the user gave away survives, not even the birthday the
+	// notification dates would reveal.
+	for _, target := range []string{"/api/v1/users/1", "/api/v1/users/1/export", "/api/v1/users"} {
+		resp, err := app.Test(httptest.NewRequest("GET", target, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, _ := io.ReadAll(resp.Body)
+		for _, personal := range []string{"Alice", "Liddell", "05-10", "Europe/Berlin"} {
+			if resp.StatusCode != fiber.StatusOK || bytes.Contains(raw, []byte(personal)) {
+				t.Errorf("GET %s = %d %s, still mentions %q", target, resp.StatusCode, raw, personal)
+			}
+		}
+	}
+
+	writes := []struct{ method, target, body string }{
+		{"PUT", "/api/v1/users/1", `{"name":"Alice","dob":"1990-05-10"}`},
+		{"PATCH", "/api/v1/users/1", `{"timezone":"Europe/Berlin"}`},
+		{"POST", "/api/v1/users/1/anonymize", ""},
+	}
+	for _, w := range writes {
+		if status, body := doRequest(t, app, w.method, w.target, w.body); status != fiber.StatusConflict || body["error"] != "User has been anonymized" {
+			t.Errorf("%s %s after anonymizing = %d %v, want 409", w.method, w.target, status, body)
+		}
+	}
+	// A batch reports the user as its own failed item.
+	status, body = doRequest(t, app, "PATCH", "/api/v1/users/batch", `[{"id":1,"name":"Alice"}]`)
+	if result := body["results"].([]any)[0].(map[string]any); status != fiber.StatusOK || result["status"] != "anonymized" || result["error"] != "User has been anonymized" {
+		t.Errorf("PATCH /api/v1/users/batch after anonymizing = %d %v, want the item anonymized", status, body)
+	}
+	if status, body := doRequest(t, app, "POST", "/api/v1/users/1/anonymize?dry_run=true", ""); status != fiber.StatusConflict {
+		t.Errorf("dry run after anonymizing = %d %v, want 409 as the real run", status, body)
+	}
+	for _, target := range []string{"/api/v1/users/404/anonymize", "/api/v1/users/404/anonymize?dry_run=true"} {
+		if status, _ := doRequest(t, app, "POST", target, ""); status != fiber.StatusNotFound {
+			t.Errorf("POST %s: status = %d, want 404", target, status)
+		}
+	}
+}
+
+func TestListUsersWithoutTotal(t *testing.T) {
+	app := newGoldenApp(t, seededRepository(t))
+
+	status, body := doRequest(t, app, "GET", "/api/v1/users?include_total=false", "")
+	if status != fiber.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	if _, ok := body["total"]; ok {
+		t.Errorf("total present with include_total=false: %v", body)
+	}
+	if _, ok := body["total_pages"]; ok {
+		t.Errorf("total_pages present with include_total=false: %v", body)
+	}
+	if users, _ := body["users"].([]any); len(users) != 3 {
+		t.Errorf("users = %v, want 3", body["users"])
+	}
+}
+
+func TestListHidesDOB(t *testing.T) {
+	svc := service.NewUserService(seededRepository(t), zap.NewNop(), service.WithClock(clock.Fixed(goldenNow)), service.WithListDOBHidden(true))
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	routes.SetupRoutes(app, handler.NewUserHandler(svc, zap.NewNop()), testAdminGuard, middleware.Tenant(config.NewHolder(&config.Config{})), routes.CachePolicies{})
+
+	status, body := doRequest(t, app, "GET", "/api/v1/users", "")
+	if status != fiber.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	for _, u := range body["users"].([]any) {
+		user := u.(map[string]any)
+		if _, ok := user["dob"]; ok || user["age"] == nil {
+			t.Errorf("listed user = %v, want an age and no dob", user)
+		}
+	}
+	if _, user := doRequest(t, app, "GET", "/api/v1/users/1", ""); user["dob"] != "1990-05-10" {
+		t.Errorf("GET /api/v1/users/1 = %v, want the dob", user)
+	}
+}
+
+func TestListUsersWithoutAge(t *testing.T) {
+	app := newGoldenApp(t, seededRepository(t))
+
+	for target, want := range map[string]bool{
+		"/api/v1/users":                   true,
+		"/api/v1/users?include_age=true":  true,
+		"/api/v1/users?include_age=false": false,
+	} {
+		status, body := doRequest(t, app, "GET", target, "")
+		if status != fiber.StatusOK {
+			t.Fatalf("GET %s: status = %d, want 200", target, status)
+		}
+		users, _ := body["users"].([]any)
+		if len(users) != 3 {
+			t.Fatalf("GET %s: users = %v, want 3", target, body["users"])
+		}
+		for _, u := range users {
+			user := u.(map[string]any)
+			if _, ok := user["age"]; ok != want {
+				t.Errorf("GET %s: age present = %v, want %v: %v", target, ok, want, user)
+			}
+			if !want {
+				for _, field := range []string{"age_valid", "is_birthday"} {
+					if _, ok := user[field]; ok {
+						t.Errorf("GET %s: %s present: %v", target, field, user)
+					}
+				}
+				if user["name"] == nil || user["dob"] == nil {
+					t.Errorf("GET %s: user = %v, want name and dob", target, user)
+				}
+			}
+		}
+	}
+}
+
+// blockingRepository holds every lookup until its context is cancelled, like
+// a query stuck behind a lock.
+type blockingRepository struct {
+	repository.UserRepository
+	cancelled chan error
+}
+
+func (r blockingRepository) GetById(ctx context.Context, id int32) (*models.User, error) {
+	<-ctx.Done()
+	r.cancelled <- ctx.Err()
+	return nil, ctx.Err()
+}
+
+func TestRequestDeadlineCancelsRepository(t *testing.T) {
+	repo := blockingRepository{cancelled: make(chan error, 1)}
+	cfg := config.NewHolder(&config.Config{RequestTimeout: 50 * time.Millisecond})
+
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	app.Use(middleware.RequestID(), middleware.Timeout(cfg))
+	routes.SetupRoutes(app, handler.NewUserHandler(service.NewUserService(repo, zap.NewNop()), zap.NewNop()), testAdminGuard, middleware.Tenant(cfg), routes.CachePolicies{})
+
+	start := time.Now()
+	status, body := doRequest(t, app, "GET", "/api/v1/users/1", "")
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("request took %v, want it to return right after the deadline", elapsed)
+	}
+	if status != fiber.StatusGatewayTimeout || body["error"] != "Request timed out" {
+		t.Errorf("status = %d, body = %v, want 504", status, body)
+	}
+
+	select {
+	case err := <-repo.cancelled:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("repository saw %v, want deadline exceeded", err)
+		}
+	default:
+		t.Error("repository context was never cancelled")
+	}
+}
+
+func TestGetUserConditional(t *testing.T) {
+	app := newGoldenApp(t, seededRepository(t))
+
+	req := httptest.NewRequest("GET", "/api/v1/users/1", nil)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lastModified := resp.Header.Get("Last-Modified")
+	if lastModified != "Sun, 15 Jun 2025 12:00:00 GMT" {
+		t.Fatalf("Last-Modified = %q, want the seeded updated_at", lastModified)
+	}
+
+	for header, want := range map[string]int{
+		lastModified:                    fiber.StatusNotModified,
+		"Sun, 15 Jun 2025 11:59:59 GMT": fiber.StatusOK,
+	} {
+		req := httptest.NewRequest("GET", "/api/v1/users/1", nil)
+		req.Header.Set("If-Modified-Since", header)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != want {
+			t.Errorf("If-Modified-Since %q: status = %d, want %d", header, resp.StatusCode, want)
+		}
+	}
+}
+
+// An infant's age_weeks moves on every week, and a cached copy must not
+// outlive the week it was fetched in.
+func TestGetUserConditionalAcrossWeeks(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(clock.Fixed(time.Date(2025, 5, 2, 9, 0, 0, 0, time.UTC)))
+	testutil.MustInsert(t, repo, testutil.NewUserBuilder().WithName("Baby").WithDOB("2025-05-01").Build())
+	get := func(now time.Time, ifModifiedSince string) (*http.Response, map[string]any) {
+		t.Helper()
+		app := newTestApp(service.NewUserService(repo, zap.NewNop(), service.WithClock(clock.Fixed(now))))
+		req := httptest.NewRequest("GET", "/api/v1/users/1", nil)
+		if ifModifiedSince != "" {
+			req.Header.Set("If-Modified-Since", ifModifiedSince)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body map[string]any
+		json.NewDecoder(resp.Body).Decode(&body)
+		return resp, body
+	}
+
+	resp, body := get(goldenNow, "")
+	lastModified := resp.Header.Get("Last-Modified")
+	if lastModified != "Thu, 12 Jun 2025 00:00:00 GMT" || body["age_weeks"] != float64(6) {
+		t.Fatalf("Last-Modified = %q, age_weeks = %v; want the start of week 6", lastModified, body["age_weeks"])
+	}
+	if resp, _ := get(time.Date(2025, 6, 18, 23, 0, 0, 0, time.UTC), lastModified); resp.StatusCode != fiber.StatusNotModified {
+		t.Errorf("within the week: status = %d, want 304", resp.StatusCode)
+	}
+	resp, body = get(time.Date(2025, 6, 19, 1, 0, 0, 0, time.UTC), lastModified)
+	if resp.StatusCode != fiber.StatusOK || body["age_weeks"] != float64(7) {
+		t.Errorf("the next week: status = %d, age_weeks = %v; want 200 with 7", resp.StatusCode, body["age_weeks"])
+	}
+	if got := resp.Header.Get("Last-Modified"); got != "Thu, 19 Jun 2025 00:00:00 GMT" {
+		t.Errorf("the next week: Last-Modified = %q, want the start of week 7", got)
+	}
+}
+
+func TestListUsersLastModified(t *testing.T) {
+	app := newGoldenApp(t, seededRepository(t))
+
+	req := httptest.NewRequest("GET", "/api/v1/users", nil)
+	req.Header.Set("If-Modified-Since", "Sun, 15 Jun 2025 12:00:00 GMT")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Errorf("status = %d, want 200; lists never answer 304", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Last-Modified"); got != "Sun, 15 Jun 2025 12:00:00 GMT" {
+		t.Errorf("Last-Modified = %q, want the newest row on the page", got)
+	}
+}
+
+func TestUserLocale(t *testing.T) {
+	svc := service.NewUserService(repository.NewMemoryUserRepository(clock.Fixed(goldenNow)), zap.NewNop(), service.WithClock(clock.Fixed(goldenNow)))
+	app := newTestApp(svc)
+	doRequest(t, app, "POST", "/api/v1/users", `{"name":"Alice","dob":"1990-05-10","locale":"de-DE"}`)
+	doRequest(t, app, "POST", "/api/v1/users", `{"name":"Bob","dob":"1990-05-10"}`)
+
+	tests := []struct {
+		name           string
+		target         string
+		acceptLanguage string
+		weekday        any
+		display        any
+	}{
+		{name: "stored locale", target: "/api/v1/users/1", weekday: "Donnerstag", display: "10. Mai 2026"},
+		{name: "header overrides it", target: "/api/v1/users/1", acceptLanguage: "ja-JP,ja;q=0.9", weekday: "木曜日", display: "2026年5月10日"},
+		{name: "unsupported header ignored", target: "/api/v1/users/1", acceptLanguage: "fr-FR", weekday: "Donnerstag", display: "10. Mai 2026"},
+		{name: "no locale at all", target: "/api/v1/users/2", weekday: nil, display: nil},
+		{name: "header alone", target: "/api/v1/users/2", acceptLanguage: "es", weekday: "jueves", display: "10 de mayo de 2026"},
+		{name: "english", target: "/api/v1/users/2", acceptLanguage: "en-US", weekday: "Thursday", display: "May 10, 2026"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.target, nil)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var body map[string]any
+			json.NewDecoder(resp.Body).Decode(&body)
+			if body["born_on_weekday"] != tt.weekday || body["next_birthday_display"] != tt.display {
+				t.Errorf("body = %v, want weekday %v and display %v", body, tt.weekday, tt.display)
+			}
+			if body["dob"] != "1990-05-10" {
+				t.Errorf("dob = %v, want it still ISO", body["dob"])
+			}
+			if got := resp.Header.Get("Vary"); !strings.Contains(got, "Accept-Language") {
+				t.Errorf("Vary = %q, want Accept-Language", got)
+			}
+		})
+	}
+
+	status, body := doRequest(t, app, "PATCH", "/api/v1/users/1", `{"locale":null}`)
+	if status != fiber.StatusOK || body["locale"] != nil || body["born_on_weekday"] != nil {
+		t.Errorf("clearing the locale = %d %v", status, body)
+	}
+	status, body = doRequest(t, app, "PUT", "/api/v1/users/2", `{"name":"Bob","dob":"1990-05-10","locale":"not a tag"}`)
+	details, _ := body["details"].([]any)
+	if status != fiber.StatusBadRequest || len(details) != 1 || details[0].(map[string]any)["rule"] != "bcp47_language_tag" {
+		t.Errorf("invalid locale = %d %v, want 400 bcp47_language_tag", status, body)
+	}
+}
+
+func TestMaxUsers(t *testing.T) {
+	svc := service.NewUserService(repository.NewMemoryUserRepository(clock.Fixed(goldenNow)), zap.NewNop(), service.WithMaxUsers(1))
+	app := newTestApp(svc)
+	alice := testutil.NewUserBuilder().WithName("Alice").WithDOB("1990-05-10").JSON()
+	if status, body := doRequest(t, app, "POST", "/api/v1/users", alice); status != fiber.StatusCreated {
+		t.Fatalf("first create = %d %v", status, body)
+	}
+
+	status, body := doRequest(t, app, "POST", "/api/v1/users", alice)
+	want := map[string]any{
+		"error": "User limit reached",
+		"details": []any{map[string]any{
+			"field": "users", "rule": "max_users", "param": "1", "message": "the tenant already holds 1 of its 1 allowed users",
+		}},
+	}
+	if status != fiber.StatusForbidden || !reflect.DeepEqual(body, want) {
+		t.Errorf("create past the quota = %d %v, want 403 %v", status, body, want)
+	}
+}
+
+func TestNamePolicy(t *testing.T) {
+	newApp := func(t *testing.T, policy service.NamePolicy) *fiber.App {
+		repo := repository.NewMemoryUserRepository(clock.Real())
+		testutil.MustInsert(t, repo, testutil.NewUserBuilder().WithName("Alice").WithDOB("1990-05-10").Build())
+		app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+		routes.SetupRoutes(app, handler.NewUserHandler(service.NewUserService(repo, zap.NewNop()), zap.NewNop(), handler.WithNamePolicy(policy)), testAdminGuard, middleware.Tenant(config.NewHolder(&config.Config{})), routes.CachePolicies{})
+		return app
+	}
+	created := map[service.NamePolicy][]string{
+		service.NamePolicyAny:           {"José", "李明", "Jean-Luc O'Brien", "R2-D2", "Alice \U0001f382"},
+		service.NamePolicyLettersSpaces: {"José", "李明"},
+		service.NamePolicyLettersPunct:  {"José", "李明", "Jean-Luc O'Brien"},
+	}
+	for policy, accepted := range created {
+		t.Run(string(policy), func(t *testing.T) {
+			app := newApp(t, policy)
+			for _, name := range []string{"José", "李明", "Jean-Luc O'Brien", "R2-D2", "Alice \U0001f382"} {
+				body, _ := json.Marshal(map[string]string{"name": name, "dob": "1990-05-10"})
+				want := fiber.StatusBadRequest
+				if slices.Contains(accepted, name) {
+					want = fiber.StatusCreated
+				}
+				if status, resp := doRequest(t, app, "POST", "/api/v1/users", string(body)); status != want {
+					t.Errorf("create %q: status = %d, want %d (body %v)", name, status, want, resp)
+				}
+			}
+		})
+	}
+
+	app := newApp(t, service.NamePolicyLettersPunct)
+	rejected := map[string]any{
+		"error": "Validation failed",
+		"details": []any{map[string]any{
+			"field": "name", "rule": "name_policy", "param": "letters_punct",
+			"message": "name may only contain letters, spaces, hyphens and apostrophes, not '2', '!'",
+		}},
+	}
+	for _, tt := range []struct{ method, target, body string }{
+		{"POST", "/api/v1/users", `{"name":"R2-D2!","dob":"1990-05-10"}`},
+		{"PUT", "/api/v1/users/1", `{"name":"R2-D2!","dob":"1990-05-10"}`},
+		{"PATCH", "/api/v1/users/1", `{"name":"R2-D2!"}`},
+	} {
+		if status, body := doRequest(t, app, tt.method, tt.target, tt.body); status != fiber.StatusBadRequest || !reflect.DeepEqual(body, rejected) {
+			t.Errorf("%s %s: status = %d, body = %v", tt.method, tt.target, status, body)
+		}
+	}
+	status, body := doRequest(t, app, "PATCH", "/api/v1/users/batch", `[{"id":1,"name":"R2-D2!"}]`)
+	if result := body["results"].([]any)[0].(map[string]any); status != fiber.StatusOK || result["status"] != "invalid" || !reflect.DeepEqual(result["details"], rejected["details"]) {
+		t.Errorf("batch: status = %d, body = %v", status, body)
+	}
+}
+
+func TestStrictJSON(t *testing.T) {
+	var calls int
+	svc := &mockUserService{
+		createUser: func(ctx context.Context, req *models.CreateUserRequest) (*models.UserResponse, error) {
+			calls++
+			return &models.UserResponse{ID: 1, Name: req.Name, DOB: req.DOB}, nil
+		},
+		updateUser: func(ctx context.Context, id int32, req *models.UpdateUserRequest) (*models.UserResponse, error) {
+			calls++
+			return &models.UserResponse{ID: id, Name: req.Name, DOB: req.DOB}, nil
+		},
+	}
+	strict := newTestApp(svc)
+	lenient := fiber.New()
+	routes.SetupRoutes(lenient, handler.NewUserHandler(svc, zap.NewNop(), handler.WithStrictJSON(false)), testAdminGuard, middleware.Tenant(config.NewHolder(&config.Config{})), routes.CachePolicies{})
+
+	tests := []struct {
+		name   string
+		app    *fiber.App
+		method string
+		target string
+		body   string
+		status int
+	}{
+		{name: "update with unknown field", app: strict, method: "PUT", target: "/api/v1/users/1", body: `{"name":"Alice","dob":"1990-05-10","nickname":"Al"}`, status: fiber.StatusBadRequest},
+		{name: "keys match case-insensitively", app: strict, method: "POST", target: "/api/v1/users", body: `{"Name":"Alice","DOB":"1990-05-10"}`, status: fiber.StatusCreated},
+		{name: "lenient create", app: lenient, method: "POST", target: "/api/v1/users", body: `{"name":"Alice","dob":"1990-05-10","nickname":"Al"}`, status: fiber.StatusCreated},
+		{name: "lenient update", app: lenient, method: "PUT", target: "/api/v1/users/1", body: `{"name":"Alice","dob":"1990-05-10","nickname":"Al"}`, status: fiber.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, body := doRequest(t, tt.app, tt.method, tt.target, tt.body); status != tt.status {
+				t.Errorf("status = %d, want %d (body %v)", status, tt.status, body)
+			}
+		})
+	}
+	if calls != 3 {
+		t.Errorf("service called %d times, want 3", calls)
+	}
+}
+
+func TestStrictJSONWithCharset(t *testing.T) {
+	app := newTestApp(&mockUserService{})
+	req := httptest.NewRequest("POST", "/api/v1/users", strings.NewReader(`{"name":"Alice","dateOfBirth":"1990-01-01"}`))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestWritesRequireJSON(t *testing.T) {
+	app := newTestApp(&mockUserService{})
+	for _, method := range []string{"POST", "PUT"} {
+		target := "/api/v1/users"
+		if method == "PUT" {
+			target += "/1"
+		}
+		req := httptest.NewRequest(method, target, strings.NewReader("name=Alice&dob=1990-05-10"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusUnsupportedMediaType {
+			t.Errorf("%s form post: status = %d, want 415", method, resp.StatusCode)
+		}
+	}
+}
+
+func TestWritesCountedInMetrics(t *testing.T) {
+	m := metrics.NewUsers(prometheus.NewRegistry())
+	repo := repository.NewMemoryUserRepository(clock.Real())
+	app := newTestApp(service.NewUserService(repo, zap.NewNop(), service.WithMetrics(m)))
+
+	steps := []struct {
+		method, target, body string
+		status               int
+	}{
+		{"POST", "/api/v1/users", `{"name":"Alice","dob":"1990-05-10"}`, fiber.StatusCreated},
+		{"POST", "/api/v1/users", `{"name":"A"}`, fiber.StatusBadRequest},
+		{"PUT", "/api/v1/users/1", `{"name":"Alicia","dob":"1990-05-10"}`, fiber.StatusOK},
+		{"DELETE", "/api/v1/users/1", "", fiber.StatusNoContent},
+		{"DELETE", "/api/v1/users/1", "", fiber.StatusNotFound},
+	}
+	for _, step := range steps {
+		if status, body := doRequest(t, app, step.method, step.target, step.body); status != step.status {
+			t.Fatalf("%s %s: status = %d, want %d (body %v)", step.method, step.target, status, step.status, body)
+		}
+	}
+
+	for name, counter := range map[string]prometheus.Counter{"created": m.Created, "updated": m.Updated, "deleted": m.Deleted} {
+		if got := promtestutil.ToFloat64(counter); got != 1 {
+			t.Errorf("users %s = %v, want 1", name, got)
+		}
+	}
+}
+
+func TestValidationFailuresCounted(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	m := metrics.NewHTTP(registry)
+	svc := service.NewUserService(repository.NewMemoryUserRepository(clock.Real()), zap.NewNop())
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	app.Use(middleware.Metrics(m))
+	tenant := middleware.Tenant(config.NewHolder(&config.Config{}))
+	routes.SetupRoutes(app, handler.NewUserHandler(svc, zap.NewNop()), testAdminGuard, tenant, routes.CachePolicies{})
+	routes.SetupV2Routes(app, handler.NewUserHandler(svc, zap.NewNop(), handler.WithStrictStatusCodes(true)), tenant, routes.CachePolicies{})
+	pass := func(c *fiber.Ctx) error { return c.Next() }
+	routes.SetupAdminRoutes(app, handler.NewAdminHandler(nil, nil, zap.NewNop(), handler.WithStats(registry)), pass, pass, routes.CachePolicies{})
+
+	steps := []struct {
+		target, body string
+		status       int
+	}{
+		{"/api/v1/users", `{"name":"Alice","dob":"not-a-date"}`, fiber.StatusBadRequest},
+		{"/api/v2/users", `{"name":"Alice","dob":"1990-13-45"}`, fiber.StatusBadRequest},
+		{"/api/v1/users", `{"name":"Alice","dob":"1990-05-10","ssn":"123-45-6789"}`, fiber.StatusBadRequest},
+		{"/api/v1/users", `{"name":"Alice","dob":"1990-05-10"}`, fiber.StatusCreated},
+	}
+	for _, step := range steps {
+		if status, body := doRequest(t, app, "POST", step.target, step.body); status != step.status {
+			t.Fatalf("POST %s %s: status = %d, want %d (body %v)", step.target, step.body, status, step.status, body)
+		}
+	}
+
+	for _, tc := range []struct{ endpoint, field string }{
+		{"POST /api/v1/users", "dob"},
+		{"POST /api/v2/users", "dob"},
+		{"POST /api/v1/users", "other"},
+	} {
+		if got := promtestutil.ToFloat64(m.ValidationFailures.WithLabelValues(tc.endpoint, tc.field)); got != 1 {
+			t.Errorf("validation failures of %s on %s = %v, want 1", tc.field, tc.endpoint, got)
+		}
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/admin/stats/validation", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := io.ReadAll(resp.Body)
+	var stats metrics.ValidationStats
+	if resp.StatusCode != fiber.StatusOK || json.Unmarshal(raw, &stats) != nil {
+		t.Fatalf("GET /admin/stats/validation = %d %s", resp.StatusCode, raw)
+	}
+	if stats.Total != 3 || stats.ByField["dob"] != 2 || stats.ByField["other"] != 1 || stats.ByEndpoint["POST /api/v1/users"]["other"] != 1 {
+		t.Errorf("validation stats = %+v", stats)
+	}
+	for _, submitted := range []string{"ssn", "123-45-6789", "not-a-date", "1990-13-45"} {
+		if strings.Contains(string(raw), submitted) {
+			t.Errorf("validation stats %s contain %q from a request", raw, submitted)
+		}
+	}
+}
+
+func TestTenantIsolation(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(clock.Fixed(time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)))
+	cfg := config.NewHolder(&config.Config{Tenants: []string{"acme", "globex"}})
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	app.Use(middleware.RequestID())
+	routes.SetupRoutes(app, handler.NewUserHandler(service.NewUserService(repo, zap.NewNop()), zap.NewNop()), testAdminGuard, middleware.Tenant(cfg), routes.CachePolicies{})
+
+	alice := testutil.NewUserBuilder().WithName("Alice").WithDOB("1990-05-10").JSON()
+	status, created := doTenantRequest(t, app, "acme", "POST", "/api/v1/users", alice)
+	if status != fiber.StatusCreated {
+		t.Fatalf("create status = %d, body = %v", status, created)
+	}
+	path := fmt.Sprintf("/api/v1/users/%v", created["id"])
+
+	if status, _ := doTenantRequest(t, app, "acme", "GET", path, ""); status != fiber.StatusOK {
+		t.Errorf("owner GET status = %d, want 200", status)
+	}
+
+	// A foreign id must be indistinguishable from one that never existed.
+	_, missing := doTenantRequest(t, app, "globex", "GET", "/api/v1/users/999", "")
+	for _, r := range []struct{ method, body string }{
+		{"GET", ""},
+		{"PUT", testutil.NewUserBuilder().WithName("Mallory").WithDOB("1990-05-10").JSON()},
+		{"DELETE", ""},
+	} {
+		status, body := doTenantRequest(t, app, "globex", r.method, path, r.body)
+		if status != fiber.StatusNotFound || !reflect.DeepEqual(body, missing) {
+			t.Errorf("cross-tenant %s = %d %v, want 404 %v", r.method, status, body, missing)
+		}
+	}
+
+	for tenantID, want := range map[string]float64{"acme": 1, "globex": 0} {
+		status, body := doTenantRequest(t, app, tenantID, "GET", "/api/v1/users", "")
+		if status != fiber.StatusOK || body["total"] != want || len(body["users"].([]any)) != int(want) {
+			t.Errorf("%s list = %d %v, want %v users", tenantID, status, body, want)
+		}
+	}
+
+	status, body := doTenantRequest(t, app, "acme", "GET", path, "")
+	if status != fiber.StatusOK || body["name"] != "Alice" {
+		t.Errorf("after foreign writes owner sees %d %v, want Alice unchanged", status, body)
+	}
+
+	if status, body := doRequest(t, app, "GET", path, ""); status != fiber.StatusBadRequest || body["error"] != "Missing tenant" {
+		t.Errorf("request without a tenant = %d %v, want 400", status, body)
+	}
+	if status, body := doTenantRequest(t, app, "initech", "GET", path, ""); status != fiber.StatusBadRequest || body["error"] != "Unknown tenant" {
+		t.Errorf("request for an unknown tenant = %d %v, want 400", status, body)
+	}
+}
+
+func TestShareTokens(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(clock.Fixed(goldenNow))
+	cfg := config.NewHolder(&config.Config{Tenants: []string{"acme", "globex"}})
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	svc := service.NewUserService(repo, zap.NewNop(), service.WithClock(clock.Fixed(goldenNow)))
+	routes.SetupRoutes(app, handler.NewUserHandler(svc, zap.NewNop()), testAdminGuard, middleware.Tenant(cfg), routes.CachePolicies{})
+
+	_, created := doTenantRequest(t, app, "acme", "POST", "/api/v1/users", testutil.NewUserBuilder().WithName("Alice").WithDOB("1990-05-10").JSON())
+	path := fmt.Sprintf("/api/v1/users/%v/share", created["id"])
+
+	status, share := doTenantRequest(t, app, "acme", "POST", path, "")
+	if status != fiber.StatusCreated || share["token"] == nil || share["url"] != "/api/v1/shared/"+share["token"].(string) {
+		t.Fatalf("create share = %d %v", status, share)
+	}
+
+	// No tenant header: the token alone is enough.
+	status, profile := doRequest(t, app, "GET", share["url"].(string), "")
+	want := map[string]any{"name": "Alice", "age": float64(35), "next_birthday": "2026-05-10"}
+	if status != fiber.StatusOK || !reflect.DeepEqual(profile, want) {
+		t.Errorf("shared profile = %d %v, want %v", status, profile, want)
+	}
+
+	status, list := doTenantRequest(t, app, "acme", "GET", path, "")
+	shares, _ := list["shares"].([]any)
+	if status != fiber.StatusOK || len(shares) != 1 || shares[0].(map[string]any)["token"] != nil {
+		t.Errorf("list shares = %d %v, want one share without its token", status, list)
+	}
+	if status, _ := doTenantRequest(t, app, "globex", "GET", path, ""); status != fiber.StatusNotFound {
+		t.Errorf("list shares from another tenant = %d, want 404", status)
+	}
+
+	revoke := fmt.Sprintf("%s/%v", path, share["id"])
+	if status, _ := doTenantRequest(t, app, "globex", "DELETE", revoke, ""); status != fiber.StatusNotFound {
+		t.Errorf("revoke from another tenant = %d, want 404", status)
+	}
+	if status, _ := doTenantRequest(t, app, "acme", "DELETE", revoke, ""); status != fiber.StatusNoContent {
+		t.Errorf("revoke = %d, want 204", status)
+	}
+	if status, body := doTenantRequest(t, app, "acme", "DELETE", revoke, ""); status != fiber.StatusNotFound || body["error"] != "Share not found" {
+		t.Errorf("revoke again = %d %v, want 404", status, body)
+	}
+	if status, body := doRequest(t, app, "GET", share["url"].(string), ""); status != fiber.StatusNotFound || body["error"] != "Share not found" {
+		t.Errorf("revoked shared profile = %d %v, want 404", status, body)
+	}
+	if status, body := doTenantRequest(t, app, "acme", "DELETE", path+"/first", ""); status != fiber.StatusBadRequest || body["error"] != "Invalid share ID" {
+		t.Errorf("revoke with a bad id = %d %v, want 400", status, body)
+	}
+}
+
+func TestPatchUser(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		want   map[string]any
+	}{
+		{name: "empty patch keeps everything", body: `{}`, status: fiber.StatusOK, want: map[string]any{"name": "Alice", "dob": "1990-05-10"}},
+		{name: "name replaced, dob absent", body: `{"name":"Alicia"}`, status: fiber.StatusOK, want: map[string]any{"name": "Alicia", "dob": "1990-05-10"}},
+		{name: "dob replaced, name absent", body: `{"dob":"1991-03-15"}`, status: fiber.StatusOK, want: map[string]any{"name": "Alice", "dob": "1991-03-15"}},
+		{name: "both replaced", body: `{"name":"Alicia","dob":"1991-03-15"}`, status: fiber.StatusOK, want: map[string]any{"name": "Alicia", "dob": "1991-03-15"}},
+		{name: "name cleared", body: `{"name":null}`, status: fiber.StatusBadRequest, want: map[string]any{"error": "Validation failed"}},
+		{name: "dob cleared", body: `{"dob":null,"name":"Alicia"}`, status: fiber.StatusBadRequest, want: map[string]any{"error": "Validation failed"}},
+		{name: "merged result invalid", body: `{"name":"A"}`, status: fiber.StatusBadRequest, want: map[string]any{"error": "Validation failed"}},
+		{name: "wrong type", body: `{"name":5}`, status: fiber.StatusBadRequest, want: map[string]any{"error": "Invalid request body"}},
+		{name: "unknown field", body: `{"nickname":"Al"}`, status: fiber.StatusBadRequest, want: map[string]any{"error": "Unknown fields in request body"}},
+		{name: "array patch", body: `[{"name":"Alicia"}]`, status: fiber.StatusBadRequest, want: map[string]any{"error": "Merge patch must be a JSON object"}},
+		{name: "null patch", body: `null`, status: fiber.StatusBadRequest, want: map[string]any{"error": "Merge patch must be a JSON object"}},
+		{name: "malformed", body: `{"name":`, status: fiber.StatusBadRequest, want: map[string]any{"error": "Invalid request body"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewMemoryUserRepository(clock.Fixed(time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)))
+			testutil.MustInsert(t, repo, testutil.NewUserBuilder().WithName("Alice").WithDOB("1990-05-10").Build())
+			app := newTestApp(service.NewUserService(repo, zap.NewNop()))
+
+			req := httptest.NewRequest("PATCH", "/api/v1/users/1", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", handler.MIMEApplicationMergePatchJSON)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var body map[string]any
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d (body %v)", resp.StatusCode, tt.status, body)
+			}
+			for key, want := range tt.want {
+				if body[key] != want {
+					t.Errorf("%s = %v, want %v", key, body[key], want)
+				}
+			}
+
+			// A rejected patch must leave the stored user alone.
+			stored, err := repo.GetById(context.Background(), 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantName, wantDOB := "Alice", "1990-05-10"
+			if tt.status == fiber.StatusOK {
+				wantName, wantDOB = tt.want["name"].(string), tt.want["dob"].(string)
+			}
+			if stored.Name != wantName || stored.DOB.Format("2006-01-02") != wantDOB {
+				t.Errorf("stored = %s %s, want %s %s", stored.Name, stored.DOB.Format("2006-01-02"), wantName, wantDOB)
+			}
+		})
+	}
+}
+
+func TestPatchUserClearReportsField(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(clock.Real())
+	testutil.MustInsert(t, repo, testutil.NewUserBuilder().WithName("Alice").WithDOB("1990-05-10").Build())
+	app := newTestApp(service.NewUserService(repo, zap.NewNop()))
+
+	for field, body := range map[string]string{"name": `{"name":null}`, "dob": `{"dob":null}`} {
+		status, resp := doRequest(t, app, "PATCH", "/api/v1/users/1", body)
+		details, _ := resp["details"].([]any)
+		if status != fiber.StatusBadRequest || len(details) != 1 {
+			t.Fatalf("clearing %s = %d %v, want one validation detail", field, status, resp)
+		}
+		if detail := details[0].(map[string]any); detail["field"] != field || detail["rule"] != "required" {
+			t.Errorf("clearing %s reported %v, want %s required", field, detail, field)
+		}
+	}
+}
+
+func TestPatchUserErrors(t *testing.T) {
+	app := newTestApp(service.NewUserService(repository.NewMemoryUserRepository(clock.Real()), zap.NewNop()))
+
+	if status, body := doRequest(t, app, "PATCH", "/api/v1/users/404", `{"name":"Nobody"}`); status != fiber.StatusNotFound || body["error"] != "User not found" {
+		t.Errorf("missing user = %d %v, want 404", status, body)
+	}
+	if status, _ := doRequest(t, app, "PATCH", "/api/v1/users/abc", `{}`); status != fiber.StatusBadRequest {
+		t.Errorf("invalid id status = %d, want 400", status)
+	}
+
+	req := httptest.NewRequest("PATCH", "/api/v1/users/1", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json-patch+json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusUnsupportedMediaType {
+		t.Errorf("JSON Patch body: status = %d, want 415", resp.StatusCode)
+	}
+}
+
+func TestUpdateUsersBatch(t *testing.T) {
+	newApp := func(t *testing.T) *fiber.App {
+		repo := repository.NewMemoryUserRepository(clock.Fixed(time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)))
+		testutil.MustInsert(t, repo,
+			testutil.NewUserBuilder().WithName("Alice").WithDOB("1990-05-10").Build(),
+			testutil.NewUserBuilder().WithName("Bob").WithDOB("2000-02-29").Build(),
+		)
+		return newTestApp(service.NewUserService(repo, zap.NewNop()))
+	}
+	statuses := func(body map[string]any) []string {
+		var out []string
+		for _, r := range body["results"].([]any) {
+			out = append(out, r.(map[string]any)["status"].(string))
+		}
+		return out
+	}
+
+	mixed := `[{"id":1,"name":"Alicia"},{"id":404,"name":"Nobody"},{"id":2,"dob":"2001-03-01"}]`
+	tests := []struct {
+		name     string
+		target   string
+		body     string
+		status   int
+		statuses []string
+		aliceIs  string
+	}{
+		{name: "mixed success and not found", target: "/api/v1/users/batch", body: mixed, status: fiber.StatusOK,
+			statuses: []string{"updated", "not_found", "updated"}, aliceIs: "Alicia"},
+		{name: "atomic with a missing id", target: "/api/v1/users/batch?atomic=true", body: mixed, status: fiber.StatusUnprocessableEntity,
+			statuses: []string{"not_applied", "not_found", "not_applied"}, aliceIs: "Alice"},
+		{name: "atomic success", target: "/api/v1/users/batch?atomic=true", body: `[{"id":1,"name":"Alicia"},{"id":2,"name":"Robert"}]`, status: fiber.StatusOK,
+			statuses: []string{"updated", "updated"}, aliceIs: "Alicia"},
+		{name: "invalid items are reported in place", target: "/api/v1/users/batch",
+			body:   `[{"id":1,"name":"A"},{"id":2,"nickname":"Bobby"},{"id":1,"name":null},{"name":"Nobody"},42,{"id":2,"dob":"2001-03-01"}]`,
+			status: fiber.StatusOK, statuses: []string{"invalid", "invalid", "invalid", "invalid", "invalid", "updated"}, aliceIs: "Alice"},
+		{name: "atomic with an invalid item", target: "/api/v1/users/batch?atomic=1", body: `[{"id":1,"name":"Alicia"},{"id":2,"dob":"soon"}]`, status: fiber.StatusUnprocessableEntity,
+			statuses: []string{"not_applied", "invalid"}, aliceIs: "Alice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newApp(t)
+			status, body := doRequest(t, app, "PATCH", tt.target, tt.body)
+			if status != tt.status {
+				t.Fatalf("status = %d, want %d (body %v)", status, tt.status, body)
+			}
+			if got := statuses(body); !reflect.DeepEqual(got, tt.statuses) {
+				t.Errorf("statuses = %v, want %v", got, tt.statuses)
+			}
+			if _, alice := doRequest(t, app, "GET", "/api/v1/users/1", ""); alice["name"] != tt.aliceIs {
+				t.Errorf("user 1 is %v, want %s", alice["name"], tt.aliceIs)
+			}
+		})
+	}
+}
+
+func TestUpdateUsersBatchItemDetails(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(clock.Real())
+	testutil.MustInsert(t, repo, testutil.NewUserBuilder().WithName("Alice").WithDOB("1990-05-10").Build())
+	app := newTestApp(service.NewUserService(repo, zap.NewNop()))
+
+	status, body := doRequest(t, app, "PATCH", "/api/v1/users/batch", `[{"id":1,"name":"Alicia"},{"id":1,"dob":null},{"id":0}]`)
+	if status != fiber.StatusOK || body["updated"] != float64(1) || body["failed"] != float64(2) {
+		t.Fatalf("status = %d, body = %v", status, body)
+	}
+	results := body["results"].([]any)
+	if updated := results[0].(map[string]any); updated["user"].(map[string]any)["name"] != "Alicia" {
+		t.Errorf("updated result = %v, want the full user", updated)
+	}
+	for i, want := range []struct{ field, rule string }{{"dob", "required"}, {"id", "required"}} {
+		result := results[i+1].(map[string]any)
+		details, _ := result["details"].([]any)
+		if len(details) == 0 {
+			t.Errorf("result %d = %v, want details", i+1, result)
+			continue
+		}
+		if d := details[0].(map[string]any); d["field"] != want.field || d["rule"] != want.rule || result["error"] != "Validation failed" {
+			t.Errorf("result %d = %v, want %s %s", i+1, result, want.field, want.rule)
+		}
+	}
+}
+
+func TestUpdateUsersBatchErrors(t *testing.T) {
+	app := newTestApp(service.NewUserService(repository.NewMemoryUserRepository(clock.Real()), zap.NewNop()))
+	tooMany := "[" + strings.Repeat(`{"id":1,"name":"Alicia"},`, models.MaxBatchSize) + `{"id":1,"name":"Alicia"}]`
+
+	tests := []struct {
+		name   string
+		target string
+		body   string
+		want   string
+	}{
+		{name: "object body", target: "/api/v1/users/batch", body: `{"id":1}`, want: "Batch must be a JSON array"},
+		{name: "malformed", target: "/api/v1/users/batch", body: `[{"id":1`, want: "Invalid request body"},
+		{name: "empty", target: "/api/v1/users/batch", body: `[]`, want: "Batch must contain between 1 and 200 items"},
+		{name: "too many", target: "/api/v1/users/batch", body: tooMany, want: "Batch must contain between 1 and 200 items"},
+		{name: "bad atomic flag", target: "/api/v1/users/batch?atomic=maybe", body: `[{"id":1}]`, want: "Invalid query parameters"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := doRequest(t, app, "PATCH", tt.target, tt.body)
+			if status != fiber.StatusBadRequest || body["error"] != tt.want {
+				t.Errorf("status = %d, body = %v, want 400 %q", status, body, tt.want)
+			}
+		})
+	}
+}
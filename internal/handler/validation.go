@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/service"
+)
+
+// newValidator resolves the name_policy tag to policy. Through the alias a
+// failure reports rule name_policy with the policy as its param.
+func newValidator(policy service.NamePolicy) *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(clientFieldName)
+	_ = v.RegisterValidation("name_chars", func(fl validator.FieldLevel) bool {
+		return len(service.NamePolicy(fl.Param()).Disallowed(fl.Field().String())) == 0
+	})
+	v.RegisterAlias("name_policy", "name_chars="+string(policy))
+	return v
+}
+
+// semanticRules are the rules a request breaks by asking for something that
+// cannot be, though each parameter parses: a range whose bounds are reversed.
+var semanticRules = map[string]bool{"ltefield": true}
+
+// validationStatus is 422 when every one of details is a semantic rule and
+// strict status codes are on, and 400 otherwise, so a request that is also
+// malformed still answers 400.
+func (h *UserHandler) validationStatus(details []models.FieldError) int {
+	if !h.strictStatus {
+		return fiber.StatusBadRequest
+	}
+	for _, d := range details {
+		if !semanticRules[d.Rule] {
+			return fiber.StatusBadRequest
+		}
+	}
+	return fiber.StatusUnprocessableEntity
+}
+
+// clientFieldName prefers the json tag, then the query tag, so body and
+// query-string validation both report the wire name.
+func clientFieldName(field reflect.StructField) string {
+	for _, key := range []string{"json", "query"} {
+		name, _, _ := strings.Cut(field.Tag.Get(key), ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// formatValidationErrors keeps the validator's struct-field order, which is
+// stable for a given type.
+func formatValidationErrors(err error) []models.FieldError {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return []models.FieldError{{Rule: "invalid", Message: err.Error()}}
+	}
+
+	details := make([]models.FieldError, 0, len(validationErrors))
+	for _, fieldErr := range validationErrors {
+		details = append(details, models.FieldError{
+			Field:   fieldErr.Field(),
+			Rule:    fieldErr.Tag(),
+			Param:   fieldErr.Param(),
+			Message: validationMessage(fieldErr),
+		})
+	}
+	return details
+}
+
+func validationMessage(fieldErr validator.FieldError) string {
+	field, param := fieldErr.Field(), fieldErr.Param()
+	unit := ""
+	if fieldErr.Kind() == reflect.String {
+		unit = " characters"
+	}
+
+	switch fieldErr.Tag() {
+	case "required":
+		return field + " is required"
+	case "min":
+		return fmt.Sprintf("%s must be at least %s%s", field, param, unit)
+	case "max":
+		return fmt.Sprintf("%s must be at most %s%s", field, param, unit)
+	case "datetime":
+		return fmt.Sprintf("%s must be a date in the format %s", field, param)
+	case "bcp47_language_tag":
+		return field + " must be a BCP 47 language tag, such as de-DE"
+	case "oneof":
+		return fmt.Sprintf("%s must be one of: %s", field, strings.ReplaceAll(param, " ", ", "))
+	case "name_policy":
+		policy := service.NamePolicy(param)
+		value, _ := fieldErr.Value().(string)
+		return service.NamePolicyMessage(policy, policy.Disallowed(value))
+	default:
+		return fmt.Sprintf("%s failed the %s rule", field, fieldErr.Tag())
+	}
+}
+
+// splitList splits the comma-separated items of a repeated query parameter.
+func splitList(values []string) []string {
+	var items []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// queryBool reads an optional boolean query parameter; absent means false.
+func queryBool(c *fiber.Ctx, key string) (bool, error) {
+	value := c.Query(key)
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+func queryBoolErrors(c *fiber.Ctx, keys ...string) []models.FieldError {
+	var details []models.FieldError
+	for _, key := range keys {
+		value := c.Query(key)
+		if value == "" {
+			continue
+		}
+		if _, err := strconv.ParseBool(value); err != nil {
+			details = append(details, models.FieldError{
+				Field:   key,
+				Rule:    "boolean",
+				Message: key + " must be true or false",
+			})
+		}
+	}
+	return details
+}
+
+// queryIntErrors reports which of keys carry a value that is not an integer,
+// since Fiber's QueryParser error does not name the offending parameter.
+func queryIntErrors(c *fiber.Ctx, keys ...string) []models.FieldError {
+	var details []models.FieldError
+	for _, key := range keys {
+		value := c.Query(key)
+		if value == "" {
+			continue
+		}
+		if _, err := strconv.Atoi(value); err != nil {
+			details = append(details, models.FieldError{
+				Field:   key,
+				Rule:    "integer",
+				Message: key + " must be an integer",
+			})
+		}
+	}
+	return details
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/service"
+)
+
+func TestFormatValidationErrorsUsesWireNames(t *testing.T) {
+	v := newValidator(service.NamePolicyAny)
+
+	err := v.Struct(models.CreateUserRequest{Name: "A", DOB: "1990/05/10"})
+	want := []models.FieldError{
+		{Field: "name", Rule: "min", Param: "2", Message: "name must be at least 2 characters"},
+		{Field: "dob", Rule: "datetime", Param: "2006-01-02", Message: "dob must be a date in the format 2006-01-02"},
+	}
+	for i := 0; i < 20; i++ {
+		if got := formatValidationErrors(err); !reflect.DeepEqual(got, want) {
+			t.Fatalf("run %d: details = %+v, want %+v", i, got, want)
+		}
+	}
+
+	err = v.Struct(models.UserListQuery{Page: -1, PageSize: 500})
+	want = []models.FieldError{
+		{Field: "page", Rule: "min", Param: "1", Message: "page must be at least 1"},
+		{Field: "page_size", Rule: "max", Param: "100", Message: "page_size must be at most 100"},
+	}
+	if got := formatValidationErrors(err); !reflect.DeepEqual(got, want) {
+		t.Errorf("query details = %+v, want %+v", got, want)
+	}
+}
+
+func TestFormatValidationErrorsFallback(t *testing.T) {
+	got := formatValidationErrors(errors.New("not a validation error"))
+	want := []models.FieldError{{Rule: "invalid", Message: "not a validation error"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("details = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,117 @@
+// Package i18n formats the human-facing dates of a response in the reader's
+// language. Machine-readable fields stay ISO 8601 and never pass through here.
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+// Supported lists the languages with their own names for months and weekdays.
+// The first is the fallback for every other tag.
+var Supported = []language.Tag{language.English, language.German, language.Spanish, language.Japanese}
+
+var matcher = language.NewMatcher(Supported)
+
+type names struct {
+	months   [12]string
+	weekdays [7]string
+	// date lays out day, month name and year.
+	date func(day int, month string, year int) string
+}
+
+var byLanguage = map[language.Tag]names{
+	language.English: {
+		months:   [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+		weekdays: [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+		date: func(day int, month string, year int) string {
+			return fmt.Sprintf("%s %d, %d", month, day, year)
+		},
+	},
+	language.German: {
+		months:   [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		weekdays: [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		date: func(day int, month string, year int) string {
+			return fmt.Sprintf("%d. %s %d", day, month, year)
+		},
+	},
+	language.Spanish: {
+		months:   [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		weekdays: [7]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+		date: func(day int, month string, year int) string {
+			return fmt.Sprintf("%d de %s de %d", day, month, year)
+		},
+	},
+	language.Japanese: {
+		months:   [12]string{"1月", "2月", "3月", "4月", "5月", "6月", "7月", "8月", "9月", "10月", "11月", "12月"},
+		weekdays: [7]string{"日曜日", "月曜日", "火曜日", "水曜日", "木曜日", "金曜日", "土曜日"},
+		date: func(day int, month string, year int) string {
+			return fmt.Sprintf("%d年%s%d日", year, month, day)
+		},
+	},
+}
+
+// Match picks the supported language closest to tags, in order of
+// preference, so de-AT reads German and an unknown or malformed tag English.
+func Match(tags ...language.Tag) language.Tag {
+	_, index, _ := matcher.Match(tags...)
+	return Supported[index]
+}
+
+// Parse matches a stored BCP 47 tag; empty or malformed is English.
+func Parse(tag string) language.Tag {
+	parsed, err := language.Parse(tag)
+	if err != nil {
+		return Supported[0]
+	}
+	return Match(parsed)
+}
+
+// ParseAcceptLanguage matches an Accept-Language header. ok is false when
+// the header names no supported language, so the caller can fall back to
+// another preference rather than to English.
+func ParseAcceptLanguage(header string) (tag language.Tag, ok bool) {
+	tags, _, err := language.ParseAcceptLanguage(header)
+	if err != nil || len(tags) == 0 {
+		return language.Und, false
+	}
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return language.Und, false
+	}
+	return Supported[index], true
+}
+
+// FormatDate is the long form of t's date, such as "May 10, 2026" or
+// "10. Mai 2026".
+func FormatDate(tag language.Tag, t time.Time) string {
+	n := lookup(tag)
+	return n.date(t.Day(), n.months[t.Month()-1], t.Year())
+}
+
+func Weekday(tag language.Tag, day time.Weekday) string {
+	return lookup(tag).weekdays[day]
+}
+
+func lookup(tag language.Tag) names {
+	if n, ok := byLanguage[tag]; ok {
+		return n
+	}
+	return byLanguage[Match(tag)]
+}
+
+type key struct{}
+
+// WithTag carries the language a request asked for, overriding the stored
+// locale of every user in its response.
+func WithTag(ctx context.Context, tag language.Tag) context.Context {
+	return context.WithValue(ctx, key{}, tag)
+}
+
+func FromContext(ctx context.Context) (language.Tag, bool) {
+	tag, ok := ctx.Value(key{}).(language.Tag)
+	return tag, ok
+}
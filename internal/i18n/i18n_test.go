@@ -0,0 +1,69 @@
+package i18n
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+func TestFormatDate(t *testing.T) {
+	date := time.Date(2026, 5, 10, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		tag     string
+		date    string
+		weekday string
+	}{
+		{tag: "en", date: "May 10, 2026", weekday: "Sunday"},
+		{tag: "en-GB", date: "May 10, 2026", weekday: "Sunday"},
+		{tag: "de", date: "10. Mai 2026", weekday: "Sonntag"},
+		{tag: "de-AT", date: "10. Mai 2026", weekday: "Sonntag"},
+		{tag: "es-MX", date: "10 de mayo de 2026", weekday: "domingo"},
+		{tag: "ja-JP", date: "2026年5月10日", weekday: "日曜日"},
+		{tag: "fr", date: "May 10, 2026", weekday: "Sunday"},
+		{tag: "not a tag", date: "May 10, 2026", weekday: "Sunday"},
+		{tag: "", date: "May 10, 2026", weekday: "Sunday"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			tag := Parse(tt.tag)
+			if got := FormatDate(tag, date); got != tt.date {
+				t.Errorf("FormatDate(%s) = %q, want %q", tt.tag, got, tt.date)
+			}
+			if got := Weekday(tag, date.Weekday()); got != tt.weekday {
+				t.Errorf("Weekday(%s) = %q, want %q", tt.tag, got, tt.weekday)
+			}
+		})
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   language.Tag
+		ok     bool
+	}{
+		{header: "de-DE,de;q=0.9,en;q=0.8", want: language.German, ok: true},
+		{header: "fr-FR, ja;q=0.5", want: language.Japanese, ok: true},
+		{header: "es", want: language.Spanish, ok: true},
+		{header: "fr-FR", ok: false},
+		{header: "", ok: false},
+		{header: ";;;", ok: false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseAcceptLanguage(tt.header)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("ParseAcceptLanguage(%q) = %v, %v; want %v, %v", tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestContext(t *testing.T) {
+	if _, ok := FromContext(context.Background()); ok {
+		t.Error("FromContext of an empty context found a tag")
+	}
+	if tag, ok := FromContext(WithTag(context.Background(), language.German)); !ok || tag != language.German {
+		t.Errorf("FromContext = %v, %v; want de", tag, ok)
+	}
+}
@@ -7,7 +7,7 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
-func NewLogger() *zap.Logger {
+func NewLogger() (*zap.Logger, zap.AtomicLevel) {
 	env := os.Getenv("ENV")
 
 	var config zap.Config
@@ -17,11 +17,33 @@ func NewLogger() *zap.Logger {
 		config = zap.NewDevelopmentConfig()
 		config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
 	}
+	config.Level = zap.NewAtomicLevelAt(defaultLevel())
 
 	logger, err := config.Build()
 	if err != nil {
 		panic(err)
 	}
 
-	return logger
+	return logger, config.Level
+}
+
+func SetLevel(level zap.AtomicLevel, name string) error {
+	if name == "" {
+		level.SetLevel(defaultLevel())
+		return nil
+	}
+
+	parsed, err := zapcore.ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	level.SetLevel(parsed)
+	return nil
+}
+
+func defaultLevel() zapcore.Level {
+	if os.Getenv("ENV") == "production" {
+		return zapcore.InfoLevel
+	}
+	return zapcore.DebugLevel
 }
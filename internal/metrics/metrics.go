@@ -0,0 +1,257 @@
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/srinivasarynh/age_calculator/internal/buildinfo"
+)
+
+const namespace = "user_api"
+
+func NewRegistry() *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		newBuildInfoCollector(),
+	)
+	return registry
+}
+
+func newBuildInfoCollector() prometheus.Collector {
+	info := buildinfo.Get()
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "build_info",
+		Help:      "Build metadata of the running binary; the value is always 1.",
+		ConstLabels: prometheus.Labels{
+			"version":    info.Version,
+			"commit":     info.Commit,
+			"build_date": info.BuildDate,
+			"go_version": info.GoVersion,
+		},
+	})
+	gauge.Set(1)
+	return gauge
+}
+
+// Cache counts lookups against the user cache. Errors covers any failed
+// cache operation, each of which falls back to the database.
+type Cache struct {
+	Hits   prometheus.Counter
+	Misses prometheus.Counter
+	Errors prometheus.Counter
+}
+
+func NewCache(registry prometheus.Registerer) *Cache {
+	counter := func(name, help string) prometheus.Counter {
+		return prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "user_cache",
+			Name:      name,
+			Help:      help,
+		})
+	}
+	m := &Cache{
+		Hits:   counter("hits_total", "User lookups served from the cache."),
+		Misses: counter("misses_total", "User lookups that went to the database."),
+		Errors: counter("errors_total", "Cache operations that failed and fell back to the database."),
+	}
+	registry.MustRegister(m.Hits, m.Misses, m.Errors)
+	return m
+}
+
+// SingleFlight counts user lookups answered by a query another lookup of the
+// same user already had in flight.
+type SingleFlight struct {
+	Collapsed prometheus.Counter
+}
+
+func NewSingleFlight(registry prometheus.Registerer) *SingleFlight {
+	m := &SingleFlight{
+		Collapsed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "user_reads_collapsed_total",
+			Help:      "User lookups that shared a concurrent identical query instead of running their own.",
+		}),
+	}
+	registry.MustRegister(m.Collapsed)
+	return m
+}
+
+// Users counts successful writes made through the service layer, and the
+// rows of CSV imports by result: "ok" for a row written or skipped by a
+// committed batch, "error" for a row rejected.
+type Users struct {
+	Created    prometheus.Counter
+	Updated    prometheus.Counter
+	Deleted    prometheus.Counter
+	ImportRows *prometheus.CounterVec
+}
+
+func NewUsers(registry prometheus.Registerer) *Users {
+	counter := func(name, help string) prometheus.Counter {
+		return prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      name,
+			Help:      help,
+		})
+	}
+	m := &Users{
+		Created: counter("users_created_total", "Users created."),
+		Updated: counter("users_updated_total", "Users updated."),
+		Deleted: counter("users_deleted_total", "Users deleted."),
+		ImportRows: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "imports_rows_total",
+			Help:      "Rows of CSV imports, by result.",
+		}, []string{"result"}),
+	}
+	registry.MustRegister(m.Created, m.Updated, m.Deleted, m.ImportRows)
+	return m
+}
+
+// Webhooks counts delivery attempts of outgoing webhooks by result, "ok" or
+// "error". The event dispatcher and the birthday notifier both send them,
+// so NewWebhooks may be called for a registry more than once; the calls
+// share one counter.
+type Webhooks struct {
+	Deliveries *prometheus.CounterVec
+}
+
+func NewWebhooks(registry prometheus.Registerer) *Webhooks {
+	deliveries := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Webhook delivery attempts, by result.",
+	}, []string{"result"})
+	if err := registry.Register(deliveries); err != nil {
+		var registered prometheus.AlreadyRegisteredError
+		if !errors.As(err, &registered) {
+			panic(err)
+		}
+		deliveries = registered.ExistingCollector.(*prometheus.CounterVec)
+	}
+	return &Webhooks{Deliveries: deliveries}
+}
+
+// Delivered counts one attempt, failed unless err is nil.
+func (m *Webhooks) Delivered(err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	m.Deliveries.WithLabelValues(result).Inc()
+}
+
+// HTTP counts the requests served, by status class such as "2xx", and
+// those still being handled. Deprecated counts the requests to deprecated
+// routes by route name, and ValidationFailures the fields requests were
+// rejected for by route and field name.
+type HTTP struct {
+	Requests           *prometheus.CounterVec
+	InFlight           prometheus.Gauge
+	Deprecated         *prometheus.CounterVec
+	ValidationFailures *prometheus.CounterVec
+}
+
+func NewHTTP(registry prometheus.Registerer) *HTTP {
+	m := &HTTP{
+		Requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "HTTP requests served, by status class.",
+		}, []string{"class"}),
+		InFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_in_flight",
+			Help:      "HTTP requests being handled.",
+		}),
+		Deprecated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "deprecated_requests_total",
+			Help:      "HTTP requests to deprecated routes, by route.",
+		}, []string{"route"}),
+		ValidationFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "validation_failures_total",
+			Help:      "Fields requests were rejected for, by endpoint and field.",
+		}, []string{"endpoint", "field"}),
+	}
+	registry.MustRegister(m.Requests, m.InFlight, m.Deprecated, m.ValidationFailures)
+	return m
+}
+
+// DBPool mirrors sql.DBStats. WaitCount and WaitDuration are cumulative
+// totals as reported by database/sql.
+type DBPool struct {
+	Open         prometheus.Gauge
+	InUse        prometheus.Gauge
+	Idle         prometheus.Gauge
+	WaitCount    prometheus.Gauge
+	WaitDuration prometheus.Gauge
+}
+
+func NewDBPool(registry prometheus.Registerer) *DBPool {
+	gauge := func(name, help string) prometheus.Gauge {
+		return prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      name,
+			Help:      help,
+		})
+	}
+	m := &DBPool{
+		Open:         gauge("open_connections", "Established connections, in use or idle."),
+		InUse:        gauge("in_use_connections", "Connections currently in use."),
+		Idle:         gauge("idle_connections", "Idle connections."),
+		WaitCount:    gauge("wait_count", "Total number of connections waited for."),
+		WaitDuration: gauge("wait_duration_seconds", "Total time spent waiting for a connection."),
+	}
+	registry.MustRegister(m.Open, m.InUse, m.Idle, m.WaitCount, m.WaitDuration)
+	return m
+}
+
+func (m *DBPool) Observe(stats sql.DBStats) {
+	m.Open.Set(float64(stats.OpenConnections))
+	m.InUse.Set(float64(stats.InUse))
+	m.Idle.Set(float64(stats.Idle))
+	m.WaitCount.Set(float64(stats.WaitCount))
+	m.WaitDuration.Set(stats.WaitDuration.Seconds())
+}
+
+// Sample observes db now and then every interval until the returned stop
+// function is called.
+func (m *DBPool) Sample(db interface{ Stats() sql.DBStats }, interval time.Duration) (stop func()) {
+	m.Observe(db.Stats())
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				m.Observe(db.Stats())
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
+
+func Handler(registry *prometheus.Registry) fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+}
@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/srinivasarynh/age_calculator/internal/buildinfo"
+)
+
+func TestBuildInfoGauge(t *testing.T) {
+	buildinfo.Version = "1.4.2"
+	defer func() { buildinfo.Version = "dev" }()
+
+	collector := newBuildInfoCollector()
+	expected := `
+# HELP user_api_build_info Build metadata of the running binary; the value is always 1.
+# TYPE user_api_build_info gauge
+user_api_build_info{build_date="dev",commit="dev",go_version="` + buildinfo.Get().GoVersion + `",version="1.4.2"} 1
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
+
+type fakeDB struct {
+	inUse atomic.Int64
+}
+
+func (f *fakeDB) Stats() sql.DBStats {
+	inUse := int(f.inUse.Load())
+	return sql.DBStats{
+		OpenConnections: inUse + 2,
+		InUse:           inUse,
+		Idle:            2,
+		WaitCount:       7,
+		WaitDuration:    1500 * time.Millisecond,
+	}
+}
+
+func TestDBPoolSample(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	pool := NewDBPool(registry)
+	db := &fakeDB{}
+	db.inUse.Store(3)
+
+	stop := pool.Sample(db, time.Millisecond)
+	defer stop()
+
+	expected := `
+# HELP user_api_db_idle_connections Idle connections.
+# TYPE user_api_db_idle_connections gauge
+user_api_db_idle_connections 2
+# HELP user_api_db_open_connections Established connections, in use or idle.
+# TYPE user_api_db_open_connections gauge
+user_api_db_open_connections 5
+# HELP user_api_db_wait_count Total number of connections waited for.
+# TYPE user_api_db_wait_count gauge
+user_api_db_wait_count 7
+# HELP user_api_db_wait_duration_seconds Total time spent waiting for a connection.
+# TYPE user_api_db_wait_duration_seconds gauge
+user_api_db_wait_duration_seconds 1.5
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"user_api_db_idle_connections", "user_api_db_open_connections",
+		"user_api_db_wait_count", "user_api_db_wait_duration_seconds"); err != nil {
+		t.Error(err)
+	}
+
+	db.inUse.Store(9)
+	deadline := time.Now().Add(time.Second)
+	for testutil.ToFloat64(pool.InUse) != 9 {
+		if time.Now().After(deadline) {
+			t.Fatalf("in_use = %v after a second, want 9", testutil.ToFloat64(pool.InUse))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWebhooksShareOneCounter(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	dispatcher, notifier := NewWebhooks(registry), NewWebhooks(registry)
+	dispatcher.Delivered(nil)
+	notifier.Delivered(errors.New("connection refused"))
+	notifier.Delivered(nil)
+
+	expected := `
+# HELP user_api_webhook_deliveries_total Webhook delivery attempts, by result.
+# TYPE user_api_webhook_deliveries_total counter
+user_api_webhook_deliveries_total{result="error"} 1
+user_api_webhook_deliveries_total{result="ok"} 2
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "user_api_webhook_deliveries_total"); err != nil {
+		t.Error(err)
+	}
+}
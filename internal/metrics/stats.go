@@ -0,0 +1,182 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Stats is a readable summary of what the registry holds, for a quick look
+// without a Prometheus server. DB and Cache are nil when their collectors
+// are not registered.
+type Stats struct {
+	UptimeSeconds float64      `json:"uptime_seconds"`
+	Runtime       RuntimeStats `json:"runtime"`
+	HTTP          HTTPStats    `json:"http"`
+	DB            *DBStats     `json:"db"`
+	Cache         *CacheStats  `json:"cache"`
+}
+
+type RuntimeStats struct {
+	Goroutines     float64 `json:"goroutines"`
+	HeapInUseBytes float64 `json:"heap_in_use_bytes"`
+	GC             GCStats `json:"gc"`
+}
+
+// GCStats comes from go_gc_duration_seconds: MaxPauseSeconds is the longest
+// recent pause the summary still holds.
+type GCStats struct {
+	Cycles            uint64  `json:"cycles"`
+	PauseTotalSeconds float64 `json:"pause_total_seconds"`
+	MaxPauseSeconds   float64 `json:"max_pause_seconds"`
+}
+
+type HTTPStats struct {
+	Requests float64            `json:"requests"`
+	ByClass  map[string]float64 `json:"by_class"`
+	InFlight float64            `json:"in_flight"`
+}
+
+// DBStats is as of the last DBPool sample.
+type DBStats struct {
+	Open                float64 `json:"open"`
+	InUse               float64 `json:"in_use"`
+	Idle                float64 `json:"idle"`
+	WaitCount           float64 `json:"wait_count"`
+	WaitDurationSeconds float64 `json:"wait_duration_seconds"`
+}
+
+// CacheStats leaves HitRatio nil until the first lookup.
+type CacheStats struct {
+	Hits     float64  `json:"hits"`
+	Misses   float64  `json:"misses"`
+	Errors   float64  `json:"errors"`
+	HitRatio *float64 `json:"hit_ratio"`
+}
+
+// Snapshot reads Stats from g as of now, so every figure is one the metrics
+// endpoint would also report.
+func Snapshot(g prometheus.Gatherer, now time.Time) (*Stats, error) {
+	families, err := g.Gather()
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]*dto.MetricFamily, len(families))
+	for _, family := range families {
+		byName[family.GetName()] = family
+	}
+	value := func(name string) (float64, bool) {
+		family, ok := byName[name]
+		if !ok || len(family.GetMetric()) == 0 {
+			return 0, false
+		}
+		return metricValue(family.GetMetric()[0]), true
+	}
+	number := func(name string) float64 {
+		v, _ := value(name)
+		return v
+	}
+
+	stats := &Stats{
+		Runtime: RuntimeStats{
+			Goroutines:     number("go_goroutines"),
+			HeapInUseBytes: number("go_memstats_heap_inuse_bytes"),
+		},
+		HTTP: HTTPStats{ByClass: map[string]float64{}, InFlight: number(namespace + "_http_requests_in_flight")},
+	}
+	if start, ok := value("process_start_time_seconds"); ok {
+		stats.UptimeSeconds = max(now.Sub(time.Unix(0, int64(start*float64(time.Second)))).Seconds(), 0)
+	}
+	if family, ok := byName["go_gc_duration_seconds"]; ok && len(family.GetMetric()) > 0 {
+		summary := family.GetMetric()[0].GetSummary()
+		stats.Runtime.GC.Cycles = summary.GetSampleCount()
+		stats.Runtime.GC.PauseTotalSeconds = summary.GetSampleSum()
+		for _, q := range summary.GetQuantile() {
+			if q.GetQuantile() == 1 {
+				stats.Runtime.GC.MaxPauseSeconds = q.GetValue()
+			}
+		}
+	}
+	if family, ok := byName[namespace+"_http_requests_total"]; ok {
+		for _, m := range family.GetMetric() {
+			for _, label := range m.GetLabel() {
+				if label.GetName() == "class" {
+					stats.HTTP.ByClass[label.GetValue()] += m.GetCounter().GetValue()
+				}
+			}
+			stats.HTTP.Requests += m.GetCounter().GetValue()
+		}
+	}
+	if open, ok := value(namespace + "_db_open_connections"); ok {
+		stats.DB = &DBStats{
+			Open:                open,
+			InUse:               number(namespace + "_db_in_use_connections"),
+			Idle:                number(namespace + "_db_idle_connections"),
+			WaitCount:           number(namespace + "_db_wait_count"),
+			WaitDurationSeconds: number(namespace + "_db_wait_duration_seconds"),
+		}
+	}
+	if hits, ok := value(namespace + "_user_cache_hits_total"); ok {
+		misses := number(namespace + "_user_cache_misses_total")
+		stats.Cache = &CacheStats{Hits: hits, Misses: misses, Errors: number(namespace + "_user_cache_errors_total")}
+		if hits+misses > 0 {
+			ratio := hits / (hits + misses)
+			stats.Cache.HitRatio = &ratio
+		}
+	}
+	return stats, nil
+}
+
+func metricValue(m *dto.Metric) float64 {
+	switch {
+	case m.GetGauge() != nil:
+		return m.GetGauge().GetValue()
+	case m.GetCounter() != nil:
+		return m.GetCounter().GetValue()
+	case m.GetUntyped() != nil:
+		return m.GetUntyped().GetValue()
+	}
+	return 0
+}
+
+// ValidationStats sums validation_failures_total since the process started:
+// ByEndpoint is keyed by endpoint, then field.
+type ValidationStats struct {
+	Total      float64                       `json:"total"`
+	ByField    map[string]float64            `json:"by_field"`
+	ByEndpoint map[string]map[string]float64 `json:"by_endpoint"`
+}
+
+// ValidationSnapshot reads ValidationStats from g.
+func ValidationSnapshot(g prometheus.Gatherer) (*ValidationStats, error) {
+	families, err := g.Gather()
+	if err != nil {
+		return nil, err
+	}
+	stats := &ValidationStats{ByField: map[string]float64{}, ByEndpoint: map[string]map[string]float64{}}
+	for _, family := range families {
+		if family.GetName() != namespace+"_validation_failures_total" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			var endpoint, field string
+			for _, label := range m.GetLabel() {
+				switch label.GetName() {
+				case "endpoint":
+					endpoint = label.GetValue()
+				case "field":
+					field = label.GetValue()
+				}
+			}
+			n := m.GetCounter().GetValue()
+			stats.Total += n
+			stats.ByField[field] += n
+			if stats.ByEndpoint[endpoint] == nil {
+				stats.ByEndpoint[endpoint] = map[string]float64{}
+			}
+			stats.ByEndpoint[endpoint][field] += n
+		}
+	}
+	return stats, nil
+}
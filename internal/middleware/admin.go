@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/srinivasarynh/age_calculator/config"
+)
+
+// AdminOnly restricts a route group to clients in ADMIN_ALLOWED_IPS and, when
+// ADMIN_TOKEN is set, to requests carrying it as a bearer token.
+func AdminOnly(cfg *config.Holder) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		current := cfg.Load()
+
+		if !adminAddress(c, current) {
+			return fiber.NewError(fiber.StatusForbidden, "Forbidden")
+		}
+		if !adminToken(c, current) {
+			return fiber.NewError(fiber.StatusUnauthorized, "Unauthorized")
+		}
+
+		return c.Next()
+	}
+}
+
+// IsAdmin reports whether c would pass AdminOnly, for routes open to everyone
+// that give admins more.
+func IsAdmin(cfg *config.Holder, c *fiber.Ctx) bool {
+	current := cfg.Load()
+	return adminAddress(c, current) && adminToken(c, current)
+}
+
+func adminAddress(c *fiber.Ctx, current *config.Config) bool {
+	// Peers on the unix socket have no address, and behind a proxy every
+	// client shares it, so they count only when ADMIN_TOKEN is set and
+	// adminToken holds each of them to it.
+	if _, local := c.Context().RemoteAddr().(*net.UnixAddr); local {
+		return current.AdminToken != ""
+	}
+	return ipAllowed(c.IP(), current.AdminAllowedIPs)
+}
+
+func adminToken(c *fiber.Ctx, current *config.Config) bool {
+	if current.AdminToken == "" {
+		return true
+	}
+	token := strings.TrimPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(current.AdminToken)) == 1
+}
+
+func ipAllowed(remote string, allowed []string) bool {
+	ip := net.ParseIP(remote)
+	if ip == nil {
+		return false
+	}
+
+	for _, entry := range allowed {
+		network, err := config.ParseIPOrCIDR(entry)
+		if err == nil && network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/srinivasarynh/age_calculator/config"
+)
+
+func TestAdminOnly(t *testing.T) {
+	tests := []struct {
+		name     string
+		allowed  []string
+		token    string
+		header   string
+		expected int
+	}{
+		{name: "ip not allowed", allowed: []string{"127.0.0.1"}, expected: fiber.StatusForbidden},
+		{name: "ip allowed", allowed: []string{"0.0.0.0"}, expected: fiber.StatusOK},
+		{name: "cidr allowed", allowed: []string{"10.0.0.0/8", "0.0.0.0/0"}, expected: fiber.StatusOK},
+		{name: "missing token", allowed: []string{"0.0.0.0"}, token: "s3cret", expected: fiber.StatusUnauthorized},
+		{name: "wrong token", allowed: []string{"0.0.0.0"}, token: "s3cret", header: "Bearer nope", expected: fiber.StatusUnauthorized},
+		{name: "valid token", allowed: []string{"0.0.0.0"}, token: "s3cret", header: "Bearer s3cret", expected: fiber.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.NewHolder(&config.Config{AdminAllowedIPs: tt.allowed, AdminToken: tt.token})
+			app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+			app.Get("/admin", AdminOnly(cfg), func(c *fiber.Ctx) error {
+				return c.SendStatus(fiber.StatusOK)
+			})
+			var isAdmin bool
+			app.Get("/open", func(c *fiber.Ctx) error {
+				isAdmin = IsAdmin(cfg, c)
+				return c.SendStatus(fiber.StatusOK)
+			})
+
+			req := httptest.NewRequest("GET", "/admin", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.expected {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.expected)
+			}
+
+			req = httptest.NewRequest("GET", "/open", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if _, err := app.Test(req); err != nil {
+				t.Fatal(err)
+			}
+			if want := tt.expected == fiber.StatusOK; isAdmin != want {
+				t.Errorf("IsAdmin = %v, want %v", isAdmin, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Deprecated announces that the route named route has been deprecated since
+// since and may stop answering at sunset, pointing clients at successor
+// (RFC 9745, RFC 8594). A zero sunset or empty successor is left out. The
+// parameters of successor, as :id, take the request's values. Metrics counts
+// each request to the route under its name.
+func Deprecated(route string, since, sunset time.Time, successor string) fiber.Handler {
+	deprecation := "@" + strconv.FormatInt(since.Unix(), 10)
+	return func(c *fiber.Ctx) error {
+		c.Locals("deprecatedRoute", route)
+		c.Set("Deprecation", deprecation)
+		if !sunset.IsZero() {
+			c.Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+		if successor != "" {
+			c.Append(fiber.HeaderLink, "<"+successorPath(c, successor)+`>; rel="successor-version"`)
+		}
+		return c.Next()
+	}
+}
+
+// successorPath fills the :name segments of successor with c's route
+// parameters of the same name.
+func successorPath(c *fiber.Ctx, successor string) string {
+	if !strings.Contains(successor, ":") {
+		return successor
+	}
+	segments := strings.Split(successor, "/")
+	for i, segment := range segments {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			segments[i] = url.PathEscape(c.Params(name))
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+)
+
+// EnvelopeMeta is the "meta" of an enveloped response.
+type EnvelopeMeta struct {
+	RequestID string    `json:"request_id,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Envelope wraps the JSON response of each route below it, for clients that
+// expect envelopes: a success becomes {"data": body, "meta": EnvelopeMeta}
+// and an error {"error": StructuredError}, the body /api/v2 sends, whether
+// the handler returned it or wrote a v1 error body. Responses that are not
+// JSON, such as exports and streams, pass through, as do paths under
+// skipPrefixes. strictStatus is as for NewErrorHandler.
+func Envelope(clk clock.Clock, strictStatus bool, skipPrefixes ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		for _, prefix := range skipPrefixes {
+			if strings.HasPrefix(c.Path(), prefix) {
+				return c.Next()
+			}
+		}
+
+		if err := c.Next(); err != nil {
+			status, body := structuredError(c, err, strictStatus)
+			body.RequestID, _ = c.Locals("requestID").(string)
+			return c.Status(status).JSON(fiber.Map{"error": body})
+		}
+
+		resp := c.Response()
+		if resp.IsBodyStream() || len(resp.Body()) == 0 || !strings.HasPrefix(string(resp.Header.ContentType()), fiber.MIMEApplicationJSON) {
+			return nil
+		}
+		requestID, _ := c.Locals("requestID").(string)
+		if resp.StatusCode() >= fiber.StatusBadRequest {
+			body, ok := structuredBody(c)
+			if !ok {
+				// /api/v2 has already written {"error": {...}}.
+				var v2 struct {
+					Error *StructuredError `json:"error"`
+				}
+				if json.Unmarshal(resp.Body(), &v2) != nil || v2.Error == nil {
+					return nil
+				}
+				body = *v2.Error
+			}
+			if body.RequestID == "" {
+				body.RequestID = requestID
+			}
+			return c.JSON(fiber.Map{"error": body})
+		}
+
+		wrapped, err := json.Marshal(struct {
+			Data json.RawMessage `json:"data"`
+			Meta EnvelopeMeta    `json:"meta"`
+		}{
+			Data: resp.Body(),
+			Meta: EnvelopeMeta{RequestID: requestID, Timestamp: clk.Now().UTC()},
+		})
+		if err != nil {
+			return nil
+		}
+		resp.SetBodyRaw(wrapped)
+		return nil
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/service"
+)
+
+func TestEnvelope(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use(Envelope(clock.Fixed(time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)), false, "/graphql"))
+	app.Get("/json", func(c *fiber.Ctx) error { return c.JSON([]int{1, 2}) })
+	app.Get("/csv", func(c *fiber.Ctx) error {
+		c.Type("csv")
+		return c.SendString("id\n1\n")
+	})
+	app.Delete("/empty", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusNoContent) })
+	app.Get("/semantic", func(c *fiber.Ctx) error { return Fail(service.ErrDOBTooEarly, "Failed to create user") })
+	app.Get("/graphql", func(c *fiber.Ctx) error { return c.JSON(fiber.Map{"data": nil}) })
+
+	tests := []struct {
+		method, target string
+		status         int
+		body           string
+	}{
+		{"GET", "/json", fiber.StatusOK, `{"data":[1,2],"meta":{"timestamp":"2025-06-15T12:00:00Z"}}`},
+		{"GET", "/csv", fiber.StatusOK, "id\n1\n"},
+		{"DELETE", "/empty", fiber.StatusNoContent, ""},
+		{"GET", "/semantic", fiber.StatusBadRequest, `{"error":{"code":"DOB_TOO_EARLY","message":"Date of birth is before the earliest year allowed"}}`},
+		{"GET", "/graphql", fiber.StatusOK, `{"data":null}`},
+	}
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest(tt.method, tt.target, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != tt.status || string(body) != tt.body {
+			t.Errorf("%s %s = %d %s, want %d %s", tt.method, tt.target, resp.StatusCode, body, tt.status, tt.body)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/service"
+)
+
+// apiError is the response ErrorHandler sends for one sentinel. Code is the
+// stable name for the failure; the v1 envelope does not carry it yet. A
+// semantic error is a well-formed request breaking a business rule, which
+// strict status codes answer with 422 instead of status.
+type apiError struct {
+	status   int
+	code     string
+	message  string
+	details  []models.FieldError
+	semantic bool
+}
+
+// errorRegistry is matched in order with errors.Is, so wrapped sentinels
+// resolve too.
+var errorRegistry = []struct {
+	err error
+	api apiError
+}{
+	{service.ErrUserNotFound, apiError{status: fiber.StatusNotFound, code: "USER_NOT_FOUND", message: "User not found"}},
+	{repository.ErrNotFound, apiError{status: fiber.StatusNotFound, code: "USER_NOT_FOUND", message: "User not found"}},
+	{service.ErrJobNotFound, apiError{status: fiber.StatusNotFound, code: "JOB_NOT_FOUND", message: "Job not found"}},
+	{repository.ErrJobNotFound, apiError{status: fiber.StatusNotFound, code: "JOB_NOT_FOUND", message: "Job not found"}},
+	{service.ErrNoUsers, apiError{status: fiber.StatusNotFound, code: "NO_USERS", message: "There are no users"}},
+	{service.ErrNameLookupDisabled, apiError{status: fiber.StatusNotFound, code: "NAME_LOOKUP_DISABLED", message: "Lookup by name is not enabled"}},
+	{repository.ErrShareNotFound, apiError{status: fiber.StatusNotFound, code: "SHARE_NOT_FOUND", message: "Share not found"}},
+	{repository.ErrOutboxEventNotFound, apiError{status: fiber.StatusNotFound, code: "EVENT_NOT_FOUND", message: "Dead-lettered event not found"}},
+	{service.ErrOutboxDisabled, apiError{status: fiber.StatusNotImplemented, code: "OUTBOX_DISABLED", message: "The events outbox is not enabled"}},
+	{service.ErrAccessTrackingDisabled, apiError{status: fiber.StatusNotImplemented, code: "ACCESS_TRACKING_DISABLED", message: "Access tracking is not enabled"}},
+	{repository.ErrAnonymized, apiError{status: fiber.StatusConflict, code: "USER_ANONYMIZED", message: "User has been anonymized"}},
+	{repository.ErrDuplicateName, apiError{status: fiber.StatusConflict, code: "DUPLICATE_NAME", message: "A user with this name already exists"}},
+	{repository.ErrFuzzySearchUnsupported, apiError{status: fiber.StatusNotImplemented, code: "FUZZY_SEARCH_UNSUPPORTED", message: "Fuzzy name search is not supported by this database"}},
+	{service.ErrUnknownJobKind, apiError{status: fiber.StatusNotImplemented, code: "JOB_KIND_DISABLED", message: "Job kind is not enabled"}},
+	{service.ErrJobNotFinished, apiError{status: fiber.StatusConflict, code: "JOB_NOT_FINISHED", message: "Job has not finished"}},
+	{service.ErrNoDownload, apiError{status: fiber.StatusNotFound, code: "NO_DOWNLOAD", message: "Job has no download"}},
+	{service.ErrDownloadExpired, apiError{status: fiber.StatusGone, code: "DOWNLOAD_EXPIRED", message: "Download has expired"}},
+	{service.ErrBackupsDisabled, apiError{status: fiber.StatusNotImplemented, code: "BACKUPS_DISABLED", message: "Backups are not enabled"}},
+	{service.ErrInvalidBackup, apiError{status: fiber.StatusBadRequest, code: "INVALID_BACKUP", message: "Invalid backup file"}},
+	{service.ErrBackupTooNew, apiError{status: fiber.StatusBadRequest, code: "BACKUP_TOO_NEW", message: "Backup is from a newer schema version than this server supports; upgrade the server first", semantic: true}},
+	{repository.ErrNotEmpty, apiError{status: fiber.StatusConflict, code: "DATABASE_NOT_EMPTY", message: "The database already has users; restore with force=truncate to replace them"}},
+	{service.ErrInvalidImport, apiError{status: fiber.StatusBadRequest, code: "INVALID_IMPORT", message: "Invalid import file"}},
+	{service.ErrInvalidDate, apiError{status: fiber.StatusBadRequest, code: "INVALID_DATE", message: "Invalid date format. Expected YYYY-MM-DD"}},
+	{service.ErrDOBTooEarly, apiError{status: fiber.StatusBadRequest, code: "DOB_TOO_EARLY", message: "Date of birth is before the earliest year allowed", semantic: true}},
+	{service.ErrInvalidCursor, apiError{
+		status:  fiber.StatusBadRequest,
+		code:    "INVALID_CURSOR",
+		message: "Invalid pagination parameters",
+		details: []models.FieldError{{
+			Field:   "cursor",
+			Rule:    "invalid",
+			Message: "cursor is malformed or was issued for a different sort",
+		}},
+	}},
+}
+
+func lookupError(err error) (apiError, bool) {
+	var verr *service.ValidationError
+	if errors.As(err, &verr) {
+		return apiError{status: fiber.StatusBadRequest, code: "VALIDATION_FAILED", message: "Validation failed", details: verr.Details}, true
+	}
+	var qerr *service.QuotaError
+	if errors.As(err, &qerr) {
+		return apiError{status: fiber.StatusForbidden, code: "QUOTA_EXCEEDED", message: "User limit reached", details: qerr.Details()}, true
+	}
+	for _, entry := range errorRegistry {
+		if errors.Is(err, entry.err) {
+			return entry.api, true
+		}
+	}
+	return apiError{}, false
+}
+
+// Known reports whether ErrorHandler has a specific response for err.
+func Known(err error) bool {
+	_, ok := lookupError(err)
+	return ok
+}
+
+type failure struct {
+	err     error
+	message string
+}
+
+func (f *failure) Error() string { return f.message + ": " + f.err.Error() }
+func (f *failure) Unwrap() error { return f.err }
+
+// Fail returns err for ErrorHandler with the message to send as a 500 when no
+// registry entry matches it.
+func Fail(err error, message string) error {
+	return &failure{err: err, message: message}
+}
+
+// ErrorHandler is NewErrorHandler with strict status codes.
+var ErrorHandler = NewErrorHandler(true)
+
+// NewErrorHandler answers registry errors with their response, failures from
+// Fail with a 500 carrying their message and anything else with its
+// *fiber.Error status or a 500. strictStatus sends 422 for semantic errors;
+// without it they keep their 400 as before.
+func NewErrorHandler(strictStatus bool) fiber.ErrorHandler {
+	return func(c *fiber.Ctx, err error) error {
+		return handleError(c, err, strictStatus)
+	}
+}
+
+func handleError(c *fiber.Ctx, err error, strictStatus bool) error {
+	if c.Method() == fiber.MethodOptions {
+		return c.SendStatus(fiber.StatusOK)
+	}
+
+	if api, ok := lookupError(err); ok {
+		body := fiber.Map{"error": api.message}
+		if api.details != nil {
+			body["details"] = api.details
+		}
+		status := api.status
+		if api.semantic && strictStatus {
+			status = fiber.StatusUnprocessableEntity
+		}
+		return c.Status(status).JSON(body)
+	}
+
+	var f *failure
+	if errors.As(err, &f) {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": f.message,
+		})
+	}
+
+	code := fiber.StatusInternalServerError
+	message := "Internal Server Error"
+
+	if e, ok := err.(*fiber.Error); ok {
+		code = e.Code
+		message = e.Message
+	}
+
+	requestID := ""
+	if id := c.Locals("requestID"); id != nil {
+		requestID = id.(string)
+	}
+
+	return c.Status(code).JSON(fiber.Map{
+		"error":      message,
+		"request_id": requestID,
+	})
+}
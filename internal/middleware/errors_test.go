@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/srinivasarynh/age_calculator/config"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/service"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestErrorHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		body   string
+	}{
+		{
+			name:   "sentinel",
+			err:    service.ErrUserNotFound,
+			status: fiber.StatusNotFound,
+			body:   `{"error":"User not found"}`,
+		},
+		{
+			name:   "wrapped sentinel",
+			err:    Fail(fmt.Errorf("loading user: %w", repository.ErrNotFound), "Failed to get user"),
+			status: fiber.StatusNotFound,
+			body:   `{"error":"User not found"}`,
+		},
+		{
+			name:   "sentinel with details",
+			err:    Fail(service.ErrInvalidCursor, "Failed to list users"),
+			status: fiber.StatusBadRequest,
+			body:   `{"details":[{"field":"cursor","rule":"invalid","message":"cursor is malformed or was issued for a different sort"}],"error":"Invalid pagination parameters"}`,
+		},
+		{
+			name:   "wrapped import error",
+			err:    Fail(fmt.Errorf("%w: the header has no dob column", service.ErrInvalidImport), "Failed to import users"),
+			status: fiber.StatusBadRequest,
+			body:   `{"error":"Invalid import file"}`,
+		},
+		{
+			name:   "unmapped failure",
+			err:    Fail(errors.New("connection reset"), "Failed to create user"),
+			status: fiber.StatusInternalServerError,
+			body:   `{"error":"Failed to create user"}`,
+		},
+		{
+			name:   "fiber error",
+			err:    fiber.NewError(fiber.StatusMethodNotAllowed, "Method Not Allowed"),
+			status: fiber.StatusMethodNotAllowed,
+			body:   `{"error":"Method Not Allowed","request_id":"req-1"}`,
+		},
+		{
+			name:   "unknown error",
+			err:    errors.New("boom"),
+			status: fiber.StatusInternalServerError,
+			body:   `{"error":"Internal Server Error","request_id":"req-1"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+			app.Use(RequestID())
+			app.Get("/", func(c *fiber.Ctx) error { return tt.err })
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("X-Request-ID", "req-1")
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			raw, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.status || string(raw) != tt.body {
+				t.Errorf("response = %d %s, want %d %s", resp.StatusCode, raw, tt.status, tt.body)
+			}
+		})
+	}
+}
+
+func TestErrorHandlerStrictStatusCodes(t *testing.T) {
+	err := Fail(fmt.Errorf("%w: 0001-01-01 is before 1900", service.ErrDOBTooEarly), "Failed to create user")
+	for strict, want := range map[bool]int{true: fiber.StatusUnprocessableEntity, false: fiber.StatusBadRequest} {
+		app := fiber.New(fiber.Config{ErrorHandler: NewErrorHandler(strict)})
+		app.Get("/", func(c *fiber.Ctx) error { return err })
+		app.Get("/format", func(c *fiber.Ctx) error { return service.ErrInvalidDate })
+
+		resp, testErr := app.Test(httptest.NewRequest("GET", "/", nil))
+		if testErr != nil {
+			t.Fatal(testErr)
+		}
+		raw, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != want || string(raw) != `{"error":"Date of birth is before the earliest year allowed"}` {
+			t.Errorf("strict %v: response = %d %s, want %d", strict, resp.StatusCode, raw, want)
+		}
+		if resp, _ := app.Test(httptest.NewRequest("GET", "/format", nil)); resp.StatusCode != fiber.StatusBadRequest {
+			t.Errorf("strict %v: unparseable date status = %d, want 400", strict, resp.StatusCode)
+		}
+	}
+}
+
+func TestLoggerRecordsMappedStatus(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use(RequestID(), Logger(zap.New(core), config.NewHolder(&config.Config{})))
+	app.Get("/", func(c *fiber.Ctx) error { return service.ErrUserNotFound })
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Fatalf("status = %d, want 404", resp.StatusCode)
+	}
+	entries := logs.All()
+	if len(entries) != 1 || entries[0].ContextMap()["status"] != int64(fiber.StatusNotFound) {
+		t.Errorf("log entries = %v, want one with status 404", entries)
+	}
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/srinivasarynh/age_calculator/internal/i18n"
+)
+
+// Locale stores the language Accept-Language asks for on c.UserContext(),
+// where it overrides each user's own locale in the response. A header naming
+// no supported language is ignored, so users keep theirs.
+func Locale() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Vary(fiber.HeaderAcceptLanguage)
+		if tag, ok := i18n.ParseAcceptLanguage(c.Get(fiber.HeaderAcceptLanguage)); ok {
+			c.SetUserContext(i18n.WithTag(c.UserContext(), tag))
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/srinivasarynh/age_calculator/internal/metrics"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+)
+
+// validationFields are the field names ValidationFailures is labelled with;
+// any other field a request is rejected for, such as an unknown key in a
+// strict JSON body, is counted as "other" so the label set stays bounded.
+var validationFields = map[string]bool{
+	"name": true, "dob": true, "timezone": true, "locale": true, "id": true,
+	"version": true, "conflict": true, "format": true, "users": true,
+	"page": true, "page_size": true, "sort": true, "cursor": true,
+	"dob_from": true, "dob_to": true, "min_age": true, "max_age": true,
+	"name_fuzzy": true, "include": true, "include_age": true,
+	"include_total": true, "include_age_months": true, "count": true,
+	"limit": true, "bounds": true, "species": true,
+}
+
+// Metrics counts each request on m by the class of the status sent, and
+// those to a Deprecated route by its name as well. Like
+// Logger it resolves a returned error into its response first, so an error
+// is counted under the status the client gets. A validation failure counts
+// each rejected field under the route it was sent to.
+func Metrics(m *metrics.HTTP) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		m.InFlight.Inc()
+		defer m.InFlight.Dec()
+
+		if err := c.Next(); err != nil {
+			if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+		m.Requests.WithLabelValues(strconv.Itoa(c.Response().StatusCode()/100) + "xx").Inc()
+		if route, ok := c.Locals("deprecatedRoute").(string); ok {
+			m.Deprecated.WithLabelValues(route).Inc()
+		}
+		if fields := rejectedFields(c); len(fields) > 0 {
+			endpoint := c.Route().Method + " " + c.Route().Path
+			for _, field := range fields {
+				m.ValidationFailures.WithLabelValues(endpoint, validationField(field)).Inc()
+			}
+		}
+		return nil
+	}
+}
+
+// rejectedFields are the fields of the details of a 400 or 422 response,
+// whether in the v1 body or under /api/v2's "error".
+func rejectedFields(c *fiber.Ctx) []string {
+	status := c.Response().StatusCode()
+	if status != fiber.StatusBadRequest && status != fiber.StatusUnprocessableEntity {
+		return nil
+	}
+	var details []models.FieldError
+	if body, ok := structuredBody(c); ok {
+		details = body.Details
+	} else {
+		var v2 struct {
+			Error *StructuredError `json:"error"`
+		}
+		if json.Unmarshal(c.Response().Body(), &v2) != nil || v2.Error == nil {
+			return nil
+		}
+		details = v2.Error.Details
+	}
+	fields := make([]string, 0, len(details))
+	for _, detail := range details {
+		fields = append(fields, detail.Field)
+	}
+	return fields
+}
+
+// validationField is field as a ValidationFailures label: its name without
+// any index, as in include[0], and "other" unless it is a known one.
+func validationField(field string) string {
+	name, _, _ := strings.Cut(field, "[")
+	if validationFields[name] {
+		return name
+	}
+	return "other"
+}
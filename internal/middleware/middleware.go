@@ -1,13 +1,24 @@
 package middleware
 
 import (
+	"context"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/srinivasarynh/age_calculator/config"
+	"github.com/srinivasarynh/age_calculator/internal/cache"
+	"github.com/srinivasarynh/age_calculator/internal/models"
 	"go.uber.org/zap"
 )
 
+type requestIDKey struct{}
+
+// RequestID also stores the id on c.UserContext(), the context handlers hand
+// to the service, so code below the handler can read it with
+// RequestIDFromContext.
 func RequestID() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		requestID := c.Get("X-Request-ID")
@@ -15,24 +26,102 @@ func RequestID() fiber.Handler {
 			requestID = uuid.New().String()
 		}
 
-		c.Set("X-Request_ID", requestID)
+		c.Set("X-Request-ID", requestID)
 		c.Locals("requestID", requestID)
+		c.SetUserContext(context.WithValue(c.UserContext(), requestIDKey{}, requestID))
 
 		return c.Next()
 	}
 }
 
-func Logger(logger *zap.Logger) fiber.Handler {
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestCache gives c.UserContext() a memo of the users read during the
+// request, which a request-scoped repository consults; it is dropped with the
+// request.
+func RequestCache() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.SetUserContext(cache.WithRequestScope(c.UserContext()))
+		return c.Next()
+	}
+}
+
+// TrailingSlash strips trailing slashes from the path, for an app that routes
+// strictly. GET and HEAD are redirected to the canonical path with 308, query
+// and all; other methods are routed as if sent to it, so a client that
+// appended a slash does not have to send its body again.
+func TrailingSlash() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		path := c.Path()
+		if len(path) <= 1 || !strings.HasSuffix(path, "/") {
+			return c.Next()
+		}
+		canonical := "/" + strings.Trim(path, "/")
+		if c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead {
+			c.Path(canonical)
+			return c.Next()
+		}
+		if query := c.Request().URI().QueryString(); len(query) > 0 {
+			canonical += "?" + string(query)
+		}
+		return c.Redirect(canonical, fiber.StatusPermanentRedirect)
+	}
+}
+
+// Timeout puts a deadline on c.UserContext() so repository queries stop once
+// the request has run for REQUEST_TIMEOUT. fasthttp does not report client
+// disconnects to handlers, so this deadline is what bounds the work a gone
+// client leaves behind. Paths under skipPrefixes, such as streaming exports,
+// get no deadline; a zero timeout disables the middleware.
+func Timeout(cfg *config.Holder, skipPrefixes ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		timeout := cfg.Load().RequestTimeout
+		if timeout <= 0 {
+			return c.Next()
+		}
+		for _, prefix := range skipPrefixes {
+			if strings.HasPrefix(c.Path(), prefix) {
+				return c.Next()
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(c.UserContext(), timeout)
+		defer cancel()
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return c.Status(fiber.StatusGatewayTimeout).JSON(fiber.Map{
+				"error":      "Request timed out",
+				"request_id": RequestIDFromContext(ctx),
+			})
+		}
+		return err
+	}
+}
+
+func Logger(logger *zap.Logger, cfg *config.Holder) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		start := time.Now()
 		requestID := c.Locals("requestID").(string)
 
+		// Resolve a returned error into its response here so the logged
+		// status is the one the client gets.
 		err := c.Next()
+		if err != nil {
+			if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+			err = nil
+		}
 
 		duration := time.Since(start)
 		status := c.Response().StatusCode()
 
-		logger.Info("HTTP Request",
+		fields := []zap.Field{
 			zap.String("request_id", requestID),
 			zap.String("method", c.Method()),
 			zap.String("path", c.Path()),
@@ -40,31 +129,66 @@ func Logger(logger *zap.Logger) fiber.Handler {
 			zap.Duration("duration", duration),
 			zap.String("ip", c.IP()),
 			zap.String("user_agent", c.Get("User-Agent")),
-		)
+		}
+		if tenantID, ok := c.Locals("tenantID").(string); ok {
+			fields = append(fields, zap.String("tenant", tenantID))
+		}
+
+		if threshold := cfg.Load().SlowRequestThreshold; threshold > 0 && duration >= threshold {
+			logger.Warn("Slow HTTP Request", append(fields, zap.Duration("threshold", threshold))...)
+		} else {
+			logger.Info("HTTP Request", fields...)
+		}
 
 		return err
 	}
 }
 
-func ErrorHandler(c *fiber.Ctx, err error) error {
-	if c.Method() == fiber.MethodOptions {
-		return c.SendStatus(fiber.StatusOK)
+// CacheControl sends policy on successful responses to the routes it is
+// attached to. Errors are always no-store so a cached 404 or 500 cannot
+// outlive the condition that caused it. An empty policy is a no-op.
+func CacheControl(policy string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if policy == "" {
+			return c.Next()
+		}
+		err := c.Next()
+		if err != nil || c.Response().StatusCode() >= fiber.StatusBadRequest {
+			c.Set(fiber.HeaderCacheControl, "no-store")
+		} else {
+			c.Set(fiber.HeaderCacheControl, policy)
+		}
+		return err
 	}
-	code := fiber.StatusInternalServerError
-	message := "Internal Server Error"
+}
 
-	if e, ok := err.(*fiber.Error); ok {
-		code = e.Code
-		message = e.Message
-	}
+// ContentType answers 415 unless the request's media type is one of accepted.
+// Parameters such as charset are ignored. GET, HEAD, DELETE and OPTIONS carry
+// no body and pass through.
+func ContentType(accepted ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodDelete, fiber.MethodOptions:
+			return c.Next()
+		}
 
-	requestID := ""
-	if id := c.Locals("requestID"); id != nil {
-		requestID = id.(string)
-	}
+		mediaType, _, _ := strings.Cut(c.Get(fiber.HeaderContentType), ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+		for _, t := range accepted {
+			if mediaType == t {
+				return c.Next()
+			}
+		}
 
-	return c.Status(code).JSON(fiber.Map{
-		"error":      message,
-		"request_id": requestID,
-	})
+		return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{
+			"error": "Unsupported Content-Type",
+			"details": []models.FieldError{{
+				Field:   fiber.HeaderContentType,
+				Rule:    "oneof",
+				Param:   strings.Join(accepted, " "),
+				Message: "Content-Type must be one of: " + strings.Join(accepted, ", "),
+			}},
+			"request_id": RequestIDFromContext(c.UserContext()),
+		})
+	}
 }
@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/srinivasarynh/age_calculator/config"
+)
+
+func TestRequestIDReachesUserContext(t *testing.T) {
+	app := fiber.New()
+	app.Use(RequestID())
+	var seen string
+	app.Get("/", func(c *fiber.Ctx) error {
+		seen = RequestIDFromContext(c.UserContext())
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Request-ID", "abc-123")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seen != "abc-123" || resp.Header.Get("X-Request-ID") != "abc-123" {
+		t.Errorf("context id = %q, header = %q, want abc-123", seen, resp.Header.Get("X-Request-ID"))
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeout  time.Duration
+		path     string
+		deadline bool
+		expected int
+	}{
+		{name: "deadline exceeded", timeout: 20 * time.Millisecond, path: "/api/slow", deadline: true, expected: fiber.StatusGatewayTimeout},
+		{name: "disabled", timeout: 0, path: "/api/slow", expected: fiber.StatusOK},
+		{name: "skipped prefix", timeout: 20 * time.Millisecond, path: "/export/slow", expected: fiber.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.NewHolder(&config.Config{RequestTimeout: tt.timeout})
+			app := fiber.New()
+			app.Use(RequestID(), Timeout(cfg, "/export"))
+			// The handler waits on the request context the way a repository
+			// query would, and only falls through when no deadline is set.
+			app.Get("/*", func(c *fiber.Ctx) error {
+				ctx := c.UserContext()
+				if _, ok := ctx.Deadline(); ok != tt.deadline {
+					t.Errorf("deadline set = %v, want %v", ok, tt.deadline)
+				}
+				if !tt.deadline {
+					return c.SendStatus(fiber.StatusOK)
+				}
+				<-ctx.Done()
+				if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					t.Errorf("ctx.Err() = %v", ctx.Err())
+				}
+				return c.SendStatus(fiber.StatusInternalServerError)
+			})
+
+			resp, err := app.Test(httptest.NewRequest("GET", tt.path, nil), 1000)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.expected {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.expected)
+			}
+		})
+	}
+}
+
+func TestContentType(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		expected    int
+	}{
+		{name: "json", method: "POST", contentType: "application/json", expected: fiber.StatusOK},
+		{name: "json with charset", method: "PUT", contentType: "Application/JSON; charset=utf-8", expected: fiber.StatusOK},
+		{name: "form encoded", method: "POST", contentType: "application/x-www-form-urlencoded", expected: fiber.StatusUnsupportedMediaType},
+		{name: "text plain", method: "POST", contentType: "text/plain", expected: fiber.StatusUnsupportedMediaType},
+		{name: "missing", method: "PUT", expected: fiber.StatusUnsupportedMediaType},
+		{name: "json suffix is not json", method: "POST", contentType: "application/jsonp", expected: fiber.StatusUnsupportedMediaType},
+		{name: "get is exempt", method: "GET", expected: fiber.StatusOK},
+		{name: "head is exempt", method: "HEAD", expected: fiber.StatusOK},
+		{name: "delete is exempt", method: "DELETE", contentType: "text/plain", expected: fiber.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Use(RequestID(), ContentType(fiber.MIMEApplicationJSON))
+			app.All("/", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+			req := httptest.NewRequest(tt.method, "/", strings.NewReader("name=Alice"))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.expected {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.expected)
+			}
+			if tt.expected != fiber.StatusUnsupportedMediaType {
+				return
+			}
+
+			var body struct {
+				Error   string `json:"error"`
+				Details []struct {
+					Field   string `json:"field"`
+					Message string `json:"message"`
+				} `json:"details"`
+				RequestID string `json:"request_id"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Error != "Unsupported Content-Type" || body.RequestID == "" || len(body.Details) != 1 ||
+				body.Details[0].Message != "Content-Type must be one of: application/json" {
+				t.Errorf("body = %+v", body)
+			}
+		})
+	}
+}
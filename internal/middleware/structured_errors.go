@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+)
+
+// StructuredError is the body of a /api/v2 error response, under "error".
+// Code is always set: the registry's code, VALIDATION_FAILED for a 400 or 422
+// with rejected fields, or else the status text such as NOT_FOUND.
+// Suggestion is the path a ROUTE_NOT_FOUND request probably meant.
+type StructuredError struct {
+	Code       string              `json:"code"`
+	Message    string              `json:"message"`
+	Details    []models.FieldError `json:"details,omitempty"`
+	RequestID  string              `json:"request_id,omitempty"`
+	Suggestion string              `json:"suggestion,omitempty"`
+}
+
+// StructuredErrors answers failures of the routes below it with
+// {"error": StructuredError}, whether the handler returned the error or wrote
+// a v1 error body itself. Semantic errors are always 422. Other bodies pass
+// through unchanged.
+func StructuredErrors() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := c.Next(); err != nil {
+			status, body := structuredError(c, err, true)
+			return c.Status(status).JSON(fiber.Map{"error": body})
+		}
+		if body, ok := structuredBody(c); ok {
+			return c.Status(c.Response().StatusCode()).JSON(fiber.Map{"error": body})
+		}
+		return nil
+	}
+}
+
+// structuredBody is the v1 error body c's handler wrote, {"error": message},
+// as a StructuredError. It reports false for any other response.
+func structuredBody(c *fiber.Ctx) (StructuredError, bool) {
+	status := c.Response().StatusCode()
+	if status < fiber.StatusBadRequest {
+		return StructuredError{}, false
+	}
+	var v1 struct {
+		Error     *string             `json:"error"`
+		Details   []models.FieldError `json:"details"`
+		RequestID string              `json:"request_id"`
+	}
+	if json.Unmarshal(c.Response().Body(), &v1) != nil || v1.Error == nil {
+		return StructuredError{}, false
+	}
+	body := StructuredError{Code: statusCode(status), Message: *v1.Error, Details: v1.Details, RequestID: v1.RequestID}
+	if len(v1.Details) > 0 && (status == fiber.StatusBadRequest || status == fiber.StatusUnprocessableEntity) {
+		body.Code = "VALIDATION_FAILED"
+	}
+	return body, true
+}
+
+// structuredError answers semantic errors with 422 when strictStatus is
+// set, and with their 400 otherwise.
+func structuredError(c *fiber.Ctx, err error, strictStatus bool) (int, StructuredError) {
+	if status, body, ok := describe(err, strictStatus); ok {
+		return status, body
+	}
+
+	status := fiber.StatusInternalServerError
+	message := "Internal Server Error"
+	if e, ok := err.(*fiber.Error); ok {
+		status = e.Code
+		message = e.Message
+	}
+	requestID, _ := c.Locals("requestID").(string)
+	return status, StructuredError{Code: statusCode(status), Message: message, RequestID: requestID}
+}
+
+// DescribeError is err as StructuredErrors would send it, for responses that
+// carry errors other than as an HTTP status, such as GraphQL's. An error
+// without a registry entry or a Fail message is INTERNAL_SERVER_ERROR.
+func DescribeError(err error) StructuredError {
+	if _, body, ok := describe(err, true); ok {
+		return body
+	}
+	return StructuredError{Code: statusCode(fiber.StatusInternalServerError), Message: "Internal Server Error"}
+}
+
+func describe(err error, strictStatus bool) (int, StructuredError, bool) {
+	if api, ok := lookupError(err); ok {
+		status := api.status
+		if api.semantic && strictStatus {
+			status = fiber.StatusUnprocessableEntity
+		}
+		return status, StructuredError{Code: api.code, Message: api.message, Details: api.details}, true
+	}
+	var f *failure
+	if errors.As(err, &f) {
+		return fiber.StatusInternalServerError, StructuredError{Code: statusCode(fiber.StatusInternalServerError), Message: f.message}, true
+	}
+	return 0, StructuredError{}, false
+}
+
+// statusCode spells status's text as a code: 404 is NOT_FOUND.
+func statusCode(status int) string {
+	return strings.ToUpper(strings.ReplaceAll(utils.StatusMessage(status), " ", "_"))
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/srinivasarynh/age_calculator/config"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+)
+
+const HeaderTenantID = "X-Tenant-ID"
+
+// Tenant resolves the X-Tenant-ID header against TENANTS and stores the
+// tenant on c.UserContext() for the repositories to scope by. With TENANTS
+// empty the header is optional and only the default tenant exists, so
+// single-tenant clients need not send it. Unknown tenants get 400 without
+// the list of valid ones.
+func Tenant(cfg *config.Holder) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		tenants := cfg.Load().Tenants
+		// The header's bytes are reused by the next request, while the id
+		// outlives this one in stored rows and background refreshes.
+		id := utils.CopyString(c.Get(HeaderTenantID))
+
+		switch {
+		case len(tenants) == 0 && (id == "" || id == tenant.Default):
+			id = tenant.Default
+		case id == "":
+			return tenantError(c, "Missing tenant", models.FieldError{
+				Field:   HeaderTenantID,
+				Rule:    "required",
+				Message: HeaderTenantID + " header is required",
+			})
+		case !slices.Contains(tenants, id):
+			return tenantError(c, "Unknown tenant", models.FieldError{
+				Field:   HeaderTenantID,
+				Rule:    "oneof",
+				Message: HeaderTenantID + " is not a known tenant",
+			})
+		}
+
+		c.Locals("tenantID", id)
+		c.SetUserContext(tenant.WithID(c.UserContext(), id))
+		return c.Next()
+	}
+}
+
+func tenantError(c *fiber.Ctx, msg string, detail models.FieldError) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error":      msg,
+		"details":    []models.FieldError{detail},
+		"request_id": RequestIDFromContext(c.UserContext()),
+	})
+}
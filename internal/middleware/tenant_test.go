@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/srinivasarynh/age_calculator/config"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+)
+
+func TestTenant(t *testing.T) {
+	tests := []struct {
+		name     string
+		tenants  []string
+		header   string
+		expected int
+		tenant   string
+		rule     string
+	}{
+		{name: "single tenant without header", expected: fiber.StatusOK, tenant: tenant.Default},
+		{name: "single tenant naming the default", header: "default", expected: fiber.StatusOK, tenant: tenant.Default},
+		{name: "single tenant naming another", header: "acme", expected: fiber.StatusBadRequest, rule: "oneof"},
+		{name: "configured tenant", tenants: []string{"acme", "globex"}, header: "globex", expected: fiber.StatusOK, tenant: "globex"},
+		{name: "missing header", tenants: []string{"acme"}, expected: fiber.StatusBadRequest, rule: "required"},
+		{name: "unknown tenant", tenants: []string{"acme"}, header: "initech", expected: fiber.StatusBadRequest, rule: "oneof"},
+		{name: "default not listed", tenants: []string{"acme"}, header: "default", expected: fiber.StatusBadRequest, rule: "oneof"},
+		{name: "match is case-sensitive", tenants: []string{"acme"}, header: "ACME", expected: fiber.StatusBadRequest, rule: "oneof"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.NewHolder(&config.Config{Tenants: tt.tenants})
+			app := fiber.New()
+			app.Use(RequestID())
+			app.Get("/", Tenant(cfg), func(c *fiber.Ctx) error {
+				return c.SendString(tenant.FromContext(c.UserContext()))
+			})
+
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.header != "" {
+				req.Header.Set(HeaderTenantID, tt.header)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.expected {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.expected)
+			}
+
+			if tt.expected == fiber.StatusOK {
+				var body [64]byte
+				n, _ := resp.Body.Read(body[:])
+				if got := string(body[:n]); got != tt.tenant {
+					t.Errorf("tenant = %q, want %q", got, tt.tenant)
+				}
+				return
+			}
+			var body struct {
+				Error     string              `json:"error"`
+				Details   []models.FieldError `json:"details"`
+				RequestID string              `json:"request_id"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if len(body.Details) != 1 || body.Details[0].Field != HeaderTenantID || body.Details[0].Rule != tt.rule || body.RequestID == "" {
+				t.Errorf("body = %+v, want one %s detail on %s and a request id", body, tt.rule, HeaderTenantID)
+			}
+		})
+	}
+}
+
+func TestTenantFollowsReload(t *testing.T) {
+	t.Setenv("TENANTS", "acme")
+	loaded, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.NewHolder(loaded)
+	app := fiber.New()
+	app.Get("/", Tenant(cfg), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	request := func() int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(HeaderTenantID, "globex")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+
+	if status := request(); status != fiber.StatusBadRequest {
+		t.Fatalf("before reload status = %d, want 400", status)
+	}
+	t.Setenv("TENANTS", "acme,globex")
+	if _, _, err := cfg.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if status := request(); status != fiber.StatusOK {
+		t.Errorf("after adding the tenant status = %d, want 200", status)
+	}
+}
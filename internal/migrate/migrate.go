@@ -0,0 +1,386 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrDirty            = errors.New("migrate: database is dirty")
+	ErrChecksumMismatch = errors.New("migrate: applied migration differs from its file")
+	ErrUnknownVersion   = errors.New("migrate: applied migration has no file")
+)
+
+// lockID keys the advisory lock that keeps two migrators from running at
+// once against the same database.
+const lockID = 7_140_365
+
+// Status describes one version, either embedded, recorded in
+// schema_migrations, or both.
+type Status struct {
+	Version  int64
+	Name     string
+	Checksum string
+	Applied  bool
+	Dirty    bool
+	// Recorded is the checksum stored when the migration was applied.
+	Recorded  string
+	AppliedAt time.Time
+	// Missing is set for a recorded version with no migration file.
+	Missing bool
+}
+
+func (s Status) Mismatch() bool {
+	return s.Applied && !s.Missing && s.Recorded != s.Checksum
+}
+
+// Step is SQL that Up runs after the numbered migrations every time. It is
+// chosen by configuration rather than recorded in schema_migrations, so it
+// must be safe to repeat. Check, when set, runs first in the same
+// transaction; its error aborts the step with the schema untouched.
+type Step struct {
+	Name  string
+	Check func(ctx context.Context, tx *sql.Tx) error
+	SQL   string
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	steps      []Step
+}
+
+func New(db *sql.DB, migrations []Migration, steps ...Step) *Migrator {
+	return &Migrator{db: db, migrations: migrations, steps: steps}
+}
+
+type record struct {
+	dirty     bool
+	checksum  string
+	appliedAt time.Time
+}
+
+// Status lists every embedded migration followed by any recorded version
+// that has no file.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		records, err := m.records(ctx, conn)
+		if err != nil {
+			return err
+		}
+		statuses = m.statuses(records)
+		return nil
+	})
+	return statuses, err
+}
+
+func (m *Migrator) statuses(records map[int64]record) []Status {
+	statuses := make([]Status, 0, len(m.migrations))
+	embedded := map[int64]bool{}
+	for _, mig := range m.migrations {
+		embedded[mig.Version] = true
+		s := Status{Version: mig.Version, Name: mig.Name, Checksum: mig.Checksum}
+		if r, ok := records[mig.Version]; ok {
+			s.Applied, s.Dirty, s.Recorded, s.AppliedAt = true, r.dirty, r.checksum, r.appliedAt
+		}
+		statuses = append(statuses, s)
+	}
+	for _, version := range sortedVersions(records) {
+		if !embedded[version] {
+			r := records[version]
+			statuses = append(statuses, Status{
+				Version: version, Applied: true, Dirty: r.dirty, Recorded: r.checksum, AppliedAt: r.appliedAt, Missing: true,
+			})
+		}
+	}
+	return statuses
+}
+
+// Up applies every pending migration in version order, then runs the steps,
+// and returns the versions it applied. It refuses to run while any recorded
+// migration is dirty, edited or unknown.
+func (m *Migrator) Up(ctx context.Context) ([]int64, error) {
+	var applied []int64
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		records, err := m.records(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := check(m.statuses(records)); err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := records[mig.Version]; ok {
+				continue
+			}
+			if _, err := conn.ExecContext(ctx, `INSERT INTO schema_migrations (version, dirty, checksum) VALUES ($1, true, $2)`, mig.Version, mig.Checksum); err != nil {
+				return err
+			}
+			err := inTx(ctx, conn, mig.Up, `UPDATE schema_migrations SET dirty = false, applied_at = CURRENT_TIMESTAMP WHERE version = $1`, mig.Version)
+			if err != nil {
+				return fmt.Errorf("migrate: applying %d_%s left the database dirty: %w", mig.Version, mig.Name, err)
+			}
+			applied = append(applied, mig.Version)
+		}
+		for _, step := range m.steps {
+			if err := runStep(ctx, conn, step); err != nil {
+				return fmt.Errorf("migrate: step %s: %w", step.Name, err)
+			}
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the n most recently applied migrations, newest first, and
+// returns the versions it reverted.
+func (m *Migrator) Down(ctx context.Context, n int) ([]int64, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("migrate: down needs a positive count, got %d", n)
+	}
+	var reverted []int64
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		records, err := m.records(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := check(m.statuses(records)); err != nil {
+			return err
+		}
+
+		versions := sortedVersions(records)
+		for i := len(versions) - 1; i >= 0 && len(reverted) < n; i-- {
+			mig, _ := m.find(versions[i])
+			if _, err := conn.ExecContext(ctx, `UPDATE schema_migrations SET dirty = true WHERE version = $1`, mig.Version); err != nil {
+				return err
+			}
+			if err := inTx(ctx, conn, mig.Down, `DELETE FROM schema_migrations WHERE version = $1`, mig.Version); err != nil {
+				return fmt.Errorf("migrate: reverting %d_%s left the database dirty: %w", mig.Version, mig.Name, err)
+			}
+			reverted = append(reverted, mig.Version)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Force records the schema as exactly version without running any SQL:
+// later versions are forgotten, every embedded version up to it is marked
+// applied and clean with its current checksum. Version 0 clears the table.
+// It is the way out of a dirty state after the schema was fixed by hand.
+func (m *Migrator) Force(ctx context.Context, version int64) error {
+	if _, ok := m.find(version); !ok && version != 0 {
+		return fmt.Errorf("migrate: no migration file for version %d", version)
+	}
+	return m.locked(ctx, func(conn *sql.Conn) error {
+		if _, err := m.records(ctx, conn); err != nil {
+			return err
+		}
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version > $1`, version); err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if mig.Version > version {
+				break
+			}
+			_, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, dirty, checksum) VALUES ($1, false, $2)
+				ON CONFLICT (version) DO UPDATE SET dirty = false, checksum = EXCLUDED.checksum`, mig.Version, mig.Checksum)
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Commit()
+	})
+}
+
+func (m *Migrator) find(version int64) (Migration, bool) {
+	for _, mig := range m.migrations {
+		if mig.Version == version {
+			return mig, true
+		}
+	}
+	return Migration{}, false
+}
+
+// check collects every dirty, edited or unknown version into one error.
+func check(statuses []Status) error {
+	var problems []string
+	var first error
+	for _, s := range statuses {
+		var err error
+		switch {
+		case s.Dirty:
+			err = ErrDirty
+			problems = append(problems, fmt.Sprintf("version %d is dirty; fix the schema by hand, then run migrate force", s.Version))
+		case s.Missing:
+			err = ErrUnknownVersion
+			problems = append(problems, fmt.Sprintf("version %d is applied but has no migration file", s.Version))
+		case s.Mismatch():
+			err = ErrChecksumMismatch
+			problems = append(problems, fmt.Sprintf("version %d was applied with checksum %s but the file has %s", s.Version, s.Recorded, s.Checksum))
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	if first == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", first, strings.Join(problems, "; "))
+}
+
+// locked runs fn on one connection holding the migration advisory lock.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID)
+
+	return fn(conn)
+}
+
+// records reads schema_migrations, creating it if needed. A table written by
+// the golang-migrate CLI holds a single (version, dirty) row; it gains the
+// checksum and applied_at columns and a row for every earlier version, and
+// rows without a checksum take the current file's.
+func (m *Migrator) records(ctx context.Context, conn *sql.Conn) (map[int64]record, error) {
+	for _, stmt := range []string{
+		`CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)`,
+		`ALTER TABLE schema_migrations ADD COLUMN IF NOT EXISTS checksum TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE schema_migrations ADD COLUMN IF NOT EXISTS applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP`,
+	} {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return nil, err
+		}
+	}
+
+	records, err := readRecords(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.adopt(ctx, conn, records); err != nil {
+		return nil, err
+	}
+	return readRecords(ctx, conn)
+}
+
+func (m *Migrator) adopt(ctx context.Context, conn *sql.Conn, records map[int64]record) error {
+	var legacy []int64
+	for version, r := range records {
+		if r.checksum == "" {
+			legacy = append(legacy, version)
+		}
+	}
+	if len(legacy) == 0 {
+		return nil
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if len(records) == 1 && !records[legacy[0]].dirty {
+		for _, mig := range m.migrations {
+			if mig.Version >= legacy[0] {
+				break
+			}
+			if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, dirty, checksum) VALUES ($1, false, $2)`, mig.Version, mig.Checksum); err != nil {
+				return err
+			}
+		}
+	}
+	for _, version := range legacy {
+		if mig, ok := m.find(version); ok {
+			if _, err := tx.ExecContext(ctx, `UPDATE schema_migrations SET checksum = $1 WHERE version = $2`, mig.Checksum, version); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+func readRecords(ctx context.Context, conn *sql.Conn) (map[int64]record, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, dirty, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := map[int64]record{}
+	for rows.Next() {
+		var version int64
+		var r record
+		if err := rows.Scan(&version, &r.dirty, &r.checksum, &r.appliedAt); err != nil {
+			return nil, err
+		}
+		records[version] = r
+	}
+	return records, rows.Err()
+}
+
+// inTx runs script and the bookkeeping statement in one transaction, so a
+// failed script leaves the row as it was before: dirty.
+func inTx(ctx context.Context, conn *sql.Conn, script, bookkeeping string, version int64) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if strings.TrimSpace(script) != "" {
+		if _, err := tx.ExecContext(ctx, script); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, bookkeeping, version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func runStep(ctx context.Context, conn *sql.Conn, step Step) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if step.Check != nil {
+		if err := step.Check(ctx, tx); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, step.SQL); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func sortedVersions(records map[int64]record) []int64 {
+	versions := make([]int64, 0, len(records))
+	for version := range records {
+		versions = append(versions, version)
+	}
+	slices.Sort(versions)
+	return versions
+}
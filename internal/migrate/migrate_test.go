@@ -0,0 +1,75 @@
+package migrate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestStatusesAndCheck(t *testing.T) {
+	m := New(nil, []Migration{
+		{Version: 1, Name: "one", Checksum: "aaa"},
+		{Version: 2, Name: "two", Checksum: "bbb"},
+		{Version: 3, Name: "three", Checksum: "ccc"},
+	})
+
+	tests := []struct {
+		name     string
+		records  map[int64]record
+		pending  []int64
+		wantErr  error
+		mentions []string
+	}{
+		{
+			name:    "clean",
+			records: map[int64]record{1: {checksum: "aaa"}},
+			pending: []int64{2, 3},
+		},
+		{
+			name:     "dirty",
+			records:  map[int64]record{1: {checksum: "aaa"}, 2: {checksum: "bbb", dirty: true}},
+			pending:  []int64{3},
+			wantErr:  ErrDirty,
+			mentions: []string{"version 2 is dirty"},
+		},
+		{
+			name:     "edited file",
+			records:  map[int64]record{1: {checksum: "old"}},
+			pending:  []int64{2, 3},
+			wantErr:  ErrChecksumMismatch,
+			mentions: []string{"version 1 was applied with checksum old but the file has aaa"},
+		},
+		{
+			name:     "every problem is reported",
+			records:  map[int64]record{1: {checksum: "old"}, 2: {checksum: "bbb", dirty: true}, 9: {checksum: "zzz"}},
+			pending:  []int64{3},
+			wantErr:  ErrChecksumMismatch,
+			mentions: []string{"version 1 was applied", "version 2 is dirty", "version 9 is applied but has no migration file"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statuses := m.statuses(tt.records)
+			var pending []int64
+			for _, s := range statuses {
+				if !s.Applied {
+					pending = append(pending, s.Version)
+				}
+			}
+			if len(pending) != len(tt.pending) || (len(pending) > 0 && pending[0] != tt.pending[0]) {
+				t.Errorf("pending = %v, want %v", pending, tt.pending)
+			}
+
+			err := check(statuses)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("check() = %v, want %v", err, tt.wantErr)
+			}
+			for _, want := range tt.mentions {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
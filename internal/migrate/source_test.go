@@ -0,0 +1,87 @@
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/srinivasarynh/age_calculator/db"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/000002_add_index.up.sql":      {Data: []byte("CREATE INDEX i ON t(c);")},
+		"m/000002_add_index.down.sql":    {Data: []byte("DROP INDEX i;")},
+		"m/000001_create_table.up.sql":   {Data: []byte("CREATE TABLE t (c INT);")},
+		"m/000010_no_down.up.sql":        {Data: []byte("SELECT 1;")},
+		"m/README.md":                    {Data: []byte("not a migration")},
+		"m/000001_create_table.down.sql": {Data: []byte("DROP TABLE t;")},
+	}
+
+	migrations, err := Load(fsys, "m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var versions []int64
+	for _, m := range migrations {
+		versions = append(versions, m.Version)
+	}
+	if len(versions) != 3 || versions[0] != 1 || versions[1] != 2 || versions[2] != 10 {
+		t.Fatalf("versions = %v, want [1 2 10]", versions)
+	}
+
+	first := migrations[0]
+	sum := sha256.Sum256([]byte("CREATE TABLE t (c INT);"))
+	if first.Name != "create_table" || first.Down != "DROP TABLE t;" || first.Checksum != hex.EncodeToString(sum[:]) {
+		t.Errorf("first migration = %+v", first)
+	}
+	if migrations[2].Down != "" {
+		t.Errorf("missing down file should load as empty, got %q", migrations[2].Down)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+		want  string
+	}{
+		{
+			name:  "down without up",
+			files: fstest.MapFS{"m/000001_a.down.sql": {}},
+			want:  "version 1 has no up file",
+		},
+		{
+			name:  "two names for one version",
+			files: fstest.MapFS{"m/000001_a.up.sql": {}, "m/000001_b.down.sql": {}},
+			want:  `named both "a" and "b"`,
+		},
+		{
+			name:  "unrecognised sql file",
+			files: fstest.MapFS{"m/seed.sql": {}},
+			want:  "seed.sql does not match",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(tt.files, "m")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmbeddedMigrationsLoad(t *testing.T) {
+	migrations, err := Load(db.Migrations, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range migrations {
+		if m.Version != int64(i+1) {
+			t.Errorf("migration %d has version %d; versions should run 1..n without gaps", i, m.Version)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Backup is the whole dataset of every tenant as GET /admin/backup writes it
+// and POST /admin/restore reads it. SchemaVersion is the last migration of
+// the server that wrote it.
+type Backup struct {
+	SchemaVersion         int64                `json:"schema_version"`
+	ExportedAt            time.Time            `json:"exported_at"`
+	Users                 []BackupUser         `json:"users"`
+	BirthdayNotifications []BackupNotification `json:"birthday_notifications"`
+	ShareTokens           []BackupShare        `json:"share_tokens"`
+}
+
+type BackupUser struct {
+	ID       int32  `json:"id"`
+	TenantID string `json:"tenant_id"`
+	Name     string `json:"name"`
+	// DOB is YYYY-MM-DD.
+	DOB          string     `json:"dob"`
+	Timezone     string     `json:"timezone,omitempty"`
+	Locale       string     `json:"locale,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	AnonymizedAt *time.Time `json:"anonymized_at,omitempty"`
+}
+
+type BackupNotification struct {
+	UserID     int32  `json:"user_id"`
+	Year       int    `json:"year"`
+	NotifiedOn string `json:"notified_on"`
+}
+
+// BackupShare keeps the token's hash, so links handed out keep working once
+// restored.
+type BackupShare struct {
+	ID        int64      `json:"id"`
+	TenantID  string     `json:"tenant_id"`
+	UserID    int32      `json:"user_id"`
+	TokenHash []byte     `json:"token_hash"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// RestoreReport counts the rows a restore loaded.
+type RestoreReport struct {
+	SchemaVersion         int64 `json:"schema_version"`
+	Users                 int   `json:"users"`
+	BirthdayNotifications int   `json:"birthday_notifications"`
+	ShareTokens           int   `json:"share_tokens"`
+}
@@ -0,0 +1,149 @@
+package models
+
+import (
+	"encoding/json"
+	"net/url"
+	"time"
+)
+
+type JobState string
+
+const (
+	JobPending JobState = "pending"
+	JobRunning JobState = "running"
+	JobDone    JobState = "done"
+	JobFailed  JobState = "failed"
+)
+
+// Job kinds.
+const (
+	JobUserImport = "user_import"
+	JobUserExport = "user_export"
+)
+
+// Job is one background job. Payload is only loaded when a worker claims the
+// job and is dropped once it finishes; Report is set once it has.
+type Job struct {
+	ID            int64
+	TenantID      string
+	Kind          string
+	State         JobState
+	Payload       []byte
+	RowsProcessed int
+	Report        json.RawMessage
+	Error         string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	FinishedAt    *time.Time
+}
+
+type JobResponse struct {
+	ID            int64           `json:"id"`
+	Kind          string          `json:"kind"`
+	State         JobState        `json:"state"`
+	RowsProcessed int             `json:"rows_processed"`
+	Error         string          `json:"error,omitempty"`
+	Report        json.RawMessage `json:"report,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+	FinishedAt    *time.Time      `json:"finished_at,omitempty"`
+}
+
+// MaxImportErrors bounds the rows listed in ImportReport.Errors; Failed still
+// counts every rejected row.
+const MaxImportErrors = 1000
+
+// Import conflict strategies, for a row naming an existing user: the same
+// name in any case and the same dob.
+const (
+	ImportConflictSkip   = "skip"
+	ImportConflictUpdate = "update"
+	ImportConflictFail   = "fail"
+)
+
+// ImportOptions tune an import. Without a Conflict strategy rows are not
+// matched against existing users, and each valid row creates one. An Atomic
+// import writes the whole file in one transaction, which any failed row
+// rolls back.
+type ImportOptions struct {
+	Conflict string `json:"conflict,omitempty" validate:"omitempty,oneof=skip update fail"`
+	Atomic   bool   `json:"atomic,omitempty"`
+}
+
+// ImportJobPayload is what an asynchronous import stores: the upload and the
+// options it was submitted with.
+type ImportJobPayload struct {
+	Options ImportOptions `json:"options"`
+	File    []byte        `json:"file"`
+}
+
+// ImportReport summarizes a CSV import. Rows counts the data rows processed,
+// which for an interrupted import is fewer than the file holds. A RolledBack
+// import wrote nothing, so Created, Updated and Skipped are 0.
+type ImportReport struct {
+	Rows       int              `json:"rows"`
+	Created    int              `json:"created"`
+	Updated    int              `json:"updated"`
+	Skipped    int              `json:"skipped"`
+	Failed     int              `json:"failed"`
+	RolledBack bool             `json:"rolled_back,omitempty"`
+	Errors     []ImportRowError `json:"errors"`
+}
+
+// ImportRowError names a rejected row by its line in the file, counting the
+// header as line 1.
+type ImportRowError struct {
+	Row     int          `json:"row"`
+	Details []FieldError `json:"details"`
+}
+
+// Export formats. An NDJSON export holds one UserResponse per line, a VCF
+// export one vCard per user.
+const (
+	ExportCSV    = "csv"
+	ExportNDJSON = "ndjson"
+	ExportVCF    = "vcf"
+	ExportXLSX   = "xlsx"
+)
+
+// vCard versions. 4.0 is the default; 3.0 is for address books that predate it.
+const (
+	VCard3 = 3
+	VCard4 = 4
+)
+
+// VCardQuery picks the vCard version of a single user's card.
+type VCardQuery struct {
+	Version int `query:"version" validate:"omitempty,oneof=3 4"`
+}
+
+// ExportUsersRequest selects the users to export; the filters mean what the
+// query parameters of the same name mean when listing. It is the body of an
+// export job and the query of a direct export alike. Version picks the vCard
+// version of a VCF export.
+type ExportUsersRequest struct {
+	Format  string `json:"format" query:"format" validate:"omitempty,oneof=csv ndjson vcf xlsx"`
+	Name    string `json:"name" query:"name" validate:"omitempty,max=100"`
+	Version int    `json:"version,omitempty" query:"version" validate:"omitempty,oneof=3 4"`
+}
+
+// Filters returns the filters r applies, as the query parameters that set
+// them.
+func (r *ExportUsersRequest) Filters() url.Values {
+	filters := url.Values{}
+	if r.Name != "" {
+		filters.Set("name", r.Name)
+	}
+	return filters
+}
+
+// ExportReport summarizes an export. ContentType and Filename describe the
+// file a finished export job offers for download; Filters is the query
+// string of the filters it applied.
+type ExportReport struct {
+	Rows        int    `json:"rows"`
+	Format      string `json:"format"`
+	ContentType string `json:"content_type"`
+	Filename    string `json:"filename,omitempty"`
+	Filters     string `json:"filters,omitempty"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxEvent is an event waiting in the events outbox to be delivered, or
+// one that was. Payload is the event's data; CreatedAt is when it happened.
+// An event that failed every attempt is dead-lettered, at DeadAt, and stays
+// until it is replayed.
+type OutboxEvent struct {
+	ID            int64           `json:"id"`
+	TenantID      string          `json:"tenant_id"`
+	Type          string          `json:"type"`
+	Payload       json.RawMessage `json:"payload"`
+	CreatedAt     time.Time       `json:"created_at"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	LastError     string          `json:"last_error,omitempty"`
+	SentAt        *time.Time      `json:"sent_at,omitempty"`
+	DeadAt        *time.Time      `json:"dead_at,omitempty"`
+}
+
+// DeadEventsResponse lists dead-lettered events, oldest first.
+type DeadEventsResponse struct {
+	Events []OutboxEvent `json:"events"`
+}
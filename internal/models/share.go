@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// ShareToken is a link to one user's public profile. Only the SHA-256 of the
+// token is stored, so a token cannot be recovered once it has been returned.
+type ShareToken struct {
+	ID        int64
+	TenantID  string
+	UserID    int32
+	TokenHash []byte
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+}
+
+type ShareResponse struct {
+	ID     int64 `json:"id"`
+	UserID int32 `json:"user_id"`
+	// Token and URL are only returned by the request that creates the share.
+	Token     string    `json:"token,omitempty"`
+	URL       string    `json:"url,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ShareListResponse lists a user's shares that are neither revoked nor
+// expired, oldest first.
+type ShareListResponse struct {
+	Shares []ShareResponse `json:"shares"`
+}
+
+// SharedProfile is what a share token reveals. It has no DOB: the age and
+// next birthday say less, and that is all an unauthenticated reader gets.
+type SharedProfile struct {
+	Name         string `json:"name"`
+	Age          int    `json:"age"`
+	NextBirthday string `json:"next_birthday"`
+}
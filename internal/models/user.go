@@ -1,59 +1,594 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
 
 type User struct {
-	ID        int32
-	Name      string
-	DOB       time.Time
+	ID       int32
+	TenantID string
+	Name     string
+	DOB      time.Time
+	// Timezone is an IANA zone name; empty means the server's default.
+	Timezone string
+	// Locale is a BCP 47 tag for display dates; empty means English.
+	Locale    string
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	// AnonymizedAt is set once the user's personal data has been scrubbed.
+	AnonymizedAt *time.Time
 }
 
 type CreateUserRequest struct {
-	Name string `json:"name" validate:"required,min=2,max=100"`
-	DOB  string `json:"dob" validate:"required,datetime=2006-01-02"`
+	Name     string `json:"name" validate:"required,min=2,max=100,name_policy"`
+	DOB      string `json:"dob" validate:"required,datetime=2006-01-02"`
+	Timezone string `json:"timezone,omitempty" validate:"omitempty,timezone"`
+	Locale   string `json:"locale,omitempty" validate:"omitempty,bcp47_language_tag"`
 }
 
+// UpdateUserRequest replaces the user, so an omitted timezone reverts it to
+// the server's default and an omitted locale clears it.
 type UpdateUserRequest struct {
-	Name string `json:"name" validate:"required,min=2,max=100"`
-	DOB  string `json:"dob" validate:"required,datetime=2006-01-02"`
+	Name     string `json:"name" validate:"required,min=2,max=100,name_policy"`
+	DOB      string `json:"dob" validate:"required,datetime=2006-01-02"`
+	Timezone string `json:"timezone,omitempty" validate:"omitempty,timezone"`
+	Locale   string `json:"locale,omitempty" validate:"omitempty,bcp47_language_tag"`
+}
+
+// UserPatch is an RFC 7386 merge patch. Each field keeps the raw JSON the
+// client sent: nil when the key was absent, null when it was cleared.
+type UserPatch struct {
+	Name     json.RawMessage `json:"name"`
+	DOB      json.RawMessage `json:"dob"`
+	Timezone json.RawMessage `json:"timezone"`
+	Locale   json.RawMessage `json:"locale"`
+}
+
+// Apply merges p onto req. A cleared field becomes empty, so validation of the
+// merged request rejects clearing a required one.
+func (p UserPatch) Apply(req *UpdateUserRequest) error {
+	fields := []struct {
+		raw json.RawMessage
+		dst *string
+	}{
+		{p.Name, &req.Name},
+		{p.DOB, &req.DOB},
+		{p.Timezone, &req.Timezone},
+		{p.Locale, &req.Locale},
+	}
+	for _, f := range fields {
+		switch {
+		case f.raw == nil:
+		case string(f.raw) == "null":
+			*f.dst = ""
+		default:
+			if err := json.Unmarshal(f.raw, f.dst); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// MaxBatchSize bounds the items in one batch update.
+const MaxBatchSize = 200
+
+// BatchUpdateItem is one partial update in a batch. A nil field is left
+// unchanged. Invalid carries the problems the handler found decoding or
+// validating the item; such an item is reported, never applied.
+type BatchUpdateItem struct {
+	ID      int32        `json:"id" validate:"required,gt=0"`
+	Name    *string      `json:"name" validate:"omitnil,min=2,max=100,name_policy"`
+	DOB     *string      `json:"dob" validate:"omitnil,datetime=2006-01-02"`
+	Invalid []FieldError `json:"-"`
+}
+
+// Outcomes of one batch item.
+const (
+	BatchUpdated       = "updated"
+	BatchNotFound      = "not_found"
+	BatchAnonymized    = "anonymized"
+	BatchDuplicateName = "duplicate_name"
+	BatchInvalid       = "invalid"
+	BatchNotApplied    = "not_applied"
+)
+
+type BatchUpdateResult struct {
+	ID      int32         `json:"id"`
+	Status  string        `json:"status"`
+	User    *UserResponse `json:"user,omitempty"`
+	Error   string        `json:"error,omitempty"`
+	Details []FieldError  `json:"details,omitempty"`
+}
+
+// BatchUpdateResponse lists one result per item, in request order. In an
+// atomic batch with any failure nothing is applied and Updated is 0.
+type BatchUpdateResponse struct {
+	Results []BatchUpdateResult `json:"results"`
+	Updated int                 `json:"updated"`
+	Failed  int                 `json:"failed"`
 }
 
 type UserResponse struct {
 	ID   int32  `json:"id"`
 	Name string `json:"name"`
-	DOB  string `json:"dob"`
-	Age  *int   `json:"age,omitempty"`
+	// DOB is empty, and left out, in lists when LIST_HIDES_DOB is set.
+	DOB string `json:"dob,omitempty"`
+	Age *int   `json:"age,omitempty"`
+	// AgeMonths and AgeWeeks are the whole calendar months and weeks since
+	// the DOB, given for infants or when the request asks.
+	AgeMonths *int `json:"age_months,omitempty"`
+	AgeWeeks  *int `json:"age_weeks,omitempty"`
+	// AgeValid is only ever set to false: the DOB is in the future and Age was clamped to 0.
+	AgeValid *bool  `json:"age_valid,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+	Locale   string `json:"locale,omitempty"`
+	// IsBirthday is only ever set to true, on the user's birthday in their
+	// own timezone.
+	IsBirthday bool `json:"is_birthday,omitempty"`
+	// BornOnWeekday and NextBirthdayDisplay are set only when a locale
+	// applies, the request's Accept-Language or else the user's own, and
+	// are written in it.
+	BornOnWeekday       string `json:"born_on_weekday,omitempty"`
+	NextBirthdayDisplay string `json:"next_birthday_display,omitempty"`
+	// HalfBirthday and NextRoundBirthday are set only when the request
+	// asks for include=milestones.
+	HalfBirthday      string         `json:"half_birthday,omitempty"`
+	NextRoundBirthday *RoundBirthday `json:"next_round_birthday,omitempty"`
+	// BirthdayYearProgress, for include=progress, is how far the user is
+	// from their last birthday to the next, from 0 to 1.
+	BirthdayYearProgress *float64 `json:"birthday_year_progress,omitempty"`
+	// AccessCount, for include=access_count, is how often the user has been
+	// read, as of the access tracker's last flush.
+	AccessCount *AccessCount `json:"access_count,omitempty"`
+	CreatedAt   time.Time    `json:"created_at,omitzero"`
+	UpdatedAt   time.Time    `json:"updated_at,omitzero"`
+	// AnonymizedAt is set once the user has been anonymized, after which the
+	// user can no longer be changed.
+	AnonymizedAt *time.Time `json:"anonymized_at,omitempty"`
+	// Deduplicated is only ever set to true, by a create that returned a
+	// user created moments before with the same name and DOB.
+	Deduplicated bool `json:"deduplicated,omitempty"`
+	// LastModified is when this representation last changed: the later of
+	// UpdatedAt and the start of the day the age last ticked over.
+	LastModified time.Time `json:"-"`
 }
 
+// AccessCount is how often a user has been read, and when last. A user never
+// read has a zero Total and no LastAccessedAt.
+type AccessCount struct {
+	Total          int64     `json:"total"`
+	LastAccessedAt time.Time `json:"last_accessed_at,omitzero"`
+}
+
+// RoundBirthday is the next birthday on which the user turns a multiple of
+// ten.
+type RoundBirthday struct {
+	Date string `json:"date"`
+	Age  int    `json:"age"`
+}
+
+// UserListResponse omits Total and TotalPages when the client passed
+// include_total=false.
 type UserListResponse struct {
 	Users      []UserResponse `json:"users"`
-	Total      int64          `json:"total"`
+	Total      *int64         `json:"total,omitempty"`
 	Page       int            `json:"page"`
 	PageSize   int            `json:"page_size"`
-	TotalPages int            `json:"total_pages"`
+	TotalPages *int           `json:"total_pages,omitempty"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// Leapling is a user born on Feb 29. LeapBirthdays counts the Feb 29s they
+// have lived to see, not the day of birth itself.
+type Leapling struct {
+	UserResponse
+	LeapBirthdays    int    `json:"leap_birthdays"`
+	NextLeapBirthday string `json:"next_leap_birthday"`
+}
+
+type LeaplingListResponse struct {
+	Users      []Leapling `json:"users"`
+	Total      int64      `json:"total"`
+	Page       int        `json:"page"`
+	PageSize   int        `json:"page_size"`
+	TotalPages int        `json:"total_pages"`
+}
+
+// Date is how /api/v2 writes a calendar date.
+type Date struct {
+	Year  int `json:"year"`
+	Month int `json:"month"`
+	Day   int `json:"day"`
+}
+
+// UserResponseV2 is a UserResponse as /api/v2 writes it, the DOB as a Date.
+// Its DOB shadows the embedded one in JSON.
+type UserResponseV2 struct {
+	UserResponse
+	DOB *Date `json:"dob,omitempty"`
+}
+
+// NewUserResponseV2 leaves DOB nil when the v1 DOB is hidden.
+func NewUserResponseV2(user *UserResponse) *UserResponseV2 {
+	v2 := &UserResponseV2{UserResponse: *user}
+	if dob, err := time.Parse(dateLayout, user.DOB); err == nil {
+		v2.DOB = &Date{Year: dob.Year(), Month: int(dob.Month()), Day: dob.Day()}
+	}
+	return v2
+}
+
+type UserListResponseV2 struct {
+	Users []*UserResponseV2 `json:"users"`
+	UserListResponse
+}
+
+func NewUserListResponseV2(list *UserListResponse) *UserListResponseV2 {
+	v2 := &UserListResponseV2{UserListResponse: *list, Users: make([]*UserResponseV2, len(list.Users))}
+	for i := range list.Users {
+		v2.Users[i] = NewUserResponseV2(&list.Users[i])
+	}
+	return v2
+}
+
+// FieldError describes one rejected input field by the name the client sent.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// AgeRankQuery asks the oldest and youngest endpoints for the top Count
+// users; without it they answer the single user.
+type AgeRankQuery struct {
+	Count *int `query:"count" validate:"omitnil,min=1,max=100"`
+}
+
+// AgeRankResponse answers an AgeRankQuery with a count.
+type AgeRankResponse struct {
+	Users []UserResponse `json:"users"`
+}
+
+// AgePercentileResponse places a user's age among the users the list filters
+// select, the user included if they match. Percentile is the share of them
+// that are younger, counting those with the same DOB as half younger, so a
+// lone user sits at 50. It is null when no user matches.
+type AgePercentileResponse struct {
+	ID         int32    `json:"id"`
+	Percentile *float64 `json:"percentile"`
+	Younger    int64    `json:"younger"`
+	Older      int64    `json:"older"`
+	SameDOB    int64    `json:"same_dob"`
+	Total      int64    `json:"total"`
+}
+
+// CollisionsQuery bounds the birthday collisions returned; Limit defaults to
+// 10.
+type CollisionsQuery struct {
+	Limit *int `query:"limit" validate:"omitnil,min=1,max=100"`
+}
+
+// BirthdayCollision is a birthday shared by Count users. Date is it as
+// MM-DD.
+type BirthdayCollision struct {
+	Month int    `json:"month"`
+	Day   int    `json:"day"`
+	Date  string `json:"date"`
+	Count int64  `json:"count"`
+}
+
+type BirthdayCollisionsResponse struct {
+	AsOf       time.Time           `json:"as_of"`
+	Collisions []BirthdayCollision `json:"collisions"`
+}
+
+// Cohort counts the users aged from MinAge to MaxAge, both included; the
+// oldest cohort has no MaxAge.
+type Cohort struct {
+	Label  string `json:"label"`
+	MinAge int    `json:"min_age"`
+	MaxAge *int   `json:"max_age,omitempty"`
+	Count  int64  `json:"count"`
+}
+
+type CohortsResponse struct {
+	AsOf    time.Time `json:"as_of"`
+	Total   int64     `json:"total"`
+	Cohorts []Cohort  `json:"cohorts"`
+}
+
+// BirthdaysResponse lists the users whose birthday it is at AsOf, each on
+// their own calendar.
+type BirthdaysResponse struct {
+	AsOf  time.Time      `json:"as_of"`
+	Count int            `json:"count"`
+	Users []UserResponse `json:"users"`
+}
+
+// EventUserBirthday is published once a year per user, when their birthday
+// begins in their timezone.
+const EventUserBirthday = "user.birthday"
+
+// BirthdayEvent is the data of a user.birthday event. Timezone is the zone the
+// birthday was reckoned in, the default one for users without their own, and
+// Date the birthday's date there.
+type BirthdayEvent struct {
+	UserID   int32  `json:"user_id"`
+	Name     string `json:"name"`
+	DOB      string `json:"dob"`
+	Age      int    `json:"age"`
+	Timezone string `json:"timezone"`
+	Date     string `json:"date"`
+}
+
+// EventUserAnonymized is published when a user's personal data has been
+// scrubbed.
+const EventUserAnonymized = "user.anonymized"
+
+// UserEvent is the data of an event about one user. It carries only the id,
+// so the event itself holds no personal data.
+type UserEvent struct {
+	UserID int32 `json:"user_id"`
+}
+
+// BirthdayNotification records that a user's birthday in Year was notified,
+// on the date NotifiedOn.
+type BirthdayNotification struct {
+	Year       int    `json:"year"`
+	NotifiedOn string `json:"notified_on"`
+}
+
+// UserDataExport is everything stored about one user, for a subject access
+// request.
+type UserDataExport struct {
+	ExportedAt            time.Time              `json:"exported_at"`
+	User                  UserResponse           `json:"user"`
+	BirthdayNotifications []BirthdayNotification `json:"birthday_notifications"`
+}
+
+type DOBValidationResponse struct {
+	AsOf  string         `json:"as_of"`
+	Count int            `json:"count"`
+	Users []UserResponse `json:"users"`
+}
+
+// dateLayout is the wire format of every date.
+const dateLayout = "2006-01-02"
+
+// MaxFilterAge bounds the min_age and max_age filters.
+const MaxFilterAge = 150
+
+const (
+	DefaultPageSize = 10
+	MaxPageSize     = 100
+	// DefaultMaxOffset bounds page × page_size unless MAX_OFFSET says
+	// otherwise.
+	DefaultMaxOffset = 10000
+)
+
+type UserListQuery struct {
+	Page     int    `query:"page" validate:"omitempty,min=1"`
+	PageSize int    `query:"page_size" validate:"omitempty,min=1,max=100"`
+	Sort     string `query:"sort" validate:"omitempty,oneof=id -id created_at -created_at age -age"`
+	Cursor   string `query:"cursor"`
+	Name     string `query:"name" validate:"omitempty,max=100"`
+	// NameFuzzy ranks the users by name similarity instead of sorting them,
+	// so it pages by offset only.
+	NameFuzzy string `query:"name_fuzzy" validate:"omitempty,max=100"`
+	// DOBFrom and DOBTo bound the DOB, inclusively.
+	DOBFrom string `query:"dob_from" validate:"omitempty,datetime=2006-01-02"`
+	DOBTo   string `query:"dob_to" validate:"omitempty,datetime=2006-01-02"`
+	// MinAge and MaxAge bound the age as of today; a DOB in the future counts
+	// as age 0.
+	MinAge *int `query:"min_age" validate:"omitnil,min=0,max=150"`
+	MaxAge *int `query:"max_age" validate:"omitnil,min=0,max=150"`
+	// IncludeTotal defaults to true; false skips the count query entirely.
+	IncludeTotal *bool `query:"include_total"`
+	// IncludeAge defaults to true; false leaves age, age_valid and
+	// is_birthday out of every row and skips working them out.
+	IncludeAge *bool `query:"include_age"`
+	// IncludeAgeMonths gives every row age_months and age_weeks, not only
+	// the infants'.
+	IncludeAgeMonths bool `query:"include_age_months"`
+}
+
+// RefreshQuery asks an aggregate route for a result computed now rather than
+// a cached one.
+type RefreshQuery struct {
+	Refresh bool `query:"refresh"`
+}
+
+// DryRunQuery asks a destructive operation to report what it would change
+// instead of changing it.
+type DryRunQuery struct {
+	DryRun bool `query:"dry_run"`
+}
+
+// MaxDryRunSample bounds the ids a DryRunResponse lists.
+const MaxDryRunSample = 100
+
+// DryRunResponse is what a destructive operation would change: how many
+// users, the ids of up to MaxDryRunSample of them, and the filters that
+// selected them, as the operation read them.
+type DryRunResponse struct {
+	AffectedCount int64          `json:"affected_count"`
+	SampleIDs     []int32        `json:"sample_ids"`
+	Filters       map[string]any `json:"filters"`
+}
+
+// UserQuery holds the options of a single user's read.
+type UserQuery struct {
+	IncludeAgeMonths bool `query:"include_age_months"`
+	// Include names what to add to the user, repeated or comma-separated.
+	Include []string `query:"include" validate:"dive,oneof=milestones progress access_count"`
+}
+
+// Filters returns the parameters of q that select users, leaving out paging,
+// sorting and what each row holds.
+func (q *UserListQuery) Filters() url.Values {
+	filters := url.Values{}
+	for key, value := range map[string]string{"name": q.Name, "name_fuzzy": q.NameFuzzy, "dob_from": q.DOBFrom, "dob_to": q.DOBTo} {
+		if value != "" {
+			filters.Set(key, value)
+		}
+	}
+	if q.MinAge != nil {
+		filters.Set("min_age", strconv.Itoa(*q.MinAge))
+	}
+	if q.MaxAge != nil {
+		filters.Set("max_age", strconv.Itoa(*q.MaxAge))
+	}
+	return filters
+}
+
+// Validate reports the problems that span parameters, which the per-field
+// validate tags cannot express; the handler reports both together. It skips
+// a comparison when either side is itself invalid, leaving that to the tags.
+func (q *UserListQuery) Validate() []FieldError {
+	var details []FieldError
+	if q.Cursor != "" && q.Page != 0 {
+		details = append(details, FieldError{
+			Field:   "cursor",
+			Rule:    "excluded_with",
+			Param:   "page",
+			Message: "cursor cannot be combined with page",
+		})
+	}
+
+	if q.NameFuzzy != "" && q.Cursor != "" {
+		details = append(details, FieldError{
+			Field:   "cursor",
+			Rule:    "excluded_with",
+			Param:   "name_fuzzy",
+			Message: "cursor cannot be combined with name_fuzzy",
+		})
+	}
+	if q.NameFuzzy != "" && q.Sort != "" {
+		details = append(details, FieldError{
+			Field:   "sort",
+			Rule:    "excluded_with",
+			Param:   "name_fuzzy",
+			Message: "sort cannot be combined with name_fuzzy, whose results are ranked by similarity",
+		})
+	}
+
+	from, fromErr := time.Parse(dateLayout, q.DOBFrom)
+	to, toErr := time.Parse(dateLayout, q.DOBTo)
+	if fromErr == nil && toErr == nil && from.After(to) {
+		details = append(details, FieldError{
+			Field:   "dob_from",
+			Rule:    "ltefield",
+			Param:   "dob_to",
+			Message: "dob_from must not be after dob_to",
+		})
+	}
+
+	validAge := func(age *int) bool { return age != nil && *age >= 0 && *age <= MaxFilterAge }
+	if validAge(q.MinAge) && validAge(q.MaxAge) && *q.MinAge > *q.MaxAge {
+		details = append(details, FieldError{
+			Field:   "min_age",
+			Rule:    "ltefield",
+			Param:   "max_age",
+			Message: "min_age must not be greater than max_age",
+		})
+	}
+	return details
+}
+
+// ValidateDepth refuses pages reaching past maxOffset rows, which the
+// database can only find by reading every row before them. Cursors are not
+// limited; 0 allows any page.
+func (q *UserListQuery) ValidateDepth(maxOffset int) []FieldError {
+	if maxOffset == 0 || q.Cursor != "" || q.Page < 1 {
+		return nil
+	}
+	size := q.PageSize
+	if size < 1 || size > MaxPageSize {
+		size = DefaultPageSize
+	}
+	if int64(q.Page)*int64(size) <= int64(maxOffset) {
+		return nil
+	}
+	return []FieldError{{
+		Field:   "page",
+		Rule:    "max_offset",
+		Param:   strconv.Itoa(maxOffset),
+		Message: fmt.Sprintf("page × page_size must not exceed %d; use cursor pagination to read further", maxOffset),
+	}}
+}
+
+func (p *UserListQuery) WantsTotal() bool {
+	return p.IncludeTotal == nil || *p.IncludeTotal
 }
 
-type PaginationParams struct {
-	Page     int `query:"page" validate:"omitempty,min=1"`
-	PageSize int `query:"page_size" validate:"omitempty,min=1,max=100"`
+func (p *UserListQuery) WantsAge() bool {
+	return p.IncludeAge == nil || *p.IncludeAge
 }
 
-func (p *PaginationParams) SetDefaults() {
-	if p.Page == 0 {
+// SortOrder is a validated sort key; a leading "-" in the query means
+// descending.
+type SortOrder struct {
+	Field string
+	Desc  bool
+}
+
+func (s SortOrder) String() string {
+	if s.Desc {
+		return "-" + s.Field
+	}
+	return s.Field
+}
+
+func (p *UserListQuery) SortOrder() SortOrder {
+	field, desc := strings.CutPrefix(p.Sort, "-")
+	if field == "" {
+		field = "id"
+	}
+	return SortOrder{Field: field, Desc: desc}
+}
+
+// SetDefaults also normalizes values that never went through validation, so
+// the repository cannot be handed a negative offset or an unbounded limit.
+func (p *UserListQuery) SetDefaults() {
+	if p.Page < 1 {
 		p.Page = 1
 	}
 
-	if p.PageSize == 0 {
-		p.PageSize = 10
+	if p.PageSize < 1 {
+		p.PageSize = DefaultPageSize
+	}
+	if p.PageSize > MaxPageSize {
+		p.PageSize = MaxPageSize
 	}
 }
 
-func (p *PaginationParams) GetOffset() int32 {
-	return int32((p.Page - 1) * p.PageSize)
+// GetOffset saturates at math.MaxInt32 so a huge page lands past the last row
+// instead of wrapping around to a negative offset.
+func (p *UserListQuery) GetOffset() int32 {
+	offset := (int64(p.Page) - 1) * int64(p.PageSize)
+	if offset < 0 {
+		return 0
+	}
+	if offset > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return int32(offset)
 }
 
-func (p *PaginationParams) GetLimit() int32 {
+func (p *UserListQuery) GetLimit() int32 {
 	return int32(p.PageSize)
 }
+
+func (p *UserListQuery) TotalPages(total int64) int {
+	if p.PageSize < 1 || total < 1 {
+		return 0
+	}
+	return int((total + int64(p.PageSize) - 1) / int64(p.PageSize))
+}
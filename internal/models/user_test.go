@@ -0,0 +1,105 @@
+package models
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestTotalPages(t *testing.T) {
+	tests := []struct {
+		pageSize int
+		total    int64
+		expected int
+	}{
+		{pageSize: 10, total: 0, expected: 0},
+		{pageSize: 10, total: 1, expected: 1},
+		{pageSize: 10, total: 10, expected: 1},
+		{pageSize: 10, total: 11, expected: 2},
+		{pageSize: 0, total: 11, expected: 0},
+		{pageSize: -3, total: 11, expected: 0},
+	}
+
+	for _, tt := range tests {
+		p := UserListQuery{PageSize: tt.pageSize}
+		if got := p.TotalPages(tt.total); got != tt.expected {
+			t.Errorf("TotalPages(%d) with page size %d = %d, want %d", tt.total, tt.pageSize, got, tt.expected)
+		}
+	}
+}
+
+func TestUserListQueryValidateDepth(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     UserListQuery
+		maxOffset int
+		deep      bool
+	}{
+		{name: "at the bound", query: UserListQuery{Page: 100, PageSize: 100}, maxOffset: 10000},
+		{name: "one page over", query: UserListQuery{Page: 101, PageSize: 100}, maxOffset: 10000, deep: true},
+		{name: "default page size at the bound", query: UserListQuery{Page: 1000}, maxOffset: 10000},
+		{name: "default page size over", query: UserListQuery{Page: 1001}, maxOffset: 10000, deep: true},
+		{name: "huge page does not overflow", query: UserListQuery{Page: 1 << 30, PageSize: 100}, maxOffset: 10000, deep: true},
+		{name: "no page", query: UserListQuery{PageSize: 100}, maxOffset: 10},
+		{name: "unbounded", query: UserListQuery{Page: 1 << 30, PageSize: 100}},
+		{name: "cursor", query: UserListQuery{Cursor: "abc", Page: 1 << 30, PageSize: 100}, maxOffset: 10000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			details := tt.query.ValidateDepth(tt.maxOffset)
+			if deep := len(details) > 0; deep != tt.deep {
+				t.Errorf("ValidateDepth(%d) = %v, want deep %v", tt.maxOffset, details, tt.deep)
+			}
+		})
+	}
+}
+
+func TestUserListQueryValidate(t *testing.T) {
+	age := func(n int) *int { return &n }
+	tests := []struct {
+		name  string
+		query UserListQuery
+		want  []string
+	}{
+		{name: "empty", query: UserListQuery{}},
+		{name: "consistent bounds", query: UserListQuery{DOBFrom: "1990-01-01", DOBTo: "1990-01-01", MinAge: age(30), MaxAge: age(30)}},
+		{name: "cursor and page", query: UserListQuery{Cursor: "abc", Page: 2}, want: []string{"cursor:excluded_with"}},
+		{name: "reversed dates", query: UserListQuery{DOBFrom: "2000-01-02", DOBTo: "2000-01-01"}, want: []string{"dob_from:ltefield"}},
+		{name: "reversed ages", query: UserListQuery{MinAge: age(40), MaxAge: age(30)}, want: []string{"min_age:ltefield"}},
+		{name: "everything at once", query: UserListQuery{Cursor: "abc", Page: 1, DOBFrom: "2000-01-02", DOBTo: "1999-01-01", MinAge: age(2), MaxAge: age(1)}, want: []string{"cursor:excluded_with", "dob_from:ltefield", "min_age:ltefield"}},
+		{name: "fuzzy name with cursor and sort", query: UserListQuery{NameFuzzy: "jon", Cursor: "abc", Sort: "name"}, want: []string{"cursor:excluded_with", "sort:excluded_with"}},
+		// Field-level problems are the validate tags' to report.
+		{name: "unparseable date", query: UserListQuery{DOBFrom: "yesterday", DOBTo: "2000-01-01"}},
+		{name: "age out of range", query: UserListQuery{MinAge: age(200), MaxAge: age(30)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, d := range tt.query.Validate() {
+				got = append(got, d.Field+":"+d.Rule)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewUserResponseV2(t *testing.T) {
+	for _, tt := range []struct {
+		dob  string
+		want string
+	}{
+		{dob: "1990-05-10", want: `{"id":1,"name":"Alice","dob":{"year":1990,"month":5,"day":10}}`},
+		// A list with LIST_HIDES_DOB leaves the DOB out in v2 as in v1.
+		{dob: "", want: `{"id":1,"name":"Alice"}`},
+	} {
+		got, err := json.Marshal(NewUserResponseV2(&UserResponse{ID: 1, Name: "Alice", DOB: tt.dob}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("dob %q: got %s, want %s", tt.dob, got, tt.want)
+		}
+	}
+}
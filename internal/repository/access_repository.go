@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+	"go.uber.org/zap"
+)
+
+// AccessIncrement is Count reads of one user, the last of them at
+// LastAccessedAt.
+type AccessIncrement struct {
+	TenantID       string
+	UserID         int32
+	Count          int64
+	LastAccessedAt time.Time
+}
+
+// AccessRepository keeps how often each user has been read.
+type AccessRepository interface {
+	// Add adds every increment to its user's count at once, keeping the
+	// latest access. Increments of users deleted since may be dropped.
+	Add(ctx context.Context, increments []AccessIncrement) error
+	// Get is the count of userID in the tenant carried by ctx, zero for a
+	// user never counted there.
+	Get(ctx context.Context, userID int32) (*models.AccessCount, error)
+}
+
+type accessRepository struct {
+	db     *sql.DB
+	logger *zap.Logger
+}
+
+func NewAccessRepository(db *sql.DB, logger *zap.Logger) AccessRepository {
+	return &accessRepository{db: db, logger: logger}
+}
+
+func (r *accessRepository) Add(ctx context.Context, increments []AccessIncrement) error {
+	if len(increments) == 0 {
+		return nil
+	}
+	// The join skips users deleted since they were read, whose counts would
+	// otherwise fail the whole batch on the foreign key.
+	query := `INSERT INTO access_counts (user_id, tenant_id, count, last_accessed_at)
+		SELECT i.user_id, i.tenant_id, i.count, i.last_accessed_at
+		FROM unnest($1::integer[], $2::text[], $3::bigint[], $4::timestamp[]) AS i(user_id, tenant_id, count, last_accessed_at)
+		JOIN users ON users.id = i.user_id AND users.tenant_id = i.tenant_id
+		ON CONFLICT (user_id) DO UPDATE SET
+			count = access_counts.count + EXCLUDED.count,
+			last_accessed_at = GREATEST(access_counts.last_accessed_at, EXCLUDED.last_accessed_at)`
+
+	ids := make(pq.Int64Array, len(increments))
+	tenants := make(pq.StringArray, len(increments))
+	counts := make(pq.Int64Array, len(increments))
+	accessed := make(pq.StringArray, len(increments))
+	for i, inc := range increments {
+		ids[i], tenants[i], counts[i] = int64(inc.UserID), inc.TenantID, inc.Count
+		accessed[i] = inc.LastAccessedAt.UTC().Format("2006-01-02 15:04:05.999999")
+	}
+	if _, err := r.db.ExecContext(ctx, query, ids, tenants, counts, accessed); err != nil {
+		r.logger.Error("Failed to add access counts", zap.Error(err), zap.Int("users", len(increments)))
+		return err
+	}
+	return nil
+}
+
+func (r *accessRepository) Get(ctx context.Context, userID int32) (*models.AccessCount, error) {
+	query := `SELECT count, last_accessed_at FROM access_counts WHERE tenant_id = $1 AND user_id = $2`
+
+	var count models.AccessCount
+	err := r.db.QueryRowContext(ctx, query, tenant.FromContext(ctx), userID).Scan(&count.Total, &count.LastAccessedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &models.AccessCount{}, nil
+	}
+	if err != nil {
+		r.logger.Error("Failed to get access count", zap.Error(err), zap.Int32("id", userID))
+		return nil, err
+	}
+	count.LastAccessedAt = count.LastAccessedAt.UTC()
+	return &count, nil
+}
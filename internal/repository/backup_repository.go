@@ -0,0 +1,248 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"go.uber.org/zap"
+)
+
+// ErrNotEmpty is returned by Restore, without truncate, into a database that
+// already has users.
+var ErrNotEmpty = errors.New("repository: database not empty")
+
+// BackupRepository reads and loads the dataset of every tenant at once: the
+// users and the birthday notification markers and share tokens that hang off
+// them. Jobs, the events outbox and access counts are left out.
+type BackupRepository interface {
+	// Snapshot calls fn with the dataset as of one moment, read under no
+	// lock that would hold up writes.
+	Snapshot(ctx context.Context, fn func(BackupSnapshot) error) error
+	// Restore loads backup in one transaction, into a database with no
+	// users unless truncate, which first deletes them and everything that
+	// references them. The id sequences then continue past the ids loaded.
+	Restore(ctx context.Context, backup *models.Backup, truncate bool) error
+}
+
+// BackupSnapshot walks each table of a Snapshot in id order.
+type BackupSnapshot interface {
+	EachUser(ctx context.Context, fn func(*models.BackupUser) error) error
+	EachNotification(ctx context.Context, fn func(*models.BackupNotification) error) error
+	EachShare(ctx context.Context, fn func(*models.BackupShare) error) error
+}
+
+type backupRepository struct {
+	db     *sql.DB
+	logger *zap.Logger
+}
+
+func NewBackupRepository(db *sql.DB, logger *zap.Logger) BackupRepository {
+	return &backupRepository{db: db, logger: logger}
+}
+
+func (r *backupRepository) Snapshot(ctx context.Context, fn func(BackupSnapshot) error) error {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		r.logger.Error("Failed to begin backup snapshot", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(backupSnapshot{tx: tx}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+type backupSnapshot struct {
+	tx *sql.Tx
+}
+
+// each calls scan on every row query returns.
+func (s backupSnapshot) each(ctx context.Context, query string, scan func(rowScanner) error) error {
+	rows, err := s.tx.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (s backupSnapshot) EachUser(ctx context.Context, fn func(*models.BackupUser) error) error {
+	query := `SELECT id, tenant_id, name, dob, timezone, locale, created_at, updated_at, anonymized_at FROM users ORDER BY id`
+
+	return s.each(ctx, query, func(row rowScanner) error {
+		var user models.User
+		if err := scanUser(row, &user); err != nil {
+			return err
+		}
+		return fn(&models.BackupUser{
+			ID:           user.ID,
+			TenantID:     user.TenantID,
+			Name:         user.Name,
+			DOB:          dateParam(user.DOB),
+			Timezone:     user.Timezone,
+			Locale:       user.Locale,
+			CreatedAt:    user.CreatedAt,
+			UpdatedAt:    user.UpdatedAt,
+			AnonymizedAt: user.AnonymizedAt,
+		})
+	})
+}
+
+func (s backupSnapshot) EachNotification(ctx context.Context, fn func(*models.BackupNotification) error) error {
+	query := `SELECT user_id, year, notified_on FROM birthday_notifications ORDER BY user_id, year`
+
+	return s.each(ctx, query, func(row rowScanner) error {
+		var marker models.BackupNotification
+		var on time.Time
+		if err := row.Scan(&marker.UserID, &marker.Year, &on); err != nil {
+			return err
+		}
+		marker.NotifiedOn = dateParam(on)
+		return fn(&marker)
+	})
+}
+
+func (s backupSnapshot) EachShare(ctx context.Context, fn func(*models.BackupShare) error) error {
+	query := `SELECT ` + shareColumns + ` FROM share_tokens ORDER BY id`
+
+	return s.each(ctx, query, func(row rowScanner) error {
+		var share models.ShareToken
+		if err := scanShare(row, &share); err != nil {
+			return err
+		}
+		return fn(&models.BackupShare{
+			ID:        share.ID,
+			TenantID:  share.TenantID,
+			UserID:    share.UserID,
+			TokenHash: share.TokenHash,
+			CreatedAt: share.CreatedAt,
+			ExpiresAt: share.ExpiresAt,
+			RevokedAt: share.RevokedAt,
+		})
+	})
+}
+
+func (r *backupRepository) Restore(ctx context.Context, backup *models.Backup, truncate bool) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("Failed to begin restore", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := r.restore(ctx, tx, backup, truncate); err != nil {
+		if isDuplicateName(err) {
+			return ErrDuplicateName
+		}
+		if !errors.Is(err, ErrNotEmpty) {
+			r.logger.Error("Failed to restore backup", zap.Error(err))
+		}
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("Failed to commit restore", zap.Error(err))
+		return err
+	}
+	r.logger.Info("Backup restored", zap.Int("users", len(backup.Users)), zap.Bool("truncated", truncate))
+	return nil
+}
+
+func (r *backupRepository) restore(ctx context.Context, tx *sql.Tx, backup *models.Backup, truncate bool) error {
+	if truncate {
+		// CASCADE takes every table referencing users along.
+		if _, err := tx.ExecContext(ctx, `TRUNCATE users RESTART IDENTITY CASCADE`); err != nil {
+			return err
+		}
+	} else {
+		// Held to the commit, so no user is created in the meantime.
+		if _, err := tx.ExecContext(ctx, `LOCK TABLE users IN EXCLUSIVE MODE`); err != nil {
+			return err
+		}
+		var exists bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users)`).Scan(&exists); err != nil {
+			return err
+		}
+		if exists {
+			return ErrNotEmpty
+		}
+	}
+
+	users := make([][]any, len(backup.Users))
+	for i, u := range backup.Users {
+		users[i] = []any{u.ID, u.TenantID, u.Name, u.DOB, nullString(u.Timezone), nullString(u.Locale), u.CreatedAt.UTC(), u.UpdatedAt.UTC(), utcOrNil(u.AnonymizedAt)}
+	}
+	if err := copyRows(ctx, tx, "users", []string{"id", "tenant_id", "name", "dob", "timezone", "locale", "created_at", "updated_at", "anonymized_at"}, users); err != nil {
+		return err
+	}
+	markers := make([][]any, len(backup.BirthdayNotifications))
+	for i, n := range backup.BirthdayNotifications {
+		markers[i] = []any{n.UserID, n.Year, n.NotifiedOn}
+	}
+	if err := copyRows(ctx, tx, "birthday_notifications", []string{"user_id", "year", "notified_on"}, markers); err != nil {
+		return err
+	}
+	shares := make([][]any, len(backup.ShareTokens))
+	for i, s := range backup.ShareTokens {
+		shares[i] = []any{s.ID, s.TenantID, s.UserID, s.TokenHash, s.CreatedAt.UTC(), s.ExpiresAt.UTC(), utcOrNil(s.RevokedAt)}
+	}
+	if err := copyRows(ctx, tx, "share_tokens", []string{"id", "tenant_id", "user_id", "token_hash", "created_at", "expires_at", "revoked_at"}, shares); err != nil {
+		return err
+	}
+
+	// The next id follows the largest restored, or is 1 for an empty table.
+	for _, table := range []string{"users", "share_tokens"} {
+		query := `SELECT setval(pg_get_serial_sequence('` + table + `', 'id'), COALESCE(MAX(id), 1), MAX(id) IS NOT NULL) FROM ` + table
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyRows loads rows into table with COPY, far faster than an INSERT each.
+func copyRows(ctx context.Context, tx *sql.Tx, table string, columns []string, rows [][]any) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(table, columns...))
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			stmt.Close()
+			return err
+		}
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
+		return err
+	}
+	return stmt.Close()
+}
+
+// nullString stores "" as NULL, as the columns that take it are read.
+func nullString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func utcOrNil(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
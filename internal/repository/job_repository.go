@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+	"go.uber.org/zap"
+)
+
+// ErrJobNotFound is returned by Get for a missing or foreign job, and by
+// Claim when nothing is pending.
+var ErrJobNotFound = errors.New("repository: job not found")
+
+// JobRepository stores background jobs. Create and Get act on the tenant
+// carried by ctx; the worker-side methods span every tenant, and a job only
+// moves out of running once.
+type JobRepository interface {
+	Create(ctx context.Context, kind string, payload []byte) (*models.Job, error)
+	Get(ctx context.Context, id int64) (*models.Job, error)
+	// Claim marks the oldest pending job running and returns it with its
+	// payload. Concurrent callers never claim the same job.
+	Claim(ctx context.Context) (*models.Job, error)
+	// Progress also serves as the heartbeat that keeps a job from going stale.
+	Progress(ctx context.Context, id int64, rows int) error
+	Finish(ctx context.Context, id int64, state models.JobState, rows int, report json.RawMessage, errMsg string) error
+	// FailStale fails running jobs whose last update is older than olderThan
+	// and returns how many it failed.
+	FailStale(ctx context.Context, olderThan time.Duration, reason string) (int64, error)
+}
+
+const jobColumns = `id, tenant_id, kind, state, rows_processed, report, error, created_at, updated_at, finished_at`
+
+type jobRepository struct {
+	db     *sql.DB
+	logger *zap.Logger
+}
+
+func NewJobRepository(db *sql.DB, logger *zap.Logger) JobRepository {
+	return &jobRepository{db: db, logger: logger}
+}
+
+func (r *jobRepository) Create(ctx context.Context, kind string, payload []byte) (*models.Job, error) {
+	query := `INSERT INTO jobs (tenant_id, kind, payload) VALUES ($1, $2, $3) RETURNING ` + jobColumns
+
+	var job models.Job
+	if err := scanJob(r.db.QueryRowContext(ctx, query, tenant.FromContext(ctx), kind, payload), &job); err != nil {
+		r.logger.Error("Failed to create job", zap.Error(err))
+		return nil, err
+	}
+
+	r.logger.Info("Job created", zap.Int64("id", job.ID), zap.String("kind", kind))
+	return &job, nil
+}
+
+func (r *jobRepository) Get(ctx context.Context, id int64) (*models.Job, error) {
+	query := `SELECT ` + jobColumns + ` FROM jobs WHERE tenant_id = $1 AND id = $2`
+
+	var job models.Job
+	if err := scanJob(r.db.QueryRowContext(ctx, query, tenant.FromContext(ctx), id), &job); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrJobNotFound
+		}
+		r.logger.Error("Failed to get job", zap.Error(err), zap.Int64("id", id))
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *jobRepository) Claim(ctx context.Context) (*models.Job, error) {
+	query := `UPDATE jobs SET state = 'running', updated_at = CURRENT_TIMESTAMP
+		WHERE id = (SELECT id FROM jobs WHERE state = 'pending' ORDER BY id FOR UPDATE SKIP LOCKED LIMIT 1)
+		RETURNING ` + jobColumns + `, payload`
+
+	var job models.Job
+	if err := scanJob(r.db.QueryRowContext(ctx, query), &job, &job.Payload); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrJobNotFound
+		}
+		r.logger.Error("Failed to claim job", zap.Error(err))
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *jobRepository) Progress(ctx context.Context, id int64, rows int) error {
+	query := `UPDATE jobs SET rows_processed = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND state = 'running'`
+
+	if _, err := r.db.ExecContext(ctx, query, rows, id); err != nil {
+		r.logger.Error("Failed to record job progress", zap.Error(err), zap.Int64("id", id))
+		return err
+	}
+	return nil
+}
+
+func (r *jobRepository) Finish(ctx context.Context, id int64, state models.JobState, rows int, report json.RawMessage, errMsg string) error {
+	query := `UPDATE jobs SET state = $1, rows_processed = $2, report = $3, error = $4, payload = NULL,
+		updated_at = CURRENT_TIMESTAMP, finished_at = CURRENT_TIMESTAMP
+		WHERE id = $5 AND state = 'running'`
+
+	// A nil RawMessage would be sent as an empty string, which is not JSON.
+	var reportParam any
+	if report != nil {
+		reportParam = []byte(report)
+	}
+	if _, err := r.db.ExecContext(ctx, query, string(state), rows, reportParam, errMsg, id); err != nil {
+		r.logger.Error("Failed to finish job", zap.Error(err), zap.Int64("id", id))
+		return err
+	}
+
+	r.logger.Info("Job finished", zap.Int64("id", id), zap.String("state", string(state)))
+	return nil
+}
+
+func (r *jobRepository) FailStale(ctx context.Context, olderThan time.Duration, reason string) (int64, error) {
+	query := `UPDATE jobs SET state = 'failed', error = $1, payload = NULL, updated_at = CURRENT_TIMESTAMP, finished_at = CURRENT_TIMESTAMP
+		WHERE state = 'running' AND updated_at < CURRENT_TIMESTAMP - make_interval(secs => $2)`
+
+	result, err := r.db.ExecContext(ctx, query, reason, olderThan.Seconds())
+	if err != nil {
+		r.logger.Error("Failed to fail stale jobs", zap.Error(err))
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// scanJob reads jobColumns followed by any extra destinations.
+func scanJob(row rowScanner, job *models.Job, extra ...any) error {
+	var state string
+	var report []byte
+	dest := append([]any{&job.ID, &job.TenantID, &job.Kind, &state, &job.RowsProcessed, &report, &job.Error,
+		&job.CreatedAt, &job.UpdatedAt, &job.FinishedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return err
+	}
+	job.State = models.JobState(state)
+	if report != nil {
+		job.Report = json.RawMessage(report)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+)
+
+type accessKey struct {
+	tenantID string
+	userID   int32
+}
+
+type memoryAccessRepository struct {
+	mu     sync.Mutex
+	counts map[accessKey]models.AccessCount
+}
+
+func NewMemoryAccessRepository() AccessRepository {
+	return &memoryAccessRepository{counts: make(map[accessKey]models.AccessCount)}
+}
+
+func (r *memoryAccessRepository) Add(ctx context.Context, increments []AccessIncrement) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, inc := range increments {
+		key := accessKey{tenantID: inc.TenantID, userID: inc.UserID}
+		count := r.counts[key]
+		count.Total += inc.Count
+		if inc.LastAccessedAt.After(count.LastAccessedAt) {
+			count.LastAccessedAt = inc.LastAccessedAt.UTC()
+		}
+		r.counts[key] = count
+	}
+	return nil
+}
+
+func (r *memoryAccessRepository) Get(ctx context.Context, userID int32) (*models.AccessCount, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := r.counts[accessKey{tenantID: tenant.FromContext(ctx), userID: userID}]
+	return &count, nil
+}
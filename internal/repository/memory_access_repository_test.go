@@ -0,0 +1,12 @@
+package repository_test
+
+import (
+	"testing"
+
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/testutil"
+)
+
+func TestMemoryAccessRepository(t *testing.T) {
+	testutil.AssertAccessRepository(t, repository.NewMemoryAccessRepository(), 1)
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+)
+
+// memoryBackupRepository holds a dataset of its own, apart from the other
+// memory repositories.
+type memoryBackupRepository struct {
+	mu     sync.Mutex
+	backup models.Backup
+}
+
+// NewMemoryBackupRepository starts from the rows of backup, or from none.
+func NewMemoryBackupRepository(backup *models.Backup) BackupRepository {
+	r := &memoryBackupRepository{}
+	if backup != nil {
+		r.backup = copyBackup(backup)
+	}
+	return r
+}
+
+func copyBackup(b *models.Backup) models.Backup {
+	return models.Backup{
+		Users:                 slices.Clone(b.Users),
+		BirthdayNotifications: slices.Clone(b.BirthdayNotifications),
+		ShareTokens:           slices.Clone(b.ShareTokens),
+	}
+}
+
+func (r *memoryBackupRepository) Snapshot(ctx context.Context, fn func(BackupSnapshot) error) error {
+	r.mu.Lock()
+	snapshot := memoryBackupSnapshot(copyBackup(&r.backup))
+	r.mu.Unlock()
+	return fn(snapshot)
+}
+
+type memoryBackupSnapshot models.Backup
+
+func (s memoryBackupSnapshot) EachUser(ctx context.Context, fn func(*models.BackupUser) error) error {
+	return eachRow(s.Users, fn)
+}
+
+func (s memoryBackupSnapshot) EachNotification(ctx context.Context, fn func(*models.BackupNotification) error) error {
+	return eachRow(s.BirthdayNotifications, fn)
+}
+
+func (s memoryBackupSnapshot) EachShare(ctx context.Context, fn func(*models.BackupShare) error) error {
+	return eachRow(s.ShareTokens, fn)
+}
+
+func eachRow[T any](rows []T, fn func(*T) error) error {
+	for i := range rows {
+		if err := fn(&rows[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *memoryBackupRepository) Restore(ctx context.Context, backup *models.Backup, truncate bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !truncate && len(r.backup.Users) > 0 {
+		return ErrNotEmpty
+	}
+	r.backup = copyBackup(backup)
+	return nil
+}
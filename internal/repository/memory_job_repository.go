@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+)
+
+type memoryJobRepository struct {
+	mu     sync.Mutex
+	clock  clock.Clock
+	nextID int64
+	jobs   map[int64]*models.Job
+}
+
+func NewMemoryJobRepository(c clock.Clock) JobRepository {
+	return &memoryJobRepository{
+		clock:  c,
+		nextID: 1,
+		jobs:   make(map[int64]*models.Job),
+	}
+}
+
+func (r *memoryJobRepository) Create(ctx context.Context, kind string, payload []byte) (*models.Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.clock.Now()
+	job := &models.Job{
+		ID:        r.nextID,
+		TenantID:  tenant.FromContext(ctx),
+		Kind:      kind,
+		State:     models.JobPending,
+		Payload:   slices.Clone(payload),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	r.jobs[job.ID] = job
+	r.nextID++
+
+	return copyJob(job, false), nil
+}
+
+func (r *memoryJobRepository) Get(ctx context.Context, id int64) (*models.Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, ok := r.jobs[id]
+	if !ok || job.TenantID != tenant.FromContext(ctx) {
+		return nil, ErrJobNotFound
+	}
+	return copyJob(job, false), nil
+}
+
+func (r *memoryJobRepository) Claim(ctx context.Context) (*models.Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var next *models.Job
+	for _, job := range r.jobs {
+		if job.State == models.JobPending && (next == nil || job.ID < next.ID) {
+			next = job
+		}
+	}
+	if next == nil {
+		return nil, ErrJobNotFound
+	}
+	next.State = models.JobRunning
+	next.UpdatedAt = r.clock.Now()
+	return copyJob(next, true), nil
+}
+
+func (r *memoryJobRepository) Progress(ctx context.Context, id int64, rows int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if job, ok := r.jobs[id]; ok && job.State == models.JobRunning {
+		job.RowsProcessed = rows
+		job.UpdatedAt = r.clock.Now()
+	}
+	return nil
+}
+
+func (r *memoryJobRepository) Finish(ctx context.Context, id int64, state models.JobState, rows int, report json.RawMessage, errMsg string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, ok := r.jobs[id]
+	if !ok || job.State != models.JobRunning {
+		return nil
+	}
+	now := r.clock.Now()
+	job.State, job.RowsProcessed, job.Report, job.Error = state, rows, slices.Clone(report), errMsg
+	job.Payload, job.UpdatedAt, job.FinishedAt = nil, now, &now
+	return nil
+}
+
+func (r *memoryJobRepository) FailStale(ctx context.Context, olderThan time.Duration, reason string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.clock.Now()
+	var n int64
+	for _, job := range r.jobs {
+		if job.State == models.JobRunning && job.UpdatedAt.Before(now.Add(-olderThan)) {
+			job.State, job.Error, job.Payload = models.JobFailed, reason, nil
+			job.UpdatedAt, job.FinishedAt = now, &now
+			n++
+		}
+	}
+	return n, nil
+}
+
+// copyJob keeps callers from mutating stored jobs. The payload is only
+// handed out to the worker that claims the job, as the SQL repository does.
+func copyJob(job *models.Job, withPayload bool) *models.Job {
+	out := *job
+	out.Report = slices.Clone(job.Report)
+	out.Payload = nil
+	if withPayload {
+		out.Payload = slices.Clone(job.Payload)
+	}
+	if job.FinishedAt != nil {
+		finished := *job.FinishedAt
+		out.FinishedAt = &finished
+	}
+	return &out
+}
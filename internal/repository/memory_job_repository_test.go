@@ -0,0 +1,14 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/testutil"
+)
+
+func TestMemoryJobRepository(t *testing.T) {
+	testutil.AssertJobRepository(t, repository.NewMemoryJobRepository(clock.Fixed(time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC))))
+}
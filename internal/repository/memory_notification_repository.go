@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+)
+
+type notificationKey struct {
+	userID int32
+	year   int
+}
+
+type memoryNotificationRepository struct {
+	mu      sync.Mutex
+	markers map[notificationKey]time.Time
+}
+
+func NewMemoryNotificationRepository() NotificationRepository {
+	return &memoryNotificationRepository{markers: make(map[notificationKey]time.Time)}
+}
+
+func (r *memoryNotificationRepository) MarkNotified(ctx context.Context, userID int32, year int, on time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := notificationKey{userID: userID, year: year}
+	if _, ok := r.markers[key]; ok {
+		return false, nil
+	}
+	r.markers[key] = on
+	return true, nil
+}
+
+func (r *memoryNotificationRepository) Unmark(ctx context.Context, userID int32, year int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.markers, notificationKey{userID: userID, year: year})
+	return nil
+}
+
+func (r *memoryNotificationRepository) ListForUser(ctx context.Context, userID int32) ([]models.BirthdayNotification, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	markers := make([]models.BirthdayNotification, 0)
+	for key, on := range r.markers {
+		if key.userID == userID {
+			markers = append(markers, models.BirthdayNotification{Year: key.year, NotifiedOn: on.Format("2006-01-02")})
+		}
+	}
+	slices.SortFunc(markers, func(a, b models.BirthdayNotification) int { return a.Year - b.Year })
+	return markers, nil
+}
+
+func (r *memoryNotificationRepository) DeleteForUser(ctx context.Context, userID int32) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key := range r.markers {
+		if key.userID == userID {
+			delete(r.markers, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+package repository_test
+
+import (
+	"testing"
+
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/testutil"
+)
+
+func TestMemoryNotificationRepository(t *testing.T) {
+	testutil.AssertNotificationRepository(t, repository.NewMemoryNotificationRepository(), 1)
+}
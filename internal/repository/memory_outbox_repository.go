@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/events"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+)
+
+// memoryOutboxRepository is the outbox of a memory user repository, whose
+// transactions restore it along with the users.
+type memoryOutboxRepository struct {
+	mu     sync.Mutex
+	nextID int64
+	events []models.OutboxEvent
+}
+
+type outboxSnapshot struct {
+	nextID int64
+	events []models.OutboxEvent
+}
+
+func (r *memoryOutboxRepository) snapshot() outboxSnapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return outboxSnapshot{nextID: r.nextID, events: slices.Clone(r.events)}
+}
+
+func (r *memoryOutboxRepository) restore(s outboxSnapshot) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID, r.events = s.nextID, s.events
+}
+
+func (r *memoryOutboxRepository) Enqueue(ctx context.Context, event events.Event) error {
+	payload, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, models.OutboxEvent{
+		ID:            r.nextID,
+		TenantID:      event.TenantID,
+		Type:          event.Type,
+		Payload:       payload,
+		CreatedAt:     event.At,
+		NextAttemptAt: event.At,
+	})
+	r.nextID++
+	return nil
+}
+
+func (r *memoryOutboxRepository) Claim(ctx context.Context, now time.Time, lease time.Duration) (*models.OutboxEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	due := -1
+	for i, event := range r.events {
+		if event.SentAt != nil || event.DeadAt != nil || event.NextAttemptAt.After(now) {
+			continue
+		}
+		if due < 0 || event.NextAttemptAt.Before(r.events[due].NextAttemptAt) {
+			due = i
+		}
+	}
+	if due < 0 {
+		return nil, ErrOutboxEventNotFound
+	}
+	event := &r.events[due]
+	event.Attempts++
+	event.NextAttemptAt = now.Add(lease)
+	return copyOutboxEvent(*event), nil
+}
+
+// find returns the event with id, or nil.
+func (r *memoryOutboxRepository) find(id int64) *models.OutboxEvent {
+	for i := range r.events {
+		if r.events[i].ID == id {
+			return &r.events[i]
+		}
+	}
+	return nil
+}
+
+func (r *memoryOutboxRepository) MarkSent(ctx context.Context, id int64, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if event := r.find(id); event != nil {
+		event.SentAt, event.LastError = &at, ""
+	}
+	return nil
+}
+
+func (r *memoryOutboxRepository) MarkFailed(ctx context.Context, id int64, reason string, retryAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if event := r.find(id); event != nil {
+		event.LastError, event.NextAttemptAt = reason, retryAt
+	}
+	return nil
+}
+
+func (r *memoryOutboxRepository) MarkDead(ctx context.Context, id int64, reason string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if event := r.find(id); event != nil {
+		event.LastError, event.DeadAt = reason, &at
+	}
+	return nil
+}
+
+func (r *memoryOutboxRepository) ListDead(ctx context.Context, limit int32) ([]models.OutboxEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := make([]models.OutboxEvent, 0)
+	for _, event := range r.events {
+		if event.DeadAt != nil && len(list) < int(limit) {
+			list = append(list, *copyOutboxEvent(event))
+		}
+	}
+	return list, nil
+}
+
+func (r *memoryOutboxRepository) Replay(ctx context.Context, id int64, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event := r.find(id)
+	if event == nil || event.DeadAt == nil {
+		return ErrOutboxEventNotFound
+	}
+	event.Attempts, event.NextAttemptAt, event.DeadAt = 0, at, nil
+	return nil
+}
+
+func (r *memoryOutboxRepository) DeleteSent(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := len(r.events)
+	r.events = slices.DeleteFunc(r.events, func(event models.OutboxEvent) bool {
+		return event.SentAt != nil && event.SentAt.Before(before)
+	})
+	return int64(n - len(r.events)), nil
+}
+
+func copyOutboxEvent(event models.OutboxEvent) *models.OutboxEvent {
+	event.Payload = slices.Clone(event.Payload)
+	return &event
+}
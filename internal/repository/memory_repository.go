@@ -0,0 +1,570 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+)
+
+type memoryUserRepository struct {
+	// txMu serializes transactions; mu guards the data.
+	txMu   sync.Mutex
+	mu     sync.RWMutex
+	clock  clock.Clock
+	nextID int32
+	users  map[int32]models.User
+	order  []int32
+	// uniqueNames plays the part of the index UniqueNamesStep creates.
+	uniqueNames bool
+	outbox      *memoryOutboxRepository
+}
+
+type MemoryOption func(*memoryUserRepository)
+
+// WithUniqueNames rejects a name another user of the tenant already has, in
+// any case, with ErrDuplicateName.
+func WithUniqueNames() MemoryOption {
+	return func(r *memoryUserRepository) {
+		r.uniqueNames = true
+	}
+}
+
+func NewMemoryUserRepository(c clock.Clock, opts ...MemoryOption) UserRepository {
+	r := &memoryUserRepository{
+		clock:  c,
+		nextID: 1,
+		users:  make(map[int32]models.User),
+		outbox: &memoryOutboxRepository{nextID: 1},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// nameTaken reports whether a user other than id holds name in the tenant.
+func (r *memoryUserRepository) nameTaken(ctx context.Context, name string, id int32) bool {
+	if !r.uniqueNames {
+		return false
+	}
+	tenantID, lower := tenant.FromContext(ctx), strings.ToLower(name)
+	for _, user := range r.users {
+		if user.ID != id && user.TenantID == tenantID && strings.ToLower(user.Name) == lower {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memoryUserRepository) Create(ctx context.Context, name string, dob time.Time) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.nameTaken(ctx, name, 0) {
+		return nil, ErrDuplicateName
+	}
+	now := r.clock.Now()
+	user := models.User{
+		ID:        r.nextID,
+		TenantID:  tenant.FromContext(ctx),
+		Name:      name,
+		DOB:       toDate(dob),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	r.users[user.ID] = user
+	r.order = append(r.order, user.ID)
+	r.nextID++
+
+	return &user, nil
+}
+
+func (r *memoryUserRepository) GetById(ctx context.Context, id int32) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.lookup(ctx, id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r *memoryUserRepository) GetByName(ctx context.Context, name string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenantID, lower := tenant.FromContext(ctx), strings.ToLower(name)
+	for _, id := range r.order {
+		if user := r.users[id]; user.TenantID == tenantID && strings.ToLower(user.Name) == lower {
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryUserRepository) FindRecent(ctx context.Context, name string, dob time.Time, within time.Duration) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenantID, dob, since := tenant.FromContext(ctx), toDate(dob), r.clock.Now().Add(-within)
+	for _, id := range r.order {
+		user := r.users[id]
+		if user.TenantID == tenantID && user.Name == name && user.DOB.Equal(dob) && user.AnonymizedAt == nil && !user.CreatedAt.Before(since) {
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryUserRepository) FindByNameDOB(ctx context.Context, name string, dob time.Time) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenantID, lower, dob := tenant.FromContext(ctx), strings.ToLower(name), toDate(dob)
+	for _, id := range r.order {
+		user := r.users[id]
+		if user.TenantID == tenantID && strings.ToLower(user.Name) == lower && user.DOB.Equal(dob) && user.AnonymizedAt == nil {
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// lookup hides rows of other tenants exactly as if they did not exist.
+func (r *memoryUserRepository) lookup(ctx context.Context, id int32) (models.User, bool) {
+	user, ok := r.users[id]
+	if !ok || user.TenantID != tenant.FromContext(ctx) {
+		return models.User{}, false
+	}
+	return user, true
+}
+
+func (r *memoryUserRepository) List(ctx context.Context, q ListQuery) ([]models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	order := r.order
+	if column, desc := sortKey(q.Sort); column != "id" || desc {
+		order = r.sorted(q.Sort)
+	}
+	tenantID := tenant.FromContext(ctx)
+	order = slices.DeleteFunc(slices.Clone(order), func(id int32) bool {
+		user := r.users[id]
+		return user.TenantID != tenantID || !q.Filter.matches(user)
+	})
+
+	start := min(max(int(q.Offset), 0), len(order))
+	if q.After != nil {
+		boundary := models.User{ID: q.After.ID, CreatedAt: q.After.CreatedAt, DOB: q.After.DOB}
+		start, _ = slices.BinarySearchFunc(order, boundary, func(id int32, b models.User) int {
+			if c := compareUsers(r.users[id], b, q.Sort); c != 0 {
+				return c
+			}
+			// Place the boundary row itself before the start of the page.
+			return -1
+		})
+	}
+	end := min(start+max(int(q.Limit), 0), len(order))
+
+	users := make([]models.User, 0, end-start)
+	for _, id := range order[start:end] {
+		users = append(users, r.users[id])
+	}
+	return users, nil
+}
+
+func (f UserFilter) matches(user models.User) bool {
+	if !f.DOBFrom.IsZero() && user.DOB.Before(toDate(f.DOBFrom)) {
+		return false
+	}
+	if !f.DOBTo.IsZero() && user.DOB.After(toDate(f.DOBTo)) {
+		return false
+	}
+	return strings.Contains(strings.ToLower(user.Name), strings.ToLower(f.Name))
+}
+
+func (r *memoryUserRepository) sorted(sortOrder models.SortOrder) []int32 {
+	ids := slices.Clone(r.order)
+	slices.SortFunc(ids, func(a, b int32) int {
+		return compareUsers(r.users[a], r.users[b], sortOrder)
+	})
+	return ids
+}
+
+// compareUsers mirrors the SQL ORDER BY, including the id tiebreaker.
+func compareUsers(a, b models.User, sortOrder models.SortOrder) int {
+	column, desc := sortKey(sortOrder)
+	c := 0
+	switch column {
+	case "created_at":
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	case "dob":
+		c = toDate(a.DOB).Compare(toDate(b.DOB))
+	}
+	if c == 0 {
+		c = cmp.Compare(a.ID, b.ID)
+	}
+	if desc {
+		return -c
+	}
+	return c
+}
+
+func (r *memoryUserRepository) Update(ctx context.Context, id int32, name string, dob time.Time) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, err := r.writable(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if r.nameTaken(ctx, name, id) {
+		return nil, ErrDuplicateName
+	}
+
+	user.Name = name
+	user.DOB = toDate(dob)
+	user.UpdatedAt = r.clock.Now()
+	r.users[id] = user
+
+	return &user, nil
+}
+
+func (r *memoryUserRepository) UpdatePartial(ctx context.Context, id int32, name *string, dob *time.Time) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, err := r.writable(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if name != nil {
+		if r.nameTaken(ctx, *name, id) {
+			return nil, ErrDuplicateName
+		}
+		user.Name = *name
+	}
+	if dob != nil {
+		user.DOB = toDate(*dob)
+	}
+	user.UpdatedAt = r.clock.Now()
+	r.users[id] = user
+
+	return &user, nil
+}
+
+func (r *memoryUserRepository) SetTimezone(ctx context.Context, id int32, timezone string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, err := r.writable(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Timezone = timezone
+	user.UpdatedAt = r.clock.Now()
+	r.users[id] = user
+
+	return &user, nil
+}
+
+func (r *memoryUserRepository) SetLocale(ctx context.Context, id int32, locale string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, err := r.writable(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Locale = locale
+	user.UpdatedAt = r.clock.Now()
+	r.users[id] = user
+
+	return &user, nil
+}
+
+func (r *memoryUserRepository) Anonymize(ctx context.Context, id int32, name string, dob time.Time) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, err := r.writable(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if r.nameTaken(ctx, name, id) {
+		return nil, ErrDuplicateName
+	}
+
+	now := r.clock.Now()
+	user.Name = name
+	user.DOB = toDate(dob)
+	user.Timezone = ""
+	user.Locale = ""
+	user.AnonymizedAt = &now
+	user.UpdatedAt = now
+	r.users[id] = user
+
+	return &user, nil
+}
+
+// writable is lookup for the writes an anonymized user refuses.
+func (r *memoryUserRepository) writable(ctx context.Context, id int32) (models.User, error) {
+	user, ok := r.lookup(ctx, id)
+	if !ok {
+		return models.User{}, ErrNotFound
+	}
+	if user.AnonymizedAt != nil {
+		return models.User{}, ErrAnonymized
+	}
+	return user, nil
+}
+
+// Transact snapshots the store and restores it if fn fails. Transactions run
+// one at a time, but writes made outside one while it runs are lost on
+// rollback, so unlike Postgres this is not isolation, only atomicity.
+func (r *memoryUserRepository) Transact(ctx context.Context, fn func(tx UserRepository) error) error {
+	r.txMu.Lock()
+	defer r.txMu.Unlock()
+	return r.atomically(fn)
+}
+
+// atomically runs fn against the transaction's repository and, should fn
+// fail, restores the store as it was before.
+func (r *memoryUserRepository) atomically(fn func(tx UserRepository) error) error {
+	r.mu.RLock()
+	users, order, nextID := maps.Clone(r.users), slices.Clone(r.order), r.nextID
+	r.mu.RUnlock()
+	outbox := r.outbox.snapshot()
+
+	if err := fn(memoryTx{r}); err != nil {
+		r.mu.Lock()
+		r.users, r.order, r.nextID = users, order, nextID
+		r.mu.Unlock()
+		r.outbox.restore(outbox)
+		return err
+	}
+	return nil
+}
+
+func (r *memoryUserRepository) Outbox() OutboxRepository {
+	return r.outbox
+}
+
+// LockTenant has nothing to do: transactions already run one at a time.
+func (r *memoryUserRepository) LockTenant(ctx context.Context) error {
+	return nil
+}
+
+// memoryTx is the repository handed to a transaction. Nested calls already
+// hold txMu, and roll back only their own writes, as a savepoint would.
+type memoryTx struct {
+	*memoryUserRepository
+}
+
+func (tx memoryTx) Transact(ctx context.Context, fn func(tx UserRepository) error) error {
+	return tx.atomically(fn)
+}
+
+func (r *memoryUserRepository) Delete(ctx context.Context, id int32) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.lookup(ctx, id); !ok {
+		return ErrNotFound
+	}
+	delete(r.users, id)
+	i := sort.Search(len(r.order), func(i int) bool { return r.order[i] >= id })
+	r.order = append(r.order[:i], r.order[i+1:]...)
+	return nil
+}
+
+func (r *memoryUserRepository) Count(ctx context.Context, filter UserFilter) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenantID := tenant.FromContext(ctx)
+	var count int64
+	for _, user := range r.users {
+		if user.TenantID == tenantID && filter.matches(user) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memoryUserRepository) CountByDOB(ctx context.Context, dob time.Time, filter UserFilter) (DOBCounts, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenantID, day := tenant.FromContext(ctx), toDate(dob)
+	var counts DOBCounts
+	for _, user := range r.users {
+		if user.TenantID != tenantID || !filter.matches(user) {
+			continue
+		}
+		switch toDate(user.DOB).Compare(day) {
+		case -1:
+			counts.Before++
+		case 0:
+			counts.Same++
+		default:
+			counts.After++
+		}
+	}
+	return counts, nil
+}
+
+func (r *memoryUserRepository) CountByDOBBounds(ctx context.Context, bounds []time.Time) ([]int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenantID := tenant.FromContext(ctx)
+	counts := make([]int64, len(bounds)+1)
+	for _, user := range r.users {
+		if user.TenantID != tenantID {
+			continue
+		}
+		// The bounds fall over time, so those the DOB is on or before come
+		// first and their number is the bucket.
+		bucket := 0
+		for bucket < len(bounds) && !toDate(user.DOB).After(toDate(bounds[bucket])) {
+			bucket++
+		}
+		counts[bucket]++
+	}
+	return counts, nil
+}
+
+func (r *memoryUserRepository) ListByBirthday(ctx context.Context, q BirthdayQuery) ([]models.User, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenantID := tenant.FromContext(ctx)
+	matches := make([]models.User, 0)
+	for _, id := range r.order {
+		user := r.users[id]
+		if user.TenantID == tenantID && id != q.ExceptID && user.AnonymizedAt == nil && slices.Contains(q.Days, monthDayOf(user.DOB)) {
+			matches = append(matches, user)
+		}
+	}
+	start := min(max(int(q.Offset), 0), len(matches))
+	end := min(start+max(int(q.Limit), 0), len(matches))
+	return slices.Clone(matches[start:end]), int64(len(matches)), nil
+}
+
+func (r *memoryUserRepository) BirthdayCollisions(ctx context.Context, limit int32) ([]BirthdayCollision, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenantID := tenant.FromContext(ctx)
+	counts := make(map[MonthDay]int64)
+	for _, user := range r.users {
+		if user.TenantID == tenantID && user.AnonymizedAt == nil {
+			counts[monthDayOf(user.DOB)]++
+		}
+	}
+	collisions := make([]BirthdayCollision, 0)
+	for day, count := range counts {
+		if count > 1 {
+			collisions = append(collisions, BirthdayCollision{MonthDay: day, Count: count})
+		}
+	}
+	slices.SortFunc(collisions, func(a, b BirthdayCollision) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.Month, b.Month); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Day, b.Day)
+	})
+	return collisions[:min(max(int(limit), 0), len(collisions))], nil
+}
+
+// SearchNames is unsupported: the memory store has no trigram index.
+func (r *memoryUserRepository) SearchNames(ctx context.Context, search NameSearch) ([]models.User, int64, error) {
+	return nil, 0, ErrFuzzySearchUnsupported
+}
+
+func (r *memoryUserRepository) ListWithDOBAfter(ctx context.Context, date time.Time) ([]models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenantID := tenant.FromContext(ctx)
+	users := make([]models.User, 0)
+	for _, id := range r.order {
+		if user := r.users[id]; user.TenantID == tenantID && user.DOB.After(date) {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func (r *memoryUserRepository) ListBirthdays(ctx context.Context, now time.Time, defaultZone string) ([]models.User, error) {
+	fallback, err := time.LoadLocation(defaultZone)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenantID := tenant.FromContext(ctx)
+	users := make([]models.User, 0)
+	for _, id := range r.order {
+		user := r.users[id]
+		if user.TenantID != tenantID || user.AnonymizedAt != nil {
+			continue
+		}
+		loc := fallback
+		if user.Timezone != "" {
+			if l, err := time.LoadLocation(user.Timezone); err == nil {
+				loc = l
+			}
+		}
+		// time.Date normalizes Feb 29 of a common year to Mar 1.
+		today := toDate(now.In(loc))
+		birthday := time.Date(today.Year(), user.DOB.Month(), user.DOB.Day(), 0, 0, 0, 0, time.UTC)
+		if user.DOB.Before(today) && birthday.Equal(today) {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func (r *memoryUserRepository) ListByDOB(ctx context.Context, latestFirst bool, limit int32) ([]models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenantID := tenant.FromContext(ctx)
+	users := make([]models.User, 0)
+	for _, id := range r.order {
+		if user := r.users[id]; user.TenantID == tenantID && user.AnonymizedAt == nil {
+			users = append(users, user)
+		}
+	}
+	slices.SortStableFunc(users, func(a, b models.User) int {
+		c := toDate(a.DOB).Compare(toDate(b.DOB))
+		if latestFirst {
+			c = -c
+		}
+		if c == 0 {
+			c = cmp.Compare(a.ID, b.ID)
+		}
+		return c
+	})
+	return users[:min(max(int(limit), 0), len(users))], nil
+}
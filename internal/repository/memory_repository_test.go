@@ -0,0 +1,250 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+	"github.com/srinivasarynh/age_calculator/internal/testutil"
+)
+
+func TestMemoryRepositoryNeverReturnsNilWithoutError(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(clock.Fixed(time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC)))
+	testutil.AssertNoNilResults(t, repo)
+}
+
+func TestMemoryRepositoryTenantIsolation(t *testing.T) {
+	testutil.AssertTenantIsolation(t, repository.NewMemoryUserRepository(clock.Real()))
+}
+
+func TestMemoryRepositoryTransactions(t *testing.T) {
+	testutil.AssertTransactions(t, repository.NewMemoryUserRepository(clock.Real()))
+}
+
+func TestMemoryRepositoryOutbox(t *testing.T) {
+	testutil.AssertOutbox(t, repository.NewMemoryUserRepository(clock.Real()))
+}
+
+func TestMemoryRepositoryAnonymization(t *testing.T) {
+	testutil.AssertAnonymization(t, repository.NewMemoryUserRepository(clock.Real()))
+}
+
+// stepClock advances by step on every call so each row gets a distinct
+// created_at.
+type stepClock struct {
+	now  time.Time
+	step time.Duration
+}
+
+func (c *stepClock) Now() time.Time {
+	c.now = c.now.Add(c.step)
+	return c.now
+}
+
+func TestMemoryRepositoryListSort(t *testing.T) {
+	ctx := context.Background()
+	// A negative step makes created_at order the reverse of id order.
+	repo := repository.NewMemoryUserRepository(&stepClock{now: time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC), step: -time.Minute})
+	dob := time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, name := range []string{"Alice", "Bob", "Carol"} {
+		if _, err := repo.Create(ctx, name, dob); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		sort models.SortOrder
+		want []int32
+	}{
+		{sort: models.SortOrder{Field: "id"}, want: []int32{1, 2, 3}},
+		{sort: models.SortOrder{Field: "id", Desc: true}, want: []int32{3, 2, 1}},
+		{sort: models.SortOrder{Field: "created_at"}, want: []int32{3, 2, 1}},
+		{sort: models.SortOrder{Field: "created_at", Desc: true}, want: []int32{1, 2, 3}},
+	}
+	for _, tt := range tests {
+		users, err := repo.List(ctx, repository.ListQuery{Limit: 2, Offset: 1, Sort: tt.sort})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []int32
+		for _, u := range users {
+			got = append(got, u.ID)
+		}
+		if !slices.Equal(got, tt.want[1:]) {
+			t.Errorf("List(%+v) ids = %v, want %v", tt.sort, got, tt.want[1:])
+		}
+	}
+}
+
+// With a fixed clock every created_at ties, so only the id tiebreaker keeps
+// keyset pages from overlapping.
+func TestMemoryRepositoryKeysetTiebreaker(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryUserRepository(clock.Fixed(time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC)))
+	dob := time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		if _, err := repo.Create(ctx, "User", dob); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, desc := range []bool{false, true} {
+		sort := models.SortOrder{Field: "created_at", Desc: desc}
+		var got []int32
+		var after *repository.Keyset
+		for page := 0; page < 5; page++ {
+			users, err := repo.List(ctx, repository.ListQuery{Limit: 2, Sort: sort, After: after})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(users) == 0 {
+				break
+			}
+			for _, u := range users {
+				got = append(got, u.ID)
+			}
+			last := users[len(users)-1]
+			after = &repository.Keyset{ID: last.ID, CreatedAt: last.CreatedAt}
+		}
+
+		want := []int32{1, 2, 3, 4, 5}
+		if desc {
+			slices.Reverse(want)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("desc=%v ids = %v, want %v", desc, got, want)
+		}
+	}
+}
+
+// Age ascending is dob descending, and users sharing a DOB keep to the id
+// tiebreaker in that same direction on every page.
+func TestMemoryRepositorySortByAge(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryUserRepository(clock.Fixed(time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC)))
+	for _, year := range []int{1990, 2000, 1990, 1980, 2000} {
+		if _, err := repo.Create(ctx, "User", time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for desc, want := range map[bool][]int32{false: {5, 2, 3, 1, 4}, true: {4, 1, 3, 2, 5}} {
+		sort := models.SortOrder{Field: "age", Desc: desc}
+		users, err := repo.List(ctx, repository.ListQuery{Limit: 10, Sort: sort})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := userIDs(users); !slices.Equal(got, want) {
+			t.Errorf("desc=%v offset ids = %v, want %v", desc, got, want)
+		}
+
+		var got []int32
+		var after *repository.Keyset
+		for page := 0; page < 5; page++ {
+			users, err := repo.List(ctx, repository.ListQuery{Limit: 2, Sort: sort, After: after})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(users) == 0 {
+				break
+			}
+			got = append(got, userIDs(users)...)
+			last := users[len(users)-1]
+			after = &repository.Keyset{ID: last.ID, DOB: last.DOB}
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("desc=%v keyset ids = %v, want %v", desc, got, want)
+		}
+	}
+}
+
+func TestMemoryRepositoryListByDOB(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryUserRepository(clock.Fixed(time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC)))
+	for _, year := range []int{1990, 2000, 1980, 2000, 1970} {
+		if _, err := repo.Create(ctx, "User", time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := repo.Anonymize(ctx, 5, "Deleted User", time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		latestFirst bool
+		limit       int32
+		want        []int32
+	}{
+		{latestFirst: false, limit: 2, want: []int32{3, 1}},
+		{latestFirst: false, limit: 10, want: []int32{3, 1, 2, 4}},
+		{latestFirst: true, limit: 2, want: []int32{2, 4}},
+		{latestFirst: true, limit: 10, want: []int32{2, 4, 1, 3}},
+	}
+	for _, tt := range tests {
+		users, err := repo.ListByDOB(ctx, tt.latestFirst, tt.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := userIDs(users); !slices.Equal(got, tt.want) {
+			t.Errorf("ListByDOB(%v, %d) ids = %v, want %v", tt.latestFirst, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func userIDs(users []models.User) []int32 {
+	ids := make([]int32, len(users))
+	for i, u := range users {
+		ids[i] = u.ID
+	}
+	return ids
+}
+
+func TestMemoryRepositoryUniqueNames(t *testing.T) {
+	ctx := context.Background()
+	dob := time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for _, unique := range []bool{false, true} {
+		var opts []repository.MemoryOption
+		if unique {
+			opts = append(opts, repository.WithUniqueNames())
+		}
+		repo := repository.NewMemoryUserRepository(clock.Real(), opts...)
+		alice, _ := repo.Create(ctx, "Alice", dob)
+		bob, _ := repo.Create(ctx, "Bob", dob)
+		taken := "BOB"
+
+		_, createErr := repo.Create(ctx, "alice", dob)
+		_, updateErr := repo.Update(ctx, alice.ID, "bob", dob)
+		_, patchErr := repo.UpdatePartial(ctx, alice.ID, &taken, nil)
+		for op, err := range map[string]error{"Create": createErr, "Update": updateErr, "UpdatePartial": patchErr} {
+			if unique && !errors.Is(err, repository.ErrDuplicateName) {
+				t.Errorf("unique %s of a taken name: err = %v, want ErrDuplicateName", op, err)
+			}
+			if !unique && err != nil {
+				t.Errorf("%s of a shared name: %v", op, err)
+			}
+		}
+
+		// A user keeps its own name, and another tenant may reuse it.
+		if _, err := repo.Update(ctx, bob.ID, "bob", dob); err != nil {
+			t.Errorf("unique=%v: renaming a user to its own name: %v", unique, err)
+		}
+		if _, err := repo.Create(tenant.WithID(ctx, "globex"), "Alice", dob); err != nil {
+			t.Errorf("unique=%v: same name in another tenant: %v", unique, err)
+		}
+
+		got, err := repo.GetByName(ctx, "ALICE")
+		if err != nil || !strings.EqualFold(got.Name, "alice") || got.TenantID != tenant.Default {
+			t.Errorf("unique=%v: GetByName(ALICE) = %+v, %v", unique, got, err)
+		}
+		if _, err := repo.GetByName(ctx, "Ali"); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("unique=%v: GetByName of a prefix: err = %v, want ErrNotFound", unique, err)
+		}
+	}
+}
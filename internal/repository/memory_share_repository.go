@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+)
+
+type memoryShareRepository struct {
+	mu     sync.Mutex
+	clock  clock.Clock
+	nextID int64
+	shares []models.ShareToken
+}
+
+func NewMemoryShareRepository(c clock.Clock) ShareRepository {
+	return &memoryShareRepository{clock: c, nextID: 1}
+}
+
+func (r *memoryShareRepository) Create(ctx context.Context, userID int32, tokenHash []byte, expiresAt time.Time) (*models.ShareToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	share := models.ShareToken{
+		ID:        r.nextID,
+		TenantID:  tenant.FromContext(ctx),
+		UserID:    userID,
+		TokenHash: slices.Clone(tokenHash),
+		CreatedAt: r.clock.Now(),
+		ExpiresAt: expiresAt,
+	}
+	r.shares = append(r.shares, share)
+	r.nextID++
+	return copyShare(share), nil
+}
+
+func (r *memoryShareRepository) ListForUser(ctx context.Context, userID int32) ([]models.ShareToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	shares := make([]models.ShareToken, 0)
+	for _, share := range r.shares {
+		if share.TenantID == tenant.FromContext(ctx) && share.UserID == userID {
+			shares = append(shares, *copyShare(share))
+		}
+	}
+	return shares, nil
+}
+
+func (r *memoryShareRepository) Revoke(ctx context.Context, userID int32, id int64, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.shares {
+		share := &r.shares[i]
+		if share.ID == id && share.TenantID == tenant.FromContext(ctx) && share.UserID == userID && share.RevokedAt == nil {
+			share.RevokedAt = &at
+			return nil
+		}
+	}
+	return ErrShareNotFound
+}
+
+func (r *memoryShareRepository) GetByHash(ctx context.Context, tokenHash []byte) (*models.ShareToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, share := range r.shares {
+		if bytes.Equal(share.TokenHash, tokenHash) {
+			return copyShare(share), nil
+		}
+	}
+	return nil, ErrShareNotFound
+}
+
+func copyShare(share models.ShareToken) *models.ShareToken {
+	share.TokenHash = slices.Clone(share.TokenHash)
+	if share.RevokedAt != nil {
+		at := *share.RevokedAt
+		share.RevokedAt = &at
+	}
+	return &share
+}
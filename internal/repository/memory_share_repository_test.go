@@ -0,0 +1,13 @@
+package repository_test
+
+import (
+	"testing"
+
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/testutil"
+)
+
+func TestMemoryShareRepository(t *testing.T) {
+	testutil.AssertShareRepository(t, repository.NewMemoryShareRepository(clock.Real()), 1)
+}
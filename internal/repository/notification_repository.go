@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"go.uber.org/zap"
+)
+
+// NotificationRepository remembers which users have been sent their birthday
+// notification, one marker per user and year, so that every instance can run
+// the scheduler without anyone being notified twice.
+type NotificationRepository interface {
+	// MarkNotified records that userID's birthday in year was notified on the
+	// date on. It reports false when the marker already existed; of concurrent
+	// callers only one gets true.
+	MarkNotified(ctx context.Context, userID int32, year int, on time.Time) (bool, error)
+	// Unmark removes the marker, so the birthday is notified again on the
+	// next run.
+	Unmark(ctx context.Context, userID int32, year int) error
+	// ListForUser returns userID's markers, oldest year first.
+	ListForUser(ctx context.Context, userID int32) ([]models.BirthdayNotification, error)
+	// DeleteForUser removes every marker of userID.
+	DeleteForUser(ctx context.Context, userID int32) error
+}
+
+type notificationRepository struct {
+	db     *sql.DB
+	logger *zap.Logger
+}
+
+func NewNotificationRepository(db *sql.DB, logger *zap.Logger) NotificationRepository {
+	return &notificationRepository{db: db, logger: logger}
+}
+
+func (r *notificationRepository) MarkNotified(ctx context.Context, userID int32, year int, on time.Time) (bool, error) {
+	query := `INSERT INTO birthday_notifications (user_id, year, notified_on) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`
+
+	result, err := r.db.ExecContext(ctx, query, userID, year, dateParam(on))
+	if err != nil {
+		r.logger.Error("Failed to mark birthday notified", zap.Error(err), zap.Int32("id", userID))
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+func (r *notificationRepository) Unmark(ctx context.Context, userID int32, year int) error {
+	query := `DELETE FROM birthday_notifications WHERE user_id = $1 AND year = $2`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, year); err != nil {
+		r.logger.Error("Failed to unmark birthday notified", zap.Error(err), zap.Int32("id", userID))
+		return err
+	}
+	return nil
+}
+
+func (r *notificationRepository) ListForUser(ctx context.Context, userID int32) ([]models.BirthdayNotification, error) {
+	query := `SELECT year, notified_on FROM birthday_notifications WHERE user_id = $1 ORDER BY year`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		r.logger.Error("Failed to list birthday notifications", zap.Error(err), zap.Int32("id", userID))
+		return nil, err
+	}
+	defer rows.Close()
+
+	markers := make([]models.BirthdayNotification, 0)
+	for rows.Next() {
+		var marker models.BirthdayNotification
+		var on time.Time
+		if err := rows.Scan(&marker.Year, &on); err != nil {
+			return nil, err
+		}
+		marker.NotifiedOn = on.Format("2006-01-02")
+		markers = append(markers, marker)
+	}
+	return markers, rows.Err()
+}
+
+func (r *notificationRepository) DeleteForUser(ctx context.Context, userID int32) error {
+	query := `DELETE FROM birthday_notifications WHERE user_id = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, userID); err != nil {
+		r.logger.Error("Failed to delete birthday notifications", zap.Error(err), zap.Int32("id", userID))
+		return err
+	}
+	return nil
+}
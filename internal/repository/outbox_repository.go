@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/events"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"go.uber.org/zap"
+)
+
+// ErrOutboxEventNotFound is returned by Claim when no event is due, and by
+// Replay for an id that is not dead-lettered.
+var ErrOutboxEventNotFound = errors.New("repository: outbox event not found")
+
+// OutboxRepository is the events outbox: events stored with the change they
+// report and delivered afterwards, at least once. Unlike the user
+// repository it spans every tenant; each event carries its own.
+type OutboxRepository interface {
+	// Enqueue stores event, due at once.
+	Enqueue(ctx context.Context, event events.Event) error
+	// Claim takes the event that has been due longest by now and leaves it
+	// due again at now plus lease, so that concurrent dispatchers claim
+	// different events and one whose dispatcher died before marking it is
+	// claimed again. Each claim counts as an attempt.
+	Claim(ctx context.Context, now time.Time, lease time.Duration) (*models.OutboxEvent, error)
+	MarkSent(ctx context.Context, id int64, at time.Time) error
+	// MarkFailed records why the last attempt failed and when to try again.
+	MarkFailed(ctx context.Context, id int64, reason string, retryAt time.Time) error
+	// MarkDead records why the last attempt failed and gives up on the event.
+	MarkDead(ctx context.Context, id int64, reason string, at time.Time) error
+	// ListDead returns up to limit dead-lettered events, oldest first.
+	ListDead(ctx context.Context, limit int32) ([]models.OutboxEvent, error)
+	// Replay makes a dead-lettered event due again at, with no attempts.
+	Replay(ctx context.Context, id int64, at time.Time) error
+	// DeleteSent removes the events delivered before before.
+	DeleteSent(ctx context.Context, before time.Time) (int64, error)
+}
+
+const outboxColumns = `id, tenant_id, type, payload, created_at, attempts, next_attempt_at, last_error, sent_at, dead_at`
+
+type outboxRepository struct {
+	db     querier
+	logger *zap.Logger
+}
+
+func NewOutboxRepository(db *sql.DB, logger *zap.Logger) OutboxRepository {
+	return &outboxRepository{db: db, logger: logger}
+}
+
+func (r *outboxRepository) Enqueue(ctx context.Context, event events.Event) error {
+	query := `INSERT INTO events_outbox (tenant_id, type, payload, created_at, next_attempt_at) VALUES ($1, $2, $3, $4, $4)`
+
+	payload, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+	if _, err := r.db.ExecContext(ctx, query, event.TenantID, event.Type, payload, event.At.UTC()); err != nil {
+		r.logger.Error("Failed to enqueue event", zap.Error(err), zap.String("type", event.Type))
+		return err
+	}
+	return nil
+}
+
+func (r *outboxRepository) Claim(ctx context.Context, now time.Time, lease time.Duration) (*models.OutboxEvent, error) {
+	query := `UPDATE events_outbox SET attempts = attempts + 1, next_attempt_at = $2
+		WHERE id = (SELECT id FROM events_outbox WHERE sent_at IS NULL AND dead_at IS NULL AND next_attempt_at <= $1
+			ORDER BY next_attempt_at, id FOR UPDATE SKIP LOCKED LIMIT 1)
+		RETURNING ` + outboxColumns
+
+	var event models.OutboxEvent
+	if err := scanOutboxEvent(r.db.QueryRowContext(ctx, query, now.UTC(), now.Add(lease).UTC()), &event); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOutboxEventNotFound
+		}
+		r.logger.Error("Failed to claim event", zap.Error(err))
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (r *outboxRepository) MarkSent(ctx context.Context, id int64, at time.Time) error {
+	query := `UPDATE events_outbox SET sent_at = $1, last_error = '' WHERE id = $2`
+
+	if _, err := r.db.ExecContext(ctx, query, at.UTC(), id); err != nil {
+		r.logger.Error("Failed to mark event sent", zap.Error(err), zap.Int64("id", id))
+		return err
+	}
+	return nil
+}
+
+func (r *outboxRepository) MarkFailed(ctx context.Context, id int64, reason string, retryAt time.Time) error {
+	query := `UPDATE events_outbox SET last_error = $1, next_attempt_at = $2 WHERE id = $3`
+
+	if _, err := r.db.ExecContext(ctx, query, reason, retryAt.UTC(), id); err != nil {
+		r.logger.Error("Failed to mark event failed", zap.Error(err), zap.Int64("id", id))
+		return err
+	}
+	return nil
+}
+
+func (r *outboxRepository) MarkDead(ctx context.Context, id int64, reason string, at time.Time) error {
+	query := `UPDATE events_outbox SET last_error = $1, dead_at = $2 WHERE id = $3`
+
+	if _, err := r.db.ExecContext(ctx, query, reason, at.UTC(), id); err != nil {
+		r.logger.Error("Failed to dead-letter event", zap.Error(err), zap.Int64("id", id))
+		return err
+	}
+	return nil
+}
+
+func (r *outboxRepository) ListDead(ctx context.Context, limit int32) ([]models.OutboxEvent, error) {
+	query := `SELECT ` + outboxColumns + ` FROM events_outbox WHERE dead_at IS NOT NULL ORDER BY id LIMIT $1`
+
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		r.logger.Error("Failed to list dead events", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]models.OutboxEvent, 0)
+	for rows.Next() {
+		var event models.OutboxEvent
+		if err := scanOutboxEvent(rows, &event); err != nil {
+			return nil, err
+		}
+		list = append(list, event)
+	}
+	return list, rows.Err()
+}
+
+func (r *outboxRepository) Replay(ctx context.Context, id int64, at time.Time) error {
+	query := `UPDATE events_outbox SET attempts = 0, next_attempt_at = $1, dead_at = NULL WHERE id = $2 AND dead_at IS NOT NULL`
+
+	result, err := r.db.ExecContext(ctx, query, at.UTC(), id)
+	if err != nil {
+		r.logger.Error("Failed to replay event", zap.Error(err), zap.Int64("id", id))
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrOutboxEventNotFound
+	}
+	return nil
+}
+
+func (r *outboxRepository) DeleteSent(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM events_outbox WHERE sent_at < $1`, before.UTC())
+	if err != nil {
+		r.logger.Error("Failed to delete sent events", zap.Error(err))
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func scanOutboxEvent(row rowScanner, event *models.OutboxEvent) error {
+	var payload []byte
+	if err := row.Scan(&event.ID, &event.TenantID, &event.Type, &payload, &event.CreatedAt, &event.Attempts,
+		&event.NextAttemptAt, &event.LastError, &event.SentAt, &event.DeadAt); err != nil {
+		return err
+	}
+	event.Payload = json.RawMessage(payload)
+	return nil
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+	"go.uber.org/zap"
+)
+
+// ErrShareNotFound is returned for a token hash nobody was given, and by
+// Revoke for a share that is missing, foreign or already revoked.
+var ErrShareNotFound = errors.New("repository: share not found")
+
+// ShareRepository stores share tokens. Create, ListForUser and Revoke act on
+// the tenant carried by ctx; GetByHash spans every tenant, since whoever
+// holds a token sends no tenant, and reports the one the token belongs to.
+type ShareRepository interface {
+	Create(ctx context.Context, userID int32, tokenHash []byte, expiresAt time.Time) (*models.ShareToken, error)
+	// ListForUser returns every share of userID, revoked and expired ones
+	// included, oldest first.
+	ListForUser(ctx context.Context, userID int32) ([]models.ShareToken, error)
+	Revoke(ctx context.Context, userID int32, id int64, at time.Time) error
+	GetByHash(ctx context.Context, tokenHash []byte) (*models.ShareToken, error)
+}
+
+const shareColumns = `id, tenant_id, user_id, token_hash, created_at, expires_at, revoked_at`
+
+type shareRepository struct {
+	db     *sql.DB
+	logger *zap.Logger
+}
+
+func NewShareRepository(db *sql.DB, logger *zap.Logger) ShareRepository {
+	return &shareRepository{db: db, logger: logger}
+}
+
+func (r *shareRepository) Create(ctx context.Context, userID int32, tokenHash []byte, expiresAt time.Time) (*models.ShareToken, error) {
+	query := `INSERT INTO share_tokens (tenant_id, user_id, token_hash, expires_at) VALUES ($1, $2, $3, $4) RETURNING ` + shareColumns
+
+	var share models.ShareToken
+	if err := scanShare(r.db.QueryRowContext(ctx, query, tenant.FromContext(ctx), userID, tokenHash, expiresAt.UTC()), &share); err != nil {
+		r.logger.Error("Failed to create share", zap.Error(err), zap.Int32("id", userID))
+		return nil, err
+	}
+	return &share, nil
+}
+
+func (r *shareRepository) ListForUser(ctx context.Context, userID int32) ([]models.ShareToken, error) {
+	query := `SELECT ` + shareColumns + ` FROM share_tokens WHERE tenant_id = $1 AND user_id = $2 ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query, tenant.FromContext(ctx), userID)
+	if err != nil {
+		r.logger.Error("Failed to list shares", zap.Error(err), zap.Int32("id", userID))
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := make([]models.ShareToken, 0)
+	for rows.Next() {
+		var share models.ShareToken
+		if err := scanShare(rows, &share); err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+	return shares, rows.Err()
+}
+
+func (r *shareRepository) Revoke(ctx context.Context, userID int32, id int64, at time.Time) error {
+	query := `UPDATE share_tokens SET revoked_at = $1 WHERE tenant_id = $2 AND user_id = $3 AND id = $4 AND revoked_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, at.UTC(), tenant.FromContext(ctx), userID, id)
+	if err != nil {
+		r.logger.Error("Failed to revoke share", zap.Error(err), zap.Int64("share_id", id))
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrShareNotFound
+	}
+	return nil
+}
+
+func (r *shareRepository) GetByHash(ctx context.Context, tokenHash []byte) (*models.ShareToken, error) {
+	query := `SELECT ` + shareColumns + ` FROM share_tokens WHERE token_hash = $1`
+
+	var share models.ShareToken
+	if err := scanShare(r.db.QueryRowContext(ctx, query, tokenHash), &share); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrShareNotFound
+		}
+		r.logger.Error("Failed to look up share", zap.Error(err))
+		return nil, err
+	}
+	return &share, nil
+}
+
+func scanShare(row rowScanner, share *models.ShareToken) error {
+	return row.Scan(&share.ID, &share.TenantID, &share.UserID, &share.TokenHash, &share.CreatedAt, &share.ExpiresAt, &share.RevokedAt)
+}
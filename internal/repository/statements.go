@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+
+	"github.com/lib/pq"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"go.uber.org/zap"
+)
+
+// StatementPreparer is a repository that can prepare its hottest queries
+// before the first request needs them.
+type StatementPreparer interface {
+	// PrepareStatements prepares the queries, which are then run prepared
+	// until the server loses them. On error none are prepared and every
+	// query runs as before.
+	PrepareStatements(ctx context.Context) error
+}
+
+// invalidStatementName is the SQLSTATE of a prepared statement the server
+// does not have, as when a pooler hands the session to another connection.
+const invalidStatementName = "26000"
+
+// statements holds the prepared statements of a user repository by query,
+// shared with the repositories its transactions are bound to.
+type statements struct {
+	mu      sync.RWMutex
+	byQuery map[string]*sql.Stmt
+}
+
+func (s *statements) get(query string) *sql.Stmt {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.byQuery[query]
+}
+
+// replace closes the statements held until now, once the queries running
+// on them are done.
+func (s *statements) replace(byQuery map[string]*sql.Stmt) {
+	s.mu.Lock()
+	old := s.byQuery
+	s.byQuery = byQuery
+	s.mu.Unlock()
+	for _, stmt := range old {
+		stmt.Close()
+	}
+}
+
+// hotQueries are the queries PrepareStatements prepares: GetById, and List
+// and Count of a tenant's users unfiltered, in the default order.
+func hotQueries() []string {
+	ctx := context.Background()
+	listConditions, _ := filterConditions(ctx, UserFilter{}, []any{nil, nil})
+	countConditions, _ := filterConditions(ctx, UserFilter{}, nil)
+	return []string{getByIDQuery, listQuery(listConditions, models.SortOrder{}), countQuery(countConditions)}
+}
+
+func (r *userRepository) PrepareStatements(ctx context.Context) error {
+	if r.pool == nil {
+		return errors.New("repository: statements cannot be prepared inside a transaction")
+	}
+	prepared := make(map[string]*sql.Stmt)
+	for _, query := range hotQueries() {
+		stmt, err := r.pool.PrepareContext(ctx, query)
+		if err != nil {
+			for _, stmt := range prepared {
+				stmt.Close()
+			}
+			return err
+		}
+		prepared[query] = stmt
+	}
+	r.stmts.replace(prepared)
+	return nil
+}
+
+// prepared is query's prepared statement, bound to r's transaction if it is
+// in one, or nil.
+func (r *userRepository) prepared(ctx context.Context, query string) *sql.Stmt {
+	stmt := r.stmts.get(query)
+	if stmt == nil {
+		return nil
+	}
+	if tx, ok := r.db.(*sql.Tx); ok {
+		return tx.StmtContext(ctx, stmt)
+	}
+	return stmt
+}
+
+// queryRow runs query, prepared if it can, and reads its row with scan.
+func (r *userRepository) queryRow(ctx context.Context, scan func(*sql.Row) error, query string, args ...any) error {
+	if stmt := r.prepared(ctx, query); stmt != nil {
+		err := scan(stmt.QueryRowContext(ctx, args...))
+		if !r.statementLost(err) {
+			return err
+		}
+	}
+	return scan(r.db.QueryRowContext(ctx, query, args...))
+}
+
+// query runs query, prepared if it can.
+func (r *userRepository) query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if stmt := r.prepared(ctx, query); stmt != nil {
+		rows, err := stmt.QueryContext(ctx, args...)
+		if !r.statementLost(err) {
+			return rows, err
+		}
+	}
+	return r.db.QueryContext(ctx, query, args...)
+}
+
+// statementLost reports whether err says the server lost a prepared
+// statement, and if so drops them all so queries run unprepared from now on.
+// Inside a transaction, which the error has aborted, it reports false.
+func (r *userRepository) statementLost(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != invalidStatementName {
+		return false
+	}
+	r.logger.Warn("Prepared statement lost, running queries unprepared", zap.Error(err))
+	r.stmts.replace(nil)
+	return r.pool != nil
+}
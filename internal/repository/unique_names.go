@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/srinivasarynh/age_calculator/internal/migrate"
+)
+
+// uniqueNameIndex enforces UNIQUE_NAMES. It exists only while the flag is on,
+// so it is kept by UniqueNamesStep rather than by a numbered migration.
+const uniqueNameIndex = "idx_users_tenant_lower_name"
+
+// maxReportedDuplicates caps how many names a DuplicateNamesError lists.
+const maxReportedDuplicates = 20
+
+// DuplicateName is a name, lowercased, that several users of a tenant share.
+type DuplicateName struct {
+	TenantID string
+	Name     string
+	IDs      []int64
+}
+
+// DuplicateNamesError stops UNIQUE_NAMES from being enforced while existing
+// users already share a name. Nothing is renamed or deleted on their behalf.
+type DuplicateNamesError struct {
+	Names []DuplicateName
+	// More is set when there were more names than Names lists.
+	More bool
+}
+
+func (e *DuplicateNamesError) Error() string {
+	var b strings.Builder
+	b.WriteString("users share a name, so UNIQUE_NAMES cannot be enforced; rename or delete all but one user of each, then migrate again:")
+	for _, d := range e.Names {
+		ids := make([]string, len(d.IDs))
+		for i, id := range d.IDs {
+			ids[i] = fmt.Sprint(id)
+		}
+		fmt.Fprintf(&b, " %q in tenant %q (ids %s);", d.Name, d.TenantID, strings.Join(ids, ", "))
+	}
+	if e.More {
+		b.WriteString(" and more")
+	}
+	return strings.TrimSuffix(b.String(), ";")
+}
+
+// UniqueNamesStep creates the case-insensitive unique index on users' names
+// when enabled and drops it otherwise, so the schema follows UNIQUE_NAMES on
+// every migrate up. Creating it fails with a *DuplicateNamesError when users
+// already share a name.
+func UniqueNamesStep(enabled bool) migrate.Step {
+	if !enabled {
+		return migrate.Step{Name: "unique_names", SQL: `DROP INDEX IF EXISTS ` + uniqueNameIndex}
+	}
+	return migrate.Step{
+		Name:  "unique_names",
+		Check: checkDuplicateNames,
+		SQL:   `CREATE UNIQUE INDEX IF NOT EXISTS ` + uniqueNameIndex + ` ON users (tenant_id, lower(name))`,
+	}
+}
+
+func checkDuplicateNames(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, `SELECT tenant_id, lower(name), array_agg(id ORDER BY id) FROM users
+		GROUP BY tenant_id, lower(name) HAVING COUNT(*) > 1 ORDER BY tenant_id, lower(name) LIMIT $1`, maxReportedDuplicates+1)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var dup DuplicateNamesError
+	for rows.Next() {
+		var d DuplicateName
+		var ids pq.Int64Array
+		if err := rows.Scan(&d.TenantID, &d.Name, &ids); err != nil {
+			return err
+		}
+		d.IDs = ids
+		dup.Names = append(dup.Names, d)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(dup.Names) == 0 {
+		return nil
+	}
+	if len(dup.Names) > maxReportedDuplicates {
+		dup.Names, dup.More = dup.Names[:maxReportedDuplicates], true
+	}
+	return &dup
+}
@@ -3,45 +3,300 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
 	"go.uber.org/zap"
 )
 
+// ErrNotFound is returned by GetById, Update and Delete when no row matches.
+// A row owned by another tenant does not match, so probing ids across tenants
+// cannot tell a foreign user from a missing one.
+var ErrNotFound = errors.New("repository: user not found")
+
+// ErrFuzzySearchUnsupported is returned by SearchNames when the store cannot
+// rank by trigram similarity, as a Postgres without the pg_trgm extension.
+var ErrFuzzySearchUnsupported = errors.New("repository: fuzzy name search unsupported")
+
+// ErrAnonymized is returned by Update, UpdatePartial, SetTimezone, SetLocale
+// and Anonymize for a user that has been anonymized, which takes no further
+// changes.
+var ErrAnonymized = errors.New("repository: user anonymized")
+
+// ErrDuplicateName is returned by Create, Update and UpdatePartial when names
+// must be unique and another user of the tenant already has the name, in any
+// case.
+var ErrDuplicateName = errors.New("repository: name already in use")
+
+// UserRepository never returns a nil result with a nil error; a missing row is
+// always ErrNotFound. Every method acts only on the rows of the tenant carried
+// by ctx (see tenant.FromContext).
+//
+//go:generate mockery --name UserRepository --output ../mocks --outpkg mocks --filename user_repository.go --with-expecter
 type UserRepository interface {
 	Create(ctx context.Context, name string, dob time.Time) (*models.User, error)
 	GetById(ctx context.Context, id int32) (*models.User, error)
-	List(ctx context.Context, limit, offset int32) ([]models.User, error)
+	// GetByName matches the whole name case-insensitively. Should several
+	// users match, because names are not unique, the oldest is returned.
+	GetByName(ctx context.Context, name string) (*models.User, error)
+	// FindRecent returns the oldest user with exactly name and dob created
+	// no more than within ago. Anonymized users are left out.
+	FindRecent(ctx context.Context, name string, dob time.Time, within time.Duration) (*models.User, error)
+	// FindByNameDOB returns the oldest user with dob whose whole name matches
+	// case-insensitively: the user an import row describes. Anonymized users
+	// are left out.
+	FindByNameDOB(ctx context.Context, name string, dob time.Time) (*models.User, error)
+	List(ctx context.Context, query ListQuery) ([]models.User, error)
 	Update(ctx context.Context, id int32, name string, dob time.Time) (*models.User, error)
+	// UpdatePartial changes only the fields that are non-nil.
+	UpdatePartial(ctx context.Context, id int32, name *string, dob *time.Time) (*models.User, error)
+	// SetTimezone stores the user's IANA zone name; "" reverts the user to
+	// the server's default zone.
+	SetTimezone(ctx context.Context, id int32, timezone string) (*models.User, error)
+	// SetLocale stores the user's BCP 47 tag; "" clears it.
+	SetLocale(ctx context.Context, id int32, locale string) (*models.User, error)
+	// Anonymize overwrites the user's name and DOB, clears the timezone and
+	// locale and marks the user anonymized for good.
+	Anonymize(ctx context.Context, id int32, name string, dob time.Time) (*models.User, error)
 	Delete(ctx context.Context, id int32) error
-	Count(ctx context.Context) (int64, error)
+	Count(ctx context.Context, filter UserFilter) (int64, error)
+	// CountByDOB counts the users matching filter born before, on and after
+	// dob.
+	CountByDOB(ctx context.Context, dob time.Time, filter UserFilter) (DOBCounts, error)
+	// CountByDOBBounds splits the users at bounds, DOBs from the latest to
+	// the earliest: the first count is of those born after bounds[0], each
+	// next one of those born on or before one bound and after the next, and
+	// the last of those born on or before the last bound.
+	CountByDOBBounds(ctx context.Context, bounds []time.Time) ([]int64, error)
+	ListWithDOBAfter(ctx context.Context, date time.Time) ([]models.User, error)
+	// ListBirthdays returns, in id order, the users whose birthday it is at
+	// the instant now on the calendar of their own zone, or of defaultZone
+	// for users without one. A Feb 29 birthday falls on Mar 1 in common
+	// years. Anonymized users have no birthday.
+	ListBirthdays(ctx context.Context, now time.Time, defaultZone string) ([]models.User, error)
+	// ListByDOB returns the first limit users in DOB order, earliest first
+	// unless latestFirst, with ties in id order. Anonymized users, whose DOB
+	// has been moved, are left out.
+	ListByDOB(ctx context.Context, latestFirst bool, limit int32) ([]models.User, error)
+	// ListByBirthday returns one page of the users born on any of q.Days, in
+	// id order, and how many there are in all. Anonymized users are left out.
+	ListByBirthday(ctx context.Context, q BirthdayQuery) ([]models.User, int64, error)
+	// BirthdayCollisions returns up to limit of the birthdays shared by more
+	// than one user, most shared first and then in calendar order.
+	// Anonymized users are left out.
+	BirthdayCollisions(ctx context.Context, limit int32) ([]BirthdayCollision, error)
+	// SearchNames returns one page of the users ranked by name similarity,
+	// and the number of matches when search.WithTotal is set.
+	SearchNames(ctx context.Context, search NameSearch) ([]models.User, int64, error)
+	// Transact runs fn against a repository bound to one transaction, which
+	// commits if fn returns nil and rolls back otherwise. Inside fn, use only
+	// the repository it is given. A nested call runs in a savepoint of the
+	// outer transaction: its failure undoes only its own writes and leaves
+	// the outer transaction usable, even after a failed statement.
+	Transact(ctx context.Context, fn func(tx UserRepository) error) error
+	// Outbox is the events outbox of the same store. Inside Transact it
+	// joins the transaction, so an event enqueued there is kept only if the
+	// transaction commits.
+	Outbox() OutboxRepository
+	// LockTenant holds, until the transaction it is called in ends, a lock
+	// that serializes writers checking the tenant's user count, so two of
+	// them cannot both take its last place.
+	LockTenant(ctx context.Context) error
+}
+
+// UserFilter narrows List and Count; the zero value matches every user.
+type UserFilter struct {
+	// Name matches case-insensitively anywhere in the user's name.
+	Name string
+	// DOBFrom and DOBTo bound the DOB inclusively; a zero bound is open.
+	DOBFrom time.Time
+	DOBTo   time.Time
+}
+
+// MonthDay is a birthday, a day of birth without its year.
+type MonthDay struct {
+	Month time.Month
+	Day   int
+}
+
+func monthDayOf(dob time.Time) MonthDay {
+	return MonthDay{Month: dob.Month(), Day: dob.Day()}
+}
+
+// BirthdayQuery selects one page of the users born on any of Days, other than
+// ExceptID.
+type BirthdayQuery struct {
+	Days     []MonthDay
+	ExceptID int32
+	Limit    int32
+	Offset   int32
+}
+
+type BirthdayCollision struct {
+	MonthDay
+	Count int64
+}
+
+// DOBCounts splits users by their DOB relative to a given date.
+type DOBCounts struct {
+	Before int64
+	Same   int64
+	After  int64
+}
+
+// NameSearch selects the users whose name is at least Threshold similar to
+// Query (0 to 1, by pg_trgm's similarity) and within Filter, most similar
+// first.
+type NameSearch struct {
+	Query     string
+	Threshold float64
+	Filter    UserFilter
+	Limit     int32
+	Offset    int32
+	WithTotal bool
+}
+
+// ListQuery selects one page. With After set the page starts just past that
+// row in sort order and Offset is ignored.
+type ListQuery struct {
+	Filter UserFilter
+	Limit  int32
+	Offset int32
+	Sort   models.SortOrder
+	After  *Keyset
+}
+
+// Keyset is the sort key of the last row on the previous page.
+type Keyset struct {
+	ID        int32
+	CreatedAt time.Time
+	DOB       time.Time
+}
+
+// sortColumns whitelists the sort fields that may reach ORDER BY.
+var sortColumns = map[string]string{
+	"id":         "id",
+	"created_at": "created_at",
+	"age":        "dob",
+}
+
+// sortKey is the column and direction that sort orders by. Age sorts by dob
+// the other way round, so the order never depends on computing an age.
+func sortKey(sort models.SortOrder) (string, bool) {
+	column, ok := sortColumns[sort.Field]
+	if !ok {
+		return "id", sort.Desc
+	}
+	if column == "dob" {
+		return column, !sort.Desc
+	}
+	return column, sort.Desc
+}
+
+// orderBy always ends with id so rows that tie on the sort column keep a
+// stable order across pages.
+func orderBy(sort models.SortOrder) string {
+	column, desc := sortKey(sort)
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+	if column == "id" {
+		return "id " + direction
+	}
+	return column + " " + direction + ", id " + direction
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
 }
 
 type userRepository struct {
-	db     *sql.DB
+	db querier
+	// pool is nil for a repository bound to a transaction.
+	pool   *sql.DB
+	stmts  *statements
 	logger *zap.Logger
 }
 
+// NewUserRepository's repository is also a StatementPreparer.
 func NewUserRepository(db *sql.DB, logger *zap.Logger) UserRepository {
 	return &userRepository{
 		db:     db,
+		pool:   db,
+		stmts:  &statements{},
 		logger: logger,
 	}
 }
 
+func (r *userRepository) Transact(ctx context.Context, fn func(tx UserRepository) error) error {
+	if r.pool == nil {
+		return r.savepoint(ctx, fn)
+	}
+	tx, err := r.pool.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("Failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(&userRepository{db: tx, stmts: r.stmts, logger: r.logger}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// savepoint runs a nested Transact. Postgres resolves a reused savepoint
+// name to the latest one, so nesting deeper needs no distinct names.
+func (r *userRepository) savepoint(ctx context.Context, fn func(tx UserRepository) error) error {
+	if _, err := r.db.ExecContext(ctx, `SAVEPOINT nested`); err != nil {
+		r.logger.Error("Failed to create savepoint", zap.Error(err))
+		return err
+	}
+	if err := fn(r); err != nil {
+		if _, rbErr := r.db.ExecContext(ctx, `ROLLBACK TO SAVEPOINT nested`); rbErr != nil {
+			r.logger.Error("Failed to roll back to savepoint", zap.Error(rbErr))
+		}
+		return err
+	}
+	_, err := r.db.ExecContext(ctx, `RELEASE SAVEPOINT nested`)
+	return err
+}
+
+func (r *userRepository) Outbox() OutboxRepository {
+	return &outboxRepository{db: r.db, logger: r.logger}
+}
+
+// tenantLockClass is the first key of LockTenant's advisory locks, the second
+// being the tenant's hash. Two-key locks never collide with the migrator's.
+const tenantLockClass = 0x75736572 // "user"
+
+func (r *userRepository) LockTenant(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1, hashtext($2))`, tenantLockClass, tenant.FromContext(ctx))
+	if err != nil {
+		r.logger.Error("Failed to lock tenant", zap.Error(err))
+	}
+	return err
+}
+
 func (r *userRepository) Create(ctx context.Context, name string, dob time.Time) (*models.User, error) {
-	query := `INSERT INTO users (name, dob) VALUES ($1, $2) RETURNING id, name, dob, created_at, updated_at`
+	query := `INSERT INTO users (tenant_id, name, dob) VALUES ($1, $2, $3::date) RETURNING id, tenant_id, name, dob, timezone, locale, created_at, updated_at, anonymized_at`
 
 	var user models.User
-	err := r.db.QueryRowContext(ctx, query, name, dob).Scan(
-		&user.ID,
-		&user.Name,
-		&user.DOB,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	err := scanUser(r.db.QueryRowContext(ctx, query, tenant.FromContext(ctx), name, dateParam(dob)), &user)
 	if err != nil {
+		if isDuplicateName(err) {
+			return nil, ErrDuplicateName
+		}
 		r.logger.Error("Failed to create user", zap.Error(err))
 		return nil, err
 	}
@@ -50,20 +305,14 @@ func (r *userRepository) Create(ctx context.Context, name string, dob time.Time)
 	return &user, nil
 }
 
-func (r *userRepository) GetById(ctx context.Context, id int32) (*models.User, error) {
-	query := `SELECT id, name, dob, created_at, updated_at FROM users WHERE id = $1`
+const getByIDQuery = `SELECT id, tenant_id, name, dob, timezone, locale, created_at, updated_at, anonymized_at FROM users WHERE tenant_id = $1 AND id = $2`
 
+func (r *userRepository) GetById(ctx context.Context, id int32) (*models.User, error) {
 	var user models.User
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&user.ID,
-		&user.Name,
-		&user.DOB,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	err := r.queryRow(ctx, func(row *sql.Row) error { return scanUser(row, &user) }, getByIDQuery, tenant.FromContext(ctx), id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
 		}
 		r.logger.Error("Failed to get user", zap.Error(err), zap.Int32("id", id))
 		return nil, err
@@ -71,10 +320,67 @@ func (r *userRepository) GetById(ctx context.Context, id int32) (*models.User, e
 	return &user, nil
 }
 
-func (r *userRepository) List(ctx context.Context, limit, offset int32) ([]models.User, error) {
-	query := `SELECT id, name, dob, created_at, updated_at FROM users ORDER BY id LIMIT $1 OFFSET $2`
+func (r *userRepository) GetByName(ctx context.Context, name string) (*models.User, error) {
+	query := `SELECT id, tenant_id, name, dob, timezone, locale, created_at, updated_at, anonymized_at FROM users WHERE tenant_id = $1 AND lower(name) = lower($2) ORDER BY id LIMIT 1`
 
-	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	var user models.User
+	err := scanUser(r.db.QueryRowContext(ctx, query, tenant.FromContext(ctx), name), &user)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		r.logger.Error("Failed to get user by name", zap.Error(err))
+		return nil, err
+	}
+	return &user, nil
+}
+
+// FindRecent measures within against the database's clock, the one that set
+// created_at.
+func (r *userRepository) FindRecent(ctx context.Context, name string, dob time.Time, within time.Duration) (*models.User, error) {
+	query := `SELECT id, tenant_id, name, dob, timezone, locale, created_at, updated_at, anonymized_at FROM users
+		WHERE tenant_id = $1 AND name = $2 AND dob = $3::date AND anonymized_at IS NULL AND created_at >= LOCALTIMESTAMP - make_interval(secs => $4)
+		ORDER BY id LIMIT 1`
+
+	var user models.User
+	err := scanUser(r.db.QueryRowContext(ctx, query, tenant.FromContext(ctx), name, dateParam(dob), within.Seconds()), &user)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		r.logger.Error("Failed to find recent user", zap.Error(err))
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *userRepository) FindByNameDOB(ctx context.Context, name string, dob time.Time) (*models.User, error) {
+	query := `SELECT id, tenant_id, name, dob, timezone, locale, created_at, updated_at, anonymized_at FROM users
+		WHERE tenant_id = $1 AND lower(name) = lower($2) AND dob = $3::date AND anonymized_at IS NULL
+		ORDER BY id LIMIT 1`
+
+	var user models.User
+	err := scanUser(r.db.QueryRowContext(ctx, query, tenant.FromContext(ctx), name, dateParam(dob)), &user)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		r.logger.Error("Failed to find user by name and dob", zap.Error(err))
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *userRepository) List(ctx context.Context, q ListQuery) ([]models.User, error) {
+	offset := q.Offset
+	if q.After != nil {
+		offset = 0
+	}
+	args := []any{q.Limit, offset}
+	conditions, args := filterConditions(ctx, q.Filter, args)
+	conditions, args = keysetCondition(q.Sort, q.After, conditions, args)
+
+	rows, err := r.query(ctx, listQuery(conditions, q.Sort), args...)
 	if err != nil {
 		r.logger.Error("Failed to list users", zap.Error(err))
 		return nil, err
@@ -84,7 +390,7 @@ func (r *userRepository) List(ctx context.Context, limit, offset int32) ([]model
 	users := make([]models.User, 0)
 	for rows.Next() {
 		var user models.User
-		if err := rows.Scan(&user.ID, &user.Name, &user.DOB, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		if err := scanUser(rows, &user); err != nil {
 			r.logger.Error("Failed to scan user", zap.Error(err))
 			return nil, err
 		}
@@ -98,20 +404,78 @@ func (r *userRepository) List(ctx context.Context, limit, offset int32) ([]model
 	return users, nil
 }
 
+// listQuery takes the limit and offset as $1 and $2.
+func listQuery(conditions []string, sort models.SortOrder) string {
+	return `SELECT id, tenant_id, name, dob, timezone, locale, created_at, updated_at, anonymized_at FROM users` +
+		where(conditions) + ` ORDER BY ` + orderBy(sort) + ` LIMIT $1 OFFSET $2`
+}
+
+// filterConditions is shared by List and Count so both always see the same
+// tenant and filter. Placeholders continue the numbering of args.
+func filterConditions(ctx context.Context, filter UserFilter, args []any) ([]string, []any) {
+	args = append(args, tenant.FromContext(ctx))
+	conditions := []string{fmt.Sprintf("tenant_id = $%d", len(args))}
+	if filter.Name != "" {
+		args = append(args, likePattern(filter.Name))
+		conditions = append(conditions, fmt.Sprintf("name ILIKE $%d", len(args)))
+	}
+	if !filter.DOBFrom.IsZero() {
+		args = append(args, dateParam(filter.DOBFrom))
+		conditions = append(conditions, fmt.Sprintf("dob >= $%d::date", len(args)))
+	}
+	if !filter.DOBTo.IsZero() {
+		args = append(args, dateParam(filter.DOBTo))
+		conditions = append(conditions, fmt.Sprintf("dob <= $%d::date", len(args)))
+	}
+	return conditions, args
+}
+
+// keysetCondition compares row values so the id tiebreaker is part of the
+// boundary.
+func keysetCondition(sort models.SortOrder, after *Keyset, conditions []string, args []any) ([]string, []any) {
+	if after == nil {
+		return conditions, args
+	}
+	column, desc := sortKey(sort)
+	op := ">"
+	if desc {
+		op = "<"
+	}
+	switch column {
+	case "created_at":
+		args = append(args, timestampParam(after.CreatedAt), after.ID)
+		return append(conditions, fmt.Sprintf("(created_at, id) %s ($%d::timestamp, $%d)", op, len(args)-1, len(args))), args
+	case "dob":
+		args = append(args, dateParam(after.DOB), after.ID)
+		return append(conditions, fmt.Sprintf("(dob, id) %s ($%d::date, $%d)", op, len(args)-1, len(args))), args
+	}
+	args = append(args, after.ID)
+	return append(conditions, fmt.Sprintf("id %s $%d", op, len(args))), args
+}
+
+func where(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
+
+// likePattern escapes LIKE wildcards so the filter is a literal substring.
+func likePattern(s string) string {
+	return "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s) + "%"
+}
+
 func (r *userRepository) Update(ctx context.Context, id int32, name string, dob time.Time) (*models.User, error) {
-	query := `UPDATE users SET name = $1, dob = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3 RETURNING id, name, dob, created_at, updated_at`
+	query := `UPDATE users SET name = $1, dob = $2::date, updated_at = CURRENT_TIMESTAMP WHERE tenant_id = $3 AND id = $4 AND anonymized_at IS NULL RETURNING id, tenant_id, name, dob, timezone, locale, created_at, updated_at, anonymized_at`
 
 	var user models.User
-	err := r.db.QueryRowContext(ctx, query, name, dob, id).Scan(
-		&user.ID,
-		&user.Name,
-		&user.DOB,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	err := scanUser(r.db.QueryRowContext(ctx, query, name, dateParam(dob), tenant.FromContext(ctx), id), &user)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, r.unwritable(ctx, id)
+		}
+		if isDuplicateName(err) {
+			return nil, ErrDuplicateName
 		}
 		r.logger.Error("Failed to update user", zap.Error(err), zap.Int32("id", id))
 		return nil, err
@@ -121,10 +485,107 @@ func (r *userRepository) Update(ctx context.Context, id int32, name string, dob
 	return &user, nil
 }
 
+// UpdatePartial keeps a column whose parameter is NULL.
+func (r *userRepository) UpdatePartial(ctx context.Context, id int32, name *string, dob *time.Time) (*models.User, error) {
+	query := `UPDATE users SET name = COALESCE($1, name), dob = COALESCE($2::date, dob), updated_at = CURRENT_TIMESTAMP
+		WHERE tenant_id = $3 AND id = $4 AND anonymized_at IS NULL RETURNING id, tenant_id, name, dob, timezone, locale, created_at, updated_at, anonymized_at`
+
+	var dobParam *string
+	if dob != nil {
+		formatted := dateParam(*dob)
+		dobParam = &formatted
+	}
+
+	var user models.User
+	err := scanUser(r.db.QueryRowContext(ctx, query, name, dobParam, tenant.FromContext(ctx), id), &user)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, r.unwritable(ctx, id)
+		}
+		if isDuplicateName(err) {
+			return nil, ErrDuplicateName
+		}
+		r.logger.Error("Failed to update user", zap.Error(err), zap.Int32("id", id))
+		return nil, err
+	}
+
+	r.logger.Info("User updated", zap.Int32("id", user.ID))
+	return &user, nil
+}
+
+func (r *userRepository) SetTimezone(ctx context.Context, id int32, timezone string) (*models.User, error) {
+	query := `UPDATE users SET timezone = NULLIF($1, ''), updated_at = CURRENT_TIMESTAMP WHERE tenant_id = $2 AND id = $3 AND anonymized_at IS NULL
+		RETURNING id, tenant_id, name, dob, timezone, locale, created_at, updated_at, anonymized_at`
+
+	var user models.User
+	err := scanUser(r.db.QueryRowContext(ctx, query, timezone, tenant.FromContext(ctx), id), &user)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, r.unwritable(ctx, id)
+		}
+		r.logger.Error("Failed to set user timezone", zap.Error(err), zap.Int32("id", id))
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *userRepository) SetLocale(ctx context.Context, id int32, locale string) (*models.User, error) {
+	query := `UPDATE users SET locale = NULLIF($1, ''), updated_at = CURRENT_TIMESTAMP WHERE tenant_id = $2 AND id = $3 AND anonymized_at IS NULL
+		RETURNING id, tenant_id, name, dob, timezone, locale, created_at, updated_at, anonymized_at`
+
+	var user models.User
+	err := scanUser(r.db.QueryRowContext(ctx, query, locale, tenant.FromContext(ctx), id), &user)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, r.unwritable(ctx, id)
+		}
+		r.logger.Error("Failed to set user locale", zap.Error(err), zap.Int32("id", id))
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *userRepository) Anonymize(ctx context.Context, id int32, name string, dob time.Time) (*models.User, error) {
+	query := `UPDATE users SET name = $1, dob = $2::date, timezone = NULL, locale = NULL, anonymized_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE tenant_id = $3 AND id = $4 AND anonymized_at IS NULL RETURNING id, tenant_id, name, dob, timezone, locale, created_at, updated_at, anonymized_at`
+
+	var user models.User
+	err := scanUser(r.db.QueryRowContext(ctx, query, name, dateParam(dob), tenant.FromContext(ctx), id), &user)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, r.unwritable(ctx, id)
+		}
+		if isDuplicateName(err) {
+			return nil, ErrDuplicateName
+		}
+		r.logger.Error("Failed to anonymize user", zap.Error(err), zap.Int32("id", id))
+		return nil, err
+	}
+
+	r.logger.Info("User anonymized", zap.Int32("id", user.ID))
+	return &user, nil
+}
+
+// unwritable explains why a write guarded by anonymized_at IS NULL matched no
+// row: either there is no such user or it has been anonymized.
+func (r *userRepository) unwritable(ctx context.Context, id int32) error {
+	var anonymized bool
+	err := r.db.QueryRowContext(ctx, `SELECT anonymized_at IS NOT NULL FROM users WHERE tenant_id = $1 AND id = $2`, tenant.FromContext(ctx), id).Scan(&anonymized)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrNotFound
+	case err != nil:
+		return err
+	case anonymized:
+		return ErrAnonymized
+	}
+	return ErrNotFound
+}
+
 func (r *userRepository) Delete(ctx context.Context, id int32) error {
-	query := `DELETE FROM users WHERE id = $1`
+	query := `DELETE FROM users WHERE tenant_id = $1 AND id = $2`
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	result, err := r.db.ExecContext(ctx, query, tenant.FromContext(ctx), id)
 	if err != nil {
 		r.logger.Error("Failed to delete user", zap.Error(err), zap.Int32("id", id))
 		return err
@@ -136,18 +597,18 @@ func (r *userRepository) Delete(ctx context.Context, id int32) error {
 	}
 
 	if rowsAffected == 0 {
-		return sql.ErrNoRows
+		return ErrNotFound
 	}
 
 	r.logger.Info("User deleted", zap.Int32("id", id))
 	return nil
 }
 
-func (r *userRepository) Count(ctx context.Context) (int64, error) {
-	query := `SELECT COUNT(*) FROM users`
+func (r *userRepository) Count(ctx context.Context, filter UserFilter) (int64, error) {
+	conditions, args := filterConditions(ctx, filter, nil)
 
 	var count int64
-	err := r.db.QueryRowContext(ctx, query).Scan(&count)
+	err := r.queryRow(ctx, func(row *sql.Row) error { return row.Scan(&count) }, countQuery(conditions), args...)
 	if err != nil {
 		r.logger.Error("Failed to count users", zap.Error(err))
 		return 0, err
@@ -155,3 +616,340 @@ func (r *userRepository) Count(ctx context.Context) (int64, error) {
 
 	return count, nil
 }
+
+func countQuery(conditions []string) string {
+	return `SELECT COUNT(*) FROM users` + where(conditions)
+}
+
+// CountByDOB takes all three counts in one pass over the filtered rows.
+func (r *userRepository) CountByDOB(ctx context.Context, dob time.Time, filter UserFilter) (DOBCounts, error) {
+	conditions, args := filterConditions(ctx, filter, []any{dateParam(dob)})
+	query := `SELECT COUNT(*) FILTER (WHERE dob < $1::date), COUNT(*) FILTER (WHERE dob = $1::date), COUNT(*) FILTER (WHERE dob > $1::date) FROM users` + where(conditions)
+
+	var counts DOBCounts
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&counts.Before, &counts.Same, &counts.After)
+	if err != nil {
+		r.logger.Error("Failed to count users by DOB", zap.Error(err))
+		return DOBCounts{}, err
+	}
+
+	return counts, nil
+}
+
+// CountByDOBBounds takes every count in one pass over the tenant's rows.
+func (r *userRepository) CountByDOBBounds(ctx context.Context, bounds []time.Time) ([]int64, error) {
+	args := make([]any, len(bounds))
+	for i, bound := range bounds {
+		args[i] = dateParam(bound)
+	}
+	columns := make([]string, len(bounds)+1)
+	for i := range columns {
+		var conditions []string
+		if i > 0 {
+			conditions = append(conditions, fmt.Sprintf("dob <= $%d::date", i))
+		}
+		if i < len(bounds) {
+			conditions = append(conditions, fmt.Sprintf("dob > $%d::date", i+1))
+		}
+		columns[i] = "COUNT(*)"
+		if len(conditions) > 0 {
+			columns[i] += " FILTER (WHERE " + strings.Join(conditions, " AND ") + ")"
+		}
+	}
+	conditions, args := filterConditions(ctx, UserFilter{}, args)
+	query := `SELECT ` + strings.Join(columns, ", ") + ` FROM users` + where(conditions)
+
+	counts := make([]int64, len(columns))
+	dest := make([]any, len(counts))
+	for i := range counts {
+		dest[i] = &counts[i]
+	}
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(dest...); err != nil {
+		r.logger.Error("Failed to count users by DOB bounds", zap.Error(err))
+		return nil, err
+	}
+	return counts, nil
+}
+
+// birthdayMonth and birthdayDay are the expressions of
+// idx_users_tenant_birthday; a query must repeat them exactly for the index
+// to apply.
+const (
+	birthdayMonth = "EXTRACT(MONTH FROM dob)"
+	birthdayDay   = "EXTRACT(DAY FROM dob)"
+)
+
+func (r *userRepository) ListByBirthday(ctx context.Context, q BirthdayQuery) ([]models.User, int64, error) {
+	if len(q.Days) == 0 {
+		return []models.User{}, 0, nil
+	}
+	args := []any{tenant.FromContext(ctx), q.ExceptID}
+	days := make([]string, len(q.Days))
+	for i, day := range q.Days {
+		args = append(args, int(day.Month), day.Day)
+		days[i] = fmt.Sprintf("($%d, $%d)", len(args)-1, len(args))
+	}
+	condition := ` WHERE tenant_id = $1 AND id <> $2 AND anonymized_at IS NULL AND (` + birthdayMonth + `, ` + birthdayDay + `) IN (` + strings.Join(days, ", ") + `)`
+
+	var users []models.User
+	var total int64
+	err := r.Transact(ctx, func(tx UserRepository) error {
+		db := tx.(*userRepository).db
+		query := `SELECT id, tenant_id, name, dob, timezone, locale, created_at, updated_at, anonymized_at FROM users` + condition +
+			fmt.Sprintf(` ORDER BY id LIMIT $%d OFFSET $%d`, len(args)+1, len(args)+2)
+		rows, err := db.QueryContext(ctx, query, append(args, q.Limit, q.Offset)...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		users = make([]models.User, 0)
+		for rows.Next() {
+			var user models.User
+			if err := scanUser(rows, &user); err != nil {
+				return err
+			}
+			users = append(users, user)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`+condition, args...).Scan(&total)
+	})
+	if err != nil {
+		r.logger.Error("Failed to list users by birthday", zap.Error(err))
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+func (r *userRepository) BirthdayCollisions(ctx context.Context, limit int32) ([]BirthdayCollision, error) {
+	query := `SELECT ` + birthdayMonth + `::int, ` + birthdayDay + `::int, COUNT(*) FROM users
+		WHERE tenant_id = $1 AND anonymized_at IS NULL
+		GROUP BY ` + birthdayMonth + `, ` + birthdayDay + `
+		HAVING COUNT(*) > 1
+		ORDER BY COUNT(*) DESC, 1, 2 LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, tenant.FromContext(ctx), limit)
+	if err != nil {
+		r.logger.Error("Failed to find birthday collisions", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	collisions := make([]BirthdayCollision, 0)
+	for rows.Next() {
+		var c BirthdayCollision
+		var month int
+		if err := rows.Scan(&month, &c.Day, &c.Count); err != nil {
+			r.logger.Error("Failed to scan birthday collision", zap.Error(err))
+			return nil, err
+		}
+		c.Month = time.Month(month)
+		collisions = append(collisions, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return collisions, nil
+}
+
+// uniqueViolation is the SQLSTATE of a write rejected by a unique index.
+const uniqueViolation = "23505"
+
+func isDuplicateName(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation && pqErr.Constraint == uniqueNameIndex
+}
+
+// undefinedFunction is the SQLSTATE Postgres reports for the % operator and
+// similarity() when pg_trgm is not installed.
+const undefinedFunction = "42883"
+
+// SearchNames filters with the % operator so the trigram index is used; %
+// reads its threshold from a setting, which is scoped to a transaction here
+// so it never leaks onto another request's pooled connection.
+func (r *userRepository) SearchNames(ctx context.Context, search NameSearch) ([]models.User, int64, error) {
+	var users []models.User
+	var total int64
+	err := r.Transact(ctx, func(tx UserRepository) error {
+		db := tx.(*userRepository).db
+		threshold := strconv.FormatFloat(search.Threshold, 'f', -1, 64)
+		if _, err := db.ExecContext(ctx, `SELECT set_config('pg_trgm.similarity_threshold', $1, true)`, threshold); err != nil {
+			return err
+		}
+
+		args := []any{search.Query}
+		conditions, args := filterConditions(ctx, search.Filter, args)
+		conditions = append(conditions, "name % $1")
+		query := `SELECT id, tenant_id, name, dob, timezone, locale, created_at, updated_at, anonymized_at FROM users` + where(conditions) +
+			fmt.Sprintf(` ORDER BY similarity(name, $1) DESC, id LIMIT $%d OFFSET $%d`, len(args)+1, len(args)+2)
+		rows, err := db.QueryContext(ctx, query, append(args, search.Limit, search.Offset)...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		users = make([]models.User, 0)
+		for rows.Next() {
+			var user models.User
+			if err := scanUser(rows, &user); err != nil {
+				return err
+			}
+			users = append(users, user)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		if search.WithTotal {
+			return db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`+where(conditions), args...).Scan(&total)
+		}
+		return nil
+	})
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == undefinedFunction {
+			return nil, 0, ErrFuzzySearchUnsupported
+		}
+		r.logger.Error("Failed to search users by name", zap.Error(err))
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+func (r *userRepository) ListWithDOBAfter(ctx context.Context, date time.Time) ([]models.User, error) {
+	query := `SELECT id, tenant_id, name, dob, timezone, locale, created_at, updated_at, anonymized_at FROM users WHERE tenant_id = $1 AND dob > $2::date ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query, tenant.FromContext(ctx), dateParam(date))
+	if err != nil {
+		r.logger.Error("Failed to list users with future DOB", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]models.User, 0)
+	for rows.Next() {
+		var user models.User
+		if err := scanUser(rows, &user); err != nil {
+			r.logger.Error("Failed to scan user", zap.Error(err))
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// ListBirthdays works out each row's local date from its own zone, so users
+// on either side of the date line are bucketed by their calendar, not UTC's.
+// Mar 1 stands in for Feb 29 when the day before it is Feb 28.
+func (r *userRepository) ListBirthdays(ctx context.Context, now time.Time, defaultZone string) ([]models.User, error) {
+	query := `SELECT id, tenant_id, name, dob, timezone, locale, created_at, updated_at, anonymized_at FROM (
+			SELECT *, ($2::timestamptz AT TIME ZONE COALESCE(timezone, $3))::date AS today FROM users WHERE tenant_id = $1 AND anonymized_at IS NULL
+		) local
+		WHERE dob < today AND (
+			to_char(dob, 'MM-DD') = to_char(today, 'MM-DD')
+			OR (to_char(dob, 'MM-DD') = '02-29' AND to_char(today, 'MM-DD') = '03-01' AND to_char(today - 1, 'MM-DD') = '02-28')
+		)
+		ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query, tenant.FromContext(ctx), now.UTC().Format(time.RFC3339Nano), defaultZone)
+	if err != nil {
+		r.logger.Error("Failed to list birthdays", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]models.User, 0)
+	for rows.Next() {
+		var user models.User
+		if err := scanUser(rows, &user); err != nil {
+			r.logger.Error("Failed to scan user", zap.Error(err))
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (r *userRepository) ListByDOB(ctx context.Context, latestFirst bool, limit int32) ([]models.User, error) {
+	direction := "ASC"
+	if latestFirst {
+		direction = "DESC"
+	}
+	query := `SELECT id, tenant_id, name, dob, timezone, locale, created_at, updated_at, anonymized_at FROM users
+		WHERE tenant_id = $1 AND anonymized_at IS NULL
+		ORDER BY dob ` + direction + `, id LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, tenant.FromContext(ctx), limit)
+	if err != nil {
+		r.logger.Error("Failed to list users by DOB", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]models.User, 0)
+	for rows.Next() {
+		var user models.User
+		if err := scanUser(rows, &user); err != nil {
+			r.logger.Error("Failed to scan user", zap.Error(err))
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+const dateLayout = "2006-01-02"
+
+// dateParam sends a DOB as a plain date string. Passing a time.Time lets
+// Postgres cast it through the session time zone, which can move the date
+// back a day.
+func dateParam(t time.Time) string {
+	return t.Format(dateLayout)
+}
+
+// timestampParam keeps the full microsecond precision of a created_at value
+// read back from a TIMESTAMP column, so a keyset boundary matches exactly.
+func timestampParam(t time.Time) string {
+	return t.Format("2006-01-02 15:04:05.999999")
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanUser pins the scanned DOB to midnight UTC so a DATE column compares and
+// formats the same way regardless of driver or server time zone.
+func scanUser(row rowScanner, user *models.User) error {
+	var timezone, locale sql.NullString
+	if err := row.Scan(&user.ID, &user.TenantID, &user.Name, &user.DOB, &timezone, &locale, &user.CreatedAt, &user.UpdatedAt, &user.AnonymizedAt); err != nil {
+		return err
+	}
+	user.DOB = toDate(user.DOB)
+	user.Timezone = timezone.String
+	user.Locale = locale.String
+	return nil
+}
+
+func toDate(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
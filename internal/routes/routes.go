@@ -1,17 +1,485 @@
 package routes
 
 import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/srinivasarynh/age_calculator/internal/handler"
+	"github.com/srinivasarynh/age_calculator/internal/middleware"
+)
+
+// APIVersion names the API mounted under APIPrefix. APIPrefixV2 holds the
+// routes whose responses changed incompatibly.
+const (
+	APIVersion  = "v1"
+	APIPrefix   = "/api/" + APIVersion
+	APIPrefixV2 = "/api/v2"
+	GraphQLPath = "/graphql"
+)
+
+// CachePolicies holds the Cache-Control value for each kind of route. The
+// zero value sends no Cache-Control at all.
+type CachePolicies struct {
+	// User covers reads of a single user.
+	User string
+	// List covers collection reads.
+	List string
+	// NoStore covers writes, admin routes and health checks.
+	NoStore string
+	// Static covers responses that only change on deploy.
+	Static string
+}
+
+// Cache picks the CachePolicies entry a route sends.
+type Cache int
+
+const (
+	CacheNoStore Cache = iota
+	CacheUser
+	CacheList
+	CacheStatic
 )
 
-func SetupRoutes(app *fiber.App, userHandler *handler.UserHandler) {
-	api := app.Group("/api/v1")
+func (p CachePolicies) policy(c Cache) string {
+	switch c {
+	case CacheUser:
+		return p.User
+	case CacheList:
+		return p.List
+	case CacheStatic:
+		return p.Static
+	}
+	return p.NoStore
+}
+
+// Auth is what a request must carry before a route's handlers run.
+type Auth int
+
+const (
+	AuthNone Auth = iota
+	// AuthTenant resolves the tenant, and with it the users the route sees.
+	AuthTenant
+	// AuthAdmin passes the admin guard.
+	AuthAdmin
+	// AuthAdminTenant passes the admin guard and resolves the tenant.
+	AuthAdminTenant
+)
+
+// Route is one mounted route. Path is the full Fiber path; Handlers run after
+// the Cache policy, last the route's handler.
+type Route struct {
+	Method  string
+	Path    string
+	Name    string
+	Summary string
+	Auth    Auth
+	Cache   Cache
+	// Streaming routes get SERVER_STREAM_WRITE_TIMEOUT instead of
+	// SERVER_WRITE_TIMEOUT, and no REQUEST_TIMEOUT, for every path under
+	// Path.
+	Streaming bool
+	// Indexed routes are listed by the API index.
+	Indexed bool
+	// Deprecation, when set, marks the route deprecated on every response.
+	Deprecation *Deprecation
+	Handlers    []fiber.Handler
+}
+
+// Deprecation says since when a route is deprecated, when it may be removed
+// and which path replaces it. Sunset and Successor are optional.
+type Deprecation struct {
+	Since     time.Time
+	Sunset    time.Time
+	Successor string
+}
+
+// Table lists every route the Setup functions mount. Its handlers are bound
+// to nil receivers; it is for the callers that only need the metadata.
+func Table() []Route {
+	return slices.Concat(userRoutesV1(nil), userRoutesV2(nil), jobRoutes(nil), graphQLRoutes(nil), indexRoutes(nil), systemRoutes(nil, nil), adminRoutes(nil))
+}
+
+// StreamingPrefixes lists the paths of the Streaming routes.
+func StreamingPrefixes() []string {
+	var prefixes []string
+	for _, route := range Table() {
+		if route.Streaming {
+			prefixes = append(prefixes, route.Path)
+		}
+	}
+	return prefixes
+}
+
+// allowOrder is the order an Allow header lists methods in.
+var allowOrder = []string{fiber.MethodGet, fiber.MethodHead, fiber.MethodPost, fiber.MethodPut, fiber.MethodPatch, fiber.MethodDelete, fiber.MethodOptions}
+
+// allowedPath is one distinct Path of Table and the methods it answers.
+type allowedPath struct {
+	segments []string
+	params   int
+	allow    string
+}
+
+func (p allowedPath) matches(segments []string) bool {
+	if len(segments) != len(p.segments) {
+		return false
+	}
+	for i, segment := range p.segments {
+		if !strings.HasPrefix(segment, ":") && segment != segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Options answers OPTIONS on every path of Table with 204 and an Allow header
+// built from Table; a GET route also answers HEAD. Where several paths match,
+// such as /users/:id and /users/oldest, the one with the fewest parameters
+// decides, as it would route a GET. Other paths and methods pass through. CORS
+// preflights are answered before this, by the CORS middleware.
+func Options() fiber.Handler {
+	methods := map[string][]string{}
+	var order []string
+	for _, route := range Table() {
+		if methods[route.Path] == nil {
+			order = append(order, route.Path)
+		}
+		methods[route.Path] = append(methods[route.Path], route.Method)
+		if route.Method == fiber.MethodGet {
+			methods[route.Path] = append(methods[route.Path], fiber.MethodHead)
+		}
+	}
+	paths := make([]allowedPath, 0, len(order))
+	for _, path := range order {
+		var allow []string
+		for _, method := range allowOrder {
+			if method == fiber.MethodOptions || slices.Contains(methods[path], method) {
+				allow = append(allow, method)
+			}
+		}
+		paths = append(paths, allowedPath{
+			segments: strings.Split(path, "/"),
+			params:   strings.Count(path, ":"),
+			allow:    strings.Join(allow, ", "),
+		})
+	}
+
+	return func(c *fiber.Ctx) error {
+		if c.Method() != fiber.MethodOptions {
+			return c.Next()
+		}
+		segments := strings.Split(strings.TrimSuffix(c.Path(), "/"), "/")
+		var best *allowedPath
+		for i := range paths {
+			if paths[i].matches(segments) && (best == nil || paths[i].params < best.params) {
+				best = &paths[i]
+			}
+		}
+		if best == nil {
+			return c.Next()
+		}
+		c.Set(fiber.HeaderAllow, best.allow)
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+// maxSuggestionDistance is how many edits a path may be from a route for
+// NotFound to suggest it.
+const maxSuggestionDistance = 2
+
+// SetupNotFound answers every path under /api, in any case, that no route
+// matches; it must be mounted after the routes. Other paths keep the
+// ErrorHandler's 404.
+func SetupNotFound(app *fiber.App) {
+	app.Use(NotFound())
+}
+
+// NotFound answers an /api request no route below it matched with 404 and
+// {"error": StructuredError} coded ROUTE_NOT_FOUND, suggesting the route of
+// Table the path most nearly spells for the same method. A path another
+// method answers keeps its 405.
+func NotFound() fiber.Handler {
+	table := Table()
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+		var e *fiber.Error
+		if !errors.As(err, &e) || e.Code != fiber.StatusNotFound {
+			return err
+		}
+		if path := strings.ToLower(c.Path()); path != "/api" && !strings.HasPrefix(path, "/api/") {
+			return err
+		}
+		requestID, _ := c.Locals("requestID").(string)
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": middleware.StructuredError{
+			Code:       "ROUTE_NOT_FOUND",
+			Message:    "No route for " + c.Method() + " " + c.Path(),
+			RequestID:  requestID,
+			Suggestion: suggestPath(table, c.Method(), c.Path()),
+		}})
+	}
+}
+
+// suggestPath is the path of the route of table for method that path is
+// fewest edits away from, ignoring case, with path's own values for the
+// route's parameters; "" when none is within maxSuggestionDistance.
+func suggestPath(table []Route, method, path string) string {
+	segments := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	best, bestDistance := "", maxSuggestionDistance+1
+	for _, route := range table {
+		if route.Method != method && (method != fiber.MethodHead || route.Method != fiber.MethodGet) {
+			continue
+		}
+		pattern := strings.Split(route.Path, "/")
+		if len(pattern) != len(segments) {
+			continue
+		}
+		suggestion := make([]string, len(pattern))
+		distance := 0
+		for i, segment := range pattern {
+			if strings.HasPrefix(segment, ":") {
+				suggestion[i] = segments[i]
+				continue
+			}
+			suggestion[i] = segment
+			distance += editDistance(segment, strings.ToLower(segments[i]))
+		}
+		if distance < bestDistance {
+			best, bestDistance = strings.Join(suggestion, "/"), distance
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b, in bytes.
+func editDistance(a, b string) int {
+	row := make([]int, len(b)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		diagonal := row[0]
+		row[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			diagonal, row[j] = row[j], min(row[j]+1, row[j-1]+1, diagonal+cost)
+		}
+	}
+	return row[len(b)]
+}
+
+var pathParam = regexp.MustCompile(`:(\w+)`)
+
+// Resources is what the API index lists, with path parameters as {name}.
+func Resources() []handler.APIResource {
+	var resources []handler.APIResource
+	for _, route := range Table() {
+		if route.Indexed {
+			resources = append(resources, handler.APIResource{
+				Name:       route.Name,
+				URL:        pathParam.ReplaceAllString(route.Path, "{$1}"),
+				Summary:    route.Summary,
+				Deprecated: route.Deprecation != nil,
+			})
+		}
+	}
+	return resources
+}
+
+// mount adds the routes of table that require auth to router, which is
+// mounted at prefix and whose own handlers must enforce auth. before runs
+// ahead of each route's Handlers.
+func mount(router fiber.Router, prefix string, cache CachePolicies, table []Route, auth Auth, before ...fiber.Handler) {
+	for _, route := range table {
+		if route.Auth != auth {
+			continue
+		}
+		var handlers []fiber.Handler
+		if d := route.Deprecation; d != nil {
+			handlers = append(handlers, middleware.Deprecated(route.Name, d.Since, d.Sunset, d.Successor))
+		}
+		handlers = append(handlers, middleware.CacheControl(cache.policy(route.Cache)))
+		handlers = append(handlers, before...)
+		handlers = append(handlers, route.Handlers...)
+		path := strings.TrimPrefix(route.Path, prefix)
+		// Get also answers HEAD, as it always has; Add would not.
+		if route.Method == fiber.MethodGet {
+			router.Get(path, handlers...)
+		} else {
+			router.Add(route.Method, path, handlers...)
+		}
+	}
+}
+
+func userRoutes(h *handler.UserHandler) []Route {
+	users := APIPrefix + "/users"
+	jsonBody := middleware.ContentType(fiber.MIMEApplicationJSON)
+	return []Route{
+		{Method: fiber.MethodGet, Path: APIPrefix + "/shared/:token", Name: "shared_profile", Summary: "Read a shared user profile", Indexed: true, Handlers: []fiber.Handler{h.SharedProfile}},
+
+		{Method: fiber.MethodGet, Path: users, Name: "list_users", Summary: "List users", Auth: AuthTenant, Cache: CacheList, Indexed: true, Handlers: []fiber.Handler{h.ListUsers}},
+		{Method: fiber.MethodPost, Path: users, Name: "create_user", Summary: "Create a user", Auth: AuthTenant, Handlers: []fiber.Handler{jsonBody, h.CreateUser}},
+		{Method: fiber.MethodPatch, Path: users + "/batch", Name: "update_users", Summary: "Update several users", Auth: AuthTenant, Handlers: []fiber.Handler{jsonBody, h.UpdateUsers}},
+		{Method: fiber.MethodPost, Path: users + "/import", Name: "import_users", Summary: "Import users from CSV", Auth: AuthTenant, Handlers: []fiber.Handler{middleware.ContentType(handler.MIMETextCSV), h.ImportUsers}},
+		{Method: fiber.MethodGet, Path: users + "/export", Name: "download_users", Summary: "Download all users", Auth: AuthTenant, Streaming: true, Handlers: []fiber.Handler{h.DownloadUsers}},
+		{Method: fiber.MethodPost, Path: users + "/export/jobs", Name: "export_users", Summary: "Export users in the background", Auth: AuthTenant, Handlers: []fiber.Handler{jsonBody, h.ExportUsers}},
+		{Method: fiber.MethodGet, Path: users + "/birthdays.ics", Name: "birthday_calendar", Summary: "Birthdays as an iCalendar feed", Auth: AuthTenant, Cache: CacheList, Streaming: true, Indexed: true, Handlers: []fiber.Handler{h.BirthdayCalendar}},
+		{Method: fiber.MethodGet, Path: users + "/birthdays/today", Name: "birthdays_today", Summary: "Users whose birthday is today", Auth: AuthTenant, Cache: CacheList, Indexed: true, Handlers: []fiber.Handler{h.BirthdaysToday}},
+		{Method: fiber.MethodGet, Path: users + "/birthday-collisions", Name: "birthday_collisions", Summary: "Users sharing a birthday", Auth: AuthTenant, Cache: CacheList, Handlers: []fiber.Handler{h.BirthdayCollisions}},
+		{Method: fiber.MethodGet, Path: users + "/stats/cohorts", Name: "age_cohorts", Summary: "Users counted by age range", Auth: AuthTenant, Cache: CacheList, Handlers: []fiber.Handler{h.AgeCohorts}},
+		{Method: fiber.MethodGet, Path: users + "/leaplings", Name: "leaplings", Summary: "Users born on Feb 29", Auth: AuthTenant, Cache: CacheList, Handlers: []fiber.Handler{h.Leaplings}},
+		{Method: fiber.MethodGet, Path: users + "/oldest", Name: "oldest_users", Summary: "Oldest users", Auth: AuthTenant, Cache: CacheList, Handlers: []fiber.Handler{h.OldestUsers}},
+		{Method: fiber.MethodGet, Path: users + "/youngest", Name: "youngest_users", Summary: "Youngest users", Auth: AuthTenant, Cache: CacheList, Handlers: []fiber.Handler{h.YoungestUsers}},
+		{Method: fiber.MethodGet, Path: users + "/by-name/:name", Name: "get_user_by_name", Summary: "Find a user by name", Auth: AuthTenant, Cache: CacheUser, Handlers: []fiber.Handler{h.GetUserByName}},
+		{Method: fiber.MethodGet, Path: users + "/:id", Name: "get_user", Summary: "Read a user", Auth: AuthTenant, Cache: CacheUser, Indexed: true, Handlers: []fiber.Handler{h.GetUser}},
+		{Method: fiber.MethodGet, Path: users + "/:id/birthday-twins", Name: "birthday_twins", Summary: "Users born on the same day", Auth: AuthTenant, Cache: CacheList, Handlers: []fiber.Handler{h.BirthdayTwins}},
+		{Method: fiber.MethodGet, Path: users + "/:id/percentile", Name: "age_percentile", Summary: "A user's age percentile", Auth: AuthTenant, Cache: CacheList, Handlers: []fiber.Handler{h.AgePercentile}},
+		{Method: fiber.MethodGet, Path: users + "/:id/vcard", Name: "get_user_vcard", Summary: "A user as a vCard", Auth: AuthTenant, Cache: CacheUser, Handlers: []fiber.Handler{h.GetUserVCard}},
+		{Method: fiber.MethodGet, Path: users + "/:id/export", Name: "export_user_data", Summary: "Everything stored about a user", Auth: AuthAdminTenant, Handlers: []fiber.Handler{h.ExportUserData}},
+		{Method: fiber.MethodPut, Path: users + "/:id", Name: "update_user", Summary: "Replace a user", Auth: AuthTenant, Handlers: []fiber.Handler{jsonBody, h.UpdateUser}},
+		{Method: fiber.MethodPatch, Path: users + "/:id", Name: "patch_user", Summary: "Change some fields of a user", Auth: AuthTenant, Handlers: []fiber.Handler{middleware.ContentType(handler.MIMEApplicationMergePatchJSON, fiber.MIMEApplicationJSON), h.PatchUser}},
+		{Method: fiber.MethodPost, Path: users + "/:id/anonymize", Name: "anonymize_user", Summary: "Anonymize a user", Auth: AuthTenant, Handlers: []fiber.Handler{h.AnonymizeUser}},
+		{Method: fiber.MethodPost, Path: users + "/:id/share", Name: "create_share", Summary: "Share a user profile", Auth: AuthTenant, Handlers: []fiber.Handler{h.CreateShare}},
+		{Method: fiber.MethodGet, Path: users + "/:id/share", Name: "list_shares", Summary: "List a user's live shares", Auth: AuthTenant, Handlers: []fiber.Handler{h.ListShares}},
+		{Method: fiber.MethodDelete, Path: users + "/:id/share/:token_id", Name: "revoke_share", Summary: "Revoke a share", Auth: AuthTenant, Handlers: []fiber.Handler{h.RevokeShare}},
+		{Method: fiber.MethodDelete, Path: users + "/:id", Name: "delete_user", Summary: "Delete a user", Auth: AuthTenant, Handlers: []fiber.Handler{h.DeleteUser}},
+	}
+}
+
+// v2UserRoutes names the user routes /api/v2 serves so far.
+var v2UserRoutes = []string{"list_users", "create_user", "get_user", "update_user", "patch_user", "delete_user"}
+
+// v1Superseded is when the v2UserRoutes deprecated their v1 forms.
+var v1Superseded = time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+
+// userRoutesV1 are the userRoutes, those /api/v2 also serves marked
+// deprecated in favour of their v2 path. No sunset is set yet.
+func userRoutesV1(h *handler.UserHandler) []Route {
+	table := userRoutes(h)
+	for i, route := range table {
+		if slices.Contains(v2UserRoutes, route.Name) {
+			table[i].Deprecation = &Deprecation{Since: v1Superseded, Successor: v2Path(route.Path)}
+		}
+	}
+	return table
+}
+
+// userRoutesV2 are the v2UserRoutes under APIPrefixV2, with "_v2" names. h
+// must be a v2 handler; only the route table is shared.
+func userRoutesV2(h *handler.UserHandler) []Route {
+	var table []Route
+	for _, route := range userRoutes(h) {
+		if slices.Contains(v2UserRoutes, route.Name) {
+			route.Path = v2Path(route.Path)
+			route.Name += "_v2"
+			route.Indexed = false
+			table = append(table, route)
+		}
+	}
+	return table
+}
+
+func v2Path(v1 string) string {
+	return APIPrefixV2 + strings.TrimPrefix(v1, APIPrefix)
+}
+
+func jobRoutes(h *handler.JobHandler) []Route {
+	jobs := APIPrefix + "/jobs"
+	return []Route{
+		{Method: fiber.MethodGet, Path: jobs + "/:id", Name: "get_job", Summary: "A background job's progress", Auth: AuthTenant, Indexed: true, Handlers: []fiber.Handler{h.GetJob}},
+		{Method: fiber.MethodGet, Path: jobs + "/:id/download", Name: "download_job", Summary: "A finished job's file", Auth: AuthTenant, Handlers: []fiber.Handler{h.Download}},
+	}
+}
+
+func graphQLRoutes(h *handler.UserHandler) []Route {
+	return []Route{
+		{Method: fiber.MethodPost, Path: GraphQLPath, Name: "graphql", Summary: "Query and change users with GraphQL", Auth: AuthTenant, Handlers: []fiber.Handler{middleware.ContentType(fiber.MIMEApplicationJSON), h.GraphQL}},
+	}
+}
+
+func indexRoutes(h *handler.IndexHandler) []Route {
+	return []Route{
+		{Method: fiber.MethodGet, Path: APIPrefix, Name: "index", Summary: "This index", Cache: CacheList, Handlers: []fiber.Handler{h.Index}},
+	}
+}
+
+func systemRoutes(h *handler.SystemHandler, metrics fiber.Handler) []Route {
+	return []Route{
+		{Method: fiber.MethodGet, Path: "/health", Name: "health", Summary: "Liveness", Indexed: true, Handlers: []fiber.Handler{h.Health}},
+		{Method: fiber.MethodGet, Path: "/ready", Name: "ready", Summary: "Readiness", Handlers: []fiber.Handler{h.Ready}},
+		{Method: fiber.MethodGet, Path: "/version", Name: "version", Summary: "Build information", Cache: CacheStatic, Indexed: true, Handlers: []fiber.Handler{h.Version}},
+		{Method: fiber.MethodGet, Path: "/metrics", Name: "metrics", Summary: "Prometheus metrics", Handlers: []fiber.Handler{metrics}},
+	}
+}
+
+func adminRoutes(h *handler.AdminHandler) []Route {
+	return []Route{
+		{Method: fiber.MethodGet, Path: "/admin/config", Name: "config", Summary: "Effective configuration", Auth: AuthAdmin, Handlers: []fiber.Handler{h.Config}},
+		{Method: fiber.MethodGet, Path: "/admin/stats", Name: "stats", Summary: "Runtime statistics", Auth: AuthAdmin, Indexed: true, Handlers: []fiber.Handler{h.Stats}},
+		{Method: fiber.MethodGet, Path: "/admin/stats/validation", Name: "validation_stats", Summary: "Validation failures by endpoint and field", Auth: AuthAdmin, Handlers: []fiber.Handler{h.ValidationStats}},
+		{Method: fiber.MethodGet, Path: "/admin/events/dead", Name: "dead_events", Summary: "Events the outbox gave up delivering", Auth: AuthAdmin, Handlers: []fiber.Handler{h.DeadEvents}},
+		{Method: fiber.MethodPost, Path: "/admin/events/:id/replay", Name: "replay_event", Summary: "Deliver a dead-lettered event again", Auth: AuthAdmin, Handlers: []fiber.Handler{h.ReplayEvent}},
+		{Method: fiber.MethodGet, Path: "/admin/backup", Name: "backup", Summary: "Gzipped JSON backup of every tenant", Auth: AuthAdmin, Streaming: true, Handlers: []fiber.Handler{h.Backup}},
+		{Method: fiber.MethodPost, Path: "/admin/restore", Name: "restore", Summary: "Load a backup into an empty database", Auth: AuthAdmin, Streaming: true, Handlers: []fiber.Handler{h.Restore}},
+		{Method: fiber.MethodPost, Path: "/admin/users/validate-dobs", Name: "validate_dobs", Summary: "Find future dates of birth", Auth: AuthAdminTenant, Handlers: []fiber.Handler{h.ValidateDOBs}},
+	}
+}
+
+// SetupRoutes scopes every user route to the tenant resolved by tenant. A
+// shared profile is read by whoever holds its token, so it takes the tenant
+// from the token instead. The admin-only user routes also pass guard, which
+// runs after tenant as the whole group resolves it first.
+func SetupRoutes(app *fiber.App, userHandler *handler.UserHandler, guard, tenant fiber.Handler, cache CachePolicies) {
+	table := userRoutesV1(userHandler)
+	mount(app, "", cache, table, AuthNone)
+	users := app.Group(APIPrefix+"/users", tenant, middleware.Locale())
+	mount(users, APIPrefix+"/users", cache, table, AuthTenant)
+	mount(users, APIPrefix+"/users", cache, table, AuthAdminTenant, guard)
+}
+
+// SetupV2Routes mounts /api/v2 on userHandler, which should be built with
+// the v2 options: WithDateObjects and WithStrictStatusCodes. Every v2 error,
+// unmatched paths included, gets the structured envelope.
+func SetupV2Routes(app *fiber.App, userHandler *handler.UserHandler, tenant fiber.Handler, cache CachePolicies) {
+	app.Group(APIPrefixV2, middleware.StructuredErrors())
+	users := app.Group(APIPrefixV2+"/users", tenant, middleware.Locale())
+	mount(users, APIPrefixV2+"/users", cache, userRoutesV2(userHandler), AuthTenant)
+}
+
+// SetupJobRoutes scopes jobs to the tenant resolved by tenant.
+func SetupJobRoutes(app *fiber.App, jobHandler *handler.JobHandler, tenant fiber.Handler, cache CachePolicies) {
+	jobs := app.Group(APIPrefix+"/jobs", tenant, middleware.CacheControl(cache.NoStore))
+	mount(jobs, APIPrefix+"/jobs", cache, jobRoutes(jobHandler), AuthTenant)
+}
+
+// SetupGraphQLRoutes serves /graphql from userHandler, scoped to the tenant
+// like the REST user routes.
+func SetupGraphQLRoutes(app *fiber.App, userHandler *handler.UserHandler, tenant fiber.Handler, cache CachePolicies) {
+	graphql := app.Group(GraphQLPath, tenant, middleware.Locale())
+	mount(graphql, GraphQLPath, cache, graphQLRoutes(userHandler), AuthTenant)
+}
+
+// SetupIndexRoutes serves the index at APIPrefix itself. It is not
+// tenant-scoped: it describes the deployment, not a tenant's data.
+func SetupIndexRoutes(app *fiber.App, indexHandler *handler.IndexHandler, cache CachePolicies) {
+	mount(app, "", cache, indexRoutes(indexHandler), AuthNone)
+}
+
+func SetupSystemRoutes(app *fiber.App, systemHandler *handler.SystemHandler, metrics fiber.Handler, cache CachePolicies) {
+	mount(app, "", cache, systemRoutes(systemHandler, metrics), AuthNone)
+}
 
-	users := api.Group("/users")
-	users.Get("", userHandler.ListUsers)
-	users.Post("", userHandler.CreateUser)
-	users.Get("/:id", userHandler.GetUser)
-	users.Put("/:id", userHandler.UpdateUser)
-	users.Delete("/:id", userHandler.DeleteUser)
+// SetupAdminRoutes runs tenant after guard, and only on routes that read user
+// data.
+func SetupAdminRoutes(app *fiber.App, adminHandler *handler.AdminHandler, guard, tenant fiber.Handler, cache CachePolicies) {
+	table := adminRoutes(adminHandler)
+	admin := app.Group("/admin", middleware.CacheControl(cache.NoStore), guard)
+	mount(admin, "/admin", cache, table, AuthAdmin)
+	mount(admin, "/admin", cache, table, AuthAdminTenant, tenant)
 }
@@ -0,0 +1,492 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/srinivasarynh/age_calculator/config"
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/handler"
+	"github.com/srinivasarynh/age_calculator/internal/metrics"
+	"github.com/srinivasarynh/age_calculator/internal/middleware"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/service"
+	"go.uber.org/zap"
+)
+
+func TestCacheControlPerRoute(t *testing.T) {
+	policies := CachePolicies{
+		User:    "private, max-age=30",
+		List:    "no-cache",
+		NoStore: "no-store",
+		Static:  "public, max-age=86400, s-maxage=600",
+	}
+	repo := repository.NewMemoryUserRepository(clock.Fixed(time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)))
+	svc := service.NewUserService(repo, zap.NewNop())
+	cfg := config.NewHolder(&config.Config{AdminAllowedIPs: []string{"0.0.0.0/0"}})
+
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	SetupRoutes(app, handler.NewUserHandler(svc, zap.NewNop()), middleware.AdminOnly(cfg), middleware.Tenant(cfg), policies)
+	SetupIndexRoutes(app, handler.NewIndexHandler(cfg, APIVersion, Resources()), policies)
+	SetupSystemRoutes(app, handler.NewSystemHandler(nil), func(c *fiber.Ctx) error { return c.SendString("") }, policies)
+	SetupAdminRoutes(app, handler.NewAdminHandler(cfg, svc, zap.NewNop()), middleware.AdminOnly(cfg), middleware.Tenant(cfg), policies)
+
+	tests := []struct {
+		method string
+		target string
+		body   string
+		want   string
+	}{
+		{method: "POST", target: "/api/v1/users", body: `{"name":"Alice","dob":"1990-05-10"}`, want: "no-store"},
+		{method: "GET", target: "/api/v1/users/1", want: "private, max-age=30"},
+		{method: "GET", target: "/api/v1/users", want: "no-cache"},
+		{method: "PUT", target: "/api/v1/users/1", body: `{"name":"Alicia","dob":"1990-05-10"}`, want: "no-store"},
+		{method: "GET", target: "/api/v1/users/99", want: "no-store"},
+		{method: "GET", target: "/api/v1", want: "no-cache"},
+		{method: "GET", target: "/version", want: "public, max-age=86400, s-maxage=600"},
+		{method: "GET", target: "/health", want: "no-store"},
+		{method: "GET", target: "/admin/config", want: "no-store"},
+	}
+	for _, tt := range tests {
+		var req = httptest.NewRequest(tt.method, tt.target, nil)
+		if tt.body != "" {
+			req = httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.Header.Get("Cache-Control"); got != tt.want {
+			t.Errorf("%s %s (status %d): Cache-Control = %q, want %q", tt.method, tt.target, resp.StatusCode, got, tt.want)
+		}
+	}
+}
+
+func TestTableMatchesMountedRoutes(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(clock.Real())
+	svc := service.NewUserService(repo, zap.NewNop())
+	cfg := config.NewHolder(&config.Config{})
+	tenant := middleware.Tenant(cfg)
+
+	app := fiber.New()
+	SetupRoutes(app, handler.NewUserHandler(svc, zap.NewNop()), middleware.AdminOnly(cfg), tenant, CachePolicies{})
+	SetupV2Routes(app, handler.NewUserHandler(svc, zap.NewNop(), handler.WithDateObjects(true)), tenant, CachePolicies{})
+	SetupJobRoutes(app, handler.NewJobHandler(nil, zap.NewNop()), tenant, CachePolicies{})
+	SetupGraphQLRoutes(app, handler.NewUserHandler(svc, zap.NewNop()), tenant, CachePolicies{})
+	SetupIndexRoutes(app, handler.NewIndexHandler(cfg, APIVersion, Resources()), CachePolicies{})
+	SetupSystemRoutes(app, handler.NewSystemHandler(nil), func(c *fiber.Ctx) error { return c.SendString("") }, CachePolicies{})
+	SetupAdminRoutes(app, handler.NewAdminHandler(cfg, svc, zap.NewNop()), middleware.AdminOnly(cfg), tenant, CachePolicies{})
+
+	entries := map[string]int{}
+	names := map[string]bool{}
+	for _, route := range Table() {
+		entries[route.Method+" "+route.Path]++
+		if names[route.Name] {
+			t.Errorf("route name %q is used twice", route.Name)
+		}
+		names[route.Name] = true
+	}
+	mounted := map[string]int{}
+	for _, route := range app.GetRoutes(true) {
+		// Get mounts a HEAD route beside each GET one.
+		if route.Method != fiber.MethodHead {
+			mounted[route.Method+" "+route.Path]++
+		}
+	}
+	for route, n := range mounted {
+		if n != 1 || entries[route] != 1 {
+			t.Errorf("%s: mounted %d times, %d entries in Table", route, n, entries[route])
+		}
+	}
+	for route := range entries {
+		if mounted[route] == 0 {
+			t.Errorf("%s is in Table but not mounted", route)
+		}
+	}
+}
+
+func TestResources(t *testing.T) {
+	want := map[string]string{
+		"list_users":      APIPrefix + "/users",
+		"get_user":        APIPrefix + "/users/{id}",
+		"get_job":         APIPrefix + "/jobs/{id}",
+		"shared_profile":  APIPrefix + "/shared/{token}",
+		"birthdays_today": APIPrefix + "/users/birthdays/today",
+		"version":         "/version",
+	}
+	got := map[string]string{}
+	for _, resource := range Resources() {
+		got[resource.Name] = resource.URL
+	}
+	for name, url := range want {
+		if got[name] != url {
+			t.Errorf("resource %s: url = %q, want %q", name, got[name], url)
+		}
+	}
+}
+
+func TestStreamingPrefixes(t *testing.T) {
+	want := []string{APIPrefix + "/users/export", APIPrefix + "/users/birthdays.ics", "/admin/backup", "/admin/restore"}
+	if got := StreamingPrefixes(); !slices.Equal(got, want) {
+		t.Errorf("StreamingPrefixes() = %v, want %v", got, want)
+	}
+}
+
+func TestDeprecatedRoute(t *testing.T) {
+	since := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	table := []Route{
+		{Method: fiber.MethodGet, Path: "/old", Name: "old", Deprecation: &Deprecation{Since: since, Sunset: sunset, Successor: "/new"}, Handlers: []fiber.Handler{func(c *fiber.Ctx) error { return c.SendString("old") }}},
+		{Method: fiber.MethodGet, Path: "/new", Name: "new", Handlers: []fiber.Handler{func(c *fiber.Ctx) error { return c.SendString("new") }}},
+	}
+	registry := prometheus.NewRegistry()
+	m := metrics.NewHTTP(registry)
+
+	app := fiber.New()
+	app.Use(middleware.Metrics(m))
+	mount(app, "", CachePolicies{}, table, AuthNone)
+
+	for range 2 {
+		resp, err := app.Test(httptest.NewRequest("GET", "/old", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.Header.Get("Deprecation"); got != "@1748736000" {
+			t.Errorf("Deprecation = %q, want @1748736000", got)
+		}
+		if got := resp.Header.Get("Sunset"); got != "Thu, 01 Jan 2026 00:00:00 GMT" {
+			t.Errorf("Sunset = %q", got)
+		}
+		if got := resp.Header.Get("Link"); got != `</new>; rel="successor-version"` {
+			t.Errorf("Link = %q", got)
+		}
+	}
+	resp, err := app.Test(httptest.NewRequest("GET", "/new", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Header.Get("Deprecation"); got != "" {
+		t.Errorf("Deprecation on the successor = %q, want none", got)
+	}
+
+	if got := promtestutil.ToFloat64(m.Deprecated.WithLabelValues("old")); got != 2 {
+		t.Errorf("deprecated requests to old = %v, want 2", got)
+	}
+	if got := promtestutil.CollectAndCount(m.Deprecated); got != 1 {
+		t.Errorf("deprecated request series = %d, want only old's", got)
+	}
+}
+
+func TestV1RoutesDeprecatedForV2(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(clock.Real())
+	svc := service.NewUserService(repo, zap.NewNop())
+	tenant := middleware.Tenant(config.NewHolder(&config.Config{}))
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	SetupRoutes(app, handler.NewUserHandler(svc, zap.NewNop()), middleware.AdminOnly(config.NewHolder(&config.Config{})), tenant, CachePolicies{})
+	SetupV2Routes(app, handler.NewUserHandler(svc, zap.NewNop(), handler.WithDateObjects(true)), tenant, CachePolicies{})
+	if _, err := svc.CreateUser(context.Background(), &models.CreateUserRequest{Name: "Alice", DOB: "1990-05-10"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		target string
+		link   string
+	}{
+		{target: "/api/v1/users", link: `</api/v2/users>; rel="successor-version"`},
+		{target: "/api/v1/users/1", link: `</api/v2/users/1>; rel="successor-version"`},
+		{target: "/api/v1/users/1/vcard"},
+		{target: "/api/v2/users/1"},
+	}
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest("GET", tt.target, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		deprecated := resp.Header.Get("Deprecation") != ""
+		if link := resp.Header.Get("Link"); resp.StatusCode != fiber.StatusOK || deprecated != (tt.link != "") || link != tt.link {
+			t.Errorf("GET %s = %d, Deprecation %q, Link %q; want Link %q", tt.target, resp.StatusCode, resp.Header.Get("Deprecation"), link, tt.link)
+		}
+	}
+}
+
+func TestZeroCachePoliciesSendNoHeader(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(clock.Real())
+	app := fiber.New()
+	SetupRoutes(app, handler.NewUserHandler(service.NewUserService(repo, zap.NewNop()), zap.NewNop()), middleware.AdminOnly(config.NewHolder(&config.Config{})), middleware.Tenant(config.NewHolder(&config.Config{})), CachePolicies{})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/users", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Header.Get("Cache-Control"); got != "" {
+		t.Errorf("Cache-Control = %q, want none", got)
+	}
+}
+
+func TestExportUserDataIsAdminOnly(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(clock.Real())
+	svc := service.NewUserService(repo, zap.NewNop())
+	if _, err := svc.CreateUser(context.Background(), &models.CreateUserRequest{Name: "Alice", DOB: "1990-05-10"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		allowed []string
+		token   string
+		header  string
+		target  string
+		want    int
+	}{
+		{name: "tenant caller", allowed: []string{"127.0.0.1"}, target: "/api/v1/users/1/export", want: fiber.StatusForbidden},
+		{name: "tenant caller reads the user", allowed: []string{"127.0.0.1"}, target: "/api/v1/users/1", want: fiber.StatusOK},
+		{name: "allowed address without the token", allowed: []string{"0.0.0.0/0"}, token: "s3cret", target: "/api/v1/users/1/export", want: fiber.StatusUnauthorized},
+		{name: "admin", allowed: []string{"0.0.0.0/0"}, token: "s3cret", header: "Bearer s3cret", target: "/api/v1/users/1/export", want: fiber.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.NewHolder(&config.Config{AdminAllowedIPs: tt.allowed, AdminToken: tt.token})
+			app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+			SetupRoutes(app, handler.NewUserHandler(svc, zap.NewNop()), middleware.AdminOnly(cfg), middleware.Tenant(cfg), CachePolicies{})
+
+			req := httptest.NewRequest("GET", tt.target, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.want {
+				t.Errorf("GET %s = %d, want %d", tt.target, resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdminTenantScope(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(clock.Real())
+	svc := service.NewUserService(repo, zap.NewNop())
+	cfg := config.NewHolder(&config.Config{AdminAllowedIPs: []string{"0.0.0.0/0"}, AdminToken: "s3cret", Tenants: []string{"acme"}})
+
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	SetupAdminRoutes(app, handler.NewAdminHandler(cfg, svc, zap.NewNop()), middleware.AdminOnly(cfg), middleware.Tenant(cfg), CachePolicies{})
+
+	tests := []struct {
+		method string
+		target string
+		tenant string
+		want   int
+	}{
+		{method: "GET", target: "/admin/config", want: fiber.StatusOK},
+		{method: "POST", target: "/admin/users/validate-dobs", want: fiber.StatusBadRequest},
+		{method: "POST", target: "/admin/users/validate-dobs", tenant: "acme", want: fiber.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.target, nil)
+		req.Header.Set("Authorization", "Bearer s3cret")
+		if tt.tenant != "" {
+			req.Header.Set(middleware.HeaderTenantID, tt.tenant)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s %s with tenant %q: status = %d, want %d", tt.method, tt.target, tt.tenant, resp.StatusCode, tt.want)
+		}
+	}
+
+	// The guard still runs first: outsiders learn nothing about tenants.
+	req := httptest.NewRequest("POST", "/admin/users/validate-dobs", nil)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("without the admin token: status = %d, want 401", resp.StatusCode)
+	}
+}
+
+// newFullApp mounts every Setup function, behind Options, over one user.
+func newFullApp(t *testing.T) *fiber.App {
+	t.Helper()
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	repo := repository.NewMemoryUserRepository(clock.Fixed(now))
+	if _, err := repo.Create(context.Background(), "Alice", time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	svc := service.NewUserService(repo, zap.NewNop(), service.WithClock(clock.Fixed(now)))
+	cfg := config.NewHolder(&config.Config{AdminAllowedIPs: []string{"0.0.0.0/0"}})
+	tenant := middleware.Tenant(cfg)
+	policies := CachePolicies{User: "private, max-age=30", List: "no-cache", NoStore: "no-store", Static: "public, max-age=86400"}
+	userHandler := handler.NewUserHandler(svc, zap.NewNop(), handler.WithClock(clock.Fixed(now)))
+
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	app.Use(Options())
+	SetupRoutes(app, userHandler, middleware.AdminOnly(cfg), tenant, policies)
+	SetupV2Routes(app, handler.NewUserHandler(svc, zap.NewNop(), handler.WithClock(clock.Fixed(now)), handler.WithDateObjects(true)), tenant, policies)
+	SetupJobRoutes(app, handler.NewJobHandler(service.NewJobRunner(repository.NewMemoryJobRepository(clock.Fixed(now)), zap.NewNop()), zap.NewNop()), tenant, policies)
+	SetupGraphQLRoutes(app, userHandler, tenant, policies)
+	SetupIndexRoutes(app, handler.NewIndexHandler(cfg, APIVersion, Resources()), policies)
+	SetupSystemRoutes(app, handler.NewSystemHandler(nil), func(c *fiber.Ctx) error { return c.SendString("# metrics\n") }, policies)
+	SetupAdminRoutes(app, handler.NewAdminHandler(cfg, svc, zap.NewNop()), middleware.AdminOnly(cfg), tenant, policies)
+	return app
+}
+
+// samplePath fills a route's parameters so the path names the seeded user.
+func samplePath(path string) string {
+	return pathParam.ReplaceAllStringFunc(path, func(param string) string {
+		if param == ":name" {
+			return "Alice"
+		}
+		return "1"
+	})
+}
+
+func TestHeadMatchesGet(t *testing.T) {
+	app := newFullApp(t)
+	for _, route := range Table() {
+		if route.Method != fiber.MethodGet {
+			continue
+		}
+		path := samplePath(route.Path)
+		get, err := app.Test(httptest.NewRequest("GET", path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		head, err := app.Test(httptest.NewRequest("HEAD", path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if body, _ := io.ReadAll(head.Body); len(body) != 0 {
+			t.Errorf("HEAD %s sent a %d byte body", path, len(body))
+		}
+		get.Header.Del("Date")
+		head.Header.Del("Date")
+		if route.Name == "health" {
+			// It reports the wall-clock time, whose length varies.
+			get.Header.Del("Content-Length")
+			head.Header.Del("Content-Length")
+		}
+		if head.StatusCode != get.StatusCode || !reflect.DeepEqual(head.Header, get.Header) {
+			t.Errorf("HEAD %s = %d %v, want GET's %d %v", path, head.StatusCode, head.Header, get.StatusCode, get.Header)
+		}
+	}
+}
+
+func TestOptionsAllow(t *testing.T) {
+	app := newFullApp(t)
+	want := map[string]string{
+		APIPrefix + "/users":           "GET, HEAD, POST, OPTIONS",
+		APIPrefix + "/users/1":         "GET, HEAD, PUT, PATCH, DELETE, OPTIONS",
+		APIPrefix + "/users/batch":     "PATCH, OPTIONS",
+		APIPrefix + "/users/oldest/":   "GET, HEAD, OPTIONS",
+		APIPrefix + "/users/1/share":   "GET, HEAD, POST, OPTIONS",
+		APIPrefix + "/users/1/share/2": "DELETE, OPTIONS",
+		APIPrefixV2 + "/users/1":       "GET, HEAD, PUT, PATCH, DELETE, OPTIONS",
+		// Only some user routes have a v2.
+		APIPrefixV2 + "/users/1/share": "",
+		GraphQLPath:                    "POST, OPTIONS",
+		"/admin/events/1/replay":       "POST, OPTIONS",
+		"/health":                      "GET, HEAD, OPTIONS",
+	}
+	for _, route := range Table() {
+		path := samplePath(route.Path)
+		resp, err := app.Test(httptest.NewRequest("OPTIONS", path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		allow := strings.Split(resp.Header.Get("Allow"), ", ")
+		if resp.StatusCode != fiber.StatusNoContent || !slices.Contains(allow, route.Method) || !slices.Contains(allow, fiber.MethodOptions) {
+			t.Errorf("OPTIONS %s = %d, Allow %v; want 204 allowing %s", path, resp.StatusCode, allow, route.Method)
+		}
+	}
+	for path, allow := range want {
+		resp, err := app.Test(httptest.NewRequest("OPTIONS", path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.Header.Get("Allow"); got != allow {
+			t.Errorf("OPTIONS %s: Allow = %q, want %q", path, got, allow)
+		}
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	svc := service.NewUserService(repository.NewMemoryUserRepository(clock.Real()), zap.NewNop())
+	tenant := middleware.Tenant(config.NewHolder(&config.Config{}))
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler, StrictRouting: true, CaseSensitive: true})
+	app.Use(middleware.RequestID())
+	SetupRoutes(app, handler.NewUserHandler(svc, zap.NewNop()), middleware.AdminOnly(config.NewHolder(&config.Config{})), tenant, CachePolicies{})
+	SetupV2Routes(app, handler.NewUserHandler(svc, zap.NewNop(), handler.WithDateObjects(true)), tenant, CachePolicies{})
+	SetupSystemRoutes(app, handler.NewSystemHandler(nil), func(c *fiber.Ctx) error { return c.SendString("") }, CachePolicies{})
+	SetupNotFound(app)
+
+	tests := []struct {
+		method, target string
+		status         int
+		suggestion     string
+	}{
+		{"GET", "/api/v1/userz", fiber.StatusNotFound, "/api/v1/users"},
+		{"HEAD", "/api/v1/usr", fiber.StatusNotFound, "/api/v1/users"},
+		{"GET", "/api/v1/userz/42/share", fiber.StatusNotFound, "/api/v1/users/42/share"},
+		{"GET", "/API/V1/Users", fiber.StatusNotFound, "/api/v1/users"},
+		{"POST", "/api/v2/user", fiber.StatusNotFound, "/api/v2/users"},
+		{"GET", "/api/v2/anything", fiber.StatusNotFound, ""},
+		{"GET", "/api", fiber.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.target, nil)
+		req.Header.Set("X-Request-ID", "req-1")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body struct {
+			Error middleware.StructuredError `json:"error"`
+		}
+		if tt.method != fiber.MethodHead {
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatalf("%s %s: %v", tt.method, tt.target, err)
+			}
+		}
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s %s: status = %d, want %d", tt.method, tt.target, resp.StatusCode, tt.status)
+		}
+		if tt.method != fiber.MethodHead && (body.Error.Code != "ROUTE_NOT_FOUND" || body.Error.RequestID != "req-1" ||
+			body.Error.Message != "No route for "+tt.method+" "+tt.target || body.Error.Suggestion != tt.suggestion) {
+			t.Errorf("%s %s: error = %+v, want ROUTE_NOT_FOUND suggesting %q", tt.method, tt.target, body.Error, tt.suggestion)
+		}
+	}
+
+	// Another method answers the path, and paths outside /api are as before.
+	for target, want := range map[string]string{
+		"DELETE /api/v1/users": `{"error":"Method Not Allowed","request_id":"req-1"}`,
+		"GET /favicon.ico":     `{"error":"Cannot GET /favicon.ico","request_id":"req-1"}`,
+		"GET /":                `{"error":"Cannot GET /","request_id":"req-1"}`,
+	} {
+		method, path, _ := strings.Cut(target, " ")
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-Request-ID", "req-1")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if body, _ := io.ReadAll(resp.Body); string(body) != want {
+			t.Errorf("%s = %d %s, want %s", target, resp.StatusCode, body, want)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/srinivasarynh/age_calculator/config"
+	"github.com/srinivasarynh/age_calculator/db"
+	"github.com/srinivasarynh/age_calculator/internal/artifact"
+	"github.com/srinivasarynh/age_calculator/internal/cache"
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/dblock"
+	"github.com/srinivasarynh/age_calculator/internal/events"
+	"github.com/srinivasarynh/age_calculator/internal/handler"
+	"github.com/srinivasarynh/age_calculator/internal/metrics"
+	"github.com/srinivasarynh/age_calculator/internal/middleware"
+	"github.com/srinivasarynh/age_calculator/internal/migrate"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/routes"
+	"github.com/srinivasarynh/age_calculator/internal/service"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+	"go.uber.org/zap"
+)
+
+// Build does not touch db; /ready reports ready only once ready returns true.
+// The job runner, and the outbox dispatcher when USER_EVENTS_WEBHOOK_URL
+// gives it somewhere to deliver, are returned unstarted, for the caller to
+// start once the database is reachable. The dispatcher is nil otherwise. So
+// is the access tracker unless ACCESS_TRACKING is on; the caller stops it
+// after the app so the last reads are written. The warm-up is for the caller
+// to run before reporting ready.
+func Build(cfg *config.Holder, db *sql.DB, registry *prometheus.Registry, logger *zap.Logger, ready func() bool) (*fiber.App, *service.JobRunner, *service.OutboxDispatcher, *service.AccessTracker, func(context.Context)) {
+	sqlUsers := repository.NewUserRepository(db, logger)
+	userRepo := sqlUsers
+	if c := cfg.Load(); c.SingleFlightReads {
+		userRepo = cache.NewSingleFlightUserRepository(userRepo, c.SingleFlightTimeout, metrics.NewSingleFlight(registry))
+	}
+	userCache := newUserCache(cfg.Load(), logger)
+	if userCache != nil {
+		userRepo = cache.NewUserRepository(userRepo, userCache, metrics.NewCache(registry), logger)
+	}
+	userRepo = cache.NewRequestScopedUserRepository(userRepo)
+	c := cfg.Load()
+	userOpts := []service.Option{
+		service.WithMetrics(metrics.NewUsers(registry)),
+		service.WithFuzzyThreshold(c.NameFuzzyThreshold),
+		service.WithUniqueNames(c.UniqueNames),
+		service.WithNameNormalization(c.NormalizeNames),
+		service.WithNamePolicy(service.NamePolicy(c.NamePolicy)),
+		service.WithMinDOBYear(c.MinDOBYear),
+		service.WithMaxUsers(int64(c.MaxUsers)),
+		service.WithDedupeWindow(c.DedupeWindow),
+		service.WithListDOBHidden(c.ListHidesDOB),
+		service.WithInfantAgeYears(c.InfantAgeYears),
+		service.WithCohortBounds(c.CohortBounds),
+		service.WithDefaultLocation(defaultLocation(c, logger)),
+		service.WithNotificationRepository(repository.NewNotificationRepository(db, logger)),
+		service.WithShareRepository(repository.NewShareRepository(db, logger)),
+		service.WithShareTTL(c.ShareTTL),
+		service.WithAggregateTTL(c.AggregateCacheTTL),
+	}
+	var dispatcher *service.OutboxDispatcher
+	if c.UserEventsWebhookURL != "" {
+		userOpts = append(userOpts, service.WithOutbox())
+		dispatcher = service.NewOutboxDispatcher(
+			repository.NewOutboxRepository(db, logger),
+			events.NewWebhook(c.UserEventsWebhookURL, 10*time.Second),
+			logger,
+			service.WithDispatcherInterval(c.OutboxPollInterval),
+			service.WithDispatcherMaxAttempts(c.OutboxMaxAttempts),
+			service.WithDispatcherRetention(c.OutboxRetention),
+			service.WithDispatcherMetrics(metrics.NewWebhooks(registry)),
+		)
+	}
+	var tracker *service.AccessTracker
+	if c.AccessTracking {
+		tracker = service.NewAccessTracker(
+			repository.NewAccessRepository(db, logger),
+			logger,
+			service.WithAccessFlushInterval(c.AccessFlushInterval),
+			service.WithAccessBufferSize(c.AccessBufferSize),
+		)
+		userOpts = append(userOpts, service.WithAccessTracker(tracker))
+	}
+	userService := service.NewUserService(userRepo, logger, userOpts...)
+
+	jobOpts := []service.JobOption{
+		service.WithJobWorkers(c.JobWorkers),
+		service.WithJobStaleAfter(c.JobStaleAfter),
+		service.WithJobLocker(dblock.New(db)),
+	}
+	exports := newExportStore(c, logger)
+	if exports != nil {
+		jobOpts = append(jobOpts, service.WithJobArtifacts(exports, c.ExportRetention))
+	}
+	jobRunner := service.NewJobRunner(repository.NewJobRepository(db, logger), logger, jobOpts...)
+	jobRunner.Handle(models.JobUserImport, service.ImportJob(userService))
+	if exports != nil {
+		jobRunner.Handle(models.JobUserExport, service.ExportJob(userService, exports))
+	}
+
+	// v1 and v2 share one service and differ only in how they respond.
+	handlerOpts := []handler.Option{handler.WithStrictJSON(c.StrictJSON), handler.WithNamePolicy(service.NamePolicy(c.NamePolicy)), handler.WithMaxOffset(c.MaxOffset), handler.WithXLSXMaxRows(c.XLSXMaxRows), handler.WithJobs(jobRunner), handler.WithAdminCheck(func(c *fiber.Ctx) bool { return middleware.IsAdmin(cfg, c) })}
+	userHandler := handler.NewUserHandler(userService, logger, append(handlerOpts, handler.WithStrictStatusCodes(c.StrictStatusCodes))...)
+	userHandlerV2 := handler.NewUserHandler(userService, logger, append(handlerOpts, handler.WithStrictStatusCodes(true), handler.WithDateObjects(true))...)
+	cachePolicies := routes.CachePolicies{
+		User:    c.CacheControlUser,
+		List:    c.CacheControlList,
+		NoStore: c.CacheControlNoStore,
+		Static:  c.CacheControlStatic,
+	}
+
+	app := New(cfg, logger)
+	app.Use(middleware.Metrics(metrics.NewHTTP(registry)))
+	app.Use(middleware.RequestCache())
+	tenant := middleware.Tenant(cfg)
+	adminGuard := middleware.AdminOnly(cfg)
+	routes.SetupRoutes(app, userHandler, adminGuard, tenant, cachePolicies)
+	routes.SetupV2Routes(app, userHandlerV2, tenant, cachePolicies)
+	routes.SetupGraphQLRoutes(app, userHandler, tenant, cachePolicies)
+	routes.SetupJobRoutes(app, handler.NewJobHandler(jobRunner, logger), tenant, cachePolicies)
+	routes.SetupIndexRoutes(app, handler.NewIndexHandler(cfg, routes.APIVersion, routes.Resources()), cachePolicies)
+	routes.SetupSystemRoutes(app, handler.NewSystemHandler(ready), metrics.Handler(registry), cachePolicies)
+	adminOpts := []handler.AdminOption{handler.WithStats(registry), handler.WithOutbox(dispatcher)}
+	if version, err := schemaVersion(); err != nil {
+		logger.Error("Backups disabled: cannot read the embedded migrations", zap.Error(err))
+	} else {
+		// A restore replaces the users behind the service's back, so nothing
+		// cached of the old ones may be served after it.
+		backupOpts := []service.BackupOption{service.WithRestoreHook(func(context.Context) error {
+			userService.ResetAggregates()
+			return nil
+		})}
+		if userCache != nil {
+			backupOpts = append(backupOpts, service.WithRestoreHook(userCache.Purge))
+		}
+		adminOpts = append(adminOpts, handler.WithBackups(service.NewBackups(repository.NewBackupRepository(db, logger), version, logger, backupOpts...)))
+	}
+	routes.SetupAdminRoutes(app, handler.NewAdminHandler(cfg, userService, logger, adminOpts...), adminGuard, tenant, cachePolicies)
+	routes.SetupNotFound(app)
+	StreamingWriteTimeout(app, cfg.Load().ServerStreamWriteTimeout, routes.StreamingPrefixes()...)
+
+	return app, jobRunner, dispatcher, tracker, warmup(c, sqlUsers.(repository.StatementPreparer), userRepo, logger)
+}
+
+// schemaVersion is the last of the embedded migrations, which backups are
+// stamped with. It lives apart from Build, whose db parameter shadows the
+// package.
+func schemaVersion() (int64, error) {
+	migrations, err := migrate.Load(db.Migrations, "migrations")
+	if err != nil || len(migrations) == 0 {
+		return 0, err
+	}
+	return migrations[len(migrations)-1].Version, nil
+}
+
+// NewBirthdayNotifier returns nil unless BIRTHDAY_NOTIFICATIONS is on. Like the
+// job runner it is returned unstarted. Each run covers the tenants configured
+// at the time.
+func NewBirthdayNotifier(cfg *config.Holder, db *sql.DB, registry prometheus.Registerer, logger *zap.Logger) *service.BirthdayNotifier {
+	c := cfg.Load()
+	if !c.BirthdayNotifications {
+		return nil
+	}
+	return service.NewBirthdayNotifier(
+		repository.NewUserRepository(db, logger),
+		repository.NewNotificationRepository(db, logger),
+		events.NewWebhook(c.BirthdayWebhookURL, 10*time.Second),
+		logger,
+		service.WithNotifierInterval(c.BirthdayCheckInterval),
+		service.WithNotifierLocation(defaultLocation(c, logger)),
+		service.WithNotifierTenants(func() []string { return servedTenants(cfg.Load()) }),
+		service.WithNotifierLocker(dblock.New(db)),
+		service.WithNotifierMetrics(metrics.NewWebhooks(registry)),
+	)
+}
+
+// servedTenants lists the tenants the tenant middleware accepts.
+func servedTenants(cfg *config.Config) []string {
+	if len(cfg.Tenants) == 0 {
+		return []string{tenant.Default}
+	}
+	return cfg.Tenants
+}
+
+// newExportStore returns nil when EXPORT_DIR cannot be created, which turns
+// export jobs off rather than stopping the server.
+func newExportStore(cfg *config.Config, logger *zap.Logger) artifact.Store {
+	dir := cfg.ExportDir
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "age_calculator-exports")
+	}
+	store, err := artifact.NewFileStore(dir)
+	if err != nil {
+		logger.Error("Invalid EXPORT_DIR, export jobs disabled", zap.Error(err))
+		return nil
+	}
+	return store
+}
+
+// defaultLocation falls back to UTC should DEFAULT_TIMEZONE, validated at
+// load, still fail to load.
+func defaultLocation(cfg *config.Config, logger *zap.Logger) *time.Location {
+	loc, err := time.LoadLocation(cfg.DefaultTimezone)
+	if err != nil {
+		logger.Error("Invalid DEFAULT_TIMEZONE, using UTC", zap.Error(err))
+		return time.UTC
+	}
+	return loc
+}
+
+// newUserCache returns nil when caching is disabled. Config validation only
+// checks the REDIS_URL scheme, so a URL go-redis still rejects turns caching
+// off rather than stopping the server. Redis being down is handled per request.
+func newUserCache(cfg *config.Config, logger *zap.Logger) cache.Cache {
+	if cfg.RedisURL != "" {
+		client, err := cache.NewRedisClient(cfg.RedisURL)
+		if err != nil {
+			logger.Error("Invalid REDIS_URL, user cache disabled", zap.Error(err))
+			return nil
+		}
+		return cache.NewRedis(client, cfg.UserCacheTTL)
+	}
+	if cfg.UserCacheSize > 0 {
+		return cache.NewLRU(cfg.UserCacheSize, cfg.UserCacheTTL, clock.Real())
+	}
+	return nil
+}
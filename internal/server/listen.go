@@ -0,0 +1,92 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/srinivasarynh/age_calculator/config"
+)
+
+// Listen opens what cfg has the server listen on: SERVER_PORT unless
+// LISTEN_TCP is off, and the LISTEN_SOCKET unix socket when set.
+func Listen(app *fiber.App, cfg *config.Config) ([]net.Listener, error) {
+	var listeners []net.Listener
+	if cfg.ListenTCP {
+		ln, err := net.Listen(app.Config().Network, ":"+cfg.ServerPort)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen: %w", err)
+		}
+		listeners = append(listeners, ln)
+	}
+	if cfg.ListenSocket != "" {
+		mode, err := strconv.ParseUint(cfg.ListenSocketMode, 8, 32)
+		if err != nil {
+			return nil, err
+		}
+		ln, err := ListenUnix(cfg.ListenSocket, os.FileMode(mode))
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, ln)
+	}
+	return listeners, nil
+}
+
+// ListenUnix listens on a unix socket at path with mode permissions. A socket
+// left behind by a server that did not shut down is removed first; one that
+// still answers, or a file that is not a socket, is an error. Closing the
+// listener, as the app's shutdown does, removes the socket again.
+func ListenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	info, err := os.Lstat(path)
+	switch {
+	case err == nil && info.Mode().Type() != os.ModeSocket:
+		return nil, fmt.Errorf("server: %s exists and is not a socket", path)
+	case err == nil:
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("server: %s is in use by another process", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, err
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// Serve serves app on every listener until it shuts down, and returns the
+// first error any of them stops with.
+func Serve(app *fiber.App, listeners []net.Listener) error {
+	errs := make(chan error, len(listeners))
+	for _, ln := range listeners {
+		go func() {
+			errs <- app.Listener(ln)
+		}()
+	}
+	var first error
+	for range listeners {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+			app.Shutdown()
+		}
+	}
+	return first
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/srinivasarynh/age_calculator/config"
+	"github.com/srinivasarynh/age_calculator/internal/handler"
+	"github.com/srinivasarynh/age_calculator/internal/middleware"
+	"github.com/srinivasarynh/age_calculator/internal/routes"
+	"go.uber.org/zap"
+)
+
+func TestServeOnUnixSocket(t *testing.T) {
+	cfg := testConfig()
+	cfg.ServerPort = "0"
+	cfg.ListenTCP = true
+	cfg.ListenSocket = filepath.Join(t.TempDir(), "api.sock")
+	cfg.ListenSocketMode = "0600"
+	cfg.AdminToken = "s3cret"
+	holder := config.NewHolder(cfg)
+
+	app := New(holder, zap.NewNop())
+	routes.SetupSystemRoutes(app, handler.NewSystemHandler(func() bool { return true }), func(c *fiber.Ctx) error { return nil }, routes.CachePolicies{})
+	routes.SetupAdminRoutes(app, handler.NewAdminHandler(holder, nil, zap.NewNop()), middleware.AdminOnly(holder), middleware.Tenant(holder), routes.CachePolicies{})
+	listeners, err := Listen(app, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listeners) != 2 {
+		t.Fatalf("Listen = %d listeners, want the port and the socket", len(listeners))
+	}
+	served := make(chan error, 1)
+	go func() { served <- Serve(app, listeners) }()
+
+	if info, err := os.Stat(cfg.ListenSocket); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("socket = %v, %v; want mode 0600", info, err)
+	}
+	overSocket := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", cfg.ListenSocket)
+		},
+	}}
+	get := func(client *http.Client, base, path string) int {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, base+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer s3cret")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if status := get(overSocket, "http://api", "/health"); status != fiber.StatusOK {
+		t.Errorf("/health over the socket = %d, want 200", status)
+	}
+	// No address is allowed, so with the token only the socket passes the
+	// admin guard.
+	if status := get(overSocket, "http://api", "/admin/config"); status != fiber.StatusOK {
+		t.Errorf("/admin/config over the socket = %d, want 200", status)
+	}
+	if status := get(http.DefaultClient, "http://"+listeners[0].Addr().String(), "/admin/config"); status != fiber.StatusForbidden {
+		t.Errorf("/admin/config over tcp = %d, want 403", status)
+	}
+
+	overSocket.CloseIdleConnections()
+	if err := app.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Serve = %v after shutdown", err)
+	}
+	if _, err := os.Lstat(cfg.ListenSocket); !os.IsNotExist(err) {
+		t.Errorf("socket still there after shutdown: %v", err)
+	}
+}
+
+func TestAdminOverUnixSocketNeedsToken(t *testing.T) {
+	cfg := testConfig()
+	cfg.ListenTCP = false
+	cfg.ListenSocket = filepath.Join(t.TempDir(), "api.sock")
+	cfg.ListenSocketMode = "0600"
+	cfg.AdminAllowedIPs = []string{"0.0.0.0/0", "::/0"}
+	holder := config.NewHolder(cfg)
+
+	app := New(holder, zap.NewNop())
+	routes.SetupAdminRoutes(app, handler.NewAdminHandler(holder, nil, zap.NewNop()), middleware.AdminOnly(holder), middleware.Tenant(holder), routes.CachePolicies{})
+	listeners, err := Listen(app, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- Serve(app, listeners) }()
+
+	overSocket := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", cfg.ListenSocket)
+		},
+	}}
+	resp, err := overSocket.Get("http://api/admin/config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	// Every address is allowed, yet a socket peer is whoever the proxy
+	// forwards, so without ADMIN_TOKEN it is refused.
+	if resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("/admin/config over the socket without ADMIN_TOKEN = %d, want 403", resp.StatusCode)
+	}
+
+	overSocket.CloseIdleConnections()
+	if err := app.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Serve = %v after shutdown", err)
+	}
+}
+
+func TestListenUnixStaleSocket(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "api.sock")
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.SetUnlinkOnClose(false)
+	stale.Close()
+
+	ln, err := ListenUnix(path, 0o660)
+	if err != nil {
+		t.Fatalf("ListenUnix over a stale socket = %v", err)
+	}
+	defer ln.Close()
+	if _, err := ListenUnix(path, 0o660); err == nil {
+		t.Error("ListenUnix on a socket in use: err = nil")
+	}
+
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, []byte("keep"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ListenUnix(file, 0o660); err == nil {
+		t.Error("ListenUnix on a regular file: err = nil")
+	}
+	if raw, err := os.ReadFile(file); err != nil || string(raw) != "keep" {
+		t.Errorf("regular file = %q, %v; want it left alone", raw, err)
+	}
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/srinivasarynh/age_calculator/config"
+	"github.com/srinivasarynh/age_calculator/internal/logger"
+	"go.uber.org/zap"
+)
+
+func Reload(cfg *config.Holder, level zap.AtomicLevel, zapLogger *zap.Logger) error {
+	changed, restartRequired, err := cfg.Reload()
+	if err != nil {
+		zapLogger.Error("Failed to reload config", zap.Error(err))
+		return err
+	}
+
+	if err := logger.SetLevel(level, cfg.Load().LogLevel); err != nil {
+		zapLogger.Error("Failed to apply log level", zap.Error(err))
+		return err
+	}
+
+	if len(restartRequired) > 0 {
+		zapLogger.Warn("Config changes require a restart to take effect", zap.Strings("fields", restartRequired))
+	}
+	zapLogger.Info("Config reloaded", zap.Strings("changed", changed))
+	return nil
+}
+
+func ReloadOnSIGHUP(cfg *config.Holder, level zap.AtomicLevel, zapLogger *zap.Logger) func() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-hup:
+				Reload(cfg, level, zapLogger)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(hup)
+		close(done)
+	}
+}
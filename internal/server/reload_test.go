@@ -0,0 +1,83 @@
+package server
+
+import (
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/srinivasarynh/age_calculator/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestReloadChangesSlowRequestThreshold(t *testing.T) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	holder := config.NewHolder(cfg)
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	app := New(holder, zap.New(core))
+	app.Get("/slow", func(c *fiber.Ctx) error {
+		time.Sleep(20 * time.Millisecond)
+		return c.SendString("ok")
+	})
+
+	request := func() string {
+		logs.TakeAll()
+		if _, err := app.Test(httptest.NewRequest("GET", "/slow", nil)); err != nil {
+			t.Fatal(err)
+		}
+		entries := logs.FilterField(zap.String("path", "/slow")).All()
+		if len(entries) != 1 {
+			t.Fatalf("got %d request log entries, want 1", len(entries))
+		}
+		return entries[0].Message
+	}
+
+	if msg := request(); msg != "HTTP Request" {
+		t.Errorf("before reload logged %q, want normal request", msg)
+	}
+
+	t.Setenv("SLOW_REQUEST_THRESHOLD", "10ms")
+	if err := Reload(holder, zap.NewAtomicLevel(), zap.NewNop()); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+
+	if msg := request(); msg != "Slow HTTP Request" {
+		t.Errorf("after reload logged %q, want slow request warning", msg)
+	}
+}
+
+func TestReloadOnSIGHUPAppliesLogLevel(t *testing.T) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	holder := config.NewHolder(cfg)
+	level := zap.NewAtomicLevelAt(zapcore.DebugLevel)
+
+	stop := ReloadOnSIGHUP(holder, level, zap.NewNop())
+	defer stop()
+
+	t.Setenv("LOG_LEVEL", "error")
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for level.Level() != zapcore.ErrorLevel {
+		if time.Now().After(deadline) {
+			t.Fatalf("log level = %v after SIGHUP, want error", level.Level())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if holder.Load().LogLevel != "error" {
+		t.Errorf("LogLevel = %q, want error", holder.Load().LogLevel)
+	}
+}
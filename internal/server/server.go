@@ -0,0 +1,72 @@
+package server
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/srinivasarynh/age_calculator/config"
+	"github.com/srinivasarynh/age_calculator/internal/buildinfo"
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/middleware"
+	"github.com/srinivasarynh/age_calculator/internal/routes"
+	"github.com/valyala/fasthttp"
+	"go.uber.org/zap"
+)
+
+func NewFiberConfig(cfg *config.Config) fiber.Config {
+	return fiber.Config{
+		ErrorHandler:   middleware.NewErrorHandler(cfg.StrictStatusCodes),
+		AppName:        "User API " + buildinfo.Version,
+		ReadTimeout:    cfg.ServerReadTimeout,
+		WriteTimeout:   cfg.ServerWriteTimeout,
+		IdleTimeout:    cfg.ServerIdleTimeout,
+		ReadBufferSize: cfg.ServerMaxHeaderSize,
+		// Paths are matched exactly; TrailingSlash takes care of a trailing
+		// slash, and a path in another case is not found.
+		StrictRouting: true,
+		CaseSensitive: true,
+	}
+}
+
+func New(cfg *config.Holder, logger *zap.Logger) *fiber.App {
+	app := fiber.New(NewFiberConfig(cfg.Load()))
+
+	app.Use(cors.New())
+	app.Use(recover.New())
+	app.Use(middleware.RequestID())
+	app.Use(middleware.Logger(logger, cfg))
+	app.Use(middleware.TrailingSlash())
+	if c := cfg.Load(); c.ResponseEnvelope {
+		app.Use(middleware.Envelope(clock.Real(), c.StrictStatusCodes, routes.GraphQLPath))
+	}
+	app.Use(middleware.Timeout(cfg, routes.StreamingPrefixes()...))
+	app.Use(routes.Options())
+
+	return app
+}
+
+// StreamingWriteTimeout relaxes the server-wide write timeout for requests
+// whose path starts with one of prefixes, so long-running streaming responses
+// such as exports are not cut off mid-body.
+func StreamingWriteTimeout(app *fiber.App, timeout time.Duration, prefixes ...string) {
+	if len(prefixes) == 0 || timeout <= 0 {
+		return
+	}
+
+	app.Server().HeaderReceived = func(header *fasthttp.RequestHeader) fasthttp.RequestConfig {
+		path := string(header.RequestURI())
+		if i := strings.IndexByte(path, '?'); i >= 0 {
+			path = path[:i]
+		}
+
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(path, prefix) {
+				return fasthttp.RequestConfig{WriteTimeout: timeout}
+			}
+		}
+		return fasthttp.RequestConfig{}
+	}
+}
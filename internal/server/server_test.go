@@ -0,0 +1,201 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/srinivasarynh/age_calculator/config"
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/handler"
+	"github.com/srinivasarynh/age_calculator/internal/middleware"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/routes"
+	"github.com/srinivasarynh/age_calculator/internal/service"
+	"github.com/valyala/fasthttp"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func testConfig() *config.Config {
+	return &config.Config{
+		ServerReadTimeout:        3 * time.Second,
+		ServerWriteTimeout:       4 * time.Second,
+		ServerIdleTimeout:        5 * time.Second,
+		ServerStreamWriteTimeout: 10 * time.Minute,
+		ServerMaxHeaderSize:      2048,
+	}
+}
+
+func TestNewAppAppliesConfig(t *testing.T) {
+	app := New(config.NewHolder(testConfig()), zap.NewNop())
+	got := app.Config()
+
+	if got.ReadTimeout != 3*time.Second {
+		t.Errorf("ReadTimeout = %v, want 3s", got.ReadTimeout)
+	}
+	if got.WriteTimeout != 4*time.Second {
+		t.Errorf("WriteTimeout = %v, want 4s", got.WriteTimeout)
+	}
+	if got.IdleTimeout != 5*time.Second {
+		t.Errorf("IdleTimeout = %v, want 5s", got.IdleTimeout)
+	}
+	if got.ReadBufferSize != 2048 {
+		t.Errorf("ReadBufferSize = %d, want 2048", got.ReadBufferSize)
+	}
+
+	srv := app.Server()
+	if srv.ReadTimeout != 3*time.Second || srv.WriteTimeout != 4*time.Second || srv.IdleTimeout != 5*time.Second {
+		t.Errorf("fasthttp server timeouts = %v/%v/%v", srv.ReadTimeout, srv.WriteTimeout, srv.IdleTimeout)
+	}
+}
+
+func TestStreamingWriteTimeout(t *testing.T) {
+	app := New(config.NewHolder(testConfig()), zap.NewNop())
+	StreamingWriteTimeout(app, time.Hour, "/api/v1/users/export")
+
+	hook := app.Server().HeaderReceived
+	if hook == nil {
+		t.Fatal("HeaderReceived hook not installed")
+	}
+
+	tests := []struct {
+		uri      string
+		expected time.Duration
+	}{
+		{uri: "/api/v1/users/export", expected: time.Hour},
+		{uri: "/api/v1/users/export?format=csv", expected: time.Hour},
+		{uri: "/api/v1/users", expected: 0},
+		{uri: "/health", expected: 0},
+	}
+
+	for _, tt := range tests {
+		var header fasthttp.RequestHeader
+		header.SetRequestURI(tt.uri)
+		if got := hook(&header).WriteTimeout; got != tt.expected {
+			t.Errorf("WriteTimeout for %s = %v, want %v", tt.uri, got, tt.expected)
+		}
+	}
+}
+
+func TestSlowClientIsDisconnected(t *testing.T) {
+	cfg := testConfig()
+	cfg.ServerReadTimeout = 200 * time.Millisecond
+
+	app := New(config.NewHolder(cfg), zap.NewNop())
+	app.Get("/health", func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go app.Listener(ln)
+	defer app.Shutdown()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("GET /health HTTP/1.1\r\nHost: localhost\r\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	start := time.Now()
+	_, err = io.ReadAll(bufio.NewReader(conn))
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		t.Fatal("server kept the slow connection open")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("disconnect took %v, want about the read timeout", elapsed)
+	}
+}
+
+func TestOptionsKeepsCORS(t *testing.T) {
+	app := New(config.NewHolder(testConfig()), zap.NewNop())
+
+	req := httptest.NewRequest("OPTIONS", "/api/v1/users/1", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusNoContent || resp.Header.Get("Allow") != "GET, HEAD, PUT, PATCH, DELETE, OPTIONS" {
+		t.Errorf("OPTIONS = %d, Allow %q", resp.StatusCode, resp.Header.Get("Allow"))
+	}
+
+	// A preflight is the CORS middleware's to answer.
+	req.Header.Set("Access-Control-Request-Method", "PUT")
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusNoContent || resp.Header.Get("Access-Control-Allow-Origin") != "*" || resp.Header.Get("Access-Control-Allow-Methods") == "" {
+		t.Errorf("preflight = %d %v, want the CORS headers", resp.StatusCode, resp.Header)
+	}
+}
+
+func TestPathVariants(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	app := New(config.NewHolder(testConfig()), zap.New(core))
+	svc := service.NewUserService(repository.NewMemoryUserRepository(clock.Real()), zap.NewNop())
+	routes.SetupRoutes(app, handler.NewUserHandler(svc, zap.NewNop()), middleware.AdminOnly(config.NewHolder(&config.Config{})), middleware.Tenant(config.NewHolder(&config.Config{})), routes.CachePolicies{})
+	routes.SetupSystemRoutes(app, handler.NewSystemHandler(nil), func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) }, routes.CachePolicies{})
+
+	alice := `{"name":"Alice","dob":"1990-05-10"}`
+	tests := []struct {
+		method, target, body string
+		status               int
+		location             string
+	}{
+		{"GET", "/api/v1/users", "", fiber.StatusOK, ""},
+		{"GET", "/api/v1/users/", "", fiber.StatusPermanentRedirect, "/api/v1/users"},
+		{"GET", "/api/v1/users//?page=2&page_size=5", "", fiber.StatusPermanentRedirect, "/api/v1/users?page=2&page_size=5"},
+		{"HEAD", "/api/v1/users/", "", fiber.StatusPermanentRedirect, "/api/v1/users"},
+		{"GET", "/health/", "", fiber.StatusPermanentRedirect, "/health"},
+		{"POST", "/api/v1/users/", alice, fiber.StatusCreated, ""},
+		{"PUT", "/api/v1/users/1/", alice, fiber.StatusOK, ""},
+		{"GET", "/api/v1/users/1", "", fiber.StatusOK, ""},
+		{"GET", "/API/v1/users", "", fiber.StatusNotFound, ""},
+		{"GET", "/api/v1/Users/1", "", fiber.StatusNotFound, ""},
+		{"POST", "/api/V1/users", alice, fiber.StatusNotFound, ""},
+		{"DELETE", "/api/v1/USERS/1/", "", fiber.StatusNotFound, ""},
+		{"OPTIONS", "/api/v1/users/", "", fiber.StatusNoContent, ""},
+		{"GET", "/Health", "", fiber.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		var body io.Reader
+		if tt.body != "" {
+			body = strings.NewReader(tt.body)
+		}
+		req := httptest.NewRequest(tt.method, tt.target, body)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Request-ID", "variant")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tt.status || resp.Header.Get("Location") != tt.location {
+			t.Errorf("%s %s = %d, Location %q; want %d, %q", tt.method, tt.target, resp.StatusCode, resp.Header.Get("Location"), tt.status, tt.location)
+		}
+		if resp.Header.Get("X-Request-ID") != "variant" {
+			t.Errorf("%s %s: X-Request-ID = %q, want the request's", tt.method, tt.target, resp.Header.Get("X-Request-ID"))
+		}
+	}
+
+	if logged := logs.FilterMessage("HTTP Request").FilterField(zap.Int("status", fiber.StatusPermanentRedirect)).Len(); logged != 4 {
+		t.Errorf("logged %d redirects, want 4", logged)
+	}
+}
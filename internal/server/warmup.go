@@ -0,0 +1,42 @@
+package server
+
+import (
+	"context"
+
+	"github.com/srinivasarynh/age_calculator/config"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+	"go.uber.org/zap"
+)
+
+// warmup returns the warm-up to run once the database is reachable. Within
+// WARMUP_TIMEOUT it prepares the hottest statements on preparer, when
+// DB_PREPARED_STATEMENTS allows, then counts each tenant's users through
+// users so the first counts find the rows in the database's cache. A failure
+// is only logged: requests run as they would have without the warm-up.
+func warmup(c *config.Config, preparer repository.StatementPreparer, users repository.UserRepository, logger *zap.Logger) func(ctx context.Context) {
+	return func(ctx context.Context) {
+		if c.WarmupTimeout <= 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(ctx, c.WarmupTimeout)
+		defer cancel()
+
+		if c.DBPreparedStatements {
+			if err := preparer.PrepareStatements(ctx); err != nil {
+				logger.Warn("Failed to prepare statements, running queries unprepared", zap.Error(err))
+			}
+		}
+		tenants := c.Tenants
+		if len(tenants) == 0 {
+			tenants = []string{tenant.Default}
+		}
+		for _, id := range tenants {
+			if _, err := users.Count(tenant.WithID(ctx, id), repository.UserFilter{}); err != nil {
+				logger.Warn("Warm-up stopped", zap.String("tenant", id), zap.Error(err))
+				return
+			}
+		}
+		logger.Info("Warm-up done", zap.Int("tenants", len(tenants)))
+	}
+}
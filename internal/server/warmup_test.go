@@ -0,0 +1,107 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/config"
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// hangingPreparer stands for a database that never answers the prepare, or
+// refuses it when err is set.
+type hangingPreparer struct {
+	err   error
+	calls int
+}
+
+func (p *hangingPreparer) PrepareStatements(ctx context.Context) error {
+	p.calls++
+	if p.err != nil {
+		return p.err
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// countingUsers records the tenant of each Count, which fails once ctx is
+// done as a query would.
+type countingUsers struct {
+	repository.UserRepository
+	tenants []string
+}
+
+func (u *countingUsers) Count(ctx context.Context, filter repository.UserFilter) (int64, error) {
+	u.tenants = append(u.tenants, tenant.FromContext(ctx))
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return u.UserRepository.Count(ctx, filter)
+}
+
+func TestWarmup(t *testing.T) {
+	refused := errors.New("prepared statements are not supported in transaction pooling mode")
+	tests := []struct {
+		name     string
+		cfg      config.Config
+		prepare  error
+		prepared int
+		counted  []string
+		warning  string
+	}{
+		{name: "disabled", cfg: config.Config{DBPreparedStatements: true}},
+		{
+			name:     "prepare refused",
+			cfg:      config.Config{WarmupTimeout: time.Second, DBPreparedStatements: true, Tenants: []string{"acme", "globex"}},
+			prepare:  refused,
+			prepared: 1,
+			counted:  []string{"acme", "globex"},
+			warning:  refused.Error(),
+		},
+		{
+			name:    "prepared statements off",
+			cfg:     config.Config{WarmupTimeout: time.Second},
+			counted: []string{tenant.Default},
+		},
+		{
+			// The prepare gives up at the timeout, and the first count fails.
+			name:     "prepare hangs",
+			cfg:      config.Config{WarmupTimeout: 20 * time.Millisecond, DBPreparedStatements: true, Tenants: []string{"acme", "globex"}},
+			prepared: 1,
+			counted:  []string{"acme"},
+			warning:  context.DeadlineExceeded.Error(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.WarnLevel)
+			preparer := &hangingPreparer{err: tt.prepare}
+			users := &countingUsers{UserRepository: repository.NewMemoryUserRepository(clock.Real())}
+
+			start := time.Now()
+			warmup(&tt.cfg, preparer, users, zap.New(core))(context.Background())
+			if elapsed := time.Since(start); elapsed > tt.cfg.WarmupTimeout+time.Second {
+				t.Errorf("warm-up took %v, past its %v timeout", elapsed, tt.cfg.WarmupTimeout)
+			}
+
+			if preparer.calls != tt.prepared || !slices.Equal(users.tenants, tt.counted) {
+				t.Errorf("prepared %d times and counted %v; want %d and %v", preparer.calls, users.tenants, tt.prepared, tt.counted)
+			}
+			warned := logs.FilterMessage("Failed to prepare statements, running queries unprepared").All()
+			if tt.warning == "" && len(warned) != 0 {
+				t.Errorf("warned %v", warned)
+			}
+			if tt.warning != "" && (len(warned) != 1 || warned[0].ContextMap()["error"] != tt.warning) {
+				t.Errorf("warnings = %v, want one for %q", warned, tt.warning)
+			}
+		})
+	}
+}
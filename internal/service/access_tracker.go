@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+	"go.uber.org/zap"
+)
+
+// ErrAccessTrackingDisabled is returned for include=access_count by a server
+// that counts no accesses.
+var ErrAccessTrackingDisabled = errors.New("access tracking not enabled")
+
+// maxAccessBatch bounds the users one write of the counts covers.
+const maxAccessBatch = 1000
+
+type access struct {
+	tenantID string
+	userID   int32
+	at       time.Time
+}
+
+type accessKey struct {
+	tenantID string
+	userID   int32
+}
+
+// AccessTracker counts the reads of each user off the read path: Record only
+// queues the read, and once started the tracker adds the queued reads up and
+// writes them, one batch every interval. A crash loses the reads since the
+// last write; a full buffer, the reads it cannot take. A failed write is
+// retried with the next.
+type AccessTracker struct {
+	repo     repository.AccessRepository
+	logger   *zap.Logger
+	clock    clock.Clock
+	interval time.Duration
+	accesses chan access
+	dropped  atomic.Int64
+
+	// pending is the reads taken off accesses and not yet written.
+	pendingMu sync.Mutex
+	pending   map[accessKey]*repository.AccessIncrement
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   sync.WaitGroup
+}
+
+type AccessOption func(*AccessTracker)
+
+func WithAccessClock(c clock.Clock) AccessOption {
+	return func(t *AccessTracker) {
+		t.clock = c
+	}
+}
+
+// WithAccessFlushInterval sets how often Start writes the counts.
+func WithAccessFlushInterval(interval time.Duration) AccessOption {
+	return func(t *AccessTracker) {
+		t.interval = interval
+	}
+}
+
+// WithAccessBufferSize sets how many reads can wait to be added up; Record
+// drops those beyond it.
+func WithAccessBufferSize(n int) AccessOption {
+	return func(t *AccessTracker) {
+		t.accesses = make(chan access, n)
+	}
+}
+
+func NewAccessTracker(repo repository.AccessRepository, logger *zap.Logger, opts ...AccessOption) *AccessTracker {
+	t := &AccessTracker{
+		repo:     repo,
+		logger:   logger,
+		clock:    clock.Real(),
+		interval: 5 * time.Second,
+		accesses: make(chan access, 1024),
+		pending:  make(map[accessKey]*repository.AccessIncrement),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Record counts a read of userID in ctx's tenant, never waiting for it.
+func (t *AccessTracker) Record(ctx context.Context, userID int32) {
+	select {
+	case t.accesses <- access{tenantID: tenant.FromContext(ctx), userID: userID, at: t.clock.Now()}:
+	default:
+		t.dropped.Add(1)
+	}
+}
+
+// Get is userID's count as of the last write.
+func (t *AccessTracker) Get(ctx context.Context, userID int32) (*models.AccessCount, error) {
+	return t.repo.Get(ctx, userID)
+}
+
+// Flush writes the reads recorded so far and returns how many users it
+// wrote counts for. On error the reads are kept for the next flush.
+func (t *AccessTracker) Flush(ctx context.Context) (int, error) {
+	t.pendingMu.Lock()
+	defer t.pendingMu.Unlock()
+	t.drain()
+
+	written := 0
+	batch := make([]repository.AccessIncrement, 0, min(len(t.pending), maxAccessBatch))
+	for len(t.pending) > 0 {
+		batch = batch[:0]
+		for _, inc := range t.pending {
+			batch = append(batch, *inc)
+			if len(batch) == maxAccessBatch {
+				break
+			}
+		}
+		if err := t.repo.Add(ctx, batch); err != nil {
+			return written, err
+		}
+		for _, inc := range batch {
+			delete(t.pending, accessKey{tenantID: inc.TenantID, userID: inc.UserID})
+		}
+		written += len(batch)
+	}
+	if dropped := t.dropped.Swap(0); dropped > 0 {
+		t.logger.Warn("Access buffer full, reads not counted", zap.Int64("dropped", dropped))
+	}
+	return written, nil
+}
+
+// drain adds the queued reads to pending; pendingMu must be held.
+func (t *AccessTracker) drain() {
+	for {
+		select {
+		case a := <-t.accesses:
+			t.add(a)
+		default:
+			return
+		}
+	}
+}
+
+func (t *AccessTracker) add(a access) {
+	key := accessKey{tenantID: a.tenantID, userID: a.userID}
+	inc, ok := t.pending[key]
+	if !ok {
+		inc = &repository.AccessIncrement{TenantID: a.tenantID, UserID: a.userID}
+		t.pending[key] = inc
+	}
+	inc.Count++
+	if a.at.After(inc.LastAccessedAt) {
+		inc.LastAccessedAt = a.at
+	}
+}
+
+// Start adds the reads up as they come and writes them every interval until
+// Stop.
+func (t *AccessTracker) Start(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.cancel != nil {
+		return errors.New("access tracker already started")
+	}
+	ctx, t.cancel = context.WithCancel(ctx)
+
+	t.done.Add(1)
+	go func() {
+		defer t.done.Done()
+		t.loop(ctx)
+	}()
+	return nil
+}
+
+// Stop ends the loop and writes the reads left, giving up at ctx.
+func (t *AccessTracker) Stop(ctx context.Context) error {
+	t.mu.Lock()
+	cancel := t.cancel
+	t.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	stopped := make(chan struct{})
+	go func() {
+		t.done.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	_, err := t.Flush(ctx)
+	return err
+}
+
+func (t *AccessTracker) loop(ctx context.Context) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case a := <-t.accesses:
+			// Taken off at once, so the buffer only has to hold a burst.
+			t.pendingMu.Lock()
+			t.add(a)
+			t.pendingMu.Unlock()
+		case <-ticker.C:
+			if written, err := t.Flush(ctx); err != nil && ctx.Err() == nil {
+				t.logger.Warn("Failed to write access counts, retrying later", zap.Int("written", written), zap.Error(err))
+			}
+		}
+	}
+}
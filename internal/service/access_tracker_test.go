@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+	"go.uber.org/zap"
+)
+
+// recordingAccesses keeps the batches written to it, failing them while err
+// is set.
+type recordingAccesses struct {
+	repository.AccessRepository
+	mu      sync.Mutex
+	batches [][]repository.AccessIncrement
+	err     error
+}
+
+func (r *recordingAccesses) Add(ctx context.Context, increments []repository.AccessIncrement) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	r.batches = append(r.batches, append([]repository.AccessIncrement(nil), increments...))
+	return r.AccessRepository.Add(ctx, increments)
+}
+
+func (r *recordingAccesses) writes() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.batches)
+}
+
+func TestAccessTrackerBatchesReads(t *testing.T) {
+	c := &manualClock{now: pinnedNow}
+	users := repository.NewMemoryUserRepository(c)
+	ctx := context.Background()
+	alice, _ := users.Create(ctx, "Alice", time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC))
+	bob, _ := users.Create(ctx, "Bob", time.Date(2000, 2, 29, 0, 0, 0, 0, time.UTC))
+	accesses := &recordingAccesses{AccessRepository: repository.NewMemoryAccessRepository()}
+	tracker := NewAccessTracker(accesses, zap.NewNop(), WithAccessClock(c))
+	svc := NewUserService(users, zap.NewNop(), WithClock(c), WithAccessTracker(tracker))
+
+	for range 3 {
+		if _, err := svc.GetUser(ctx, alice.ID); err != nil {
+			t.Fatal(err)
+		}
+		c.Advance(time.Minute)
+	}
+	if _, err := svc.GetUser(ctx, bob.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.GetUser(ctx, 999); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("GetUser of a missing user: err = %v", err)
+	}
+	if n := accesses.writes(); n != 0 {
+		t.Fatalf("%d writes before the flush; reads must not wait on them", n)
+	}
+
+	written, err := tracker.Flush(ctx)
+	if err != nil || written != 2 || accesses.writes() != 1 {
+		t.Fatalf("Flush = %d, %v after %d writes; want both users in one write", written, err, accesses.writes())
+	}
+	got, err := svc.GetUser(WithAccessCount(ctx), alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lastRead := pinnedNow.Add(2 * time.Minute)
+	if want := (models.AccessCount{Total: 3, LastAccessedAt: lastRead}); got.AccessCount == nil || *got.AccessCount != want {
+		t.Errorf("access_count = %+v, want %+v", got.AccessCount, want)
+	}
+	if !got.LastModified.Equal(lastRead) {
+		t.Errorf("LastModified = %v, want the last read at %v", got.LastModified, lastRead)
+	}
+
+	// The read just made is counted by the next flush, which writes nothing
+	// more when nothing more was read.
+	if written, err := tracker.Flush(ctx); err != nil || written != 1 {
+		t.Errorf("second Flush = %d, %v; want alice's last read", written, err)
+	}
+	if written, err := tracker.Flush(ctx); err != nil || written != 0 || accesses.writes() != 2 {
+		t.Errorf("idle Flush = %d, %v after %d writes; want no write", written, err, accesses.writes())
+	}
+}
+
+func TestAccessTrackerKeepsReadsWhenWriteFails(t *testing.T) {
+	ctx := tenant.WithID(context.Background(), "acme")
+	accesses := &recordingAccesses{AccessRepository: repository.NewMemoryAccessRepository(), err: errors.New("connection refused")}
+	tracker := NewAccessTracker(accesses, zap.NewNop())
+
+	tracker.Record(ctx, 1)
+	if _, err := tracker.Flush(ctx); err == nil {
+		t.Fatal("Flush with the database down: err = nil")
+	}
+	tracker.Record(ctx, 1)
+	accesses.err = nil
+	if written, err := tracker.Flush(ctx); err != nil || written != 1 {
+		t.Fatalf("Flush once the database is back = %d, %v", written, err)
+	}
+	if count, err := tracker.Get(ctx, 1); err != nil || count.Total != 2 {
+		t.Errorf("count = %+v, %v; want both reads, in acme", count, err)
+	}
+}
+
+func TestAccessTrackerDropsReadsBeyondBuffer(t *testing.T) {
+	ctx := context.Background()
+	tracker := NewAccessTracker(repository.NewMemoryAccessRepository(), zap.NewNop(), WithAccessBufferSize(2))
+
+	for range 5 {
+		tracker.Record(ctx, 1)
+	}
+	if _, err := tracker.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := tracker.Get(ctx, 1); count.Total != 2 {
+		t.Errorf("count = %d, want the 2 reads the buffer held", count.Total)
+	}
+}
+
+func TestAccessTrackerStopWritesLastReads(t *testing.T) {
+	ctx := context.Background()
+	accesses := &recordingAccesses{AccessRepository: repository.NewMemoryAccessRepository()}
+	tracker := NewAccessTracker(accesses, zap.NewNop(), WithAccessFlushInterval(time.Hour))
+	if err := tracker.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for range 10 {
+		tracker.Record(ctx, 1)
+	}
+	if err := tracker.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := tracker.Get(ctx, 1); count.Total != 10 || accesses.writes() != 1 {
+		t.Errorf("count = %d in %d writes, want all 10 in one", count.Total, accesses.writes())
+	}
+}
+
+func TestAccessCountWithoutTracking(t *testing.T) {
+	users := repository.NewMemoryUserRepository(&manualClock{now: pinnedNow})
+	ctx := context.Background()
+	alice, _ := users.Create(ctx, "Alice", time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC))
+	svc := NewUserService(users, zap.NewNop())
+
+	got, err := svc.GetUser(ctx, alice.ID)
+	if err != nil || got.AccessCount != nil {
+		t.Errorf("GetUser = %+v, %v; want no access_count", got, err)
+	}
+	if _, err := svc.GetUser(WithAccessCount(ctx), alice.ID); !errors.Is(err, ErrAccessTrackingDisabled) {
+		t.Errorf("GetUser asking for access_count: err = %v, want ErrAccessTrackingDisabled", err)
+	}
+}
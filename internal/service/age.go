@@ -0,0 +1,120 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+)
+
+const dateLayout = "2006-01-02"
+
+type AgeBreakdown struct {
+	Years  int `json:"years"`
+	Months int `json:"months"`
+	Days   int `json:"days"`
+}
+
+func ParseDOB(value string) (time.Time, error) {
+	dob, err := time.ParseInLocation(dateLayout, value, time.UTC)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %v", ErrInvalidDate, err)
+	}
+	return dob, nil
+}
+
+// parseDOB is ParseDOB for a DOB about to be stored, which must also not be
+// before the minimum year.
+func (s *userService) parseDOB(value string) (time.Time, error) {
+	dob, err := ParseDOB(value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if dob.Year() < s.minDOBYear {
+		return time.Time{}, fmt.Errorf("%w: %s is before %d", ErrDOBTooEarly, value, s.minDOBYear)
+	}
+	return dob, nil
+}
+
+// dobError is the detail for an error of parseDOB, in the handler's terms.
+func dobError(err error, minYear int) models.FieldError {
+	if errors.Is(err, ErrDOBTooEarly) {
+		return models.FieldError{Field: "dob", Rule: "min_year", Param: strconv.Itoa(minYear), Message: fmt.Sprintf("dob must be in %d or later", minYear)}
+	}
+	return models.FieldError{Field: "dob", Rule: "datetime", Param: dateLayout, Message: "dob must be a date in the format " + dateLayout}
+}
+
+// CalculateAge returns whole years between dob and now. A dob after now
+// yields 0; callers that need to tell that apart from a newborn use
+// IsFutureDOB.
+func CalculateAge(dob, now time.Time) int {
+	nowYear, nowMonth, nowDay := now.Date()
+	dobYear, dobMonth, dobDay := dob.Date()
+	age := nowYear - dobYear
+
+	if nowMonth < dobMonth || (nowMonth == dobMonth && nowDay < dobDay) {
+		age--
+	}
+	if age < 0 {
+		return 0
+	}
+	return age
+}
+
+func IsFutureDOB(dob, now time.Time) bool {
+	return toDate(dob).After(toDate(now))
+}
+
+// CalculateAgeBreakdown splits the time between dob and now into whole years,
+// months and days. A monthly anniversary that does not exist in a short month
+// rolls over into the next one, the same way CalculateAge treats a Feb 29
+// birthday as falling on Mar 1 in common years.
+func CalculateAgeBreakdown(dob, now time.Time) AgeBreakdown {
+	dob = toDate(dob)
+	now = toDate(now)
+	if now.Before(dob) {
+		return AgeBreakdown{}
+	}
+
+	months := (now.Year()-dob.Year())*12 + int(now.Month()-dob.Month())
+	for monthAnniversary(dob, months).After(now) {
+		months--
+	}
+
+	anchor := monthAnniversary(dob, months)
+	return AgeBreakdown{
+		Years:  months / 12,
+		Months: months % 12,
+		Days:   int(now.Sub(anchor).Hours() / 24),
+	}
+}
+
+// AgeInMonthsAndWeeks counts the whole months of CalculateAgeBreakdown and
+// the whole weeks since dob, as of now's date; a dob after now yields 0.
+func AgeInMonthsAndWeeks(dob, now time.Time) (months, weeks int) {
+	b := CalculateAgeBreakdown(dob, now)
+	return b.Years*12 + b.Months, max(int(toDate(now).Sub(toDate(dob)).Hours()/24)/7, 0)
+}
+
+func (b AgeBreakdown) From(dob time.Time) time.Time {
+	return monthAnniversary(toDate(dob), b.Years*12+b.Months).AddDate(0, 0, b.Days)
+}
+
+// latestDOBForAge is the latest DOB for which CalculateAge reports at least
+// years on now. When that birthday does not exist, as Feb 29 in a common year,
+// it is the last day of the month.
+func latestDOBForAge(now time.Time, years int) time.Time {
+	year, month, day := now.Date()
+	lastDay := time.Date(year-years, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	return time.Date(year-years, month, min(day, lastDay), 0, 0, 0, 0, time.UTC)
+}
+
+func monthAnniversary(dob time.Time, months int) time.Time {
+	return time.Date(dob.Year(), dob.Month()+time.Month(months), dob.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func toDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
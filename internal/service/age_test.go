@@ -0,0 +1,212 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestCalculateAgeBreakdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		dob      time.Time
+		now      time.Time
+		expected AgeBreakdown
+	}{
+		{name: "same day", dob: date(1990, 5, 10), now: date(1990, 5, 10), expected: AgeBreakdown{}},
+		{name: "birthday", dob: date(1990, 5, 10), now: date(2025, 5, 10), expected: AgeBreakdown{Years: 35}},
+		{name: "day before birthday", dob: date(1990, 5, 10), now: date(2025, 5, 9), expected: AgeBreakdown{Years: 34, Months: 11, Days: 29}},
+		{name: "end of month into short month", dob: date(2024, 1, 31), now: date(2024, 2, 29), expected: AgeBreakdown{Days: 29}},
+		{name: "end of month rolls over", dob: date(2023, 1, 31), now: date(2023, 3, 3), expected: AgeBreakdown{Months: 1}},
+		{name: "leapling before rollover", dob: date(2000, 2, 29), now: date(2001, 2, 28), expected: AgeBreakdown{Months: 11, Days: 30}},
+		{name: "leapling on Mar 1", dob: date(2000, 2, 29), now: date(2001, 3, 1), expected: AgeBreakdown{Years: 1}},
+		{name: "future dob", dob: date(2030, 1, 1), now: date(2025, 1, 1), expected: AgeBreakdown{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CalculateAgeBreakdown(tt.dob, tt.now)
+			if got != tt.expected {
+				t.Errorf("CalculateAgeBreakdown(%s, %s) = %+v, want %+v",
+					tt.dob.Format(dateLayout), tt.now.Format(dateLayout), got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseDOB(t *testing.T) {
+	if _, err := ParseDOB("1990-05-10"); err != nil {
+		t.Errorf("ParseDOB(valid) error = %v", err)
+	}
+	for _, input := range []string{"", "1990-5-10", "1990-02-30", "10-05-1990", "1990-05-10T00:00:00Z"} {
+		if _, err := ParseDOB(input); !errors.Is(err, ErrInvalidDate) {
+			t.Errorf("ParseDOB(%q) error = %v, want ErrInvalidDate", input, err)
+		}
+	}
+}
+
+func FuzzParseDOB(f *testing.F) {
+	for _, seed := range []string{
+		"1990-05-10", "2000-02-29", "1900-02-29", "2024-12-31", "0001-01-01", "9999-12-31",
+		"", "1990-1-1", "1990-00-10", "1990-05-32", "+990-05-10", "１９９０-05-10", "\x00\xff",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		dob, err := ParseDOB(input)
+		if err != nil {
+			if !errors.Is(err, ErrInvalidDate) {
+				t.Fatalf("ParseDOB(%q) error %v does not wrap ErrInvalidDate", input, err)
+			}
+			return
+		}
+
+		if got := dob.Format(dateLayout); got != input {
+			t.Fatalf("ParseDOB(%q) round-trips to %q", input, got)
+		}
+		if dob.Location() != time.UTC || dob.Hour() != 0 || dob.Minute() != 0 || dob.Second() != 0 {
+			t.Fatalf("ParseDOB(%q) = %v, want midnight UTC", input, dob)
+		}
+	})
+}
+
+func FuzzCalculateAgeBreakdown(f *testing.F) {
+	seeds := [][6]int{
+		{2000, 2, 29, 2025, 2, 28},
+		{2000, 2, 29, 2025, 3, 1},
+		{2000, 2, 29, 2028, 2, 29},
+		{2023, 1, 31, 2023, 3, 1},
+		{2023, 1, 31, 2023, 2, 28},
+		{1990, 12, 31, 2025, 1, 1},
+		{1, 1, 1, 9999, 12, 31},
+		{9999, 12, 31, 9999, 12, 31},
+		{1990, 5, 10, 1980, 1, 1},
+	}
+	for _, s := range seeds {
+		f.Add(s[0], s[1], s[2], s[3], s[4], s[5])
+	}
+
+	f.Fuzz(func(t *testing.T, dobY, dobM, dobD, nowY, nowM, nowD int) {
+		dob := date(dobY%9999+1, time.Month(dobM%12+1), dobD%31+1)
+		now := date(nowY%9999+1, time.Month(nowM%12+1), nowD%31+1)
+		if dob.Year() < 1 || dob.Year() > 9999 || now.Year() < 1 || now.Year() > 9999 {
+			t.Skip()
+		}
+
+		b := CalculateAgeBreakdown(dob, now)
+		if b.Years < 0 || b.Months < 0 || b.Months > 11 || b.Days < 0 || b.Days > 30 {
+			t.Fatalf("breakdown %+v out of range for dob %s now %s", b, dob.Format(dateLayout), now.Format(dateLayout))
+		}
+
+		if now.Before(dob) {
+			if b != (AgeBreakdown{}) {
+				t.Fatalf("future dob %s produced %+v", dob.Format(dateLayout), b)
+			}
+			return
+		}
+
+		if age := CalculateAge(dob, now); age < 0 || age != b.Years {
+			t.Fatalf("CalculateAge(%s, %s) = %d, breakdown years %d", dob.Format(dateLayout), now.Format(dateLayout), age, b.Years)
+		}
+		if got := b.From(dob); !got.Equal(now) {
+			t.Fatalf("%+v from %s = %s, want %s", b, dob.Format(dateLayout), got.Format(dateLayout), now.Format(dateLayout))
+		}
+	})
+}
+
+func TestHumanAge(t *testing.T) {
+	tests := []struct {
+		species string
+		dob     time.Time
+		now     time.Time
+		want    float64
+	}{
+		{species: "dog", dob: date(2024, 12, 15), now: date(2025, 6, 15), want: 15.5}, // 182/365 of a year, on the line to 31
+		{species: "dog", dob: date(2024, 6, 15), now: date(2025, 6, 15), want: 31},
+		{species: "dog", dob: date(2023, 6, 15), now: date(2025, 6, 15), want: 42.1},
+		{species: "dog", dob: date(2015, 6, 15), now: date(2025, 6, 15), want: 67.8},
+		{species: "dog", dob: date(2030, 1, 1), now: date(2025, 6, 15), want: 0},
+		{species: "cat", dob: date(2025, 1, 1), now: date(2025, 7, 2), want: 7.5},
+		{species: "cat", dob: date(2024, 6, 15), now: date(2025, 6, 15), want: 15},
+		{species: "cat", dob: date(2023, 12, 15), now: date(2025, 6, 15), want: 19.5},
+		{species: "cat", dob: date(2023, 6, 15), now: date(2025, 6, 15), want: 24},
+		{species: "cat", dob: date(2015, 6, 15), now: date(2025, 6, 15), want: 56},
+		{species: "human", dob: date(1990, 1, 1), now: date(2025, 7, 2), want: 35.5},
+		{species: "human", dob: date(2000, 2, 29), now: date(2025, 3, 1), want: 25},
+	}
+	for _, tt := range tests {
+		if got := HumanAge(tt.species, tt.dob, tt.now); got != tt.want {
+			t.Errorf("HumanAge(%s, %s, %s) = %v, want %v", tt.species, tt.dob.Format(dateLayout), tt.now.Format(dateLayout), got, tt.want)
+		}
+	}
+}
+
+func TestNextHalfBirthday(t *testing.T) {
+	tests := []struct {
+		dob, today, want time.Time
+	}{
+		{dob: date(1990, 8, 31), today: date(2025, 1, 10), want: date(2025, 2, 28)},
+		{dob: date(1990, 8, 31), today: date(2024, 1, 10), want: date(2024, 2, 29)},
+		{dob: date(1990, 8, 31), today: date(2025, 3, 1), want: date(2026, 2, 28)},
+		{dob: date(2000, 8, 29), today: date(2025, 2, 1), want: date(2025, 2, 28)},
+		{dob: date(1990, 3, 31), today: date(2025, 6, 15), want: date(2025, 9, 30)},
+		{dob: date(2000, 2, 29), today: date(2025, 6, 15), want: date(2025, 8, 29)},
+		{dob: date(1990, 12, 15), today: date(2025, 6, 15), want: date(2025, 6, 15)},
+		{dob: date(2025, 6, 15), today: date(2025, 6, 15), want: date(2025, 12, 15)},
+	}
+	for _, tt := range tests {
+		if got := NextHalfBirthday(tt.dob, tt.today); !got.Equal(tt.want) {
+			t.Errorf("NextHalfBirthday(%s, %s) = %s, want %s", tt.dob.Format(dateLayout), tt.today.Format(dateLayout), got.Format(dateLayout), tt.want.Format(dateLayout))
+		}
+	}
+}
+
+func TestNextRoundBirthday(t *testing.T) {
+	tests := []struct {
+		dob, today, want time.Time
+		age              int
+	}{
+		{dob: date(1990, 5, 10), today: date(2025, 6, 15), want: date(2030, 5, 10), age: 40},
+		{dob: date(1995, 6, 15), today: date(2025, 6, 15), want: date(2025, 6, 15), age: 30},
+		{dob: date(1985, 6, 16), today: date(2025, 6, 15), want: date(2025, 6, 16), age: 40},
+		// In a common year a leapling turns 30 on Mar 1, the day the age ticks over.
+		{dob: date(1996, 2, 29), today: date(2026, 2, 28), want: date(2026, 3, 1), age: 30},
+		{dob: date(1996, 2, 29), today: date(2026, 3, 1), want: date(2026, 3, 1), age: 30},
+		{dob: date(2025, 6, 15), today: date(2025, 6, 15), want: date(2035, 6, 15), age: 10},
+	}
+	for _, tt := range tests {
+		if got, age := NextRoundBirthday(tt.dob, tt.today); !got.Equal(tt.want) || age != tt.age {
+			t.Errorf("NextRoundBirthday(%s, %s) = %s, %d; want %s, %d", tt.dob.Format(dateLayout), tt.today.Format(dateLayout), got.Format(dateLayout), age, tt.want.Format(dateLayout), tt.age)
+		}
+	}
+}
+
+func TestLastMilestoneChange(t *testing.T) {
+	tests := []struct {
+		dob, today, want time.Time
+	}{
+		{dob: date(1990, 12, 15), today: date(2025, 6, 15), want: date(2024, 6, 16)},
+		{dob: date(1990, 12, 15), today: date(2025, 6, 20), want: date(2025, 6, 16)},
+		{dob: date(1995, 6, 15), today: date(2025, 6, 16), want: date(2025, 6, 16)},
+	}
+	for _, tt := range tests {
+		if got := lastMilestoneChange(tt.dob, tt.today); !got.Equal(tt.want) {
+			t.Errorf("lastMilestoneChange(%s, %s) = %s, want %s", tt.dob.Format(dateLayout), tt.today.Format(dateLayout), got.Format(dateLayout), tt.want.Format(dateLayout))
+		}
+	}
+}
+
+func TestParseSpecies(t *testing.T) {
+	if species, err := ParseSpecies(""); err != nil || species != SpeciesHuman {
+		t.Errorf(`ParseSpecies("") = %q, %v; want human`, species, err)
+	}
+	var verr *ValidationError
+	if _, err := ParseSpecies("hamster"); !errors.As(err, &verr) || verr.Details[0].Message != "species must be one of cat, dog, human" {
+		t.Errorf("ParseSpecies(hamster) err = %v, want a ValidationError", err)
+	}
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxAggregateEntries bounds the results an aggregate cache holds, since
+	// their keys come from the query string; past it results go uncached.
+	maxAggregateEntries = 1000
+	// aggregateRefreshTimeout bounds a background refresh, which no request
+	// waits on.
+	aggregateRefreshTimeout = 30 * time.Second
+)
+
+type refreshKey struct{}
+
+// WithRefresh has the aggregate endpoints compute their result afresh
+// instead of serving a cached one.
+func WithRefresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, refreshKey{}, true)
+}
+
+// aggregateCache keeps aggregate results per tenant and key. A result older
+// than ttl is still served, while one background refresh per key replaces it.
+// A ttl of 0 caches nothing.
+type aggregateCache[T any] struct {
+	ttl    time.Duration
+	clock  clock.Clock
+	logger *zap.Logger
+
+	mu      sync.Mutex
+	entries map[string]*aggregateEntry[T]
+	resetAt time.Time
+}
+
+type aggregateEntry[T any] struct {
+	value      T
+	at         time.Time
+	refreshing bool
+}
+
+func newAggregateCache[T any](ttl time.Duration, c clock.Clock, logger *zap.Logger) *aggregateCache[T] {
+	return &aggregateCache[T]{ttl: ttl, clock: c, logger: logger, entries: make(map[string]*aggregateEntry[T])}
+}
+
+func (c *aggregateCache[T]) get(ctx context.Context, key string, compute func(context.Context) (T, error)) (T, error) {
+	if c.ttl <= 0 {
+		return compute(ctx)
+	}
+	key = tenant.FromContext(ctx) + "/" + key
+	if refresh, _ := ctx.Value(refreshKey{}).(bool); !refresh {
+		c.mu.Lock()
+		entry, ok := c.entries[key]
+		if ok {
+			if !entry.refreshing && c.clock.Now().Sub(entry.at) >= c.ttl {
+				entry.refreshing = true
+				go c.refresh(context.WithoutCancel(ctx), key, compute)
+			}
+			value := entry.value
+			c.mu.Unlock()
+			return value, nil
+		}
+		c.mu.Unlock()
+	}
+
+	at := c.clock.Now()
+	value, err := compute(ctx)
+	if err != nil {
+		return value, err
+	}
+	c.store(key, value, at, false)
+	return value, nil
+}
+
+func (c *aggregateCache[T]) refresh(ctx context.Context, key string, compute func(context.Context) (T, error)) {
+	ctx, cancel := context.WithTimeout(ctx, aggregateRefreshTimeout)
+	defer cancel()
+
+	at := c.clock.Now()
+	value, err := compute(ctx)
+	if err != nil {
+		c.logger.Warn("Failed to refresh aggregate; serving the stale result", zap.String("key", key), zap.Error(err))
+		c.mu.Lock()
+		if entry, ok := c.entries[key]; ok {
+			entry.refreshing = false
+		}
+		c.mu.Unlock()
+		return
+	}
+	c.store(key, value, at, true)
+}
+
+// reset drops every result, so the next read of each key computes afresh.
+// Results of computations that began before it are not stored.
+func (c *aggregateCache[T]) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+	c.resetAt = c.clock.Now()
+}
+
+// store keeps value computed at at, unless a result computed later is
+// already there or the cache was reset since. done ends the key's background
+// refresh.
+func (c *aggregateCache[T]) store(key string, value T, at time.Time, done bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if at.Before(c.resetAt) {
+		return
+	}
+	entry, ok := c.entries[key]
+	if !ok {
+		if len(c.entries) >= maxAggregateEntries {
+			return
+		}
+		entry = &aggregateEntry[T]{}
+		c.entries[key] = entry
+	}
+	if done {
+		entry.refreshing = false
+	}
+	if !at.Before(entry.at) {
+		entry.value, entry.at = value, at
+	}
+}
+
+func (s *userService) ResetAggregates() {
+	s.cohorts.reset()
+	s.collisions.reset()
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+	"github.com/srinivasarynh/age_calculator/internal/testutil"
+	"go.uber.org/zap"
+)
+
+// countingCohorts counts the cohort queries that reach the store, holding
+// each until gate, when set, is closed, and failing them while err is set.
+type countingCohorts struct {
+	repository.UserRepository
+	queries atomic.Int32
+	gate    chan struct{}
+	mu      sync.Mutex
+	err     error
+}
+
+func (r *countingCohorts) CountByDOBBounds(ctx context.Context, bounds []time.Time) ([]int64, error) {
+	r.queries.Add(1)
+	if r.gate != nil {
+		<-r.gate
+	}
+	r.mu.Lock()
+	err := r.err
+	r.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return r.UserRepository.CountByDOBBounds(ctx, bounds)
+}
+
+func newCachedCohorts(t *testing.T) (UserService, *countingCohorts, *manualClock) {
+	t.Helper()
+	c := &manualClock{now: pinnedNow}
+	repo := &countingCohorts{UserRepository: repository.NewMemoryUserRepository(c)}
+	testutil.MustInsert(t, repo, testutil.NewUserBuilder().WithName("Alice").WithDOB("1990-05-10").Build())
+	return NewUserService(repo, zap.NewNop(), WithClock(c), WithAggregateTTL(time.Minute)), repo, c
+}
+
+func cohortsAsOf(t *testing.T, svc UserService, ctx context.Context) (time.Time, int64) {
+	t.Helper()
+	got, err := svc.AgeCohorts(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got.AsOf, got.Total
+}
+
+func TestAggregateStaleWhileRevalidate(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		svc, repo, c := newCachedCohorts(t)
+		ctx := context.Background()
+
+		if asOf, _ := cohortsAsOf(t, svc, ctx); !asOf.Equal(pinnedNow) || repo.queries.Load() != 1 {
+			t.Fatalf("first read as of %v after %d queries", asOf, repo.queries.Load())
+		}
+		c.Advance(30 * time.Second)
+		if asOf, _ := cohortsAsOf(t, svc, ctx); !asOf.Equal(pinnedNow) || repo.queries.Load() != 1 {
+			t.Errorf("read within the ttl as of %v after %d queries, want the cached one", asOf, repo.queries.Load())
+		}
+
+		// Past the ttl the stale result is served at once and refreshed
+		// behind it.
+		testutil.MustInsert(t, repo, testutil.NewUserBuilder().WithName("Bob").WithDOB("2000-01-01").Build())
+		c.Advance(time.Minute)
+		refreshedAt := c.Now()
+		if asOf, total := cohortsAsOf(t, svc, ctx); !asOf.Equal(pinnedNow) || total != 1 {
+			t.Errorf("stale read = as of %v, total %d; want the old result", asOf, total)
+		}
+		synctest.Wait()
+		if repo.queries.Load() != 2 {
+			t.Fatalf("queries = %d after the stale read, want a background refresh", repo.queries.Load())
+		}
+		if asOf, total := cohortsAsOf(t, svc, ctx); !asOf.Equal(refreshedAt) || total != 2 {
+			t.Errorf("read after the refresh = as of %v, total %d; want %v with Bob", asOf, total, refreshedAt)
+		}
+	})
+}
+
+func TestAggregateRefreshesOnceAtATime(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		svc, repo, c := newCachedCohorts(t)
+		ctx := context.Background()
+		cohortsAsOf(t, svc, ctx)
+
+		repo.gate = make(chan struct{})
+		c.Advance(2 * time.Minute)
+		var wg sync.WaitGroup
+		for range 20 {
+			wg.Go(func() {
+				if asOf, _ := cohortsAsOf(t, svc, ctx); !asOf.Equal(pinnedNow) {
+					t.Errorf("stale read as of %v, want %v", asOf, pinnedNow)
+				}
+			})
+		}
+		wg.Wait()
+		synctest.Wait()
+		if n := repo.queries.Load(); n != 2 {
+			t.Errorf("queries = %d for 20 stale reads, want a single refresh", n)
+		}
+		close(repo.gate)
+	})
+}
+
+func TestResetAggregates(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		svc, repo, c := newCachedCohorts(t)
+		ctx := context.Background()
+		cohortsAsOf(t, svc, ctx)
+
+		testutil.MustInsert(t, repo, testutil.NewUserBuilder().WithName("Bob").WithDOB("2000-01-01").Build())
+		svc.ResetAggregates()
+		if _, total := cohortsAsOf(t, svc, ctx); total != 2 || repo.queries.Load() != 2 {
+			t.Errorf("read after a reset = total %d after %d queries, want Bob counted afresh", total, repo.queries.Load())
+		}
+
+		// A refresh that began before the reset read the old rows, and must
+		// not put them back.
+		repo.gate = make(chan struct{})
+		c.Advance(2 * time.Minute)
+		cohortsAsOf(t, svc, ctx)
+		synctest.Wait()
+		c.Advance(time.Second)
+		svc.ResetAggregates()
+		close(repo.gate)
+		synctest.Wait()
+		repo.gate = nil
+		if asOf, _ := cohortsAsOf(t, svc, ctx); !asOf.Equal(c.Now()) || repo.queries.Load() != 4 {
+			t.Errorf("read after a reset during a refresh = as of %v after %d queries, want computed at %v", asOf, repo.queries.Load(), c.Now())
+		}
+	})
+}
+
+func TestAggregateKeepsStaleResultWhenRefreshFails(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		svc, repo, c := newCachedCohorts(t)
+		ctx := context.Background()
+		cohortsAsOf(t, svc, ctx)
+
+		repo.err = errors.New("connection refused")
+		c.Advance(2 * time.Minute)
+		cohortsAsOf(t, svc, ctx)
+		synctest.Wait()
+		if asOf, _ := cohortsAsOf(t, svc, ctx); !asOf.Equal(pinnedNow) {
+			t.Errorf("read after a failed refresh as of %v, want the stale %v", asOf, pinnedNow)
+		}
+		synctest.Wait()
+
+		repo.mu.Lock()
+		repo.err = nil
+		repo.mu.Unlock()
+		cohortsAsOf(t, svc, ctx)
+		synctest.Wait()
+		if asOf, _ := cohortsAsOf(t, svc, ctx); !asOf.Equal(c.Now()) {
+			t.Errorf("read once the store is back as of %v, want %v", asOf, c.Now())
+		}
+	})
+}
+
+func TestAggregateRefreshAndTenants(t *testing.T) {
+	svc, repo, c := newCachedCohorts(t)
+	ctx := context.Background()
+	cohortsAsOf(t, svc, ctx)
+	c.Advance(time.Second)
+
+	if asOf, _ := cohortsAsOf(t, svc, WithRefresh(ctx)); !asOf.Equal(c.Now()) || repo.queries.Load() != 2 {
+		t.Errorf("forced refresh as of %v after %d queries, want a new query now", asOf, repo.queries.Load())
+	}
+	if asOf, _ := cohortsAsOf(t, svc, ctx); !asOf.Equal(c.Now()) {
+		t.Errorf("read after a forced refresh as of %v, want its result", asOf)
+	}
+	if _, total := cohortsAsOf(t, svc, tenant.WithID(ctx, "acme")); total != 0 || repo.queries.Load() != 3 {
+		t.Errorf("acme's cohorts total %d after %d queries, want its own, empty", total, repo.queries.Load())
+	}
+}
+
+func TestBirthdayCollisionsCached(t *testing.T) {
+	c := &manualClock{now: pinnedNow}
+	repo := repository.NewMemoryUserRepository(c)
+	for _, name := range []string{"Alice", "Bob"} {
+		testutil.MustInsert(t, repo, testutil.NewUserBuilder().WithName(name).WithDOB("1990-05-10").Build())
+	}
+	svc := NewUserService(repo, zap.NewNop(), WithClock(c), WithAggregateTTL(time.Minute))
+	ctx := context.Background()
+
+	first, err := svc.BirthdayCollisions(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.MustInsert(t, repo, testutil.NewUserBuilder().WithName("Carol").WithDOB("1990-05-10").Build())
+	c.Advance(time.Second)
+	second, err := svc.BirthdayCollisions(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := models.BirthdayCollision{Month: 5, Day: 10, Date: "05-10", Count: 2}
+	if !second.AsOf.Equal(pinnedNow) || len(second.Collisions) != 1 || second.Collisions[0] != want || second != first {
+		t.Errorf("collisions within the ttl = %+v, want the cached %+v", second, first)
+	}
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrBackupsDisabled is returned for backups on a server without a
+	// backup repository.
+	ErrBackupsDisabled = errors.New("backups not enabled")
+	// ErrBackupTooNew is a backup written by a server with migrations this
+	// one does not have.
+	ErrBackupTooNew = errors.New("backup schema version is newer than this server's")
+	// ErrInvalidBackup is returned for a restore body that is not a backup
+	// document at all; a document with bad rows is a *ValidationError.
+	ErrInvalidBackup = errors.New("invalid backup file")
+)
+
+// Backups writes the dataset of every tenant as one JSON document and loads
+// such documents back, for moving a small deployment between environments.
+type Backups struct {
+	repo          repository.BackupRepository
+	schemaVersion int64
+	logger        *zap.Logger
+	clock         clock.Clock
+	onRestore     []func(context.Context) error
+}
+
+type BackupOption func(*Backups)
+
+func WithBackupClock(c clock.Clock) BackupOption {
+	return func(b *Backups) {
+		b.clock = c
+	}
+}
+
+// WithRestoreHook has Restore call fn once a restore has committed, to drop
+// what is cached of the rows it replaced. An error from fn is logged; the
+// restore stands.
+func WithRestoreHook(fn func(context.Context) error) BackupOption {
+	return func(b *Backups) {
+		b.onRestore = append(b.onRestore, fn)
+	}
+}
+
+// NewBackups stamps backups with schemaVersion, the last migration this
+// server knows, and restores none from a later one.
+func NewBackups(repo repository.BackupRepository, schemaVersion int64, logger *zap.Logger, opts ...BackupOption) *Backups {
+	b := &Backups{repo: repo, schemaVersion: schemaVersion, logger: logger, clock: clock.Real()}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Write streams the backup to w a row at a time, from one snapshot, so the
+// dataset is never held whole.
+func (b *Backups) Write(ctx context.Context, w io.Writer) error {
+	return b.repo.Snapshot(ctx, func(snapshot repository.BackupSnapshot) error {
+		header, err := json.Marshal(struct {
+			SchemaVersion int64     `json:"schema_version"`
+			ExportedAt    time.Time `json:"exported_at"`
+		}{b.schemaVersion, b.clock.Now().UTC()})
+		if err != nil {
+			return err
+		}
+		// The header without its closing brace, for the tables to follow.
+		if _, err := w.Write(header[:len(header)-1]); err != nil {
+			return err
+		}
+		if err := writeRows(w, "users", func(emit func(any) error) error {
+			return snapshot.EachUser(ctx, func(u *models.BackupUser) error { return emit(u) })
+		}); err != nil {
+			return err
+		}
+		if err := writeRows(w, "birthday_notifications", func(emit func(any) error) error {
+			return snapshot.EachNotification(ctx, func(n *models.BackupNotification) error { return emit(n) })
+		}); err != nil {
+			return err
+		}
+		if err := writeRows(w, "share_tokens", func(emit func(any) error) error {
+			return snapshot.EachShare(ctx, func(s *models.BackupShare) error { return emit(s) })
+		}); err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, "}\n")
+		return err
+	})
+}
+
+// writeRows writes the array name holds, one row for each emit of walk.
+func writeRows(w io.Writer, name string, walk func(emit func(any) error) error) error {
+	if _, err := fmt.Fprintf(w, ",%q:[", name); err != nil {
+		return err
+	}
+	first := true
+	if err := walk(func(row any) error {
+		raw, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+		if !first {
+			raw = append([]byte{','}, raw...)
+		}
+		first = false
+		_, err = w.Write(raw)
+		return err
+	}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "]")
+	return err
+}
+
+// Restore loads the backup r holds, in one transaction: into a database with
+// no users, or with truncate in place of the users it has.
+func (b *Backups) Restore(ctx context.Context, r io.Reader, truncate bool) (*models.RestoreReport, error) {
+	var backup models.Backup
+	if err := json.NewDecoder(r).Decode(&backup); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	if backup.SchemaVersion > b.schemaVersion {
+		return nil, fmt.Errorf("%w: backup is at version %d, this server at %d", ErrBackupTooNew, backup.SchemaVersion, b.schemaVersion)
+	}
+	if err := checkBackup(&backup); err != nil {
+		return nil, err
+	}
+	if err := b.repo.Restore(ctx, &backup, truncate); err != nil {
+		return nil, err
+	}
+	for _, fn := range b.onRestore {
+		if err := fn(ctx); err != nil {
+			b.logger.Error("Failed to drop cached rows after a restore", zap.Error(err))
+		}
+	}
+	return &models.RestoreReport{
+		SchemaVersion:         backup.SchemaVersion,
+		Users:                 len(backup.Users),
+		BirthdayNotifications: len(backup.BirthdayNotifications),
+		ShareTokens:           len(backup.ShareTokens),
+	}, nil
+}
+
+// checkBackup catches what the database would only reject part way
+// through, with less to say about which row.
+func checkBackup(backup *models.Backup) error {
+	invalid := func(field, rule, message string) error {
+		return &ValidationError{Details: []models.FieldError{{Field: field, Rule: rule, Message: message}}}
+	}
+	if backup.SchemaVersion < 1 {
+		return invalid("schema_version", "required", "schema_version is required")
+	}
+	users := make(map[int32]bool, len(backup.Users))
+	for i, u := range backup.Users {
+		row := fmt.Sprintf("users[%d]", i)
+		if u.ID < 1 || users[u.ID] {
+			return invalid(row+".id", "unique", fmt.Sprintf("%s.id must be a positive id no other user has", row))
+		}
+		users[u.ID] = true
+		if u.TenantID == "" || u.Name == "" {
+			return invalid(row, "required", row+" needs a tenant_id and a name")
+		}
+		if _, err := time.Parse(dateLayout, u.DOB); err != nil {
+			return invalid(row+".dob", "datetime", row+".dob must be YYYY-MM-DD")
+		}
+	}
+	for i, n := range backup.BirthdayNotifications {
+		row := fmt.Sprintf("birthday_notifications[%d]", i)
+		if !users[n.UserID] {
+			return invalid(row+".user_id", "exists", fmt.Sprintf("%s.user_id names no user of the backup", row))
+		}
+		if _, err := time.Parse(dateLayout, n.NotifiedOn); err != nil {
+			return invalid(row+".notified_on", "datetime", row+".notified_on must be YYYY-MM-DD")
+		}
+	}
+	shares := make(map[int64]bool, len(backup.ShareTokens))
+	for i, sh := range backup.ShareTokens {
+		row := fmt.Sprintf("share_tokens[%d]", i)
+		if sh.ID < 1 || shares[sh.ID] {
+			return invalid(row+".id", "unique", fmt.Sprintf("%s.id must be a positive id no other share has", row))
+		}
+		shares[sh.ID] = true
+		if !users[sh.UserID] {
+			return invalid(row+".user_id", "exists", fmt.Sprintf("%s.user_id names no user of the backup", row))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"go.uber.org/zap"
+)
+
+func sampleBackup() *models.Backup {
+	anonymized := pinnedNow.Add(-time.Hour)
+	return &models.Backup{
+		SchemaVersion: 16,
+		ExportedAt:    pinnedNow,
+		Users: []models.BackupUser{
+			{ID: 1, TenantID: "acme", Name: "Alice", DOB: "1990-05-10", Timezone: "Europe/Paris", Locale: "fr", CreatedAt: pinnedNow.Add(-48 * time.Hour), UpdatedAt: pinnedNow.Add(-24 * time.Hour)},
+			{ID: 7, TenantID: "default", Name: "Anonymized user 7", DOB: "2000-01-01", CreatedAt: pinnedNow.Add(-48 * time.Hour), UpdatedAt: anonymized, AnonymizedAt: &anonymized},
+		},
+		BirthdayNotifications: []models.BackupNotification{{UserID: 1, Year: 2025, NotifiedOn: "2025-05-10"}},
+		ShareTokens: []models.BackupShare{
+			{ID: 3, TenantID: "acme", UserID: 1, TokenHash: []byte{0xde, 0xad}, CreatedAt: pinnedNow.Add(-time.Hour), ExpiresAt: pinnedNow.Add(time.Hour)},
+		},
+	}
+}
+
+func TestBackupRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	source := NewBackups(repository.NewMemoryBackupRepository(sampleBackup()), 16, zap.NewNop(), WithBackupClock(&manualClock{now: pinnedNow}))
+	var written bytes.Buffer
+	if err := source.Write(ctx, &written); err != nil {
+		t.Fatal(err)
+	}
+
+	target := NewBackups(repository.NewMemoryBackupRepository(nil), 16, zap.NewNop(), WithBackupClock(&manualClock{now: pinnedNow}))
+	report, err := target.Restore(ctx, bytes.NewReader(written.Bytes()), false)
+	if err != nil {
+		t.Fatalf("Restore = %v", err)
+	}
+	if want := (models.RestoreReport{SchemaVersion: 16, Users: 2, BirthdayNotifications: 1, ShareTokens: 1}); *report != want {
+		t.Errorf("report = %+v, want %+v", *report, want)
+	}
+
+	var again bytes.Buffer
+	if err := target.Write(ctx, &again); err != nil {
+		t.Fatal(err)
+	}
+	var got models.Backup
+	if err := json.Unmarshal(again.Bytes(), &got); err != nil {
+		t.Fatalf("backup of the restore is not JSON: %v\n%s", err, again.String())
+	}
+	if want := sampleBackup(); !reflect.DeepEqual(&got, want) {
+		t.Errorf("backup of the restore = %+v, want %+v", got, *want)
+	}
+}
+
+func TestBackupEmptyTables(t *testing.T) {
+	backups := NewBackups(repository.NewMemoryBackupRepository(nil), 16, zap.NewNop(), WithBackupClock(&manualClock{now: pinnedNow}))
+	var written bytes.Buffer
+	if err := backups.Write(context.Background(), &written); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"schema_version":16,"exported_at":"2025-06-15T12:00:00Z","users":[],"birthday_notifications":[],"share_tokens":[]}` + "\n"
+	if written.String() != want {
+		t.Errorf("backup = %s, want %s", written.String(), want)
+	}
+}
+
+func TestRestoreRefusals(t *testing.T) {
+	ctx := context.Background()
+	encode := func(b *models.Backup) string {
+		raw, err := json.Marshal(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(raw)
+	}
+	newer := sampleBackup()
+	newer.SchemaVersion = 17
+	badDOB := sampleBackup()
+	badDOB.Users[1].DOB = "01/01/2000"
+	orphan := sampleBackup()
+	orphan.ShareTokens[0].UserID = 2
+
+	tests := []struct {
+		name  string
+		body  string
+		want  error
+		field string
+	}{
+		{name: "not json", body: "PK\x03\x04", want: ErrInvalidBackup},
+		{name: "newer schema", body: encode(newer), want: ErrBackupTooNew},
+		{name: "no schema version", body: `{"users":[]}`, field: "schema_version"},
+		{name: "bad dob", body: encode(badDOB), field: "users[1].dob"},
+		{name: "share of no user", body: encode(orphan), field: "share_tokens[0].user_id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backups := NewBackups(repository.NewMemoryBackupRepository(nil), 16, zap.NewNop())
+			_, err := backups.Restore(ctx, strings.NewReader(tt.body), false)
+			if tt.want != nil {
+				if !errors.Is(err, tt.want) {
+					t.Errorf("Restore: err = %v, want %v", err, tt.want)
+				}
+				return
+			}
+			var invalid *ValidationError
+			if !errors.As(err, &invalid) || invalid.Details[0].Field != tt.field {
+				t.Errorf("Restore: err = %v, want a validation error on %s", err, tt.field)
+			}
+		})
+	}
+}
+
+func TestRestoreIntoExistingUsers(t *testing.T) {
+	ctx := context.Background()
+	existing := sampleBackup()
+	existing.Users = existing.Users[1:]
+	existing.BirthdayNotifications, existing.ShareTokens = nil, nil
+	repo := repository.NewMemoryBackupRepository(existing)
+	backups := NewBackups(repo, 16, zap.NewNop())
+	body, err := json.Marshal(sampleBackup())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := backups.Restore(ctx, bytes.NewReader(body), false); !errors.Is(err, repository.ErrNotEmpty) {
+		t.Fatalf("Restore without truncate: err = %v, want ErrNotEmpty", err)
+	}
+	report, err := backups.Restore(ctx, bytes.NewReader(body), true)
+	if err != nil || report.Users != 2 {
+		t.Fatalf("Restore with truncate = %+v, %v", report, err)
+	}
+}
+
+func TestRestoreHooksRunAfterCommit(t *testing.T) {
+	ctx := context.Background()
+	existing := sampleBackup()
+	existing.Users = existing.Users[1:]
+	existing.BirthdayNotifications, existing.ShareTokens = nil, nil
+	var purged []string
+	backups := NewBackups(repository.NewMemoryBackupRepository(existing), 16, zap.NewNop(),
+		WithRestoreHook(func(context.Context) error {
+			purged = append(purged, "users")
+			return errors.New("redis: connection refused")
+		}),
+		WithRestoreHook(func(context.Context) error {
+			purged = append(purged, "aggregates")
+			return nil
+		}),
+	)
+	body, err := json.Marshal(sampleBackup())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := backups.Restore(ctx, bytes.NewReader(body), false); err == nil || len(purged) != 0 {
+		t.Fatalf("refused restore: err = %v, hooks run %v; want none run", err, purged)
+	}
+	if _, err := backups.Restore(ctx, bytes.NewReader(body), true); err != nil {
+		t.Fatalf("Restore = %v; a failing hook must not fail it", err)
+	}
+	if want := []string{"users", "aggregates"}; !slices.Equal(purged, want) {
+		t.Errorf("hooks run = %v, want %v", purged, want)
+	}
+}
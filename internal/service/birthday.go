@@ -0,0 +1,125 @@
+package service
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+)
+
+// locations caches time.LoadLocation, which reads the zone database on every
+// call.
+var locations sync.Map
+
+// UserLocation is the user's stored timezone, or fallback when they have none
+// or it is not one this binary knows.
+func UserLocation(user *models.User, fallback *time.Location) *time.Location {
+	if user.Timezone == "" {
+		return fallback
+	}
+	if loc, ok := locations.Load(user.Timezone); ok {
+		return loc.(*time.Location)
+	}
+	loc, err := time.LoadLocation(user.Timezone)
+	if err != nil {
+		return fallback
+	}
+	locations.Store(user.Timezone, loc)
+	return loc
+}
+
+// IsBirthdayToday reports whether the instant now falls on the user's
+// birthday in their own timezone, with fallback standing in for users who
+// have none. It agrees with the age: a Feb 29 birthday falls on Mar 1 in
+// common years, the day CalculateAge ticks over.
+func IsBirthdayToday(user *models.User, now time.Time, fallback *time.Location) bool {
+	return isBirthday(user.DOB, now.In(UserLocation(user, fallback)))
+}
+
+// isBirthday takes now already in the user's timezone. The day of birth
+// itself is not a birthday.
+func isBirthday(dob, now time.Time) bool {
+	today := toDate(now)
+	birthday := time.Date(today.Year(), dob.Month(), dob.Day(), 0, 0, 0, 0, time.UTC)
+	return toDate(dob).Before(today) && birthday.Equal(today)
+}
+
+// NextBirthdayOf is NextBirthday on the user's own calendar, worked out from
+// resp's age: the day it next ticks over, which is today on their birthday.
+// It reports false when resp has no DOB or no age.
+func NextBirthdayOf(resp *models.UserResponse) (time.Time, bool) {
+	dob, err := time.Parse(dateLayout, resp.DOB)
+	if err != nil || resp.Age == nil {
+		return time.Time{}, false
+	}
+	years := *resp.Age + 1
+	if resp.IsBirthday {
+		years--
+	}
+	return monthAnniversary(dob, years*12), true
+}
+
+// BirthdayYearProgress is the share, to 4 decimals, of the year from the
+// user's last birthday to their next that has gone by on now's date: 0 on a
+// birthday and on the day of birth, and under 1 the day before the next.
+// The year is 365 or 366 days long, with a Feb 29 birthday on Mar 1 in
+// common years as everywhere else. A DOB after now yields 0.
+func BirthdayYearProgress(dob, now time.Time) float64 {
+	if IsFutureDOB(dob, now) {
+		return 0
+	}
+	return math.Round(birthdayYearFraction(dob, now)*1e4) / 1e4
+}
+
+// birthdayYearFraction is BirthdayYearProgress unrounded, for a dob not
+// after now.
+func birthdayYearFraction(dob, now time.Time) float64 {
+	dob, now = toDate(dob), toDate(now)
+	years := CalculateAge(dob, now)
+	last := monthAnniversary(dob, years*12)
+	next := monthAnniversary(dob, (years+1)*12)
+	return float64(now.Sub(last)) / float64(next.Sub(last))
+}
+
+// birthdayTwinDays are the days of birth whose birthday in year falls on the
+// same date as that of dob. In common years Feb 29 birthdays fall on Mar 1,
+// so leaplings and those born on Mar 1 share theirs.
+func birthdayTwinDays(dob time.Time, year int) []repository.MonthDay {
+	day := repository.MonthDay{Month: dob.Month(), Day: dob.Day()}
+	leapDay := repository.MonthDay{Month: time.February, Day: 29}
+	march1 := repository.MonthDay{Month: time.March, Day: 1}
+	if isLeapYear(year) || (day != leapDay && day != march1) {
+		return []repository.MonthDay{day}
+	}
+	return []repository.MonthDay{leapDay, march1}
+}
+
+func isLeapYear(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
+
+// LeapBirthdays counts the Feb 29s after dob up to and including today.
+func LeapBirthdays(dob, today time.Time) int {
+	count := 0
+	for year := dob.Year() + 1; year <= today.Year(); year++ {
+		if isLeapYear(year) && !time.Date(year, time.February, 29, 0, 0, 0, 0, time.UTC).After(today) {
+			count++
+		}
+	}
+	return count
+}
+
+// NextLeapDay is the first Feb 29 on or after today and after dob.
+func NextLeapDay(dob, today time.Time) time.Time {
+	for year := today.Year(); ; year++ {
+		if !isLeapYear(year) {
+			continue
+		}
+		leapDay := time.Date(year, time.February, 29, 0, 0, 0, 0, time.UTC)
+		if !leapDay.Before(today) && leapDay.After(toDate(dob)) {
+			return leapDay
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+	"go.uber.org/zap"
+)
+
+// BirthdayCalendar writes an iCalendar file to w with a yearly event for each
+// user matching params' filters, in id order, leaving out anonymized users. Each event starts on the user's
+// next birthday in their timezone and is titled with the age they turn then.
+// Like ExportUsers it reads a page at a time, so w receives the calendar as it
+// is generated; a failure part way leaves it without END:VCALENDAR.
+func (s *userService) BirthdayCalendar(ctx context.Context, params *models.UserListQuery, w io.Writer) error {
+	filter, err := s.listFilter(params)
+	if err != nil {
+		return err
+	}
+
+	now := s.clock.Now()
+	cal := newLineWriter(w)
+	cal.line("BEGIN", "VCALENDAR")
+	cal.line("VERSION", "2.0")
+	cal.line("PRODID", "-//age_calculator//Birthdays//EN")
+	cal.line("CALSCALE", "GREGORIAN")
+	cal.line("X-WR-CALNAME", "Birthdays")
+
+	rows := 0
+	query := repository.ListQuery{Filter: filter, Limit: exportPageSize}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		users, err := s.repo.List(ctx, query)
+		if err != nil {
+			return err
+		}
+		for i := range users {
+			// An anonymized user's DOB is no longer their birthday.
+			if users[i].AnonymizedAt == nil {
+				writeBirthday(ctx, cal, &users[i], now, s.location)
+			}
+		}
+		if err := cal.flush(); err != nil {
+			return err
+		}
+		rows += len(users)
+
+		if len(users) < exportPageSize {
+			break
+		}
+		last := users[len(users)-1]
+		query.After = &repository.Keyset{ID: last.ID, CreatedAt: last.CreatedAt}
+	}
+
+	cal.line("END", "VCALENDAR")
+	if err := cal.flush(); err != nil {
+		return err
+	}
+	s.logger.Info("Birthday calendar exported", zap.Int("rows", rows))
+	return nil
+}
+
+// NextBirthday is the first birthday on or after today, a date in the user's
+// timezone, and after the day of birth itself.
+func NextBirthday(dob, today time.Time) time.Time {
+	born := toDate(dob)
+	year := today.Year()
+	for {
+		// time.Date moves Feb 29 to Mar 1 in common years, as CalculateAge does.
+		birthday := time.Date(year, dob.Month(), dob.Day(), 0, 0, 0, 0, time.UTC)
+		if !birthday.Before(today) && birthday.After(born) {
+			return birthday
+		}
+		year++
+	}
+}
+
+// writeBirthday writes the yearly event of user's birthday.
+func writeBirthday(ctx context.Context, c *lineWriter, user *models.User, now time.Time, fallback *time.Location) {
+	first := NextBirthday(user.DOB, toDate(now.In(UserLocation(user, fallback))))
+
+	c.line("BEGIN", "VEVENT")
+	c.line("UID", fmt.Sprintf("birthday-%s-%d@age_calculator", tenant.FromContext(ctx), user.ID))
+	c.line("DTSTAMP", now.UTC().Format("20060102T150405Z"))
+	c.line("DTSTART;VALUE=DATE", first.Format("20060102"))
+	if user.DOB.Month() == time.February && user.DOB.Day() == 29 {
+		// Day 60 is Feb 29 in leap years and Mar 1 otherwise, the day the age
+		// ticks over.
+		c.line("RRULE", "FREQ=YEARLY;BYYEARDAY=60")
+	} else {
+		c.line("RRULE", "FREQ=YEARLY")
+	}
+	c.line("SUMMARY", textValue(fmt.Sprintf("%s's birthday (turns %d)", user.Name, CalculateAge(user.DOB, first))))
+	c.line("TRANSP", "TRANSPARENT")
+	c.line("END", "VEVENT")
+}
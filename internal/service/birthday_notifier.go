@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/events"
+	"github.com/srinivasarynh/age_calculator/internal/metrics"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+	"go.uber.org/zap"
+)
+
+// BirthdayNotifier publishes a user.birthday event for each user whose
+// birthday has begun in their timezone, once per user and year. Every
+// instance may run one: the marker each run claims before publishing keeps
+// the others from notifying the same birthday, and with a Locker only one
+// instance runs at a time.
+type BirthdayNotifier struct {
+	users     repository.UserRepository
+	markers   repository.NotificationRepository
+	publisher events.Publisher
+	logger    *zap.Logger
+	clock     clock.Clock
+	location  *time.Location
+	interval  time.Duration
+	tenants   func() []string
+	locker    Locker
+	metrics   *metrics.Webhooks
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   sync.WaitGroup
+}
+
+type NotifierOption func(*BirthdayNotifier)
+
+func WithNotifierClock(c clock.Clock) NotifierOption {
+	return func(n *BirthdayNotifier) {
+		n.clock = c
+	}
+}
+
+// WithNotifierInterval sets how often Start looks for birthdays. A birthday
+// is notified at most this long after it begins.
+func WithNotifierInterval(d time.Duration) NotifierOption {
+	return func(n *BirthdayNotifier) {
+		n.interval = d
+	}
+}
+
+// WithNotifierLocation sets the timezone of users who have none, as
+// WithDefaultLocation does for the user service.
+func WithNotifierLocation(loc *time.Location) NotifierOption {
+	return func(n *BirthdayNotifier) {
+		n.location = loc
+	}
+}
+
+// WithNotifierTenants sets the tenants each run covers; tenants is called on
+// every run so that reloaded TENANTS take effect. The default covers only
+// the default tenant.
+func WithNotifierTenants(tenants func() []string) NotifierOption {
+	return func(n *BirthdayNotifier) {
+		n.tenants = tenants
+	}
+}
+
+// WithNotifierLocker makes Start skip a run while another instance is in
+// one. RunOnce does not lock.
+func WithNotifierLocker(l Locker) NotifierOption {
+	return func(n *BirthdayNotifier) {
+		n.locker = l
+	}
+}
+
+// WithNotifierMetrics counts every publish attempt in m.
+func WithNotifierMetrics(m *metrics.Webhooks) NotifierOption {
+	return func(n *BirthdayNotifier) {
+		n.metrics = m
+	}
+}
+
+func NewBirthdayNotifier(users repository.UserRepository, markers repository.NotificationRepository, publisher events.Publisher, logger *zap.Logger, opts ...NotifierOption) *BirthdayNotifier {
+	n := &BirthdayNotifier{
+		users:     users,
+		markers:   markers,
+		publisher: publisher,
+		logger:    logger,
+		clock:     clock.Real(),
+		location:  time.UTC,
+		interval:  time.Hour,
+		tenants:   func() []string { return []string{tenant.Default} },
+		metrics:   metrics.NewWebhooks(prometheus.NewRegistry()),
+	}
+	for _, opt := range opts {
+		opt(n)
+	}
+	return n
+}
+
+// RunOnce notifies the birthdays not yet notified this year and returns how
+// many it published. A failed publish gives the marker back, so the next run
+// tries that user again; the other users are still notified.
+func (n *BirthdayNotifier) RunOnce(ctx context.Context) (int, error) {
+	now := n.clock.Now()
+	published := 0
+	var errs []error
+	for _, id := range n.tenants() {
+		count, err := n.notifyTenant(tenant.WithID(ctx, id), now)
+		published += count
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return published, errors.Join(errs...)
+}
+
+func (n *BirthdayNotifier) notifyTenant(ctx context.Context, now time.Time) (int, error) {
+	users, err := n.users.ListBirthdays(ctx, now, n.location.String())
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	var errs []error
+	for i := range users {
+		user := &users[i]
+		if !IsBirthdayToday(user, now, n.location) {
+			continue
+		}
+		loc := UserLocation(user, n.location)
+		local := now.In(loc)
+		marked, err := n.markers.MarkNotified(ctx, user.ID, local.Year(), toDate(local))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !marked {
+			continue
+		}
+
+		event := events.Event{
+			Type:     models.EventUserBirthday,
+			TenantID: tenant.FromContext(ctx),
+			At:       now.UTC(),
+			Data: models.BirthdayEvent{
+				UserID:   user.ID,
+				Name:     user.Name,
+				DOB:      user.DOB.Format(dateLayout),
+				Age:      CalculateAge(user.DOB, local),
+				Timezone: loc.String(),
+				Date:     local.Format(dateLayout),
+			},
+		}
+		err = n.publisher.Publish(ctx, event)
+		n.metrics.Delivered(err)
+		if err != nil {
+			n.logger.Warn("Failed to publish birthday, retrying next run", zap.Int32("id", user.ID), zap.Error(err))
+			if err := n.markers.Unmark(ctx, user.ID, local.Year()); err != nil {
+				errs = append(errs, err)
+			}
+			errs = append(errs, err)
+			continue
+		}
+		published++
+	}
+	return published, errors.Join(errs...)
+}
+
+// Start runs the notifier at once and then every interval until Stop.
+func (n *BirthdayNotifier) Start(ctx context.Context) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.cancel != nil {
+		return errors.New("birthday notifier already started")
+	}
+	ctx, n.cancel = context.WithCancel(ctx)
+
+	n.done.Add(1)
+	go func() {
+		defer n.done.Done()
+		n.loop(ctx)
+	}()
+	return nil
+}
+
+// Stop ends the loop and waits for a run in progress, or for ctx.
+func (n *BirthdayNotifier) Stop(ctx context.Context) error {
+	n.mu.Lock()
+	cancel := n.cancel
+	n.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	stopped := make(chan struct{})
+	go func() {
+		n.done.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (n *BirthdayNotifier) loop(ctx context.Context) {
+	ticker := time.NewTicker(n.interval)
+	defer ticker.Stop()
+	for {
+		published, err := n.run(ctx)
+		if err != nil && ctx.Err() == nil {
+			n.logger.Error("Birthday notification run failed", zap.Int("published", published), zap.Error(err))
+		} else if published > 0 {
+			n.logger.Info("Birthdays notified", zap.Int("published", published))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (n *BirthdayNotifier) run(ctx context.Context) (int, error) {
+	if n.locker == nil {
+		return n.RunOnce(ctx)
+	}
+	published := 0
+	ran, err := n.locker.WithLock(ctx, notifierLockKey, func(ctx context.Context) error {
+		var err error
+		published, err = n.RunOnce(ctx)
+		return err
+	})
+	if !ran && err == nil {
+		n.logger.Debug("Birthday notification run skipped, another instance is running one")
+	}
+	return published, err
+}
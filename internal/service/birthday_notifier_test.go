@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/srinivasarynh/age_calculator/internal/events"
+	"github.com/srinivasarynh/age_calculator/internal/metrics"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+	"go.uber.org/zap"
+)
+
+// drain returns the names in the events waiting on ch.
+func drain(t *testing.T, ch <-chan events.Event) []string {
+	t.Helper()
+	var names []string
+	for {
+		select {
+		case event := <-ch:
+			if event.Type != models.EventUserBirthday {
+				t.Errorf("event type = %q", event.Type)
+			}
+			names = append(names, event.Data.(models.BirthdayEvent).Name)
+		default:
+			return names
+		}
+	}
+}
+
+func TestBirthdayNotifierFollowsEachUsersTimezone(t *testing.T) {
+	c := &manualClock{now: time.Date(2025, 6, 14, 23, 30, 0, 0, time.UTC)}
+	users := repository.NewMemoryUserRepository(c)
+	ctx := context.Background()
+	for _, u := range []struct{ name, timezone string }{
+		{"West", "Etc/GMT+8"},
+		{"East", "Pacific/Tongatapu"},
+		{"Default", ""},
+	} {
+		created, _ := users.Create(ctx, u.name, time.Date(1990, 6, 15, 0, 0, 0, 0, time.UTC))
+		users.SetTimezone(ctx, created.ID, u.timezone)
+	}
+	hub := events.NewHub()
+	ch, unsubscribe := hub.Subscribe(10)
+	defer unsubscribe()
+	notifier := NewBirthdayNotifier(users, repository.NewMemoryNotificationRepository(), hub, zap.NewNop(), WithNotifierClock(c))
+
+	steps := []struct {
+		advance time.Duration
+		want    []string
+	}{
+		// 12:30 on Jun 15 in Tonga, still Jun 14 elsewhere.
+		{want: []string{"East"}},
+		{advance: 10 * time.Minute},
+		// Jun 15 has begun in UTC.
+		{advance: 50 * time.Minute, want: []string{"Default"}},
+		// 00:30 on Jun 15 at UTC-8.
+		{advance: 8 * time.Hour, want: []string{"West"}},
+		{advance: 12 * time.Hour},
+		// 09:30 UTC on Jun 15 a year on, when it is Jun 15 in all three zones.
+		{advance: 365*24*time.Hour - 11*time.Hour, want: []string{"West", "East", "Default"}},
+	}
+	for _, step := range steps {
+		c.Advance(step.advance)
+		published, err := notifier.RunOnce(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := drain(t, ch); published != len(step.want) || !slices.Equal(got, step.want) {
+			t.Errorf("at %s: published %d, %v; want %v", c.Now(), published, got, step.want)
+		}
+	}
+}
+
+func TestBirthdayNotifierEvent(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	users := repository.NewMemoryUserRepository(&manualClock{now: now})
+	acme := tenant.WithID(context.Background(), "acme")
+	users.Create(acme, "Leapling", time.Date(2000, 2, 29, 0, 0, 0, 0, time.UTC))
+	hub := events.NewHub()
+	ch, unsubscribe := hub.Subscribe(1)
+	defer unsubscribe()
+
+	notifier := NewBirthdayNotifier(users, repository.NewMemoryNotificationRepository(), hub, zap.NewNop(),
+		WithNotifierClock(&manualClock{now: now}),
+		WithNotifierTenants(func() []string { return []string{tenant.Default, "acme"} }))
+	if _, err := notifier.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	event := <-ch
+	want := models.BirthdayEvent{UserID: 1, Name: "Leapling", DOB: "2000-02-29", Age: 25, Timezone: "UTC", Date: "2025-03-01"}
+	if event.TenantID != "acme" || !event.At.Equal(now) || event.Data != want {
+		t.Errorf("event = %+v, want %+v in acme", event, want)
+	}
+}
+
+func TestBirthdayNotifierNotifiesOnceAcrossInstances(t *testing.T) {
+	c := &manualClock{now: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
+	users := repository.NewMemoryUserRepository(c)
+	for range 20 {
+		users.Create(context.Background(), "Twin", time.Date(1990, 6, 15, 0, 0, 0, 0, time.UTC))
+	}
+	markers := repository.NewMemoryNotificationRepository()
+	hub := events.NewHub()
+	ch, unsubscribe := hub.Subscribe(100)
+	defer unsubscribe()
+
+	results := make(chan int)
+	for range 3 {
+		go func() {
+			published, _ := NewBirthdayNotifier(users, markers, hub, zap.NewNop(), WithNotifierClock(c)).RunOnce(context.Background())
+			results <- published
+		}()
+	}
+	total := <-results + <-results + <-results
+	if got := len(drain(t, ch)); total != 20 || got != 20 {
+		t.Errorf("three instances published %d events (%d received), want 20", total, got)
+	}
+}
+
+type failingPublisher struct {
+	events.Publisher
+	failures int
+}
+
+func (p *failingPublisher) Publish(ctx context.Context, event events.Event) error {
+	if p.failures > 0 {
+		p.failures--
+		return errors.New("connection refused")
+	}
+	return p.Publisher.Publish(ctx, event)
+}
+
+func TestBirthdayNotifierRetriesFailedPublish(t *testing.T) {
+	c := &manualClock{now: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
+	users := repository.NewMemoryUserRepository(c)
+	users.Create(context.Background(), "Alice", time.Date(1990, 6, 15, 0, 0, 0, 0, time.UTC))
+	users.Create(context.Background(), "Bob", time.Date(1991, 6, 15, 0, 0, 0, 0, time.UTC))
+	hub := events.NewHub()
+	ch, unsubscribe := hub.Subscribe(10)
+	defer unsubscribe()
+	m := metrics.NewWebhooks(prometheus.NewRegistry())
+	notifier := NewBirthdayNotifier(users, repository.NewMemoryNotificationRepository(), &failingPublisher{Publisher: hub, failures: 1}, zap.NewNop(), WithNotifierClock(c), WithNotifierMetrics(m))
+
+	if published, err := notifier.RunOnce(context.Background()); err == nil || published != 1 {
+		t.Errorf("run with a failing publish = %d, %v; want 1 and an error", published, err)
+	}
+	if got := drain(t, ch); !slices.Equal(got, []string{"Bob"}) {
+		t.Errorf("first run delivered %v, want Bob", got)
+	}
+	c.Advance(time.Hour)
+	if published, err := notifier.RunOnce(context.Background()); err != nil || published != 1 {
+		t.Errorf("retry = %d, %v; want 1", published, err)
+	}
+	if got := drain(t, ch); !slices.Equal(got, []string{"Alice"}) {
+		t.Errorf("retry delivered %v, want Alice", got)
+	}
+	if failed, ok := promtestutil.ToFloat64(m.Deliveries.WithLabelValues("error")), promtestutil.ToFloat64(m.Deliveries.WithLabelValues("ok")); failed != 1 || ok != 2 {
+		t.Errorf("deliveries counted = %v failed, %v ok; want 1 and 2", failed, ok)
+	}
+}
+
+func TestBirthdayNotifierStartStop(t *testing.T) {
+	c := &manualClock{now: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
+	users := repository.NewMemoryUserRepository(c)
+	users.Create(context.Background(), "Alice", time.Date(1990, 6, 15, 0, 0, 0, 0, time.UTC))
+	hub := events.NewHub()
+	ch, unsubscribe := hub.Subscribe(1)
+	defer unsubscribe()
+	notifier := NewBirthdayNotifier(users, repository.NewMemoryNotificationRepository(), hub, zap.NewNop(), WithNotifierClock(c), WithNotifierInterval(time.Hour))
+
+	if err := notifier.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := notifier.Start(context.Background()); err == nil {
+		t.Error("second Start succeeded")
+	}
+	select {
+	case event := <-ch:
+		if name := event.Data.(models.BirthdayEvent).Name; name != "Alice" {
+			t.Errorf("first run notified %s", name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start did not run the notifier")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := notifier.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// heldLocker stands in for another instance holding every lock.
+type heldLocker struct {
+	keys chan string
+}
+
+func (l heldLocker) WithLock(ctx context.Context, key string, fn func(ctx context.Context) error) (bool, error) {
+	l.keys <- key
+	return false, nil
+}
+
+func TestBirthdayNotifierSkipsRunWhileLocked(t *testing.T) {
+	c := &manualClock{now: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
+	users := repository.NewMemoryUserRepository(c)
+	users.Create(context.Background(), "Alice", time.Date(1990, 6, 15, 0, 0, 0, 0, time.UTC))
+	hub := events.NewHub()
+	ch, unsubscribe := hub.Subscribe(1)
+	defer unsubscribe()
+	locker := heldLocker{keys: make(chan string, 1)}
+	notifier := NewBirthdayNotifier(users, repository.NewMemoryNotificationRepository(), hub, zap.NewNop(), WithNotifierClock(c), WithNotifierInterval(time.Hour), WithNotifierLocker(locker))
+
+	if err := notifier.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case key := <-locker.keys:
+		if key != notifierLockKey {
+			t.Errorf("locked %q, want %q", key, notifierLockKey)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start did not try the lock")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := notifier.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if names := drain(t, ch); len(names) != 0 {
+		t.Errorf("notified %v while another instance held the lock", names)
+	}
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"go.uber.org/zap"
+)
+
+func TestIsBirthdayToday(t *testing.T) {
+	tests := []struct {
+		name string
+		dob  string
+		now  time.Time
+		want bool
+	}{
+		{name: "birthday", dob: "1990-06-15", now: time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC), want: true},
+		{name: "day before", dob: "1990-06-15", now: time.Date(2025, 6, 14, 23, 59, 59, 0, time.UTC)},
+		{name: "day of birth", dob: "2025-06-15", now: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)},
+		{name: "leapling in a common year", dob: "2000-02-29", now: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC), want: true},
+		{name: "leapling on Feb 28", dob: "2000-02-29", now: time.Date(2025, 2, 28, 12, 0, 0, 0, time.UTC)},
+		{name: "leapling in a leap year", dob: "2000-02-29", now: time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC), want: true},
+		{name: "Mar 1 in a leap year", dob: "2000-02-29", now: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dob, _ := ParseDOB(tt.dob)
+			user := &models.User{DOB: dob}
+			if got := IsBirthdayToday(user, tt.now, time.UTC); got != tt.want {
+				t.Errorf("IsBirthdayToday(%s at %s) = %v, want %v", tt.dob, tt.now, got, tt.want)
+			}
+			// The age ticks over on exactly the days that are birthdays.
+			if ticked := CalculateAge(dob, tt.now) > CalculateAge(dob, tt.now.AddDate(0, 0, -1)); ticked != tt.want {
+				t.Errorf("age ticked over = %v, birthday = %v", ticked, tt.want)
+			}
+		})
+	}
+}
+
+// At 23:30 UTC on Jun 14 it is 15:30 on Jun 14 at UTC-8 and already 12:30 on
+// Jun 15 at UTC+13.
+func TestBirthdaysFollowEachUsersTimezone(t *testing.T) {
+	now := time.Date(2025, 6, 14, 23, 30, 0, 0, time.UTC)
+	repo := repository.NewMemoryUserRepository(clock.Fixed(now))
+	ctx := context.Background()
+	users := []struct {
+		name, dob, timezone string
+	}{
+		{"West Jun 14", "1990-06-14", "Etc/GMT+8"},
+		{"West Jun 15", "1990-06-15", "Etc/GMT+8"},
+		{"East Jun 14", "1990-06-14", "Pacific/Tongatapu"},
+		{"East Jun 15", "1990-06-15", "Pacific/Tongatapu"},
+		{"Default Jun 14", "1990-06-14", ""},
+		{"Default Jun 15", "1990-06-15", ""},
+	}
+	svc := NewUserService(repo, zap.NewNop(), WithClock(clock.Fixed(now)))
+	for _, u := range users {
+		if _, err := svc.CreateUser(ctx, &models.CreateUserRequest{Name: u.name, DOB: u.dob, Timezone: u.timezone}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names := func(svc UserService) []string {
+		t.Helper()
+		list, err := svc.BirthdaysToday(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		for _, u := range list.Users {
+			out = append(out, u.Name)
+			if !u.IsBirthday || *u.Age != 35 {
+				t.Errorf("%s: is_birthday = %v, age = %d; want a 35th birthday", u.Name, u.IsBirthday, *u.Age)
+			}
+		}
+		return out
+	}
+	if got, want := names(svc), []string{"West Jun 14", "East Jun 15", "Default Jun 14"}; !slices.Equal(got, want) {
+		t.Errorf("birthdays with a UTC default = %v, want %v", got, want)
+	}
+	tonga, _ := time.LoadLocation("Pacific/Tongatapu")
+	eastern := NewUserService(repo, zap.NewNop(), WithClock(clock.Fixed(now)), WithDefaultLocation(tonga))
+	if got, want := names(eastern), []string{"West Jun 14", "East Jun 15", "Default Jun 15"}; !slices.Equal(got, want) {
+		t.Errorf("birthdays with a UTC+13 default = %v, want %v", got, want)
+	}
+
+	// GetUser agrees with the list.
+	for id, want := range map[int32]bool{1: true, 2: false, 3: false, 4: true} {
+		user, err := svc.GetUser(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.IsBirthday != want {
+			t.Errorf("%s: is_birthday = %v, want %v", user.Name, user.IsBirthday, want)
+		}
+	}
+}
+
+func TestNextBirthday(t *testing.T) {
+	tests := []struct {
+		dob, today, want string
+	}{
+		{dob: "1990-06-15", today: "2025-06-15", want: "2025-06-15"},
+		{dob: "1990-06-15", today: "2025-06-16", want: "2026-06-15"},
+		{dob: "2000-02-29", today: "2025-01-01", want: "2025-03-01"},
+		{dob: "2000-02-29", today: "2027-03-02", want: "2028-02-29"},
+		{dob: "2025-06-15", today: "2025-06-15", want: "2026-06-15"},
+		{dob: "2030-01-01", today: "2025-06-15", want: "2031-01-01"},
+	}
+	for _, tt := range tests {
+		dob, _ := ParseDOB(tt.dob)
+		today, _ := ParseDOB(tt.today)
+		if got := NextBirthday(dob, today).Format(dateLayout); got != tt.want {
+			t.Errorf("NextBirthday(%s, %s) = %s, want %s", tt.dob, tt.today, got, tt.want)
+		}
+		// NextBirthdayOf must agree, from the response for that day.
+		resp := newUserResponse(&models.User{DOB: dob}, responseOptions{now: today, location: time.UTC})
+		if got, ok := NextBirthdayOf(resp); !ok || got.Format(dateLayout) != tt.want {
+			t.Errorf("NextBirthdayOf(%s on %s) = %s, %v; want %s", tt.dob, tt.today, got.Format(dateLayout), ok, tt.want)
+		}
+	}
+}
+
+func TestBirthdayTwinDays(t *testing.T) {
+	leapDay := repository.MonthDay{Month: time.February, Day: 29}
+	march1 := repository.MonthDay{Month: time.March, Day: 1}
+	tests := []struct {
+		dob  string
+		year int
+		want []repository.MonthDay
+	}{
+		{"1990-05-10", 2025, []repository.MonthDay{{Month: time.May, Day: 10}}},
+		{"2000-02-29", 2024, []repository.MonthDay{leapDay}},
+		{"1990-03-01", 2024, []repository.MonthDay{march1}},
+		{"2000-02-29", 2025, []repository.MonthDay{leapDay, march1}},
+		{"1990-03-01", 2025, []repository.MonthDay{leapDay, march1}},
+		{"1990-02-28", 2025, []repository.MonthDay{{Month: time.February, Day: 28}}},
+	}
+	for _, tt := range tests {
+		dob, _ := time.Parse(dateLayout, tt.dob)
+		if got := birthdayTwinDays(dob, tt.year); !slices.Equal(got, tt.want) {
+			t.Errorf("birthdayTwinDays(%s, %d) = %v, want %v", tt.dob, tt.year, got, tt.want)
+		}
+	}
+}
+
+func TestIsLeapYear(t *testing.T) {
+	for year, want := range map[int]bool{1900: false, 1996: true, 2000: true, 2023: false, 2024: true, 2100: false, 2400: true} {
+		if got := isLeapYear(year); got != want {
+			t.Errorf("isLeapYear(%d) = %v, want %v", year, got, want)
+		}
+	}
+}
+
+func TestLeapBirthdays(t *testing.T) {
+	tests := []struct {
+		dob, today  time.Time
+		count       int
+		nextLeapDay time.Time
+	}{
+		{dob: date(2000, 2, 29), today: date(2025, 6, 15), count: 6, nextLeapDay: date(2028, 2, 29)},
+		{dob: date(2000, 2, 29), today: date(2024, 2, 28), count: 5, nextLeapDay: date(2024, 2, 29)},
+		{dob: date(2000, 2, 29), today: date(2024, 2, 29), count: 6, nextLeapDay: date(2024, 2, 29)},
+		{dob: date(2024, 2, 29), today: date(2024, 2, 29), count: 0, nextLeapDay: date(2028, 2, 29)},
+		// 1900 was not a leap year, so a leapling of 1896 waited eight years.
+		{dob: date(1896, 2, 29), today: date(1903, 6, 1), count: 0, nextLeapDay: date(1904, 2, 29)},
+		{dob: date(1896, 2, 29), today: date(1905, 6, 1), count: 1, nextLeapDay: date(1908, 2, 29)},
+		{dob: date(1996, 2, 29), today: date(2001, 1, 1), count: 1, nextLeapDay: date(2004, 2, 29)},
+	}
+	for _, tt := range tests {
+		if got := LeapBirthdays(tt.dob, tt.today); got != tt.count {
+			t.Errorf("LeapBirthdays(%s, %s) = %d, want %d", tt.dob.Format(dateLayout), tt.today.Format(dateLayout), got, tt.count)
+		}
+		if got := NextLeapDay(tt.dob, tt.today); !got.Equal(tt.nextLeapDay) {
+			t.Errorf("NextLeapDay(%s, %s) = %s, want %s", tt.dob.Format(dateLayout), tt.today.Format(dateLayout), got.Format(dateLayout), tt.nextLeapDay.Format(dateLayout))
+		}
+	}
+}
+
+func TestBirthdayYearProgress(t *testing.T) {
+	tests := []struct {
+		name     string
+		dob, now time.Time
+		want     float64
+	}{
+		{name: "birthday", dob: date(1990, 6, 15), now: date(2025, 6, 15), want: 0},
+		{name: "day before the next", dob: date(1990, 6, 15), now: date(2026, 6, 14), want: 0.9973},   // 364/365
+		{name: "half way", dob: date(1990, 1, 1), now: date(2025, 7, 2), want: 0.4986},                // 182/365
+		{name: "year with a Feb 29", dob: date(1990, 3, 1), now: date(2024, 2, 29), want: 0.9973},     // 365/366
+		{name: "leapling before Mar 1", dob: date(2000, 2, 29), now: date(2025, 2, 28), want: 0.9973}, // from 2024-02-29, 365/366
+		{name: "leapling on Mar 1", dob: date(2000, 2, 29), now: date(2025, 3, 1), want: 0},
+		{name: "day of birth", dob: date(2025, 6, 15), now: date(2025, 6, 15), want: 0},
+		{name: "future DOB", dob: date(2030, 1, 1), now: date(2025, 6, 15), want: 0},
+	}
+	for _, tt := range tests {
+		if got := BirthdayYearProgress(tt.dob, tt.now); got != tt.want {
+			t.Errorf("%s: BirthdayYearProgress(%s, %s) = %v, want %v", tt.name, tt.dob.Format(dateLayout), tt.now.Format(dateLayout), got, tt.want)
+		}
+	}
+}
+
+// At 23:30 UTC on Jun 14 a Jun 15 birthday has begun in Tonga but not in UTC.
+func TestBirthdayYearProgressFollowsTimezone(t *testing.T) {
+	now := time.Date(2025, 6, 14, 23, 30, 0, 0, time.UTC)
+	repo := repository.NewMemoryUserRepository(clock.Fixed(now))
+	ctx := context.Background()
+	svc := NewUserService(repo, zap.NewNop(), WithClock(clock.Fixed(now)))
+	for id, timezone := range map[int32]string{1: "", 2: "Pacific/Tongatapu"} {
+		if _, err := svc.CreateUser(ctx, &models.CreateUserRequest{Name: fmt.Sprintf("User %d", id), DOB: "1990-06-15", Timezone: timezone}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for id, want := range map[int32]float64{1: 0.9973, 2: 0} {
+		user, err := svc.GetUser(WithBirthdayYearProgress(ctx), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.BirthdayYearProgress == nil || *user.BirthdayYearProgress != want {
+			t.Errorf("%s: birthday_year_progress = %v, want %v", user.Name, user.BirthdayYearProgress, want)
+		}
+	}
+	if user, err := svc.GetUser(ctx, 1); err != nil || user.BirthdayYearProgress != nil {
+		t.Errorf("GetUser without asking = %+v, %v; want no progress", user, err)
+	}
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+)
+
+const (
+	maxCohortBounds = 20
+	maxCohortAge    = 150
+)
+
+// ParseCohortBounds reads a comma-separated list of strictly increasing ages
+// from 1 to maxCohortAge; "" yields nil.
+func ParseCohortBounds(value string) ([]int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	items := strings.Split(value, ",")
+	if len(items) > maxCohortBounds {
+		return nil, cohortBoundsError("max", strconv.Itoa(maxCohortBounds), fmt.Sprintf("bounds must have at most %d ages", maxCohortBounds))
+	}
+	bounds := make([]int, len(items))
+	for i, item := range items {
+		bound, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil {
+			return nil, cohortBoundsError("numeric", "", "bounds must be whole numbers separated by commas")
+		}
+		if bound < 1 || bound > maxCohortAge {
+			return nil, cohortBoundsError("range", fmt.Sprintf("1 %d", maxCohortAge), fmt.Sprintf("bounds must be ages from 1 to %d", maxCohortAge))
+		}
+		if i > 0 && bound <= bounds[i-1] {
+			return nil, cohortBoundsError("increasing", "", "bounds must be strictly increasing")
+		}
+		bounds[i] = bound
+	}
+	return bounds, nil
+}
+
+func cohortBoundsError(rule, param, message string) error {
+	return &ValidationError{Details: []models.FieldError{{Field: "bounds", Rule: rule, Param: param, Message: message}}}
+}
+
+// AgeCohorts compares DOBs with the latest DOB of each bound's age, so like
+// AgePercentile it counts in the store and on the default calendar.
+func (s *userService) AgeCohorts(ctx context.Context, bounds []int) (*models.CohortsResponse, error) {
+	if bounds == nil {
+		bounds = s.cohortBounds
+	}
+	return s.cohorts.get(ctx, fmt.Sprint(bounds), func(ctx context.Context) (*models.CohortsResponse, error) {
+		return s.ageCohorts(ctx, bounds)
+	})
+}
+
+func (s *userService) ageCohorts(ctx context.Context, bounds []int) (*models.CohortsResponse, error) {
+	now := s.clock.Now()
+	today := now.In(s.location)
+	dobs := make([]time.Time, len(bounds))
+	for i, bound := range bounds {
+		dobs[i] = latestDOBForAge(today, bound)
+	}
+	counts, err := s.repo.CountByDOBBounds(ctx, dobs)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &models.CohortsResponse{AsOf: now.UTC(), Cohorts: make([]models.Cohort, len(counts))}
+	for i, count := range counts {
+		cohort := models.Cohort{Count: count}
+		if i > 0 {
+			cohort.MinAge = bounds[i-1]
+		}
+		if i < len(bounds) {
+			maxAge := bounds[i] - 1
+			cohort.MaxAge = &maxAge
+			cohort.Label = fmt.Sprintf("%d-%d", cohort.MinAge, maxAge)
+			if maxAge == cohort.MinAge {
+				cohort.Label = strconv.Itoa(maxAge)
+			}
+		} else {
+			cohort.Label = fmt.Sprintf("%d+", cohort.MinAge)
+		}
+		resp.Cohorts[i] = cohort
+		resp.Total += count
+	}
+	return resp, nil
+}
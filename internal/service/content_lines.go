@@ -0,0 +1,64 @@
+package service
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxContentLine is the longest a content line of an iCalendar or vCard file
+// may be, in octets, before RFC 5545 and RFC 6350 require it to be folded.
+const maxContentLine = 75
+
+// lineWriter writes the content lines of iCalendar and vCard files, keeping
+// the first error.
+type lineWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func newLineWriter(w io.Writer) *lineWriter {
+	return &lineWriter{w: bufio.NewWriter(w)}
+}
+
+// line writes name:value terminated by CRLF, folded so that no line exceeds
+// maxContentLine octets. A fold never splits a UTF-8 sequence.
+func (c *lineWriter) line(name, value string) {
+	if c.err != nil {
+		return
+	}
+	s := name + ":" + value
+	limit := maxContentLine
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		c.write(s[:cut] + "\r\n ")
+		s = s[cut:]
+		// The leading space of a continuation line counts towards it.
+		limit = maxContentLine - 1
+	}
+	c.write(s + "\r\n")
+}
+
+func (c *lineWriter) write(s string) {
+	if c.err == nil {
+		_, c.err = c.w.WriteString(s)
+	}
+}
+
+func (c *lineWriter) flush() error {
+	if c.err == nil {
+		c.err = c.w.Flush()
+	}
+	return c.err
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// textValue escapes a TEXT value, the same way in both formats.
+func textValue(s string) string {
+	return textEscaper.Replace(s)
+}
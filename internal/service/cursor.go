@@ -0,0 +1,49 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+)
+
+// cursorToken is the decoded form of next_cursor. It records the sort order it
+// was issued for, so a cursor cannot be replayed against a different ordering.
+type cursorToken struct {
+	Sort      string    `json:"s"`
+	ID        int32     `json:"id"`
+	CreatedAt time.Time `json:"c,omitzero"`
+	DOB       string    `json:"d,omitempty"`
+}
+
+func encodeCursor(last models.User, sort models.SortOrder) string {
+	token := cursorToken{Sort: sort.String(), ID: last.ID}
+	switch sort.Field {
+	case "created_at":
+		token.CreatedAt = last.CreatedAt
+	case "age":
+		token.DOB = last.DOB.Format(dateLayout)
+	}
+	raw, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(cursor string, sort models.SortOrder) (*repository.Keyset, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var token cursorToken
+	if err := json.Unmarshal(raw, &token); err != nil || token.Sort != sort.String() {
+		return nil, ErrInvalidCursor
+	}
+	after := &repository.Keyset{ID: token.ID, CreatedAt: token.CreatedAt}
+	if sort.Field == "age" {
+		if after.DOB, err = time.Parse(dateLayout, token.DOB); err != nil {
+			return nil, ErrInvalidCursor
+		}
+	}
+	return after, nil
+}
@@ -0,0 +1,379 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/artifact"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrJobNotFound     = errors.New("job not found")
+	ErrUnknownJobKind  = errors.New("unknown job kind")
+	ErrJobNotFinished  = errors.New("job has not finished")
+	ErrNoDownload      = errors.New("job has no download")
+	ErrDownloadExpired = errors.New("job download has expired")
+)
+
+type JobService interface {
+	SubmitJob(ctx context.Context, kind string, payload []byte) (*models.JobResponse, error)
+	GetJob(ctx context.Context, id int64) (*models.JobResponse, error)
+	// Download opens the file a finished job produced.
+	Download(ctx context.Context, id int64) (*JobDownload, error)
+}
+
+// JobDownload is a job's file. The caller closes Body.
+type JobDownload struct {
+	ContentType string
+	Filename    string
+	// Rows and Filters are those of the export's report.
+	Rows    int
+	Filters string
+	Body    io.ReadCloser
+}
+
+// JobFunc runs one job in the tenant it was submitted for; job carries the
+// payload. It reports rows processed through progress; its report is stored as
+// JSON whether or not it fails. It should stop soon after ctx is done.
+type JobFunc func(ctx context.Context, job *models.Job, progress func(rows int)) (any, error)
+
+// jobPollInterval is how often idle workers look for jobs submitted by other
+// instances.
+const jobPollInterval = 5 * time.Second
+
+// JobRunner stores submitted jobs and runs them on a pool of workers. Jobs
+// live in the repository, so any instance's workers may run them and their
+// status survives a restart.
+type JobRunner struct {
+	repo       repository.JobRepository
+	logger     *zap.Logger
+	funcs      map[string]JobFunc
+	workers    int
+	staleAfter time.Duration
+	wake       chan struct{}
+
+	artifacts artifact.Store
+	retention time.Duration
+	locker    Locker
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   sync.WaitGroup
+}
+
+type JobOption func(*JobRunner)
+
+// WithJobWorkers sets how many jobs run at once. With 0, Start only sweeps
+// stale jobs and jobs run when RunNext is called.
+func WithJobWorkers(n int) JobOption {
+	return func(r *JobRunner) {
+		r.workers = n
+	}
+}
+
+// WithJobStaleAfter sets how long a running job may go without progress
+// before it is presumed abandoned by a worker that died.
+func WithJobStaleAfter(d time.Duration) JobOption {
+	return func(r *JobRunner) {
+		r.staleAfter = d
+	}
+}
+
+// WithJobArtifacts serves the files jobs write to store, such as exports, and
+// deletes each one retention after it was written.
+func WithJobArtifacts(store artifact.Store, retention time.Duration) JobOption {
+	return func(r *JobRunner) {
+		r.artifacts, r.retention = store, retention
+	}
+}
+
+// WithJobLocker keeps artifact pruning to one instance at a time; the others
+// skip it until their next sweep.
+func WithJobLocker(l Locker) JobOption {
+	return func(r *JobRunner) {
+		r.locker = l
+	}
+}
+
+func NewJobRunner(repo repository.JobRepository, logger *zap.Logger, opts ...JobOption) *JobRunner {
+	r := &JobRunner{
+		repo:       repo,
+		logger:     logger,
+		funcs:      make(map[string]JobFunc),
+		workers:    1,
+		staleAfter: 2 * time.Minute,
+		wake:       make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Handle registers fn for kind. Call it before Start.
+func (r *JobRunner) Handle(kind string, fn JobFunc) {
+	r.funcs[kind] = fn
+}
+
+func (r *JobRunner) SubmitJob(ctx context.Context, kind string, payload []byte) (*models.JobResponse, error) {
+	if _, ok := r.funcs[kind]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownJobKind, kind)
+	}
+	job, err := r.repo.Create(ctx, kind, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+	return newJobResponse(job), nil
+}
+
+func (r *JobRunner) GetJob(ctx context.Context, id int64) (*models.JobResponse, error) {
+	job, err := r.repo.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrJobNotFound) {
+			return nil, ErrJobNotFound
+		}
+		return nil, err
+	}
+	return newJobResponse(job), nil
+}
+
+// jobArtifact names the file job id writes to the artifact store.
+func jobArtifact(id int64) string {
+	return fmt.Sprintf("job-%d", id)
+}
+
+// Download serves a job whose report names the file's content_type and
+// filename, as an ExportReport does. Once retention has pruned the file the
+// job still reports done, but its download has expired.
+func (r *JobRunner) Download(ctx context.Context, id int64) (*JobDownload, error) {
+	job, err := r.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.State == models.JobPending || job.State == models.JobRunning {
+		return nil, ErrJobNotFinished
+	}
+
+	var file struct {
+		ContentType string `json:"content_type"`
+		Filename    string `json:"filename"`
+		Rows        int    `json:"rows"`
+		Filters     string `json:"filters"`
+	}
+	if job.State != models.JobDone || r.artifacts == nil || json.Unmarshal(job.Report, &file) != nil || file.ContentType == "" {
+		return nil, ErrNoDownload
+	}
+	body, err := r.artifacts.Open(ctx, jobArtifact(id))
+	if err != nil {
+		if errors.Is(err, artifact.ErrNotFound) {
+			return nil, ErrDownloadExpired
+		}
+		return nil, err
+	}
+	return &JobDownload{ContentType: file.ContentType, Filename: file.Filename, Rows: file.Rows, Filters: file.Filters, Body: body}, nil
+}
+
+// Start fails the jobs left running by a worker that is gone and prunes
+// expired artifacts, then starts the workers. It returns at once; Stop ends them.
+func (r *JobRunner) Start(ctx context.Context) error {
+	if err := r.failStale(ctx); err != nil {
+		return err
+	}
+	r.pruneArtifacts(ctx)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancel != nil {
+		return errors.New("job runner already started")
+	}
+	ctx, r.cancel = context.WithCancel(ctx)
+
+	r.done.Add(r.workers + 1)
+	for range r.workers {
+		go func() {
+			defer r.done.Done()
+			r.work(ctx)
+		}()
+	}
+	go func() {
+		defer r.done.Done()
+		r.sweep(ctx)
+	}()
+	return nil
+}
+
+// Stop tells running jobs to wind down and waits for them, or for ctx. An
+// import finishes the batch it is writing before it stops.
+func (r *JobRunner) Stop(ctx context.Context) error {
+	r.mu.Lock()
+	cancel := r.cancel
+	r.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	stopped := make(chan struct{})
+	go func() {
+		r.done.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *JobRunner) work(ctx context.Context) {
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+	for {
+		for ctx.Err() == nil && r.RunNext(ctx) {
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *JobRunner) sweep(ctx context.Context) {
+	ticker := time.NewTicker(r.staleAfter)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.failStale(ctx); err != nil {
+				r.logger.Error("Failed to sweep stale jobs", zap.Error(err))
+			}
+			r.pruneArtifacts(ctx)
+		}
+	}
+}
+
+func (r *JobRunner) pruneArtifacts(ctx context.Context) {
+	if r.artifacts == nil {
+		return
+	}
+	prune := func(ctx context.Context) error {
+		n, err := r.artifacts.Prune(ctx, time.Now().Add(-r.retention))
+		if n > 0 {
+			r.logger.Info("Pruned job artifacts", zap.Int("count", n))
+		}
+		return err
+	}
+	var err error
+	if r.locker == nil {
+		err = prune(ctx)
+	} else {
+		_, err = r.locker.WithLock(ctx, pruneLockKey, prune)
+	}
+	if err != nil && ctx.Err() == nil {
+		r.logger.Error("Failed to prune job artifacts", zap.Error(err))
+	}
+}
+
+func (r *JobRunner) failStale(ctx context.Context) error {
+	n, err := r.repo.FailStale(ctx, r.staleAfter, "worker stopped before the job finished")
+	if n > 0 {
+		r.logger.Warn("Failed stale jobs", zap.Int64("count", n))
+	}
+	return err
+}
+
+// RunNext claims the oldest pending job and runs it in the calling goroutine.
+// It returns false when there was nothing to run. Bookkeeping writes outlive
+// ctx, so a job interrupted by Stop is still recorded as failed.
+func (r *JobRunner) RunNext(ctx context.Context) bool {
+	job, err := r.repo.Claim(ctx)
+	if err != nil {
+		if !errors.Is(err, repository.ErrJobNotFound) && ctx.Err() == nil {
+			r.logger.Error("Failed to claim job", zap.Error(err))
+		}
+		return false
+	}
+
+	logger := r.logger.With(zap.Int64("job_id", job.ID), zap.String("kind", job.Kind), zap.String("tenant", job.TenantID))
+	logger.Info("Job started")
+	bookkeeping := context.WithoutCancel(ctx)
+
+	rows := 0
+	progress := func(n int) {
+		rows = n
+		if err := r.repo.Progress(bookkeeping, job.ID, n); err != nil {
+			logger.Error("Failed to record job progress", zap.Error(err))
+		}
+	}
+	report, err := r.run(tenant.WithID(ctx, job.TenantID), job, progress)
+
+	state, errMsg := models.JobDone, ""
+	if err != nil {
+		state, errMsg = models.JobFailed, err.Error()
+		if ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+			errMsg = fmt.Sprintf("interrupted by shutdown after %d rows", rows)
+		}
+		logger.Warn("Job failed", zap.Error(err))
+	}
+	var reportJSON json.RawMessage
+	if report != nil {
+		if reportJSON, err = json.Marshal(report); err != nil {
+			logger.Error("Failed to encode job report", zap.Error(err))
+			reportJSON = nil
+		}
+		if string(reportJSON) == "null" {
+			reportJSON = nil
+		}
+	}
+	if err := r.repo.Finish(bookkeeping, job.ID, state, rows, reportJSON, errMsg); err != nil {
+		logger.Error("Failed to finish job", zap.Error(err))
+	}
+	return true
+}
+
+// run turns a panicking job into a failed one instead of killing the worker.
+func (r *JobRunner) run(ctx context.Context, job *models.Job, progress func(rows int)) (report any, err error) {
+	fn, ok := r.funcs[job.Kind]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownJobKind, job.Kind)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("job panicked: %v", p)
+		}
+	}()
+	return fn(ctx, job, progress)
+}
+
+func newJobResponse(job *models.Job) *models.JobResponse {
+	return &models.JobResponse{
+		ID:            job.ID,
+		Kind:          job.Kind,
+		State:         job.State,
+		RowsProcessed: job.RowsProcessed,
+		Error:         job.Error,
+		Report:        job.Report,
+		CreatedAt:     job.CreatedAt,
+		UpdatedAt:     job.UpdatedAt,
+		FinishedAt:    job.FinishedAt,
+	}
+}
@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/artifact"
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+	"go.uber.org/zap"
+)
+
+// manualClock only moves when the test advances it.
+type manualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *manualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestJobRunnerLifecycle(t *testing.T) {
+	runner := NewJobRunner(repository.NewMemoryJobRepository(clock.Fixed(pinnedNow)), zap.NewNop(), WithJobWorkers(0))
+	var gotPayload string
+	var gotTenant string
+	runner.Handle("count", func(ctx context.Context, job *models.Job, progress func(rows int)) (any, error) {
+		gotPayload, gotTenant = string(job.Payload), tenant.FromContext(ctx)
+		progress(2)
+		progress(3)
+		return map[string]int{"rows": 3}, nil
+	})
+	ctx := tenant.WithID(context.Background(), "acme")
+
+	job, err := runner.SubmitJob(ctx, "count", []byte("a,b,c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.State != models.JobPending || job.Report != nil {
+		t.Errorf("submitted job = %+v, want pending without a report", job)
+	}
+	if got, err := runner.GetJob(ctx, job.ID); err != nil || got.State != models.JobPending {
+		t.Errorf("GetJob before running = %+v, %v", got, err)
+	}
+
+	if !runner.RunNext(context.Background()) {
+		t.Fatal("RunNext found nothing to run")
+	}
+	if gotPayload != "a,b,c" || gotTenant != "acme" {
+		t.Errorf("job ran with payload %q in tenant %q", gotPayload, gotTenant)
+	}
+	done, err := runner.GetJob(ctx, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done.State != models.JobDone || done.RowsProcessed != 3 || string(done.Report) != `{"rows":3}` || done.FinishedAt == nil {
+		t.Errorf("finished job = %+v (report %s)", done, done.Report)
+	}
+	if runner.RunNext(context.Background()) {
+		t.Error("RunNext ran a job twice")
+	}
+
+	if _, err := runner.GetJob(tenant.WithID(context.Background(), "globex"), job.ID); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("GetJob from another tenant: err = %v, want ErrJobNotFound", err)
+	}
+	if _, err := runner.SubmitJob(ctx, "unknown", nil); !errors.Is(err, ErrUnknownJobKind) {
+		t.Errorf("SubmitJob of an unregistered kind: err = %v, want ErrUnknownJobKind", err)
+	}
+}
+
+func TestJobRunnerRecordsFailures(t *testing.T) {
+	tests := []struct {
+		name       string
+		fn         JobFunc
+		wantErr    string
+		wantReport string
+	}{
+		{
+			name: "error with a partial report",
+			fn: func(ctx context.Context, job *models.Job, progress func(rows int)) (any, error) {
+				progress(100)
+				return &models.ImportReport{Rows: 100, Created: 100, Errors: []models.ImportRowError{}}, errors.New("connection reset")
+			},
+			wantErr:    "connection reset",
+			wantReport: `{"rows":100,"created":100,"updated":0,"skipped":0,"failed":0,"errors":[]}`,
+		},
+		{
+			name: "error without a report",
+			fn: func(ctx context.Context, job *models.Job, progress func(rows int)) (any, error) {
+				var report *models.ImportReport
+				return report, ErrInvalidImport
+			},
+			wantErr: "invalid import file",
+		},
+		{
+			name: "panic",
+			fn: func(ctx context.Context, job *models.Job, progress func(rows int)) (any, error) {
+				panic("index out of range")
+			},
+			wantErr: "job panicked: index out of range",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := NewJobRunner(repository.NewMemoryJobRepository(clock.Fixed(pinnedNow)), zap.NewNop())
+			runner.Handle("job", tt.fn)
+			job, err := runner.SubmitJob(context.Background(), "job", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			runner.RunNext(context.Background())
+			got, err := runner.GetJob(context.Background(), job.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.State != models.JobFailed || got.Error != tt.wantErr || string(got.Report) != tt.wantReport {
+				t.Errorf("job = %+v (report %s), want failed with %q", got, got.Report, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJobRunnerStopLetsTheRunningJobFinish(t *testing.T) {
+	runner := NewJobRunner(repository.NewMemoryJobRepository(clock.Real()), zap.NewNop(), WithJobWorkers(1))
+	started := make(chan struct{})
+	runner.Handle("import", func(ctx context.Context, job *models.Job, progress func(rows int)) (any, error) {
+		close(started)
+		<-ctx.Done()
+		// The batch in flight when shutdown began still completes.
+		progress(100)
+		return &models.ImportReport{Rows: 100, Created: 100}, ctx.Err()
+	})
+	if err := runner.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	job, err := runner.SubmitJob(context.Background(), "import", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("worker never picked up the job")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := runner.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := runner.GetJob(context.Background(), job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.State != models.JobFailed || got.RowsProcessed != 100 || got.Error != "interrupted by shutdown after 100 rows" || got.Report == nil {
+		t.Errorf("interrupted job = %+v", got)
+	}
+}
+
+func TestJobRunnerStartFailsStaleJobs(t *testing.T) {
+	c := &manualClock{now: pinnedNow}
+	repo := repository.NewMemoryJobRepository(c)
+	stale, _ := repo.Create(context.Background(), models.JobUserImport, nil)
+	if _, err := repo.Claim(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	c.Advance(3 * time.Minute)
+	fresh, _ := repo.Create(context.Background(), models.JobUserImport, nil)
+	repo.Claim(context.Background())
+
+	runner := NewJobRunner(repo, zap.NewNop(), WithJobWorkers(0), WithJobStaleAfter(2*time.Minute))
+	if err := runner.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer runner.Stop(context.Background())
+
+	if got, _ := runner.GetJob(context.Background(), stale.ID); got.State != models.JobFailed || got.Error == "" {
+		t.Errorf("stale job = %+v, want failed", got)
+	}
+	if got, _ := runner.GetJob(context.Background(), fresh.ID); got.State != models.JobRunning {
+		t.Errorf("recently updated job = %+v, want still running", got)
+	}
+}
+
+func TestJobRunnerDownload(t *testing.T) {
+	store, err := artifact.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner := NewJobRunner(repository.NewMemoryJobRepository(clock.Real()), zap.NewNop(), WithJobWorkers(0), WithJobArtifacts(store, time.Hour))
+	runner.Handle("file", func(ctx context.Context, job *models.Job, progress func(rows int)) (any, error) {
+		if string(job.Payload) == "fail" {
+			return nil, errors.New("disk full")
+		}
+		w, err := store.Create(ctx, jobArtifact(job.ID))
+		if err != nil {
+			return nil, err
+		}
+		io.WriteString(w, "id\n1\n")
+		return &models.ExportReport{Rows: 1, ContentType: "text/csv", Filename: "users.csv"}, w.Close()
+	})
+	ctx := context.Background()
+
+	ok, _ := runner.SubmitJob(ctx, "file", nil)
+	failed, _ := runner.SubmitJob(ctx, "file", []byte("fail"))
+	if _, err := runner.Download(ctx, ok.ID); !errors.Is(err, ErrJobNotFinished) {
+		t.Errorf("Download of a pending job: err = %v, want ErrJobNotFinished", err)
+	}
+	runner.RunNext(ctx)
+	runner.RunNext(ctx)
+
+	download, err := runner.Download(ctx, ok.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(download.Body)
+	download.Body.Close()
+	if download.ContentType != "text/csv" || download.Filename != "users.csv" || string(body) != "id\n1\n" {
+		t.Errorf("download = %+v with body %q", download, body)
+	}
+	if _, err := runner.Download(ctx, failed.ID); !errors.Is(err, ErrNoDownload) {
+		t.Errorf("Download of a failed job: err = %v, want ErrNoDownload", err)
+	}
+	if _, err := runner.Download(ctx, 99); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Download of a missing job: err = %v, want ErrJobNotFound", err)
+	}
+
+	// A negative retention makes every file already expired.
+	expired := NewJobRunner(runner.repo, zap.NewNop(), WithJobWorkers(0), WithJobArtifacts(store, -time.Hour))
+	if err := expired.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer expired.Stop(ctx)
+	if _, err := expired.Download(ctx, ok.ID); !errors.Is(err, ErrDownloadExpired) {
+		t.Errorf("Download after pruning: err = %v, want ErrDownloadExpired", err)
+	}
+}
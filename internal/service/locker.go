@@ -0,0 +1,16 @@
+package service
+
+import "context"
+
+// Locker keeps a piece of work to one instance at a time; *dblock.Locker is
+// one. WithLock returns false, without running fn, while another instance
+// holds key.
+type Locker interface {
+	WithLock(ctx context.Context, key string, fn func(ctx context.Context) error) (bool, error)
+}
+
+// Keys of the work the service locks.
+const (
+	notifierLockKey = "birthday-notifier"
+	pruneLockKey    = "job-artifact-prune"
+)
@@ -0,0 +1,50 @@
+package service
+
+import "time"
+
+// NextHalfBirthday is the first day, on or after today, that falls six
+// calendar months after a birthday. Where that day does not exist, as six
+// months after Aug 31, it is the last day of the month.
+func NextHalfBirthday(dob, today time.Time) time.Time {
+	for years := CalculateAge(dob, today); ; years++ {
+		if half := milestoneDate(dob, years*12+6); !half.Before(today) {
+			return half
+		}
+	}
+}
+
+// NextRoundBirthday is the first birthday, on or after today, on which the
+// user turns a multiple of ten, and that age. It is the day the age ticks
+// over, so a Feb 29 birthday falls on Mar 1 in common years.
+func NextRoundBirthday(dob, today time.Time) (time.Time, int) {
+	years := CalculateAge(dob, today)
+	if years == 0 || years%10 != 0 || !isBirthday(dob, today) {
+		years = (years/10 + 1) * 10
+	}
+	return monthAnniversary(toDate(dob), years*12), years
+}
+
+// lastMilestoneChange is the most recent day, up to today, on which either
+// milestone moved on: the day after a half birthday or a round birthday.
+func lastMilestoneChange(dob, today time.Time) time.Time {
+	years := CalculateAge(dob, today)
+	half := milestoneDate(dob, years*12+6)
+	if !half.Before(today) {
+		half = milestoneDate(dob, years*12-6)
+	}
+	changed := half.AddDate(0, 0, 1)
+	for round := years / 10 * 10; round > 0; round -= 10 {
+		if after := monthAnniversary(toDate(dob), round*12).AddDate(0, 0, 1); !after.After(today) {
+			return latest(changed, after)
+		}
+	}
+	return changed
+}
+
+// milestoneDate is the date months calendar months after dob, clamped to the
+// end of the month where that day does not exist.
+func milestoneDate(dob time.Time, months int) time.Time {
+	first := time.Date(dob.Year(), dob.Month()+time.Month(months), 1, 0, 0, 0, 0, time.UTC)
+	lastDay := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(dob.Day(), lastDay)-1)
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"golang.org/x/text/unicode/norm"
+)
+
+// ValidationError is input the service itself rejects, after the handler's
+// checks passed: a name that normalization leaves too short, for one.
+type ValidationError struct {
+	Details []models.FieldError
+}
+
+func (e *ValidationError) Error() string { return "validation failed" }
+
+// invisible are the format characters dropped from names: zero-width spaces
+// and bidi controls, which render as nothing yet defeat search and the
+// duplicate check. ZWJ and ZWNJ stay, since emoji sequences and scripts such
+// as Persian need them.
+var invisible = map[rune]bool{
+	'\u200b': true, // zero width space
+	'\u2060': true, // word joiner
+	'\ufeff': true, // zero width no-break space
+	'\u200e': true, // left-to-right mark
+	'\u200f': true, // right-to-left mark
+	'\u061c': true, // arabic letter mark
+	// Bidi embeddings, overrides and isolates.
+	'\u202a': true, '\u202b': true, '\u202c': true, '\u202d': true, '\u202e': true,
+	'\u2066': true, '\u2067': true, '\u2068': true, '\u2069': true,
+}
+
+// NormalizeName trims name, collapses each run of whitespace into one space,
+// drops control and invisible characters and composes what is left to NFC.
+// Letters and combining marks of every script pass through.
+func NormalizeName(name string) string {
+	cleaned := strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsSpace(r):
+			return ' '
+		case unicode.IsControl(r), invisible[r]:
+			return -1
+		}
+		return r
+	}, name)
+	return norm.NFC.String(strings.Join(strings.Fields(cleaned), " "))
+}
+
+// normalizeName applies NormalizeName unless it is disabled, and checks the
+// result against the rules of models.CreateUserRequest.
+func (s *userService) normalizeName(name string) (string, error) {
+	if !s.normalizeNames {
+		return name, nil
+	}
+	name = NormalizeName(name)
+	if details := nameErrors(name); len(details) > 0 {
+		return "", &ValidationError{Details: details}
+	}
+	return name, nil
+}
+
+// nameErrors gives the messages the handler's validator gives for the name
+// rules.
+func nameErrors(name string) []models.FieldError {
+	switch n := utf8.RuneCountInString(name); {
+	case n == 0:
+		return []models.FieldError{{Field: "name", Rule: "required", Message: "name is required"}}
+	case n < 2:
+		return []models.FieldError{{Field: "name", Rule: "min", Param: "2", Message: "name must be at least 2 characters"}}
+	case n > 100:
+		return []models.FieldError{{Field: "name", Rule: "max", Param: "100", Message: "name must be at most 100 characters"}}
+	}
+	return nil
+}
+
+// NamePolicy restricts the characters a name may hold, beyond the length
+// rules.
+type NamePolicy string
+
+const (
+	// NamePolicyAny accepts every character, as normalization leaves it.
+	NamePolicyAny NamePolicy = "any"
+	// NamePolicyLettersSpaces accepts letters of any script, their combining
+	// marks and spaces.
+	NamePolicyLettersSpaces NamePolicy = "letters_spaces"
+	// NamePolicyLettersPunct also accepts hyphens and apostrophes, as in
+	// "Jean-Luc O'Brien".
+	NamePolicyLettersPunct NamePolicy = "letters_punct"
+)
+
+// maxDisallowed bounds the characters a policy error lists, so a name
+// written entirely in emoji does not come back whole.
+const maxDisallowed = 5
+
+// Disallowed lists the distinct characters of name that p rejects, in the
+// order they first appear and at most maxDisallowed of them.
+func (p NamePolicy) Disallowed(name string) []rune {
+	if p == NamePolicyAny || p == "" {
+		return nil
+	}
+	var found []rune
+	for _, r := range name {
+		if p.allows(r) || slices.Contains(found, r) {
+			continue
+		}
+		found = append(found, r)
+		if len(found) == maxDisallowed {
+			break
+		}
+	}
+	return found
+}
+
+func (p NamePolicy) allows(r rune) bool {
+	switch {
+	// ZWNJ and ZWJ shape letters in scripts such as Persian and Devanagari.
+	case unicode.In(r, unicode.L, unicode.M), unicode.IsSpace(r), r == '\u200c', r == '\u200d':
+		return true
+	case p == NamePolicyLettersPunct:
+		// ASCII and typographic forms of each.
+		return strings.ContainsRune("-\u2010'\u2019", r)
+	}
+	return false
+}
+
+// NamePolicyMessage is the message of a name p rejects for holding
+// disallowed.
+func NamePolicyMessage(p NamePolicy, disallowed []rune) string {
+	quoted := make([]string, len(disallowed))
+	for i, r := range disallowed {
+		quoted[i] = strconv.QuoteRune(r)
+	}
+	allowed := "letters and spaces"
+	if p == NamePolicyLettersPunct {
+		allowed = "letters, spaces, hyphens and apostrophes"
+	}
+	return fmt.Sprintf("name may only contain %s, not %s", allowed, strings.Join(quoted, ", "))
+}
+
+// namePolicyErrors gives the detail the handler's validator gives for a name
+// p rejects.
+func namePolicyErrors(p NamePolicy, name string) []models.FieldError {
+	disallowed := p.Disallowed(name)
+	if len(disallowed) == 0 {
+		return nil
+	}
+	return []models.FieldError{{Field: "name", Rule: "name_policy", Param: string(p), Message: NamePolicyMessage(p, disallowed)}}
+}
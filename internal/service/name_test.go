@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"go.uber.org/zap"
+)
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"already clean", "Alice Smith", "Alice Smith"},
+		{"trimmed", "  Alice\t", "Alice"},
+		{"internal whitespace collapsed", "Alice \t\n  Smith", "Alice Smith"},
+		{"no-break space", "Alice\u00a0Smith", "Alice Smith"},
+		{"control characters", "Ali\x00ce\x1b", "Alice"},
+		{"zero-width space", "Al\u200bice", "Alice"},
+		{"byte order mark", "\ufeffAlice", "Alice"},
+		{"decomposed to NFC", "Rene\u0301e", "Renée"},
+		{"precomposed kept", "Renée", "Renée"},
+		{"combining mark after a zero-width space", "Rene\u200b\u0301e", "Renée"},
+		{"stacked marks", "a\u0323\u0302", "ậ"},
+		{"Vietnamese", "Nguyê\u0303n", "Nguyễn"},
+		{"Hebrew", "דוד  כהן", "דוד כהן"},
+		{"Arabic with a right-to-left mark", "\u200fمحمد علي", "محمد علي"},
+		{"bidi override", "\u202eAlice\u202c", "Alice"},
+		{"Persian keeps ZWNJ", "می\u200cخواهم", "می\u200cخواهم"},
+		{"Devanagari", "अनिता", "अनिता"},
+		{"CJK", "  山田 太郎 ", "山田 太郎"},
+		{"emoji", "Alice \U0001f382", "Alice \U0001f382"},
+		{"ZWJ emoji sequence", "The \U0001f468\u200d\U0001f469\u200d\U0001f467 Family", "The \U0001f468\u200d\U0001f469\u200d\U0001f467 Family"},
+		{"emoji with skin tone and variation selector", "✌\ufe0f\U0001f3fd Bo", "✌\ufe0f\U0001f3fd Bo"},
+		{"only invisible", "\u200b \u200e\t", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeName(tt.in); got != tt.want {
+			t.Errorf("%s: NormalizeName(%+q) = %+q, want %+q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWritesNormalizeNames(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryUserRepository(clock.Fixed(pinnedNow))
+	svc := NewUserService(repo, zap.NewNop(), WithClock(clock.Fixed(pinnedNow)))
+
+	created, err := svc.CreateUser(ctx, &models.CreateUserRequest{Name: "  Rene\u0301e \u200b  Smith ", DOB: "1990-05-10"})
+	if err != nil || created.Name != "Renée Smith" {
+		t.Fatalf("CreateUser = %+v, %v; want the name normalized", created, err)
+	}
+	updated, err := svc.UpdateUser(ctx, created.ID, &models.UpdateUserRequest{Name: "Renée\t\tJones", DOB: "1990-05-10"})
+	if err != nil || updated.Name != "Renée Jones" {
+		t.Errorf("UpdateUser = %+v, %v; want the name normalized", updated, err)
+	}
+	list, err := svc.ListUsers(ctx, &models.UserListQuery{Name: "Rene\u0301e  J"})
+	if err != nil || len(list.Users) != 1 {
+		t.Errorf("ListUsers by a decomposed name = %+v, %v; want the user", list, err)
+	}
+
+	// The handler's min=2 passed on the raw value; the normalized one fails.
+	short := models.FieldError{Field: "name", Rule: "min", Param: "2", Message: "name must be at least 2 characters"}
+	var verr *ValidationError
+	if _, err := svc.CreateUser(ctx, &models.CreateUserRequest{Name: " A\u200b ", DOB: "1990-05-10"}); !errors.As(err, &verr) || !reflect.DeepEqual(verr.Details, []models.FieldError{short}) {
+		t.Errorf("CreateUser of a name too short once normalized: err = %v, want a min ValidationError", err)
+	}
+	if _, err := svc.UpdateUser(ctx, created.ID, &models.UpdateUserRequest{Name: "\u200e\u200f", DOB: "1990-05-10"}); !errors.As(err, &verr) || verr.Details[0].Rule != "required" {
+		t.Errorf("UpdateUser to an invisible name: err = %v, want a required ValidationError", err)
+	}
+	name := " B "
+	batch, err := svc.UpdateUsers(ctx, []models.BatchUpdateItem{{ID: created.ID, Name: &name}}, false)
+	if err != nil || batch.Results[0].Status != models.BatchInvalid || !reflect.DeepEqual(batch.Results[0].Details, []models.FieldError{short}) {
+		t.Errorf("UpdateUsers = %+v, %v; want the item invalid", batch, err)
+	}
+}
+
+func TestNameNormalizationDisabled(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryUserRepository(clock.Fixed(pinnedNow))
+	svc := NewUserService(repo, zap.NewNop(), WithClock(clock.Fixed(pinnedNow)), WithNameNormalization(false))
+
+	raw := "  Rene\u0301e  Smith "
+	created, err := svc.CreateUser(ctx, &models.CreateUserRequest{Name: raw, DOB: "1990-05-10"})
+	if err != nil || created.Name != raw {
+		t.Errorf("CreateUser = %+v, %v; want the name as sent", created, err)
+	}
+}
+
+func TestNamePolicyDisallowed(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  map[NamePolicy][]rune
+	}{
+		{"ASCII", "Alice Smith", nil},
+		{"accented", "José Müller", nil},
+		{"decomposed", "Jose\u0301", nil},
+		{"CJK", "李明", nil},
+		{"Cyrillic", "Анна Каренина", nil},
+		{"Devanagari", "अनिता", nil},
+		{"Persian ZWNJ", "می\u200cخواهم", nil},
+		{"hyphen and apostrophe", "Jean-Luc O'Brien", map[NamePolicy][]rune{NamePolicyLettersSpaces: {'-', '\''}}},
+		{"typographic apostrophe", "O’Brien", map[NamePolicy][]rune{NamePolicyLettersSpaces: {'’'}}},
+		{"digits", "R2-D2", map[NamePolicy][]rune{NamePolicyLettersSpaces: {'2', '-'}, NamePolicyLettersPunct: {'2'}}},
+		{"Arabic-Indic digit", "علي٣", map[NamePolicy][]rune{NamePolicyLettersSpaces: {'٣'}, NamePolicyLettersPunct: {'٣'}}},
+		{"emoji", "Alice \U0001f382", map[NamePolicy][]rune{NamePolicyLettersSpaces: {'\U0001f382'}, NamePolicyLettersPunct: {'\U0001f382'}}},
+		{"other punctuation", "Alice, Bob & Co.", map[NamePolicy][]rune{NamePolicyLettersSpaces: {',', '&', '.'}, NamePolicyLettersPunct: {',', '&', '.'}}},
+		{"at most five, distinct", "1122334455667788", map[NamePolicy][]rune{NamePolicyLettersSpaces: []rune("12345"), NamePolicyLettersPunct: []rune("12345")}},
+	}
+	for _, tt := range tests {
+		for _, policy := range []NamePolicy{NamePolicyAny, NamePolicyLettersSpaces, NamePolicyLettersPunct} {
+			if got := policy.Disallowed(tt.input); !reflect.DeepEqual(got, tt.want[policy]) {
+				t.Errorf("%s: %s.Disallowed(%q) = %q, want %q", tt.name, policy, tt.input, got, tt.want[policy])
+			}
+		}
+	}
+}
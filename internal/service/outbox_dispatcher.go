@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/events"
+	"github.com/srinivasarynh/age_calculator/internal/metrics"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"go.uber.org/zap"
+)
+
+// ErrOutboxDisabled is returned for dead-letter requests to a server that
+// publishes no events through the outbox.
+var ErrOutboxDisabled = errors.New("events outbox not enabled")
+
+const (
+	// outboxLease is how long a claimed event is left to its dispatcher. It
+	// must outlast a delivery, which the webhook bounds to 10s.
+	outboxLease = time.Minute
+	// maxOutboxBackoff caps the wait before an event is tried again.
+	maxOutboxBackoff = time.Hour
+	// outboxPruneEvery spaces out the deletion of delivered events.
+	outboxPruneEvery = time.Hour
+	// MaxDeadEvents bounds the dead letters DeadEvents lists.
+	MaxDeadEvents = 100
+)
+
+// OutboxDispatcher delivers the events of the outbox to a publisher, at
+// least once each: an event is marked sent only once it was delivered, and one
+// whose dispatcher stopped before that is claimed again when its lease runs
+// out. Every instance may run one. A failed event is tried again after
+// a wait that doubles from a second, and dead-lettered after maxAttempts
+// until it is replayed.
+type OutboxDispatcher struct {
+	outbox      repository.OutboxRepository
+	publisher   events.Publisher
+	logger      *zap.Logger
+	clock       clock.Clock
+	interval    time.Duration
+	maxAttempts int
+	retention   time.Duration
+	pruned      time.Time
+	metrics     *metrics.Webhooks
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   sync.WaitGroup
+}
+
+type DispatcherOption func(*OutboxDispatcher)
+
+func WithDispatcherClock(c clock.Clock) DispatcherOption {
+	return func(d *OutboxDispatcher) {
+		d.clock = c
+	}
+}
+
+// WithDispatcherInterval sets how often Start looks for due events.
+func WithDispatcherInterval(interval time.Duration) DispatcherOption {
+	return func(d *OutboxDispatcher) {
+		d.interval = interval
+	}
+}
+
+// WithDispatcherMaxAttempts sets how many deliveries an event gets before it
+// is dead-lettered.
+func WithDispatcherMaxAttempts(n int) DispatcherOption {
+	return func(d *OutboxDispatcher) {
+		d.maxAttempts = n
+	}
+}
+
+// WithDispatcherMetrics counts every delivery attempt in m.
+func WithDispatcherMetrics(m *metrics.Webhooks) DispatcherOption {
+	return func(d *OutboxDispatcher) {
+		d.metrics = m
+	}
+}
+
+// WithDispatcherRetention keeps delivered events this long before deleting
+// them; 0 keeps them for good.
+func WithDispatcherRetention(retention time.Duration) DispatcherOption {
+	return func(d *OutboxDispatcher) {
+		d.retention = retention
+	}
+}
+
+func NewOutboxDispatcher(outbox repository.OutboxRepository, publisher events.Publisher, logger *zap.Logger, opts ...DispatcherOption) *OutboxDispatcher {
+	d := &OutboxDispatcher{
+		outbox:      outbox,
+		publisher:   publisher,
+		logger:      logger,
+		clock:       clock.Real(),
+		interval:    time.Second,
+		maxAttempts: 10,
+		retention:   7 * 24 * time.Hour,
+		metrics:     metrics.NewWebhooks(prometheus.NewRegistry()),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// RunOnce delivers every event due and returns how many it delivered. A failed
+// delivery leaves the event for a later run; the other events are still
+// delivered.
+func (d *OutboxDispatcher) RunOnce(ctx context.Context) (int, error) {
+	delivered := 0
+	var errs []error
+	for ctx.Err() == nil {
+		event, err := d.outbox.Claim(ctx, d.clock.Now(), outboxLease)
+		if errors.Is(err, repository.ErrOutboxEventNotFound) {
+			break
+		}
+		if err != nil {
+			errs = append(errs, err)
+			break
+		}
+		if err := d.deliver(ctx, event); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		delivered++
+	}
+	if err := d.prune(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	return delivered, errors.Join(errs...)
+}
+
+func (d *OutboxDispatcher) deliver(ctx context.Context, event *models.OutboxEvent) error {
+	err := d.publisher.Publish(ctx, events.Event{Type: event.Type, TenantID: event.TenantID, At: event.CreatedAt, Data: event.Payload})
+	d.metrics.Delivered(err)
+	now := d.clock.Now()
+	if err == nil {
+		return d.outbox.MarkSent(ctx, event.ID, now)
+	}
+	if ctx.Err() != nil {
+		// Stopping: the event is claimed again once its lease runs out.
+		return err
+	}
+	if event.Attempts >= d.maxAttempts {
+		d.logger.Error("Event dead-lettered", zap.Int64("id", event.ID), zap.String("type", event.Type), zap.Int("attempts", event.Attempts), zap.Error(err))
+		return errors.Join(err, d.outbox.MarkDead(ctx, event.ID, err.Error(), now))
+	}
+	backoff := min(time.Second<<(event.Attempts-1), maxOutboxBackoff)
+	return errors.Join(err, d.outbox.MarkFailed(ctx, event.ID, err.Error(), now.Add(backoff)))
+}
+
+// prune deletes the events delivered more than retention ago, at most once
+// every outboxPruneEvery.
+func (d *OutboxDispatcher) prune(ctx context.Context) error {
+	now := d.clock.Now()
+	if d.retention <= 0 || now.Sub(d.pruned) < outboxPruneEvery {
+		return nil
+	}
+	deleted, err := d.outbox.DeleteSent(ctx, now.Add(-d.retention))
+	if err != nil {
+		return err
+	}
+	d.pruned = now
+	if deleted > 0 {
+		d.logger.Info("Delivered events pruned", zap.Int64("deleted", deleted))
+	}
+	return nil
+}
+
+// DeadEvents lists the oldest MaxDeadEvents dead-lettered events.
+func (d *OutboxDispatcher) DeadEvents(ctx context.Context) (*models.DeadEventsResponse, error) {
+	list, err := d.outbox.ListDead(ctx, MaxDeadEvents)
+	if err != nil {
+		return nil, err
+	}
+	return &models.DeadEventsResponse{Events: list}, nil
+}
+
+// Replay makes a dead-lettered event due at once, with its attempts reset.
+func (d *OutboxDispatcher) Replay(ctx context.Context, id int64) error {
+	return d.outbox.Replay(ctx, id, d.clock.Now())
+}
+
+// Start runs the dispatcher at once and then every interval until Stop.
+func (d *OutboxDispatcher) Start(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.cancel != nil {
+		return errors.New("outbox dispatcher already started")
+	}
+	ctx, d.cancel = context.WithCancel(ctx)
+
+	d.done.Add(1)
+	go func() {
+		defer d.done.Done()
+		d.loop(ctx)
+	}()
+	return nil
+}
+
+// Stop ends the loop and waits for a delivery in progress, or for ctx.
+func (d *OutboxDispatcher) Stop(ctx context.Context) error {
+	d.mu.Lock()
+	cancel := d.cancel
+	d.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	stopped := make(chan struct{})
+	go func() {
+		d.done.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *OutboxDispatcher) loop(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		delivered, err := d.RunOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			d.logger.Warn("Event delivery failed, retrying later", zap.Int("delivered", delivered), zap.Error(err))
+		} else if delivered > 0 {
+			d.logger.Debug("Events delivered", zap.Int("delivered", delivered))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/srinivasarynh/age_calculator/internal/events"
+	"github.com/srinivasarynh/age_calculator/internal/metrics"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"go.uber.org/zap"
+)
+
+// deliveredUsers returns the user ids of the user events waiting on ch.
+func deliveredUsers(t *testing.T, ch <-chan events.Event) []int32 {
+	t.Helper()
+	var ids []int32
+	for {
+		select {
+		case event := <-ch:
+			var data models.UserEvent
+			if err := json.Unmarshal(event.Data.(json.RawMessage), &data); err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, data.UserID)
+		default:
+			return ids
+		}
+	}
+}
+
+func TestAnonymizeStoresEventInOutbox(t *testing.T) {
+	c := &manualClock{now: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
+	users := repository.NewMemoryUserRepository(c)
+	ctx := context.Background()
+	alice, _ := users.Create(ctx, "Alice", time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC))
+	hub := events.NewHub()
+	ch, unsubscribe := hub.Subscribe(10)
+	defer unsubscribe()
+	// The outbox takes the publisher's place: nothing is published directly.
+	svc := NewUserService(users, zap.NewNop(), WithClock(c), WithOutbox(), WithPublisher(hub),
+		WithNotificationRepository(repository.NewMemoryNotificationRepository()))
+
+	if _, err := svc.AnonymizeUser(ctx, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.AnonymizeUser(ctx, alice.ID); !errors.Is(err, repository.ErrAnonymized) {
+		t.Fatalf("second AnonymizeUser: err = %v, want ErrAnonymized", err)
+	}
+	if got := deliveredUsers(t, ch); got != nil {
+		t.Errorf("published %v before any dispatch", got)
+	}
+
+	delivered, err := NewOutboxDispatcher(users.Outbox(), hub, zap.NewNop(), WithDispatcherClock(c)).RunOnce(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := deliveredUsers(t, ch); delivered != 1 || !slices.Equal(got, []int32{alice.ID}) {
+		t.Errorf("dispatched %d, %v; want the one anonymization", delivered, got)
+	}
+}
+
+// crashingPublisher stops its dispatcher, as a crash would, in the middle of
+// its crashAt'th delivery.
+type crashingPublisher struct {
+	events.Publisher
+	crashAt int
+	stop    context.CancelFunc
+	calls   int
+}
+
+func (p *crashingPublisher) Publish(ctx context.Context, event events.Event) error {
+	p.calls++
+	if p.calls == p.crashAt {
+		p.stop()
+		return ctx.Err()
+	}
+	return p.Publisher.Publish(ctx, event)
+}
+
+func TestOutboxDispatcherRedeliversAfterRestart(t *testing.T) {
+	c := &manualClock{now: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
+	outbox := repository.NewMemoryUserRepository(c).Outbox()
+	ctx := context.Background()
+	for id := int32(1); id <= 3; id++ {
+		outbox.Enqueue(ctx, events.Event{Type: models.EventUserAnonymized, TenantID: "acme", At: c.Now(), Data: models.UserEvent{UserID: id}})
+	}
+	hub := events.NewHub()
+	ch, unsubscribe := hub.Subscribe(10)
+	defer unsubscribe()
+
+	crashCtx, stop := context.WithCancel(ctx)
+	crashed := NewOutboxDispatcher(outbox, &crashingPublisher{Publisher: hub, crashAt: 2, stop: stop}, zap.NewNop(), WithDispatcherClock(c))
+	if delivered, _ := crashed.RunOnce(crashCtx); delivered != 1 {
+		t.Errorf("crashed run delivered %d, want 1", delivered)
+	}
+	if got := deliveredUsers(t, ch); !slices.Equal(got, []int32{1}) {
+		t.Errorf("before the crash: delivered %v, want user 1", got)
+	}
+
+	// The event being delivered is leased to the dead dispatcher; the one it
+	// never reached is due.
+	restarted := NewOutboxDispatcher(outbox, hub, zap.NewNop(), WithDispatcherClock(c))
+	if delivered, err := restarted.RunOnce(ctx); err != nil || delivered != 1 {
+		t.Errorf("after restart: RunOnce = %d, %v; want 1", delivered, err)
+	}
+	if got := deliveredUsers(t, ch); !slices.Equal(got, []int32{3}) {
+		t.Errorf("after restart: delivered %v, want user 3", got)
+	}
+
+	c.Advance(outboxLease)
+	if delivered, err := restarted.RunOnce(ctx); err != nil || delivered != 1 {
+		t.Errorf("after the lease: RunOnce = %d, %v; want 1", delivered, err)
+	}
+	if got := deliveredUsers(t, ch); !slices.Equal(got, []int32{2}) {
+		t.Errorf("after the lease: delivered %v, want user 2", got)
+	}
+	if delivered, err := restarted.RunOnce(ctx); err != nil || delivered != 0 {
+		t.Errorf("once all are sent: RunOnce = %d, %v; want 0", delivered, err)
+	}
+}
+
+func TestOutboxDispatcherBacksOffThenDeadLetters(t *testing.T) {
+	c := &manualClock{now: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)}
+	outbox := repository.NewMemoryUserRepository(c).Outbox()
+	ctx := context.Background()
+	outbox.Enqueue(ctx, events.Event{Type: models.EventUserAnonymized, TenantID: "acme", At: c.Now(), Data: models.UserEvent{UserID: 7}})
+	hub := events.NewHub()
+	ch, unsubscribe := hub.Subscribe(10)
+	defer unsubscribe()
+	publisher := &failingPublisher{Publisher: hub, failures: 3}
+	m := metrics.NewWebhooks(prometheus.NewRegistry())
+	dispatcher := NewOutboxDispatcher(outbox, publisher, zap.NewNop(), WithDispatcherClock(c), WithDispatcherMaxAttempts(3), WithDispatcherMetrics(m))
+
+	// Attempts 1 and 2 wait 1s and 2s before the next; the third is the last.
+	for _, wait := range []time.Duration{time.Second, 2 * time.Second} {
+		if _, err := dispatcher.RunOnce(ctx); err == nil {
+			t.Fatal("RunOnce with a failing webhook: err = nil")
+		}
+		c.Advance(wait - time.Millisecond)
+		if delivered, err := dispatcher.RunOnce(ctx); err != nil || delivered != 0 {
+			t.Errorf("RunOnce before the retry is due = %d, %v", delivered, err)
+		}
+		c.Advance(time.Millisecond)
+	}
+	if _, err := dispatcher.RunOnce(ctx); err == nil {
+		t.Fatal("last attempt: err = nil")
+	}
+
+	dead, err := dispatcher.DeadEvents(ctx)
+	if err != nil || len(dead.Events) != 1 || dead.Events[0].Attempts != 3 || dead.Events[0].LastError != "connection refused" {
+		t.Fatalf("DeadEvents = %+v, %v; want the event after 3 attempts", dead, err)
+	}
+	c.Advance(24 * time.Hour)
+	if delivered, err := dispatcher.RunOnce(ctx); err != nil || delivered != 0 {
+		t.Errorf("RunOnce with the event dead = %d, %v; want 0", delivered, err)
+	}
+
+	if err := dispatcher.Replay(ctx, dead.Events[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := dispatcher.Replay(ctx, dead.Events[0].ID); !errors.Is(err, repository.ErrOutboxEventNotFound) {
+		t.Errorf("second Replay: err = %v, want ErrOutboxEventNotFound", err)
+	}
+	if delivered, err := dispatcher.RunOnce(ctx); err != nil || delivered != 1 {
+		t.Errorf("RunOnce after replay = %d, %v; want 1", delivered, err)
+	}
+	if got := deliveredUsers(t, ch); !slices.Equal(got, []int32{7}) {
+		t.Errorf("delivered %v, want user 7", got)
+	}
+	if failed, ok := promtestutil.ToFloat64(m.Deliveries.WithLabelValues("error")), promtestutil.ToFloat64(m.Deliveries.WithLabelValues("ok")); failed != 3 || ok != 1 {
+		t.Errorf("deliveries counted = %v failed, %v ok; want 3 and 1", failed, ok)
+	}
+}
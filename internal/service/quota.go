@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+)
+
+// QuotaError is a create refused because the tenant already holds Limit
+// users, MAX_USERS; Count is how many it holds.
+type QuotaError struct {
+	Limit int64
+	Count int64
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("user quota exceeded: %d of %d", e.Count, e.Limit)
+}
+
+// Details describes the quota the way validation details describe a field.
+func (e *QuotaError) Details() []models.FieldError {
+	return []models.FieldError{quotaDetail(e.Limit, e.Count)}
+}
+
+func quotaDetail(limit, count int64) models.FieldError {
+	return models.FieldError{
+		Field:   "users",
+		Rule:    "max_users",
+		Param:   strconv.FormatInt(limit, 10),
+		Message: fmt.Sprintf("the tenant already holds %d of its %d allowed users", count, limit),
+	}
+}
+
+// quotaRoom is how many more users the tenant may hold. It runs inside tx,
+// whose lock keeps concurrent creates waiting until tx ends, so the count
+// cannot go stale before the rows are written. Without a quota it is
+// unlimited and queries nothing.
+func (s *userService) quotaRoom(ctx context.Context, tx repository.UserRepository) (room, count int64, err error) {
+	if s.maxUsers == 0 {
+		return -1, 0, nil
+	}
+	if err := tx.LockTenant(ctx); err != nil {
+		return 0, 0, err
+	}
+	count, err = tx.Count(ctx, repository.UserFilter{})
+	if err != nil {
+		return 0, 0, err
+	}
+	return max(s.maxUsers-count, 0), count, nil
+}
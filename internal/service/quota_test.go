@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+	"go.uber.org/zap"
+)
+
+func TestMaxUsers(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(clock.Fixed(pinnedNow))
+	svc := NewUserService(repo, zap.NewNop(), WithClock(clock.Fixed(pinnedNow)), WithMaxUsers(2))
+	acme := tenant.WithID(context.Background(), "acme")
+	req := &models.CreateUserRequest{Name: "Alice", DOB: "1990-05-10"}
+
+	for range 2 {
+		if _, err := svc.CreateUser(acme, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := svc.CreateUser(acme, req)
+	var qerr *QuotaError
+	if !errors.As(err, &qerr) || qerr.Limit != 2 || qerr.Count != 2 {
+		t.Fatalf("create past the quota: err = %v, want a QuotaError at 2 of 2", err)
+	}
+
+	// The quota is per tenant.
+	if _, err := svc.CreateUser(context.Background(), req); err != nil {
+		t.Errorf("create in another tenant: %v", err)
+	}
+
+	// Deleting makes room again.
+	if err := svc.DeleteUser(acme, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.CreateUser(acme, req); err != nil {
+		t.Errorf("create after a delete: %v", err)
+	}
+}
+
+func TestMaxUsersImport(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(clock.Fixed(pinnedNow))
+	svc := NewUserService(repo, zap.NewNop(), WithClock(clock.Fixed(pinnedNow)), WithMaxUsers(3))
+	ctx := context.Background()
+	repo.Create(ctx, "Alice", time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC))
+
+	report, err := svc.ImportUsers(ctx, strings.NewReader("name,dob\nBob,1991-01-01\nX,1992-01-01\nCarol,1993-01-01\nDave,1994-01-01\n"), models.ImportOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Created != 2 || report.Failed != 2 {
+		t.Fatalf("report = %+v, want 2 created and 2 failed", report)
+	}
+	quota := report.Errors[1]
+	if quota.Row != 5 || quota.Details[0].Rule != "max_users" || quota.Details[0].Param != "3" {
+		t.Errorf("quota row error = %+v, want row 5 with rule max_users", quota)
+	}
+	if n, _ := repo.Count(ctx, repository.UserFilter{}); n != 3 {
+		t.Errorf("users = %d, want 3", n)
+	}
+}
+
+func TestMaxUsersConcurrentCreates(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(clock.Fixed(pinnedNow))
+	svc := NewUserService(repo, zap.NewNop(), WithClock(clock.Fixed(pinnedNow)), WithMaxUsers(5))
+	ctx := context.Background()
+	for range 4 {
+		repo.Create(ctx, "Alice", time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC))
+	}
+
+	errs := make(chan error, 2)
+	var start sync.WaitGroup
+	start.Add(1)
+	for range 2 {
+		go func() {
+			start.Wait()
+			_, err := svc.CreateUser(ctx, &models.CreateUserRequest{Name: "Bob", DOB: "1991-01-01"})
+			errs <- err
+		}()
+	}
+	start.Done()
+
+	var succeeded, refused int
+	for range 2 {
+		var qerr *QuotaError
+		switch err := <-errs; {
+		case err == nil:
+			succeeded++
+		case errors.As(err, &qerr):
+			refused++
+		default:
+			t.Errorf("create: %v", err)
+		}
+	}
+	if succeeded != 1 || refused != 1 {
+		t.Errorf("%d succeeded and %d were refused, want one each", succeeded, refused)
+	}
+	if n, _ := repo.Count(ctx, repository.UserFilter{}); n != 5 {
+		t.Errorf("users = %d, want 5", n)
+	}
+}
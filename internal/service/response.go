@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/i18n"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"golang.org/x/text/language"
+)
+
+type responseOptions struct {
+	now time.Time
+	// location is the timezone of users who have none.
+	location *time.Location
+	// withoutAge leaves out the age and everything else worked out from the
+	// DOB on the user's calendar.
+	withoutAge bool
+	// withoutDOB leaves out the DOB itself, though the age is still given.
+	withoutDOB bool
+	// locale is the request's Accept-Language, which overrides each user's
+	// own; hasLocale is false when the request sent none.
+	locale    language.Tag
+	hasLocale bool
+	// Users younger than infantAgeYears, or all with ageMonths, also get
+	// their age in months and weeks.
+	infantAgeYears int
+	ageMonths      bool
+	// milestones adds the half birthday and the next round birthday.
+	milestones bool
+	// progress adds how far the user is through their birthday year.
+	progress bool
+	// accessCount adds how often the user has been read; only GetUser
+	// answers it.
+	accessCount bool
+}
+
+type ageMonthsKey struct{}
+
+// WithAgeMonths asks for age_months and age_weeks on every user the service
+// returns for ctx, whatever their age.
+func WithAgeMonths(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ageMonthsKey{}, true)
+}
+
+type milestonesKey struct{}
+
+// WithMilestones asks for half_birthday and next_round_birthday on every user
+// the service returns for ctx.
+func WithMilestones(ctx context.Context) context.Context {
+	return context.WithValue(ctx, milestonesKey{}, true)
+}
+
+type progressKey struct{}
+
+// WithBirthdayYearProgress asks for birthday_year_progress on every user the
+// service returns for ctx.
+func WithBirthdayYearProgress(ctx context.Context) context.Context {
+	return context.WithValue(ctx, progressKey{}, true)
+}
+
+type accessCountKey struct{}
+
+// WithAccessCount asks GetUser for the user's access_count, which fails with
+// ErrAccessTrackingDisabled unless the service tracks accesses.
+func WithAccessCount(ctx context.Context) context.Context {
+	return context.WithValue(ctx, accessCountKey{}, true)
+}
+
+func (s *userService) responseOptions(ctx context.Context) responseOptions {
+	opts := responseOptions{now: s.clock.Now(), location: s.location, infantAgeYears: s.infantAgeYears}
+	opts.locale, opts.hasLocale = i18n.FromContext(ctx)
+	opts.ageMonths, _ = ctx.Value(ageMonthsKey{}).(bool)
+	opts.milestones, _ = ctx.Value(milestonesKey{}).(bool)
+	opts.progress, _ = ctx.Value(progressKey{}).(bool)
+	opts.accessCount, _ = ctx.Value(accessCountKey{}).(bool)
+	return opts
+}
+
+// collectionOptions are for responses listing many users, which hide their
+// DOBs when the service is set to.
+func (s *userService) collectionOptions(ctx context.Context) responseOptions {
+	opts := s.responseOptions(ctx)
+	opts.withoutDOB = s.hideListDOB
+	return opts
+}
+
+// listResponseOptions applies the list's include options.
+func (s *userService) listResponseOptions(ctx context.Context, params *models.UserListQuery) responseOptions {
+	opts := s.collectionOptions(ctx)
+	opts.withoutAge = !params.WantsAge()
+	opts.ageMonths = opts.ageMonths || params.IncludeAgeMonths
+	return opts
+}
+
+// toUserResponse is the only place a stored user becomes an API response, so
+// create, update, get and list cannot drift apart. The computed age is written
+// to age, which lets toUserResponses back a whole page with one allocation.
+// Everything derived from the date is worked out on the user's own calendar.
+func toUserResponse(user *models.User, opts responseOptions, age *int) models.UserResponse {
+	var dob string
+	if !opts.withoutDOB {
+		dob = user.DOB.Format(dateLayout)
+	}
+	if opts.withoutAge {
+		// Only UpdatedAt is left to change the representation.
+		return models.UserResponse{
+			ID:           user.ID,
+			Name:         user.Name,
+			DOB:          dob,
+			Timezone:     user.Timezone,
+			Locale:       user.Locale,
+			CreatedAt:    user.CreatedAt,
+			UpdatedAt:    user.UpdatedAt,
+			AnonymizedAt: user.AnonymizedAt,
+			LastModified: user.UpdatedAt,
+		}
+	}
+	now := opts.now.In(UserLocation(user, opts.location))
+	*age = CalculateAge(user.DOB, now)
+	resp := models.UserResponse{
+		ID:           user.ID,
+		Name:         user.Name,
+		DOB:          dob,
+		Age:          age,
+		AgeValid:     ageValidity(user.DOB, now),
+		Timezone:     user.Timezone,
+		Locale:       user.Locale,
+		IsBirthday:   isBirthday(user.DOB, now),
+		CreatedAt:    user.CreatedAt,
+		UpdatedAt:    user.UpdatedAt,
+		AnonymizedAt: user.AnonymizedAt,
+		LastModified: latest(user.UpdatedAt, lastAgeChange(user.DOB, now)),
+	}
+	if opts.ageMonths || *age < opts.infantAgeYears {
+		months, weeks := AgeInMonthsAndWeeks(user.DOB, now)
+		resp.AgeMonths, resp.AgeWeeks = &months, &weeks
+		resp.LastModified = latest(resp.LastModified, lastMonthOrWeekChange(user.DOB, now, months, weeks))
+	}
+	if opts.milestones {
+		today := toDate(now)
+		resp.HalfBirthday = NextHalfBirthday(user.DOB, today).Format(dateLayout)
+		date, age := NextRoundBirthday(user.DOB, today)
+		resp.NextRoundBirthday = &models.RoundBirthday{Date: date.Format(dateLayout), Age: age}
+		changed := lastMilestoneChange(user.DOB, today)
+		resp.LastModified = latest(resp.LastModified, time.Date(changed.Year(), changed.Month(), changed.Day(), 0, 0, 0, 0, now.Location()))
+	}
+	if opts.progress {
+		progress := BirthdayYearProgress(user.DOB, now)
+		resp.BirthdayYearProgress = &progress
+		// It moves on every day.
+		resp.LastModified = latest(resp.LastModified, time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()))
+	}
+	if tag, ok := displayLocale(user, opts); ok {
+		resp.NextBirthdayDisplay = i18n.FormatDate(tag, NextBirthday(user.DOB, toDate(now)))
+		// The weekday would give the DOB away where it is hidden.
+		if !opts.withoutDOB {
+			resp.BornOnWeekday = i18n.Weekday(tag, user.DOB.Weekday())
+		}
+	}
+	return resp
+}
+
+// displayLocale is the language of the user's display dates, if any applies.
+func displayLocale(user *models.User, opts responseOptions) (language.Tag, bool) {
+	if opts.hasLocale {
+		return opts.locale, true
+	}
+	if user.Locale != "" {
+		return i18n.Parse(user.Locale), true
+	}
+	return language.Und, false
+}
+
+// lastAgeChange is the start, in now's timezone, of the most recent day on
+// which the computed age (or age_valid) changed, or the zero time while the
+// DOB is still in the future.
+func lastAgeChange(dob, now time.Time) time.Time {
+	if IsFutureDOB(dob, now) {
+		return time.Time{}
+	}
+	today := toDate(now)
+	years := today.Year() - dob.Year()
+	birthday := monthAnniversary(dob, years*12)
+	if birthday.After(today) {
+		birthday = monthAnniversary(dob, (years-1)*12)
+	}
+	return time.Date(birthday.Year(), birthday.Month(), birthday.Day(), 0, 0, 0, 0, now.Location())
+}
+
+// lastMonthOrWeekChange is the start, in now's timezone, of the most recent
+// day on which months or weeks, as AgeInMonthsAndWeeks gave them for now,
+// moved on, or the zero time while the DOB is still in the future.
+func lastMonthOrWeekChange(dob, now time.Time, months, weeks int) time.Time {
+	if IsFutureDOB(dob, now) {
+		return time.Time{}
+	}
+	changed := latest(monthAnniversary(toDate(dob), months), toDate(dob).AddDate(0, 0, 7*weeks))
+	return time.Date(changed.Year(), changed.Month(), changed.Day(), 0, 0, 0, 0, now.Location())
+}
+
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// toUserResponses backs the ages of a whole page with one allocation.
+func toUserResponses(users []models.User, opts responseOptions) []models.UserResponse {
+	responses := make([]models.UserResponse, len(users))
+	if opts.withoutAge {
+		for i := range users {
+			responses[i] = toUserResponse(&users[i], opts, nil)
+		}
+		return responses
+	}
+	ages := make([]int, len(users))
+	for i := range users {
+		responses[i] = toUserResponse(&users[i], opts, &ages[i])
+	}
+	return responses
+}
+
+func newUserResponse(user *models.User, opts responseOptions) *models.UserResponse {
+	resp := toUserResponse(user, opts, new(int))
+	return &resp
+}
+
+// ageValidity returns nil for a normal DOB so list responses do not allocate
+// per row, and a pointer to false when the DOB lies in the future.
+func ageValidity(dob, now time.Time) *bool {
+	if !IsFutureDOB(dob, now) {
+		return nil
+	}
+	valid := false
+	return &valid
+}
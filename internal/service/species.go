@@ -0,0 +1,76 @@
+package service
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+)
+
+// SpeciesHuman is the species an age is taken to be of when none is given.
+const SpeciesHuman = "human"
+
+// speciesAges converts an age in years, with the fraction of the year since
+// the last birthday, to the human age it is taken to be. A species is added
+// here.
+var speciesAges = map[string]func(years float64) float64{
+	SpeciesHuman: func(years float64) float64 { return years },
+	// Wang et al. (2020), from DNA methylation: 16·ln(years) + 31, which
+	// holds from the first birthday. The first year climbs linearly to 31.
+	"dog": func(years float64) float64 {
+		if years < 1 {
+			return 31 * years
+		}
+		return 16*math.Log(years) + 31
+	},
+	// The veterinary rule of thumb: 15 at one, 24 at two, then 4 a year.
+	"cat": func(years float64) float64 {
+		switch {
+		case years < 1:
+			return 15 * years
+		case years < 2:
+			return 15 + 9*(years-1)
+		}
+		return 24 + 4*(years-2)
+	},
+}
+
+// Species lists the species HumanAge knows, in order.
+func Species() []string {
+	return slices.Sorted(maps.Keys(speciesAges))
+}
+
+// ParseSpecies checks species, "" meaning SpeciesHuman.
+func ParseSpecies(species string) (string, error) {
+	if species == "" {
+		return SpeciesHuman, nil
+	}
+	if _, ok := speciesAges[species]; !ok {
+		known := Species()
+		return "", &ValidationError{Details: []models.FieldError{{
+			Field:   "species",
+			Rule:    "oneof",
+			Param:   strings.Join(known, " "),
+			Message: "species must be one of " + strings.Join(known, ", "),
+		}}}
+	}
+	return species, nil
+}
+
+// HumanAge is the equivalent human age of something of species born on dob,
+// as of now, to one decimal. species must have passed ParseSpecies.
+func HumanAge(species string, dob, now time.Time) float64 {
+	return math.Round(speciesAges[species](fractionalAge(dob, now))*10) / 10
+}
+
+// fractionalAge is CalculateAge plus the share of the current year of life
+// already gone; a dob after now yields 0.
+func fractionalAge(dob, now time.Time) float64 {
+	if IsFutureDOB(dob, now) {
+		return 0
+	}
+	return float64(CalculateAge(dob, now)) + birthdayYearFraction(dob, now)
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/events"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+	"go.uber.org/zap"
+)
+
+// AnonymizeUser renames the user "Deleted User <id>", moves the DOB to Jan 1
+// of the birth year so that age statistics stay roughly right, clears the
+// timezone and forgets the dates of their birthday notifications, which would
+// still give the birthday away. The user stays listable but takes no further
+// changes; anonymizing again is ErrAnonymized.
+func (s *userService) AnonymizeUser(ctx context.Context, id int32) (*models.UserResponse, error) {
+	var user *models.User
+	err := s.repo.Transact(ctx, func(tx repository.UserRepository) error {
+		current, err := tx.GetById(ctx, id)
+		if err != nil {
+			return err
+		}
+		if current.AnonymizedAt != nil {
+			return repository.ErrAnonymized
+		}
+		birthYear := time.Date(current.DOB.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+		user, err = tx.Anonymize(ctx, id, fmt.Sprintf("Deleted User %d", id), birthYear)
+		if err != nil {
+			return err
+		}
+		return s.enqueue(ctx, tx, models.EventUserAnonymized, models.UserEvent{UserID: id})
+	})
+	if err != nil {
+		return nil, notFound(err)
+	}
+	// The markers live outside the transaction, so they go only once the
+	// anonymization has committed; a rollback must not lose them and have
+	// the birthday notified twice. The user is known to be in this tenant,
+	// so these are its markers.
+	if err := s.notifications.DeleteForUser(ctx, id); err != nil {
+		s.logger.Error("Failed to forget the birthday notifications of an anonymized user", zap.Int32("id", id), zap.Error(err))
+	}
+	s.metrics.Updated.Inc()
+	s.publish(ctx, models.EventUserAnonymized, models.UserEvent{UserID: id})
+
+	return newUserResponse(user, s.responseOptions(ctx)), nil
+}
+
+// AnonymizeUserDryRun runs only the read AnonymizeUser selects the user with,
+// outside a transaction, so that nothing is locked either.
+func (s *userService) AnonymizeUserDryRun(ctx context.Context, id int32) (*models.DryRunResponse, error) {
+	current, err := s.repo.GetById(ctx, id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if current.AnonymizedAt != nil {
+		return nil, repository.ErrAnonymized
+	}
+	return &models.DryRunResponse{AffectedCount: 1, SampleIDs: []int32{id}, Filters: map[string]any{"id": id}}, nil
+}
+
+func (s *userService) event(ctx context.Context, eventType string, data any) events.Event {
+	return events.Event{Type: eventType, TenantID: tenant.FromContext(ctx), At: s.clock.Now().UTC(), Data: data}
+}
+
+// enqueue stores the event in tx's outbox with WithOutbox, and does nothing
+// otherwise; a failure fails the transaction.
+func (s *userService) enqueue(ctx context.Context, tx repository.UserRepository, eventType string, data any) error {
+	if !s.outbox {
+		return nil
+	}
+	return tx.Outbox().Enqueue(ctx, s.event(ctx, eventType, data))
+}
+
+// publish, for a service without an outbox, never fails the write that
+// triggered the event, which has already committed.
+func (s *userService) publish(ctx context.Context, eventType string, data any) {
+	if s.outbox || s.publisher == nil {
+		return
+	}
+	if err := s.publisher.Publish(ctx, s.event(ctx, eventType, data)); err != nil {
+		s.logger.Warn("Failed to publish event", zap.String("type", eventType), zap.Error(err))
+	}
+}
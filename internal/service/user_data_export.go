@@ -0,0 +1,72 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+)
+
+// ExportUserData answers a subject access request. The tree stores a user's
+// row and the markers of their birthday notifications, and nothing else about
+// them; a store added later belongs here too.
+func (s *userService) ExportUserData(ctx context.Context, id int32) (*models.UserDataExport, error) {
+	user, err := s.repo.GetById(ctx, id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	markers, err := s.notifications.ListForUser(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := s.responseOptions(ctx)
+	return &models.UserDataExport{
+		ExportedAt:            opts.now.UTC(),
+		User:                  *newUserResponse(user, opts),
+		BirthdayNotifications: markers,
+	}, nil
+}
+
+// UserDataZip bundles export as user-<id>.json, the document itself, and
+// user-<id>.csv, its user record as one row.
+func UserDataZip(export *models.UserDataExport) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	name := fmt.Sprintf("user-%d", export.User.ID)
+
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name + ".json", Method: zip.Deflate, Modified: export.ExportedAt})
+	if err != nil {
+		return nil, err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(export); err != nil {
+		return nil, err
+	}
+
+	w, err = zw.CreateHeader(&zip.FileHeader{Name: name + ".csv", Method: zip.Deflate, Modified: export.ExportedAt})
+	if err != nil {
+		return nil, err
+	}
+	user := export.User
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "name", "dob", "age", "timezone", "locale", "created_at", "updated_at"})
+	cw.Write([]string{strconv.Itoa(int(user.ID)), user.Name, user.DOB, strconv.Itoa(*user.Age), user.Timezone, user.Locale,
+		user.CreatedAt.UTC().Format(time.RFC3339), user.UpdatedAt.UTC().Format(time.RFC3339)})
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
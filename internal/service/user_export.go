@@ -0,0 +1,207 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/artifact"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"go.uber.org/zap"
+)
+
+// exportPageSize is how many users one query of an export reads, and so how
+// often progress is reported and cancellation is checked.
+const exportPageSize = 500
+
+var exportContentTypes = map[string]string{
+	models.ExportCSV:    "text/csv",
+	models.ExportNDJSON: "application/x-ndjson",
+	models.ExportVCF:    "text/vcard",
+	models.ExportXLSX:   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// ExportContentType is the media type of an export in format, "" meaning CSV.
+func ExportContentType(format string) (string, bool) {
+	if format == "" {
+		format = models.ExportCSV
+	}
+	contentType, ok := exportContentTypes[format]
+	return contentType, ok
+}
+
+// ExportUsers writes the users matching req to w in id order, CSV unless req
+// asks otherwise. It reads by keyset a page at a time, so no query or
+// transaction stays open for the whole export; a user created meanwhile is
+// included only if it sorts after the page being read. Ages are computed as of
+// the moment the export starts. Once ctx is done it stops before the next
+// page and returns the report so far with ctx's error.
+func (s *userService) ExportUsers(ctx context.Context, req *models.ExportUsersRequest, w io.Writer, progress func(rows int)) (*models.ExportReport, error) {
+	format := req.Format
+	if format == "" {
+		format = models.ExportCSV
+	}
+	contentType, ok := ExportContentType(format)
+	if !ok {
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+	opts := s.collectionOptions(ctx)
+	enc, err := newExportEncoder(req, format, !opts.withoutDOB, w)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.ExportReport{Format: format, ContentType: contentType, Filters: req.Filters().Encode()}
+	query := repository.ListQuery{
+		Filter: exportFilter(req),
+		Limit:  exportPageSize,
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		users, err := s.repo.List(ctx, query)
+		if err != nil {
+			return report, err
+		}
+		for i := range users {
+			var age int
+			if err := enc.encode(toUserResponse(&users[i], opts, &age)); err != nil {
+				return report, err
+			}
+		}
+		if err := enc.flush(); err != nil {
+			return report, err
+		}
+		report.Rows += len(users)
+		if progress != nil {
+			progress(report.Rows)
+		}
+
+		if len(users) < exportPageSize {
+			break
+		}
+		last := users[len(users)-1]
+		query.After = &repository.Keyset{ID: last.ID, CreatedAt: last.CreatedAt}
+	}
+	if c, ok := enc.(exportCloser); ok {
+		if err := c.close(); err != nil {
+			return report, err
+		}
+	}
+
+	s.logger.Info("Users exported", zap.Int("rows", report.Rows), zap.String("format", format))
+	return report, nil
+}
+
+// CountExport counts the users ExportUsers would write for req.
+func (s *userService) CountExport(ctx context.Context, req *models.ExportUsersRequest) (int64, error) {
+	return s.repo.Count(ctx, exportFilter(req))
+}
+
+// exportFilter is the filter ExportUsers and CountExport select users by.
+func exportFilter(req *models.ExportUsersRequest) repository.UserFilter {
+	return repository.UserFilter{Name: req.Name}
+}
+
+type exportEncoder interface {
+	encode(user models.UserResponse) error
+	flush() error
+}
+
+// exportCloser is an encoder whose file needs a trailer after the last user.
+type exportCloser interface {
+	close() error
+}
+
+// newExportEncoder drops the dob column of a CSV or XLSX export unless
+// withDOB.
+func newExportEncoder(req *models.ExportUsersRequest, format string, withDOB bool, w io.Writer) (exportEncoder, error) {
+	switch format {
+	case models.ExportNDJSON:
+		buf := bufio.NewWriter(w)
+		return ndjsonExport{buf: buf, enc: json.NewEncoder(buf)}, nil
+	case models.ExportVCF:
+		return vcardExport{c: newLineWriter(w), version: req.Version}, nil
+	case models.ExportXLSX:
+		return newXLSXExport(w, withDOB)
+	}
+	cw := csv.NewWriter(w)
+	header := []string{"id", "name", "dob", "age"}
+	if !withDOB {
+		header = []string{"id", "name", "age"}
+	}
+	if err := cw.Write(header); err != nil {
+		return nil, err
+	}
+	return csvExport{w: cw, withDOB: withDOB}, nil
+}
+
+type csvExport struct {
+	w       *csv.Writer
+	withDOB bool
+}
+
+func (e csvExport) encode(user models.UserResponse) error {
+	if !e.withDOB {
+		return e.w.Write([]string{strconv.Itoa(int(user.ID)), user.Name, strconv.Itoa(*user.Age)})
+	}
+	return e.w.Write([]string{strconv.Itoa(int(user.ID)), user.Name, user.DOB, strconv.Itoa(*user.Age)})
+}
+
+func (e csvExport) flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type ndjsonExport struct {
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func (e ndjsonExport) encode(user models.UserResponse) error {
+	return e.enc.Encode(user)
+}
+
+func (e ndjsonExport) flush() error {
+	return e.buf.Flush()
+}
+
+// ExportFilename names an export made at at, to the minute in UTC, as in
+// users-export-20250115-1030.csv for prefix users-export and format csv.
+func ExportFilename(prefix, format string, at time.Time) string {
+	return fmt.Sprintf("%s-%s.%s", prefix, at.UTC().Format("20060102-1504"), format)
+}
+
+// ExportJob runs exports submitted as jobs; the payload is the JSON of an
+// ExportUsersRequest. The file is written to store and published only once
+// the export succeeds, so a failed or interrupted job leaves nothing behind.
+// It is named for the time the job was submitted.
+func ExportJob(users UserService, store artifact.Store) JobFunc {
+	return func(ctx context.Context, job *models.Job, progress func(rows int)) (any, error) {
+		var req models.ExportUsersRequest
+		if err := json.Unmarshal(job.Payload, &req); err != nil {
+			return nil, fmt.Errorf("invalid export request: %w", err)
+		}
+		w, err := store.Create(ctx, jobArtifact(job.ID))
+		if err != nil {
+			return nil, err
+		}
+		report, err := users.ExportUsers(ctx, &req, w, progress)
+		if err != nil {
+			w.Abort()
+			return report, err
+		}
+		if err := w.Close(); err != nil {
+			return report, err
+		}
+		report.Filename = ExportFilename("users-export", report.Format, job.CreatedAt)
+		return report, nil
+	}
+}
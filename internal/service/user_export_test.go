@@ -0,0 +1,232 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/testutil"
+	"go.uber.org/zap"
+)
+
+func TestExportUsers(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(clock.Fixed(pinnedNow))
+	svc := NewUserService(repo, zap.NewNop(), WithClock(clock.Fixed(pinnedNow)))
+	ctx := context.Background()
+	alice, alison := time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC), time.Date(2000, 2, 29, 0, 0, 0, 0, time.UTC)
+	repo.Create(ctx, "Alice", alice)
+	repo.Create(ctx, "Bob", time.Date(1985, 1, 1, 0, 0, 0, 0, time.UTC))
+	repo.Create(ctx, "Smith, Alison", alison)
+
+	var csv strings.Builder
+	report, err := svc.ExportUsers(ctx, &models.ExportUsersRequest{Name: "ali"}, &csv, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Rows != 2 || report.Format != models.ExportCSV || report.ContentType != "text/csv" {
+		t.Errorf("report = %+v", report)
+	}
+	want := "id,name,dob,age\n" +
+		"1,Alice,1990-05-10," + strconv.Itoa(CalculateAge(alice, pinnedNow)) + "\n" +
+		"3,\"Smith, Alison\",2000-02-29," + strconv.Itoa(CalculateAge(alison, pinnedNow)) + "\n"
+	if csv.String() != want {
+		t.Errorf("CSV export =\n%s\nwant\n%s", csv.String(), want)
+	}
+
+	var ndjson strings.Builder
+	if _, err := svc.ExportUsers(ctx, &models.ExportUsersRequest{Format: models.ExportNDJSON}, &ndjson, nil); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(ndjson.String(), "\n"), "\n")
+	var names []string
+	for _, line := range lines {
+		var user models.UserResponse
+		if err := json.Unmarshal([]byte(line), &user); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		names = append(names, user.Name)
+	}
+	if want := []string{"Alice", "Bob", "Smith, Alison"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("NDJSON export names = %v, want %v", names, want)
+	}
+}
+
+// TestCountExportMatchesExport checks that CountExport counts the rows
+// ExportUsers writes, including for names the list endpoints would normalize.
+func TestCountExportMatchesExport(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(clock.Fixed(pinnedNow))
+	svc := NewUserService(repo, zap.NewNop(), WithClock(clock.Fixed(pinnedNow)))
+	ctx := context.Background()
+	repo.Create(ctx, "Alice", time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC))
+	repo.Create(ctx, "Bob", time.Date(1985, 1, 1, 0, 0, 0, 0, time.UTC))
+	repo.Create(ctx, "Smith, Alison", time.Date(2000, 2, 29, 0, 0, 0, 0, time.UTC))
+
+	for _, name := range []string{"", "ali", "bob", "  ali", "\u200b", "nobody"} {
+		req := &models.ExportUsersRequest{Name: name}
+		report, err := svc.ExportUsers(ctx, req, &strings.Builder{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count, err := svc.CountExport(ctx, req); err != nil || count != int64(report.Rows) {
+			t.Errorf("name %q: CountExport = %d, %v; the export wrote %d rows", name, count, err, report.Rows)
+		}
+	}
+}
+
+func TestExportUsersXLSX(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(clock.Fixed(pinnedNow))
+	ctx := context.Background()
+	alice := time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC)
+	repo.Create(ctx, "Alice", alice)
+	repo.Create(ctx, `<Bob> & "Co" `, time.Date(1900, 3, 1, 0, 0, 0, 0, time.UTC))
+	repo.Create(ctx, "Carol", time.Date(1900, 2, 28, 0, 0, 0, 0, time.UTC))
+
+	for _, hidden := range []bool{false, true} {
+		svc := NewUserService(repo, zap.NewNop(), WithClock(clock.Fixed(pinnedNow)), WithListDOBHidden(hidden))
+		var file bytes.Buffer
+		report, err := svc.ExportUsers(ctx, &models.ExportUsersRequest{Format: models.ExportXLSX}, &file, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Rows != 3 || report.ContentType != "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet" {
+			t.Errorf("report = %+v", report)
+		}
+		sheet := testutil.ReadXLSX(t, file.Bytes())
+		if sheet.FrozenRows != 1 || len(sheet.Rows) != 4 {
+			t.Fatalf("hidden=%v: %d rows with %d frozen, want 4 with the header frozen", hidden, len(sheet.Rows), sheet.FrozenRows)
+		}
+
+		age := strconv.Itoa(CalculateAge(alice, pinnedNow))
+		header := []testutil.XLSXCell{
+			{Ref: "A1", Type: "inlineStr", Style: 2, Text: "id"},
+			{Ref: "B1", Type: "inlineStr", Style: 2, Text: "name"},
+			{Ref: "C1", Type: "inlineStr", Style: 2, Text: "dob"},
+			{Ref: "D1", Type: "inlineStr", Style: 2, Text: "age"},
+		}
+		// 33003 is 1990-05-10 as spreadsheets count days, and 61 is
+		// 1900-03-01; earlier dates are text.
+		rows := [][]testutil.XLSXCell{
+			{{Ref: "A2", Value: "1"}, {Ref: "B2", Type: "inlineStr", Text: "Alice"}, {Ref: "C2", Style: 1, Value: "33003"}, {Ref: "D2", Value: age}},
+			{{Ref: "A3", Value: "2"}, {Ref: "B3", Type: "inlineStr", Text: `<Bob> & "Co" `}, {Ref: "C3", Style: 1, Value: "61"}, {Ref: "D3", Value: "125"}},
+			{{Ref: "A4", Value: "3"}, {Ref: "B4", Type: "inlineStr", Text: "Carol"}, {Ref: "C4", Type: "inlineStr", Text: "1900-02-28"}, {Ref: "D4", Value: "125"}},
+		}
+		want := append([][]testutil.XLSXCell{header}, rows...)
+		if hidden {
+			want = [][]testutil.XLSXCell{
+				{header[0], header[1], {Ref: "C1", Type: "inlineStr", Style: 2, Text: "age"}},
+				{rows[0][0], rows[0][1], {Ref: "C2", Value: age}},
+				{rows[1][0], rows[1][1], {Ref: "C3", Value: "125"}},
+				{rows[2][0], rows[2][1], {Ref: "C4", Value: "125"}},
+			}
+		}
+		if !reflect.DeepEqual(sheet.Rows, want) {
+			t.Errorf("hidden=%v: rows =\n%+v\nwant\n%+v", hidden, sheet.Rows, want)
+		}
+	}
+}
+
+func TestListDOBHidden(t *testing.T) {
+	ctx := context.Background()
+	dob := time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC)
+	age := strconv.Itoa(CalculateAge(dob, pinnedNow))
+	for _, hidden := range []bool{false, true} {
+		repo := repository.NewMemoryUserRepository(clock.Fixed(pinnedNow))
+		svc := NewUserService(repo, zap.NewNop(), WithClock(clock.Fixed(pinnedNow)), WithListDOBHidden(hidden))
+		repo.Create(ctx, "Alice", dob)
+
+		wantDOB := "1990-05-10"
+		if hidden {
+			wantDOB = ""
+		}
+		list, err := svc.ListUsers(ctx, &models.UserListQuery{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		oldest, err := svc.UsersByAge(ctx, true, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, got := range map[string]models.UserResponse{"ListUsers": list.Users[0], "UsersByAge": oldest[0]} {
+			if got.DOB != wantDOB || got.Age == nil || strconv.Itoa(*got.Age) != age {
+				t.Errorf("hidden %v: %s = %+v, want dob %q and the age", hidden, name, got, wantDOB)
+			}
+		}
+		if user, err := svc.GetUser(ctx, 1); err != nil || user.DOB != "1990-05-10" {
+			t.Errorf("hidden %v: GetUser = %+v, %v; want the DOB", hidden, user, err)
+		}
+
+		var csv strings.Builder
+		if _, err := svc.ExportUsers(ctx, &models.ExportUsersRequest{}, &csv, nil); err != nil {
+			t.Fatal(err)
+		}
+		want := "id,name,dob,age\n1,Alice,1990-05-10," + age + "\n"
+		if hidden {
+			want = "id,name,age\n1,Alice," + age + "\n"
+		}
+		if csv.String() != want {
+			t.Errorf("hidden %v: CSV export =\n%s\nwant\n%s", hidden, csv.String(), want)
+		}
+
+		var ndjson, vcf strings.Builder
+		if _, err := svc.ExportUsers(ctx, &models.ExportUsersRequest{Format: models.ExportNDJSON}, &ndjson, nil); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := svc.ExportUsers(ctx, &models.ExportUsersRequest{Format: models.ExportVCF}, &vcf, nil); err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Contains(ndjson.String(), `"dob"`); got == hidden {
+			t.Errorf("hidden %v: NDJSON export = %s", hidden, ndjson.String())
+		}
+		if got := strings.Contains(vcf.String(), "BDAY"); got == hidden {
+			t.Errorf("hidden %v: vCard export = %s", hidden, vcf.String())
+		}
+	}
+}
+
+func TestExportUsersPagesByKeyset(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(clock.Fixed(pinnedNow))
+	svc := NewUserService(repo, zap.NewNop())
+	if _, err := svc.ImportUsers(context.Background(), strings.NewReader(importCSV(1200)), models.ImportOptions{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var progress []int
+	var out strings.Builder
+	report, err := svc.ExportUsers(context.Background(), &models.ExportUsersRequest{}, &out, func(rows int) {
+		progress = append(progress, rows)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{500, 1000, 1200}; !reflect.DeepEqual(progress, want) {
+		t.Errorf("progress = %v, want %v", progress, want)
+	}
+	if lines := strings.Count(out.String(), "\n"); report.Rows != 1200 || lines != 1201 {
+		t.Errorf("exported %d rows in %d lines, want 1200 plus the header", report.Rows, lines)
+	}
+}
+
+func TestExportUsersStopsBetweenPages(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(clock.Fixed(pinnedNow))
+	svc := NewUserService(repo, zap.NewNop())
+	if _, err := svc.ImportUsers(context.Background(), strings.NewReader(importCSV(600)), models.ImportOptions{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var out strings.Builder
+	report, err := svc.ExportUsers(ctx, &models.ExportUsersRequest{}, &out, func(rows int) { cancel() })
+	if !errors.Is(err, context.Canceled) || report == nil || report.Rows != 500 {
+		t.Errorf("report = %+v, err = %v; want the first page and context.Canceled", report, err)
+	}
+}
@@ -0,0 +1,334 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidImport is returned when a CSV upload cannot be read at all, as
+// opposed to having rows that fail validation.
+var ErrInvalidImport = errors.New("invalid import file")
+
+// importBatchSize is how many rows share one transaction, and so how often
+// progress is reported and cancellation is checked.
+const importBatchSize = 100
+
+type importRow struct {
+	line int
+	req  models.CreateUserRequest
+	// invalid is set for a row that could not be split into columns.
+	invalid []models.FieldError
+}
+
+// ImportUsers reads a CSV with a header naming the name and dob columns, in
+// any order; other columns are ignored. Rows failing the create rules are
+// reported and skipped, and opts.Conflict decides what a row naming an
+// existing user does. Each batch commits on its own and is never cut short:
+// once ctx is done the import stops before the next batch and returns the
+// report of the batches done with ctx's error. An atomic import commits once
+// all batches are written instead, and the first batch with a failed row
+// rolls them all back and ends it.
+func (s *userService) ImportUsers(ctx context.Context, r io.Reader, opts models.ImportOptions, progress func(rows int)) (*models.ImportReport, error) {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%w: the file is empty", ErrInvalidImport)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+	columns, err := importColumns(header)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.ImportReport{Errors: []models.ImportRowError{}}
+	// Writes ignore ctx's cancellation so shutdown cannot abort a batch halfway.
+	writeCtx := context.WithoutCancel(ctx)
+	if opts.Atomic {
+		var total importCounts
+		err = s.repo.Transact(writeCtx, func(tx repository.UserRepository) error {
+			return s.readImport(reader, len(header), columns, func(batch []importRow) error {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				counts, err := s.importBatch(writeCtx, tx, report, batch, opts)
+				if err != nil {
+					return err
+				}
+				total.add(counts)
+				if progress != nil {
+					progress(report.Rows)
+				}
+				if report.Failed > 0 {
+					return errRollback
+				}
+				return nil
+			})
+		})
+		if err != nil {
+			report.RolledBack = true
+		} else {
+			s.addImported(report, total)
+		}
+		if errors.Is(err, errRollback) {
+			err = nil
+		}
+	} else {
+		err = s.readImport(reader, len(header), columns, func(batch []importRow) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var counts importCounts
+			err := s.repo.Transact(writeCtx, func(tx repository.UserRepository) error {
+				var err error
+				counts, err = s.importBatch(writeCtx, tx, report, batch, opts)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			s.addImported(report, counts)
+			if progress != nil {
+				progress(report.Rows)
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		return report, err
+	}
+
+	s.logger.Info("Users imported", zap.Int("rows", report.Rows), zap.Int("created", report.Created), zap.Int("updated", report.Updated),
+		zap.Int("skipped", report.Skipped), zap.Int("failed", report.Failed), zap.Bool("rolled_back", report.RolledBack))
+	return report, nil
+}
+
+// readImport splits the rows after the header into batches of
+// importBatchSize and hands each to write, the last one possibly short.
+func (s *userService) readImport(reader *csv.Reader, width int, columns map[string]int, write func(batch []importRow) error) error {
+	batch := make([]importRow, 0, importBatchSize)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var parseErr *csv.ParseError
+		if err != nil && !(errors.As(err, &parseErr) && errors.Is(err, csv.ErrFieldCount)) {
+			return fmt.Errorf("%w: %v", ErrInvalidImport, err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		row := importRow{line: line}
+		if err != nil {
+			row.invalid = []models.FieldError{{Rule: "columns", Param: strconv.Itoa(width), Message: fmt.Sprintf("row must have %d columns", width)}}
+		} else {
+			row.req = models.CreateUserRequest{Name: record[columns["name"]], DOB: record[columns["dob"]]}
+			if s.normalizeNames {
+				row.req.Name = NormalizeName(row.req.Name)
+			}
+		}
+		batch = append(batch, row)
+
+		if len(batch) == importBatchSize {
+			if err := write(batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	return write(batch)
+}
+
+// ImportJob runs imports submitted as jobs; the payload is a
+// models.ImportJobPayload. Jobs queued before imports took options stored
+// the bare file, which is imported without any.
+func ImportJob(users UserService) JobFunc {
+	return func(ctx context.Context, job *models.Job, progress func(rows int)) (any, error) {
+		var payload models.ImportJobPayload
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			payload = models.ImportJobPayload{File: job.Payload}
+		}
+		return users.ImportUsers(ctx, bytes.NewReader(payload.File), payload.Options, progress)
+	}
+}
+
+// importColumns maps the required columns to their positions.
+func importColumns(header []string) (map[string]int, error) {
+	columns := make(map[string]int, 2)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if name == "name" || name == "dob" {
+			if _, dup := columns[name]; dup {
+				return nil, fmt.Errorf("%w: column %s appears more than once", ErrInvalidImport, name)
+			}
+			columns[name] = i
+		}
+	}
+	for _, name := range []string{"name", "dob"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%w: the header has no %s column", ErrInvalidImport, name)
+		}
+	}
+	return columns, nil
+}
+
+// importCounts are the writes of committed batches.
+type importCounts struct {
+	created, updated, skipped int
+}
+
+func (c *importCounts) add(o importCounts) {
+	c.created += o.created
+	c.updated += o.updated
+	c.skipped += o.skipped
+}
+
+func (s *userService) addImported(report *models.ImportReport, counts importCounts) {
+	report.Created += counts.created
+	report.Updated += counts.updated
+	report.Skipped += counts.skipped
+	s.metrics.Created.Add(float64(counts.created))
+	s.metrics.Updated.Add(float64(counts.updated))
+	s.metrics.ImportRows.WithLabelValues("ok").Add(float64(counts.created + counts.updated + counts.skipped))
+}
+
+// importBatch writes the valid rows of batch through tx and reports the
+// others. A row naming an existing user is matched only under a conflict
+// strategy, and then does not count against the quota.
+func (s *userService) importBatch(ctx context.Context, tx repository.UserRepository, report *models.ImportReport, batch []importRow, opts models.ImportOptions) (importCounts, error) {
+	report.Rows += len(batch)
+
+	type user struct {
+		line int
+		name string
+		dob  time.Time
+	}
+	valid := make([]user, 0, len(batch))
+	for _, row := range batch {
+		details := row.invalid
+		if details == nil {
+			details = s.importRowErrors(row.req)
+		}
+		if len(details) > 0 {
+			s.rejectRow(report, row.line, details)
+			continue
+		}
+		dob, _ := ParseDOB(row.req.DOB)
+		valid = append(valid, user{line: row.line, name: row.req.Name, dob: dob})
+	}
+
+	var counts importCounts
+	if len(valid) == 0 {
+		return counts, nil
+	}
+	room, count, err := s.quotaRoom(ctx, tx)
+	if err != nil {
+		return counts, err
+	}
+	if opts.Conflict != "" {
+		// Held until tx ends, so a concurrent import cannot create the user
+		// a row of this one was just found not to match.
+		if err := tx.LockTenant(ctx); err != nil {
+			return counts, err
+		}
+	}
+	for _, u := range valid {
+		if opts.Conflict != "" {
+			existing, err := tx.FindByNameDOB(ctx, u.name, u.dob)
+			if err != nil && !errors.Is(err, repository.ErrNotFound) {
+				return counts, err
+			}
+			if existing != nil {
+				switch opts.Conflict {
+				case models.ImportConflictSkip:
+					counts.skipped++
+				case models.ImportConflictUpdate:
+					err := s.guardNames(ctx, tx, func(tx repository.UserRepository) error {
+						_, err := tx.Update(ctx, existing.ID, u.name, u.dob)
+						return err
+					})
+					if errors.Is(err, repository.ErrDuplicateName) {
+						s.rejectRow(report, u.line, []models.FieldError{duplicateNameDetail()})
+						continue
+					}
+					if err != nil {
+						return counts, err
+					}
+					counts.updated++
+				default:
+					s.rejectRow(report, u.line, []models.FieldError{conflictDetail(existing.ID)})
+				}
+				continue
+			}
+		}
+		// Rows past the quota are reported, and the ones that fit kept.
+		if room >= 0 && int64(counts.created) == room {
+			s.rejectRow(report, u.line, []models.FieldError{quotaDetail(s.maxUsers, count+int64(counts.created))})
+			continue
+		}
+		err := s.guardNames(ctx, tx, func(tx repository.UserRepository) error {
+			_, err := tx.Create(ctx, u.name, u.dob)
+			return err
+		})
+		if errors.Is(err, repository.ErrDuplicateName) {
+			s.rejectRow(report, u.line, []models.FieldError{duplicateNameDetail()})
+			continue
+		}
+		if err != nil {
+			return counts, err
+		}
+		counts.created++
+	}
+	return counts, nil
+}
+
+func duplicateNameDetail() models.FieldError {
+	return models.FieldError{Field: "name", Rule: "unique", Message: "a user with this name already exists"}
+}
+
+func conflictDetail(id int32) models.FieldError {
+	return models.FieldError{
+		Field:   "name",
+		Rule:    "conflict",
+		Param:   strconv.Itoa(int(id)),
+		Message: fmt.Sprintf("user %d already has this name and dob", id),
+	}
+}
+
+func (s *userService) rejectRow(report *models.ImportReport, line int, details []models.FieldError) {
+	report.Failed++
+	s.metrics.ImportRows.WithLabelValues("error").Inc()
+	if len(report.Errors) < models.MaxImportErrors {
+		report.Errors = append(report.Errors, models.ImportRowError{Row: line, Details: details})
+	}
+}
+
+// importRowErrors applies the rules of models.CreateUserRequest, the name
+// policy and the minimum DOB year, with the messages the handler's validator
+// gives for them.
+func (s *userService) importRowErrors(req models.CreateUserRequest) []models.FieldError {
+	details := nameErrors(req.Name)
+	if len(details) == 0 {
+		details = namePolicyErrors(s.namePolicy, req.Name)
+	}
+	if req.DOB == "" {
+		details = append(details, models.FieldError{Field: "dob", Rule: "required", Message: "dob is required"})
+	} else if _, err := s.parseDOB(req.DOB); err != nil {
+		details = append(details, dobError(err, s.minDOBYear))
+	}
+	return details
+}
@@ -0,0 +1,313 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/metrics"
+	"github.com/srinivasarynh/age_calculator/internal/mocks"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+func TestImportUsers(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(clock.Fixed(pinnedNow))
+	svc := NewUserService(repo, zap.NewNop())
+
+	csv := "\ufeffDOB,email,Name\n" +
+		"1990-05-10,alice@example.com,Alice\n" +
+		"1985-01-01,,B\n" +
+		"1990-13-01,,Carol\n" +
+		"2000-02-29,,\"Smith, Dave\"\n" +
+		"1999-09-09,only two\n" +
+		",,Eve\n"
+	report, err := svc.ImportUsers(context.Background(), strings.NewReader(csv), models.ImportOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Rows != 6 || report.Created != 2 || report.Failed != 4 {
+		t.Errorf("report = %+v, want 6 rows, 2 created, 4 failed", report)
+	}
+	type rejection struct {
+		row   int
+		rules []string
+	}
+	var got []rejection
+	for _, e := range report.Errors {
+		r := rejection{row: e.Row}
+		for _, d := range e.Details {
+			r.rules = append(r.rules, d.Field+":"+d.Rule)
+		}
+		got = append(got, r)
+	}
+	want := []rejection{
+		{row: 3, rules: []string{"name:min"}},
+		{row: 4, rules: []string{"dob:datetime"}},
+		{row: 6, rules: []string{":columns"}},
+		{row: 7, rules: []string{"dob:required"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("errors = %+v, want %+v", got, want)
+	}
+
+	users, err := repo.List(context.Background(), repository.ListQuery{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[0].Name != "Alice" || users[1].Name != "Smith, Dave" || users[1].DOB.Format(dateLayout) != "2000-02-29" {
+		t.Errorf("imported users = %+v", users)
+	}
+}
+
+func TestImportUsersReportsDuplicateNames(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(clock.Fixed(pinnedNow), repository.WithUniqueNames())
+	svc := NewUserService(repo, zap.NewNop(), WithUniqueNames(true))
+	if _, err := repo.Create(context.Background(), "Alice", pinnedNow.AddDate(-30, 0, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	csv := "name,dob\nBob,1990-05-10\nALICE,1985-01-01\nCarol,2000-02-29\n"
+	report, err := svc.ImportUsers(context.Background(), strings.NewReader(csv), models.ImportOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Rows != 3 || report.Created != 2 || report.Failed != 1 {
+		t.Errorf("report = %+v, want 3 rows, 2 created, 1 failed", report)
+	}
+	if len(report.Errors) != 1 || report.Errors[0].Row != 3 || report.Errors[0].Details[0].Rule != "unique" {
+		t.Errorf("errors = %+v, want row 3 a duplicate name", report.Errors)
+	}
+	if count, _ := repo.Count(context.Background(), repository.UserFilter{}); count != 3 {
+		t.Errorf("users = %d, want Alice and the 2 imported", count)
+	}
+}
+
+func TestImportUsersRejectsUnreadableFiles(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+		want string
+	}{
+		{name: "empty", csv: "", want: "empty"},
+		{name: "no dob column", csv: "name,birthday\nAlice,1990-05-10\n", want: "no dob column"},
+		{name: "repeated column", csv: "name,dob,name\n", want: "name appears more than once"},
+		{name: "broken quoting", csv: "name,dob\n\"Alice,1990-05-10\nBob\",x\"y\n", want: "quote"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewUserService(repository.NewMemoryUserRepository(clock.Fixed(pinnedNow)), zap.NewNop())
+			_, err := svc.ImportUsers(context.Background(), strings.NewReader(tt.csv), models.ImportOptions{}, nil)
+			if !errors.Is(err, ErrInvalidImport) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want ErrInvalidImport mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func importCSV(rows int) string {
+	var b strings.Builder
+	b.WriteString("name,dob\n")
+	for i := range rows {
+		fmt.Fprintf(&b, "User %d,1990-01-01\n", i)
+	}
+	return b.String()
+}
+
+func TestImportUsersReportsProgressPerBatch(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	m := metrics.NewUsers(registry)
+	svc := NewUserService(repository.NewMemoryUserRepository(clock.Fixed(pinnedNow)), zap.NewNop(), WithMetrics(m))
+
+	var progress []int
+	report, err := svc.ImportUsers(context.Background(), strings.NewReader(importCSV(250)), models.ImportOptions{}, func(rows int) {
+		progress = append(progress, rows)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{100, 200, 250}; !reflect.DeepEqual(progress, want) {
+		t.Errorf("progress = %v, want %v", progress, want)
+	}
+	if report.Created != 250 || promtestutil.ToFloat64(m.Created) != 250 {
+		t.Errorf("created = %d, counter = %v; want 250", report.Created, promtestutil.ToFloat64(m.Created))
+	}
+}
+
+func TestImportUsersCountsRowsByResult(t *testing.T) {
+	m := metrics.NewUsers(prometheus.NewRegistry())
+	svc := NewUserService(repository.NewMemoryUserRepository(clock.Fixed(pinnedNow)), zap.NewNop(), WithMetrics(m))
+	csv := "name,dob\nAlice,1990-05-10\nB,1990-05-10\nCarol,not a date\nDave,1985-01-01\n"
+
+	if _, err := svc.ImportUsers(context.Background(), strings.NewReader(csv), models.ImportOptions{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if ok, failed := promtestutil.ToFloat64(m.ImportRows.WithLabelValues("ok")), promtestutil.ToFloat64(m.ImportRows.WithLabelValues("error")); ok != 2 || failed != 2 {
+		t.Errorf("rows counted = %v ok, %v error; want 2 each", ok, failed)
+	}
+
+	// An atomic import that rolls back wrote nothing, so only its rejected
+	// row counts.
+	if _, err := svc.ImportUsers(context.Background(), strings.NewReader(csv), models.ImportOptions{Atomic: true}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if ok, failed := promtestutil.ToFloat64(m.ImportRows.WithLabelValues("ok")), promtestutil.ToFloat64(m.ImportRows.WithLabelValues("error")); ok != 2 || failed != 4 {
+		t.Errorf("rows counted after the rollback = %v ok, %v error; want 2 and 4", ok, failed)
+	}
+}
+
+func TestImportUsersStopsBetweenBatches(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(clock.Fixed(pinnedNow))
+	svc := NewUserService(repo, zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	report, err := svc.ImportUsers(ctx, strings.NewReader(importCSV(250)), models.ImportOptions{}, func(rows int) { cancel() })
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if report == nil || report.Rows != 100 || report.Created != 100 {
+		t.Errorf("report = %+v, want the first batch only", report)
+	}
+	if n, _ := repo.Count(context.Background(), repository.UserFilter{}); n != 100 {
+		t.Errorf("stored %d users, want the 100 of the finished batch", n)
+	}
+}
+
+func TestImportUsersConflicts(t *testing.T) {
+	// Row 2 names Alice in another case, row 3 shares only Bob's name and row
+	// 5 repeats row 4, which the import itself creates.
+	const csv = "name,dob\nALICE,1990-05-10\nBob,2001-01-01\nCarol,1985-03-03\ncarol,1985-03-03\n"
+	tests := []struct {
+		name       string
+		opts       models.ImportOptions
+		want       models.ImportReport
+		conflicts  []int
+		aliceName  string
+		storedWant int64
+	}{
+		{name: "no strategy", want: models.ImportReport{Created: 4}, aliceName: "Alice", storedWant: 6},
+		{name: "skip", opts: models.ImportOptions{Conflict: models.ImportConflictSkip}, want: models.ImportReport{Created: 2, Skipped: 2}, aliceName: "Alice", storedWant: 4},
+		{name: "update", opts: models.ImportOptions{Conflict: models.ImportConflictUpdate}, want: models.ImportReport{Created: 2, Updated: 2}, aliceName: "ALICE", storedWant: 4},
+		{name: "fail", opts: models.ImportOptions{Conflict: models.ImportConflictFail}, want: models.ImportReport{Created: 2, Failed: 2}, conflicts: []int{2, 5}, aliceName: "Alice", storedWant: 4},
+		{name: "atomic skip", opts: models.ImportOptions{Conflict: models.ImportConflictSkip, Atomic: true}, want: models.ImportReport{Created: 2, Skipped: 2}, aliceName: "Alice", storedWant: 4},
+		{name: "atomic update", opts: models.ImportOptions{Conflict: models.ImportConflictUpdate, Atomic: true}, want: models.ImportReport{Created: 2, Updated: 2}, aliceName: "ALICE", storedWant: 4},
+		{name: "atomic fail", opts: models.ImportOptions{Conflict: models.ImportConflictFail, Atomic: true}, want: models.ImportReport{Failed: 2, RolledBack: true}, conflicts: []int{2, 5}, aliceName: "Alice", storedWant: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := repository.NewMemoryUserRepository(clock.Fixed(pinnedNow))
+			alice, _ := repo.Create(ctx, "Alice", time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC))
+			repo.Create(ctx, "Bob", time.Date(2000, 2, 29, 0, 0, 0, 0, time.UTC))
+			svc := NewUserService(repo, zap.NewNop())
+
+			report, err := svc.ImportUsers(ctx, strings.NewReader(csv), tt.opts, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := *report
+			got.Rows, got.Errors = 0, nil
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("report = %+v, want %+v", got, tt.want)
+			}
+			var conflicts []int
+			for _, e := range report.Errors {
+				if len(e.Details) == 1 && e.Details[0].Rule == "conflict" {
+					conflicts = append(conflicts, e.Row)
+				}
+			}
+			if !reflect.DeepEqual(conflicts, tt.conflicts) {
+				t.Errorf("conflicting rows = %v, want %v", conflicts, tt.conflicts)
+			}
+
+			if user, err := repo.GetById(ctx, alice.ID); err != nil || user.Name != tt.aliceName {
+				t.Errorf("Alice after the import = %+v, %v; want named %q", user, err, tt.aliceName)
+			}
+			if n, _ := repo.Count(ctx, repository.UserFilter{}); n != tt.storedWant {
+				t.Errorf("stored %d users, want %d", n, tt.storedWant)
+			}
+		})
+	}
+}
+
+func TestImportUsersAtomicRollsBackEveryBatch(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(clock.Fixed(pinnedNow))
+	svc := NewUserService(repo, zap.NewNop())
+
+	var progress []int
+	report, err := svc.ImportUsers(context.Background(), strings.NewReader(importCSV(250)+"B,1990-01-01\n"+importCSV(10)[len("name,dob\n"):]), models.ImportOptions{Atomic: true}, func(rows int) {
+		progress = append(progress, rows)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.RolledBack || report.Rows != 261 || report.Created != 0 || report.Failed != 1 || report.Errors[0].Row != 252 {
+		t.Errorf("report = %+v, want 261 rows rolled back for the one at line 252", report)
+	}
+	if want := []int{100, 200, 261}; !reflect.DeepEqual(progress, want) {
+		t.Errorf("progress = %v, want %v", progress, want)
+	}
+	if n, _ := repo.Count(context.Background(), repository.UserFilter{}); n != 0 {
+		t.Errorf("stored %d users, want none", n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	report, err = svc.ImportUsers(ctx, strings.NewReader(importCSV(250)), models.ImportOptions{Atomic: true}, func(rows int) { cancel() })
+	if !errors.Is(err, context.Canceled) || !report.RolledBack || report.Created != 0 {
+		t.Errorf("cancelled import = %+v, %v; want rolled back with context.Canceled", report, err)
+	}
+	if n, _ := repo.Count(context.Background(), repository.UserFilter{}); n != 0 {
+		t.Errorf("stored %d users after a cancelled atomic import, want none", n)
+	}
+}
+
+func TestImportUsersRepositoryError(t *testing.T) {
+	repo := mocks.NewUserRepository(t)
+	boom := errors.New("connection reset")
+	repo.EXPECT().Transact(mock.Anything, mock.Anything).Return(boom)
+	svc := NewUserService(repo, zap.NewNop())
+
+	if _, err := svc.ImportUsers(context.Background(), strings.NewReader(importCSV(3)), models.ImportOptions{}, nil); !errors.Is(err, boom) {
+		t.Errorf("err = %v, want the repository error", err)
+	}
+}
+
+func TestImportRowErrorsMatchCreateRules(t *testing.T) {
+	tests := []struct {
+		req    models.CreateUserRequest
+		policy NamePolicy
+		want   []string
+	}{
+		{req: models.CreateUserRequest{Name: "Al", DOB: "1990-05-10"}},
+		{req: models.CreateUserRequest{Name: strings.Repeat("é", 100), DOB: "1990-05-10"}},
+		{req: models.CreateUserRequest{Name: strings.Repeat("é", 101), DOB: "1990-05-10"}, want: []string{"name must be at most 100 characters"}},
+		{req: models.CreateUserRequest{}, want: []string{"name is required", "dob is required"}},
+		{req: models.CreateUserRequest{Name: "A", DOB: "10/05/1990"}, want: []string{"name must be at least 2 characters", "dob must be a date in the format 2006-01-02"}},
+		{req: models.CreateUserRequest{Name: "R2-D2", DOB: "1990-05-10"}, policy: NamePolicyAny},
+		{req: models.CreateUserRequest{Name: "R2-D2", DOB: "1990-05-10"}, policy: NamePolicyLettersPunct, want: []string{`name may only contain letters, spaces, hyphens and apostrophes, not '2'`}},
+		{req: models.CreateUserRequest{Name: "Al", DOB: "1900-01-01"}},
+		{req: models.CreateUserRequest{Name: "Al", DOB: "0001-01-01"}, want: []string{"dob must be in 1900 or later"}},
+	}
+	for _, tt := range tests {
+		s := &userService{namePolicy: tt.policy, minDOBYear: 1900}
+		var got []string
+		for _, d := range s.importRowErrors(tt.req) {
+			got = append(got, d.Message)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("importRowErrors(%+v) = %v, want %v", tt.req, got, tt.want)
+		}
+	}
+}
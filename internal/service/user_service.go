@@ -3,151 +3,941 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"math"
+	"strconv"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/events"
+	"github.com/srinivasarynh/age_calculator/internal/metrics"
 	"github.com/srinivasarynh/age_calculator/internal/models"
 	"github.com/srinivasarynh/age_calculator/internal/repository"
 	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
 )
 
 var (
 	ErrUserNotFound = errors.New("user not found")
 	ErrInvalidDate  = errors.New("invalid date format")
+	// ErrDOBTooEarly is a DOB before the minimum year, most likely a zero
+	// date from another system.
+	ErrDOBTooEarly   = errors.New("date of birth is too early")
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrNameLookupDisabled is returned by GetUserByName unless names are
+	// unique, since otherwise a name does not identify one user.
+	ErrNameLookupDisabled = errors.New("lookup by name requires unique names")
+	// ErrNoUsers is returned by UsersByAge when there is no user to rank.
+	ErrNoUsers = errors.New("no users")
 )
 
+//go:generate mockery --name UserService --output ../mocks --outpkg mocks --filename user_service.go --with-expecter
 type UserService interface {
 	CreateUser(ctx context.Context, req *models.CreateUserRequest) (*models.UserResponse, error)
 	GetUser(ctx context.Context, id int32) (*models.UserResponse, error)
-	ListUsers(ctx context.Context, params *models.PaginationParams) (*models.UserListResponse, error)
+	GetUserByName(ctx context.Context, name string) (*models.UserResponse, error)
+	// BirthdaysToday lists the users whose birthday it is now in their own
+	// timezone.
+	BirthdaysToday(ctx context.Context) (*models.BirthdaysResponse, error)
+	ListUsers(ctx context.Context, params *models.UserListQuery) (*models.UserListResponse, error)
+	// AgePercentile ranks the user's age among the users matching params'
+	// filters; paging and sorting do not apply.
+	AgePercentile(ctx context.Context, id int32, params *models.UserListQuery) (*models.AgePercentileResponse, error)
+	// BirthdayTwins lists one page of the other users who share the user's
+	// birthday this year; only params' page and page_size apply.
+	BirthdayTwins(ctx context.Context, id int32, params *models.UserListQuery) (*models.UserListResponse, error)
+	// Leaplings lists one page of the users born on Feb 29; only params'
+	// page and page_size apply.
+	Leaplings(ctx context.Context, params *models.UserListQuery) (*models.LeaplingListResponse, error)
+	// AgeCohorts counts the users in each age range between bounds, which
+	// must have passed ParseCohortBounds; nil uses the configured bounds.
+	AgeCohorts(ctx context.Context, bounds []int) (*models.CohortsResponse, error)
+	// BirthdayCollisions lists the limit birthdays shared by the most users.
+	BirthdayCollisions(ctx context.Context, limit int) (*models.BirthdayCollisionsResponse, error)
+	// UsersByAge returns the count oldest users, or the youngest unless
+	// oldest, leaving out anonymized users.
+	UsersByAge(ctx context.Context, oldest bool, count int) ([]models.UserResponse, error)
 	UpdateUser(ctx context.Context, id int32, req *models.UpdateUserRequest) (*models.UserResponse, error)
+	// PatchUser hands patch the user as an update request to change, then
+	// applies it, all in one transaction holding the user's row, so
+	// concurrent patches cannot overwrite each other. An error from patch is
+	// returned as it is. The read is not recorded as an access.
+	PatchUser(ctx context.Context, id int32, patch func(req *models.UpdateUserRequest) error) (*models.UserResponse, error)
+	UpdateUsers(ctx context.Context, items []models.BatchUpdateItem, atomic bool) (*models.BatchUpdateResponse, error)
+	// AnonymizeUser irreversibly replaces the user's personal data, keeping
+	// the row and its birth year.
+	AnonymizeUser(ctx context.Context, id int32) (*models.UserResponse, error)
+	// AnonymizeUserDryRun reports the user AnonymizeUser would anonymize,
+	// failing as it would, and changes nothing.
+	AnonymizeUserDryRun(ctx context.Context, id int32) (*models.DryRunResponse, error)
 	DeleteUser(ctx context.Context, id int32) error
+	// ImportUsers calls progress, when non-nil, with the rows read so far
+	// after each batch it writes.
+	ImportUsers(ctx context.Context, r io.Reader, opts models.ImportOptions, progress func(rows int)) (*models.ImportReport, error)
+	// ExportUsers calls progress, when non-nil, with the rows written so far
+	// after each page.
+	ExportUsers(ctx context.Context, req *models.ExportUsersRequest, w io.Writer, progress func(rows int)) (*models.ExportReport, error)
+	// CountExport counts the users ExportUsers would write for req.
+	CountExport(ctx context.Context, req *models.ExportUsersRequest) (int64, error)
+	// BirthdayCalendar writes an iCalendar file of the birthdays of the users
+	// matching params' filters to w; paging and sorting do not apply.
+	BirthdayCalendar(ctx context.Context, params *models.UserListQuery, w io.Writer) error
+	// ExportUserData gathers everything stored about the user.
+	ExportUserData(ctx context.Context, id int32) (*models.UserDataExport, error)
+	FindFutureDOBs(ctx context.Context) (*models.DOBValidationResponse, error)
+	// CreateShare issues a token for the user's public profile, returned
+	// only this once.
+	CreateShare(ctx context.Context, id int32) (*models.ShareResponse, error)
+	ListShares(ctx context.Context, id int32) (*models.ShareListResponse, error)
+	RevokeShare(ctx context.Context, id int32, shareID int64) error
+	// SharedProfile is the public profile a live token leads to, from any
+	// tenant; any other token is ErrShareNotFound.
+	SharedProfile(ctx context.Context, token string) (*models.SharedProfile, error)
+	// ResetAggregates drops the cached cohorts and birthday collisions, for
+	// when the users are replaced other than through this service.
+	ResetAggregates()
 }
 
 type userService struct {
-	repo   repository.UserRepository
-	logger *zap.Logger
+	repo           repository.UserRepository
+	logger         *zap.Logger
+	clock          clock.Clock
+	metrics        *metrics.Users
+	fuzzyThreshold float64
+	uniqueNames    bool
+	normalizeNames bool
+	namePolicy     NamePolicy
+	minDOBYear     int
+	maxUsers       int64
+	dedupeWindow   time.Duration
+	hideListDOB    bool
+	infantAgeYears int
+	cohortBounds   []int
+	location       *time.Location
+	notifications  repository.NotificationRepository
+	shares         repository.ShareRepository
+	shareTTL       time.Duration
+	publisher      events.Publisher
+	outbox         bool
+	access         *AccessTracker
+	aggregateTTL   time.Duration
+	cohorts        *aggregateCache[*models.CohortsResponse]
+	collisions     *aggregateCache[*models.BirthdayCollisionsResponse]
 }
 
-func NewUserService(repo repository.UserRepository, logger *zap.Logger) UserService {
-	return &userService{
-		repo:   repo,
-		logger: logger,
+type Option func(*userService)
+
+func WithClock(c clock.Clock) Option {
+	return func(s *userService) {
+		s.clock = c
+	}
+}
+
+// WithMetrics counts writes on m. Without it the counters are kept but not
+// exported.
+func WithMetrics(m *metrics.Users) Option {
+	return func(s *userService) {
+		s.metrics = m
+	}
+}
+
+// WithFuzzyThreshold sets how similar, from 0 to 1, a name must be to match
+// name_fuzzy. It defaults to 0.3, pg_trgm's own default.
+func WithFuzzyThreshold(threshold float64) Option {
+	return func(s *userService) {
+		s.fuzzyThreshold = threshold
+	}
+}
+
+// WithUniqueNames enables GetUserByName, and has batches and imports write
+// each item in a savepoint so a duplicate name fails only its own item.
+// Uniqueness itself is enforced by the repository.
+func WithUniqueNames(enabled bool) Option {
+	return func(s *userService) {
+		s.uniqueNames = enabled
+	}
+}
+
+// WithNameNormalization turns NormalizeName on every name written or looked
+// up on or off. It is on by default.
+func WithNameNormalization(enabled bool) Option {
+	return func(s *userService) {
+		s.normalizeNames = enabled
+	}
+}
+
+// WithNamePolicy applies p to imported names; the handler applies it to the
+// other writes. It defaults to NamePolicyAny.
+func WithNamePolicy(p NamePolicy) Option {
+	return func(s *userService) {
+		s.namePolicy = p
+	}
+}
+
+// WithMinDOBYear rejects DOBs written before Jan 1 of year with
+// ErrDOBTooEarly. It defaults to 1900.
+func WithMinDOBYear(year int) Option {
+	return func(s *userService) {
+		s.minDOBYear = year
+	}
+}
+
+// WithMaxUsers refuses creates that would give a tenant more than limit users
+// with a *QuotaError. 0, the default, is no limit.
+func WithMaxUsers(limit int64) Option {
+	return func(s *userService) {
+		s.maxUsers = limit
+	}
+}
+
+// WithDedupeWindow makes CreateUser return, marked Deduplicated, the user
+// created no more than window ago with the same name and DOB instead of
+// creating another, so a form submitted twice yields one user. 0, the
+// default, always creates.
+func WithDedupeWindow(window time.Duration) Option {
+	return func(s *userService) {
+		s.dedupeWindow = window
+	}
+}
+
+// WithListDOBHidden leaves the DOB out of responses listing several users,
+// and out of exports, while keeping their ages; a user fetched, created or
+// updated by id still has it.
+func WithListDOBHidden(hidden bool) Option {
+	return func(s *userService) {
+		s.hideListDOB = hidden
+	}
+}
+
+// WithInfantAgeYears gives age_months and age_weeks for users younger than
+// years; 0 gives them only to requests that ask. It defaults to 2.
+func WithInfantAgeYears(years int) Option {
+	return func(s *userService) {
+		s.infantAgeYears = years
+	}
+}
+
+// WithCohortBounds sets the ages AgeCohorts splits users at when given none.
+// They default to 18, 30, 45 and 65.
+func WithCohortBounds(bounds []int) Option {
+	return func(s *userService) {
+		s.cohortBounds = bounds
+	}
+}
+
+// WithAggregateTTL serves AgeCohorts and BirthdayCollisions from results up
+// to ttl old, and past it still serves them while they are refreshed in the
+// background. 0, the default, computes them on every call.
+func WithAggregateTTL(ttl time.Duration) Option {
+	return func(s *userService) {
+		s.aggregateTTL = ttl
+	}
+}
+
+// WithDefaultLocation sets the timezone of users who have none. It defaults
+// to UTC.
+func WithDefaultLocation(loc *time.Location) Option {
+	return func(s *userService) {
+		s.location = loc
+	}
+}
+
+// WithNotificationRepository is where the birthday notifier keeps its
+// markers, which a user's data export includes.
+func WithNotificationRepository(repo repository.NotificationRepository) Option {
+	return func(s *userService) {
+		s.notifications = repo
+	}
+}
+
+// WithShareRepository is where share tokens are kept.
+func WithShareRepository(repo repository.ShareRepository) Option {
+	return func(s *userService) {
+		s.shares = repo
+	}
+}
+
+// WithShareTTL is how long a share token lasts. It defaults to a week.
+func WithShareTTL(ttl time.Duration) Option {
+	return func(s *userService) {
+		s.shareTTL = ttl
+	}
+}
+
+// WithPublisher publishes the service's user events, such as
+// user.anonymized, to p. Without it no events are sent.
+func WithPublisher(p events.Publisher) Option {
+	return func(s *userService) {
+		s.publisher = p
+	}
+}
+
+// WithOutbox stores the service's user events in the repository's events
+// outbox, in the transaction of the write they report, for an
+// OutboxDispatcher to deliver. It takes the place of WithPublisher.
+func WithOutbox() Option {
+	return func(s *userService) {
+		s.outbox = true
+	}
+}
+
+// WithAccessTracker counts every GetUser on t and enables
+// include=access_count. The caller starts and stops t.
+func WithAccessTracker(t *AccessTracker) Option {
+	return func(s *userService) {
+		s.access = t
+	}
+}
+
+func NewUserService(repo repository.UserRepository, logger *zap.Logger, opts ...Option) UserService {
+	s := &userService{
+		repo:           repo,
+		logger:         logger,
+		clock:          clock.Real(),
+		metrics:        metrics.NewUsers(prometheus.NewRegistry()),
+		fuzzyThreshold: 0.3,
+		normalizeNames: true,
+		namePolicy:     NamePolicyAny,
+		minDOBYear:     1900,
+		infantAgeYears: 2,
+		cohortBounds:   []int{18, 30, 45, 65},
+		location:       time.UTC,
+		notifications:  repository.NewMemoryNotificationRepository(),
+		shareTTL:       7 * 24 * time.Hour,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.shares == nil {
+		s.shares = repository.NewMemoryShareRepository(s.clock)
 	}
+	s.cohorts = newAggregateCache[*models.CohortsResponse](s.aggregateTTL, s.clock, s.logger)
+	s.collisions = newAggregateCache[*models.BirthdayCollisionsResponse](s.aggregateTTL, s.clock, s.logger)
+	return s
 }
 
 func (s *userService) CreateUser(ctx context.Context, req *models.CreateUserRequest) (*models.UserResponse, error) {
-	dob, err := time.Parse("2006-01-02", req.DOB)
+	dob, err := s.parseDOB(req.DOB)
 	if err != nil {
 		s.logger.Error("Invalid DOB format", zap.Error(err))
-		return nil, ErrInvalidDate
+		return nil, err
+	}
+	name, err := s.normalizeName(req.Name)
+	if err != nil {
+		return nil, err
 	}
 
-	user, err := s.repo.Create(ctx, req.Name, dob)
+	var user *models.User
+	var deduplicated bool
+	if s.maxUsers == 0 && s.dedupeWindow == 0 && req.Timezone == "" && req.Locale == "" {
+		user, err = s.repo.Create(ctx, name, dob)
+	} else {
+		err = s.repo.Transact(ctx, func(tx repository.UserRepository) error {
+			if s.dedupeWindow > 0 {
+				// The lock makes a concurrent duplicate wait for this
+				// create's commit, and then find it.
+				if err := tx.LockTenant(ctx); err != nil {
+					return err
+				}
+				recent, err := tx.FindRecent(ctx, name, dob, s.dedupeWindow)
+				if err == nil {
+					user, deduplicated = recent, true
+					return nil
+				}
+				if !errors.Is(err, repository.ErrNotFound) {
+					return err
+				}
+			}
+			room, count, err := s.quotaRoom(ctx, tx)
+			if err != nil {
+				return err
+			}
+			if room == 0 {
+				return &QuotaError{Limit: s.maxUsers, Count: count}
+			}
+			user, err = tx.Create(ctx, name, dob)
+			if err == nil && req.Timezone != "" {
+				user, err = tx.SetTimezone(ctx, user.ID, req.Timezone)
+			}
+			if err == nil && req.Locale != "" {
+				user, err = tx.SetLocale(ctx, user.ID, req.Locale)
+			}
+			return err
+		})
+	}
 	if err != nil {
 		return nil, err
 	}
+	resp := newUserResponse(user, s.responseOptions(ctx))
+	if deduplicated {
+		resp.Deduplicated = true
+		return resp, nil
+	}
+	s.metrics.Created.Inc()
 
-	return &models.UserResponse{
-		ID:   user.ID,
-		Name: user.Name,
-		DOB:  user.DOB.Format("2006-01-02"),
-	}, nil
+	return resp, nil
 }
 
 func (s *userService) GetUser(ctx context.Context, id int32) (*models.UserResponse, error) {
+	opts := s.responseOptions(ctx)
+	if opts.accessCount && s.access == nil {
+		return nil, ErrAccessTrackingDisabled
+	}
 	user, err := s.repo.GetById(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, notFound(err)
+	}
+	if s.access != nil {
+		s.access.Record(ctx, id)
 	}
 
-	if user == nil {
-		return nil, ErrUserNotFound
+	resp := newUserResponse(user, opts)
+	if opts.accessCount {
+		count, err := s.access.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		resp.AccessCount = count
+		resp.LastModified = latest(resp.LastModified, count.LastAccessedAt)
+	}
+	return resp, nil
+}
+
+func (s *userService) GetUserByName(ctx context.Context, name string) (*models.UserResponse, error) {
+	if !s.uniqueNames {
+		return nil, ErrNameLookupDisabled
+	}
+	if s.normalizeNames {
+		name = NormalizeName(name)
+	}
+	user, err := s.repo.GetByName(ctx, name)
+	if err != nil {
+		return nil, notFound(err)
 	}
 
-	age := CalculateAge(user.DOB)
-	return &models.UserResponse{
-		ID:   user.ID,
-		Name: user.Name,
-		DOB:  user.DOB.Format("2006-01-02"),
-		Age:  &age,
+	return newUserResponse(user, s.responseOptions(ctx)), nil
+}
+
+func (s *userService) BirthdaysToday(ctx context.Context) (*models.BirthdaysResponse, error) {
+	opts := s.collectionOptions(ctx)
+	users, err := s.repo.ListBirthdays(ctx, opts.now, s.location.String())
+	if err != nil {
+		return nil, err
+	}
+
+	responses := toUserResponses(users, opts)
+	return &models.BirthdaysResponse{
+		AsOf:  opts.now.UTC(),
+		Count: len(responses),
+		Users: responses,
 	}, nil
 }
 
-func (s *userService) ListUsers(ctx context.Context, params *models.PaginationParams) (*models.UserListResponse, error) {
+func (s *userService) BirthdayTwins(ctx context.Context, id int32, params *models.UserListQuery) (*models.UserListResponse, error) {
 	params.SetDefaults()
+	user, err := s.repo.GetById(ctx, id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if user.AnonymizedAt != nil {
+		return nil, repository.ErrAnonymized
+	}
 
-	users, err := s.repo.List(ctx, params.GetLimit(), params.GetOffset())
+	opts := s.collectionOptions(ctx)
+	users, total, err := s.repo.ListByBirthday(ctx, repository.BirthdayQuery{
+		Days:     birthdayTwinDays(user.DOB, opts.now.In(s.location).Year()),
+		ExceptID: id,
+		Limit:    params.GetLimit(),
+		Offset:   params.GetOffset(),
+	})
 	if err != nil {
 		return nil, err
 	}
+	totalPages := params.TotalPages(total)
+	return &models.UserListResponse{
+		Users:      toUserResponses(users, opts),
+		Total:      &total,
+		Page:       params.Page,
+		PageSize:   params.PageSize,
+		TotalPages: &totalPages,
+	}, nil
+}
 
-	total, err := s.repo.Count(ctx)
+func (s *userService) Leaplings(ctx context.Context, params *models.UserListQuery) (*models.LeaplingListResponse, error) {
+	params.SetDefaults()
+	opts := s.collectionOptions(ctx)
+	users, total, err := s.repo.ListByBirthday(ctx, repository.BirthdayQuery{
+		Days:   []repository.MonthDay{{Month: time.February, Day: 29}},
+		Limit:  params.GetLimit(),
+		Offset: params.GetOffset(),
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	userResponses := make([]models.UserResponse, 0, len(users))
-	for _, user := range users {
-		age := CalculateAge(user.DOB)
-		userResponses = append(userResponses, models.UserResponse{
-			ID:   user.ID,
-			Name: user.Name,
-			DOB:  user.DOB.Format("2006-01-02"),
-			Age:  &age,
-		})
+	responses := toUserResponses(users, opts)
+	leaplings := make([]models.Leapling, len(users))
+	for i := range users {
+		today := toDate(opts.now.In(UserLocation(&users[i], opts.location)))
+		leaplings[i] = models.Leapling{
+			UserResponse:     responses[i],
+			LeapBirthdays:    LeapBirthdays(users[i].DOB, today),
+			NextLeapBirthday: NextLeapDay(users[i].DOB, today).Format(dateLayout),
+		}
 	}
-
-	totalPages := int(math.Ceil(float64(total) / float64(params.PageSize)))
-
-	return &models.UserListResponse{
-		Users:      userResponses,
+	return &models.LeaplingListResponse{
+		Users:      leaplings,
 		Total:      total,
 		Page:       params.Page,
 		PageSize:   params.PageSize,
-		TotalPages: totalPages,
+		TotalPages: params.TotalPages(total),
 	}, nil
 }
 
-func (s *userService) UpdateUser(ctx context.Context, id int32, req *models.UpdateUserRequest) (*models.UserResponse, error) {
-	dob, err := time.Parse("2006-01-02", req.DOB)
+func (s *userService) BirthdayCollisions(ctx context.Context, limit int) (*models.BirthdayCollisionsResponse, error) {
+	return s.collisions.get(ctx, strconv.Itoa(limit), func(ctx context.Context) (*models.BirthdayCollisionsResponse, error) {
+		return s.birthdayCollisions(ctx, limit)
+	})
+}
+
+func (s *userService) birthdayCollisions(ctx context.Context, limit int) (*models.BirthdayCollisionsResponse, error) {
+	now := s.clock.Now()
+	found, err := s.repo.BirthdayCollisions(ctx, int32(limit))
 	if err != nil {
-		s.logger.Error("Invalid DOB format", zap.Error(err))
-		return nil, ErrInvalidDate
+		return nil, err
 	}
+	collisions := make([]models.BirthdayCollision, len(found))
+	for i, c := range found {
+		collisions[i] = models.BirthdayCollision{
+			Month: int(c.Month),
+			Day:   c.Day,
+			Date:  fmt.Sprintf("%02d-%02d", int(c.Month), c.Day),
+			Count: c.Count,
+		}
+	}
+	return &models.BirthdayCollisionsResponse{AsOf: now.UTC(), Collisions: collisions}, nil
+}
 
-	user, err := s.repo.Update(ctx, id, req.Name, dob)
+func (s *userService) UsersByAge(ctx context.Context, oldest bool, count int) ([]models.UserResponse, error) {
+	users, err := s.repo.ListByDOB(ctx, !oldest, int32(count))
 	if err != nil {
 		return nil, err
 	}
-	if user == nil {
-		return nil, ErrUserNotFound
+	if len(users) == 0 {
+		return nil, ErrNoUsers
 	}
+	return toUserResponses(users, s.collectionOptions(ctx)), nil
+}
 
-	return &models.UserResponse{
-		ID:   user.ID,
-		Name: user.Name,
-		DOB:  user.DOB.Format("2006-01-02"),
-	}, nil
+// AgePercentile compares DOBs rather than ages, so it never loads the rows
+// and users on other calendars rank by the same date.
+func (s *userService) AgePercentile(ctx context.Context, id int32, params *models.UserListQuery) (*models.AgePercentileResponse, error) {
+	filter, err := s.listFilter(params)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.repo.GetById(ctx, id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	counts, err := s.repo.CountByDOB(ctx, user.DOB, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.AgePercentileResponse{
+		ID:      user.ID,
+		Younger: counts.After,
+		Older:   counts.Before,
+		SameDOB: counts.Same,
+		Total:   counts.Before + counts.Same + counts.After,
+	}
+	if result.Total > 0 {
+		percentile := math.Round(float64(2*counts.After+counts.Same)/float64(2*result.Total)*1000) / 10
+		result.Percentile = &percentile
+	}
+	return result, nil
 }
 
-func (s *userService) DeleteUser(ctx context.Context, id int32) error {
-	err := s.repo.Delete(ctx, id)
+// ListUsers runs the page and count queries concurrently. They are separate
+// statements, so a write landing between them can skew the total; it is
+// reconciled against the page where the rows pin it down exactly.
+func (s *userService) ListUsers(ctx context.Context, params *models.UserListQuery) (*models.UserListResponse, error) {
+	params.SetDefaults()
+
+	filter, err := s.listFilter(params)
+	if err != nil {
+		return nil, err
+	}
+	if params.NameFuzzy != "" {
+		return s.searchUsers(ctx, params, filter)
+	}
+	query := repository.ListQuery{
+		Filter: filter,
+		Limit:  params.GetLimit(),
+		Offset: params.GetOffset(),
+		Sort:   params.SortOrder(),
+	}
+	if params.Cursor != "" {
+		after, err := decodeCursor(params.Cursor, query.Sort)
+		if err != nil {
+			return nil, err
+		}
+		query.After = after
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	var users []models.User
+	g.Go(func() error {
+		var err error
+		users, err = s.repo.List(gctx, query)
+		return err
+	})
+	var total int64
+	if params.WantsTotal() {
+		g.Go(func() error {
+			var err error
+			total, err = s.repo.Count(gctx, query.Filter)
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	list := &models.UserListResponse{
+		Users:    toUserResponses(users, s.listResponseOptions(ctx, params)),
+		Page:     params.Page,
+		PageSize: params.PageSize,
+	}
+	if params.WantsTotal() {
+		if query.After == nil {
+			total = reconcileTotal(total, query, len(users))
+		}
+		totalPages := params.TotalPages(total)
+		list.Total, list.TotalPages = &total, &totalPages
+	}
+	if len(users) == int(query.Limit) {
+		list.NextCursor = encodeCursor(users[len(users)-1], query.Sort)
+	}
+	return list, nil
+}
+
+// searchUsers answers a name_fuzzy list. Its order is the similarity ranking,
+// so there is no cursor, and the total comes from the same transaction as the
+// page.
+func (s *userService) searchUsers(ctx context.Context, params *models.UserListQuery, filter repository.UserFilter) (*models.UserListResponse, error) {
+	users, total, err := s.repo.SearchNames(ctx, repository.NameSearch{
+		Query:     params.NameFuzzy,
+		Threshold: s.fuzzyThreshold,
+		Filter:    filter,
+		Limit:     params.GetLimit(),
+		Offset:    params.GetOffset(),
+		WithTotal: params.WantsTotal(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	list := &models.UserListResponse{
+		Users:    toUserResponses(users, s.listResponseOptions(ctx, params)),
+		Page:     params.Page,
+		PageSize: params.PageSize,
+	}
+	if params.WantsTotal() {
+		totalPages := params.TotalPages(total)
+		list.Total, list.TotalPages = &total, &totalPages
+	}
+	return list, nil
+}
+
+// listFilter folds the age bounds into the DOB range, which is what the
+// repository filters on. They are worked out from CalculateAge as of now, so a
+// listed user's age always lies within them.
+func (s *userService) listFilter(params *models.UserListQuery) (repository.UserFilter, error) {
+	filter := repository.UserFilter{Name: params.Name}
+	if s.normalizeNames {
+		filter.Name = NormalizeName(filter.Name)
+	}
+	if params.DOBFrom != "" {
+		from, err := ParseDOB(params.DOBFrom)
+		if err != nil {
+			return filter, err
+		}
+		filter.DOBFrom = from
+	}
+	if params.DOBTo != "" {
+		to, err := ParseDOB(params.DOBTo)
+		if err != nil {
+			return filter, err
+		}
+		filter.DOBTo = to
+	}
+
+	now := s.clock.Now()
+	if params.MaxAge != nil {
+		from := latestDOBForAge(now, *params.MaxAge+1).AddDate(0, 0, 1)
+		if from.After(filter.DOBFrom) {
+			filter.DOBFrom = from
+		}
+	}
+	// min_age=0 also matches DOBs in the future, whose age is reported as 0.
+	if params.MinAge != nil && *params.MinAge > 0 {
+		to := latestDOBForAge(now, *params.MinAge)
+		if filter.DOBTo.IsZero() || to.Before(filter.DOBTo) {
+			filter.DOBTo = to
+		}
+	}
+	return filter, nil
+}
+
+// reconcileTotal corrects a count taken outside the page's snapshot: a short
+// page ends the result set, and a page can never reach past the total. An
+// empty page says nothing, so the count stands.
+func reconcileTotal(total int64, query repository.ListQuery, n int) int64 {
+	if n == 0 {
+		return total
+	}
+	seen := int64(query.Offset) + int64(n)
+	if n < int(query.Limit) {
+		return seen
+	}
+	return max(total, seen)
+}
+
+func (s *userService) UpdateUser(ctx context.Context, id int32, req *models.UpdateUserRequest) (*models.UserResponse, error) {
+	name, dob, err := s.updateFields(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var user *models.User
+	err = s.repo.Transact(ctx, func(tx repository.UserRepository) error {
+		user, err = replaceUser(ctx, tx, id, name, dob, req)
+		return err
+	})
 	if err != nil {
-		if err.Error() == "sql: no rows in result set" {
-			return ErrUserNotFound
+		return nil, notFound(err)
+	}
+	s.metrics.Updated.Inc()
+
+	return newUserResponse(user, s.responseOptions(ctx)), nil
+}
+
+func (s *userService) PatchUser(ctx context.Context, id int32, patch func(req *models.UpdateUserRequest) error) (*models.UserResponse, error) {
+	var user *models.User
+	err := s.repo.Transact(ctx, func(tx repository.UserRepository) error {
+		// Changing nothing locks the row and returns it as it stands, so a
+		// concurrent patch waits for this one to commit instead of starting
+		// from the same version.
+		current, err := tx.UpdatePartial(ctx, id, nil, nil)
+		if err != nil {
+			return err
 		}
+		req := models.UpdateUserRequest{Name: current.Name, DOB: current.DOB.Format(dateLayout), Timezone: current.Timezone, Locale: current.Locale}
+		if err := patch(&req); err != nil {
+			return err
+		}
+		name, dob, err := s.updateFields(&req)
+		if err != nil {
+			return err
+		}
+		user, err = replaceUser(ctx, tx, id, name, dob, &req)
 		return err
+	})
+	if err != nil {
+		return nil, notFound(err)
+	}
+	s.metrics.Updated.Inc()
+
+	return newUserResponse(user, s.responseOptions(ctx)), nil
+}
+
+// updateFields parses req's DOB and normalizes its name.
+func (s *userService) updateFields(req *models.UpdateUserRequest) (string, time.Time, error) {
+	dob, err := s.parseDOB(req.DOB)
+	if err != nil {
+		s.logger.Error("Invalid DOB format", zap.Error(err))
+		return "", time.Time{}, err
+	}
+
+	name, err := s.normalizeName(req.Name)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return name, dob, nil
+}
+
+// replaceUser writes every field of req through tx, with name and dob as
+// updateFields made them.
+func replaceUser(ctx context.Context, tx repository.UserRepository, id int32, name string, dob time.Time, req *models.UpdateUserRequest) (*models.User, error) {
+	user, err := tx.Update(ctx, id, name, dob)
+	if err == nil && user.Timezone != req.Timezone {
+		user, err = tx.SetTimezone(ctx, id, req.Timezone)
+	}
+	if err == nil && user.Locale != req.Locale {
+		user, err = tx.SetLocale(ctx, id, req.Locale)
+	}
+	return user, err
+}
+
+// errRollback aborts an atomic batch's transaction after a failed item.
+var errRollback = errors.New("batch rolled back")
+
+// UpdateUsers applies a batch of partial updates in one transaction. Every
+// item is checked before the transaction begins, so row locks are held only
+// while writing. Without atomic, failed items are reported and the rest
+// commit; with it, any failure leaves every item unapplied.
+func (s *userService) UpdateUsers(ctx context.Context, items []models.BatchUpdateItem, atomic bool) (*models.BatchUpdateResponse, error) {
+	type change struct {
+		index int
+		name  *string
+		dob   *time.Time
+	}
+
+	results := make([]models.BatchUpdateResult, len(items))
+	changes := make([]change, 0, len(items))
+	seen := make(map[int32]bool, len(items))
+	for i, item := range items {
+		results[i] = models.BatchUpdateResult{ID: item.ID}
+		invalid := item.Invalid
+		if len(invalid) == 0 && seen[item.ID] {
+			invalid = []models.FieldError{{Field: "id", Rule: "unique", Message: "id appears more than once in the batch"}}
+		}
+		ch := change{index: i, name: item.Name}
+		if len(invalid) == 0 && item.Name != nil {
+			name, err := s.normalizeName(*item.Name)
+			var verr *ValidationError
+			if errors.As(err, &verr) {
+				invalid = verr.Details
+			}
+			ch.name = &name
+		}
+		if len(invalid) == 0 && item.DOB != nil {
+			dob, err := s.parseDOB(*item.DOB)
+			if err != nil {
+				invalid = []models.FieldError{dobError(err, s.minDOBYear)}
+			}
+			ch.dob = &dob
+		}
+		if len(invalid) > 0 {
+			results[i].Status, results[i].Error, results[i].Details = models.BatchInvalid, "Validation failed", invalid
+			continue
+		}
+		seen[item.ID] = true
+		changes = append(changes, ch)
 	}
+
+	response := &models.BatchUpdateResponse{Results: results, Failed: len(items) - len(changes)}
+	notApplied := func() *models.BatchUpdateResponse {
+		for _, ch := range changes {
+			if results[ch.index].Status == "" {
+				results[ch.index].Status = models.BatchNotApplied
+			}
+		}
+		return response
+	}
+	if atomic && response.Failed > 0 {
+		return notApplied(), nil
+	}
+
+	updated := make([]*models.User, len(items))
+	err := s.repo.Transact(ctx, func(tx repository.UserRepository) error {
+		for _, ch := range changes {
+			var user *models.User
+			err := s.guardNames(ctx, tx, func(tx repository.UserRepository) error {
+				var err error
+				user, err = tx.UpdatePartial(ctx, items[ch.index].ID, ch.name, ch.dob)
+				return err
+			})
+			if status, message, ok := batchItemError(err); ok {
+				results[ch.index].Status, results[ch.index].Error = status, message
+				response.Failed++
+				if atomic {
+					return errRollback
+				}
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			updated[ch.index] = user
+		}
+		return nil
+	})
+	if errors.Is(err, errRollback) {
+		return notApplied(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	opts := s.responseOptions(ctx)
+	for i, user := range updated {
+		if user != nil {
+			results[i].Status, results[i].User = models.BatchUpdated, newUserResponse(user, opts)
+			response.Updated++
+		}
+	}
+	s.metrics.Updated.Add(float64(response.Updated))
+	return response, nil
+}
+
+// batchItemError reports the write errors that fail one batch item rather
+// than the whole batch, with the status and message to report it by.
+func batchItemError(err error) (status, message string, ok bool) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return models.BatchNotFound, "User not found", true
+	case errors.Is(err, repository.ErrAnonymized):
+		return models.BatchAnonymized, "User has been anonymized", true
+	case errors.Is(err, repository.ErrDuplicateName):
+		return models.BatchDuplicateName, "A user with this name already exists", true
+	}
+	return "", "", false
+}
+
+// guardNames runs write through tx, in a savepoint of it when names are
+// unique: the index's violation aborts a Postgres transaction, and rolling
+// back to the savepoint lets the rest of tx go on.
+func (s *userService) guardNames(ctx context.Context, tx repository.UserRepository, write func(tx repository.UserRepository) error) error {
+	if !s.uniqueNames {
+		return write(tx)
+	}
+	return tx.Transact(ctx, write)
+}
+
+func (s *userService) DeleteUser(ctx context.Context, id int32) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return notFound(err)
+	}
+	s.metrics.Deleted.Inc()
 	return nil
 }
 
-func CalculateAge(dob time.Time) int {
-	now := time.Now()
-	age := now.Year() - dob.Year()
+// notFound maps the repository's not-found sentinel, however deeply wrapped,
+// onto ErrUserNotFound and passes every other error through.
+func notFound(err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrUserNotFound
+	}
+	return err
+}
 
-	if now.Month() < dob.Month() || (now.Month() == dob.Month() && now.Day() < dob.Day()) {
-		age--
+func (s *userService) FindFutureDOBs(ctx context.Context) (*models.DOBValidationResponse, error) {
+	opts := s.responseOptions(ctx)
+	today := toDate(opts.now)
+	users, err := s.repo.ListWithDOBAfter(ctx, today)
+	if err != nil {
+		return nil, err
 	}
-	return age
+
+	responses := toUserResponses(users, opts)
+	return &models.DOBValidationResponse{
+		AsOf:  today.Format(dateLayout),
+		Count: len(responses),
+		Users: responses,
+	}, nil
 }
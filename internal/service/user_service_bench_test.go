@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"go.uber.org/zap"
+)
+
+func newBenchmarkRepository(b *testing.B, n int) repository.UserRepository {
+	b.Helper()
+	repo := repository.NewMemoryUserRepository(clock.Fixed(pinnedNow))
+	start := time.Date(1950, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		if _, err := repo.Create(context.Background(), "User", start.AddDate(0, 0, i*3)); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return repo
+}
+
+func BenchmarkCalculateAge(b *testing.B) {
+	dob := time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		CalculateAge(dob, pinnedNow)
+	}
+}
+
+func BenchmarkListUsers(b *testing.B) {
+	svc := NewUserService(newBenchmarkRepository(b, 10000), zap.NewNop(), WithClock(clock.Fixed(pinnedNow)))
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		params := models.UserListQuery{Page: 1, PageSize: 10000}
+		if _, err := svc.ListUsers(ctx, &params); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkListUsersIncludeAge measures a 100-row page with and without the
+// per-row age computation.
+func BenchmarkListUsersIncludeAge(b *testing.B) {
+	svc := NewUserService(newBenchmarkRepository(b, 100), zap.NewNop(), WithClock(clock.Fixed(pinnedNow)))
+	ctx := context.Background()
+	includeTotal := false
+
+	for _, includeAge := range []bool{true, false} {
+		b.Run(fmt.Sprintf("include_age=%v", includeAge), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				params := models.UserListQuery{Page: 1, PageSize: 100, IncludeTotal: &includeTotal, IncludeAge: &includeAge}
+				if _, err := svc.ListUsers(ctx, &params); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// slowRepository adds a fixed per-query latency, standing in for a loaded
+// database, and gives up early when the context is cancelled.
+type slowRepository struct {
+	repository.UserRepository
+	delay time.Duration
+}
+
+func (r slowRepository) wait(ctx context.Context) error {
+	select {
+	case <-time.After(r.delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r slowRepository) List(ctx context.Context, query repository.ListQuery) ([]models.User, error) {
+	if err := r.wait(ctx); err != nil {
+		return nil, err
+	}
+	return r.UserRepository.List(ctx, query)
+}
+
+func (r slowRepository) Count(ctx context.Context, filter repository.UserFilter) (int64, error) {
+	if err := r.wait(ctx); err != nil {
+		return 0, err
+	}
+	return r.UserRepository.Count(ctx, filter)
+}
+
+// BenchmarkListUsersSlowQueries measures wall time per request with 50ms per
+// query; List and Count overlap, so a request with the total costs about one
+// query rather than two.
+func BenchmarkListUsersSlowQueries(b *testing.B) {
+	repo := slowRepository{UserRepository: newBenchmarkRepository(b, 100), delay: 50 * time.Millisecond}
+	svc := NewUserService(repo, zap.NewNop(), WithClock(clock.Fixed(pinnedNow)))
+	ctx := context.Background()
+
+	for _, includeTotal := range []bool{true, false} {
+		b.Run(fmt.Sprintf("include_total=%v", includeTotal), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				params := models.UserListQuery{IncludeTotal: &includeTotal}
+				if _, err := svc.ListUsers(ctx, &params); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkGetUserAccessTracking measures GetUser with and without its read
+// being counted; the tracker is started, as in the server, so the buffer is
+// drained as the reads come.
+func BenchmarkGetUserAccessTracking(b *testing.B) {
+	repo := newBenchmarkRepository(b, 1)
+	ctx := context.Background()
+
+	for _, tracking := range []bool{false, true} {
+		b.Run(fmt.Sprintf("tracking=%v", tracking), func(b *testing.B) {
+			opts := []Option{WithClock(clock.Fixed(pinnedNow))}
+			if tracking {
+				tracker := NewAccessTracker(repository.NewMemoryAccessRepository(), zap.NewNop())
+				if err := tracker.Start(ctx); err != nil {
+					b.Fatal(err)
+				}
+				b.Cleanup(func() { tracker.Stop(ctx) })
+				opts = append(opts, WithAccessTracker(tracker))
+			}
+			svc := NewUserService(repo, zap.NewNop(), opts...)
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := svc.GetUser(ctx, 1); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
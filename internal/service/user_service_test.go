@@ -1,10 +1,31 @@
 package service
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/metrics"
+	"github.com/srinivasarynh/age_calculator/internal/mocks"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/testutil"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
 )
 
+var pinnedNow = time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+
 func TestCalculateAge(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -12,9 +33,9 @@ func TestCalculateAge(t *testing.T) {
 		expected int
 	}{
 		{
-			name:     "Age 34 - birthday passes",
+			name:     "Age 35 - birthday passed",
 			dob:      time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC),
-			expected: 34,
+			expected: 35,
 		},
 		{
 			name:     "Age 33 - birthday not yet",
@@ -31,37 +52,1189 @@ func TestCalculateAge(t *testing.T) {
 			dob:      time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
 			expected: 25,
 		},
+		{
+			name:     "Leap day birthday in a common year",
+			dob:      time.Date(2000, 2, 29, 0, 0, 0, 0, time.UTC),
+			expected: 25,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			age := CalculateAge(tt.dob)
+			age := CalculateAge(tt.dob, pinnedNow)
 
-			if age < tt.expected-1 || age > tt.expected+1 {
-				t.Errorf("CalculateAge(%v) = %d, want approximately %d", tt.dob, age, tt.expected)
+			if age != tt.expected {
+				t.Errorf("CalculateAge(%v) = %d, want %d", tt.dob, age, tt.expected)
 			}
 		})
 	}
 }
 
 func TestCalculateAgeEdgeCases(t *testing.T) {
-	now := time.Now()
+	now := pinnedNow
 
 	dob := now.AddDate(-30, 0, 0)
-	age := CalculateAge(dob)
+	age := CalculateAge(dob, now)
 	if age != 30 {
 		t.Errorf("Birthday today: expected 30, got %d", age)
 	}
 
 	dob = now.AddDate(-30, 0, 1)
-	age = CalculateAge(dob)
+	age = CalculateAge(dob, now)
 	if age != 29 {
 		t.Errorf("Birthday tomorrow: expected 29, got %d", age)
 	}
 
 	dob = now.AddDate(-30, 0, -1)
-	age = CalculateAge(dob)
+	age = CalculateAge(dob, now)
 	if age != 30 {
 		t.Errorf("Birthday yesterday: expected 30, got %d", age)
 	}
+
+	dob = now.AddDate(1, 0, 0)
+	age = CalculateAge(dob, now)
+	if age != 0 {
+		t.Errorf("DOB next year: expected 0, got %d", age)
+	}
+
+	leapling := time.Date(2000, 2, 29, 0, 0, 0, 0, time.UTC)
+	if age := CalculateAge(leapling, time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC)); age != 24 {
+		t.Errorf("Leapling on Feb 28: expected 24, got %d", age)
+	}
+	if age := CalculateAge(leapling, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)); age != 25 {
+		t.Errorf("Leapling on Mar 1: expected 25, got %d", age)
+	}
+}
+
+func TestServiceUsesInjectedClock(t *testing.T) {
+	alice := testutil.NewUserBuilder().WithID(1).WithName("Alice").WithDOB("1990-06-15").Build()
+	bob := testutil.NewUserBuilder().WithID(2).WithName("Bob").WithDOB("1990-06-16").Build()
+
+	repo := mocks.NewUserRepository(t)
+	repo.EXPECT().GetById(mock.Anything, int32(1)).Return(&alice, nil)
+	repo.EXPECT().List(mock.Anything, listQuery(10, 0)).Return([]models.User{alice, bob}, nil)
+	repo.EXPECT().Count(mock.Anything, repository.UserFilter{}).Return(2, nil)
+	svc := NewUserService(repo, zap.NewNop(), WithClock(clock.Fixed(pinnedNow)))
+
+	user, err := svc.GetUser(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *user.Age != 35 {
+		t.Errorf("GetUser age = %d, want 35", *user.Age)
+	}
+
+	list, err := svc.ListUsers(context.Background(), &models.UserListQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *list.Users[0].Age != 35 || *list.Users[1].Age != 34 {
+		t.Errorf("ListUsers ages = %d, %d, want 35, 34", *list.Users[0].Age, *list.Users[1].Age)
+	}
+}
+
+func listQuery(limit, offset int32) repository.ListQuery {
+	return repository.ListQuery{Limit: limit, Offset: offset, Sort: models.SortOrder{Field: "id"}}
+}
+
+func newMockedService(t *testing.T) (UserService, *mocks.UserRepository) {
+	t.Helper()
+	repo := mocks.NewUserRepository(t)
+	return NewUserService(repo, zap.NewNop(), WithClock(clock.Fixed(pinnedNow))), repo
+}
+
+func TestListUsersCountFailure(t *testing.T) {
+	svc, repo := newMockedService(t)
+	countErr := errors.New("count timed out")
+	repo.EXPECT().List(mock.Anything, listQuery(10, 0)).Return([]models.User{testutil.NewUserBuilder().Build()}, nil).Maybe()
+	repo.EXPECT().Count(mock.Anything, repository.UserFilter{}).Return(0, countErr)
+
+	list, err := svc.ListUsers(context.Background(), &models.UserListQuery{})
+	if !errors.Is(err, countErr) || list != nil {
+		t.Errorf("ListUsers = %v, %v, want nil, %v", list, err, countErr)
+	}
+}
+
+func TestListUsersWithoutTotalSkipsCount(t *testing.T) {
+	svc, repo := newMockedService(t)
+	repo.EXPECT().List(mock.Anything, listQuery(10, 0)).Return([]models.User{testutil.NewUserBuilder().Build()}, nil)
+
+	includeTotal := false
+	list, err := svc.ListUsers(context.Background(), &models.UserListQuery{IncludeTotal: &includeTotal})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != nil || list.TotalPages != nil || len(list.Users) != 1 {
+		t.Errorf("list = %+v, want one user and no totals", list)
+	}
+	repo.AssertNotCalled(t, "Count", mock.Anything, mock.Anything)
+}
+
+func TestListUsersQueriesShareFilter(t *testing.T) {
+	svc, repo := newMockedService(t)
+	var listed repository.ListQuery
+	var counted repository.UserFilter
+	repo.EXPECT().List(mock.Anything, mock.Anything).
+		Run(func(ctx context.Context, query repository.ListQuery) { listed = query }).
+		Return([]models.User{}, nil)
+	repo.EXPECT().Count(mock.Anything, mock.Anything).
+		Run(func(ctx context.Context, filter repository.UserFilter) { counted = filter }).
+		Return(0, nil)
+
+	if _, err := svc.ListUsers(context.Background(), &models.UserListQuery{Name: "ali"}); err != nil {
+		t.Fatal(err)
+	}
+	if listed.Filter != (repository.UserFilter{Name: "ali"}) || counted != listed.Filter {
+		t.Errorf("List filter = %+v, Count filter = %+v, want both {Name: ali}", listed.Filter, counted)
+	}
+}
+
+// TestListUsersAgeFiltersMatchCalculatedAge checks, day by day around the
+// boundaries, that the DOB range built from min_age and max_age holds exactly
+// the DOBs whose CalculateAge lies within them, Feb 29 included.
+func TestListUsersAgeFiltersMatchCalculatedAge(t *testing.T) {
+	age := func(n int) *int { return &n }
+	nows := []time.Time{
+		pinnedNow,
+		time.Date(2024, 2, 28, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, 2, 29, 9, 0, 0, 0, time.UTC),
+		time.Date(2025, 2, 28, 9, 0, 0, 0, time.UTC),
+		time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC),
+	}
+	bounds := []struct{ min, max *int }{
+		{min: age(1)},
+		{max: age(0)},
+		{min: age(0), max: age(0)},
+		{min: age(3), max: age(4)},
+		{min: age(4)},
+	}
+	for _, now := range nows {
+		svc := NewUserService(nil, zap.NewNop(), WithClock(clock.Fixed(now))).(*userService)
+		for _, b := range bounds {
+			filter, err := svc.listFilter(&models.UserListQuery{MinAge: b.min, MaxAge: b.max})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for dob := now.AddDate(-7, 0, 0); dob.Before(now.AddDate(1, 0, 0)); dob = dob.AddDate(0, 0, 1) {
+				dob := toDate(dob)
+				a := CalculateAge(dob, now)
+				want := (b.min == nil || a >= *b.min) && (b.max == nil || a <= *b.max)
+				got := (filter.DOBFrom.IsZero() || !dob.Before(filter.DOBFrom)) && (filter.DOBTo.IsZero() || !dob.After(filter.DOBTo))
+				if got != want {
+					t.Fatalf("now %s, bounds %v-%v: DOB %s (age %d) matched = %v, want %v",
+						now.Format(dateLayout), b.min, b.max, dob.Format(dateLayout), a, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestListUsersCombinesDOBAndAgeBounds(t *testing.T) {
+	svc := NewUserService(nil, zap.NewNop(), WithClock(clock.Fixed(pinnedNow))).(*userService)
+	thirty := 30
+	filter, err := svc.listFilter(&models.UserListQuery{DOBFrom: "1990-01-01", DOBTo: "2000-12-31", MinAge: &thirty})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := filter.DOBFrom.Format(dateLayout) + ".." + filter.DOBTo.Format(dateLayout); got != "1990-01-01..1995-06-15" {
+		t.Errorf("DOB range = %s, want the tighter bound of each side", got)
+	}
+	if _, err := svc.listFilter(&models.UserListQuery{DOBTo: "2000-02-30"}); !errors.Is(err, ErrInvalidDate) {
+		t.Errorf("unvalidated dob_to: err = %v, want ErrInvalidDate", err)
+	}
+}
+
+func TestListUsersNameFuzzy(t *testing.T) {
+	repo := mocks.NewUserRepository(t)
+	svc := NewUserService(repo, zap.NewNop(), WithClock(clock.Fixed(pinnedNow)), WithFuzzyThreshold(0.5))
+	thirty := 30
+	want := repository.NameSearch{
+		Query:     "jonh",
+		Threshold: 0.5,
+		Filter:    repository.UserFilter{DOBTo: time.Date(1995, time.June, 15, 0, 0, 0, 0, time.UTC)},
+		Limit:     10,
+		Offset:    10,
+		WithTotal: true,
+	}
+	jon := testutil.NewUserBuilder().WithID(7).WithName("Jon").Build()
+	repo.EXPECT().SearchNames(mock.Anything, want).Return([]models.User{jon}, 11, nil)
+
+	list, err := svc.ListUsers(context.Background(), &models.UserListQuery{NameFuzzy: "jonh", MinAge: &thirty, Page: 2, PageSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Users) != 1 || list.Users[0].Name != "Jon" || *list.Total != 11 || *list.TotalPages != 2 || list.NextCursor != "" {
+		t.Errorf("list = %+v", list)
+	}
+}
+
+// Count runs outside the page's snapshot, so a write between the two queries
+// can leave it stale; the page itself bounds the true total.
+func TestListUsersReconcilesTotal(t *testing.T) {
+	users := func(n int) []models.User {
+		out := make([]models.User, n)
+		for i := range out {
+			out[i] = testutil.NewUserBuilder().WithID(int32(i + 1)).Build()
+		}
+		return out
+	}
+	tests := []struct {
+		name  string
+		rows  int
+		count int64
+		want  int64
+	}{
+		{name: "short page ends the result set", rows: 3, count: 12, want: 23},
+		{name: "full page cannot exceed the total", rows: 10, count: 15, want: 30},
+		{name: "consistent count is kept", rows: 10, count: 45, want: 45},
+		{name: "empty page keeps the count", rows: 0, count: 4, want: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, repo := newMockedService(t)
+			repo.EXPECT().List(mock.Anything, listQuery(10, 20)).Return(users(tt.rows), nil)
+			repo.EXPECT().Count(mock.Anything, repository.UserFilter{}).Return(tt.count, nil)
+
+			list, err := svc.ListUsers(context.Background(), &models.UserListQuery{Page: 3})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *list.Total != tt.want {
+				t.Errorf("total = %d, want %d", *list.Total, tt.want)
+			}
+		})
+	}
+}
+
+func TestListUsersListFailure(t *testing.T) {
+	svc, repo := newMockedService(t)
+	listErr := errors.New("connection reset")
+	repo.EXPECT().List(mock.Anything, mock.Anything).Return(nil, listErr)
+	repo.EXPECT().Count(mock.Anything, mock.Anything).Return(0, nil).Maybe()
+
+	if _, err := svc.ListUsers(context.Background(), &models.UserListQuery{}); !errors.Is(err, listErr) {
+		t.Errorf("err = %v, want %v", err, listErr)
+	}
+}
+
+func TestListUsersListFailureCancelsCount(t *testing.T) {
+	svc, repo := newMockedService(t)
+	listErr := errors.New("connection reset")
+	countCancelled := make(chan struct{})
+	repo.EXPECT().List(mock.Anything, mock.Anything).Return(nil, listErr)
+	repo.EXPECT().Count(mock.Anything, mock.Anything).
+		RunAndReturn(func(ctx context.Context, filter repository.UserFilter) (int64, error) {
+			<-ctx.Done()
+			close(countCancelled)
+			return 0, ctx.Err()
+		}).Maybe()
+
+	if _, err := svc.ListUsers(context.Background(), &models.UserListQuery{}); !errors.Is(err, listErr) {
+		t.Errorf("err = %v, want %v", err, listErr)
+	}
+	select {
+	case <-countCancelled:
+	case <-time.After(time.Second):
+		t.Error("Count was not cancelled after List failed")
+	}
+}
+
+func TestListUsersTranslatesPagination(t *testing.T) {
+	svc, repo := newMockedService(t)
+	repo.EXPECT().List(mock.Anything, listQuery(20, 40)).Return([]models.User{}, nil)
+	repo.EXPECT().Count(mock.Anything, repository.UserFilter{}).Return(41, nil)
+
+	list, err := svc.ListUsers(context.Background(), &models.UserListQuery{Page: 3, PageSize: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *list.TotalPages != 3 || len(list.Users) != 0 {
+		t.Errorf("list = %+v, want 3 pages and no users", list)
+	}
+}
+
+func TestListUsersPassesSortOrder(t *testing.T) {
+	svc, repo := newMockedService(t)
+	want := repository.ListQuery{Limit: 10, Offset: 0, Sort: models.SortOrder{Field: "created_at", Desc: true}}
+	repo.EXPECT().List(mock.Anything, want).Return([]models.User{}, nil)
+	repo.EXPECT().Count(mock.Anything, repository.UserFilter{}).Return(0, nil)
+
+	if _, err := svc.ListUsers(context.Background(), &models.UserListQuery{Sort: "-created_at"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetUserRepositoryErrorIsNotNotFound(t *testing.T) {
+	svc, repo := newMockedService(t)
+	dbErr := errors.New("too many connections")
+	repo.EXPECT().GetById(mock.Anything, int32(7)).Return(nil, dbErr)
+
+	_, err := svc.GetUser(context.Background(), 7)
+	if !errors.Is(err, dbErr) || errors.Is(err, ErrUserNotFound) {
+		t.Errorf("err = %v, want %v", err, dbErr)
+	}
+}
+
+func TestCreateUserPassesParsedDOB(t *testing.T) {
+	svc, repo := newMockedService(t)
+	want := testutil.NewUserBuilder().WithName("Alice").WithDOB("1990-05-10").Build()
+	repo.EXPECT().Create(mock.Anything, "Alice", want.DOB).Return(&want, nil)
+
+	user, err := svc.CreateUser(context.Background(), testutil.NewUserBuilder().WithName("Alice").WithDOB("1990-05-10").CreateRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.DOB != "1990-05-10" {
+		t.Errorf("DOB = %s, want 1990-05-10", user.DOB)
+	}
+}
+
+func TestCreateUserDedupeWindow(t *testing.T) {
+	clk := &manualClock{now: pinnedNow}
+	repo := repository.NewMemoryUserRepository(clk)
+	m := metrics.NewUsers(prometheus.NewRegistry())
+	svc := NewUserService(repo, zap.NewNop(), WithClock(clk), WithMetrics(m), WithDedupeWindow(5*time.Second))
+	ctx := context.Background()
+	req := &models.CreateUserRequest{Name: "Alice", DOB: "1990-05-10"}
+
+	results := make(chan *models.UserResponse, 2)
+	var start sync.WaitGroup
+	start.Add(1)
+	for range 2 {
+		go func() {
+			start.Wait()
+			user, err := svc.CreateUser(ctx, req)
+			if err != nil {
+				t.Error(err)
+			}
+			results <- user
+		}()
+	}
+	start.Done()
+	first, second := <-results, <-results
+	if first == nil || second == nil {
+		t.FailNow()
+	}
+	if first.ID != second.ID || first.Deduplicated == second.Deduplicated {
+		t.Errorf("concurrent creates = %+v and %+v, want the same user, deduplicated once", first, second)
+	}
+	if n, _ := repo.Count(ctx, repository.UserFilter{}); n != 1 || promtestutil.ToFloat64(m.Created) != 1 {
+		t.Errorf("users = %d, created counter = %v; want 1 each", n, promtestutil.ToFloat64(m.Created))
+	}
+
+	if user, err := svc.CreateUser(ctx, &models.CreateUserRequest{Name: "Alice", DOB: "1990-05-11"}); err != nil || user.Deduplicated {
+		t.Errorf("create with another DOB = %+v, %v; want a new user", user, err)
+	}
+	clk.Advance(6 * time.Second)
+	if user, err := svc.CreateUser(ctx, req); err != nil || user.Deduplicated {
+		t.Errorf("create after the window = %+v, %v; want a new user", user, err)
+	}
+}
+
+func TestWritesWithInvalidDOBNeverReachRepository(t *testing.T) {
+	svc, repo := newMockedService(t)
+
+	_, err := svc.CreateUser(context.Background(), &models.CreateUserRequest{Name: "Alice", DOB: "1990-13-01"})
+	if !errors.Is(err, ErrInvalidDate) {
+		t.Errorf("CreateUser err = %v, want ErrInvalidDate", err)
+	}
+	_, err = svc.UpdateUser(context.Background(), 1, &models.UpdateUserRequest{Name: "Alice", DOB: "yesterday"})
+	if !errors.Is(err, ErrInvalidDate) {
+		t.Errorf("UpdateUser err = %v, want ErrInvalidDate", err)
+	}
+	repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+	repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUpdateUserMissingRowIsNotFound(t *testing.T) {
+	svc, repo := newMockedService(t)
+	repo.EXPECT().Transact(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, fn func(repository.UserRepository) error) error {
+		return fn(repo)
+	})
+	repo.EXPECT().Update(mock.Anything, int32(9), "Alice", mock.Anything).Return(nil, repository.ErrNotFound)
+
+	if _, err := svc.UpdateUser(context.Background(), 9, testutil.NewUserBuilder().WithName("Alice").UpdateRequest()); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("err = %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestPatchUser(t *testing.T) {
+	c := &manualClock{now: pinnedNow}
+	users := repository.NewMemoryUserRepository(c)
+	ctx := context.Background()
+	alice, _ := users.Create(ctx, "Alice", time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC))
+	accesses := &recordingAccesses{AccessRepository: repository.NewMemoryAccessRepository()}
+	tracker := NewAccessTracker(accesses, zap.NewNop(), WithAccessClock(c))
+	svc := NewUserService(users, zap.NewNop(), WithClock(c), WithAccessTracker(tracker))
+
+	got, err := svc.PatchUser(ctx, alice.ID, func(req *models.UpdateUserRequest) error {
+		if req.Name != "Alice" || req.DOB != "1990-05-10" {
+			t.Errorf("patch started from %+v, want the stored user", req)
+		}
+		req.Name = "Alicia"
+		return nil
+	})
+	if err != nil || got.Name != "Alicia" || got.DOB != "1990-05-10" {
+		t.Fatalf("PatchUser = %+v, %v; want the name changed and the DOB kept", got, err)
+	}
+	if written, err := tracker.Flush(ctx); err != nil || written != 0 {
+		t.Errorf("Flush = %d, %v; a patch must not count as an access", written, err)
+	}
+
+	rejected := errors.New("rejected")
+	if _, err := svc.PatchUser(ctx, alice.ID, func(req *models.UpdateUserRequest) error {
+		req.Name = "Alison"
+		return rejected
+	}); !errors.Is(err, rejected) {
+		t.Errorf("PatchUser err = %v, want the patch's error", err)
+	}
+	if stored, _ := users.GetById(ctx, alice.ID); stored.Name != "Alicia" {
+		t.Errorf("name = %q after a rejected patch, want Alicia", stored.Name)
+	}
+
+	if _, err := svc.PatchUser(ctx, 999, func(*models.UpdateUserRequest) error { return nil }); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("PatchUser of a missing user: err = %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestPatchUserConcurrentPatchesKeepBothChanges(t *testing.T) {
+	users := repository.NewMemoryUserRepository(clock.Real())
+	ctx := context.Background()
+	alice, _ := users.Create(ctx, "Alice", time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC))
+	svc := NewUserService(users, zap.NewNop())
+
+	patches := []func(req *models.UpdateUserRequest){
+		func(req *models.UpdateUserRequest) { req.Name = "Alicia" },
+		func(req *models.UpdateUserRequest) { req.DOB = "1991-06-11" },
+	}
+	var wg sync.WaitGroup
+	for _, apply := range patches {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := svc.PatchUser(ctx, alice.ID, func(req *models.UpdateUserRequest) error {
+				apply(req)
+				return nil
+			}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	stored, _ := users.GetById(ctx, alice.ID)
+	if stored.Name != "Alicia" || stored.DOB.Format("2006-01-02") != "1991-06-11" {
+		t.Errorf("stored %s born %s; want both patches applied", stored.Name, stored.DOB.Format("2006-01-02"))
+	}
+}
+
+func TestDeleteUserErrors(t *testing.T) {
+	dbErr := errors.New("deadlock detected")
+	tests := []struct {
+		name     string
+		repoErr  error
+		expected error
+	}{
+		{name: "missing row", repoErr: repository.ErrNotFound, expected: ErrUserNotFound},
+		{name: "wrapped missing row", repoErr: fmt.Errorf("delete user 3: %w", repository.ErrNotFound), expected: ErrUserNotFound},
+		{name: "raw sql.ErrNoRows is not translated", repoErr: sql.ErrNoRows, expected: sql.ErrNoRows},
+		{name: "database error", repoErr: dbErr, expected: dbErr},
+		{name: "deleted", repoErr: nil, expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, repo := newMockedService(t)
+			repo.EXPECT().Delete(mock.Anything, int32(3)).Return(tt.repoErr)
+
+			if err := svc.DeleteUser(context.Background(), 3); !errors.Is(err, tt.expected) {
+				t.Errorf("err = %v, want %v", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFutureDOBBoundaries(t *testing.T) {
+	tests := []struct {
+		name    string
+		dob     string
+		invalid bool
+	}{
+		{name: "day before", dob: "2025-06-14"},
+		{name: "day of", dob: "2025-06-15"},
+		{name: "day after", dob: "2025-06-16", invalid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewMemoryUserRepository(clock.Fixed(pinnedNow))
+			testutil.MustInsert(t, repo, testutil.NewUserBuilder().WithDOB(tt.dob).Build())
+			svc := NewUserService(repo, zap.NewNop(), WithClock(clock.Fixed(pinnedNow)))
+
+			user, err := svc.GetUser(context.Background(), 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			list, err := svc.ListUsers(context.Background(), &models.UserListQuery{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, got := range []models.UserResponse{*user, list.Users[0]} {
+				if *got.Age != 0 {
+					t.Errorf("age = %d, want 0", *got.Age)
+				}
+				if tt.invalid && (got.AgeValid == nil || *got.AgeValid) {
+					t.Errorf("age_valid = %v, want false", got.AgeValid)
+				}
+				if !tt.invalid && got.AgeValid != nil {
+					t.Errorf("age_valid = %v, want unset", *got.AgeValid)
+				}
+			}
+		})
+	}
+}
+
+func TestInfantAgeInMonths(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(clock.Fixed(pinnedNow))
+	testutil.MustInsert(t, repo,
+		testutil.NewUserBuilder().WithName("Six Weeks").WithDOB("2025-05-04").Build(),
+		testutil.NewUserBuilder().WithName("Eighteen Months").WithDOB("2023-12-15").Build(),
+		testutil.NewUserBuilder().WithName("Turns Two").WithDOB("2023-06-15").Build(),
+		testutil.NewUserBuilder().WithName("Day Before Two").WithDOB("2023-06-16").Build(),
+	)
+	ctx := context.Background()
+
+	tests := []struct {
+		id            int32
+		months, weeks int
+		infant        bool
+	}{
+		{id: 1, months: 1, weeks: 6, infant: true},
+		{id: 2, months: 18, weeks: 78, infant: true},
+		{id: 3, months: 24, weeks: 104},
+		{id: 4, months: 23, weeks: 104, infant: true},
+	}
+	svc := NewUserService(repo, zap.NewNop(), WithClock(clock.Fixed(pinnedNow)))
+	list, err := svc.ListUsers(ctx, &models.UserListQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	forced, err := svc.ListUsers(ctx, &models.UserListQuery{IncludeAgeMonths: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tt := range tests {
+		user, err := svc.GetUser(ctx, tt.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, got := range []models.UserResponse{*user, list.Users[i]} {
+			if !tt.infant {
+				if got.AgeMonths != nil || got.AgeWeeks != nil {
+					t.Errorf("%s: age_months = %v, age_weeks = %v, want unset", got.Name, got.AgeMonths, got.AgeWeeks)
+				}
+				continue
+			}
+			if got.AgeMonths == nil || got.AgeWeeks == nil || *got.AgeMonths != tt.months || *got.AgeWeeks != tt.weeks {
+				t.Errorf("%s: age_months = %v, age_weeks = %v, want %d and %d", got.Name, got.AgeMonths, got.AgeWeeks, tt.months, tt.weeks)
+			}
+		}
+
+		user, err = svc.GetUser(WithAgeMonths(ctx), tt.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, got := range []models.UserResponse{*user, forced.Users[i]} {
+			if got.AgeMonths == nil || *got.AgeMonths != tt.months || *got.AgeWeeks != tt.weeks {
+				t.Errorf("%s when asked: age_months = %v, want %d", got.Name, got.AgeMonths, tt.months)
+			}
+		}
+	}
+
+	never := NewUserService(repo, zap.NewNop(), WithClock(clock.Fixed(pinnedNow)), WithInfantAgeYears(0))
+	if user, err := never.GetUser(ctx, 1); err != nil || user.AgeMonths != nil {
+		t.Errorf("with the cutoff at 0: GetUser = %+v, %v, want no age_months", user, err)
+	}
+}
+
+func TestMinDOBYearBoundaries(t *testing.T) {
+	tests := []struct {
+		name    string
+		dob     string
+		minYear int
+		early   bool
+	}{
+		{name: "zero date", dob: "0001-01-01", minYear: 1900, early: true},
+		{name: "last day before", dob: "1899-12-31", minYear: 1900, early: true},
+		{name: "first day of the year", dob: "1900-01-01", minYear: 1900},
+		{name: "zero date allowed", dob: "0001-01-01", minYear: 1},
+		{name: "raised year", dob: "1949-06-30", minYear: 1950, early: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := repository.NewMemoryUserRepository(clock.Fixed(pinnedNow))
+			testutil.MustInsert(t, repo, testutil.NewUserBuilder().WithName("Alice").WithDOB("1990-05-10").Build())
+			svc := NewUserService(repo, zap.NewNop(), WithClock(clock.Fixed(pinnedNow)), WithMinDOBYear(tt.minYear))
+
+			_, createErr := svc.CreateUser(ctx, &models.CreateUserRequest{Name: "Bob", DOB: tt.dob})
+			_, updateErr := svc.UpdateUser(ctx, 1, &models.UpdateUserRequest{Name: "Alice", DOB: tt.dob})
+			for op, err := range map[string]error{"CreateUser": createErr, "UpdateUser": updateErr} {
+				if got := errors.Is(err, ErrDOBTooEarly); got != tt.early || (!tt.early && err != nil) {
+					t.Errorf("%s: err = %v, want too early %v", op, err, tt.early)
+				}
+			}
+
+			batch, err := svc.UpdateUsers(ctx, []models.BatchUpdateItem{{ID: 1, DOB: &tt.dob}}, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result := batch.Results[0]
+			if !tt.early && result.Status != models.BatchUpdated {
+				t.Errorf("UpdateUsers = %+v, want updated", result)
+			}
+			want := []models.FieldError{{Field: "dob", Rule: "min_year", Param: fmt.Sprint(tt.minYear), Message: fmt.Sprintf("dob must be in %d or later", tt.minYear)}}
+			if tt.early && (result.Status != models.BatchInvalid || !reflect.DeepEqual(result.Details, want)) {
+				t.Errorf("UpdateUsers = %+v, want invalid with %v", result, want)
+			}
+		})
+	}
+}
+
+func TestFindFutureDOBs(t *testing.T) {
+	svc, repo := newMockedService(t)
+	today := time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC)
+	future := testutil.NewUserBuilder().WithID(4).WithName("Time Traveller").WithDOB("2030-01-01").Build()
+	repo.EXPECT().ListWithDOBAfter(mock.Anything, today).Return([]models.User{future}, nil)
+
+	report, err := svc.FindFutureDOBs(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.AsOf != "2025-06-15" || report.Count != 1 || report.Users[0].ID != 4 || *report.Users[0].AgeValid {
+		t.Errorf("report = %+v", report)
+	}
+}
+
+func TestGetUserTranslatesWrappedNotFound(t *testing.T) {
+	svc, repo := newMockedService(t)
+	repo.EXPECT().GetById(mock.Anything, int32(7)).Return(nil, fmt.Errorf("scanning user 7: %w", repository.ErrNotFound))
+
+	if _, err := svc.GetUser(context.Background(), 7); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("err = %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestMemoryRepositoryNotFound(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(clock.Fixed(pinnedNow))
+	ctx := context.Background()
+
+	if user, err := repo.GetById(ctx, 1); user != nil || !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetById = %v, %v, want nil, ErrNotFound", user, err)
+	}
+	if user, err := repo.Update(ctx, 1, "Alice", pinnedNow); user != nil || !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Update = %v, %v, want nil, ErrNotFound", user, err)
+	}
+	if err := repo.Delete(ctx, 1); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Delete = %v, want ErrNotFound", err)
+	}
+}
+
+func TestWriteResponsesMatchGetUser(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(clock.Fixed(pinnedNow))
+	svc := NewUserService(repo, zap.NewNop(), WithClock(clock.Fixed(pinnedNow)))
+	ctx := context.Background()
+
+	created, err := svc.CreateUser(ctx, testutil.NewUserBuilder().WithName("Alice").WithDOB("1990-05-10").CreateRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetched, err := svc.GetUser(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(created, fetched) {
+		t.Errorf("CreateUser = %+v, GetUser = %+v", created, fetched)
+	}
+	if created.Age == nil || *created.Age != 35 || !created.CreatedAt.Equal(pinnedNow) {
+		t.Errorf("CreateUser age/timestamps = %v, %v", created.Age, created.CreatedAt)
+	}
+
+	updated, err := svc.UpdateUser(ctx, created.ID, testutil.NewUserBuilder().WithName("Alicia").WithDOB("2030-01-01").UpdateRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fetched, _ = svc.GetUser(ctx, created.ID); !reflect.DeepEqual(updated, fetched) {
+		t.Errorf("UpdateUser = %+v, GetUser = %+v", updated, fetched)
+	}
+	if *updated.Age != 0 || updated.AgeValid == nil || *updated.AgeValid {
+		t.Errorf("UpdateUser with future DOB = age %d, age_valid %v", *updated.Age, updated.AgeValid)
+	}
+}
+
+func TestListUsersNormalizesUnvalidatedPagination(t *testing.T) {
+	tests := []struct {
+		name   string
+		params models.UserListQuery
+		limit  int32
+		offset int32
+	}{
+		{name: "negative page", params: models.UserListQuery{Page: -5, PageSize: 10}, limit: 10, offset: 0},
+		{name: "negative page size", params: models.UserListQuery{Page: 2, PageSize: -1}, limit: 10, offset: 10},
+		{name: "page size above maximum", params: models.UserListQuery{Page: 1, PageSize: 5000}, limit: 100, offset: 0},
+		{name: "offset overflows int32", params: models.UserListQuery{Page: math.MaxInt32, PageSize: 100}, limit: 100, offset: math.MaxInt32},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, repo := newMockedService(t)
+			repo.EXPECT().List(mock.Anything, listQuery(tt.limit, tt.offset)).Return([]models.User{}, nil)
+			repo.EXPECT().Count(mock.Anything, repository.UserFilter{}).Return(3, nil)
+
+			params := tt.params
+			list, err := svc.ListUsers(context.Background(), &params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if list.Users == nil || len(list.Users) != 0 {
+				t.Errorf("users = %#v, want empty non-nil slice", list.Users)
+			}
+		})
+	}
+}
+
+func TestDOBRoundTripsInNonUTCZone(t *testing.T) {
+	for _, name := range []string{"Pacific/Kiritimati", "Pacific/Pago_Pago"} {
+		t.Run(name, func(t *testing.T) {
+			loc, err := time.LoadLocation(name)
+			if err != nil {
+				t.Skipf("time zone data unavailable: %v", err)
+			}
+			saved := time.Local
+			time.Local = loc
+			t.Cleanup(func() { time.Local = saved })
+
+			now := time.Date(2025, 6, 15, 23, 30, 0, 0, loc)
+			repo := repository.NewMemoryUserRepository(clock.Fixed(now))
+			svc := NewUserService(repo, zap.NewNop(), WithClock(clock.Fixed(now)))
+
+			created, err := svc.CreateUser(context.Background(), &models.CreateUserRequest{Name: "Alice", DOB: "1990-05-10"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			fetched, err := svc.GetUser(context.Background(), created.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if created.DOB != "1990-05-10" || fetched.DOB != "1990-05-10" {
+				t.Errorf("DOB = %s / %s, want 1990-05-10", created.DOB, fetched.DOB)
+			}
+		})
+	}
+}
+
+// Age is derived from the clock, so the representation changes on each
+// birthday even when the row does not.
+func TestLastModifiedFollowsBirthdays(t *testing.T) {
+	updated := time.Date(2024, 1, 1, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		dob  string
+		now  time.Time
+		want time.Time
+	}{
+		{name: "birthday after update", dob: "1990-05-10", now: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC), want: time.Date(2025, 5, 10, 0, 0, 0, 0, time.UTC)},
+		{name: "birthday later this year", dob: "1990-12-01", now: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC), want: time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "update after last birthday", dob: "1990-12-01", now: time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC), want: updated},
+		{name: "leapling in a common year", dob: "2000-02-29", now: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC), want: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "future dob", dob: "2030-01-01", now: time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC), want: updated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := testutil.NewUserBuilder().WithDOB(tt.dob).WithTimestamps(updated, updated).Build()
+			repo := mocks.NewUserRepository(t)
+			repo.EXPECT().GetById(mock.Anything, user.ID).Return(&user, nil)
+			svc := NewUserService(repo, zap.NewNop(), WithClock(clock.Fixed(tt.now)))
+
+			resp, err := svc.GetUser(context.Background(), user.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !resp.LastModified.Equal(tt.want) {
+				t.Errorf("LastModified = %v, want %v", resp.LastModified, tt.want)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func newBatchRepository(t *testing.T) repository.UserRepository {
+	t.Helper()
+	repo := repository.NewMemoryUserRepository(clock.Fixed(pinnedNow))
+	testutil.MustInsert(t, repo,
+		testutil.NewUserBuilder().WithName("Alice").WithDOB("1990-05-10").Build(),
+		testutil.NewUserBuilder().WithName("Bob").WithDOB("2000-02-29").Build(),
+		testutil.NewUserBuilder().WithName("Carol").WithDOB("1985-12-01").Build(),
+	)
+	return repo
+}
+
+func batchStatuses(r *models.BatchUpdateResponse) []string {
+	statuses := make([]string, len(r.Results))
+	for i, result := range r.Results {
+		statuses[i] = result.Status
+	}
+	return statuses
+}
+
+func TestUpdateUsersBatch(t *testing.T) {
+	invalid := []models.FieldError{{Field: "name", Rule: "min"}}
+	items := []models.BatchUpdateItem{
+		{ID: 1, Name: ptr("Alicia")},
+		{ID: 99, Name: ptr("Nobody")},
+		{ID: 2, DOB: ptr("2001-03-01")},
+		{ID: 3, Name: ptr("C"), Invalid: invalid},
+		{ID: 1, DOB: ptr("1990-01-01")},
+		{ID: 3, DOB: ptr("2001-02-30")},
+	}
+
+	tests := []struct {
+		name     string
+		atomic   bool
+		statuses []string
+		updated  int
+		failed   int
+		names    []string
+	}{
+		{
+			name:     "partial",
+			statuses: []string{models.BatchUpdated, models.BatchNotFound, models.BatchUpdated, models.BatchInvalid, models.BatchInvalid, models.BatchInvalid},
+			updated:  2,
+			failed:   4,
+			names:    []string{"Alicia", "Bob", "Carol"},
+		},
+		{
+			name:     "atomic",
+			atomic:   true,
+			statuses: []string{models.BatchNotApplied, models.BatchNotApplied, models.BatchNotApplied, models.BatchInvalid, models.BatchInvalid, models.BatchInvalid},
+			failed:   3,
+			names:    []string{"Alice", "Bob", "Carol"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newBatchRepository(t)
+			svc := NewUserService(repo, zap.NewNop(), WithClock(clock.Fixed(pinnedNow)))
+
+			result, err := svc.UpdateUsers(context.Background(), items, tt.atomic)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := batchStatuses(result); !reflect.DeepEqual(got, tt.statuses) {
+				t.Errorf("statuses = %v, want %v", got, tt.statuses)
+			}
+			if result.Updated != tt.updated || result.Failed != tt.failed {
+				t.Errorf("updated %d, failed %d; want %d and %d", result.Updated, result.Failed, tt.updated, tt.failed)
+			}
+			for i, want := range tt.names {
+				user, err := repo.GetById(context.Background(), int32(i+1))
+				if err != nil || user.Name != want {
+					t.Errorf("user %d = %+v, %v; want %s", i+1, user, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateUsersBatchResults(t *testing.T) {
+	repo := newBatchRepository(t)
+	svc := NewUserService(repo, zap.NewNop(), WithClock(clock.Fixed(pinnedNow)))
+
+	result, err := svc.UpdateUsers(context.Background(), []models.BatchUpdateItem{
+		{ID: 2, DOB: ptr("2001-03-01")},
+		{ID: 2, Name: ptr("Robert")},
+		{ID: 7},
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if user := result.Results[0].User; user == nil || user.Name != "Bob" || user.DOB != "2001-03-01" || *user.Age != 24 {
+		t.Errorf("updated user = %+v, want Bob born 2001-03-01, age 24", user)
+	}
+	if dup := result.Results[1]; dup.Status != models.BatchInvalid || len(dup.Details) != 1 || dup.Details[0].Rule != "unique" {
+		t.Errorf("duplicate id result = %+v, want a unique violation", dup)
+	}
+	if missing := result.Results[2]; missing.Status != models.BatchNotFound || missing.Error != "User not found" || missing.User != nil {
+		t.Errorf("missing id result = %+v", missing)
+	}
+}
+
+func TestUpdateUsersReportsAnonymizedItems(t *testing.T) {
+	repo := newBatchRepository(t)
+	svc := NewUserService(repo, zap.NewNop(), WithClock(clock.Fixed(pinnedNow)))
+	ctx := context.Background()
+	if _, err := svc.AnonymizeUser(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := svc.UpdateUsers(ctx, []models.BatchUpdateItem{
+		{ID: 1, Name: ptr("Alicia")},
+		{ID: 2, Name: ptr("Robert")},
+		{ID: 3, DOB: ptr("1986-01-02")},
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{models.BatchUpdated, models.BatchAnonymized, models.BatchUpdated}
+	if got := batchStatuses(result); !reflect.DeepEqual(got, want) || result.Updated != 2 || result.Failed != 1 {
+		t.Errorf("result = %v (updated %d, failed %d), want %v", got, result.Updated, result.Failed, want)
+	}
+	if anonymized := result.Results[1]; anonymized.Error != "User has been anonymized" || anonymized.User != nil {
+		t.Errorf("anonymized result = %+v", anonymized)
+	}
+	if user, err := repo.GetById(ctx, 1); err != nil || user.Name != "Alicia" {
+		t.Errorf("user 1 = %+v, %v; want it renamed", user, err)
+	}
+	if user, err := repo.GetById(ctx, 3); err != nil || user.DOB.Format(dateLayout) != "1986-01-02" {
+		t.Errorf("user 3 = %+v, %v; want the new DOB", user, err)
+	}
+}
+
+// failingAnonymize fails every Anonymize made inside a transaction.
+type failingAnonymize struct {
+	repository.UserRepository
+}
+
+func (r failingAnonymize) Transact(ctx context.Context, fn func(tx repository.UserRepository) error) error {
+	return r.UserRepository.Transact(ctx, func(tx repository.UserRepository) error {
+		return fn(failingAnonymize{tx})
+	})
+}
+
+func (failingAnonymize) Anonymize(ctx context.Context, id int32, name string, dob time.Time) (*models.User, error) {
+	return nil, errors.New("deadlock detected")
+}
+
+func TestAnonymizeUserForgetsNotificationsOnlyOnCommit(t *testing.T) {
+	repo := newBatchRepository(t)
+	markers := repository.NewMemoryNotificationRepository()
+	ctx := context.Background()
+	if _, err := markers.MarkNotified(ctx, 2, 2024, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+
+	failing := NewUserService(failingAnonymize{repo}, zap.NewNop(), WithNotificationRepository(markers))
+	if _, err := failing.AnonymizeUser(ctx, 2); err == nil {
+		t.Fatal("AnonymizeUser succeeded through a failing Anonymize")
+	}
+	if got, _ := markers.ListForUser(ctx, 2); len(got) != 1 {
+		t.Errorf("markers after a failed anonymization = %v, want them kept", got)
+	}
+
+	svc := NewUserService(repo, zap.NewNop(), WithNotificationRepository(markers))
+	if _, err := svc.AnonymizeUser(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := markers.ListForUser(ctx, 2); len(got) != 0 {
+		t.Errorf("markers after the anonymization = %v, want none", got)
+	}
+}
+
+func TestUpdateUsersReportsDuplicateNames(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(clock.Fixed(pinnedNow), repository.WithUniqueNames())
+	testutil.MustInsert(t, repo,
+		testutil.NewUserBuilder().WithName("Alice").WithDOB("1990-05-10").Build(),
+		testutil.NewUserBuilder().WithName("Bob").WithDOB("2000-02-29").Build(),
+		testutil.NewUserBuilder().WithName("Carol").WithDOB("1985-12-01").Build(),
+	)
+	svc := NewUserService(repo, zap.NewNop(), WithClock(clock.Fixed(pinnedNow)), WithUniqueNames(true))
+
+	result, err := svc.UpdateUsers(context.Background(), []models.BatchUpdateItem{
+		{ID: 1, Name: ptr("Alicia")},
+		{ID: 2, Name: ptr("carol")},
+		{ID: 3, DOB: ptr("1986-01-02")},
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{models.BatchUpdated, models.BatchDuplicateName, models.BatchUpdated}
+	if got := batchStatuses(result); !reflect.DeepEqual(got, want) || result.Updated != 2 || result.Failed != 1 {
+		t.Errorf("result = %v (updated %d, failed %d), want %v", got, result.Updated, result.Failed, want)
+	}
+	if user, err := repo.GetById(context.Background(), 2); err != nil || user.Name != "Bob" {
+		t.Errorf("user 2 = %+v, %v; want Bob unchanged", user, err)
+	}
+}
+
+func TestUpdateUsersValidatesBeforeTransaction(t *testing.T) {
+	svc, repo := newMockedService(t)
+
+	result, err := svc.UpdateUsers(context.Background(), []models.BatchUpdateItem{
+		{ID: 1, Name: ptr("Alicia")},
+		{ID: 2, DOB: ptr("someday")},
+	}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := batchStatuses(result); !reflect.DeepEqual(got, []string{models.BatchNotApplied, models.BatchInvalid}) {
+		t.Errorf("statuses = %v", got)
+	}
+	repo.AssertNotCalled(t, "Transact", mock.Anything, mock.Anything)
+}
+
+func TestUpdateUsersRepositoryError(t *testing.T) {
+	svc, repo := newMockedService(t)
+	dbErr := errors.New("deadlock detected")
+	repo.EXPECT().Transact(mock.Anything, mock.Anything).Return(dbErr)
+
+	if _, err := svc.UpdateUsers(context.Background(), []models.BatchUpdateItem{{ID: 1, Name: ptr("Alicia")}}, false); !errors.Is(err, dbErr) {
+		t.Errorf("err = %v, want the database error", err)
+	}
+}
+
+func TestUpdateUsersAtomicRollsBack(t *testing.T) {
+	repo := newBatchRepository(t)
+	m := metrics.NewUsers(prometheus.NewRegistry())
+	svc := NewUserService(repo, zap.NewNop(), WithMetrics(m))
+
+	result, err := svc.UpdateUsers(context.Background(), []models.BatchUpdateItem{
+		{ID: 1, Name: ptr("Alicia")},
+		{ID: 99, Name: ptr("Nobody")},
+		{ID: 2, Name: ptr("Robert")},
+	}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{models.BatchNotApplied, models.BatchNotFound, models.BatchNotApplied}
+	if got := batchStatuses(result); !reflect.DeepEqual(got, want) || result.Updated != 0 || result.Failed != 1 {
+		t.Errorf("result = %v (updated %d, failed %d), want %v", got, result.Updated, result.Failed, want)
+	}
+	if user, err := repo.GetById(context.Background(), 1); err != nil || user.Name != "Alice" {
+		t.Errorf("user 1 = %+v, %v; want the write rolled back", user, err)
+	}
+	if got := promtestutil.ToFloat64(m.Updated); got != 0 {
+		t.Errorf("updated counter = %v, want 0", got)
+	}
+}
+
+func TestAgePercentile(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryUserRepository(clock.Fixed(pinnedNow))
+	svc := NewUserService(repo, zap.NewNop(), WithClock(clock.Fixed(pinnedNow)))
+
+	lone, err := repo.Create(ctx, "Lone", time.Date(1985, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := svc.AgePercentile(ctx, lone.ID, &models.UserListQuery{})
+	if err != nil || got.Percentile == nil || *got.Percentile != 50 || got.SameDOB != 1 || got.Total != 1 {
+		t.Fatalf("single user = %+v, %v; want percentile 50", got, err)
+	}
+
+	// With Lone, 1980 to 1989 and 1985 twice.
+	for year := 1980; year <= 1989; year++ {
+		if _, err := repo.Create(ctx, "User", time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	from1985 := models.UserListQuery{DOBFrom: "1985-01-01"}
+	nobody := models.UserListQuery{Name: "nobody"}
+	tests := []struct {
+		name   string
+		id     int32
+		params *models.UserListQuery
+		want   models.AgePercentileResponse
+		pct    float64
+	}{
+		{"tied DOB", lone.ID, &models.UserListQuery{}, models.AgePercentileResponse{Younger: 4, Older: 5, SameDOB: 2, Total: 11}, 45.5},
+		{"youngest", 11, &models.UserListQuery{}, models.AgePercentileResponse{Younger: 0, Older: 10, SameDOB: 1, Total: 11}, 4.5},
+		{"oldest", 2, &models.UserListQuery{}, models.AgePercentileResponse{Younger: 10, Older: 0, SameDOB: 1, Total: 11}, 95.5},
+		{"filtered", lone.ID, &from1985, models.AgePercentileResponse{Younger: 4, Older: 0, SameDOB: 2, Total: 6}, 83.3},
+	}
+	for _, tt := range tests {
+		got, err := svc.AgePercentile(ctx, tt.id, tt.params)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got.Percentile == nil || *got.Percentile != tt.pct {
+			t.Errorf("%s: percentile = %v, want %v", tt.name, got.Percentile, tt.pct)
+		}
+		got.ID, got.Percentile = 0, nil
+		if !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("%s: counts = %+v, want %+v", tt.name, *got, tt.want)
+		}
+	}
+
+	if got, err := svc.AgePercentile(ctx, lone.ID, &nobody); err != nil || got.Percentile != nil || got.Total != 0 {
+		t.Errorf("empty population = %+v, %v; want a null percentile", got, err)
+	}
+	if _, err := svc.AgePercentile(ctx, 404, &models.UserListQuery{}); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("missing user: err = %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestAgeCohorts(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryUserRepository(clock.Fixed(pinnedNow))
+	// As of 2025-06-15: a DOB still to come, 15, a day short of 18, 18
+	// today, 29, 35, 65 today and 125.
+	for _, dob := range []string{"2025-06-16", "2010-01-01", "2007-06-16", "2007-06-15", "1996-01-01", "1990-05-10", "1960-06-15", "1900-01-01"} {
+		testutil.MustInsert(t, repo, testutil.NewUserBuilder().WithName("User "+dob).WithDOB(dob).Build())
+	}
+	svc := NewUserService(repo, zap.NewNop(), WithClock(clock.Fixed(pinnedNow)))
+
+	tests := []struct {
+		bounds []int
+		want   []string
+	}{
+		{nil, []string{"0-17: 3", "18-29: 2", "30-44: 1", "45-64: 0", "65+: 2"}},
+		{[]int{10, 20}, []string{"0-9: 1", "10-19: 3", "20+: 4"}},
+		{[]int{}, []string{"0+: 8"}},
+	}
+	for _, tt := range tests {
+		got, err := svc.AgeCohorts(ctx, tt.bounds)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var cohorts []string
+		for _, c := range got.Cohorts {
+			cohorts = append(cohorts, fmt.Sprintf("%s: %d", c.Label, c.Count))
+		}
+		if !slices.Equal(cohorts, tt.want) || got.Total != 8 || !got.AsOf.Equal(pinnedNow) {
+			t.Errorf("AgeCohorts(%v) = %v (total %d), want %v", tt.bounds, cohorts, got.Total, tt.want)
+		}
+	}
+	if got, _ := svc.AgeCohorts(ctx, []int{18}); got.Cohorts[0].MaxAge == nil || *got.Cohorts[0].MaxAge != 17 || got.Cohorts[1].MaxAge != nil || got.Cohorts[1].MinAge != 18 {
+		t.Errorf("AgeCohorts(18) = %+v, want 0 to 17 and 18 onwards", got.Cohorts)
+	}
+}
+
+func TestParseCohortBounds(t *testing.T) {
+	if bounds, err := ParseCohortBounds(""); err != nil || bounds != nil {
+		t.Errorf(`ParseCohortBounds("") = %v, %v; want nil`, bounds, err)
+	}
+	if bounds, err := ParseCohortBounds("18, 30,150"); err != nil || !slices.Equal(bounds, []int{18, 30, 150}) {
+		t.Errorf("ParseCohortBounds = %v, %v; want 18, 30, 150", bounds, err)
+	}
+	for value, rule := range map[string]string{
+		"18,thirty": "numeric",
+		"18,":       "numeric",
+		"0,18":      "range",
+		"18,151":    "range",
+		"30,18":     "increasing",
+		"18,18":     "increasing",
+		"1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,17,18,19,20,21": "max",
+	} {
+		_, err := ParseCohortBounds(value)
+		var verr *ValidationError
+		if !errors.As(err, &verr) || verr.Details[0].Field != "bounds" || verr.Details[0].Rule != rule {
+			t.Errorf("ParseCohortBounds(%q) = %v, want a %s error on bounds", value, err, rule)
+		}
+	}
 }
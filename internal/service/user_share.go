@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+	"go.uber.org/zap"
+)
+
+// SharedPath is where a share token is read, followed by the token.
+const SharedPath = "/api/v1/shared/"
+
+func (s *userService) CreateShare(ctx context.Context, id int32) (*models.ShareResponse, error) {
+	user, err := s.repo.GetById(ctx, id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if user.AnonymizedAt != nil {
+		return nil, repository.ErrAnonymized
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+	share, err := s.shares.Create(ctx, id, hashShareToken(token), s.clock.Now().Add(s.shareTTL))
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Share created", zap.Int32("id", id), zap.Int64("share_id", share.ID))
+
+	resp := toShareResponse(share)
+	resp.Token, resp.URL = token, SharedPath+token
+	return &resp, nil
+}
+
+func (s *userService) ListShares(ctx context.Context, id int32) (*models.ShareListResponse, error) {
+	if _, err := s.repo.GetById(ctx, id); err != nil {
+		return nil, notFound(err)
+	}
+	shares, err := s.shares.ListForUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	resp := &models.ShareListResponse{Shares: make([]models.ShareResponse, 0, len(shares))}
+	for i := range shares {
+		if shareLive(&shares[i], now) {
+			resp.Shares = append(resp.Shares, toShareResponse(&shares[i]))
+		}
+	}
+	return resp, nil
+}
+
+func (s *userService) RevokeShare(ctx context.Context, id int32, shareID int64) error {
+	if _, err := s.repo.GetById(ctx, id); err != nil {
+		return notFound(err)
+	}
+	if err := s.shares.Revoke(ctx, id, shareID, s.clock.Now()); err != nil {
+		return err
+	}
+	s.logger.Info("Share revoked", zap.Int32("id", id), zap.Int64("share_id", shareID))
+	return nil
+}
+
+// SharedProfile answers ErrShareNotFound alike for a token that never
+// existed, has expired or was revoked, and for a user since deleted or
+// anonymized, so a reader learns nothing from which it was.
+func (s *userService) SharedProfile(ctx context.Context, token string) (*models.SharedProfile, error) {
+	share, err := s.shares.GetByHash(ctx, hashShareToken(token))
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock.Now()
+	if !shareLive(share, now) {
+		return nil, repository.ErrShareNotFound
+	}
+	user, err := s.repo.GetById(tenant.WithID(ctx, share.TenantID), share.UserID)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && user.AnonymizedAt != nil) {
+		return nil, repository.ErrShareNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	today := toDate(now.In(UserLocation(user, s.location)))
+	return &models.SharedProfile{
+		Name:         user.Name,
+		Age:          CalculateAge(user.DOB, today),
+		NextBirthday: NextBirthday(user.DOB, today).Format(dateLayout),
+	}, nil
+}
+
+func hashShareToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+func shareLive(share *models.ShareToken, now time.Time) bool {
+	return share.RevokedAt == nil && share.ExpiresAt.After(now)
+}
+
+func toShareResponse(share *models.ShareToken) models.ShareResponse {
+	return models.ShareResponse{ID: share.ID, UserID: share.UserID, CreatedAt: share.CreatedAt, ExpiresAt: share.ExpiresAt}
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+	"go.uber.org/zap"
+)
+
+func TestSharedProfile(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(clock.Fixed(pinnedNow))
+	shares := repository.NewMemoryShareRepository(clock.Fixed(pinnedNow))
+	svc := NewUserService(repo, zap.NewNop(), WithClock(clock.Fixed(pinnedNow)), WithShareRepository(shares), WithShareTTL(time.Hour))
+	ctx := tenant.WithID(context.Background(), "acme")
+	user, _ := repo.Create(ctx, "Alice", time.Date(1990, 6, 16, 0, 0, 0, 0, time.UTC))
+	// Already Jun 16 there, so the birthday is today.
+	repo.SetTimezone(ctx, user.ID, "Pacific/Kiritimati")
+
+	share, err := svc.CreateShare(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if share.Token == "" || share.URL != SharedPath+share.Token || !share.ExpiresAt.Equal(pinnedNow.Add(time.Hour)) {
+		t.Errorf("CreateShare = %+v", share)
+	}
+
+	// The token carries its tenant; the reader's context has none.
+	profile, err := svc.SharedProfile(context.Background(), share.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.Name != "Alice" || profile.Age != 35 || profile.NextBirthday != "2025-06-16" {
+		t.Errorf("SharedProfile = %+v", profile)
+	}
+	body, _ := json.Marshal(profile)
+	if strings.Contains(string(body), "dob") {
+		t.Errorf("shared profile %s gives away the dob", body)
+	}
+
+	if list, err := svc.ListShares(ctx, user.ID); err != nil || len(list.Shares) != 1 || list.Shares[0].Token != "" {
+		t.Errorf("ListShares = %+v, %v; want the share without its token", list, err)
+	}
+	if _, err := svc.ListShares(context.Background(), user.ID); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("ListShares from another tenant: err = %v, want ErrUserNotFound", err)
+	}
+
+	later := NewUserService(repo, zap.NewNop(), WithClock(clock.Fixed(pinnedNow.Add(time.Hour))), WithShareRepository(shares))
+	if _, err := later.SharedProfile(context.Background(), share.Token); !errors.Is(err, repository.ErrShareNotFound) {
+		t.Errorf("SharedProfile once expired: err = %v, want ErrShareNotFound", err)
+	}
+	if list, err := later.ListShares(ctx, user.ID); err != nil || len(list.Shares) != 0 {
+		t.Errorf("ListShares once expired = %+v, %v; want none", list, err)
+	}
+
+	if _, err := svc.SharedProfile(context.Background(), share.Token+"x"); !errors.Is(err, repository.ErrShareNotFound) {
+		t.Errorf("SharedProfile of an unknown token: err = %v, want ErrShareNotFound", err)
+	}
+}
+
+func TestSharedProfileGone(t *testing.T) {
+	tests := []struct {
+		name string
+		gone func(svc UserService, ctx context.Context, id int32, shareID int64) error
+	}{
+		{name: "revoked", gone: func(svc UserService, ctx context.Context, id int32, shareID int64) error {
+			return svc.RevokeShare(ctx, id, shareID)
+		}},
+		{name: "anonymized", gone: func(svc UserService, ctx context.Context, id int32, _ int64) error {
+			_, err := svc.AnonymizeUser(ctx, id)
+			return err
+		}},
+		{name: "deleted", gone: func(svc UserService, ctx context.Context, id int32, _ int64) error {
+			return svc.DeleteUser(ctx, id)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewMemoryUserRepository(clock.Fixed(pinnedNow))
+			svc := NewUserService(repo, zap.NewNop(), WithClock(clock.Fixed(pinnedNow)))
+			ctx := context.Background()
+			user, _ := repo.Create(ctx, "Alice", time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC))
+			share, err := svc.CreateShare(ctx, user.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tt.gone(svc, ctx, user.ID, share.ID); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := svc.SharedProfile(ctx, share.Token); !errors.Is(err, repository.ErrShareNotFound) {
+				t.Errorf("SharedProfile: err = %v, want ErrShareNotFound", err)
+			}
+		})
+	}
+}
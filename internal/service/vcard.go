@@ -0,0 +1,58 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+)
+
+// VCard is user as a vCard of version 3 or 4, any other version meaning 4.
+// Users have no email address or nickname, so neither is written.
+func VCard(user *models.UserResponse, version int) []byte {
+	var buf bytes.Buffer
+	c := newLineWriter(&buf)
+	writeVCard(c, user, version)
+	c.flush()
+	return buf.Bytes()
+}
+
+func writeVCard(c *lineWriter, user *models.UserResponse, version int) {
+	c.line("BEGIN", "VCARD")
+	if version == models.VCard3 {
+		c.line("VERSION", "3.0")
+	} else {
+		c.line("VERSION", "4.0")
+	}
+	c.line("UID", fmt.Sprintf("urn:age-calculator:user:%d", user.ID))
+	c.line("FN", textValue(user.Name))
+	if version == models.VCard3 {
+		// 3.0 requires N. The name is not split into its parts, so they are
+		// left empty and FN alone is displayed.
+		c.line("N", ";;;;")
+	}
+	// A hidden DOB leaves the card without a birthday.
+	switch {
+	case user.DOB == "":
+	case version == models.VCard3:
+		c.line("BDAY", user.DOB)
+	default:
+		c.line("BDAY", strings.ReplaceAll(user.DOB, "-", ""))
+	}
+	c.line("END", "VCARD")
+}
+
+type vcardExport struct {
+	c       *lineWriter
+	version int
+}
+
+func (e vcardExport) encode(user models.UserResponse) error {
+	writeVCard(e.c, &user, e.version)
+	return e.c.err
+}
+
+func (e vcardExport) flush() error {
+	return e.c.flush()
+}
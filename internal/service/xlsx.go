@@ -0,0 +1,191 @@
+package service
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+)
+
+// maxXLSXRows is the most rows a worksheet holds, its header row included.
+const maxXLSXRows = 1_048_576
+
+var errXLSXFull = errors.New("export exceeds the rows of an xlsx worksheet")
+
+// xlsxExport writes a workbook of one worksheet, Users, a row per user under
+// a frozen header row. The parts every workbook needs come first, so the
+// sheet can be streamed as the last part of the zip.
+type xlsxExport struct {
+	zip     *zip.Writer
+	sheet   *bufio.Writer
+	withDOB bool
+	row     int
+}
+
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+		`</Types>`
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Users" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+		`</Relationships>`
+	// Cell style 1 formats a date as yyyy-mm-dd; style 2 is the bold header.
+	xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd"/></numFmts>` +
+		`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+		`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+		`<cellXfs count="3">` +
+		`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+		`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+		`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+		`</cellXfs>` +
+		`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+		`</styleSheet>`
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>` +
+		`<sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+const (
+	xlsxStyleDate   = 1
+	xlsxStyleHeader = 2
+)
+
+func newXLSXExport(w io.Writer, withDOB bool) (*xlsxExport, error) {
+	zw := zip.NewWriter(w)
+	for _, part := range []struct{ name, body string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", xlsxStyles},
+	} {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return nil, err
+		}
+	}
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	e := &xlsxExport{zip: zw, sheet: bufio.NewWriter(f), withDOB: withDOB}
+	e.sheet.WriteString(xlsxSheetStart)
+
+	header := []string{"id", "name", "dob", "age"}
+	if !withDOB {
+		header = []string{"id", "name", "age"}
+	}
+	e.startRow()
+	for i, title := range header {
+		e.stringCell(i, title, xlsxStyleHeader)
+	}
+	e.sheet.WriteString("</row>")
+	return e, nil
+}
+
+func (e *xlsxExport) encode(user models.UserResponse) error {
+	if e.row == maxXLSXRows {
+		return errXLSXFull
+	}
+	e.startRow()
+	e.numberCell(0, strconv.Itoa(int(user.ID)), 0)
+	e.stringCell(1, user.Name, 0)
+	col := 2
+	if e.withDOB {
+		if serial, ok := excelDate(user.DOB); ok {
+			e.numberCell(col, strconv.Itoa(serial), xlsxStyleDate)
+		} else {
+			e.stringCell(col, user.DOB, 0)
+		}
+		col++
+	}
+	e.numberCell(col, strconv.Itoa(*user.Age), 0)
+	_, err := e.sheet.WriteString("</row>")
+	return err
+}
+
+func (e *xlsxExport) flush() error {
+	return e.sheet.Flush()
+}
+
+// close ends the sheet and writes the zip's directory; the file is not
+// readable before.
+func (e *xlsxExport) close() error {
+	e.sheet.WriteString(xlsxSheetEnd)
+	if err := e.sheet.Flush(); err != nil {
+		return err
+	}
+	return e.zip.Close()
+}
+
+func (e *xlsxExport) startRow() {
+	e.row++
+	fmt.Fprintf(e.sheet, `<row r="%d">`, e.row)
+}
+
+func (e *xlsxExport) numberCell(col int, value string, style int) {
+	fmt.Fprintf(e.sheet, `<c r="%s%d"%s><v>%s</v></c>`, xlsxColumn(col), e.row, xlsxStyleAttr(style), value)
+}
+
+// stringCell writes value inline rather than to a shared string table, so
+// nothing has to be held until the end.
+func (e *xlsxExport) stringCell(col int, value string, style int) {
+	fmt.Fprintf(e.sheet, `<c r="%s%d" t="inlineStr"%s><is><t xml:space="preserve">`, xlsxColumn(col), e.row, xlsxStyleAttr(style))
+	xml.EscapeText(e.sheet, []byte(value))
+	e.sheet.WriteString(`</t></is></c>`)
+}
+
+func xlsxStyleAttr(style int) string {
+	if style == 0 {
+		return ""
+	}
+	return ` s="` + strconv.Itoa(style) + `"`
+}
+
+// xlsxColumn names the col-th column, from 0; exports have far fewer than 26.
+func xlsxColumn(col int) string {
+	return string(rune('A' + col))
+}
+
+// excelDate converts a DOB to the day number spreadsheets store dates as,
+// counted from Dec 30, 1899. Before Mar 1, 1900 Excel, which counts a Feb 29
+// that 1900 did not have, and other applications disagree by a day, so those
+// dates are left for a text cell.
+func excelDate(dob string) (int, bool) {
+	date, err := time.Parse(dateLayout, strings.TrimSpace(dob))
+	if err != nil || date.Before(time.Date(1900, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		return 0, false
+	}
+	return int(date.Sub(time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)).Hours() / 24), true
+}
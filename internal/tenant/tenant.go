@@ -0,0 +1,25 @@
+// Package tenant carries the tenant a request acts for from the HTTP layer
+// down to the repositories, which scope every query by it.
+package tenant
+
+import "context"
+
+// Default is the tenant of a deployment that configures none, and of every
+// row that existed before tenants did.
+const Default = "default"
+
+type key struct{}
+
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, key{}, id)
+}
+
+// FromContext returns Default when ctx carries no tenant, so code paths that
+// never pass through the tenant middleware, such as tests and tools, keep
+// working against the default tenant.
+func FromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(key{}).(string); ok && id != "" {
+		return id
+	}
+	return Default
+}
@@ -0,0 +1,40 @@
+package testutil
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+)
+
+// AssertAccessRepository checks that increments add up per user, keep the
+// latest access whatever order they come in, and are counted per tenant.
+// userID must name an existing user of the default tenant never counted.
+func AssertAccessRepository(t *testing.T, repo repository.AccessRepository, userID int32) {
+	t.Helper()
+	ctx := context.Background()
+	first := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	if count, err := repo.Get(ctx, userID); err != nil || *count != (models.AccessCount{}) {
+		t.Errorf("Get before any access = %+v, %v; want a zero count", count, err)
+	}
+	if err := repo.Add(ctx, nil); err != nil {
+		t.Errorf("Add of nothing = %v", err)
+	}
+	if err := repo.Add(ctx, []repository.AccessIncrement{{TenantID: tenant.Default, UserID: userID, Count: 3, LastAccessedAt: first}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add(ctx, []repository.AccessIncrement{{TenantID: tenant.Default, UserID: userID, Count: 2, LastAccessedAt: first.Add(-time.Hour)}}); err != nil {
+		t.Fatal(err)
+	}
+	count, err := repo.Get(ctx, userID)
+	if err != nil || count.Total != 5 || !count.LastAccessedAt.Equal(first) {
+		t.Errorf("Get = %+v, %v; want 5 accesses, the last at %v", count, err, first)
+	}
+	if count, err := repo.Get(tenant.WithID(ctx, "other"), userID); err != nil || count.Total != 0 {
+		t.Errorf("Get from another tenant = %+v, %v; want a zero count", count, err)
+	}
+}
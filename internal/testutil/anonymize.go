@@ -0,0 +1,89 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+)
+
+// AssertAnonymization checks that Anonymize overwrites the personal fields,
+// that the user stays readable but refuses every later write, and that it no
+// longer has birthdays. repo must be empty.
+func AssertAnonymization(t *testing.T, repo repository.UserRepository) {
+	t.Helper()
+	ctx := context.Background()
+	dob := time.Date(1990, 6, 15, 0, 0, 0, 0, time.UTC)
+	created, err := repo.Create(ctx, "Alice", dob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.SetTimezone(ctx, created.ID, "Europe/Berlin"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.SetLocale(ctx, created.ID, "de-DE"); err != nil {
+		t.Fatal(err)
+	}
+	// Warms a caching decorator, which must not serve the old row afterwards.
+	if _, err := repo.GetById(ctx, created.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	jan1 := time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
+	user, err := repo.Anonymize(ctx, created.ID, "Deleted User", jan1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Name != "Deleted User" || !user.DOB.Equal(jan1) || user.Timezone != "" || user.Locale != "" || user.AnonymizedAt == nil {
+		t.Errorf("Anonymize = %+v", user)
+	}
+	stored, err := repo.GetById(ctx, created.ID)
+	if err != nil || stored.Name != "Deleted User" || stored.AnonymizedAt == nil {
+		t.Errorf("GetById after Anonymize = %+v, %v", stored, err)
+	}
+	if users, err := repo.List(ctx, repository.ListQuery{Limit: 10}); err != nil || len(users) != 1 {
+		t.Errorf("List after Anonymize = %+v, %v; want the user", users, err)
+	}
+
+	name := "Alice"
+	writes := map[string]func() error{
+		"Update": func() error {
+			_, err := repo.Update(ctx, created.ID, "Alice", dob)
+			return err
+		},
+		"UpdatePartial": func() error {
+			_, err := repo.UpdatePartial(ctx, created.ID, &name, nil)
+			return err
+		},
+		"SetTimezone": func() error {
+			_, err := repo.SetTimezone(ctx, created.ID, "Europe/Berlin")
+			return err
+		},
+		"SetLocale": func() error {
+			_, err := repo.SetLocale(ctx, created.ID, "de-DE")
+			return err
+		},
+		"Anonymize": func() error {
+			_, err := repo.Anonymize(ctx, created.ID, "Deleted User", jan1)
+			return err
+		},
+	}
+	for op, write := range writes {
+		if err := write(); !errors.Is(err, repository.ErrAnonymized) {
+			t.Errorf("%s of an anonymized user: err = %v, want ErrAnonymized", op, err)
+		}
+	}
+	if _, err := repo.Anonymize(ctx, created.ID+1, "Deleted User", jan1); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Anonymize of a missing user: err = %v, want ErrNotFound", err)
+	}
+
+	newYear := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	if users, err := repo.ListBirthdays(ctx, newYear, "UTC"); err != nil || len(users) != 0 {
+		t.Errorf("ListBirthdays on Jan 1 = %+v, %v; want no anonymized users", users, err)
+	}
+	if err := repo.Delete(ctx, created.ID); err != nil {
+		t.Errorf("Delete of an anonymized user: %v", err)
+	}
+}
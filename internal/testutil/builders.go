@@ -0,0 +1,94 @@
+package testutil
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+)
+
+const dateLayout = "2006-01-02"
+
+// DefaultTimestamp is the CreatedAt/UpdatedAt a built user gets unless
+// overridden.
+var DefaultTimestamp = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type UserBuilder struct {
+	user models.User
+	age  *int
+}
+
+func NewUserBuilder() *UserBuilder {
+	return &UserBuilder{user: models.User{
+		ID:        1,
+		Name:      "Test User",
+		DOB:       time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
+		CreatedAt: DefaultTimestamp,
+		UpdatedAt: DefaultTimestamp,
+	}}
+}
+
+func (b *UserBuilder) WithID(id int32) *UserBuilder {
+	b.user.ID = id
+	return b
+}
+
+func (b *UserBuilder) WithName(name string) *UserBuilder {
+	b.user.Name = name
+	return b
+}
+
+// WithDOB panics on a malformed date; builders are for test literals only.
+func (b *UserBuilder) WithDOB(dob string) *UserBuilder {
+	parsed, err := time.Parse(dateLayout, dob)
+	if err != nil {
+		panic("testutil: invalid DOB " + dob)
+	}
+	b.user.DOB = parsed
+	return b
+}
+
+func (b *UserBuilder) WithTimestamps(createdAt, updatedAt time.Time) *UserBuilder {
+	b.user.CreatedAt = createdAt
+	b.user.UpdatedAt = updatedAt
+	return b
+}
+
+func (b *UserBuilder) WithAge(age int) *UserBuilder {
+	b.age = &age
+	return b
+}
+
+func (b *UserBuilder) Build() models.User {
+	return b.user
+}
+
+func (b *UserBuilder) CreateRequest() *models.CreateUserRequest {
+	return &models.CreateUserRequest{Name: b.user.Name, DOB: b.user.DOB.Format(dateLayout)}
+}
+
+func (b *UserBuilder) UpdateRequest() *models.UpdateUserRequest {
+	return &models.UpdateUserRequest{Name: b.user.Name, DOB: b.user.DOB.Format(dateLayout)}
+}
+
+// JSON returns the create/update payload as a request body string.
+func (b *UserBuilder) JSON() string {
+	raw, err := json.Marshal(b.CreateRequest())
+	if err != nil {
+		panic(err)
+	}
+	return string(raw)
+}
+
+func (b *UserBuilder) Response() *models.UserResponse {
+	resp := &models.UserResponse{
+		ID:   b.user.ID,
+		Name: b.user.Name,
+		DOB:  b.user.DOB.Format(dateLayout),
+	}
+	if b.age != nil {
+		age := *b.age
+		resp.Age = &age
+	}
+	return resp
+}
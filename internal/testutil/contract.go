@@ -0,0 +1,73 @@
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+)
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// AssertNoNilResults calls every UserRepository method against an empty store
+// and fails if one returns a nil pointer, slice or map alongside a nil error.
+// Methods are discovered by reflection, so new ones are covered automatically.
+func AssertNoNilResults(t *testing.T, repo repository.UserRepository) {
+	t.Helper()
+	for _, violation := range NilResults(repo) {
+		t.Error(violation)
+	}
+}
+
+func NilResults(repo repository.UserRepository) []string {
+	iface := reflect.TypeOf((*repository.UserRepository)(nil)).Elem()
+	value := reflect.ValueOf(repo)
+
+	var violations []string
+	for i := 0; i < iface.NumMethod(); i++ {
+		method := iface.Method(i)
+		args := make([]reflect.Value, method.Type.NumIn())
+		for j := range args {
+			if in := method.Type.In(j); in == contextType {
+				args[j] = reflect.ValueOf(context.Background())
+			} else if in.Kind() == reflect.Func {
+				args[j] = zeroFunc(in)
+			} else {
+				args[j] = reflect.Zero(in)
+			}
+		}
+
+		results := value.MethodByName(method.Name).Call(args)
+		last := len(results) - 1
+		if method.Type.Out(last) == errorType && !results[last].IsNil() {
+			continue
+		}
+
+		for j, result := range results[:last] {
+			switch result.Kind() {
+			case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
+				if result.IsNil() {
+					violations = append(violations, fmt.Sprintf("%s returned nil %s with a nil error", method.Name, method.Type.Out(j)))
+				}
+			}
+		}
+	}
+	return violations
+}
+
+// zeroFunc builds a callback of type fn that does nothing and returns zero
+// values, such as the body passed to Transact.
+func zeroFunc(fn reflect.Type) reflect.Value {
+	return reflect.MakeFunc(fn, func([]reflect.Value) []reflect.Value {
+		out := make([]reflect.Value, fn.NumOut())
+		for i := range out {
+			out[i] = reflect.Zero(fn.Out(i))
+		}
+		return out
+	})
+}
@@ -0,0 +1,123 @@
+package testutil
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+)
+
+type nilNilRepository struct {
+	repository.UserRepository
+}
+
+func (nilNilRepository) Create(ctx context.Context, name string, dob time.Time) (*models.User, error) {
+	return &models.User{}, nil
+}
+
+func (nilNilRepository) GetById(ctx context.Context, id int32) (*models.User, error) {
+	return nil, nil
+}
+
+func (nilNilRepository) GetByName(ctx context.Context, name string) (*models.User, error) {
+	return nil, repository.ErrNotFound
+}
+
+func (nilNilRepository) FindRecent(ctx context.Context, name string, dob time.Time, within time.Duration) (*models.User, error) {
+	return nil, repository.ErrNotFound
+}
+
+func (nilNilRepository) FindByNameDOB(ctx context.Context, name string, dob time.Time) (*models.User, error) {
+	return nil, repository.ErrNotFound
+}
+
+func (nilNilRepository) List(ctx context.Context, query repository.ListQuery) ([]models.User, error) {
+	return nil, nil
+}
+
+func (nilNilRepository) Update(ctx context.Context, id int32, name string, dob time.Time) (*models.User, error) {
+	return nil, repository.ErrNotFound
+}
+
+func (nilNilRepository) UpdatePartial(ctx context.Context, id int32, name *string, dob *time.Time) (*models.User, error) {
+	return nil, nil
+}
+
+func (nilNilRepository) SetTimezone(ctx context.Context, id int32, timezone string) (*models.User, error) {
+	return nil, repository.ErrNotFound
+}
+
+func (nilNilRepository) SetLocale(ctx context.Context, id int32, locale string) (*models.User, error) {
+	return nil, repository.ErrNotFound
+}
+
+func (nilNilRepository) Anonymize(ctx context.Context, id int32, name string, dob time.Time) (*models.User, error) {
+	return nil, repository.ErrNotFound
+}
+
+func (nilNilRepository) ListBirthdays(ctx context.Context, now time.Time, defaultZone string) ([]models.User, error) {
+	return []models.User{}, nil
+}
+
+func (nilNilRepository) ListByDOB(ctx context.Context, latestFirst bool, limit int32) ([]models.User, error) {
+	return []models.User{}, nil
+}
+
+func (nilNilRepository) ListByBirthday(ctx context.Context, q repository.BirthdayQuery) ([]models.User, int64, error) {
+	return []models.User{}, 0, nil
+}
+
+func (nilNilRepository) BirthdayCollisions(ctx context.Context, limit int32) ([]repository.BirthdayCollision, error) {
+	return []repository.BirthdayCollision{}, nil
+}
+
+func (nilNilRepository) LockTenant(ctx context.Context) error {
+	return nil
+}
+
+func (r nilNilRepository) Transact(ctx context.Context, fn func(tx repository.UserRepository) error) error {
+	return fn(r)
+}
+
+func (nilNilRepository) Outbox() repository.OutboxRepository {
+	return nil
+}
+
+func (nilNilRepository) Delete(ctx context.Context, id int32) error {
+	return nil
+}
+
+func (nilNilRepository) Count(ctx context.Context, filter repository.UserFilter) (int64, error) {
+	return 0, nil
+}
+
+func (nilNilRepository) CountByDOB(ctx context.Context, dob time.Time, filter repository.UserFilter) (repository.DOBCounts, error) {
+	return repository.DOBCounts{}, nil
+}
+
+func (nilNilRepository) CountByDOBBounds(ctx context.Context, bounds []time.Time) ([]int64, error) {
+	return []int64{0}, nil
+}
+
+func (nilNilRepository) ListWithDOBAfter(ctx context.Context, date time.Time) ([]models.User, error) {
+	return []models.User{}, nil
+}
+
+func (nilNilRepository) SearchNames(ctx context.Context, search repository.NameSearch) ([]models.User, int64, error) {
+	return nil, 0, repository.ErrFuzzySearchUnsupported
+}
+
+func TestNilResultsFlagsNilWithoutError(t *testing.T) {
+	got := NilResults(nilNilRepository{})
+	want := []string{
+		"GetById returned nil *models.User with a nil error",
+		"List returned nil []models.User with a nil error",
+		"UpdatePartial returned nil *models.User with a nil error",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NilResults = %q, want %q", got, want)
+	}
+}
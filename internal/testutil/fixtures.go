@@ -0,0 +1,98 @@
+package testutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"gopkg.in/yaml.v3"
+)
+
+type UserFixture struct {
+	Name string `json:"name" yaml:"name"`
+	DOB  string `json:"dob" yaml:"dob"`
+}
+
+// FixtureLoader seeds users through a repository, so the same fixture files
+// work against the in-memory and SQL implementations.
+type FixtureLoader struct {
+	repo repository.UserRepository
+}
+
+func NewFixtureLoader(repo repository.UserRepository) *FixtureLoader {
+	return &FixtureLoader{repo: repo}
+}
+
+// Load reads a .json, .yaml or .yml file holding a list of users and inserts
+// them in file order, returning the stored rows.
+func (l *FixtureLoader) Load(ctx context.Context, path string) ([]models.User, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("testutil: reading fixture: %w", err)
+	}
+
+	var fixtures []UserFixture
+	switch ext := filepath.Ext(path); ext {
+	case ".json":
+		err = json.Unmarshal(raw, &fixtures)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(raw, &fixtures)
+	default:
+		return nil, fmt.Errorf("testutil: unsupported fixture format %q", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("testutil: parsing %s: %w", path, err)
+	}
+
+	users := make([]models.User, 0, len(fixtures))
+	for _, fixture := range fixtures {
+		dob, err := time.Parse(dateLayout, fixture.DOB)
+		if err != nil {
+			return nil, fmt.Errorf("testutil: fixture %q: %w", fixture.Name, err)
+		}
+		user, err := l.repo.Create(ctx, fixture.Name, dob)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	return users, nil
+}
+
+// Insert stores already-built users; IDs and timestamps are assigned by the
+// repository.
+func (l *FixtureLoader) Insert(ctx context.Context, users ...models.User) ([]models.User, error) {
+	stored := make([]models.User, 0, len(users))
+	for _, u := range users {
+		user, err := l.repo.Create(ctx, u.Name, u.DOB)
+		if err != nil {
+			return nil, err
+		}
+		stored = append(stored, *user)
+	}
+	return stored, nil
+}
+
+func MustLoad(t testing.TB, repo repository.UserRepository, path string) []models.User {
+	t.Helper()
+	users, err := NewFixtureLoader(repo).Load(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return users
+}
+
+func MustInsert(t testing.TB, repo repository.UserRepository, users ...models.User) []models.User {
+	t.Helper()
+	stored, err := NewFixtureLoader(repo).Insert(context.Background(), users...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stored
+}
@@ -0,0 +1,69 @@
+package testutil
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+)
+
+func TestFixtureLoaderFormats(t *testing.T) {
+	for _, path := range []string{"testdata/users.yaml", "testdata/users.json"} {
+		t.Run(path, func(t *testing.T) {
+			repo := repository.NewMemoryUserRepository(clock.Fixed(DefaultTimestamp))
+			users := MustLoad(t, repo, path)
+
+			if len(users) != 2 {
+				t.Fatalf("loaded %d users, want 2", len(users))
+			}
+			if users[0].ID != 1 || users[0].Name != "Alice" || !users[0].DOB.Equal(time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC)) {
+				t.Errorf("first user = %+v", users[0])
+			}
+
+			count, err := repo.Count(context.Background(), repository.UserFilter{})
+			if err != nil || count != 2 {
+				t.Errorf("Count = %d, %v, want 2", count, err)
+			}
+		})
+	}
+}
+
+func TestFixtureLoaderErrors(t *testing.T) {
+	tests := []struct {
+		path     string
+		contains string
+	}{
+		{path: "testdata/missing.yaml", contains: "reading fixture"},
+		{path: "testdata/bad_dob.yaml", contains: `fixture "Broken"`},
+		{path: "fixtures_test.go", contains: "unsupported fixture format"},
+	}
+
+	for _, tt := range tests {
+		repo := repository.NewMemoryUserRepository(clock.Fixed(DefaultTimestamp))
+		_, err := NewFixtureLoader(repo).Load(context.Background(), tt.path)
+		if err == nil || !strings.Contains(err.Error(), tt.contains) {
+			t.Errorf("Load(%s) error = %v, want it to contain %q", tt.path, err, tt.contains)
+		}
+	}
+}
+
+func TestUserBuilderDefaults(t *testing.T) {
+	b := NewUserBuilder().WithName("A").WithDOB("1990-05-10")
+
+	user := b.Build()
+	if user.ID != 1 || user.Name != "A" || user.CreatedAt != DefaultTimestamp {
+		t.Errorf("Build() = %+v", user)
+	}
+	if req := b.CreateRequest(); req.Name != "A" || req.DOB != "1990-05-10" {
+		t.Errorf("CreateRequest() = %+v", req)
+	}
+	if got := b.JSON(); got != `{"name":"A","dob":"1990-05-10"}` {
+		t.Errorf("JSON() = %s", got)
+	}
+	if resp := b.WithAge(35).Response(); resp.DOB != "1990-05-10" || *resp.Age != 35 {
+		t.Errorf("Response() = %+v", resp)
+	}
+}
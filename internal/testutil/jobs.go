@@ -0,0 +1,105 @@
+package testutil
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+)
+
+// AssertJobRepository walks jobs in two tenants through their lifecycle
+// against a store with no jobs: reads stay within a tenant, claims go oldest
+// first across tenants, and a finished job never changes again.
+func AssertJobRepository(t *testing.T, repo repository.JobRepository) {
+	t.Helper()
+	acme := tenant.WithID(context.Background(), "acme")
+	globex := tenant.WithID(context.Background(), "globex")
+	ctx := context.Background()
+
+	first, err := repo.Create(acme, models.JobUserImport, []byte("name,dob\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.State != models.JobPending || first.TenantID != "acme" || first.Payload != nil || first.FinishedAt != nil {
+		t.Errorf("Create = %+v, want a pending acme job without its payload", first)
+	}
+	second, err := repo.Create(globex, models.JobUserImport, []byte("second"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := repo.Get(acme, first.ID); err != nil || got.ID != first.ID {
+		t.Errorf("Get by owner = %+v, %v", got, err)
+	}
+	if _, err := repo.Get(globex, first.ID); !errors.Is(err, repository.ErrJobNotFound) {
+		t.Errorf("Get across tenants: err = %v, want ErrJobNotFound", err)
+	}
+
+	claimed, err := repo.Claim(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claimed.ID != first.ID || claimed.State != models.JobRunning || string(claimed.Payload) != "name,dob\n" || claimed.TenantID != "acme" {
+		t.Errorf("first Claim = %+v, want the acme job running with its payload", claimed)
+	}
+	if claimed, err := repo.Claim(ctx); err != nil || claimed.ID != second.ID {
+		t.Errorf("second Claim = %+v, %v; want the globex job", claimed, err)
+	}
+	if _, err := repo.Claim(ctx); !errors.Is(err, repository.ErrJobNotFound) {
+		t.Errorf("Claim with nothing pending: err = %v, want ErrJobNotFound", err)
+	}
+
+	if err := repo.Progress(ctx, first.ID, 5); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := repo.Get(acme, first.ID); got.RowsProcessed != 5 || got.State != models.JobRunning {
+		t.Errorf("after Progress = %+v, want 5 rows and still running", got)
+	}
+
+	report := json.RawMessage(`{"rows": 7, "created": 6}`)
+	if err := repo.Finish(ctx, first.ID, models.JobDone, 7, report, ""); err != nil {
+		t.Fatal(err)
+	}
+	done, err := repo.Get(acme, first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done.State != models.JobDone || done.RowsProcessed != 7 || done.FinishedAt == nil || !sameJSON(t, done.Report, report) {
+		t.Errorf("after Finish = %+v (report %s)", done, done.Report)
+	}
+	repo.Progress(ctx, first.ID, 99)
+	repo.Finish(ctx, first.ID, models.JobFailed, 0, nil, "late")
+	if got, _ := repo.Get(acme, first.ID); got.State != models.JobDone || got.RowsProcessed != 7 || got.Error != "" {
+		t.Errorf("a finished job changed: %+v", got)
+	}
+
+	if n, err := repo.FailStale(ctx, time.Hour, "worker gone"); err != nil || n != 0 {
+		t.Errorf("FailStale(1h) = %d, %v; want nothing stale yet", n, err)
+	}
+	// A negative age reaches the job updated a moment ago.
+	if n, err := repo.FailStale(ctx, -time.Minute, "worker gone"); err != nil || n != 1 {
+		t.Errorf("FailStale(-1m) = %d, %v; want the running globex job", n, err)
+	}
+	stale, err := repo.Get(globex, second.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stale.State != models.JobFailed || stale.Error != "worker gone" || stale.FinishedAt == nil || stale.Report != nil {
+		t.Errorf("stale job = %+v, want failed with the reason", stale)
+	}
+}
+
+func sameJSON(t *testing.T, a, b json.RawMessage) bool {
+	t.Helper()
+	var x, y any
+	if json.Unmarshal(a, &x) != nil || json.Unmarshal(b, &y) != nil {
+		return false
+	}
+	return reflect.DeepEqual(x, y)
+}
@@ -0,0 +1,71 @@
+package testutil
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+)
+
+// AssertNotificationRepository checks that a birthday is marked once per
+// year, even by concurrent callers, can be marked again once unmarked, and
+// is forgotten with the rest of the user's markers by DeleteForUser.
+// userID must name an existing user with no markers.
+func AssertNotificationRepository(t *testing.T, repo repository.NotificationRepository, userID int32) {
+	t.Helper()
+	ctx := context.Background()
+	on := time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	marked := 0
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := repo.MarkNotified(ctx, userID, 2025, on)
+			if err != nil {
+				t.Error(err)
+			}
+			if ok {
+				mu.Lock()
+				marked++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if marked != 1 {
+		t.Errorf("%d concurrent MarkNotified calls succeeded, want 1", marked)
+	}
+
+	if ok, err := repo.MarkNotified(ctx, userID, 2026, on.AddDate(1, 0, 0)); err != nil || !ok {
+		t.Errorf("MarkNotified for the next year = %v, %v; want true", ok, err)
+	}
+	want := []models.BirthdayNotification{{Year: 2025, NotifiedOn: "2025-06-15"}, {Year: 2026, NotifiedOn: "2026-06-15"}}
+	if got, err := repo.ListForUser(ctx, userID); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ListForUser = %+v, %v; want %+v", got, err, want)
+	}
+	if got, err := repo.ListForUser(ctx, userID+1); err != nil || got == nil || len(got) != 0 {
+		t.Errorf("ListForUser of another user = %#v, %v; want empty", got, err)
+	}
+	if err := repo.Unmark(ctx, userID, 2025); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := repo.MarkNotified(ctx, userID, 2025, on); err != nil || !ok {
+		t.Errorf("MarkNotified after Unmark = %v, %v; want true", ok, err)
+	}
+	if err := repo.Unmark(ctx, userID, 2024); err != nil {
+		t.Errorf("Unmark without a marker: %v", err)
+	}
+	if err := repo.DeleteForUser(ctx, userID); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := repo.ListForUser(ctx, userID); err != nil || len(got) != 0 {
+		t.Errorf("ListForUser after DeleteForUser = %+v, %v; want empty", got, err)
+	}
+}
@@ -0,0 +1,123 @@
+package testutil
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/events"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+)
+
+// AssertOutbox checks the outbox of a user repository whose outbox is empty:
+// an event enqueued in a transaction is kept only if it commits, events are
+// claimed oldest first and not again while their lease lasts, and failed and
+// dead-lettered events are tried again when due and when replayed.
+func AssertOutbox(t *testing.T, repo repository.UserRepository) {
+	t.Helper()
+	ctx := context.Background()
+	outbox := repo.Outbox()
+	at := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	event := func(userID int32, offset time.Duration) events.Event {
+		return events.Event{Type: "user.anonymized", TenantID: "acme", At: at.Add(offset), Data: map[string]int32{"user_id": userID}}
+	}
+
+	boom := errors.New("boom")
+	if err := repo.Transact(ctx, func(tx repository.UserRepository) error {
+		if err := tx.Outbox().Enqueue(ctx, event(1, 0)); err != nil {
+			return err
+		}
+		return boom
+	}); !errors.Is(err, boom) {
+		t.Fatalf("Transact returned %v, want the error from fn", err)
+	}
+	if _, err := outbox.Claim(ctx, at.Add(time.Hour), time.Minute); !errors.Is(err, repository.ErrOutboxEventNotFound) {
+		t.Fatalf("Claim after a rolled back enqueue: err = %v, want ErrOutboxEventNotFound", err)
+	}
+
+	if err := repo.Transact(ctx, func(tx repository.UserRepository) error {
+		return tx.Outbox().Enqueue(ctx, event(2, time.Second))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := outbox.Enqueue(ctx, event(3, 2*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := outbox.Claim(ctx, at, time.Minute); !errors.Is(err, repository.ErrOutboxEventNotFound) {
+		t.Errorf("Claim before any event is due: err = %v, want ErrOutboxEventNotFound", err)
+	}
+
+	now := at.Add(time.Minute)
+	first, err := outbox.Claim(ctx, now, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data struct {
+		UserID int32 `json:"user_id"`
+	}
+	if json.Unmarshal(first.Payload, &data) != nil || data.UserID != 2 || first.Type != "user.anonymized" || first.TenantID != "acme" ||
+		!first.CreatedAt.Equal(at.Add(time.Second)) || first.Attempts != 1 || !first.NextAttemptAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("first Claim = %+v (payload %s), want user 2's event, attempted once and leased", first, first.Payload)
+	}
+	second, err := outbox.Claim(ctx, now, time.Minute)
+	if err != nil || second.ID == first.ID || json.Unmarshal(second.Payload, &data) != nil || data.UserID != 3 {
+		t.Fatalf("second Claim = %+v, %v; want user 3's event", second, err)
+	}
+	if _, err := outbox.Claim(ctx, now, time.Minute); !errors.Is(err, repository.ErrOutboxEventNotFound) {
+		t.Errorf("Claim while both are leased: err = %v, want ErrOutboxEventNotFound", err)
+	}
+
+	// The first one's dispatcher never came back; its lease runs out.
+	now = now.Add(time.Minute)
+	again, err := outbox.Claim(ctx, now, time.Minute)
+	if err != nil || again.ID != first.ID || again.Attempts != 2 {
+		t.Fatalf("Claim after the lease = %+v, %v; want the first event, attempted twice", again, err)
+	}
+	if err := outbox.MarkSent(ctx, first.ID, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := outbox.MarkFailed(ctx, second.ID, "webhook answered 500", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := outbox.Claim(ctx, now.Add(59*time.Minute), time.Minute); !errors.Is(err, repository.ErrOutboxEventNotFound) {
+		t.Errorf("Claim with one sent and one waiting to retry: err = %v, want ErrOutboxEventNotFound", err)
+	}
+	now = now.Add(time.Hour)
+	retried, err := outbox.Claim(ctx, now, time.Minute)
+	if err != nil || retried.ID != second.ID || retried.LastError != "webhook answered 500" {
+		t.Fatalf("Claim once the retry is due = %+v, %v", retried, err)
+	}
+
+	if err := outbox.Replay(ctx, second.ID, now); !errors.Is(err, repository.ErrOutboxEventNotFound) {
+		t.Errorf("Replay of a live event: err = %v, want ErrOutboxEventNotFound", err)
+	}
+	if err := outbox.MarkDead(ctx, second.ID, "webhook answered 410", now); err != nil {
+		t.Fatal(err)
+	}
+	dead, err := outbox.ListDead(ctx, 10)
+	if err != nil || len(dead) != 1 || dead[0].ID != second.ID || dead[0].DeadAt == nil || !dead[0].DeadAt.Equal(now) || dead[0].LastError != "webhook answered 410" {
+		t.Fatalf("ListDead = %+v, %v; want the second event", dead, err)
+	}
+	if _, err := outbox.Claim(ctx, now.Add(24*time.Hour), time.Minute); !errors.Is(err, repository.ErrOutboxEventNotFound) {
+		t.Errorf("Claim with the only unsent event dead: err = %v, want ErrOutboxEventNotFound", err)
+	}
+	if err := outbox.Replay(ctx, second.ID, now); err != nil {
+		t.Fatal(err)
+	}
+	if dead, err := outbox.ListDead(ctx, 10); err != nil || dead == nil || len(dead) != 0 {
+		t.Errorf("ListDead after replay = %#v, %v; want empty", dead, err)
+	}
+	replayed, err := outbox.Claim(ctx, now, time.Minute)
+	if err != nil || replayed.ID != second.ID || replayed.Attempts != 1 {
+		t.Fatalf("Claim after replay = %+v, %v; want the second event, on its first attempt again", replayed, err)
+	}
+
+	if deleted, err := outbox.DeleteSent(ctx, at.Add(2*time.Minute)); err != nil || deleted != 0 {
+		t.Errorf("DeleteSent before the first was sent = %d, %v; want 0", deleted, err)
+	}
+	if deleted, err := outbox.DeleteSent(ctx, now); err != nil || deleted != 1 {
+		t.Errorf("DeleteSent = %d, %v; want the first event deleted", deleted, err)
+	}
+}
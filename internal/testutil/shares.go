@@ -0,0 +1,67 @@
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+)
+
+// AssertShareRepository checks that shares are listed per user and tenant,
+// are found by hash from any tenant, and are revoked at most once. userID
+// must name an existing user of the default tenant with no shares.
+func AssertShareRepository(t *testing.T, repo repository.ShareRepository, userID int32) {
+	t.Helper()
+	ctx := context.Background()
+	expires := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	first, err := repo.Create(ctx, userID, []byte("hash-1"), expires)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := repo.Create(ctx, userID, []byte("hash-2"), expires.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.UserID != userID || first.TenantID != tenant.Default || !first.ExpiresAt.Equal(expires) || first.RevokedAt != nil || second.ID <= first.ID {
+		t.Errorf("Create = %+v, then %+v", first, second)
+	}
+
+	shares, err := repo.ListForUser(ctx, userID)
+	if err != nil || len(shares) != 2 || shares[0].ID != first.ID || shares[1].ID != second.ID {
+		t.Errorf("ListForUser = %+v, %v; want both shares, oldest first", shares, err)
+	}
+	other := tenant.WithID(ctx, "other")
+	if shares, err := repo.ListForUser(other, userID); err != nil || shares == nil || len(shares) != 0 {
+		t.Errorf("ListForUser from another tenant = %#v, %v; want empty", shares, err)
+	}
+
+	found, err := repo.GetByHash(other, []byte("hash-2"))
+	if err != nil || found.ID != second.ID || found.TenantID != tenant.Default || !bytes.Equal(found.TokenHash, []byte("hash-2")) {
+		t.Errorf("GetByHash from another tenant = %+v, %v; want the share and its tenant", found, err)
+	}
+	if _, err := repo.GetByHash(ctx, []byte("nobody")); !errors.Is(err, repository.ErrShareNotFound) {
+		t.Errorf("GetByHash of an unknown hash: err = %v, want ErrShareNotFound", err)
+	}
+
+	if err := repo.Revoke(other, userID, first.ID, expires); !errors.Is(err, repository.ErrShareNotFound) {
+		t.Errorf("Revoke from another tenant: err = %v, want ErrShareNotFound", err)
+	}
+	if err := repo.Revoke(ctx, userID+1, first.ID, expires); !errors.Is(err, repository.ErrShareNotFound) {
+		t.Errorf("Revoke for another user: err = %v, want ErrShareNotFound", err)
+	}
+	revokedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := repo.Revoke(ctx, userID, first.ID, revokedAt); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Revoke(ctx, userID, first.ID, revokedAt); !errors.Is(err, repository.ErrShareNotFound) {
+		t.Errorf("second Revoke: err = %v, want ErrShareNotFound", err)
+	}
+	if found, err := repo.GetByHash(ctx, []byte("hash-1")); err != nil || found.RevokedAt == nil || !found.RevokedAt.Equal(revokedAt) {
+		t.Errorf("GetByHash of a revoked share = %+v, %v; want it with RevokedAt", found, err)
+	}
+}
@@ -0,0 +1,121 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+)
+
+// AssertTenantIsolation seeds an empty store with users in two tenants and
+// checks that every UserRepository method only ever sees the tenant on its
+// context. Each foreign lookup follows a successful one by the owner, so a
+// caching decorator is exercised with a warm entry.
+func AssertTenantIsolation(t *testing.T, repo repository.UserRepository) {
+	t.Helper()
+	acme := tenant.WithID(context.Background(), "acme")
+	globex := tenant.WithID(context.Background(), "globex")
+	past := time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC)
+	future := time.Date(2999, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	create := func(ctx context.Context, name string, dob time.Time) int32 {
+		t.Helper()
+		user, err := repo.Create(ctx, name, dob)
+		if err != nil {
+			t.Fatalf("Create(%s): %v", name, err)
+		}
+		if user.TenantID != tenant.FromContext(ctx) {
+			t.Errorf("Create(%s) TenantID = %q, want %q", name, user.TenantID, tenant.FromContext(ctx))
+		}
+		return user.ID
+	}
+	alice := create(acme, "Alice", past)
+	zed := create(acme, "Zed", future)
+	bob := create(globex, "Bob", past)
+	yan := create(globex, "Yan", future)
+
+	ids := func(users []models.User) []int32 {
+		out := make([]int32, len(users))
+		for i, u := range users {
+			out[i] = u.ID
+		}
+		return out
+	}
+	notFound := func(op string, err error) {
+		t.Helper()
+		if !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("%s across tenants: err = %v, want ErrNotFound", op, err)
+		}
+	}
+
+	if user, err := repo.GetById(acme, alice); err != nil || user.TenantID != "acme" {
+		t.Fatalf("GetById by owner = %+v, %v", user, err)
+	}
+	_, err := repo.GetById(globex, alice)
+	notFound("GetById", err)
+	_, err = repo.GetById(context.Background(), alice)
+	notFound("GetById from the default tenant", err)
+	if user, err := repo.FindByNameDOB(acme, "alice", past); err != nil || user.ID != alice {
+		t.Errorf("FindByNameDOB by owner = %+v, %v; want Alice", user, err)
+	}
+	_, err = repo.FindByNameDOB(globex, "Alice", past)
+	notFound("FindByNameDOB", err)
+
+	for _, tt := range []struct {
+		ctx  context.Context
+		want []int32
+	}{
+		{acme, []int32{alice, zed}},
+		{globex, []int32{bob, yan}},
+		{context.Background(), []int32{}},
+	} {
+		name := tenant.FromContext(tt.ctx)
+		users, err := repo.List(tt.ctx, repository.ListQuery{Limit: 10, Sort: models.SortOrder{Field: "id"}})
+		if err != nil || !slices.Equal(ids(users), tt.want) {
+			t.Errorf("List(%s) = %v, %v; want %v", name, ids(users), err, tt.want)
+		}
+		if count, err := repo.Count(tt.ctx, repository.UserFilter{}); err != nil || count != int64(len(tt.want)) {
+			t.Errorf("Count(%s) = %d, %v; want %d", name, count, err, len(tt.want))
+		}
+	}
+	if users, err := repo.List(globex, repository.ListQuery{Limit: 10, Filter: repository.UserFilter{Name: "ali"}}); err != nil || len(users) != 0 {
+		t.Errorf("filtered List(globex) = %v, %v; want no users", ids(users), err)
+	}
+	if count, err := repo.Count(globex, repository.UserFilter{Name: "ali"}); err != nil || count != 0 {
+		t.Errorf("filtered Count(globex) = %d, %v; want 0", count, err)
+	}
+	after := &repository.Keyset{ID: alice}
+	if users, err := repo.List(globex, repository.ListQuery{Limit: 10, After: after}); err != nil || !slices.Equal(ids(users), []int32{bob, yan}) {
+		t.Errorf("List(globex) after a foreign id = %v, %v; want %v", ids(users), err, []int32{bob, yan})
+	}
+
+	today := time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC)
+	if users, err := repo.ListWithDOBAfter(globex, today); err != nil || !slices.Equal(ids(users), []int32{yan}) {
+		t.Errorf("ListWithDOBAfter(globex) = %v, %v; want %v", ids(users), err, []int32{yan})
+	}
+
+	_, err = repo.Update(globex, alice, "Mallory", past)
+	notFound("Update", err)
+	_, err = repo.Anonymize(globex, alice, "Deleted User", past)
+	notFound("Anonymize", err)
+	err = repo.Delete(globex, alice)
+	notFound("Delete", err)
+	if user, err := repo.GetById(acme, alice); err != nil || user.Name != "Alice" {
+		t.Errorf("after foreign writes, owner sees %+v, %v; want Alice unchanged", user, err)
+	}
+
+	if _, err := repo.Update(acme, alice, "Alicia", past); err != nil {
+		t.Errorf("Update by owner: %v", err)
+	}
+	if err := repo.Delete(acme, alice); err != nil {
+		t.Errorf("Delete by owner: %v", err)
+	}
+	if count, err := repo.Count(globex, repository.UserFilter{}); err != nil || count != 2 {
+		t.Errorf("Count(globex) after acme's delete = %d, %v; want 2", count, err)
+	}
+}
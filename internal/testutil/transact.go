@@ -0,0 +1,92 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+)
+
+// AssertTransactions checks UpdatePartial and Transact against an empty
+// store: a failed transaction leaves no trace, a committed one is visible,
+// and nested calls join the outer transaction, a failed one undoing only its
+// own writes. Each read through repo follows
+// a write inside a transaction, so a caching decorator must have evicted it.
+func AssertTransactions(t *testing.T, repo repository.UserRepository) {
+	t.Helper()
+	ctx := context.Background()
+	dob := time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC)
+	user, err := repo.Create(ctx, "Alice", dob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetById(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	name := "Alicia"
+	boom := errors.New("boom")
+	err = repo.Transact(ctx, func(tx repository.UserRepository) error {
+		if _, err := tx.UpdatePartial(ctx, user.ID, &name, nil); err != nil {
+			return err
+		}
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("Transact returned %v, want the error from fn", err)
+	}
+	if got, err := repo.GetById(ctx, user.ID); err != nil || got.Name != "Alice" {
+		t.Errorf("after rollback GetById = %+v, %v; want Alice unchanged", got, err)
+	}
+
+	newDOB := time.Date(1991, 3, 15, 0, 0, 0, 0, time.UTC)
+	err = repo.Transact(ctx, func(tx repository.UserRepository) error {
+		if _, err := tx.UpdatePartial(ctx, user.ID, nil, &newDOB); err != nil {
+			return err
+		}
+		return tx.Transact(ctx, func(inner repository.UserRepository) error {
+			_, err := inner.UpdatePartial(ctx, user.ID, &name, nil)
+			return err
+		})
+	})
+	if err != nil {
+		t.Fatalf("Transact: %v", err)
+	}
+	got, err := repo.GetById(ctx, user.ID)
+	if err != nil || got.Name != name || !got.DOB.Equal(newDOB) {
+		t.Errorf("after commit GetById = %+v, %v; want %s born %s", got, err, name, newDOB.Format("2006-01-02"))
+	}
+
+	renamed, laterDOB := "Ally", time.Date(1992, 7, 1, 0, 0, 0, 0, time.UTC)
+	err = repo.Transact(ctx, func(tx repository.UserRepository) error {
+		err := tx.Transact(ctx, func(inner repository.UserRepository) error {
+			if _, err := inner.UpdatePartial(ctx, user.ID, &renamed, nil); err != nil {
+				return err
+			}
+			return boom
+		})
+		if !errors.Is(err, boom) {
+			return fmt.Errorf("nested Transact returned %v, want the error from fn", err)
+		}
+		_, err = tx.UpdatePartial(ctx, user.ID, nil, &laterDOB)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Transact after a failed nested call: %v", err)
+	}
+	got, err = repo.GetById(ctx, user.ID)
+	if err != nil || got.Name != name || !got.DOB.Equal(laterDOB) {
+		t.Errorf("after a failed nested call GetById = %+v, %v; want %s born %s", got, err, name, laterDOB.Format("2006-01-02"))
+	}
+	newDOB = laterDOB
+
+	if _, err := repo.UpdatePartial(ctx, user.ID+1000, &name, nil); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("UpdatePartial on a missing id: err = %v, want ErrNotFound", err)
+	}
+	if updated, err := repo.UpdatePartial(ctx, user.ID, nil, nil); err != nil || updated.Name != name || !updated.DOB.Equal(newDOB) {
+		t.Errorf("UpdatePartial with no changes = %+v, %v; want the row as it was", updated, err)
+	}
+}
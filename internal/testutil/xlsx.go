@@ -0,0 +1,79 @@
+package testutil
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+)
+
+// XLSXCell is one cell of a worksheet as stored: Type is "" for a number and
+// "inlineStr" for text, and Style indexes the workbook's cell formats.
+type XLSXCell struct {
+	Ref   string `xml:"r,attr"`
+	Type  string `xml:"t,attr"`
+	Style int    `xml:"s,attr"`
+	Value string `xml:"v"`
+	Text  string `xml:"is>t"`
+}
+
+// XLSXSheet is the first worksheet of an xlsx file.
+type XLSXSheet struct {
+	Rows [][]XLSXCell
+	// FrozenRows is how many rows at the top stay in view when scrolling.
+	FrozenRows int
+}
+
+// ReadXLSX opens file as an xlsx workbook and reads its first worksheet,
+// failing t when it is not one.
+func ReadXLSX(t testing.TB, file []byte) XLSXSheet {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(file), int64(len(file)))
+	if err != nil {
+		t.Fatalf("not a zip file: %v", err)
+	}
+	parts := map[string]bool{}
+	var sheet []byte
+	for _, f := range zr.File {
+		parts[f.Name] = true
+		if f.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sheet, err = io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml", "xl/worksheets/sheet1.xml"} {
+		if !parts[name] {
+			t.Fatalf("workbook lacks %s", name)
+		}
+	}
+
+	var doc struct {
+		Pane struct {
+			YSplit int    `xml:"ySplit,attr"`
+			State  string `xml:"state,attr"`
+		} `xml:"sheetViews>sheetView>pane"`
+		Rows []struct {
+			Cells []XLSXCell `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(sheet, &doc); err != nil {
+		t.Fatalf("worksheet is not XML: %v", err)
+	}
+	out := XLSXSheet{}
+	if doc.Pane.State == "frozen" {
+		out.FrozenRows = doc.Pane.YSplit
+	}
+	for _, row := range doc.Rows {
+		out.Rows = append(out.Rows, row.Cells)
+	}
+	return out
+}
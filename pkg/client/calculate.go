@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// Calculation is an age the server worked out without storing anything.
+type Calculation struct {
+	DOB                   string `json:"dob"`
+	AsOf                  string `json:"asOf"`
+	Age                   int    `json:"age"`
+	AgeValid              bool   `json:"ageValid"`
+	Years                 int    `json:"years"`
+	Months                int    `json:"months"`
+	Days                  int    `json:"days"`
+	NextBirthday          string `json:"nextBirthday"`
+	DaysUntilNextBirthday int    `json:"daysUntilNextBirthday"`
+	IsBirthday            bool   `json:"isBirthday"`
+}
+
+const calculateQuery = `query Calculate($dob: String!, $asOf: String) {
+	calculate(dob: $dob, asOf: $asOf) {
+		dob asOf age ageValid years months days nextBirthday daysUntilNextBirthday isBirthday
+	}
+}`
+
+// Calculate asks the server for the age of someone born on dob, as of asOf
+// or, when it is empty, the server's today. Both are dates written
+// YYYY-MM-DD; an invalid one is ErrValidation.
+func (c *Client) Calculate(ctx context.Context, dob, asOf string) (*Calculation, error) {
+	variables := map[string]any{"dob": dob}
+	if asOf != "" {
+		variables["asOf"] = asOf
+	}
+	var resp struct {
+		Data struct {
+			Calculate *Calculation `json:"calculate"`
+		} `json:"data"`
+		Errors []graphQLError `json:"errors"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/graphql", nil, map[string]any{"query": calculateQuery, "variables": variables}, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Errors) > 0 {
+		return nil, resp.Errors[0].err()
+	}
+	return resp.Data.Calculate, nil
+}
+
+type graphQLError struct {
+	Message    string `json:"message"`
+	Extensions struct {
+		Code    string       `json:"code"`
+		Details []FieldError `json:"details"`
+	} `json:"extensions"`
+}
+
+// graphQLStatus is the status the REST routes answer each code with, for
+// the codes a query here can meet.
+var graphQLStatus = map[string]int{
+	"INVALID_DATE":      http.StatusBadRequest,
+	"VALIDATION_FAILED": http.StatusBadRequest,
+	"USER_NOT_FOUND":    http.StatusNotFound,
+}
+
+// err is e as an *Error. GraphQL answers 200 whatever happened, so Status is
+// taken from the code instead, and is 500 for a code not known here.
+func (e graphQLError) err() *Error {
+	status, ok := graphQLStatus[e.Extensions.Code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	return &Error{Status: status, Code: e.Extensions.Code, Message: e.Message, Details: e.Extensions.Details}
+}
@@ -0,0 +1,261 @@
+// Package client is a Go client for the age calculator API. It speaks
+// /api/v1 and returns the server's own request and response models.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultTimeout    = 30 * time.Second
+	DefaultMaxRetries = 3
+	// maxBackoff bounds the wait between retries when the server does not
+	// send Retry-After.
+	maxBackoff = 10 * time.Second
+)
+
+// Client calls the API at one base URL. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	apiKey     string
+	tenant     string
+	http       *http.Client
+	maxRetries int
+	// wait sleeps between retries; tests replace it to skip the sleep.
+	wait func(ctx context.Context, d time.Duration) error
+}
+
+type Option func(*Client)
+
+// WithAPIKey sends key as X-API-Key on every request.
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithTenant sends id as X-Tenant-ID on every request.
+func WithTenant(id string) Option {
+	return func(c *Client) {
+		c.tenant = id
+	}
+}
+
+// WithTimeout bounds each attempt of a request, its body included. It
+// replaces DefaultTimeout.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.http.Timeout = d
+	}
+}
+
+// WithHTTPClient sends requests through hc instead of a client of its own;
+// WithTimeout then sets hc's timeout.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.http = hc
+	}
+}
+
+// WithMaxRetries is how many times a request answered 429 or 503 is sent
+// again; 0 turns retries off.
+func WithMaxRetries(n int) Option {
+	return func(c *Client) {
+		c.maxRetries = n
+	}
+}
+
+// New returns a client of the API at baseURL, such as http://localhost:8080.
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		http:       &http.Client{Timeout: DefaultTimeout},
+		maxRetries: DefaultMaxRetries,
+		wait:       sleep,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+var (
+	// ErrNotFound matches an *Error for a 404.
+	ErrNotFound = errors.New("not found")
+	// ErrValidation matches an *Error for a 400 or 422, whose Details name
+	// the rejected fields.
+	ErrValidation = errors.New("validation failed")
+)
+
+// Error is a response the server answered with an error status. Code is set
+// when the server sent one: it always does for /api/v2 and GraphQL, never
+// for /api/v1.
+type Error struct {
+	Status    int
+	Code      string
+	Message   string
+	Details   []FieldError
+	RequestID string
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%d %s: %s", e.Status, http.StatusText(e.Status), e.Message)
+	if len(e.Details) > 0 {
+		messages := make([]string, len(e.Details))
+		for i, detail := range e.Details {
+			messages[i] = detail.Message
+		}
+		msg += " (" + strings.Join(messages, "; ") + ")"
+	}
+	return msg
+}
+
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.Status == http.StatusNotFound
+	case ErrValidation:
+		return e.Status == http.StatusBadRequest || e.Status == http.StatusUnprocessableEntity
+	}
+	return false
+}
+
+// parseError reads either error body the server sends: v1's
+// {"error": message, "details": [...]}, or the structured
+// {"error": {"code", "message", "details", "request_id"}}.
+func parseError(status int, raw []byte) *Error {
+	apiErr := &Error{Status: status}
+	var payload struct {
+		Error   json.RawMessage `json:"error"`
+		Details []FieldError    `json:"details"`
+	}
+	if json.Unmarshal(raw, &payload) == nil && len(payload.Error) > 0 {
+		var structured struct {
+			Code      string       `json:"code"`
+			Message   string       `json:"message"`
+			Details   []FieldError `json:"details"`
+			RequestID string       `json:"request_id"`
+		}
+		if json.Unmarshal(payload.Error, &apiErr.Message) == nil {
+			apiErr.Details = payload.Details
+			return apiErr
+		}
+		if json.Unmarshal(payload.Error, &structured) == nil {
+			apiErr.Code = structured.Code
+			apiErr.Message = structured.Message
+			apiErr.Details = structured.Details
+			apiErr.RequestID = structured.RequestID
+			return apiErr
+		}
+	}
+	apiErr.Message = strings.TrimSpace(string(raw))
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(status)
+	}
+	return apiErr
+}
+
+// send makes the request, retrying it while the server answers 429 or 503,
+// and returns the response of the last attempt when it succeeded. Any error
+// status is returned as an *Error.
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body any) (*http.Response, error) {
+	var raw []byte
+	if body != nil {
+		var err error
+		if raw, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(raw))
+		if err != nil {
+			return nil, err
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		req.Header.Set("Accept", "application/json")
+		if c.apiKey != "" {
+			req.Header.Set("X-API-Key", c.apiKey)
+		}
+		if c.tenant != "" {
+			req.Header.Set("X-Tenant-ID", c.tenant)
+		}
+
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode < http.StatusBadRequest {
+			return resp, nil
+		}
+		errBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable
+		if !retryable || attempt >= c.maxRetries {
+			return nil, parseError(resp.StatusCode, errBody)
+		}
+		if err := c.wait(ctx, retryDelay(resp.Header.Get("Retry-After"), attempt, time.Now())); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// do sends the request and decodes a JSON response into out, unless out is
+// nil or the response has no body.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	resp, err := c.send(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if out == nil || len(raw) == 0 {
+		return nil
+	}
+	return json.Unmarshal(raw, out)
+}
+
+// retryDelay is what Retry-After asks for, in seconds or as a date, or else
+// a backoff that doubles with each attempt from half a second.
+func retryDelay(retryAfter string, attempt int, now time.Time) time.Duration {
+	if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(retryAfter); err == nil {
+		return max(at.Sub(now), 0)
+	}
+	return min(500*time.Millisecond<<attempt, maxBackoff)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
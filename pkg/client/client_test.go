@@ -0,0 +1,360 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/srinivasarynh/age_calculator/config"
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/handler"
+	"github.com/srinivasarynh/age_calculator/internal/middleware"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/routes"
+	"github.com/srinivasarynh/age_calculator/internal/service"
+	"go.uber.org/zap"
+)
+
+var testNow = time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+
+// newTestClient returns a client of the real handlers, over an empty
+// in-memory repository, on a clock fixed at testNow.
+func newTestClient(t *testing.T, opts ...Option) *Client {
+	t.Helper()
+	fixed := clock.Fixed(testNow)
+	svc := service.NewUserService(repository.NewMemoryUserRepository(fixed), zap.NewNop(), service.WithClock(fixed))
+	userHandler := handler.NewUserHandler(svc, zap.NewNop(), handler.WithClock(fixed))
+	tenant := middleware.Tenant(config.NewHolder(&config.Config{}))
+
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	routes.SetupRoutes(app, userHandler, middleware.AdminOnly(config.NewHolder(&config.Config{})), tenant, routes.CachePolicies{})
+	routes.SetupGraphQLRoutes(app, userHandler, tenant, routes.CachePolicies{})
+
+	srv := httptest.NewServer(adaptor.FiberApp(app))
+	t.Cleanup(srv.Close)
+	return New(srv.URL, opts...)
+}
+
+func mustCreate(t *testing.T, c *Client, name, dob string) *UserResponse {
+	t.Helper()
+	user, err := c.CreateUser(context.Background(), &CreateUserRequest{Name: name, DOB: dob})
+	if err != nil {
+		t.Fatalf("creating %s: %v", name, err)
+	}
+	return user
+}
+
+func TestUserLifecycle(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	created := mustCreate(t, c, "Alice", "1990-05-10")
+	if created.ID != 1 || created.Name != "Alice" || created.Age == nil || *created.Age != 35 {
+		t.Fatalf("created = %+v", created)
+	}
+
+	got, err := c.GetUser(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Alice" || got.DOB != "1990-05-10" {
+		t.Errorf("got = %+v", got)
+	}
+
+	updated, err := c.UpdateUser(ctx, created.ID, &UpdateUserRequest{Name: "Alicia", DOB: "1990-05-10", Timezone: "Europe/Paris"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Name != "Alicia" || updated.Timezone != "Europe/Paris" {
+		t.Errorf("updated = %+v", updated)
+	}
+
+	if err := c.DeleteUser(ctx, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.GetUser(ctx, created.ID)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("get after delete: err = %v, want ErrNotFound", err)
+	}
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusNotFound || apiErr.Message != "User not found" {
+		t.Errorf("err = %#v", err)
+	}
+	if errors.Is(err, ErrValidation) {
+		t.Error("a 404 is not ErrValidation")
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	c := newTestClient(t)
+
+	_, err := c.CreateUser(context.Background(), &CreateUserRequest{Name: "A", DOB: "1990-05-10"})
+	if !errors.Is(err, ErrValidation) {
+		t.Fatalf("err = %v, want ErrValidation", err)
+	}
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("err = %T, want *Error", err)
+	}
+	want := []FieldError{{Field: "name", Rule: "min", Param: "2", Message: "name must be at least 2 characters"}}
+	if apiErr.Status != http.StatusBadRequest || !reflect.DeepEqual(apiErr.Details, want) {
+		t.Errorf("err = %+v, want 400 with %+v", apiErr, want)
+	}
+	if got := err.Error(); got != "400 Bad Request: Validation failed (name must be at least 2 characters)" {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func TestListUsersOptions(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+	mustCreate(t, c, "Alice", "1990-05-10")
+	mustCreate(t, c, "Bob", "2000-02-29")
+	mustCreate(t, c, "Carol", "2025-01-01")
+
+	tests := []struct {
+		name  string
+		opts  []ListOption
+		want  []string
+		total bool
+	}{
+		{name: "defaults", want: []string{"Alice", "Bob", "Carol"}, total: true},
+		{name: "paged", opts: []ListOption{WithPage(2), WithPageSize(2)}, want: []string{"Carol"}, total: true},
+		{name: "by age", opts: []ListOption{WithAgeRange(20, 40), WithSort("-age")}, want: []string{"Alice", "Bob"}, total: true},
+		{name: "by dob", opts: []ListOption{WithDOBRange("2000-01-01", "")}, want: []string{"Bob", "Carol"}, total: true},
+		{name: "by name", opts: []ListOption{WithName("bob"), WithoutTotal()}, want: []string{"Bob"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := c.ListUsers(ctx, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, user := range list.Users {
+				names = append(names, user.Name)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("names = %v, want %v", names, tt.want)
+			}
+			if (list.Total != nil) != tt.total {
+				t.Errorf("total = %v, want present %v", list.Total, tt.total)
+			}
+		})
+	}
+
+	list, err := c.ListUsers(ctx, WithoutAge())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Users[0].Age != nil {
+		t.Errorf("WithoutAge: age = %d", *list.Users[0].Age)
+	}
+
+	if _, err := c.ListUsers(ctx, WithPageSize(1000)); !errors.Is(err, ErrValidation) {
+		t.Errorf("page size 1000: err = %v, want ErrValidation", err)
+	}
+}
+
+func TestCalculate(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	got, err := c.Calculate(ctx, "2000-02-29", "2025-06-15")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Calculation{DOB: "2000-02-29", AsOf: "2025-06-15", Age: 25, AgeValid: true, Years: 25, Months: 3, Days: 17, NextBirthday: "2026-03-01", DaysUntilNextBirthday: 259}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Calculate = %+v, want %+v", got, want)
+	}
+
+	if got, err := c.Calculate(ctx, "1990-06-15", ""); err != nil || got.AsOf != "2025-06-15" || !got.IsBirthday {
+		t.Errorf("as of the server's today: %+v, %v", got, err)
+	}
+
+	_, err = c.Calculate(ctx, "1990-13-01", "")
+	var apiErr *Error
+	if !errors.Is(err, ErrValidation) || !errors.As(err, &apiErr) || apiErr.Code != "INVALID_DATE" {
+		t.Errorf("invalid dob: err = %#v, want ErrValidation with INVALID_DATE", err)
+	}
+}
+
+func TestExport(t *testing.T) {
+	c := newTestClient(t)
+	mustCreate(t, c, "Alice", "1990-05-10")
+	mustCreate(t, c, "Bob", "2000-02-29")
+
+	var out bytes.Buffer
+	n, err := c.Export(context.Background(), &ExportUsersRequest{Name: "bob"}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "id,name,dob,age\n2,Bob,2000-02-29,25\n"; out.String() != want || n != int64(len(want)) {
+		t.Errorf("export = %d bytes %q, want %q", n, out.String(), want)
+	}
+
+	if _, err := c.Export(context.Background(), &ExportUsersRequest{Format: "xml"}, &out); !errors.Is(err, ErrValidation) {
+		t.Errorf("unknown format: err = %v, want ErrValidation", err)
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.Write([]byte(`{"id":1,"name":"Alice"}`))
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL+"/", WithAPIKey("secret"), WithTenant("acme"))
+	if _, err := c.GetUser(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if got.Get("X-API-Key") != "secret" || got.Get("X-Tenant-ID") != "acme" || got.Get("Accept") != "application/json" {
+		t.Errorf("headers = %v", got)
+	}
+}
+
+// retryServer answers each request with the next of statuses, and 200 once
+// they run out.
+func retryServer(t *testing.T, headers []string, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := int(calls.Add(1)) - 1
+		if i >= len(statuses) {
+			w.Write([]byte(`{"id":1,"name":"Alice"}`))
+			return
+		}
+		if i < len(headers) && headers[i] != "" {
+			w.Header().Set("Retry-After", headers[i])
+		}
+		w.WriteHeader(statuses[i])
+		w.Write([]byte(`{"error":"slow down"}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestRetries(t *testing.T) {
+	later := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	tests := []struct {
+		name     string
+		headers  []string
+		statuses []int
+		retries  int
+		calls    int32
+		status   int
+		// firstWait bounds the wait before the first retry, when set.
+		firstWait [2]time.Duration
+	}{
+		{name: "429 then success", headers: []string{"2"}, statuses: []int{429}, retries: 3, calls: 2, firstWait: [2]time.Duration{2 * time.Second, 2 * time.Second}},
+		{name: "503 then success", headers: []string{later}, statuses: []int{503}, retries: 3, calls: 2, firstWait: [2]time.Duration{59 * time.Minute, time.Hour}},
+		{name: "gives up", statuses: []int{503, 503, 503}, retries: 2, calls: 3, status: 503},
+		{name: "retries off", statuses: []int{429}, retries: 0, calls: 1, status: 429},
+		{name: "500 not retried", statuses: []int{500}, retries: 3, calls: 1, status: 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, calls := retryServer(t, tt.headers, tt.statuses...)
+			c := New(srv.URL, WithMaxRetries(tt.retries))
+			var waits []time.Duration
+			c.wait = func(ctx context.Context, d time.Duration) error {
+				waits = append(waits, d)
+				return nil
+			}
+
+			_, err := c.CreateUser(context.Background(), &CreateUserRequest{Name: "Alice", DOB: "1990-05-10"})
+			if got := calls.Load(); got != tt.calls {
+				t.Errorf("calls = %d, want %d", got, tt.calls)
+			}
+			if len(waits) != int(tt.calls)-1 {
+				t.Errorf("waits = %v, want %d", waits, tt.calls-1)
+			}
+			var apiErr *Error
+			switch {
+			case tt.status == 0 && err != nil:
+				t.Errorf("err = %v, want success", err)
+			case tt.status != 0 && (!errors.As(err, &apiErr) || apiErr.Status != tt.status || apiErr.Message != "slow down"):
+				t.Errorf("err = %v, want %d slow down", err, tt.status)
+			}
+			if bounds := tt.firstWait; bounds[1] != 0 && len(waits) > 0 && (waits[0] < bounds[0] || waits[0] > bounds[1]) {
+				t.Errorf("first wait = %v, want within %v", waits[0], bounds)
+			}
+		})
+	}
+}
+
+func TestRetryStopsWithContext(t *testing.T) {
+	srv, calls := retryServer(t, []string{"60"}, 429)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := New(srv.URL).GetUser(ctx, 1)
+	if !errors.Is(err, context.DeadlineExceeded) || calls.Load() != 1 {
+		t.Errorf("err = %v after %d calls, want the deadline after 1", err, calls.Load())
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header  string
+		attempt int
+		want    time.Duration
+	}{
+		{header: "3", want: 3 * time.Second},
+		{header: "0", want: 0},
+		{header: "Sun, 15 Jun 2025 12:00:30 GMT", want: 30 * time.Second},
+		{header: "Sun, 15 Jun 2025 11:00:00 GMT", want: 0},
+		{header: "", attempt: 0, want: 500 * time.Millisecond},
+		{header: "soon", attempt: 2, want: 2 * time.Second},
+		{header: "", attempt: 10, want: maxBackoff},
+	}
+	for _, tt := range tests {
+		if got := retryDelay(tt.header, tt.attempt, now); got != tt.want {
+			t.Errorf("retryDelay(%q, %d) = %v, want %v", tt.header, tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want *Error
+	}{
+		{
+			name: "v1",
+			body: `{"error":"Invalid pagination parameters","details":[{"field":"page","rule":"min","param":"1","message":"page must be at least 1"}]}`,
+			want: &Error{Status: 400, Message: "Invalid pagination parameters", Details: []FieldError{{Field: "page", Rule: "min", Param: "1", Message: "page must be at least 1"}}},
+		},
+		{
+			name: "structured",
+			body: `{"error":{"code":"VALIDATION_FAILED","message":"Validation failed","details":[{"field":"name","rule":"required","message":"name is required"}],"request_id":"req-1"}}`,
+			want: &Error{Status: 400, Code: "VALIDATION_FAILED", Message: "Validation failed", Details: []FieldError{{Field: "name", Rule: "required", Message: "name is required"}}, RequestID: "req-1"},
+		},
+		{name: "plain text", body: "upstream timed out\n", want: &Error{Status: 400, Message: "upstream timed out"}},
+		{name: "empty", body: "", want: &Error{Status: 400, Message: "Bad Request"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseError(400, []byte(tt.body)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseError = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	if !strings.HasPrefix((&Error{Status: 503, Message: "x"}).Error(), "503 Service Unavailable: x") {
+		t.Error("Error() does not lead with the status")
+	}
+}
@@ -0,0 +1,16 @@
+package client
+
+import "github.com/srinivasarynh/age_calculator/internal/models"
+
+// The request and response types are the server's own, aliased here because
+// code outside this module cannot import them from internal/.
+type (
+	CreateUserRequest  = models.CreateUserRequest
+	UpdateUserRequest  = models.UpdateUserRequest
+	ExportUsersRequest = models.ExportUsersRequest
+	UserResponse       = models.UserResponse
+	UserListResponse   = models.UserListResponse
+	RoundBirthday      = models.RoundBirthday
+	AccessCount        = models.AccessCount
+	FieldError         = models.FieldError
+)
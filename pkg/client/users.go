@@ -0,0 +1,145 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const usersPath = "/api/v1/users"
+
+func userPath(id int32) string {
+	return usersPath + "/" + strconv.Itoa(int(id))
+}
+
+func (c *Client) CreateUser(ctx context.Context, req *CreateUserRequest) (*UserResponse, error) {
+	var user UserResponse
+	if err := c.do(ctx, http.MethodPost, usersPath, nil, req, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (c *Client) GetUser(ctx context.Context, id int32) (*UserResponse, error) {
+	var user UserResponse
+	if err := c.do(ctx, http.MethodGet, userPath(id), nil, nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// UpdateUser replaces the user, so an omitted timezone or locale is cleared.
+func (c *Client) UpdateUser(ctx context.Context, id int32, req *UpdateUserRequest) (*UserResponse, error) {
+	var user UserResponse
+	if err := c.do(ctx, http.MethodPut, userPath(id), nil, req, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (c *Client) DeleteUser(ctx context.Context, id int32) error {
+	return c.do(ctx, http.MethodDelete, userPath(id), nil, nil, nil)
+}
+
+// ListOption sets a query parameter of ListUsers.
+type ListOption func(url.Values)
+
+func WithPage(page int) ListOption {
+	return func(q url.Values) { q.Set("page", strconv.Itoa(page)) }
+}
+
+func WithPageSize(size int) ListOption {
+	return func(q url.Values) { q.Set("page_size", strconv.Itoa(size)) }
+}
+
+// WithSort orders the list by id, created_at or age, descending with a
+// leading "-".
+func WithSort(sort string) ListOption {
+	return func(q url.Values) { q.Set("sort", sort) }
+}
+
+// WithCursor continues a list from the NextCursor of its previous page.
+func WithCursor(cursor string) ListOption {
+	return func(q url.Values) { q.Set("cursor", cursor) }
+}
+
+func WithName(name string) ListOption {
+	return func(q url.Values) { q.Set("name", name) }
+}
+
+// WithNameFuzzy ranks users by how close their name is to name.
+func WithNameFuzzy(name string) ListOption {
+	return func(q url.Values) { q.Set("name_fuzzy", name) }
+}
+
+// WithDOBRange bounds the DOB, inclusively, by dates written YYYY-MM-DD;
+// an empty bound is left open.
+func WithDOBRange(from, to string) ListOption {
+	return func(q url.Values) {
+		if from != "" {
+			q.Set("dob_from", from)
+		}
+		if to != "" {
+			q.Set("dob_to", to)
+		}
+	}
+}
+
+// WithAgeRange bounds the age as of today, inclusively.
+func WithAgeRange(minAge, maxAge int) ListOption {
+	return func(q url.Values) {
+		q.Set("min_age", strconv.Itoa(minAge))
+		q.Set("max_age", strconv.Itoa(maxAge))
+	}
+}
+
+// WithoutTotal skips counting the users, leaving Total and TotalPages nil.
+func WithoutTotal() ListOption {
+	return func(q url.Values) { q.Set("include_total", "false") }
+}
+
+// WithoutAge leaves the age out of every user.
+func WithoutAge() ListOption {
+	return func(q url.Values) { q.Set("include_age", "false") }
+}
+
+func (c *Client) ListUsers(ctx context.Context, opts ...ListOption) (*UserListResponse, error) {
+	query := url.Values{}
+	for _, opt := range opts {
+		opt(query)
+	}
+	var list UserListResponse
+	if err := c.do(ctx, http.MethodGet, usersPath, query, nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// Export streams every user req selects to w in req's format, csv unless it
+// names another, and returns the number of bytes written. A failure once the
+// server has started sending leaves w holding a truncated file.
+func (c *Client) Export(ctx context.Context, req *ExportUsersRequest, w io.Writer) (int64, error) {
+	query := url.Values{}
+	if req.Format != "" {
+		query.Set("format", req.Format)
+	}
+	if req.Name != "" {
+		query.Set("name", req.Name)
+	}
+	if req.Version != 0 {
+		query.Set("version", strconv.Itoa(req.Version))
+	}
+	resp, err := c.send(ctx, http.MethodGet, usersPath+"/export", query, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	n, err := io.Copy(w, resp.Body)
+	if err != nil {
+		return n, fmt.Errorf("export interrupted after %d bytes: %w", n, err)
+	}
+	return n, nil
+}
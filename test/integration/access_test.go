@@ -0,0 +1,41 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+	"github.com/srinivasarynh/age_calculator/internal/testutil"
+	"go.uber.org/zap"
+)
+
+func TestSQLAccessRepository(t *testing.T) {
+	resetDatabase(t)
+	testutil.AssertAccessRepository(t, repository.NewAccessRepository(testDB, zap.NewNop()), seedUser(t, "Alice", "1990-06-15"))
+}
+
+func TestSQLAccessRepositorySkipsDeletedUsers(t *testing.T) {
+	resetDatabase(t)
+	ctx := context.Background()
+	alice := seedUser(t, "Alice", "1990-06-15")
+	bob := seedUser(t, "Bob", "1990-06-16")
+	if _, err := testDB.Exec(`DELETE FROM users WHERE id = $1`, bob); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := repository.NewAccessRepository(testDB, zap.NewNop())
+	at := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	if err := repo.Add(ctx, []repository.AccessIncrement{
+		{TenantID: tenant.Default, UserID: alice, Count: 1, LastAccessedAt: at},
+		{TenantID: tenant.Default, UserID: bob, Count: 1, LastAccessedAt: at},
+	}); err != nil {
+		t.Fatalf("Add with a deleted user = %v", err)
+	}
+	if count, err := repo.Get(ctx, alice); err != nil || count.Total != 1 {
+		t.Errorf("Get = %+v, %v; want 1 access", count, err)
+	}
+}
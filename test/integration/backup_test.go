@@ -0,0 +1,90 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/service"
+	"go.uber.org/zap"
+)
+
+func TestBackupRestoreRoundTrip(t *testing.T) {
+	resetDatabase(t)
+	ctx := context.Background()
+	alice := seedUser(t, "Alice", "1990-06-15")
+	bob := seedUser(t, "Bob", "1985-01-02")
+	if _, err := testDB.Exec(`UPDATE users SET timezone = 'Europe/Paris', locale = 'fr' WHERE id = $1`, alice); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := testDB.Exec(`UPDATE users SET name = 'Anonymized user', anonymized_at = NOW() WHERE id = $1`, bob); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repository.NewNotificationRepository(testDB, zap.NewNop()).MarkNotified(ctx, alice, 2025, time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	shares := repository.NewShareRepository(testDB, zap.NewNop())
+	share, err := shares.Create(ctx, alice, []byte("hash"), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backups := service.NewBackups(repository.NewBackupRepository(testDB, zap.NewNop()), 16, zap.NewNop())
+	backup := func() models.Backup {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := backups.Write(ctx, &buf); err != nil {
+			t.Fatal(err)
+		}
+		var b models.Backup
+		if err := json.Unmarshal(buf.Bytes(), &b); err != nil {
+			t.Fatalf("backup is not JSON: %v", err)
+		}
+		b.ExportedAt = time.Time{}
+		return b
+	}
+	before := backup()
+	if len(before.Users) != 2 || len(before.BirthdayNotifications) != 1 || len(before.ShareTokens) != 1 {
+		t.Fatalf("backup = %+v, want 2 users, a marker and a share", before)
+	}
+	raw, err := json.Marshal(before)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := backups.Restore(ctx, bytes.NewReader(raw), false); !errors.Is(err, repository.ErrNotEmpty) {
+		t.Fatalf("Restore into the users it came from: err = %v, want ErrNotEmpty", err)
+	}
+
+	resetDatabase(t)
+	if _, err := backups.Restore(ctx, bytes.NewReader(raw), false); err != nil {
+		t.Fatalf("Restore = %v", err)
+	}
+	if after := backup(); !reflect.DeepEqual(after, before) {
+		t.Errorf("backup of the restore = %+v, want %+v", after, before)
+	}
+
+	// New rows continue past the restored ids.
+	if carol := seedUser(t, "Carol", "2000-03-04"); carol <= bob {
+		t.Errorf("new user id = %d, want past the restored %d", carol, bob)
+	}
+	next, err := shares.Create(ctx, bob, []byte("other"), time.Now().Add(time.Hour))
+	if err != nil || next.ID <= share.ID {
+		t.Errorf("new share = %+v, %v; want an id past %d", next, err, share.ID)
+	}
+
+	if _, err := backups.Restore(ctx, bytes.NewReader(raw), true); err != nil {
+		t.Fatalf("Restore with truncate = %v", err)
+	}
+	if after := backup(); !reflect.DeepEqual(after, before) {
+		t.Errorf("backup after truncate = %+v, want %+v", after, before)
+	}
+}
@@ -0,0 +1,91 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/dblock"
+)
+
+// heldAdvisoryLocks counts the advisory locks any session holds.
+func heldAdvisoryLocks(t *testing.T) int {
+	t.Helper()
+	var n int
+	if err := testDB.QueryRow(`SELECT COUNT(*) FROM pg_locks WHERE locktype = 'advisory' AND granted`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestDBLockContention(t *testing.T) {
+	ctx := context.Background()
+	first, second := dblock.New(testDB), dblock.New(testDB)
+
+	lock, err := first.AcquireTryLock(ctx, "contended")
+	if err != nil || lock == nil {
+		t.Fatalf("first AcquireTryLock = %v, %v", lock, err)
+	}
+	if other, err := second.AcquireTryLock(ctx, "contended"); err != nil || other != nil {
+		t.Fatalf("second AcquireTryLock while held = %v, %v; want nil, nil", other, err)
+	}
+	ran, err := second.WithLock(ctx, "contended", func(ctx context.Context) error {
+		t.Error("fn ran while the lock was held elsewhere")
+		return nil
+	})
+	if ran || err != nil {
+		t.Errorf("WithLock while held = %v, %v; want false, nil", ran, err)
+	}
+	if other, err := second.AcquireTryLock(ctx, "another key"); err != nil || other == nil {
+		t.Errorf("AcquireTryLock of another key = %v, %v", other, err)
+	} else if err := other.Release(); err != nil {
+		t.Error(err)
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if err := lock.Release(); !errors.Is(err, dblock.ErrNotHeld) {
+		t.Errorf("second Release = %v, want ErrNotHeld", err)
+	}
+	ran, err = second.WithLock(ctx, "contended", func(ctx context.Context) error { return nil })
+	if !ran || err != nil {
+		t.Errorf("WithLock after release = %v, %v; want true, nil", ran, err)
+	}
+	if n := heldAdvisoryLocks(t); n != 0 {
+		t.Errorf("%d advisory locks still held after every release", n)
+	}
+}
+
+func TestDBLockReleasedWithContext(t *testing.T) {
+	first, second := dblock.New(testDB), dblock.New(testDB)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	lock, err := first.AcquireTryLock(ctx, "cancelled")
+	if err != nil || lock == nil {
+		t.Fatalf("AcquireTryLock = %v, %v", lock, err)
+	}
+	cancel()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		other, err := second.AcquireTryLock(context.Background(), "cancelled")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if other != nil {
+			other.Release()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("lock still held after its context was cancelled")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := lock.Release(); !errors.Is(err, dblock.ErrNotHeld) {
+		t.Errorf("Release after the context ended = %v, want ErrNotHeld", err)
+	}
+}
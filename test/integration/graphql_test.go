@@ -0,0 +1,63 @@
+//go:build integration
+
+package integration
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestGraphQLQueryWithVariables(t *testing.T) {
+	resetDatabase(t)
+	alice := seedUser(t, "Alice", "1990-05-10")
+	seedUser(t, "Bob", "2000-02-29")
+
+	type user struct {
+		ID   int32  `json:"id"`
+		Name string `json:"name"`
+		DOB  string `json:"dob"`
+		Age  *int   `json:"age"`
+	}
+	var resp struct {
+		Data struct {
+			User  *user `json:"user"`
+			Users struct {
+				Total *int   `json:"total"`
+				Users []user `json:"users"`
+			} `json:"users"`
+		} `json:"data"`
+		Errors []struct {
+			Message    string         `json:"message"`
+			Extensions map[string]any `json:"extensions"`
+		} `json:"errors"`
+	}
+	query := map[string]any{
+		"query": `query Lookup($id: Int!, $filter: UserFilter) {
+			user(id: $id) { id name dob age }
+			users(filter: $filter) { total users { name } }
+		}`,
+		"variables": map[string]any{"id": alice, "filter": map[string]any{"name": "bob"}},
+	}
+	if status := call(t, http.MethodPost, "/graphql", query, &resp); status != http.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	if len(resp.Errors) > 0 {
+		t.Fatalf("errors = %+v", resp.Errors)
+	}
+	if got := resp.Data.User; got == nil || got.ID != alice || got.Name != "Alice" || got.DOB != "1990-05-10" || got.Age == nil {
+		t.Errorf("user = %+v", got)
+	}
+	if got := resp.Data.Users; got.Total == nil || *got.Total != 1 || !reflect.DeepEqual(got.Users, []user{{Name: "Bob"}}) {
+		t.Errorf("users = %+v, want only Bob", got)
+	}
+
+	resp.Errors = nil
+	query["variables"] = map[string]any{"id": alice + 100}
+	if status := call(t, http.MethodPost, "/graphql", query, &resp); status != http.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	if len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != "USER_NOT_FOUND" {
+		t.Errorf("errors = %+v, want USER_NOT_FOUND", resp.Errors)
+	}
+}
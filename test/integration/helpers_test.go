@@ -0,0 +1,66 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/testutil"
+	"go.uber.org/zap"
+)
+
+func resetDatabase(t *testing.T) {
+	t.Helper()
+	if _, err := testDB.Exec(`TRUNCATE users RESTART IDENTITY CASCADE`); err != nil {
+		t.Fatalf("truncating users: %v", err)
+	}
+	if _, err := testDB.Exec(`TRUNCATE events_outbox RESTART IDENTITY`); err != nil {
+		t.Fatalf("truncating events_outbox: %v", err)
+	}
+}
+
+func seedUser(t *testing.T, name, dob string) int32 {
+	t.Helper()
+	repo := repository.NewUserRepository(testDB, zap.NewNop())
+	user := testutil.NewUserBuilder().WithName(name).WithDOB(dob).Build()
+	return testutil.MustInsert(t, repo, user)[0].ID
+}
+
+func call(t *testing.T, method, path string, body any, out any) int {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(raw)
+	}
+
+	req, err := http.NewRequest(method, baseURL+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("decoding %s %s response: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
@@ -0,0 +1,151 @@
+//go:build integration
+
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/testutil"
+	"go.uber.org/zap"
+)
+
+func resetJobs(t *testing.T) {
+	t.Helper()
+	if _, err := testDB.Exec(`TRUNCATE jobs RESTART IDENTITY`); err != nil {
+		t.Fatalf("truncating jobs: %v", err)
+	}
+}
+
+func TestSQLJobRepository(t *testing.T) {
+	resetJobs(t)
+	testutil.AssertJobRepository(t, repository.NewJobRepository(testDB, zap.NewNop()))
+}
+
+// waitForJob polls until job has finished.
+func waitForJob(t *testing.T, job *models.JobResponse) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for job.State != models.JobDone && job.State != models.JobFailed {
+		if time.Now().After(deadline) {
+			t.Fatalf("job still %s after 10s", job.State)
+		}
+		time.Sleep(50 * time.Millisecond)
+		if status := call(t, "GET", fmt.Sprintf("/api/v1/jobs/%d", job.ID), nil, job); status != http.StatusOK {
+			t.Fatalf("GET job status = %d", status)
+		}
+	}
+}
+
+func TestAsyncImport(t *testing.T) {
+	resetDatabase(t)
+	resetJobs(t)
+
+	csv := "name,dob\nAlice,1990-05-10\nB,1985-01-01\nCarol,not-a-date\nDave,2000-02-29\n"
+	resp, err := http.Post(baseURL+"/api/v1/users/import?async=true", "text/csv", strings.NewReader(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var job models.JobResponse
+	err = json.NewDecoder(resp.Body).Decode(&job)
+	resp.Body.Close()
+	if err != nil || resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("submit = %d, %v", resp.StatusCode, err)
+	}
+	if want := fmt.Sprintf("/api/v1/jobs/%d", job.ID); resp.Header.Get("Location") != want {
+		t.Errorf("Location = %q, want %q", resp.Header.Get("Location"), want)
+	}
+
+	waitForJob(t, &job)
+
+	var report models.ImportReport
+	if err := json.Unmarshal(job.Report, &report); err != nil {
+		t.Fatal(err)
+	}
+	if job.State != models.JobDone || job.RowsProcessed != 4 || report.Created != 2 || report.Failed != 2 {
+		t.Errorf("job = %+v, report = %+v", job, report)
+	}
+	var list models.UserListResponse
+	if call(t, "GET", "/api/v1/users", nil, &list); *list.Total != 2 {
+		t.Errorf("users after import = %d, want 2", *list.Total)
+	}
+}
+
+func TestImportConflictStrategies(t *testing.T) {
+	csv := "name,dob\nALICE,1990-05-10\nBob,2001-01-01\nCarol,1985-03-03\n"
+	tests := []struct {
+		query  string
+		status int
+		want   models.ImportReport
+		users  int64
+	}{
+		{query: "conflict=skip", status: http.StatusOK, want: models.ImportReport{Rows: 3, Created: 2, Skipped: 1}, users: 4},
+		{query: "conflict=update", status: http.StatusOK, want: models.ImportReport{Rows: 3, Created: 2, Updated: 1}, users: 4},
+		{query: "conflict=fail", status: http.StatusOK, want: models.ImportReport{Rows: 3, Created: 2, Failed: 1}, users: 4},
+		{query: "conflict=fail&atomic=true", status: http.StatusUnprocessableEntity, want: models.ImportReport{Rows: 3, Failed: 1, RolledBack: true}, users: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			resetDatabase(t)
+			seedUser(t, "Alice", "1990-05-10")
+			seedUser(t, "Bob", "2000-02-29")
+
+			resp, err := http.Post(baseURL+"/api/v1/users/import?"+tt.query, "text/csv", strings.NewReader(csv))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var report models.ImportReport
+			err = json.NewDecoder(resp.Body).Decode(&report)
+			resp.Body.Close()
+			if err != nil || resp.StatusCode != tt.status {
+				t.Fatalf("import = %d, %v; want %d", resp.StatusCode, err, tt.status)
+			}
+			report.Errors = nil
+			if !reflect.DeepEqual(report, tt.want) {
+				t.Errorf("report = %+v, want %+v", report, tt.want)
+			}
+			var list models.UserListResponse
+			if call(t, "GET", "/api/v1/users", nil, &list); *list.Total != tt.users {
+				t.Errorf("users after import = %d, want %d", *list.Total, tt.users)
+			}
+		})
+	}
+}
+
+func TestAsyncExport(t *testing.T) {
+	resetDatabase(t)
+	resetJobs(t)
+	seedUser(t, "Alice", "1990-05-10")
+	seedUser(t, "Bob", "1985-01-01")
+	seedUser(t, "Alicia", "2000-02-29")
+
+	var job models.JobResponse
+	if status := call(t, "POST", "/api/v1/users/export/jobs", models.ExportUsersRequest{Name: "ali"}, &job); status != http.StatusAccepted {
+		t.Fatalf("submit = %d", status)
+	}
+	waitForJob(t, &job)
+	if job.State != models.JobDone || job.RowsProcessed != 2 {
+		t.Fatalf("job = %+v", job)
+	}
+
+	resp, err := http.Get(fmt.Sprintf("%s/api/v1/jobs/%d/download", baseURL, job.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/csv" {
+		t.Fatalf("download = %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if lines := strings.Split(strings.TrimSpace(string(body)), "\n"); len(lines) != 3 || lines[0] != "id,name,dob,age" {
+		t.Errorf("download body = %q", body)
+	}
+}
@@ -0,0 +1,119 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/srinivasarynh/age_calculator/config"
+	"github.com/srinivasarynh/age_calculator/internal/metrics"
+	"github.com/srinivasarynh/age_calculator/internal/server"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"go.uber.org/zap"
+)
+
+var (
+	testDB  *sql.DB
+	testDSN string
+	baseURL string
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	ctx := context.Background()
+
+	container, err := postgres.Run(ctx, "postgres:15-alpine",
+		postgres.WithDatabase("userdb"),
+		postgres.WithUsername("postgres"),
+		postgres.WithPassword("postgres"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(60*time.Second),
+		),
+	)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "starting postgres: %v\n", err)
+		return 1
+	}
+	defer testcontainers.TerminateContainer(container)
+
+	dsn, err := container.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "connection string: %v\n", err)
+		return 1
+	}
+
+	testDSN = dsn
+	testDB, err = sql.Open("postgres", dsn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "opening database: %v\n", err)
+		return 1
+	}
+	defer testDB.Close()
+
+	if err := applyMigrations(testDB); err != nil {
+		fmt.Fprintf(os.Stderr, "applying migrations: %v\n", err)
+		return 1
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "loading config: %v\n", err)
+		return 1
+	}
+
+	app, jobRunner, _, _, _ := server.Build(config.NewHolder(cfg), testDB, metrics.NewRegistry(), zap.NewNop(), nil)
+	if err := jobRunner.Start(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "starting job workers: %v\n", err)
+		return 1
+	}
+	defer jobRunner.Stop(ctx)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "listening: %v\n", err)
+		return 1
+	}
+	go app.Listener(ln)
+	defer app.Shutdown()
+	baseURL = "http://" + ln.Addr().String()
+
+	return m.Run()
+}
+
+func applyMigrations(db *sql.DB) error {
+	files, err := filepath.Glob(filepath.Join("..", "..", "db", "migrations", "*.up.sql"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		contents, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(string(contents)) == "" {
+			continue
+		}
+		if _, err := db.Exec(string(contents)); err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(file), err)
+		}
+	}
+	return nil
+}
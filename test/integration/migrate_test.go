@@ -0,0 +1,306 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/db"
+	"github.com/srinivasarynh/age_calculator/internal/migrate"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/service"
+	"go.uber.org/zap"
+)
+
+// freshDatabase creates an empty database next to the shared one, so the
+// migrator starts from nothing.
+func freshDatabase(t *testing.T, name string) *sql.DB {
+	t.Helper()
+	if _, err := testDB.Exec(fmt.Sprintf(`DROP DATABASE IF EXISTS %s`, name)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := testDB.Exec(fmt.Sprintf(`CREATE DATABASE %s`, name)); err != nil {
+		t.Fatal(err)
+	}
+
+	dsn, err := url.Parse(testDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dsn.Path = "/" + name
+	conn, err := sql.Open("postgres", dsn.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+type migrationRow struct {
+	Version  int64
+	Dirty    bool
+	Checksum string
+}
+
+func migrationRows(t *testing.T, conn *sql.DB) []migrationRow {
+	t.Helper()
+	rows, err := conn.Query(`SELECT version, dirty, checksum FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var out []migrationRow
+	for rows.Next() {
+		var r migrationRow
+		if err := rows.Scan(&r.Version, &r.Dirty, &r.Checksum); err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+func cleanRows(migrations []migrate.Migration) []migrationRow {
+	rows := make([]migrationRow, len(migrations))
+	for i, m := range migrations {
+		rows[i] = migrationRow{Version: m.Version, Checksum: m.Checksum}
+	}
+	return rows
+}
+
+func TestMigrateUpDownStatus(t *testing.T) {
+	ctx := context.Background()
+	conn := freshDatabase(t, "migrate_up_down")
+	migrations, err := migrate.Load(db.Migrations, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := migrate.New(conn, migrations)
+	n := len(migrations)
+
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != n {
+		t.Errorf("applied %v, want all %d migrations", applied, n)
+	}
+	if got := migrationRows(t, conn); !reflect.DeepEqual(got, cleanRows(migrations)) {
+		t.Errorf("schema_migrations = %+v, want %+v", got, cleanRows(migrations))
+	}
+	if applied, err := m.Up(ctx); err != nil || len(applied) != 0 {
+		t.Errorf("second up applied %v, %v; want nothing", applied, err)
+	}
+
+	reverted, err := m.Down(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int64{migrations[n-1].Version, migrations[n-2].Version}; !reflect.DeepEqual(reverted, want) {
+		t.Errorf("reverted %v, want %v", reverted, want)
+	}
+	if got := migrationRows(t, conn); !reflect.DeepEqual(got, cleanRows(migrations[:n-2])) {
+		t.Errorf("after down 2, schema_migrations = %+v", got)
+	}
+	var outboxTables int
+	if err := conn.QueryRow(`SELECT COUNT(*) FROM information_schema.tables WHERE table_name = 'events_outbox'`).Scan(&outboxTables); err != nil || outboxTables != 0 {
+		t.Errorf("table from the second to last migration still present (err %v)", err)
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, s := range statuses {
+		if s.Applied != (i < n-2) || s.Checksum != migrations[i].Checksum {
+			t.Errorf("status %d = %+v", i, s)
+		}
+	}
+
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := migrationRows(t, conn); !reflect.DeepEqual(got, cleanRows(migrations)) {
+		t.Errorf("after re-applying, schema_migrations = %+v", got)
+	}
+}
+
+func TestMigrateDetectsChecksumMismatch(t *testing.T) {
+	ctx := context.Background()
+	conn := freshDatabase(t, "migrate_checksum")
+	migrations, err := migrate.Load(db.Migrations, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := migrate.New(conn, migrations)
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := conn.Exec(`UPDATE schema_migrations SET checksum = 'edited' WHERE version = 1`); err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !statuses[0].Mismatch() || statuses[0].Recorded != "edited" {
+		t.Errorf("status for version 1 = %+v, want a mismatch", statuses[0])
+	}
+	if _, err := m.Up(ctx); !errors.Is(err, migrate.ErrChecksumMismatch) {
+		t.Errorf("up with an edited migration: err = %v, want ErrChecksumMismatch", err)
+	}
+	if _, err := m.Down(ctx, 1); !errors.Is(err, migrate.ErrChecksumMismatch) {
+		t.Errorf("down with an edited migration: err = %v, want ErrChecksumMismatch", err)
+	}
+
+	last := migrations[len(migrations)-1].Version
+	if err := m.Force(ctx, last); err != nil {
+		t.Fatal(err)
+	}
+	if got := migrationRows(t, conn); !reflect.DeepEqual(got, cleanRows(migrations)) {
+		t.Errorf("after force, schema_migrations = %+v", got)
+	}
+}
+
+func TestMigrateFailureLeavesDirty(t *testing.T) {
+	ctx := context.Background()
+	conn := freshDatabase(t, "migrate_dirty")
+	broken := []migrate.Migration{
+		{Version: 1, Name: "ok", Up: `CREATE TABLE a (id INT)`, Down: `DROP TABLE a`, Checksum: "one"},
+		{Version: 2, Name: "broken", Up: `CREATE TABLE b (`, Checksum: "two"},
+	}
+	m := migrate.New(conn, broken)
+
+	applied, err := m.Up(ctx)
+	if err == nil || !reflect.DeepEqual(applied, []int64{1}) {
+		t.Fatalf("up = %v, %v; want version 1 applied and an error for 2", applied, err)
+	}
+	want := []migrationRow{{Version: 1, Checksum: "one"}, {Version: 2, Dirty: true, Checksum: "two"}}
+	if got := migrationRows(t, conn); !reflect.DeepEqual(got, want) {
+		t.Errorf("schema_migrations = %+v, want %+v", got, want)
+	}
+	if _, err := m.Up(ctx); !errors.Is(err, migrate.ErrDirty) {
+		t.Errorf("up on a dirty database: err = %v, want ErrDirty", err)
+	}
+
+	if err := m.Force(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if got := migrationRows(t, conn); !reflect.DeepEqual(got, want[:1]) {
+		t.Errorf("after force 1, schema_migrations = %+v", got)
+	}
+
+	if err := m.Force(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := migrationRows(t, conn); len(got) != 0 {
+		t.Errorf("after force 0, schema_migrations = %+v, want empty", got)
+	}
+}
+
+func TestMigrateAdoptsGolangMigrateTable(t *testing.T) {
+	ctx := context.Background()
+	conn := freshDatabase(t, "migrate_legacy")
+	if err := applyMigrations(conn); err != nil {
+		t.Fatal(err)
+	}
+	migrations, err := migrate.Load(db.Migrations, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := migrations[len(migrations)-1].Version
+	for _, stmt := range []string{
+		`CREATE TABLE schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)`,
+		fmt.Sprintf(`INSERT INTO schema_migrations (version, dirty) VALUES (%d, false)`, last),
+	} {
+		if _, err := conn.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := migrate.New(conn, migrations)
+	if applied, err := m.Up(ctx); err != nil || len(applied) != 0 {
+		t.Fatalf("up on an adopted database = %v, %v; want nothing to do", applied, err)
+	}
+	if got := migrationRows(t, conn); !reflect.DeepEqual(got, cleanRows(migrations)) {
+		t.Errorf("adopted schema_migrations = %+v, want %+v", got, cleanRows(migrations))
+	}
+}
+
+func TestUniqueNamesStep(t *testing.T) {
+	ctx := context.Background()
+	conn := freshDatabase(t, "migrate_unique_names")
+	migrations, err := migrate.Load(db.Migrations, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrate.New(conn, migrations).Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	repo := repository.NewUserRepository(conn, zap.NewNop())
+	dob := time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for _, name := range []string{"Alice", "alice", "Bob"} {
+		if _, err := repo.Create(ctx, name, dob); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Existing duplicates are reported and left alone.
+	_, err = migrate.New(conn, migrations, repository.UniqueNamesStep(true)).Up(ctx)
+	var dup *repository.DuplicateNamesError
+	if !errors.As(err, &dup) || len(dup.Names) != 1 || dup.Names[0].Name != "alice" || !reflect.DeepEqual(dup.Names[0].IDs, []int64{1, 2}) {
+		t.Fatalf("Up with duplicates: err = %v", err)
+	}
+	if got, err := repo.Count(ctx, repository.UserFilter{}); err != nil || got != 3 {
+		t.Errorf("users after the failed step = %d, %v; want all 3", got, err)
+	}
+
+	if _, err := repo.Update(ctx, 2, "Alicia", dob); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrate.New(conn, migrations, repository.UniqueNamesStep(true)).Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Create(ctx, "BOB", dob); !errors.Is(err, repository.ErrDuplicateName) {
+		t.Errorf("Create of a taken name: err = %v, want ErrDuplicateName", err)
+	}
+	taken := "ALICE"
+	if _, err := repo.UpdatePartial(ctx, 2, &taken, nil); !errors.Is(err, repository.ErrDuplicateName) {
+		t.Errorf("UpdatePartial to a taken name: err = %v, want ErrDuplicateName", err)
+	}
+	if got, err := repo.GetByName(ctx, "bob"); err != nil || got.Name != "Bob" {
+		t.Errorf("GetByName(bob) = %+v, %v", got, err)
+	}
+
+	// A duplicate fails only its own item of a batch or an import, though
+	// Postgres aborts the transaction at the violation.
+	svc := service.NewUserService(repo, zap.NewNop(), service.WithUniqueNames(true))
+	bobby := "Bobby"
+	batch, err := svc.UpdateUsers(ctx, []models.BatchUpdateItem{{ID: 2, Name: &taken}, {ID: 3, Name: &bobby}}, false)
+	if err != nil || batch.Updated != 1 || batch.Results[0].Status != models.BatchDuplicateName {
+		t.Errorf("UpdateUsers with a taken name = %+v, %v; want it reported and the rest updated", batch, err)
+	}
+	report, err := svc.ImportUsers(ctx, strings.NewReader("name,dob\nalicia,1990-01-01\nCarol,1990-01-01\n"), models.ImportOptions{}, nil)
+	if err != nil || report.Created != 1 || report.Failed != 1 {
+		t.Errorf("ImportUsers with a taken name = %+v, %v; want it reported and the rest created", report, err)
+	}
+
+	// Turning the flag off drops the index again.
+	if _, err := migrate.New(conn, migrations, repository.UniqueNamesStep(false)).Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Create(ctx, "BOB", dob); err != nil {
+		t.Errorf("Create after dropping the index: %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/events"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/service"
+	"github.com/srinivasarynh/age_calculator/internal/tenant"
+	"github.com/srinivasarynh/age_calculator/internal/testutil"
+	"go.uber.org/zap"
+)
+
+func TestSQLOutbox(t *testing.T) {
+	resetDatabase(t)
+	testutil.AssertOutbox(t, repository.NewUserRepository(testDB, zap.NewNop()))
+}
+
+// TestSQLOutboxClaimsEachEventOnce races dispatchers over one outbox; SKIP
+// LOCKED must hand every event to exactly one of them.
+func TestSQLOutboxClaimsEachEventOnce(t *testing.T) {
+	resetDatabase(t)
+	ctx := context.Background()
+	outbox := repository.NewOutboxRepository(testDB, zap.NewNop())
+	now := time.Now()
+	const total = 50
+	for i := range total {
+		if err := outbox.Enqueue(ctx, events.Event{Type: models.EventUserAnonymized, TenantID: tenant.Default, At: now, Data: models.UserEvent{UserID: int32(i)}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var mu sync.Mutex
+	claimed := make(map[int64]int)
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				event, err := outbox.Claim(ctx, now.Add(time.Second), time.Hour)
+				if err != nil {
+					return
+				}
+				mu.Lock()
+				claimed[event.ID]++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(claimed) != total {
+		t.Errorf("claimed %d events, want %d", len(claimed), total)
+	}
+	for id, n := range claimed {
+		if n != 1 {
+			t.Errorf("event %d claimed %d times", id, n)
+		}
+	}
+}
+
+// TestOutboxSurvivesDispatcherRestart anonymizes a user, lets a dispatcher
+// claim the event and die before delivering it, and starts another.
+func TestOutboxSurvivesDispatcherRestart(t *testing.T) {
+	resetDatabase(t)
+	ctx := context.Background()
+	id := seedUser(t, "Alice", "1990-06-15")
+	users := repository.NewUserRepository(testDB, zap.NewNop())
+	svc := service.NewUserService(users, zap.NewNop(), service.WithOutbox(), service.WithNotificationRepository(repository.NewNotificationRepository(testDB, zap.NewNop())))
+	if _, err := svc.AnonymizeUser(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+
+	outbox := repository.NewOutboxRepository(testDB, zap.NewNop())
+	if _, err := outbox.Claim(ctx, time.Now(), time.Minute); err != nil {
+		t.Fatalf("the anonymization stored no event: %v", err)
+	}
+
+	hub := events.NewHub()
+	ch, unsubscribe := hub.Subscribe(10)
+	defer unsubscribe()
+	restarted := service.NewOutboxDispatcher(outbox, hub, zap.NewNop(), service.WithDispatcherClock(clock.Fixed(time.Now().Add(2*time.Minute))))
+	delivered, err := restarted.RunOnce(ctx)
+	if err != nil || delivered != 1 {
+		t.Fatalf("RunOnce after the lease = %d, %v; want the event delivered", delivered, err)
+	}
+	if event := <-ch; event.Type != models.EventUserAnonymized || event.TenantID != tenant.Default {
+		t.Errorf("delivered %+v", event)
+	}
+	if delivered, err := restarted.RunOnce(ctx); err != nil || delivered != 0 {
+		t.Errorf("second RunOnce = %d, %v; want nothing left", delivered, err)
+	}
+}
@@ -0,0 +1,91 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/service"
+	"go.uber.org/zap"
+)
+
+// TestMaxUsersConcurrentCreates races creates for the last place under the
+// quota; the tenant lock must let exactly one through.
+func TestMaxUsersConcurrentCreates(t *testing.T) {
+	resetDatabase(t)
+	for _, name := range []string{"Alice", "Bob", "Carol", "Dave"} {
+		seedUser(t, name, "1990-06-15")
+	}
+	svc := service.NewUserService(repository.NewUserRepository(testDB, zap.NewNop()), zap.NewNop(), service.WithMaxUsers(5))
+
+	const racers = 8
+	errs := make(chan error, racers)
+	var start sync.WaitGroup
+	start.Add(1)
+	for range racers {
+		go func() {
+			start.Wait()
+			_, err := svc.CreateUser(context.Background(), &models.CreateUserRequest{Name: "Eve", DOB: "1991-01-01"})
+			errs <- err
+		}()
+	}
+	start.Done()
+
+	succeeded := 0
+	for range racers {
+		var qerr *service.QuotaError
+		switch err := <-errs; {
+		case err == nil:
+			succeeded++
+		case !errors.As(err, &qerr):
+			t.Errorf("create: %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("%d creates succeeded, want 1", succeeded)
+	}
+	var n int
+	if err := testDB.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&n); err != nil || n != 5 {
+		t.Errorf("users = %d (err %v), want 5", n, err)
+	}
+}
+
+// TestDedupeWindowConcurrentCreates submits the same user twice at once; the
+// second create must wait for the first and return its row.
+func TestDedupeWindowConcurrentCreates(t *testing.T) {
+	resetDatabase(t)
+	svc := service.NewUserService(repository.NewUserRepository(testDB, zap.NewNop()), zap.NewNop(), service.WithDedupeWindow(5*time.Second))
+
+	results := make(chan *models.UserResponse, 2)
+	var start sync.WaitGroup
+	start.Add(1)
+	for range 2 {
+		go func() {
+			start.Wait()
+			user, err := svc.CreateUser(context.Background(), &models.CreateUserRequest{Name: "Alice", DOB: "1990-06-15"})
+			if err != nil {
+				t.Error(err)
+			}
+			results <- user
+		}()
+	}
+	start.Done()
+
+	first, second := <-results, <-results
+	if first == nil || second == nil {
+		t.FailNow()
+	}
+	if first.ID != second.ID || first.Deduplicated == second.Deduplicated {
+		t.Errorf("creates returned %+v and %+v, want one user, deduplicated once", first, second)
+	}
+	var n int
+	if err := testDB.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&n); err != nil || n != 1 {
+		t.Errorf("users = %d (err %v), want 1", n, err)
+	}
+}
@@ -0,0 +1,155 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/srinivasarynh/age_calculator/config"
+	"github.com/srinivasarynh/age_calculator/db"
+	"github.com/srinivasarynh/age_calculator/internal/migrate"
+)
+
+func schemaProblems(t *testing.T, conn *sql.DB, version int64) []string {
+	t.Helper()
+	req := db.SchemaRequirements()
+	req.Version = version
+	err := config.VerifySchema(context.Background(), conn, req)
+	if err == nil {
+		return nil
+	}
+	var schemaErr *config.SchemaError
+	if !errors.As(err, &schemaErr) {
+		t.Fatalf("VerifySchema error = %v, want a *config.SchemaError", err)
+	}
+	return schemaErr.Problems
+}
+
+func TestVerifySchema(t *testing.T) {
+	ctx := context.Background()
+	migrations, err := migrate.Load(db.Migrations, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest := migrations[len(migrations)-1].Version
+
+	empty := freshDatabase(t, "schema_empty")
+	want := []string{
+		"table schema_migrations missing; run migrations",
+		"table users missing; run migrations",
+		"table jobs missing; run migrations",
+		"table birthday_notifications missing; run migrations",
+		"table share_tokens missing; run migrations",
+		"table events_outbox missing; run migrations",
+		"table access_counts missing; run migrations",
+		"extension pg_trgm missing; install it or run migrations as a role allowed to",
+	}
+	if got := schemaProblems(t, empty, latest); !reflect.DeepEqual(got, want) {
+		t.Errorf("empty database: problems = %q, want %q", got, want)
+	}
+
+	conn := freshDatabase(t, "schema_check")
+	m := migrate.New(conn, migrations)
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := schemaProblems(t, conn, latest); got != nil {
+		t.Errorf("migrated database: problems = %q", got)
+	}
+
+	// The last migration creates access_counts.
+	if _, err := m.Down(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{
+		fmt.Sprintf("schema is at version %d, this build needs %d; run migrations", migrations[len(migrations)-2].Version, latest),
+		"table access_counts missing; run migrations",
+	}
+	if got := schemaProblems(t, conn, latest); !reflect.DeepEqual(got, want) {
+		t.Errorf("one migration behind: problems = %q, want %q", got, want)
+	}
+
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		`ALTER TABLE users ALTER COLUMN dob TYPE TEXT`,
+		`DROP TABLE share_tokens`,
+	} {
+		if _, err := conn.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want = []string{
+		"column users.dob is text, want date; run migrations",
+		"table share_tokens missing; run migrations",
+	}
+	if got := schemaProblems(t, conn, latest); !reflect.DeepEqual(got, want) {
+		t.Errorf("altered by hand: problems = %q, want %q", got, want)
+	}
+}
+
+func TestVerifySchemaPrivileges(t *testing.T) {
+	ctx := context.Background()
+	migrations, err := migrate.Load(db.Migrations, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := freshDatabase(t, "schema_privileges")
+	if _, err := migrate.New(conn, migrations).Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Roles belong to the cluster, not the database.
+	for _, stmt := range []string{
+		`DROP ROLE IF EXISTS schema_reader`,
+		`CREATE ROLE schema_reader LOGIN PASSWORD 'reader'`,
+	} {
+		if _, err := testDB.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() { testDB.Exec(`DROP ROLE IF EXISTS schema_reader`) })
+	if _, err := conn.Exec(`GRANT SELECT ON ALL TABLES IN SCHEMA public TO schema_reader`); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Exec(`REVOKE ALL ON ALL TABLES IN SCHEMA public FROM schema_reader`) })
+
+	dsn, err := url.Parse(testDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dsn.User = url.UserPassword("schema_reader", "reader")
+	dsn.Path = "/schema_privileges"
+	reader, err := sql.Open("postgres", dsn.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	want := []string{
+		"database role lacks INSERT on users",
+		"database role lacks UPDATE on users",
+		"database role lacks DELETE on users",
+		"database role lacks INSERT on jobs",
+		"database role lacks UPDATE on jobs",
+		"database role lacks INSERT on birthday_notifications",
+		"database role lacks DELETE on birthday_notifications",
+		"database role lacks INSERT on share_tokens",
+		"database role lacks UPDATE on share_tokens",
+		"database role lacks INSERT on events_outbox",
+		"database role lacks UPDATE on events_outbox",
+		"database role lacks DELETE on events_outbox",
+		"database role lacks INSERT on access_counts",
+		"database role lacks UPDATE on access_counts",
+	}
+	if got := schemaProblems(t, reader, migrations[len(migrations)-1].Version); !reflect.DeepEqual(got, want) {
+		t.Errorf("read-only role: problems = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build integration
+
+package integration
+
+import (
+	"testing"
+
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/testutil"
+	"go.uber.org/zap"
+)
+
+func TestSQLShareRepository(t *testing.T) {
+	resetDatabase(t)
+	testutil.AssertShareRepository(t, repository.NewShareRepository(testDB, zap.NewNop()), seedUser(t, "Alice", "1990-06-15"))
+}
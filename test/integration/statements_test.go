@@ -0,0 +1,110 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"go.uber.org/zap"
+)
+
+// singleConnDB is a pool of one connection, so every query runs in the
+// session a statement was prepared in, or was deallocated from.
+func singleConnDB(t testing.TB) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("postgres", testDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func assertHotQueries(t *testing.T, repo repository.UserRepository, id int32) {
+	t.Helper()
+	ctx := context.Background()
+	if user, err := repo.GetById(ctx, id); err != nil || user.Name != "Alice" {
+		t.Errorf("GetById = %+v, %v", user, err)
+	}
+	if users, err := repo.List(ctx, repository.ListQuery{Limit: 10}); err != nil || len(users) != 1 {
+		t.Errorf("List = %v, %v", users, err)
+	}
+	if n, err := repo.Count(ctx, repository.UserFilter{}); err != nil || n != 1 {
+		t.Errorf("Count = %d, %v", n, err)
+	}
+}
+
+func TestPreparedStatements(t *testing.T) {
+	resetDatabase(t)
+	id := seedUser(t, "Alice", "1990-05-10")
+	db := singleConnDB(t)
+	repo := repository.NewUserRepository(db, zap.NewNop())
+	if err := repo.(repository.StatementPreparer).PrepareStatements(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	assertHotQueries(t, repo, id)
+
+	if err := repo.Transact(context.Background(), func(tx repository.UserRepository) error {
+		assertHotQueries(t, tx, id)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// As a pooler in transaction mode would, the session loses them.
+	if _, err := db.Exec(`DEALLOCATE ALL`); err != nil {
+		t.Fatal(err)
+	}
+	assertHotQueries(t, repo, id)
+}
+
+func TestPreparedStatementsFallBackWhenPrepareFails(t *testing.T) {
+	resetDatabase(t)
+	id := seedUser(t, "Alice", "1990-05-10")
+	repo := repository.NewUserRepository(singleConnDB(t), zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := repo.(repository.StatementPreparer).PrepareStatements(ctx); err == nil {
+		t.Fatal("PrepareStatements with a cancelled context: err = nil")
+	}
+	assertHotQueries(t, repo, id)
+}
+
+// BenchmarkGetById compares GetById run unprepared, as without the warm-up,
+// with it prepared:
+//
+//	go test -tags integration -run '^$' -bench GetById ./test/integration
+func BenchmarkGetById(b *testing.B) {
+	ctx := context.Background()
+	users := repository.NewUserRepository(testDB, zap.NewNop())
+	user, err := users.Create(ctx, "Bench", time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { users.Delete(ctx, user.ID) })
+
+	for _, prepared := range []bool{false, true} {
+		name := "unprepared"
+		if prepared {
+			name = "prepared"
+		}
+		b.Run(name, func(b *testing.B) {
+			repo := repository.NewUserRepository(singleConnDB(b), zap.NewNop())
+			if prepared {
+				if err := repo.(repository.StatementPreparer).PrepareStatements(ctx); err != nil {
+					b.Fatal(err)
+				}
+			}
+			for b.Loop() {
+				if _, err := repo.GetById(ctx, user.ID); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,495 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/srinivasarynh/age_calculator/internal/clock"
+	"github.com/srinivasarynh/age_calculator/internal/models"
+	"github.com/srinivasarynh/age_calculator/internal/repository"
+	"github.com/srinivasarynh/age_calculator/internal/testutil"
+	"go.uber.org/zap"
+)
+
+func TestUserLifecycle(t *testing.T) {
+	resetDatabase(t)
+
+	var created models.UserResponse
+	status := call(t, http.MethodPost, "/api/v1/users", map[string]string{"name": "Alice", "dob": "1990-05-10"}, &created)
+	if status != http.StatusCreated {
+		t.Fatalf("create status = %d, want 201", status)
+	}
+	if created.ID == 0 || created.Name != "Alice" || created.DOB != "1990-05-10" {
+		t.Fatalf("created = %+v", created)
+	}
+
+	var fetched models.UserResponse
+	path := fmt.Sprintf("/api/v1/users/%d", created.ID)
+	if status := call(t, http.MethodGet, path, nil, &fetched); status != http.StatusOK {
+		t.Fatalf("get status = %d, want 200", status)
+	}
+	if fetched.DOB != "1990-05-10" || fetched.Age == nil {
+		t.Errorf("fetched = %+v", fetched)
+	}
+
+	var updated models.UserResponse
+	status = call(t, http.MethodPut, path, map[string]string{"name": "Alice Updated", "dob": "1991-03-15"}, &updated)
+	if status != http.StatusOK {
+		t.Fatalf("update status = %d, want 200", status)
+	}
+	if updated.Name != "Alice Updated" || updated.DOB != "1991-03-15" {
+		t.Errorf("updated = %+v", updated)
+	}
+
+	if status := call(t, http.MethodDelete, path, nil, nil); status != http.StatusNoContent {
+		t.Fatalf("delete status = %d, want 204", status)
+	}
+	if status := call(t, http.MethodGet, path, nil, nil); status != http.StatusNotFound {
+		t.Errorf("get after delete status = %d, want 404", status)
+	}
+}
+
+func TestListUsersPagination(t *testing.T) {
+	resetDatabase(t)
+	for i := 1; i <= 12; i++ {
+		seedUser(t, fmt.Sprintf("User %02d", i), "1990-01-01")
+	}
+
+	var page models.UserListResponse
+	if status := call(t, http.MethodGet, "/api/v1/users?page=3&page_size=5", nil, &page); status != http.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	if page.Total == nil || *page.Total != 12 || page.TotalPages == nil || *page.TotalPages != 3 || page.Page != 3 || page.PageSize != 5 {
+		t.Errorf("metadata = %+v", page)
+	}
+	if len(page.Users) != 2 || page.Users[0].Name != "User 11" || page.Users[1].Name != "User 12" {
+		t.Errorf("users = %+v", page.Users)
+	}
+}
+
+func TestListUsersKeysetAcrossInserts(t *testing.T) {
+	resetDatabase(t)
+	for i := 1; i <= 5; i++ {
+		seedUser(t, fmt.Sprintf("User %02d", i), "1990-01-01")
+	}
+
+	seen := map[int32]bool{}
+	cursor := ""
+	for page := 0; page < 5; page++ {
+		path := "/api/v1/users?sort=-created_at&page_size=2"
+		if cursor != "" {
+			path += "&cursor=" + cursor
+		}
+		var list models.UserListResponse
+		if status := call(t, http.MethodGet, path, nil, &list); status != http.StatusOK {
+			t.Fatalf("status = %d, want 200", status)
+		}
+		for _, u := range list.Users {
+			if seen[u.ID] {
+				t.Errorf("user %d returned on two pages", u.ID)
+			}
+			seen[u.ID] = true
+		}
+		// Rows newer than the cursor must not shift later pages.
+		seedUser(t, fmt.Sprintf("Inserted %d", page), "2000-01-01")
+		if list.NextCursor == "" {
+			break
+		}
+		cursor = list.NextCursor
+	}
+	if len(seen) != 5 {
+		t.Errorf("saw %d seeded users, want 5", len(seen))
+	}
+}
+
+func TestListUsersSortByAgeKeyset(t *testing.T) {
+	resetDatabase(t)
+	for i, dob := range []string{"1990-01-01", "2000-01-01", "1990-01-01", "1980-01-01", "2000-01-01"} {
+		seedUser(t, fmt.Sprintf("User %02d", i+1), dob)
+	}
+
+	for sort, want := range map[string][]string{
+		"age":  {"User 05", "User 02", "User 03", "User 01", "User 04"},
+		"-age": {"User 04", "User 01", "User 03", "User 02", "User 05"},
+	} {
+		var got []string
+		cursor := ""
+		for page := 0; page < 5; page++ {
+			path := "/api/v1/users?page_size=2&sort=" + sort
+			if cursor != "" {
+				path += "&cursor=" + cursor
+			}
+			var list models.UserListResponse
+			if status := call(t, http.MethodGet, path, nil, &list); status != http.StatusOK {
+				t.Fatalf("status = %d, want 200", status)
+			}
+			for _, u := range list.Users {
+				got = append(got, u.Name)
+			}
+			if list.NextCursor == "" {
+				break
+			}
+			cursor = list.NextCursor
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("sort=%s pages = %v, want %v", sort, got, want)
+		}
+	}
+}
+
+func TestOldestAndYoungestUsers(t *testing.T) {
+	resetDatabase(t)
+	var empty map[string]any
+	if status := call(t, http.MethodGet, "/api/v1/users/oldest", nil, &empty); status != http.StatusNotFound || empty["error"] != "There are no users" {
+		t.Errorf("oldest of no users = %d %v, want 404", status, empty)
+	}
+	for i, dob := range []string{"1990-01-01", "2000-01-01", "1980-01-01", "2000-01-01"} {
+		seedUser(t, fmt.Sprintf("User %02d", i+1), dob)
+	}
+
+	for path, want := range map[string][]string{
+		"/api/v1/users/oldest?count=2":   {"User 03", "User 01"},
+		"/api/v1/users/youngest?count=3": {"User 02", "User 04", "User 01"},
+	} {
+		var list models.AgeRankResponse
+		if status := call(t, http.MethodGet, path, nil, &list); status != http.StatusOK {
+			t.Fatalf("GET %s status = %d, want 200", path, status)
+		}
+		var got []string
+		for _, u := range list.Users {
+			got = append(got, u.Name)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("GET %s = %v, want %v", path, got, want)
+		}
+	}
+	var youngest models.UserResponse
+	if status := call(t, http.MethodGet, "/api/v1/users/youngest", nil, &youngest); status != http.StatusOK || youngest.Name != "User 02" {
+		t.Errorf("youngest = %d %+v, want User 02", status, youngest)
+	}
+}
+
+func TestAgePercentile(t *testing.T) {
+	resetDatabase(t)
+	var ids []int32
+	for _, dob := range []string{"1980-01-01", "1990-01-01", "1990-01-01", "2000-01-01", "2010-01-01"} {
+		ids = append(ids, seedUser(t, "User "+dob, dob))
+	}
+
+	var got models.AgePercentileResponse
+	if status := call(t, http.MethodGet, fmt.Sprintf("/api/v1/users/%d/percentile", ids[1]), nil, &got); status != http.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	if got.Younger != 2 || got.Older != 1 || got.SameDOB != 2 || got.Total != 5 || got.Percentile == nil || *got.Percentile != 60 {
+		t.Errorf("percentile = %+v", got)
+	}
+	if status := call(t, http.MethodGet, fmt.Sprintf("/api/v1/users/%d/percentile?dob_to=1999-12-31", ids[1]), nil, &got); status != http.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	if got.Younger != 0 || got.Total != 3 || got.Percentile == nil || *got.Percentile != 33.3 {
+		t.Errorf("filtered percentile = %+v", got)
+	}
+}
+
+func TestAgeCohorts(t *testing.T) {
+	resetDatabase(t)
+	now := time.Now().UTC()
+	for _, years := range []int{5, 17, 18, 40, 40, 90} {
+		seedUser(t, fmt.Sprintf("Aged %d", years), now.AddDate(-years, 0, 0).Format("2006-01-02"))
+	}
+
+	var got models.CohortsResponse
+	if status := call(t, http.MethodGet, "/api/v1/users/stats/cohorts?bounds=18,65", nil, &got); status != http.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	var counts []int64
+	for _, c := range got.Cohorts {
+		counts = append(counts, c.Count)
+	}
+	if !slices.Equal(counts, []int64{2, 3, 1}) || got.Total != 6 || got.Cohorts[2].Label != "65+" {
+		t.Errorf("cohorts = %+v", got)
+	}
+}
+
+func TestBirthdayTwinsAndCollisions(t *testing.T) {
+	resetDatabase(t)
+	var ids []int32
+	for _, dob := range []string{"1990-05-10", "1985-05-10", "2001-05-10", "1990-07-04", "1970-07-04", "1999-12-31"} {
+		ids = append(ids, seedUser(t, "User "+dob, dob))
+	}
+
+	var twins models.UserListResponse
+	if status := call(t, http.MethodGet, fmt.Sprintf("/api/v1/users/%d/birthday-twins?page_size=1&page=2", ids[0]), nil, &twins); status != http.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	if twins.Total == nil || *twins.Total != 2 || len(twins.Users) != 1 || twins.Users[0].ID != ids[2] {
+		t.Errorf("twins page 2 = %+v, total %v", twins.Users, twins.Total)
+	}
+
+	var collisions models.BirthdayCollisionsResponse
+	if status := call(t, http.MethodGet, "/api/v1/users/birthday-collisions", nil, &collisions); status != http.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	want := []models.BirthdayCollision{{Month: 5, Day: 10, Date: "05-10", Count: 3}, {Month: 7, Day: 4, Date: "07-04", Count: 2}}
+	if !slices.Equal(collisions.Collisions, want) {
+		t.Errorf("collisions = %+v, want %+v", collisions.Collisions, want)
+	}
+}
+
+func TestListUsersNameFilterIsLiteral(t *testing.T) {
+	resetDatabase(t)
+	for _, name := range []string{"Alice", "Al_ce", "100% Bob"} {
+		seedUser(t, name, "1990-01-01")
+	}
+
+	for query, want := range map[string]string{"_": "Al_ce", "%": "100% Bob", "ALI": "Alice"} {
+		var list models.UserListResponse
+		if status := call(t, http.MethodGet, "/api/v1/users?name="+url.QueryEscape(query), nil, &list); status != http.StatusOK {
+			t.Fatalf("name=%s status = %d, want 200", query, status)
+		}
+		if len(list.Users) != 1 || list.Users[0].Name != want || list.Total == nil || *list.Total != 1 {
+			t.Errorf("name=%s users = %+v, total = %v, want only %s", query, list.Users, list.Total, want)
+		}
+	}
+}
+
+func TestListUsersDOBFilters(t *testing.T) {
+	resetDatabase(t)
+	for _, dob := range []string{"1980-06-01", "1990-01-01", "1990-12-31", "2000-01-01"} {
+		seedUser(t, "User "+dob, dob)
+	}
+
+	var list models.UserListResponse
+	if status := call(t, http.MethodGet, "/api/v1/users?dob_from=1990-01-01&dob_to=1990-12-31", nil, &list); status != http.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	if len(list.Users) != 2 || list.Users[0].DOB != "1990-01-01" || list.Users[1].DOB != "1990-12-31" || *list.Total != 2 {
+		t.Errorf("users = %+v, total = %v, want the two 1990 DOBs", list.Users, list.Total)
+	}
+}
+
+func TestListUsersNameFuzzyRanksBySimilarity(t *testing.T) {
+	resetDatabase(t)
+	for _, name := range []string{"Mary Jones", "Jon", "Johnny Smith", "John"} {
+		seedUser(t, name, "1990-01-01")
+	}
+
+	// Similarities to "Johnn" are 0.57, 0.36, 0.25 and 0.13; the last two fall
+	// under the default threshold of 0.3.
+	var list models.UserListResponse
+	if status := call(t, http.MethodGet, "/api/v1/users?name_fuzzy=Johnn", nil, &list); status != http.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	var names []string
+	for _, u := range list.Users {
+		names = append(names, u.Name)
+	}
+	if !slices.Equal(names, []string{"John", "Johnny Smith"}) || *list.Total != 2 {
+		t.Errorf("names = %v, total = %v; want John then Johnny Smith", names, list.Total)
+	}
+}
+
+// The SQL and memory repositories must agree on whose birthday it is, each
+// user in their own zone.
+func TestListBirthdaysByTimezone(t *testing.T) {
+	resetDatabase(t)
+	ctx := context.Background()
+	sqlRepo := repository.NewUserRepository(testDB, zap.NewNop())
+	memory := repository.NewMemoryUserRepository(clock.Real())
+	users := []struct {
+		name, dob, timezone string
+	}{
+		{"West Jun 14", "1990-06-14", "Etc/GMT+8"},
+		{"West Jun 15", "1990-06-15", "Etc/GMT+8"},
+		{"East Jun 14", "1990-06-14", "Pacific/Tongatapu"},
+		{"East Jun 15", "1990-06-15", "Pacific/Tongatapu"},
+		{"Default Jun 14", "1990-06-14", ""},
+		{"Leapling", "2000-02-29", ""},
+	}
+	for _, repo := range []repository.UserRepository{sqlRepo, memory} {
+		for _, u := range users {
+			dob, _ := time.Parse("2006-01-02", u.dob)
+			created, err := repo.Create(ctx, u.name, dob)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := repo.SetTimezone(ctx, created.ID, u.timezone); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	tests := []struct {
+		now         time.Time
+		defaultZone string
+		want        []string
+	}{
+		{now: time.Date(2025, 6, 14, 23, 30, 0, 0, time.UTC), defaultZone: "UTC", want: []string{"West Jun 14", "East Jun 15", "Default Jun 14"}},
+		{now: time.Date(2025, 6, 14, 23, 30, 0, 0, time.UTC), defaultZone: "Pacific/Tongatapu", want: []string{"West Jun 14", "East Jun 15"}},
+		{now: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC), defaultZone: "UTC", want: []string{"Leapling"}},
+		{now: time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC), defaultZone: "UTC", want: []string{"Leapling"}},
+		{now: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), defaultZone: "UTC"},
+	}
+	for _, tt := range tests {
+		for name, repo := range map[string]repository.UserRepository{"sql": sqlRepo, "memory": memory} {
+			list, err := repo.ListBirthdays(ctx, tt.now, tt.defaultZone)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, u := range list {
+				got = append(got, u.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("%s at %s in %s: birthdays = %v, want %v", name, tt.now, tt.defaultZone, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	resetDatabase(t)
+
+	requests := []struct {
+		method string
+		body   any
+	}{
+		{method: http.MethodGet},
+		{method: http.MethodPut, body: map[string]string{"name": "Nobody", "dob": "1990-01-01"}},
+		{method: http.MethodDelete},
+	}
+	for _, r := range requests {
+		if status := call(t, r.method, "/api/v1/users/999", r.body, nil); status != http.StatusNotFound {
+			t.Errorf("%s status = %d, want 404", r.method, status)
+		}
+	}
+}
+
+func TestSQLRepositoryNeverReturnsNilWithoutError(t *testing.T) {
+	resetDatabase(t)
+	testutil.AssertNoNilResults(t, repository.NewUserRepository(testDB, zap.NewNop()))
+}
+
+func TestSQLRepositoryTenantIsolation(t *testing.T) {
+	resetDatabase(t)
+	testutil.AssertTenantIsolation(t, repository.NewUserRepository(testDB, zap.NewNop()))
+}
+
+func TestSQLRepositoryTransactions(t *testing.T) {
+	resetDatabase(t)
+	testutil.AssertTransactions(t, repository.NewUserRepository(testDB, zap.NewNop()))
+}
+
+func TestSQLRepositoryAnonymization(t *testing.T) {
+	resetDatabase(t)
+	testutil.AssertAnonymization(t, repository.NewUserRepository(testDB, zap.NewNop()))
+}
+
+func TestRejectedWrites(t *testing.T) {
+	resetDatabase(t)
+	id := seedUser(t, "Alice", "1990-05-10")
+
+	bodies := []map[string]string{
+		{"name": "A", "dob": "1990-05-10"},
+		{"name": strings.Repeat("x", 101), "dob": "1990-05-10"},
+		{"name": "Alice", "dob": "1990-13-40"},
+		{"name": "Alice"},
+	}
+	for _, body := range bodies {
+		if status := call(t, http.MethodPost, "/api/v1/users", body, nil); status != http.StatusBadRequest {
+			t.Errorf("create %v status = %d, want 400", body, status)
+		}
+		if status := call(t, http.MethodPut, fmt.Sprintf("/api/v1/users/%d", id), body, nil); status != http.StatusBadRequest {
+			t.Errorf("update %v status = %d, want 400", body, status)
+		}
+	}
+
+	var count int
+	if err := testDB.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("users table has %d rows, want 1", count)
+	}
+}
+
+func TestDOBSurvivesNonUTCSessionTimeZone(t *testing.T) {
+	resetDatabase(t)
+
+	db, err := sql.Open("postgres", testDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	if _, err := db.Exec(`SET TIME ZONE 'Pacific/Kiritimati'`); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := time.Local
+	time.Local = time.FixedZone("UTC-10", -10*60*60)
+	defer func() { time.Local = saved }()
+
+	repo := repository.NewUserRepository(db, zap.NewNop())
+	dob := time.Date(1990, 5, 10, 0, 0, 0, 0, time.UTC)
+	created, err := repo.Create(context.Background(), "Alice", dob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetched, err := repo.GetById(context.Background(), created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range []*models.User{created, fetched} {
+		if got := u.DOB.Format("2006-01-02"); got != "1990-05-10" {
+			t.Errorf("DOB = %s, want 1990-05-10", got)
+		}
+	}
+
+	var user models.UserResponse
+	call(t, http.MethodGet, fmt.Sprintf("/api/v1/users/%d", created.ID), nil, &user)
+	if user.DOB != "1990-05-10" {
+		t.Errorf("API DOB = %s, want 1990-05-10", user.DOB)
+	}
+}
+
+// A query blocked on a table lock must give up when its context does, rather
+// than holding a connection until the lock is released.
+func TestRepositoryQueriesAbortOnCancel(t *testing.T) {
+	resetDatabase(t)
+	id := seedUser(t, "Alice", "1990-05-10")
+
+	tx, err := testDB.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`LOCK TABLE users IN ACCESS EXCLUSIVE MODE`); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := repository.NewUserRepository(testDB, zap.NewNop())
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := repo.GetById(ctx, id); err == nil {
+		t.Fatal("GetById succeeded while the table was locked")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("GetById returned after %v, want it to abort near the 200ms deadline", elapsed)
+	}
+	if _, err := repo.List(ctx, repository.ListQuery{Limit: 10}); err == nil {
+		t.Error("List succeeded with an expired context")
+	}
+}